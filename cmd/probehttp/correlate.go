@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/url"
+
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/parser"
+)
+
+// schemeCorrelator links the http and https results of the same input, host
+// and port for -correlate-schemes. Results whose sibling scheme was not
+// probed pass straight through; the others are held until their sibling
+// arrives or every URL expanded from their input has reported.
+type schemeCorrelator struct {
+	defaults parser.DefaultPorts
+	schemes  map[string]int                // pairing key -> number of schemes probed
+	pending  map[string]int                // input -> results still expected
+	held     map[string]output.ProbeResult // pairing key -> result waiting for its sibling
+	heldKeys map[string][]string           // input -> keys of its held results
+}
+
+// newSchemeCorrelator prepares correlation for the URLs about to be probed.
+// vhostURLs maps IP targets to the -vhost-list URLs probed against them,
+// whose results count towards the IP target's input.
+func newSchemeCorrelator(urls []string, originalInputMap map[string]string, vhostURLs map[string][]string, defaults parser.DefaultPorts) *schemeCorrelator {
+	c := &schemeCorrelator{
+		defaults: defaults,
+		schemes:  make(map[string]int),
+		pending:  make(map[string]int),
+		held:     make(map[string]output.ProbeResult),
+		heldKeys: make(map[string][]string),
+	}
+	for _, u := range urls {
+		input := originalInputMap[u]
+		c.pending[input]++
+		if key, ok := c.key(input, "", parser.NormalizeURL(u, defaults)); ok {
+			c.schemes[key]++
+		}
+		target, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+		for _, vhostURL := range vhostURLs[u] {
+			c.pending[input]++
+			if key, ok := c.key(input, target.Hostname(), parser.NormalizeURL(vhostURL, defaults)); ok {
+				c.schemes[key]++
+			}
+		}
+	}
+	return c
+}
+
+// key returns the pairing key of a normalized URL: the input, the IP of a
+// -vhost-list candidate, host and explicit port. Default ports are already
+// stripped by normalization, so http://host/ and https://host/ pair up.
+func (c *schemeCorrelator) key(input, vhostIP, normalizedURL string) (string, bool) {
+	u, err := url.Parse(normalizedURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	return input + "\x00" + vhostIP + "\x00" + u.Hostname() + "\x00" + u.Port(), true
+}
+
+// add takes the next result and returns the results ready to be written,
+// in arrival order of the pair.
+func (c *schemeCorrelator) add(result output.ProbeResult) []output.ProbeResult {
+	var ready []output.ProbeResult
+
+	var vhostIP string
+	if result.VhostCandidate != "" {
+		vhostIP = result.HostIP
+	}
+	key, ok := c.key(result.Input, vhostIP, result.NormalizedURL)
+	siblingProbed := ok && c.schemes[key] > 1
+	result.SiblingSchemeProbed = &siblingProbed
+
+	switch {
+	case !siblingProbed:
+		ready = append(ready, result)
+	case c.isHeld(key):
+		sibling := c.held[key]
+		delete(c.held, key)
+		if c.converged(sibling, result) {
+			sibling.Converged = true
+			result.Converged = true
+		}
+		ready = append(ready, sibling, result)
+	default:
+		c.held[key] = result
+		c.heldKeys[result.Input] = append(c.heldKeys[result.Input], key)
+	}
+
+	// Once an input has no more results coming, release whatever it still holds
+	c.pending[result.Input]--
+	if c.pending[result.Input] <= 0 {
+		ready = append(ready, c.release(result.Input)...)
+		delete(c.pending, result.Input)
+	}
+	return ready
+}
+
+// flush returns every result still held, e.g. when probing was cancelled or
+// skipped results were suppressed.
+func (c *schemeCorrelator) flush() []output.ProbeResult {
+	var ready []output.ProbeResult
+	for input := range c.heldKeys {
+		ready = append(ready, c.release(input)...)
+	}
+	return ready
+}
+
+func (c *schemeCorrelator) isHeld(key string) bool {
+	_, ok := c.held[key]
+	return ok
+}
+
+// release removes and returns the held results of input.
+func (c *schemeCorrelator) release(input string) []output.ProbeResult {
+	var ready []output.ProbeResult
+	for _, key := range c.heldKeys[input] {
+		if result, ok := c.held[key]; ok {
+			ready = append(ready, result)
+			delete(c.held, key)
+		}
+	}
+	delete(c.heldKeys, input)
+	return ready
+}
+
+// converged reports whether both sibling probes completed and ended on the
+// same final URL.
+func (c *schemeCorrelator) converged(a, b output.ProbeResult) bool {
+	if a.Error != "" || b.Error != "" || a.FinalURL == "" || b.FinalURL == "" {
+		return false
+	}
+	return parser.NormalizeURL(a.FinalURL, c.defaults) == parser.NormalizeURL(b.FinalURL, c.defaults)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/parser"
+)
+
+func correlatorResult(url, input, finalURL, errMsg string) output.ProbeResult {
+	return output.ProbeResult{
+		Input:         input,
+		URL:           url,
+		NormalizedURL: parser.NormalizeURL(url, parser.StandardPorts),
+		FinalURL:      finalURL,
+		Error:         errMsg,
+	}
+}
+
+func TestSchemeCorrelator(t *testing.T) {
+	urls := []string{
+		"http://a.example/", "https://a.example/", // converge
+		"http://b.example/", "https://b.example/", // diverge
+		"https://c.example/", // no sibling
+		"http://d.example:8080/", "https://d.example:8080/", "http://d.example:8081/",
+	}
+	inputs := map[string]string{
+		"http://a.example/": "a.example", "https://a.example/": "a.example",
+		"http://b.example/": "b.example", "https://b.example/": "b.example",
+		"https://c.example/":     "https://c.example",
+		"http://d.example:8080/": "d.example", "https://d.example:8080/": "d.example", "http://d.example:8081/": "d.example",
+	}
+	c := newSchemeCorrelator(urls, inputs, nil, parser.StandardPorts)
+
+	// Unpaired results pass straight through
+	ready := c.add(correlatorResult("https://c.example/", "https://c.example", "https://c.example/", ""))
+	if len(ready) != 1 || ready[0].SiblingSchemeProbed == nil || *ready[0].SiblingSchemeProbed || ready[0].Converged {
+		t.Fatalf("unpaired result = %+v", ready)
+	}
+
+	// The first of a pair is held until its sibling arrives
+	if ready := c.add(correlatorResult("http://a.example/", "a.example", "https://a.example/", "")); len(ready) != 0 {
+		t.Fatalf("first sibling should be held, got %d results", len(ready))
+	}
+	ready = c.add(correlatorResult("https://a.example/", "a.example", "https://a.example:443/", ""))
+	if len(ready) != 2 {
+		t.Fatalf("pair released %d results, want 2", len(ready))
+	}
+	for _, r := range ready {
+		if !*r.SiblingSchemeProbed || !r.Converged {
+			t.Errorf("%s: sibling_scheme_probed=%v converged=%v, want both true", r.URL, *r.SiblingSchemeProbed, r.Converged)
+		}
+	}
+
+	// Different final URLs, or a failed sibling, don't converge
+	c.add(correlatorResult("https://b.example/", "b.example", "https://b.example/", ""))
+	ready = c.add(correlatorResult("http://b.example/", "b.example", "http://b.example/", ""))
+	if len(ready) != 2 || ready[0].Converged || ready[1].Converged || !*ready[0].SiblingSchemeProbed {
+		t.Errorf("diverged pair = %+v", ready)
+	}
+
+	// Port 8081 has no https sibling; 8080 pairs despite the 8081 result in between
+	c.add(correlatorResult("http://d.example:8080/", "d.example", "http://d.example:8080/", ""))
+	if ready := c.add(correlatorResult("http://d.example:8081/", "d.example", "http://d.example:8081/", "")); len(ready) != 1 {
+		t.Fatalf("8081 result should pass through, got %d", len(ready))
+	}
+	ready = c.add(correlatorResult("https://d.example:8080/", "d.example", "", "connection refused"))
+	if len(ready) != 2 || ready[0].Converged {
+		t.Errorf("failed sibling pair = %+v", ready)
+	}
+	if len(c.flush()) != 0 {
+		t.Error("nothing should remain held")
+	}
+}
+
+func TestSchemeCorrelator_ReleasesWhenInputExhausted(t *testing.T) {
+	urls := []string{"http://a.example/", "https://a.example/", "http://a.example:8080/"}
+	inputs := map[string]string{"http://a.example/": "a.example", "https://a.example/": "a.example", "http://a.example:8080/": "a.example"}
+
+	// Sibling result never arrives (e.g. suppressed): flush releases the held result
+	c := newSchemeCorrelator(urls, inputs, nil, parser.StandardPorts)
+	c.add(correlatorResult("http://a.example/", "a.example", "http://a.example/", ""))
+	if ready := c.flush(); len(ready) != 1 || !*ready[0].SiblingSchemeProbed || ready[0].Converged {
+		t.Errorf("flush = %+v", ready)
+	}
+
+	// The input's last expected result releases everything it still holds
+	c = newSchemeCorrelator(urls, inputs, nil, parser.StandardPorts)
+	c.pending["a.example"] = 2 // one sibling was suppressed
+	c.add(correlatorResult("https://a.example/", "a.example", "https://a.example/", ""))
+	if ready := c.add(correlatorResult("http://a.example:8080/", "a.example", "http://a.example:8080/", "")); len(ready) != 2 {
+		t.Errorf("exhausted input released %d results, want 2", len(ready))
+	}
+}
+
+// -vhost-list results count towards their IP target's input and pair up
+// with the other scheme's result for the same hostname.
+func TestSchemeCorrelator_VhostCandidates(t *testing.T) {
+	urls := []string{"http://10.0.0.1/", "https://10.0.0.1/"}
+	inputs := map[string]string{"http://10.0.0.1/": "10.0.0.1", "https://10.0.0.1/": "10.0.0.1"}
+	vhosts := map[string][]string{
+		"http://10.0.0.1/":  {"http://app.example/"},
+		"https://10.0.0.1/": {"https://app.example/"},
+	}
+	c := newSchemeCorrelator(urls, inputs, vhosts, parser.StandardPorts)
+	vhostResult := func(url, finalURL string) output.ProbeResult {
+		result := correlatorResult(url, "10.0.0.1", finalURL, "")
+		result.VhostCandidate = "app.example"
+		result.HostIP = "10.0.0.1"
+		return result
+	}
+
+	// The IP's http result stays held while vhost results arrive
+	if ready := c.add(correlatorResult("http://10.0.0.1/", "10.0.0.1", "https://10.0.0.1/", "")); len(ready) != 0 {
+		t.Fatalf("first sibling should be held, got %d results", len(ready))
+	}
+	if ready := c.add(vhostResult("http://app.example/", "https://app.example/")); len(ready) != 0 {
+		t.Fatalf("first vhost sibling should be held, got %d results", len(ready))
+	}
+	ready := c.add(correlatorResult("https://10.0.0.1/", "10.0.0.1", "https://10.0.0.1/", ""))
+	if len(ready) != 2 || !ready[0].Converged || !ready[1].Converged {
+		t.Fatalf("IP pair = %+v, want both converged", ready)
+	}
+	ready = c.add(vhostResult("https://app.example/", "https://app.example/"))
+	if len(ready) != 2 {
+		t.Fatalf("vhost pair released %d results, want 2", len(ready))
+	}
+	for _, r := range ready {
+		if !*r.SiblingSchemeProbed || !r.Converged {
+			t.Errorf("%s: sibling_scheme_probed=%v converged=%v, want both true", r.URL, *r.SiblingSchemeProbed, r.Converged)
+		}
+	}
+	if len(c.flush()) != 0 {
+		t.Error("nothing should remain held")
+	}
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/probe"
+	"probeHTTP/pkg/version"
+)
+
+// Statuses of a -doctor check.
+const (
+	doctorPass = "pass"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+	doctorSkip = "skip" // Not applicable, e.g. network checks with -doctor-target none
+)
+
+// doctorMaxClockSkew is the clock difference to the targets' Date headers
+// that -doctor warns about: certificate validity and signed requests start
+// failing around it.
+const doctorMaxClockSkew = 5 * time.Minute
+
+// doctorCheck is the outcome of one -doctor check. A critical check that
+// fails makes the scan pointless, and -doctor exit non-zero.
+type doctorCheck struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Detail   string `json:"detail"`
+}
+
+// doctorReport is the JSON form of a -doctor run.
+type doctorReport struct {
+	RunID   string        `json:"run_id"`
+	Version string        `json:"version"`
+	Targets []string      `json:"targets,omitempty"` // None with -doctor-target none
+	Checks  []doctorCheck `json:"checks"`
+	OK      bool          `json:"ok"` // No critical check failed
+}
+
+// strategyOutcome is how one TLS strategy fared against the targets.
+type strategyOutcome struct {
+	Name   string // TLS strategy and protocol, e.g. "TLS 1.3/HTTP/2"
+	Legacy bool   // TLS 1.0/1.1, which well-known endpoints no longer offer
+	OK     bool   // At least one target answered
+	Error  string // First error when no target answered
+}
+
+// writablePath is an output path -doctor tried to write to.
+type writablePath struct {
+	Flag string
+	Path string
+	Err  error
+}
+
+// runDoctor runs the -doctor checks with a prober built from cfg, writes the
+// report as JSON to stdout and, unless -silent, as text to stderr, and
+// returns the exit code.
+func runDoctor(ctx context.Context, cfg *config.Config, stdout, stderr io.Writer) int {
+	targets := cfg.DoctorTargets
+	if len(targets) == 0 {
+		targets = config.DefaultDoctorTargets
+	}
+	if len(targets) == 1 && targets[0] == config.DoctorTargetNone {
+		targets = nil
+	}
+
+	prober := probe.NewProber(cfg)
+	defer prober.Close()
+
+	var checks []doctorCheck
+	if len(targets) == 0 {
+		for _, name := range []string{"dns", "http", "tls", "http3", "clock"} {
+			checks = append(checks, doctorCheck{Name: name, Status: doctorSkip, Critical: isCriticalDoctorCheck(name), Detail: "-doctor-target none"})
+		}
+	} else {
+		checks = append(checks, doctorNetworkChecks(ctx, cfg, prober, targets)...)
+	}
+
+	want := expectedFileDescriptors(cfg)
+	limit, err := raiseFileLimit(want)
+	checks = append(checks, evaluateFileLimit(limit, want, cfg.Concurrency, err))
+	checks = append(checks, evaluateOutputPaths(checkOutputPaths(cfg)))
+
+	report := doctorReport{
+		RunID:   cfg.RunID,
+		Version: version.GetShortVersion(),
+		Targets: targets,
+		Checks:  checks,
+		OK:      doctorOK(checks),
+	}
+	if !cfg.Silent {
+		writeDoctorReport(stderr, report)
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		cfg.Logger.Error("failed to write doctor report", "error", err)
+		return exitFatal
+	}
+	if !report.OK {
+		return exitFatal
+	}
+	return exitOK
+}
+
+// doctorNetworkChecks resolves and probes targets: plainly, with each TLS
+// strategy and over HTTP/3. The probes run concurrently.
+func doctorNetworkChecks(ctx context.Context, cfg *config.Config, prober *probe.Prober, targets []string) []doctorCheck {
+	_, dnsStats := prober.PrefetchDNS(ctx, targets, time.Duration(cfg.PrefetchDNSTimeout)*time.Second)
+
+	var httpsTargets []string
+	for _, target := range targets {
+		if u, err := url.Parse(target); err == nil && u.Scheme == "https" {
+			httpsTargets = append(httpsTargets, target)
+		}
+	}
+	modernStrategies, legacyStrategies := probe.GetTLSStrategies()
+	strategies := probe.GetOrderedStrategies(true)                                         // HTTP/3 is checked on its own
+	http3 := probe.StrategyWithProtocol{Strategy: modernStrategies[0], Protocol: "HTTP/3"} // TLS 1.3
+
+	var wg sync.WaitGroup
+	results := make([]output.ProbeResult, len(targets))
+	for i, target := range targets {
+		wg.Go(func() { results[i] = prober.ProbeURL(ctx, target, target) })
+	}
+	strategyResults := make([][]output.ProbeResult, len(strategies))
+	for i, sp := range strategies {
+		strategyResults[i] = make([]output.ProbeResult, len(httpsTargets))
+		for j, target := range httpsTargets {
+			wg.Go(func() { strategyResults[i][j] = prober.ProbeStrategy(ctx, target, sp) })
+		}
+	}
+	var http3Results []output.ProbeResult
+	if !cfg.DisableHTTP3 {
+		http3Results = make([]output.ProbeResult, len(httpsTargets))
+		for j, target := range httpsTargets {
+			wg.Go(func() { http3Results[j] = prober.ProbeStrategy(ctx, target, http3) })
+		}
+	}
+	wg.Wait()
+
+	outcomes := make([]strategyOutcome, len(strategies))
+	for i, sp := range strategies {
+		outcomes[i] = strategyOutcome{Name: sp.Strategy.Name + "/" + sp.Protocol}
+		for _, legacy := range legacyStrategies {
+			outcomes[i].Legacy = outcomes[i].Legacy || legacy.Name == sp.Strategy.Name
+		}
+		for _, result := range strategyResults[i] {
+			if result.Error == "" {
+				outcomes[i].OK = true
+			} else if outcomes[i].Error == "" {
+				outcomes[i].Error = result.Error
+			}
+		}
+	}
+
+	checks := []doctorCheck{
+		evaluateDNS(dnsStats),
+		evaluateReachability(results),
+		evaluateTLSStrategies(outcomes),
+	}
+	if cfg.DisableHTTP3 {
+		checks = append(checks, doctorCheck{Name: "http3", Status: doctorSkip, Detail: "-disable-http3"})
+	} else {
+		checks = append(checks, evaluateHTTP3(http3Results))
+	}
+	return append(checks, evaluateClock(results))
+}
+
+// isCriticalDoctorCheck reports whether failing check name fails -doctor.
+// HTTP/3 falls back to HTTP/2 and clock skew rarely stops a scan, so those
+// only warn.
+func isCriticalDoctorCheck(name string) bool {
+	return name != "http3" && name != "clock"
+}
+
+// evaluateDNS checks that the targets' hostnames resolve.
+func evaluateDNS(stats probe.PrefetchStats) doctorCheck {
+	check := doctorCheck{Name: "dns", Critical: true}
+	switch {
+	case stats.Hosts == 0:
+		check.Status, check.Detail = doctorSkip, "targets are IP literals"
+	case stats.Resolved == 0:
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("none of %d hostnames resolved (%d not found, %d errors); check the resolver configuration", stats.Hosts, stats.NXDomain, stats.Errors)
+	case stats.Resolved < stats.Hosts:
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("%d of %d hostnames resolved (%d not found, %d errors)", stats.Resolved, stats.Hosts, stats.NXDomain, stats.Errors)
+	default:
+		check.Status = doctorPass
+		check.Detail = fmt.Sprintf("%d hostnames resolved in %s", stats.Hosts, stats.Duration.Round(time.Millisecond))
+	}
+	return check
+}
+
+// evaluateReachability checks that the targets answer a regular probe, with
+// TLS fallback and retries as configured. Any HTTP status counts.
+func evaluateReachability(results []output.ProbeResult) doctorCheck {
+	check := doctorCheck{Name: "http", Critical: true}
+	answered := 0
+	var firstErr string
+	for _, result := range results {
+		if result.Error == "" {
+			answered++
+		} else if firstErr == "" {
+			firstErr = fmt.Sprintf("%s: %s", result.Input, result.Error)
+		}
+	}
+	switch {
+	case answered == 0:
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("no target answered; check outbound connectivity and firewall rules (%s)", firstErr)
+	case answered < len(results):
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("%d of %d targets answered (%s)", answered, len(results), firstErr)
+	default:
+		check.Status = doctorPass
+		check.Detail = fmt.Sprintf("%d targets answered", answered)
+	}
+	return check
+}
+
+// evaluateTLSStrategies checks HTTPS with each TLS strategy. Well-known
+// endpoints don't offer TLS 1.0/1.1, so legacy strategies are listed but
+// never fail the check; a modern strategy that fails while another works
+// points at an intercepting proxy or a restricted TLS stack.
+func evaluateTLSStrategies(outcomes []strategyOutcome) doctorCheck {
+	check := doctorCheck{Name: "tls", Critical: true}
+	var ok, failed, legacy []string
+	var firstErr string
+	for _, o := range outcomes {
+		switch {
+		case o.OK:
+			ok = append(ok, o.Name)
+		case o.Legacy:
+			legacy = append(legacy, o.Name)
+		default:
+			failed = append(failed, o.Name)
+			if firstErr == "" {
+				firstErr = o.Error
+			}
+		}
+	}
+	switch {
+	case len(outcomes) == 0:
+		check.Status, check.Detail = doctorSkip, "no https:// target"
+		return check
+	case len(ok) == 0:
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("no TLS strategy completed a request (%s)", firstErr)
+		return check
+	case len(failed) > 0:
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("ok: %s; failed: %s (%s)", strings.Join(ok, ", "), strings.Join(failed, ", "), firstErr)
+	default:
+		check.Status = doctorPass
+		check.Detail = "ok: " + strings.Join(ok, ", ")
+	}
+	if len(legacy) > 0 {
+		check.Detail += "; not offered by the targets: " + strings.Join(legacy, ", ")
+	}
+	return check
+}
+
+// evaluateHTTP3 checks that QUIC over UDP/443 gets through.
+func evaluateHTTP3(results []output.ProbeResult) doctorCheck {
+	check := doctorCheck{Name: "http3"}
+	if len(results) == 0 {
+		check.Status, check.Detail = doctorSkip, "no https:// target"
+		return check
+	}
+	for _, result := range results {
+		if result.Error == "" {
+			check.Status = doctorPass
+			check.Detail = result.Input + " answered over HTTP/3"
+			return check
+		}
+	}
+	check.Status = doctorWarn
+	check.Detail = fmt.Sprintf("no target answered over HTTP/3, UDP may be blocked; each HTTPS probe waits out -http3-timeout unless -disable-http3 is set (%s)", results[0].Error)
+	return check
+}
+
+// evaluateClock compares the local clock with the targets' Date headers.
+// The smallest skew counts: one server with a wrong clock doesn't make the
+// local clock wrong.
+func evaluateClock(results []output.ProbeResult) doctorCheck {
+	check := doctorCheck{Name: "clock"}
+	var best time.Duration
+	measured := false
+	for _, result := range results {
+		if result.ClockSkewMs == nil {
+			continue
+		}
+		skew := time.Duration(*result.ClockSkewMs) * time.Millisecond
+		if !measured || skew.Abs() < best.Abs() {
+			best, measured = skew, true
+		}
+	}
+	switch {
+	case !measured:
+		check.Status, check.Detail = doctorSkip, "no target sent a Date header"
+	case best.Abs() > doctorMaxClockSkew:
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("local clock is %s off the targets' Date headers; certificate validation may fail", best.Abs().Round(time.Second))
+	default:
+		check.Status = doctorPass
+		check.Detail = fmt.Sprintf("within %s of the targets' Date headers", best.Abs().Round(time.Second))
+	}
+	return check
+}
+
+// evaluateFileLimit checks the open file limit after raiseFileLimit against
+// the descriptors -c and -max-tls-handshakes need.
+func evaluateFileLimit(limit, want uint64, concurrency int, err error) doctorCheck {
+	check := doctorCheck{Name: "fd_limit", Critical: true}
+	switch {
+	case errors.Is(err, errors.ErrUnsupported):
+		check.Status, check.Detail = doctorSkip, "not available on this platform"
+	case err != nil:
+		check.Status, check.Detail = doctorWarn, fmt.Sprintf("could not read the open file limit: %v", err)
+	case limit < uint64(concurrency):
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("open file limit %d is below -c %d; raise it (ulimit -n) or lower -c", limit, concurrency)
+	case limit < want:
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("open file limit %d is below the expected %d; lower -c or -max-tls-handshakes, or raise the limit (ulimit -n)", limit, want)
+	default:
+		check.Status = doctorPass
+		check.Detail = fmt.Sprintf("open file limit %d covers the expected %d", limit, want)
+	}
+	return check
+}
+
+// evaluateOutputPaths checks that every configured output path is writable.
+func evaluateOutputPaths(paths []writablePath) doctorCheck {
+	check := doctorCheck{Name: "output", Critical: true}
+	if len(paths) == 0 {
+		check.Status, check.Detail = doctorPass, "results go to stdout"
+		return check
+	}
+	var ok, failed []string
+	for _, p := range paths {
+		if p.Err != nil {
+			failed = append(failed, fmt.Sprintf("-%s %s: %v", p.Flag, p.Path, p.Err))
+		} else {
+			ok = append(ok, p.Path)
+		}
+	}
+	if len(failed) > 0 {
+		check.Status, check.Detail = doctorFail, strings.Join(failed, "; ")
+	} else {
+		check.Status, check.Detail = doctorPass, "writable: "+strings.Join(ok, ", ")
+	}
+	return check
+}
+
+// checkOutputPaths tries to write where the configured outputs go, without
+// creating or truncating them.
+func checkOutputPaths(cfg *config.Config) []writablePath {
+	var paths []writablePath
+	add := func(flag, path string, dir bool) {
+		if path == "" {
+			return
+		}
+		target := path
+		if !dir {
+			target = filepath.Dir(path)
+		}
+		paths = append(paths, writablePath{Flag: flag, Path: path, Err: checkWritableDir(target)})
+	}
+	add("o", cfg.OutputFile, false)
+	if cfg.StoreResponse {
+		add("srd", cfg.StoreResponseDir, true)
+	}
+	add("manifest", cfg.Manifest, false)
+	add("host-profiles", cfg.HostProfiles, false)
+	add("openmetrics", cfg.OpenMetrics, false)
+	add("cert-report", cfg.CertReport, false)
+	add("alive-output", cfg.AliveOutput, false)
+	add("sqlite", cfg.SQLiteFile, false)
+	return paths
+}
+
+// checkWritableDir creates and removes a file in dir or, when dir doesn't
+// exist yet, in its nearest existing parent, where the run would create it.
+func checkWritableDir(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if !errors.Is(err, fs.ErrNotExist) || parent == dir {
+			return err
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".probehttp-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// doctorOK reports whether no critical check failed.
+func doctorOK(checks []doctorCheck) bool {
+	for _, check := range checks {
+		if check.Critical && check.Status == doctorFail {
+			return false
+		}
+	}
+	return true
+}
+
+// writeDoctorReport prints the text form of report.
+func writeDoctorReport(w io.Writer, report doctorReport) {
+	fmt.Fprintf(w, "probeHTTP doctor (%s)\n", report.Version)
+	if len(report.Targets) > 0 {
+		fmt.Fprintf(w, "  Targets: %s\n", strings.Join(report.Targets, ", "))
+	}
+	var failed []string
+	for _, check := range report.Checks {
+		fmt.Fprintf(w, "  %-4s  %-8s  %s\n", strings.ToUpper(check.Status), check.Name, check.Detail)
+		if check.Critical && check.Status == doctorFail {
+			failed = append(failed, check.Name)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(w, "Critical checks failed: %s\n", strings.Join(failed, ", "))
+	} else {
+		fmt.Fprintln(w, "No critical check failed")
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/probe"
+)
+
+func skewMs(d time.Duration) *int64 {
+	ms := d.Milliseconds()
+	return &ms
+}
+
+func TestEvaluateDNS(t *testing.T) {
+	tests := []struct {
+		stats probe.PrefetchStats
+		want  string
+	}{
+		{probe.PrefetchStats{Hosts: 2, Resolved: 2}, doctorPass},
+		{probe.PrefetchStats{Hosts: 2, Resolved: 1, NXDomain: 1}, doctorWarn},
+		{probe.PrefetchStats{Hosts: 2, Errors: 2}, doctorFail},
+		{probe.PrefetchStats{IPLiterals: 1}, doctorSkip},
+	}
+	for _, tt := range tests {
+		if got := evaluateDNS(tt.stats); got.Status != tt.want || !got.Critical {
+			t.Errorf("evaluateDNS(%+v) = %+v, want critical %s", tt.stats, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateReachability(t *testing.T) {
+	ok := output.ProbeResult{Input: "https://a.example/", StatusCode: 403}
+	refused := output.ProbeResult{Input: "https://b.example/", Error: "connection refused"}
+	tests := []struct {
+		results []output.ProbeResult
+		want    string
+	}{
+		{[]output.ProbeResult{ok, ok}, doctorPass},
+		{[]output.ProbeResult{ok, refused}, doctorWarn},
+		{[]output.ProbeResult{refused, refused}, doctorFail},
+	}
+	for _, tt := range tests {
+		got := evaluateReachability(tt.results)
+		if got.Status != tt.want {
+			t.Errorf("%d results: %+v, want %s", len(tt.results), got, tt.want)
+		}
+		if tt.want != doctorPass && !strings.Contains(got.Detail, "b.example/: connection refused") {
+			t.Errorf("detail %q does not name the first error", got.Detail)
+		}
+	}
+}
+
+func TestEvaluateTLSStrategies(t *testing.T) {
+	modern := strategyOutcome{Name: "TLS 1.3/HTTP/2", OK: true}
+	intercepted := strategyOutcome{Name: "TLS 1.2 Secure/HTTP/2", Error: "x509: certificate signed by unknown authority"}
+	legacy := strategyOutcome{Name: "TLS 1.0/HTTP/1.1", Legacy: true, Error: "protocol version not supported"}
+
+	got := evaluateTLSStrategies([]strategyOutcome{modern, legacy})
+	if got.Status != doctorPass || !strings.Contains(got.Detail, "not offered by the targets: TLS 1.0/HTTP/1.1") {
+		t.Errorf("modern ok, legacy refused: %+v, want pass listing the legacy strategy", got)
+	}
+	got = evaluateTLSStrategies([]strategyOutcome{modern, intercepted, legacy})
+	if got.Status != doctorWarn || !strings.Contains(got.Detail, "unknown authority") {
+		t.Errorf("one modern strategy failing: %+v, want warn with its error", got)
+	}
+	got = evaluateTLSStrategies([]strategyOutcome{intercepted, legacy})
+	if got.Status != doctorFail || !got.Critical {
+		t.Errorf("no strategy working: %+v, want a critical fail", got)
+	}
+	if got := evaluateTLSStrategies(nil); got.Status != doctorSkip {
+		t.Errorf("no https target: %+v, want skip", got)
+	}
+}
+
+func TestEvaluateHTTP3(t *testing.T) {
+	timeout := output.ProbeResult{Input: "https://a.example/", Error: "timeout: no recent network activity"}
+	answered := output.ProbeResult{Input: "https://b.example/", StatusCode: 200, Protocol: "HTTP/3"}
+	if got := evaluateHTTP3([]output.ProbeResult{timeout, answered}); got.Status != doctorPass {
+		t.Errorf("one target over HTTP/3: %+v, want pass", got)
+	}
+	got := evaluateHTTP3([]output.ProbeResult{timeout})
+	if got.Status != doctorWarn || got.Critical || !strings.Contains(got.Detail, "-disable-http3") {
+		t.Errorf("UDP blocked: %+v, want a non-critical warn suggesting -disable-http3", got)
+	}
+}
+
+func TestEvaluateClock(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []output.ProbeResult
+		want    string
+	}{
+		{"in sync", []output.ProbeResult{{ClockSkewMs: skewMs(2 * time.Second)}}, doctorPass},
+		{"local clock off", []output.ProbeResult{{ClockSkewMs: skewMs(-10 * time.Minute)}, {ClockSkewMs: skewMs(9 * time.Minute)}}, doctorWarn},
+		{"one server off", []output.ProbeResult{{ClockSkewMs: skewMs(time.Hour)}, {ClockSkewMs: skewMs(-time.Second)}}, doctorPass},
+		{"no Date header", []output.ProbeResult{{}, {Error: "refused"}}, doctorSkip},
+	}
+	for _, tt := range tests {
+		if got := evaluateClock(tt.results); got.Status != tt.want || got.Critical {
+			t.Errorf("%s: %+v, want non-critical %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateFileLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit uint64
+		err   error
+		want  string
+	}{
+		{"plenty", 65536, nil, doctorPass},
+		{"below expected", 100, nil, doctorWarn},
+		{"below concurrency", 10, nil, doctorFail},
+		{"unsupported", 0, errors.ErrUnsupported, doctorSkip},
+		{"unreadable", 0, errors.New("operation not permitted"), doctorWarn},
+	}
+	for _, tt := range tests {
+		if got := evaluateFileLimit(tt.limit, 124, 20, tt.err); got.Status != tt.want {
+			t.Errorf("%s: %+v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOutputPaths(t *testing.T) {
+	dir := t.TempDir()
+	notADir := filepath.Join(dir, "file")
+	os.WriteFile(notADir, nil, 0644)
+
+	cfg := config.New()
+	cfg.OutputFile = filepath.Join(dir, "results.jsonl")
+	cfg.StoreResponse = true
+	cfg.StoreResponseDir = filepath.Join(dir, "not", "created", "yet")
+	cfg.Manifest = filepath.Join(notADir, "manifest.json")
+
+	paths := checkOutputPaths(cfg)
+	if len(paths) != 3 || paths[0].Err != nil || paths[1].Err != nil || paths[2].Err == nil {
+		t.Fatalf("checkOutputPaths = %+v; want -o and -srd writable, -manifest not", paths)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "not")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("checking -srd created it: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("probe files left behind: %v", entries)
+	}
+
+	got := evaluateOutputPaths(paths)
+	if got.Status != doctorFail || !strings.Contains(got.Detail, "-manifest") {
+		t.Errorf("evaluateOutputPaths = %+v, want a fail naming -manifest", got)
+	}
+	if got := evaluateOutputPaths(nil); got.Status != doctorPass {
+		t.Errorf("no output paths: %+v, want pass", got)
+	}
+}
+
+func TestRunDoctor_NoTargets(t *testing.T) {
+	cfg := config.New()
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.RunID = "doctor-1"
+	cfg.DoctorTargets = []string{config.DoctorTargetNone}
+	cfg.OutputFile = filepath.Join(t.TempDir(), "results.jsonl")
+
+	var stdout, stderr bytes.Buffer
+	if code := runDoctor(context.Background(), cfg, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d, report:\n%s", code, stderr.String())
+	}
+	var report doctorReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("JSON report: %v\n%s", err, stdout.String())
+	}
+	if !report.OK || report.RunID != "doctor-1" || len(report.Checks) != 7 || report.Checks[0].Status != doctorSkip {
+		t.Errorf("report = %+v", report)
+	}
+	if !strings.Contains(stderr.String(), "SKIP  dns") || !strings.Contains(stderr.String(), "No critical check failed") {
+		t.Errorf("text report:\n%s", stderr.String())
+	}
+
+	// An unwritable output path is critical
+	cfg.OutputFile = filepath.Join(cfg.OutputFile, "nested.jsonl")
+	os.WriteFile(filepath.Dir(cfg.OutputFile), nil, 0644)
+	cfg.Silent = true
+	stdout.Reset()
+	stderr.Reset()
+	if code := runDoctor(context.Background(), cfg, &stdout, &stderr); code != exitFatal {
+		t.Errorf("exit code %d with an unwritable -o, want %d", code, exitFatal)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("text report with -silent:\n%s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"ok": false`) {
+		t.Errorf("JSON report:\n%s", stdout.String())
+	}
+}
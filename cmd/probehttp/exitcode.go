@@ -0,0 +1,28 @@
+package main
+
+import "probeHTTP/internal/config"
+
+// Process exit codes returned by run.
+const (
+	exitOK            = 0
+	exitFatal         = 1 // startup or configuration error
+	exitNoneReachable = 2 // run completed but no target answered with an -alive-codes status
+	exitInterrupted   = 3 // cancelled by SIGINT/SIGTERM before completion
+)
+
+// exitCode maps the outcome of a run to the process exit code under policy.
+// alive is the number of live results (output.IsAlive). The always policy
+// keeps the historical behavior of exiting 0 whenever the run gets this far.
+func exitCode(policy string, alive int, interrupted bool) int {
+	if policy != config.ExitCodePolicyOutcome {
+		return exitOK
+	}
+	switch {
+	case interrupted:
+		return exitInterrupted
+	case alive == 0:
+		return exitNoneReachable
+	default:
+		return exitOK
+	}
+}
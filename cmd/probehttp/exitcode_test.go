@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		alive       int
+		interrupted bool
+		want        int
+	}{
+		{"always with results", config.ExitCodePolicyAlways, 3, false, exitOK},
+		{"always with nothing alive", config.ExitCodePolicyAlways, 0, false, exitOK},
+		{"always interrupted", config.ExitCodePolicyAlways, 0, true, exitOK},
+		{"outcome with results", config.ExitCodePolicyOutcome, 1, false, exitOK},
+		{"outcome with nothing alive", config.ExitCodePolicyOutcome, 0, false, exitNoneReachable},
+		{"outcome interrupted", config.ExitCodePolicyOutcome, 5, true, exitInterrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.policy, tt.alive, tt.interrupted); got != tt.want {
+				t.Errorf("exitCode(%q, %d, %v) = %d, want %d", tt.policy, tt.alive, tt.interrupted, got, tt.want)
+			}
+		})
+	}
+}
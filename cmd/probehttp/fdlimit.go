@@ -0,0 +1,34 @@
+package main
+
+import (
+	"log/slog"
+
+	"probeHTTP/internal/config"
+)
+
+// fdHeadroom covers descriptors held outside of in-flight probes: idle
+// pooled connections, DNS sockets, log, output and response storage files.
+const fdHeadroom = 64
+
+// expectedFileDescriptors estimates the descriptors a run needs: one per
+// worker connection, one per concurrent TLS attempt and some headroom.
+func expectedFileDescriptors(cfg *config.Config) uint64 {
+	return uint64(cfg.Concurrency) + uint64(cfg.MaxTLSHandshakes) + fdHeadroom
+}
+
+// checkFileLimit raises the open file limit to the expected usage where the
+// hard limit allows it, and warns when the soft limit stays below it.
+func checkFileLimit(cfg *config.Config, logger *slog.Logger) {
+	want := expectedFileDescriptors(cfg)
+	limit, err := raiseFileLimit(want)
+	if err != nil {
+		logger.Debug("could not check open file limit", "error", err)
+		return
+	}
+	if limit < want {
+		logger.Warn("open file limit is below expected usage; lower -c or -max-tls-handshakes, or raise the limit (ulimit -n)",
+			"limit", limit,
+			"expected", want,
+		)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package main
+
+import "errors"
+
+// raiseFileLimit is not supported on this platform.
+func raiseFileLimit(want uint64) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
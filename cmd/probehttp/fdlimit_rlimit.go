@@ -0,0 +1,29 @@
+//go:build linux || darwin
+
+package main
+
+import "syscall"
+
+// raiseFileLimit raises the soft RLIMIT_NOFILE towards want, capped at the
+// hard limit, and returns the resulting soft limit.
+func raiseFileLimit(want uint64) (uint64, error) {
+	var lim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &lim); err != nil {
+		return 0, err
+	}
+	if uint64(lim.Cur) >= want {
+		return uint64(lim.Cur), nil
+	}
+
+	current := uint64(lim.Cur)
+	target := min(want, uint64(lim.Max))
+	if target <= current {
+		return current, nil
+	}
+	lim.Cur = target
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &lim); err != nil {
+		// e.g. macOS rejects values above kern.maxfilesperproc
+		return current, nil
+	}
+	return target, nil
+}
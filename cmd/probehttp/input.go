@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/parser"
+)
+
+// remoteInput describes a target list fetched over HTTP(S), for provenance.
+type remoteInput struct {
+	URL          string
+	ETag         string
+	LastModified string
+}
+
+// isRemoteInput reports whether an -i value is an http(s) URL rather than a path.
+func isRemoteInput(input string) bool {
+	lower := strings.ToLower(input)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// remoteInputClient returns the plain client for fetching a remote target
+// list. Like the probing client it gives up after -timeout seconds, which
+// covers reading the whole list.
+func remoteInputClient(timeout int) *http.Client {
+	return &http.Client{Timeout: time.Duration(timeout) * time.Second}
+}
+
+// fetchRemoteInput GETs a target list. authHeader is an optional "Name: value"
+// header. The returned body fails with an error once more than maxSize bytes
+// have been read, so oversized inputs abort instead of being silently cut.
+func fetchRemoteInput(ctx context.Context, client *http.Client, rawURL, authHeader string, maxSize int64) (io.ReadCloser, remoteInput, error) {
+	info := remoteInput{URL: rawURL}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, info, fmt.Errorf("invalid input URL: %v", err)
+	}
+	if authHeader != "" {
+		name, value, _ := strings.Cut(authHeader, ":")
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		req.Header.Set(name, strings.TrimSpace(value))
+		client = dropHeaderOffHost(client, name)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, info, fmt.Errorf("failed to fetch input: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, info, fmt.Errorf("failed to fetch input: HTTP %d", resp.StatusCode)
+	}
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		resp.Body.Close()
+		return nil, info, fmt.Errorf("input is %d bytes, exceeds -input-max-size of %d", resp.ContentLength, maxSize)
+	}
+
+	info.ETag = resp.Header.Get("ETag")
+	info.LastModified = resp.Header.Get("Last-Modified")
+
+	body := resp.Body
+	if maxSize > 0 {
+		body = &maxSizeReader{ReadCloser: resp.Body, remaining: maxSize, max: maxSize}
+	}
+	return body, info, nil
+}
+
+// dropHeaderOffHost returns a copy of client that removes header from
+// redirects to a host other than the one first requested, so -input-auth-header
+// credentials only go to the list's own host. Other redirect checks are those
+// of client, or the default limit of 10 redirects.
+func dropHeaderOffHost(client *http.Client, header string) *http.Client {
+	c := *client
+	check := client.CheckRedirect
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			req.Header.Del(header)
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &c
+}
+
+// maxSizeReader errors once more than max bytes are read.
+type maxSizeReader struct {
+	io.ReadCloser
+	remaining int64
+	max       int64
+}
+
+func (r *maxSizeReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, fmt.Errorf("input exceeds -input-max-size of %d bytes", r.max)
+	}
+	// Read one byte past the limit so overflow is detected rather than truncated
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, fmt.Errorf("input exceeds -input-max-size of %d bytes", r.max)
+	}
+	return n, err
+}
+
+// readScanTargets parses port scanner output in the given -input-format.
+func readScanTargets(lines []string, format string) ([]parser.ScanTarget, parser.ScanSkips) {
+	if format == config.InputFormatMasscanJSON {
+		return parser.ParseMasscanJSON(lines)
+	}
+	return parser.ParseNmapGrepable(lines)
+}
+
+// inputProbes returns how many probes an input line is planned to expand to:
+// one per scheme and port, times its -path-variants, and for an IP target
+// one more per -vhost-list hostname on each of those URLs. Redirect hops,
+// retries and -methods-check/-cors-check requests are not probes.
+func inputProbes(cfg *config.Config, inputURL string, defaults parser.DefaultPorts) int {
+	n := parser.CountExpansions(inputURL, cfg.AllSchemes, cfg.IgnorePorts, cfg.CustomPorts, defaults)
+	if cfg.PathVariants {
+		n *= 1 + parser.CountPathVariants(inputURL)
+	}
+	host := strings.Trim(parser.ParseInputURL(inputURL).Host, "[]")
+	if len(cfg.VhostHosts) > 0 && net.ParseIP(host) != nil {
+		n *= 1 + len(cfg.VhostHosts)
+	}
+	return n
+}
+
+// estimateProbes returns the probes input lines expand to by inputProbes.
+// It is an upper bound: invalid, excluded, out-of-scope and duplicate
+// targets, which are dropped later, are counted too.
+func estimateProbes(cfg *config.Config, lines []string, defaults parser.DefaultPorts) int {
+	total := 0
+	for _, line := range lines {
+		inputURL, _ := parser.SplitAnnotation(line, cfg.MetaDelim)
+		if inputURL == "" {
+			continue
+		}
+		target, hint, _ := parser.ParseInputLine(inputURL, cfg.InputFormat == config.InputFormatHints)
+		total += inputProbes(cfg, parser.WithSchemeHint(target, hint), defaults)
+	}
+	return total
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/parser"
+)
+
+func TestIsRemoteInput(t *testing.T) {
+	for input, want := range map[string]bool{
+		"https://internal.example/scope/latest.txt": true,
+		"HTTP://internal.example/scope.txt":         true,
+		"targets.txt":                               false,
+		"/tmp/http-targets.txt":                     false,
+		"":                                          false,
+	} {
+		if got := isRemoteInput(input); got != want {
+			t.Errorf("isRemoteInput(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestFetchRemoteInput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("ETag", `"v42"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte("https://a.example\n# comment\nhttps://b.example\n"))
+	}))
+	defer server.Close()
+
+	body, info, err := fetchRemoteInput(context.Background(), http.DefaultClient, server.URL, "Authorization: Bearer secret", 1024)
+	if err != nil {
+		t.Fatalf("fetchRemoteInput: %v", err)
+	}
+	defer body.Close()
+
+	urls, err := readURLs(body)
+	if err != nil {
+		t.Fatalf("readURLs: %v", err)
+	}
+	if strings.Join(urls, ",") != "https://a.example,https://b.example" {
+		t.Errorf("urls = %v", urls)
+	}
+	if info.URL != server.URL || info.ETag != `"v42"` || info.LastModified != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("provenance = %+v", info)
+	}
+
+	if _, _, err := fetchRemoteInput(context.Background(), http.DefaultClient, server.URL, "", 1024); err == nil {
+		t.Error("expected error for non-2xx response")
+	}
+}
+
+func TestFetchRemoteInput_MaxSize(t *testing.T) {
+	list := strings.Repeat("https://target.example\n", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/streamed" {
+			// Flushing forces chunked encoding, so no Content-Length up front
+			w.Write([]byte(list[:10]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(list[10:]))
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(list)))
+		w.Write([]byte(list))
+	}))
+	defer server.Close()
+
+	if _, _, err := fetchRemoteInput(context.Background(), http.DefaultClient, server.URL, "", 100); err == nil {
+		t.Error("expected error when Content-Length exceeds the cap")
+	}
+
+	body, _, err := fetchRemoteInput(context.Background(), http.DefaultClient, server.URL+"/streamed", "", 100)
+	if err != nil {
+		t.Fatalf("fetchRemoteInput: %v", err)
+	}
+	defer body.Close()
+	if _, err := readURLs(body); err == nil {
+		t.Error("expected read error when streamed body exceeds the cap")
+	}
+
+	body, _, err = fetchRemoteInput(context.Background(), http.DefaultClient, server.URL+"/streamed", "", int64(len(list)))
+	if err != nil {
+		t.Fatalf("fetchRemoteInput: %v", err)
+	}
+	defer body.Close()
+	if urls, err := readURLs(body); err != nil || len(urls) != 100 {
+		t.Errorf("body at exactly the cap: %d urls, err %v; want 100, nil", len(urls), err)
+	}
+}
+
+func TestFetchRemoteInput_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("https://a.example\n"))
+		w.(http.Flusher).Flush()
+		<-release // never finish the list
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	body, _, err := fetchRemoteInput(context.Background(), remoteInputClient(1), server.URL, "", 1024)
+	if err == nil {
+		_, err = readURLs(body)
+		body.Close()
+	}
+	if err == nil {
+		t.Fatal("expected the stalled list to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up after %v, want about the 1s -timeout", elapsed)
+	}
+}
+
+func TestFetchRemoteInput_AuthHeaderStaysOnHost(t *testing.T) {
+	var leaked []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("X-Api-Key"); key != "" {
+			leaked = append(leaked, key)
+		}
+		w.Write([]byte("https://a.example\n"))
+	}))
+	defer other.Close()
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path+"="+r.Header.Get("X-Api-Key"))
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/list", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, other.URL+"/list", http.StatusFound)
+		default:
+			w.Write([]byte("https://a.example\n"))
+		}
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/moved", "/away"} {
+		body, _, err := fetchRemoteInput(context.Background(), remoteInputClient(5), server.URL+path, "X-API-Key: secret", 1024)
+		if err != nil {
+			t.Fatalf("%s: fetchRemoteInput: %v", path, err)
+		}
+		body.Close()
+	}
+	if strings.Join(seen, ",") != "/moved=secret,/list=secret,/away=secret" {
+		t.Errorf("requests to the list's host = %v, want the header on each", seen)
+	}
+	if len(leaked) != 0 {
+		t.Errorf("header sent to another host: %v", leaked)
+	}
+}
+
+func TestEstimateProbes(t *testing.T) {
+	defaults := parser.StandardPorts
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		lines     []string
+		want      int
+	}{
+		{"bare host, both schemes", func(cfg *config.Config) {}, []string{"example.com"}, 2},
+		{"scheme and port given", func(cfg *config.Config) {}, []string{"https://example.com:8443"}, 1},
+		{"inputs differ", func(cfg *config.Config) {}, []string{"example.com", "https://example.org", "http://example.net"}, 4},
+		{"empty lines and annotations", func(cfg *config.Config) { cfg.MetaDelim = "|" }, []string{"", "| only a note", "https://example.com | team=web"}, 1},
+		{"custom ports", func(cfg *config.Config) { cfg.CustomPorts = "80,443,8080" }, []string{"example.com"}, 6},
+		{"path variants", func(cfg *config.Config) { cfg.PathVariants = true }, []string{"https://example.com/admin"}, 1 + len(parser.PathVariants("/admin"))},
+		{"vhosts on IP targets only", func(cfg *config.Config) { cfg.VhostHosts = []string{"a.example", "b.example"} },
+			[]string{"https://192.0.2.1", "https://[2001:db8::1]", "https://example.com"}, 3 + 3 + 1},
+		{"scheme hint", func(cfg *config.Config) { cfg.InputFormat = config.InputFormatHints }, []string{"example.com:8443 [https]"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.New()
+			tt.configure(cfg)
+			if got := estimateProbes(cfg, tt.lines, defaults); got != tt.want {
+				t.Errorf("estimateProbes(%q) = %d, want %d", tt.lines, got, tt.want)
+			}
+		})
+	}
+}
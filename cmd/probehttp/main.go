@@ -3,35 +3,78 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"maps"
+	"net/url"
 	"os"
 	"os/signal"
+	"runtime"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	"golang.org/x/term"
 
 	"probeHTTP/internal/config"
+	"probeHTTP/internal/deadlist"
+	"probeHTTP/internal/output"
 	"probeHTTP/internal/parser"
 	"probeHTTP/internal/probe"
+	"probeHTTP/pkg/version"
 )
 
 func main() {
+	if code := run(); code != exitOK {
+		os.Exit(code)
+	}
+}
+
+// run performs the scan and returns the process exit code. Deferred cleanup
+// (output files, routes, debug log) completes before main exits with it.
+func run() int {
+	startTime := time.Now()
+
 	// Parse configuration
 	cfg, err := config.ParseFlags()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return exitFatal
 	}
 	defer cfg.Close() // Clean up debug log file
 
+	// run_id ties together every artifact of this run: results, manifest,
+	// storage index, sqlite rows and the debug log. A generated ID differs on
+	// every rerun, so -no-timestamp keeps it out of the results
+	generatedRunID := cfg.RunID == ""
+	if generatedRunID {
+		cfg.RunID = uuid.NewString()
+	}
+	if cfg.DebugLogger != nil {
+		cfg.DebugLogger.Info("run started",
+			"run_id", cfg.RunID,
+			"version", version.GetShortVersion(),
+			"start_time", startTime.Format(time.RFC3339Nano),
+		)
+	}
+
+	// -doctor checks the environment instead of reading any input
+	if cfg.Doctor {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runDoctor(ctx, cfg, os.Stdout, os.Stderr)
+	}
+
 	// If no arguments provided and nothing is piped to stdin, show help
 	if flag.NFlag() == 0 && cfg.InputFile == "" && !config.HasPipedData() {
 		flag.Usage()
-		os.Exit(0)
+		return exitOK
 	}
 
 	// Set up context with cancellation support for graceful shutdown
@@ -54,20 +97,70 @@ func main() {
 				"path", cfg.StoreResponseDir,
 				"error", err,
 			)
-			os.Exit(1)
+			return exitFatal
 		}
 		cfg.Logger.Info("response storage enabled",
 			"directory", cfg.StoreResponseDir,
 		)
 	}
 
+	// Make sure concurrent connections won't run out of file descriptors
+	checkFileLimit(cfg, cfg.Logger)
+
+	// Create prober; goroutines running before it form the leak-check baseline
+	baselineGoroutines := runtime.NumGoroutine()
+	prober := probe.NewProber(cfg)
+	defer prober.Close() // Clean up HTTP clients and transports
+	if cfg.PinFile != "" {
+		pins, err := probe.LoadPinFile(cfg.PinFile)
+		if err != nil {
+			cfg.Logger.Error("failed to load pin file", "path", cfg.PinFile, "error", err)
+			return exitFatal
+		}
+		prober.SetPins(pins)
+	}
+	if cfg.HealthInterval > 0 {
+		go prober.MonitorHealth(ctx, cfg.HealthInterval)
+	}
+	var deadHosts *deadlist.List
+	if cfg.DeadlistFile != "" {
+		deadHosts, err = deadlist.Load(cfg.DeadlistFile)
+		if err != nil {
+			cfg.Logger.Error("failed to load deadlist", "path", cfg.DeadlistFile, "error", err)
+			return exitFatal
+		}
+	}
+
 	// Get input reader
 	var inputReader io.Reader
-	if cfg.InputFile != "" {
+	var remote *remoteInput
+	if isRemoteInput(cfg.InputFile) {
+		// Scope files are fetched with a plain client so probing flags
+		// (insecure, TLS, ...) don't affect them unless asked. The
+		// plain client honors HTTP(S)_PROXY from the environment, the probe
+		// client doesn't; both give up after -timeout
+		client := remoteInputClient(cfg.Timeout)
+		if cfg.InputUseProbeClient {
+			client = prober.HTTPClient()
+		}
+		body, info, err := fetchRemoteInput(ctx, client, cfg.InputFile, cfg.InputAuthHeader, cfg.InputMaxSize)
+		if err != nil {
+			cfg.Logger.Error("failed to fetch input URL", "url", cfg.InputFile, "error", err)
+			return exitFatal
+		}
+		defer body.Close()
+		remote = &info
+		cfg.Logger.Info("fetched remote input",
+			"url", info.URL,
+			"etag", info.ETag,
+			"last_modified", info.LastModified,
+		)
+		inputReader = body
+	} else if cfg.InputFile != "" {
 		file, err := os.Open(cfg.InputFile)
 		if err != nil {
 			cfg.Logger.Error("failed to open input file", "file", cfg.InputFile, "error", err)
-			os.Exit(1)
+			return exitFatal
 		}
 		defer file.Close()
 		inputReader = file
@@ -81,7 +174,7 @@ func main() {
 		file, err := os.Create(cfg.OutputFile)
 		if err != nil {
 			cfg.Logger.Error("failed to create output file", "file", cfg.OutputFile, "error", err)
-			os.Exit(1)
+			return exitFatal
 		}
 		defer file.Close()
 		outputWriter = file
@@ -89,88 +182,341 @@ func main() {
 		outputWriter = os.Stdout
 	}
 
+	// Open per-route output files; invalid route expressions are rejected here
+	var router *output.Router
+	if len(cfg.Routes) > 0 {
+		router, err = output.NewRouter(cfg.Routes)
+		if err != nil {
+			cfg.Logger.Error("failed to set up output routes", "error", err)
+			return exitFatal
+		}
+		defer func() {
+			if err := router.Close(); err != nil {
+				cfg.Logger.Error("failed to close route output", "error", err)
+			}
+		}()
+	}
+
+	// -sqlite writes every result next to the JSON output. Results still
+	// drain after SIGINT, so Close below commits everything written.
+	var sqliteOut *output.SQLiteWriter
+	if cfg.SQLiteFile != "" {
+		runID := cfg.SQLiteRunID
+		if runID == "" {
+			runID = cfg.RunID
+		}
+		sqliteOut, err = output.NewSQLiteWriter(cfg.SQLiteFile, runID, cfg.SQLiteBatch)
+		if err != nil {
+			cfg.Logger.Error("failed to open sqlite output", "file", cfg.SQLiteFile, "error", err)
+			return exitFatal
+		}
+		defer func() {
+			if err := sqliteOut.Close(); err != nil {
+				cfg.Logger.Error("failed to close sqlite output", "file", cfg.SQLiteFile, "error", err)
+			}
+		}()
+		cfg.Logger.Info("writing results to sqlite", "file", cfg.SQLiteFile, "run_id", runID)
+	}
+
+	// Hash input as it is read so the manifest can record its checksum
+	inputHash := sha256.New()
+	inputReader = io.TeeReader(inputReader, inputHash)
+
 	// Read URLs from input
 	urls, err := readURLs(inputReader)
 	if err != nil {
 		cfg.Logger.Error("failed to read input URLs", "error", err)
-		os.Exit(1)
+		return exitFatal
 	}
 	cfg.Logger.Info("loaded URLs", "count", len(urls))
 
 	// Expand URLs based on scheme and port configuration
 	expandedURLs := []string{}
 	originalInputMap := make(map[string]string)
+	expansionByURL := make(map[string]*output.Expansion)
+	variantOfByURL := make(map[string]string) // -path-variants: variant URL -> base URL
+	defaultPorts := parser.DefaultPorts{HTTP: cfg.DefaultHTTPPort, HTTPS: cfg.DefaultHTTPSPort}
+	invalidCount := 0
+	excludedCount := 0
+	outOfScopeCount := 0 // -scope-file refusals
+	// -use-url-credentials: userinfo of each input, keyed by expanded URL
+	credentialsByURL := make(map[string]*url.Userinfo)
+	credentialInputs := 0
+	// Passthrough annotations keyed by target (first annotation wins)
+	metaByInput := make(map[string]*output.Meta)
+	// Running count of probes the inputs expand to (see inputProbes), checked
+	// against -max-total-probes before each input is materialized
+	plannedProbes := 0
+	budgetWarned := false
+
+	// Port scanner output names open ports, so each one is probed as found
+	// instead of going through the line-based expansion below
+	inputLines := urls
+	if cfg.InputFormat == config.InputFormatMasscanJSON || cfg.InputFormat == config.InputFormatNmapGrepable {
+		targets, skips := readScanTargets(urls, cfg.InputFormat)
+		cfg.Logger.Info("loaded scan targets", "format", cfg.InputFormat, "targets", len(targets), "skipped", skips.Total())
+		for _, reason := range slices.Sorted(maps.Keys(skips)) {
+			cfg.Logger.Info("skipped scan records", "reason", reason, "count", skips[reason])
+		}
+		for _, target := range targets {
+			inputURL := target.HostPort()
+			if err := parser.ValidateURL(inputURL, cfg.AllowPrivateIPs); err != nil {
+				cfg.Logger.Warn("skipping invalid URL", "url", inputURL, "error", err)
+				invalidCount++
+				continue
+			}
+			for _, e := range parser.ExpandScanTarget(target, cfg.AllSchemes, defaultPorts) {
+				if cfg.ExcludeMatcher.Excluded(e.URL) {
+					excludedCount++
+					continue
+				}
+				if !cfg.Scope.Allows(e.URL) {
+					cfg.Logger.Warn("refusing out-of-scope input", "input", inputURL, "url", e.URL, "error", "out_of_scope")
+					outOfScopeCount++
+					continue
+				}
+				expandedURLs = append(expandedURLs, e.URL)
+				// The first target to plan a URL keeps it
+				if _, planned := originalInputMap[e.URL]; !planned {
+					originalInputMap[e.URL] = inputURL
+					expansionByURL[e.URL] = &e.Expansion
+				}
+			}
+		}
+		inputLines = nil
+	}
+
+	for i, inputLine := range inputLines {
+		// Split off any passthrough annotation so it never reaches URL parsing
+		inputURL, annotation := parser.SplitAnnotation(inputLine, cfg.MetaDelim)
+		if inputURL == "" {
+			continue
+		}
+
+		// Keep only the target column, plus its scheme hint with -input-format hints
+		target, hint, err := parser.ParseInputLine(inputURL, cfg.InputFormat == config.InputFormatHints)
+		if err != nil {
+			cfg.Logger.Warn("ignoring malformed scheme hint", "line", parser.StripUserinfo(inputURL), "error", err)
+		}
+		if cfg.NormalizePath {
+			target = parser.CollapsePathSlashes(target)
+		}
+		// Credentials never reach probe URLs, logs or output
+		user := parser.ParseInputURL(target).User
+		if user != nil {
+			target = parser.StripUserinfo(target)
+			credentialInputs++
+		}
+		inputURL = parser.WithSchemeHint(target, hint)
+		if annotation != "" {
+			if _, exists := metaByInput[inputURL]; !exists {
+				meta := &output.Meta{Raw: annotation}
+				if cfg.MetaKV {
+					if fields, ok := parser.ParseAnnotationKV(annotation); ok {
+						meta.Fields = fields
+					}
+				}
+				metaByInput[inputURL] = meta
+			}
+		}
 
-	for _, inputURL := range urls {
 		// Validate URL
-		if err := parser.ValidateURL(inputURL, cfg.AllowPrivateIPs); err != nil {
+		if err := parser.ValidateURL(target, cfg.AllowPrivateIPs); err != nil {
 			cfg.Logger.Warn("skipping invalid URL", "url", inputURL, "error", err)
+			invalidCount++
 			continue
 		}
 
-		expanded := parser.ExpandURLs(inputURL, cfg.AllSchemes, cfg.IgnorePorts, cfg.CustomPorts)
+		probes := inputProbes(cfg, inputURL, defaultPorts)
+		plannedProbes += probes
+		if cfg.MaxTotalProbes > 0 && plannedProbes > cfg.MaxTotalProbes && !budgetWarned {
+			// What the inputs so far planned plus what the rest would add
+			estimate := plannedProbes + estimateProbes(cfg, inputLines[i+1:], defaultPorts)
+			if !cfg.Force {
+				cfg.Logger.Error("URL expansion exceeds -max-total-probes",
+					"inputs", len(urls),
+					"input_probes", probes,
+					"estimated_total", estimate,
+					"max_total_probes", cfg.MaxTotalProbes,
+					"hint", "narrow -p/-ip/-as, raise -max-total-probes, or pass -force",
+				)
+				return exitFatal
+			}
+			cfg.Logger.Warn("URL expansion exceeds -max-total-probes, continuing due to -force",
+				"inputs", len(urls),
+				"input_probes", probes,
+				"estimated_total", estimate,
+				"max_total_probes", cfg.MaxTotalProbes,
+			)
+			budgetWarned = true
+		}
+
+		expanded := parser.ExpandURLs(inputURL, cfg.AllSchemes, cfg.IgnorePorts, cfg.CustomPorts, defaultPorts)
+		if cfg.PathVariants {
+			expanded = parser.WithPathVariants(expanded)
+		}
 		if cfg.DebugLogger != nil {
 			cfg.DebugLogger.Info("expanded URL",
 				"input", inputURL,
 				"expanded_count", len(expanded),
-				"expanded_urls", expanded,
+				"expanded_urls", expanded.URLs(),
 			)
 		}
-		for _, expandedURL := range expanded {
-			expandedURLs = append(expandedURLs, expandedURL)
-			originalInputMap[expandedURL] = inputURL
+		refused := 0
+		for _, e := range expanded {
+			if cfg.ExcludeMatcher.Excluded(e.URL) {
+				excludedCount++
+				continue
+			}
+			if !cfg.Scope.Allows(e.URL) {
+				refused++
+				continue
+			}
+			// A variant that another input plans as a base path keeps that provenance
+			if _, planned := expansionByURL[e.URL]; planned && e.VariantOf != "" {
+				continue
+			}
+			expandedURLs = append(expandedURLs, e.URL)
+			if user != nil && cfg.UseURLCredentials {
+				if _, exists := credentialsByURL[e.URL]; !exists {
+					credentialsByURL[e.URL] = user
+				}
+			}
+			// The first input to plan a URL keeps it, as its annotation does,
+			// unless that input only planned it as a path variant
+			if _, planned := expansionByURL[e.URL]; planned && variantOfByURL[e.URL] == "" {
+				continue
+			}
+			originalInputMap[e.URL] = inputURL
+			expansionByURL[e.URL] = &e.Expansion
+			if e.VariantOf != "" {
+				variantOfByURL[e.URL] = e.VariantOf
+			} else {
+				delete(variantOfByURL, e.URL)
+			}
+		}
+		if refused > 0 {
+			cfg.Logger.Warn("refusing out-of-scope input", "input", inputURL, "urls", refused, "error", "out_of_scope")
+			outOfScopeCount += refused
 		}
 	}
 
 	cfg.Logger.Info("expanded URLs", "count", len(expandedURLs))
+	if credentialInputs > 0 {
+		if cfg.UseURLCredentials {
+			cfg.Logger.Info("sending input URL credentials as Basic Authorization", "inputs", credentialInputs)
+		} else {
+			cfg.Logger.Warn("stripped credentials from input URLs, pass -use-url-credentials to send them", "inputs", credentialInputs)
+		}
+	}
+	if excludedCount > 0 {
+		cfg.Logger.Info("excluded out-of-scope URLs", "count", excludedCount)
+	}
+	if outOfScopeCount > 0 {
+		cfg.Logger.Warn("refused URLs outside -scope-file", "count", outOfScopeCount, "error", "out_of_scope")
+	}
 
-	// Deduplicate URLs that resolve to the same endpoint
-	// (e.g., http://host and http://host:80 are the same)
+	// Deduplicate URLs that resolve to the same endpoint. Expanded URLs are
+	// normalized (e.g., example.com and example.com:80 both expand to
+	// http://example.com/), so an exact pass suffices and every kept URL
+	// keeps its entry in originalInputMap.
 	beforeDedup := len(expandedURLs)
 	expandedURLs = parser.DeduplicateURLs(expandedURLs)
 	afterDedup := len(expandedURLs)
 	if beforeDedup != afterDedup {
 		cfg.Logger.Info("deduplicated URLs", "before", beforeDedup, "after", afterDedup)
-		// Rebuild originalInputMap for deduplicated URLs - O(n) instead of O(n²)
-		// Pre-compute normalized mappings
-		normalizedMap := make(map[string]string)
-		for origURL, origInput := range originalInputMap {
-			normalized := parser.NormalizeURL(origURL)
-			if _, exists := normalizedMap[normalized]; !exists {
-				normalizedMap[normalized] = origInput
+	}
+
+	// -shard: keep this machine's bucket of the deduplicated URLs. Buckets
+	// hash the normalized URL, so machines agree regardless of input order.
+	if cfg.ShardSpec != nil {
+		kept := make([]string, 0, len(expandedURLs)/cfg.ShardSpec.Count+1)
+		for _, urlStr := range expandedURLs {
+			if cfg.ShardSpec.Contains(parser.NormalizeURL(urlStr, defaultPorts)) {
+				kept = append(kept, urlStr)
 			}
 		}
+		expandedURLs = kept
+		cfg.Logger.Info("selected shard", "shard", cfg.ShardSpec.String(), "kept", len(expandedURLs), "total", afterDedup)
+	}
+
+	// Results carry the normalized form of the URL they probed
+	expansionByNormalized := make(map[string]*output.Expansion, len(expandedURLs))
+	variantOfByNormalized := make(map[string]string, len(variantOfByURL))
+	for _, urlStr := range expandedURLs {
+		normalized := parser.NormalizeURL(urlStr, defaultPorts)
+		expansionByNormalized[normalized] = expansionByURL[urlStr]
+		if base, ok := variantOfByURL[urlStr]; ok {
+			variantOfByNormalized[normalized] = base
+		}
+	}
 
-		// O(n) lookup
-		newOriginalInputMap := make(map[string]string)
+	// -deadlist: URLs of hosts that kept failing in earlier runs are reported
+	// without being probed, except on their periodic retry run
+	probeURLs := expandedURLs
+	var skippedResults []output.ProbeResult
+	deadlistedCount := 0
+	if deadHosts != nil {
+		probeURLs = make([]string, 0, len(expandedURLs))
 		for _, urlStr := range expandedURLs {
-			if originalInput, exists := originalInputMap[urlStr]; exists {
-				newOriginalInputMap[urlStr] = originalInput
-			} else {
-				normalized := parser.NormalizeURL(urlStr)
-				if origInput, exists := normalizedMap[normalized]; exists {
-					newOriginalInputMap[urlStr] = origInput
-				} else {
-					// Fallback: use the URL itself as input
-					newOriginalInputMap[urlStr] = urlStr
+			if u, err := url.Parse(urlStr); err == nil && deadHosts.Skip(u.Hostname(), cfg.DeadlistThreshold, cfg.DeadlistRetryEvery) {
+				skippedResults = append(skippedResults, prober.DeadlistedResult(urlStr, originalInputMap[urlStr]))
+				continue
+			}
+			probeURLs = append(probeURLs, urlStr)
+		}
+		deadlistedCount = len(skippedResults)
+		if deadlistedCount > 0 {
+			cfg.Logger.Info("skipped URLs of deadlisted hosts", "count", deadlistedCount, "deadlist", cfg.DeadlistFile)
+		}
+	}
+
+	// -prefetch-dns: URLs of hostnames that don't exist are reported without
+	// entering the worker pool
+	if cfg.PrefetchDNS {
+		nxdomain, stats := prober.PrefetchDNS(ctx, probeURLs, time.Duration(cfg.PrefetchDNSTimeout)*time.Second)
+		cfg.Logger.Info("dns prefetch completed",
+			"hosts", stats.Hosts,
+			"ip_literals", stats.IPLiterals,
+			"resolved", stats.Resolved,
+			"nxdomain", stats.NXDomain,
+			"errors", stats.Errors,
+			"duration", stats.Duration.Round(time.Millisecond),
+		)
+		if len(nxdomain) > 0 {
+			resolved := make([]string, 0, len(probeURLs))
+			for _, urlStr := range probeURLs {
+				if u, err := url.Parse(urlStr); err == nil {
+					if lookupErr, ok := nxdomain[u.Hostname()]; ok {
+						skippedResults = append(skippedResults, prober.NXDomainResult(urlStr, originalInputMap[urlStr], lookupErr))
+						continue
+					}
 				}
+				resolved = append(resolved, urlStr)
 			}
+			probeURLs = resolved
 		}
-		originalInputMap = newOriginalInputMap
 	}
 
-	// Create prober
-	prober := probe.NewProber(cfg)
-	defer prober.Close() // Clean up HTTP clients and transports
+	if len(credentialsByURL) > 0 {
+		prober.SetURLCredentials(credentialsByURL)
+	}
 
 	// Process URLs with worker pool
-	results := prober.ProcessURLs(ctx, expandedURLs, originalInputMap, cfg.Concurrency)
+	results := prober.ProcessURLs(ctx, probeURLs, originalInputMap, cfg.Concurrency)
 
 	// Write results
-	successCount := 0
-	errorCount := 0
+	tally := output.NewTally()
 	completed := 0
 	total := len(expandedURLs)
+	// -vhost-list adds a result per hostname to every IP target probed
+	vhostURLs := make(map[string][]string)
+	for _, u := range probeURLs {
+		if candidates := prober.VhostURLs(u); len(candidates) > 0 {
+			vhostURLs[u] = candidates
+			total += len(candidates)
+		}
+	}
 
 	// Check if stderr is a terminal for progress display
 	showProgress := !cfg.Silent && term.IsTerminal(int(os.Stderr.Fd()))
@@ -204,33 +550,99 @@ func main() {
 		fmt.Fprintf(os.Stderr, "\033[s\033[%d;1H\033[K[%d/%d] %s\033[u", termHeight, completed, total, displayURL)
 	}
 
-	for result := range results {
-		completed++
-		updateStatusBar(result.URL)
+	// -host-profiles aggregates every result per hostname, written at the end
+	var profiler *output.Profiler
+	if cfg.HostProfiles != "" {
+		profiler = output.NewProfiler(cfg.AliveStatus, defaultPorts.ForScheme)
+	}
 
-		// Skip results with errors in JSON output (but emit diagnostic results)
-		if result.Error != "" {
-			if result.SNIRequired {
-				// Emit SNI diagnostic results — these are valuable security intelligence
-				if diagJSON, err := json.Marshal(result); err == nil {
-					fmt.Fprintln(outputWriter, string(diagJSON))
-				}
+	// -openmetrics keeps the latest gauges per target, written at the end
+	var metrics *output.MetricsExporter
+	if cfg.OpenMetrics != "" {
+		metrics = output.NewMetricsExporter(cfg.OpenMetricsHostOnly, defaultPorts.ForScheme)
+	}
+
+	// -cert-report keeps each unique certificate and its hosts, written at the end
+	var certs *output.CertRegistry
+	if cfg.CertReport != "" {
+		certs = output.NewCertRegistry()
+	}
+
+	// -alive-output keeps the distinct base URLs of live results, written at the end
+	var aliveSet *output.AliveSet
+	if cfg.AliveOutput != "" {
+		aliveSet = output.NewAliveSet(cfg.AliveStatus, defaultPorts.ForScheme)
+	}
+
+	// alive counts live results (-alive-codes) for -exit-code-policy
+	alive := 0
+
+	// writeResult records, routes and writes one finished result
+	writeResult := func(result output.ProbeResult) {
+		tally.Record(result)
+		if output.IsAlive(result, cfg.AliveStatus) {
+			alive++
+		}
+		if profiler != nil {
+			profiler.Record(result)
+		}
+		if metrics != nil {
+			metrics.Record(result)
+		}
+		if certs != nil {
+			certs.Record(result)
+		}
+		if aliveSet != nil {
+			aliveSet.Record(result)
+		}
+		if deadHosts != nil {
+			deadHosts.Observe(result)
+		}
+		if cfg.NoTimestamp {
+			result.Timestamp = ""
+			result.StartedAt = ""
+			result.CompletedAt = ""
+			if generatedRunID {
+				result.RunID = ""
 			}
-			errorCount++
-			continue
 		}
 
 		jsonData, err := json.Marshal(result)
 		if err != nil {
 			cfg.Logger.Error("failed to marshal result", "error", err)
-			continue
+			return
+		}
+
+		// Routes see every result, including errors
+		if router != nil {
+			if err := router.Write(result, jsonData); err != nil {
+				cfg.Logger.Error("failed to write routed result", "error", err)
+			}
+		}
+
+		if sqliteOut != nil {
+			if err := sqliteOut.Write(result, jsonData); err != nil {
+				cfg.Logger.Error("failed to write sqlite result", "error", err)
+			}
+		}
+
+		// Skip results with errors in JSON output (but emit diagnostic results)
+		if result.Error != "" {
+			if result.SNIRequired || result.ErrorType == output.ErrorTypeSkippedFirstAlive || result.ErrorType == output.ErrorTypeSkippedDeadlisted {
+				// Emit SNI diagnostic results — these are valuable security intelligence.
+				// -first-alive and -deadlist skips are emitted so every expanded URL is accounted for.
+				fmt.Fprintln(outputWriter, string(jsonData))
+			} else {
+				tally.RecordFiltered()
+			}
+			return
 		}
 
 		// Write JSON to output
 		fmt.Fprintln(outputWriter, string(jsonData))
 
-		// If output file is specified AND status is successful (2XX), print summary to console
-		if cfg.OutputFile != "" && result.StatusCode >= 200 && result.StatusCode < 300 {
+		// If output file is specified AND the result is live (-alive-codes), print summary to console
+		if cfg.OutputFile != "" && output.IsAlive(result, cfg.AliveStatus) {
 			// Build status chain string: [301 -> 302 -> 200]
 			chainParts := make([]string, len(result.ChainStatusCodes))
 			for i, code := range result.ChainStatusCodes {
@@ -244,10 +656,42 @@ func main() {
 				fmt.Printf("%s %s\n", result.URL, chainStr)
 			}
 		}
+	}
 
-		successCount++
+	// -correlate-schemes holds results until their other-scheme sibling arrives
+	var correlator *schemeCorrelator
+	if cfg.CorrelateSchemes {
+		correlator = newSchemeCorrelator(expandedURLs, originalInputMap, vhostURLs, defaultPorts)
 	}
 
+	handleResult := func(result output.ProbeResult) {
+		completed++
+		updateStatusBar(result.URL)
+		result.Meta = metaByInput[result.Input]
+		result.Expansion = expansionByNormalized[result.NormalizedURL]
+		result.VariantOf = variantOfByNormalized[result.NormalizedURL]
+		if correlator == nil {
+			writeResult(result)
+			return
+		}
+		for _, ready := range correlator.add(result) {
+			writeResult(ready)
+		}
+	}
+	for _, result := range skippedResults {
+		handleResult(result)
+	}
+	for result := range results {
+		handleResult(result)
+	}
+	if correlator != nil {
+		for _, ready := range correlator.flush() {
+			writeResult(ready)
+		}
+	}
+	// Taken before -health-interval cancels the context on its own
+	interrupted := ctx.Err() != nil
+
 	// Clean up terminal state
 	if showProgress && termHeight > 0 {
 		// Reset scroll region to full terminal
@@ -258,11 +702,112 @@ func main() {
 		fmt.Fprintf(os.Stderr, "\033[%d;1H", termHeight-1)
 	}
 
+	counts := tally.Counts()
 	cfg.Logger.Info("probing completed",
 		"total", len(expandedURLs),
-		"success", successCount,
-		"errors", errorCount,
+		"success", counts.Succeeded,
+		"errors", counts.Failed,
+		"cancelled", counts.Cancelled,
+		"excluded", excludedCount,
+		"out_of_scope", outOfScopeCount,
 	)
+
+	if cfg.Stats {
+		writeStats(os.Stderr, cfg.RunID, len(expandedURLs), excludedCount, outOfScopeCount, counts, time.Since(startTime), prober.TransferStats(statsTopHosts), prober.DNSCacheStats(), prober.AdaptiveStats())
+	}
+
+	// Written after the results channel drains, which also covers runs
+	// cancelled by SIGINT/SIGTERM
+	if profiler != nil {
+		if err := profiler.WriteProfiles(cfg.HostProfiles); err != nil {
+			cfg.Logger.Error("failed to write host profiles", "file", cfg.HostProfiles, "error", err)
+		}
+	}
+	if metrics != nil {
+		if err := metrics.WriteOpenMetrics(cfg.OpenMetrics); err != nil {
+			cfg.Logger.Error("failed to write OpenMetrics", "file", cfg.OpenMetrics, "error", err)
+		}
+	}
+	if certs != nil {
+		report := certs.Report(time.Now(), cfg.CertReportDays)
+		if err := output.WriteCertReport(cfg.CertReport, report); err != nil {
+			cfg.Logger.Error("failed to write certificate report", "file", cfg.CertReport, "error", err)
+		} else {
+			cfg.Logger.Info("certificate report written",
+				"file", cfg.CertReport,
+				"certificates", report.Certificates,
+				"expired", len(report.Expired),
+				"expiring_soon", len(report.ExpiringSoon),
+				"self_signed", len(report.SelfSigned),
+			)
+		}
+	}
+	if aliveSet != nil {
+		if err := aliveSet.WriteFile(cfg.AliveOutput); err != nil {
+			cfg.Logger.Error("failed to write alive output", "file", cfg.AliveOutput, "error", err)
+		} else {
+			cfg.Logger.Info("alive output written", "file", cfg.AliveOutput, "urls", len(aliveSet.Lines()))
+		}
+	}
+	// An interrupted run didn't see every URL of a host, so it says nothing
+	// about which hosts are dead
+	if deadHosts != nil {
+		if interrupted {
+			cfg.Logger.Warn("not updating deadlist of interrupted run", "path", cfg.DeadlistFile)
+		} else {
+			deadHosts.Merge(time.Now())
+			if err := deadHosts.Save(cfg.DeadlistFile); err != nil {
+				cfg.Logger.Error("failed to save deadlist", "path", cfg.DeadlistFile, "error", err)
+			} else {
+				cfg.Logger.Info("deadlist updated", "path", cfg.DeadlistFile, "hosts", len(deadHosts.Entries()))
+			}
+		}
+	}
+	if cfg.Manifest != "" {
+		manifest := &output.Manifest{
+			Version:      version.GetShortVersion(),
+			RunID:        cfg.RunID,
+			Interrupted:  interrupted,
+			InputSHA256:  hex.EncodeToString(inputHash.Sum(nil)),
+			Inputs:       len(urls),
+			Invalid:      invalidCount,
+			Excluded:     excludedCount,
+			OutOfScope:   outOfScopeCount,
+			Deadlisted:   deadlistedCount,
+			Expanded:     beforeDedup,
+			Deduplicated: afterDedup,
+			Counts:       counts,
+			Config:       cfg.Redacted(),
+		}
+		if cfg.ShardSpec != nil {
+			manifest.Shard = &output.ShardInfo{
+				Spec:  cfg.ShardSpec.String(),
+				Kept:  len(expandedURLs),
+				Total: afterDedup,
+			}
+		}
+		if remote != nil {
+			manifest.InputURL = parser.StripUserinfo(remote.URL)
+			manifest.InputETag = remote.ETag
+			manifest.InputLastModified = remote.LastModified
+		} else {
+			manifest.InputFile = cfg.InputFile
+		}
+		manifest.SetTiming(startTime, time.Now())
+		if err := output.WriteManifest(cfg.Manifest, manifest); err != nil {
+			cfg.Logger.Error("failed to write manifest", "file", cfg.Manifest, "error", err)
+		}
+	}
+
+	// With -health-interval, close the prober early so goroutines it leaves
+	// behind can be told apart from the ones it owns
+	if cfg.HealthInterval > 0 {
+		cancel()
+		prober.Close()
+		probe.ReportGoroutineLeak(cfg, baselineGoroutines)
+	}
+
+	return exitCode(cfg.ExitCodePolicy, alive, interrupted)
 }
 
 // readURLs reads URLs from the input reader, skipping comments and empty lines.
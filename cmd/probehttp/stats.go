@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/probe"
+)
+
+// statsTopHosts is the number of heaviest hosts listed in the -stats summary.
+const statsTopHosts = 10
+
+// writeStats prints the -stats run summary.
+func writeStats(w io.Writer, runID string, total, excluded, outOfScope int, counts output.TallyCounts, elapsed time.Duration, transfer probe.TransferStats, dns probe.DNSCacheStats, adaptive probe.AdaptiveStats) {
+	fmt.Fprintf(w, "\nRun summary (%s)\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "  Run ID:     %s\n", runID)
+	fmt.Fprintf(w, "  URLs:       %d (%d succeeded, %d failed, %d cancelled)\n", total, counts.Succeeded, counts.Failed, counts.Cancelled)
+	fmt.Fprintf(w, "  Excluded:   %d\n", excluded)
+	if outOfScope > 0 {
+		fmt.Fprintf(w, "  Refused:    %d (outside -scope-file)\n", outOfScope)
+	}
+	fmt.Fprintf(w, "  Downloaded: %s\n", formatBytes(transfer.Downloaded))
+	fmt.Fprintf(w, "  Uploaded:   %s\n", formatBytes(transfer.Uploaded))
+	if adaptive.Ceiling > 0 {
+		fmt.Fprintf(w, "  Concurrency: %d at the end, %d-%d during the run (bounds %d-%d; %d increases, %d decreases)\n",
+			adaptive.Current, adaptive.Min, adaptive.Max, adaptive.Floor, adaptive.Ceiling, adaptive.Increases, adaptive.Decreases)
+	}
+	if dns.Hits+dns.Misses > 0 {
+		fmt.Fprintf(w, "  DNS cache:  %d hits, %d misses\n", dns.Hits, dns.Misses)
+	}
+	if len(counts.PageCategories) > 0 {
+		fmt.Fprintf(w, "  Page categories:\n")
+		for _, category := range slices.Sorted(maps.Keys(counts.PageCategories)) {
+			fmt.Fprintf(w, "    %-40s %10d\n", category, counts.PageCategories[category])
+		}
+	}
+	if redirects := counts.Redirects; redirects != nil {
+		fmt.Fprintf(w, "  Redirects:  %d inputs to another host, %d cross-host chains, %d https->http downgrades\n",
+			redirects.OffHostInputs, redirects.CrossHostChains, redirects.HTTPSDowngrades)
+		if len(redirects.TopDestinations) > 0 {
+			fmt.Fprintf(w, "  Top redirect destinations:\n")
+			for _, dest := range redirects.TopDestinations {
+				fmt.Fprintf(w, "    %-40s %10d\n", dest.Host, dest.Inputs)
+			}
+		}
+	}
+	if len(transfer.TopHosts) > 0 {
+		fmt.Fprintf(w, "  Top hosts by traffic:\n")
+		for _, host := range transfer.TopHosts {
+			fmt.Fprintf(w, "    %-40s %10s down %10s up\n", host.Host, formatBytes(host.Downloaded), formatBytes(host.Uploaded))
+		}
+	}
+}
+
+// formatBytes renders n with a binary unit suffix (B, KiB, MiB, GiB).
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 2; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMG"[exp])
+}
@@ -0,0 +1,148 @@
+package cdn
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"probeHTTP/internal/output"
+)
+
+// Cache statuses reported by InspectCache.
+const (
+	CacheHit     = "hit"
+	CacheMiss    = "miss"
+	CacheDynamic = "dynamic" // Response is not cacheable or bypassed the cache
+	CacheUnknown = "unknown" // Cache headers present but inconclusive
+)
+
+// cacheHeaders are the headers whose presence means an intermediary cache
+// was involved, even if its verdict can't be interpreted.
+var cacheHeaders = []string{"Age", "X-Cache", "X-Cache-Hits", "Cf-Cache-Status", "X-Served-By", "Via", "X-Varnish"}
+
+// cacheStatusRule interprets one vendor's cache status header.
+// Check returns "" when the header is absent or not understood.
+type cacheStatusRule struct {
+	Name  string
+	Check func(http.Header) string
+}
+
+var cacheStatusRules = []cacheStatusRule{
+	{
+		// HIT, MISS, EXPIRED, STALE, UPDATING, REVALIDATED, BYPASS, DYNAMIC
+		Name: "Cloudflare",
+		Check: func(h http.Header) string {
+			switch strings.ToUpper(strings.TrimSpace(h.Get("Cf-Cache-Status"))) {
+			case "HIT", "STALE", "UPDATING", "REVALIDATED":
+				return CacheHit
+			case "MISS", "EXPIRED":
+				return CacheMiss
+			case "BYPASS", "DYNAMIC":
+				return CacheDynamic
+			}
+			return ""
+		},
+	},
+	{
+		// Fastly/Varnish "HIT" or "MISS, HIT" (one entry per cache in the path,
+		// the last being the edge that answered), CloudFront "Hit from cloudfront",
+		// Akamai "TCP_MEM_HIT from a23-...". Akamai marks uncacheable objects
+		// with X-Check-Cacheable: NO.
+		Name: "X-Cache",
+		Check: func(h http.Header) string {
+			xcache := h.Get("X-Cache")
+			if xcache == "" {
+				return ""
+			}
+			parts := strings.Split(xcache, ",")
+			verdict := strings.ToUpper(strings.TrimSpace(parts[len(parts)-1]))
+			switch {
+			case strings.Contains(verdict, "HIT"):
+				return CacheHit
+			case strings.Contains(verdict, "PASS"), strings.Contains(verdict, "LAMBDAGENERATED"):
+				return CacheDynamic
+			case strings.Contains(verdict, "MISS"):
+				if strings.EqualFold(strings.TrimSpace(h.Get("X-Check-Cacheable")), "NO") {
+					return CacheDynamic
+				}
+				return CacheMiss
+			}
+			return ""
+		},
+	},
+	{
+		// Fastly hit counts per cache in the path, e.g. "0, 3"
+		Name: "X-Cache-Hits",
+		Check: func(h http.Header) string {
+			hits := h.Get("X-Cache-Hits")
+			if hits == "" {
+				return ""
+			}
+			parts := strings.Split(hits, ",")
+			n, err := strconv.Atoi(strings.TrimSpace(parts[len(parts)-1]))
+			if err != nil {
+				return ""
+			}
+			if n > 0 {
+				return CacheHit
+			}
+			return CacheMiss
+		},
+	},
+	{
+		// Varnish: "X-Varnish: <request id>" on a miss,
+		// "<request id> <id of the cached object's request>" on a hit
+		Name: "Varnish",
+		Check: func(h http.Header) string {
+			ids := strings.Fields(h.Get("X-Varnish"))
+			switch len(ids) {
+			case 0:
+				return ""
+			case 1:
+				return CacheMiss
+			}
+			return CacheHit
+		},
+	},
+}
+
+// InspectCache reports how an intermediary cache handled the response,
+// based on vendor cache status headers, Age and Via. It returns nil when
+// no cache-related header is present.
+func InspectCache(headers http.Header) *output.CacheInfo {
+	present := false
+	for _, key := range cacheHeaders {
+		if headers.Get(key) != "" {
+			present = true
+			break
+		}
+	}
+	if !present {
+		return nil
+	}
+
+	info := &output.CacheInfo{Status: CacheUnknown}
+	if age, err := strconv.Atoi(strings.TrimSpace(headers.Get("Age"))); err == nil && age >= 0 {
+		info.AgeSeconds = &age
+	}
+	for _, value := range headers.Values("Via") {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				info.Via = append(info.Via, hop)
+			}
+		}
+	}
+
+	for _, rule := range cacheStatusRules {
+		if status := rule.Check(headers); status != "" {
+			info.Status = status
+			return info
+		}
+	}
+
+	// A non-zero Age means the response was stored by some cache
+	if info.AgeSeconds != nil && *info.AgeSeconds > 0 {
+		info.Status = CacheHit
+	}
+	return info
+}
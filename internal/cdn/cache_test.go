@@ -0,0 +1,116 @@
+package cdn
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestInspectCache(t *testing.T) {
+	age := func(n int) *int { return &n }
+
+	tests := []struct {
+		name       string
+		headers    http.Header
+		wantStatus string
+		wantAge    *int
+		wantVia    []string
+	}{
+		{
+			"Cloudflare hit",
+			http.Header{"Cf-Cache-Status": {"HIT"}, "Age": {"1834"}, "Cf-Ray": {"8a1b2c3d4e5f-FRA"}, "Server": {"cloudflare"}},
+			CacheHit, age(1834), nil,
+		},
+		{
+			"Cloudflare dynamic",
+			http.Header{"Cf-Cache-Status": {"DYNAMIC"}, "Cf-Ray": {"8a1b2c3d4e5f-VIE"}},
+			CacheDynamic, nil, nil,
+		},
+		{
+			"Cloudflare expired",
+			http.Header{"Cf-Cache-Status": {"EXPIRED"}, "Age": {"0"}},
+			CacheMiss, age(0), nil,
+		},
+		{
+			"Fastly shielded hit",
+			http.Header{
+				"X-Served-By":  {"cache-iad-kiad7000025-IAD, cache-fra-eddf8230061-FRA"},
+				"X-Cache":      {"MISS, HIT"},
+				"X-Cache-Hits": {"0, 4"},
+				"Age":          {"95"},
+				"Via":          {"1.1 varnish, 1.1 varnish"},
+			},
+			CacheHit, age(95), []string{"1.1 varnish", "1.1 varnish"},
+		},
+		{
+			"Fastly miss",
+			http.Header{"X-Served-By": {"cache-ams21048-AMS"}, "X-Cache": {"MISS"}, "X-Cache-Hits": {"0"}, "Via": {"1.1 varnish"}, "Age": {"0"}},
+			CacheMiss, age(0), []string{"1.1 varnish"},
+		},
+		{
+			"Fastly pass",
+			http.Header{"X-Served-By": {"cache-ams21048-AMS"}, "X-Cache": {"PASS"}},
+			CacheDynamic, nil, nil,
+		},
+		{
+			"Akamai memory hit",
+			http.Header{"X-Cache": {"TCP_MEM_HIT from a2-20-133-61.deploy.akamaitechnologies.com (AkamaiGHost/11.4.3-53413389) (-)"}},
+			CacheHit, nil, nil,
+		},
+		{
+			"Akamai uncacheable miss",
+			http.Header{"X-Cache": {"TCP_MISS from a23-55-178-13.deploy.akamaitechnologies.com (AkamaiGHost/11.4.3-53413389) (-)"}, "X-Check-Cacheable": {"NO"}},
+			CacheDynamic, nil, nil,
+		},
+		{
+			"CloudFront refresh hit",
+			http.Header{"X-Cache": {"RefreshHit from cloudfront"}, "Via": {"1.1 3f0f4a0e1ba8.cloudfront.net (CloudFront)"}, "X-Amz-Cf-Pop": {"FRA56-P5"}},
+			CacheHit, nil, []string{"1.1 3f0f4a0e1ba8.cloudfront.net (CloudFront)"},
+		},
+		{
+			"Varnish hit",
+			http.Header{"X-Varnish": {"32773 32770"}, "Age": {"12"}, "Via": {"1.1 varnish (Varnish/7.4)"}},
+			CacheHit, age(12), []string{"1.1 varnish (Varnish/7.4)"},
+		},
+		{
+			"Varnish miss",
+			http.Header{"X-Varnish": {"32776"}, "Age": {"0"}, "Via": {"1.1 varnish (Varnish/7.4)"}},
+			CacheMiss, age(0), []string{"1.1 varnish (Varnish/7.4)"},
+		},
+		{
+			"Age only",
+			http.Header{"Age": {"300"}},
+			CacheHit, age(300), nil,
+		},
+		{
+			"Via only",
+			http.Header{"Via": {"1.1 proxy.corp.example"}, "Age": {"garbage"}},
+			CacheUnknown, nil, []string{"1.1 proxy.corp.example"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := InspectCache(tt.headers)
+			if info == nil {
+				t.Fatal("InspectCache() = nil")
+			}
+			if info.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", info.Status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(info.AgeSeconds, tt.wantAge) {
+				t.Errorf("AgeSeconds = %v, want %v", info.AgeSeconds, tt.wantAge)
+			}
+			if !reflect.DeepEqual(info.Via, tt.wantVia) {
+				t.Errorf("Via = %q, want %q", info.Via, tt.wantVia)
+			}
+		})
+	}
+}
+
+func TestInspectCache_NoCacheHeaders(t *testing.T) {
+	headers := http.Header{"Server": {"nginx"}, "Cache-Control": {"no-store"}, "Content-Type": {"text/html"}}
+	if info := InspectCache(headers); info != nil {
+		t.Errorf("InspectCache() = %+v, want nil", info)
+	}
+}
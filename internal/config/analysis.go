@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnalysisFeatures is a set of analysis steps, used for -disable-analysis.
+// The prober tests bits in the hot path instead of comparing names.
+type AnalysisFeatures uint8
+
+// Analysis steps that -disable-analysis can turn off. A disabled step
+// leaves its fields zero-valued or omitted.
+const (
+	AnalysisHash        AnalysisFeatures = 1 << iota // body_mmh3 and json_canonical_mmh3
+	AnalysisTitle                                    // title, title_source and page metadata (canonical_url, generator, lang)
+	AnalysisWords                                    // words and lines
+	AnalysisCSP                                      // csp and the CSP domains of discovered_domains
+	AnalysisHeadersHash                              // header_mmh3
+)
+
+// analysisFeatureNames lists the -disable-analysis names in help order.
+var analysisFeatureNames = []struct {
+	name    string
+	feature AnalysisFeatures
+}{
+	{"hash", AnalysisHash},
+	{"title", AnalysisTitle},
+	{"words", AnalysisWords},
+	{"csp", AnalysisCSP},
+	{"headers-hash", AnalysisHeadersHash},
+}
+
+// AnalysisFeatureNames returns the names accepted by ParseAnalysisFeatures.
+func AnalysisFeatureNames() []string {
+	names := make([]string, len(analysisFeatureNames))
+	for i, f := range analysisFeatureNames {
+		names[i] = f.name
+	}
+	return names
+}
+
+// ParseAnalysisFeatures parses a comma-separated list of feature names,
+// case-insensitive. An empty list is the empty set.
+func ParseAnalysisFeatures(list string) (AnalysisFeatures, error) {
+	var features AnalysisFeatures
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, f := range analysisFeatureNames {
+			if f.name == name {
+				features |= f.feature
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown analysis feature %q (want %s)", name, strings.Join(AnalysisFeatureNames(), ", "))
+		}
+	}
+	return features, nil
+}
+
+// Has reports whether f contains every feature of other.
+func (f AnalysisFeatures) Has(other AnalysisFeatures) bool {
+	return f&other == other
+}
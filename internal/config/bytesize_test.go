@@ -0,0 +1,38 @@
+package config
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"1024", 1024, false},
+		{"500k", 500 * 1024, false},
+		{"500KB", 500 * 1024, false},
+		{"2m", 2 * 1024 * 1024, false},
+		{"2MB", 2 * 1024 * 1024, false},
+		{"1g", 1 << 30, false},
+		{" 64k ", 64 * 1024, false},
+		{"100b", 100, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"-1k", 0, true},
+		{"1.5m", 0, true},
+		{"10t", 0, true},
+		{"99999999999999g", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseByteSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadClientCert loads the -client-cert/-client-key key pair, the optional
+// -client-ca bundle and the -client-cert-hosts globs into cfg.
+func loadClientCert(cfg *Config) error {
+	if cfg.ClientCert == "" && cfg.ClientKey == "" {
+		if cfg.ClientCertHosts != "" {
+			return fmt.Errorf("-client-cert-hosts requires -client-cert and -client-key")
+		}
+	} else {
+		if cfg.ClientCert == "" {
+			return fmt.Errorf("-client-key requires -client-cert")
+		}
+		if cfg.ClientKey == "" {
+			return fmt.Errorf("-client-cert requires -client-key")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return fmt.Errorf("-client-cert/-client-key: failed to load key pair from %s and %s: %v", cfg.ClientCert, cfg.ClientKey, err)
+		}
+		cfg.ClientCertificate = &cert
+	}
+
+	for _, host := range strings.Split(cfg.ClientCertHosts, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			cfg.ClientCertHostList = append(cfg.ClientCertHostList, host)
+		}
+	}
+
+	if cfg.ClientCA != "" {
+		data, err := os.ReadFile(cfg.ClientCA)
+		if err != nil {
+			return fmt.Errorf("-client-ca: %v", err)
+		}
+		// Extend rather than replace the system roots so public hosts still verify
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("-client-ca: no PEM certificates found in %s", cfg.ClientCA)
+		}
+		cfg.RootCAs = pool
+	}
+	return nil
+}
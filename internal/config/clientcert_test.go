@@ -0,0 +1,111 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestKeyPair writes a self-signed certificate and its key as PEM files.
+func writeTestKeyPair(t *testing.T, dir string) (certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "probehttp client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath = filepath.Join(dir, "client.pem")
+	keyPath = filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestLoadClientCert(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestKeyPair(t, dir)
+
+	cfg := New()
+	cfg.ClientCert = certPath
+	cfg.ClientKey = keyPath
+	cfg.ClientCA = certPath
+	cfg.ClientCertHosts = "*.Corp.Example, api.internal"
+	if err := loadClientCert(cfg); err != nil {
+		t.Fatalf("loadClientCert: %v", err)
+	}
+	if cfg.ClientCertificate == nil {
+		t.Fatal("ClientCertificate not loaded")
+	}
+	if cfg.RootCAs == nil {
+		t.Error("RootCAs not set from -client-ca")
+	}
+	if len(cfg.ClientCertHostList) != 2 || cfg.ClientCertHostList[0] != "*.corp.example" {
+		t.Errorf("ClientCertHostList = %v", cfg.ClientCertHostList)
+	}
+}
+
+func TestLoadClientCert_Errors(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestKeyPair(t, dir)
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a pem file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cert    string
+		key     string
+		ca      string
+		hosts   string
+		wantErr string
+	}{
+		{"cert without key", certPath, "", "", "", "-client-cert requires -client-key"},
+		{"key without cert", "", keyPath, "", "", "-client-key requires -client-cert"},
+		{"hosts without cert", "", "", "", "*.corp.example", "-client-cert-hosts requires"},
+		{"missing cert file", filepath.Join(dir, "missing.pem"), keyPath, "", "", "failed to load key pair"},
+		{"garbage key", certPath, garbage, "", "", "failed to load key pair"},
+		{"garbage CA", "", "", garbage, "", "no PEM certificates found"},
+		{"missing CA", "", "", filepath.Join(dir, "missing-ca.pem"), "", "-client-ca"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := New()
+			cfg.ClientCert = tt.cert
+			cfg.ClientKey = tt.key
+			cfg.ClientCA = tt.ca
+			cfg.ClientCertHosts = tt.hosts
+			err := loadClientCert(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadClientCert() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,88 +1,286 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
+	"probeHTTP/internal/fingerprint"
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/parser"
+	"probeHTTP/internal/scope"
+	"probeHTTP/internal/storage"
 	"probeHTTP/pkg/version"
 )
 
 // Config holds the CLI configuration
 type Config struct {
-	InputFile          string
-	OutputFile         string
-	FollowRedirects    bool
-	MaxRedirects       int
-	Timeout            int
-	Concurrency        int
-	Silent             bool
-	Debug              bool
-	SameHostOnly       bool
-	UserAgent          string
-	RandomUserAgent    bool
-	AllSchemes         bool
-	IgnorePorts        bool
-	CustomPorts        string
-	InsecureSkipVerify bool
-	AllowPrivateIPs    bool // NEW: Allow scanning private IPs
-	MaxBodySize        int64 // NEW: Maximum response body size in bytes
-	MaxRetries         int   // NEW: Maximum number of retries
-	TLSHandshakeTimeout int  // NEW: Timeout for TLS handshake attempts in seconds
-	RateLimitTimeout   int   // NEW: Timeout for rate limit wait in seconds
-	RateLimitPerHost   int   // Requests per second per host (default 10)
-	RateLimitBurst     int   // Burst size for rate limiter (default 1)
-	DisableHTTP3       bool  // NEW: Disable HTTP/3 (QUIC) support
-	DebugLogFile       string // NEW: Debug log file path (optional)
-	Version            bool   // NEW: Show version information
+	InputFile                string
+	InputAuthHeader          string `json:"-"` // "Name: value" header sent when fetching a remote -i URL (kept out of the manifest)
+	InputMaxSizeValue        string // Raw -input-max-size value (e.g. "50m")
+	InputMaxSize             int64  // Maximum size of a remote input list in bytes
+	InputUseProbeClient      bool   // Fetch remote input with the probing HTTP client
+	MetaDelim                string // Delimiter separating a passthrough annotation from the target on input lines
+	MetaKV                   bool   // Parse annotations made of k=v pairs into a map
+	InputFormat              string // Input format: plain, hints ("host:port [https]"), masscan-json or nmap-grepable
+	UseURLCredentials        bool   // Send input userinfo as Basic Authorization to that target (stripped from probe URLs either way)
+	NormalizePath            bool   // Collapse duplicate slashes in input paths
+	MaxTotalProbes           int    // Abort when inputs would expand to more URLs than this (0 = unlimited)
+	Force                    bool   // Warn and continue instead of aborting over -max-total-probes
+	OutputFile               string
+	FollowRedirects          bool
+	MaxRedirects             int
+	Analyze3xxBody           bool // Analyze bodies of redirects that have no Location
+	AnalyzeBestHop           bool // Analyze the body of the best hop of chains ending in 4xx/5xx
+	Timeout                  int
+	Concurrency              int
+	AdaptiveConcurrency      bool // Adjust the probes running at once between AdaptiveFloor and Concurrency (AIMD)
+	AdaptiveFloor            int  // Starting and lowest concurrency with AdaptiveConcurrency
+	Silent                   bool
+	Debug                    bool
+	SameHostOnly             bool
+	SameOriginOnly           bool // Block redirects that change scheme, host or port (stricter than SameHostOnly)
+	SendReferer              bool // Send the previous hop's URL as Referer on redirects, per Referrer-Policy
+	UserAgent                string
+	RandomUserAgent          bool
+	Accept                   string // Accept header sent with probes (empty = browser-like default)
+	AcceptLanguage           string // Accept-Language header sent with probes (empty = en-US,en;q=0.9)
+	RandomizeHeaders         bool   // Pick Accept/Accept-Language and browser headers matching the User-Agent's family
+	AllSchemes               bool
+	IgnorePorts              bool
+	CustomPorts              string
+	PathVariants             bool   // Also probe trailing-slash and case variants of input paths
+	DefaultHTTPPort          string // Port assumed for http:// URLs without an explicit port
+	DefaultHTTPSPort         string // Port assumed for https:// URLs without an explicit port
+	InsecureSkipVerify       bool
+	AllowPrivateIPs          bool             // NEW: Allow scanning private IPs
+	MaxBodySize              int64            // NEW: Maximum response body size in bytes
+	MaxBodySizeBinary        int64            // Maximum body size for non-text content types (-1 = MaxBodySize, see BinaryBodyLimit)
+	MaxBodySizeValue         string           // Raw -max-body-size value (e.g. "10m")
+	MaxBodySizeBinaryValue   string           // Raw -max-body-size-binary value ("" = same as -max-body-size)
+	AttachmentReadLimitValue string           // Raw -attachment-read-limit value (e.g. "64k")
+	AttachmentReadLimit      int64            // Most body bytes read from Content-Disposition: attachment responses (0 = headers only)
+	DisableAnalysis          string           // Raw -disable-analysis list (e.g. "hash,title")
+	DisabledAnalysis         AnalysisFeatures `json:"-"` // Parsed DisableAnalysis
+	EarlyExit                bool             // Stop reading final bodies once the title and keywords are resolved
+	ReadAheadValue           string           // Raw -read-ahead value (e.g. "256k")
+	ReadAhead                int64            // Most body bytes read with EarlyExit
+	MaxRetries               int              // NEW: Maximum number of retries
+	TLSHandshakeTimeout      int              // NEW: Timeout for TLS handshake attempts in seconds
+	HTTP3TimeoutValue        string           // Raw -http3-timeout value (e.g. "3s")
+	HTTP3Timeout             time.Duration    // Deadline of the HTTP/3 attempt; hosts that hit it skip HTTP/3 for the rest of the run
+	MaxTLSHandshakes         int              // Concurrent TLS attempts across all workers (0 = 2× concurrency)
+	RateLimitTimeout         int              // NEW: Timeout for rate limit wait in seconds
+	RateLimitPerHost         int              // Requests per second per host (default 10)
+	RateLimitBurst           int              // Burst size for rate limiter (default 1)
+	DNSCacheTTL              int              // Seconds to cache DNS answers shared by all workers
+	NoDNSCache               bool             // Resolve every connection independently
+	PrefetchDNS              bool             // Resolve all hostnames before probing; URLs of nonexistent hosts are not probed
+	PrefetchDNSTimeout       int              // Per-lookup timeout in seconds for -prefetch-dns
+	DisableAdaptiveRate      bool             // Don't slow down hosts that answer 429/503
+	DisableHTTP3             bool             // NEW: Disable HTTP/3 (QUIC) support
+	Preset                   string           // Named flag bundle (fast, thorough, stealth) applied before explicit flags
+	HTTP10Fallback           bool             // Retry failing http:// targets with a raw, leniently parsed HTTP/1.0 request
+	SchemeFallback           bool             // Retry cross-protocol failures (TLS vs plain HTTP) once with the opposite scheme
+	Proxy                    string           // http:// or https:// proxy every probe is sent through
+	ProxyURL                 *url.URL         `json:"-"` // Parsed Proxy (nil = direct connections)
+	ProxyErrorSignature      string           // Body or header text of the proxy's own error pages, marking its 502/504 as proxy_error
+	Coalesce                 bool             // Share one in-flight probe among concurrent probes of the same normalized URL
+	HedgeValue               string           // Raw -hedge value (e.g. "2s"; empty = disabled)
+	Hedge                    time.Duration    // Send one duplicate GET when no response headers arrived after this long (0 = disabled)
+	DebugLogFile             string           // NEW: Debug log file path (optional)
+	DebugLogMaxSizeValue     string           // Raw -debug-log-max-size value (e.g. "50m"; "0" = no rotation)
+	DebugLogMaxSize          int64            // Rotate the debug log once it reaches this many bytes (0 = never)
+	DebugLogBackups          int              // Number of rotated debug logs to keep
+	PanicFatal               bool             // Crash on a panic while probing instead of reporting an internal_panic result
+	DebugOnError             bool             // Attach the probe's debug transcript to failed results (debug field)
+	MaxDebugSizeValue        string           // Raw -max-debug-size value (e.g. "16k"; "0" = unlimited)
+	MaxDebugSize             int64            // Truncate the debug field at this many bytes (0 = unlimited)
+	HealthIntervalValue      string           // Raw -health-interval value (e.g. "30s"; empty = disabled)
+	HealthInterval           time.Duration    // Log goroutine/connection/in-flight counts this often (0 = disabled)
+	Version                  bool             // NEW: Show version information
+	ListPortGroups           bool             // Print the named port groups usable in -p and exit
+	Doctor                   bool             // Check the environment (DNS, egress, TLS, HTTP/3, file limit, output paths) and exit
+	DoctorTargets            []string         // Endpoints the -doctor network checks probe; "none" skips those checks
 	// Feature detection options
-	ResolveIP      bool     // Resolve and report IP addresses
-	DetectHSTS     bool     // Detect HSTS headers
-	TechDetect     bool     // Enable technology detection
-	DetectCDN      bool     // Enable CDN detection
-	DetectCNAME    bool     // Enable CNAME resolution
+	ResolveIP               bool                    // Resolve and report IP addresses
+	DetectHSTS              bool                    // Detect HSTS headers
+	CSP                     bool                    // Report a Content-Security-Policy summary
+	TechDetect              bool                    // Enable technology detection
+	DetectCDN               bool                    // Enable CDN detection
+	CacheInfo               bool                    // Report intermediary cache status, Age and Via
+	CompressionInfo         bool                    // Report Content-Encoding and compression_assessment of the final response
+	CompressionMinSizeValue string                  // Raw -compression-min-size value (e.g. "50k")
+	CompressionMinSize      int64                   // Unencoded text responses this large are assessed as "missing"
+	CookieInfo              bool                    // Report security attributes of cookies set along the chain
+	MethodsCheck            bool                    // Send OPTIONS to the final URL and report Allow / Access-Control-Allow-Methods
+	CORSCheck               bool                    // Send a cross-origin CORS preflight to the final URL (overrides cors_allow_methods)
+	DetectWAF               bool                    // Enable WAF detection
+	DetectCNAME             bool                    // Enable CNAME resolution
+	JSONCanonicalHash       bool                    // Hash canonicalized JSON bodies (json_canonical_mmh3)
+	Forms                   bool                    // Report HTML forms: forms_count, login_form, form_actions, cross_origin_form
+	Timing                  bool                    // Report per-phase request timings (timings, chain_timings)
+	ReverseDNS              bool                    // Reverse DNS (PTR) lookup for IP targets
+	ReverseDNSAlways        bool                    // Also perform PTR lookups for hostname targets
+	Keywords                string                  // Comma-separated body keywords to match
+	KeywordsFile            string                  // File with one body keyword per line
+	KeywordList             []string                // Merged keywords from Keywords and KeywordsFile
+	ParkedSignaturesFile    string                  // File with extra page_category signatures ("category kind value" lines)
+	PageClassifier          *fingerprint.Classifier `json:"-"` // Built-in signatures plus ParkedSignaturesFile
+	Excludes                []string                // Out-of-scope hosts, globs, CIDRs and URL prefixes (-exclude)
+	ExcludeFile             string                  // File with one exclusion entry per line
+	ExcludeMatcher          *scope.Matcher          `json:"-"` // Built from Excludes and ExcludeFile (nil = nothing excluded)
+	ScopeFile               string                  // File with the allowed apex domains, globs and CIDRs; everything else is refused
+	Scope                   *scope.Scope            `json:"-"` // Built from ScopeFile and ExcludeMatcher (nil = everything in scope)
+	DeadlistFile            string                  // Host deadlist carried across runs (-deadlist)
+	DeadlistThreshold       int                     // Skip deadlisted hosts with at least this many failed runs in a row
+	DeadlistRetryEvery      int                     // Probe skipped deadlisted hosts once every this many runs (0 = never)
+	Shard                   string                  // "i/n": keep only the i-th of n hash buckets of URLs (-shard)
+	ShardSpec               *scope.Shard            `json:"-"` // Parsed Shard (nil = whole input)
+	// Client certificate (mTLS) options
+	ClientCert         string           // PEM client certificate presented to servers that request one
+	ClientKey          string           // PEM private key for ClientCert
+	ClientCA           string           // PEM CA bundle added to the roots used to verify servers
+	ClientCertHosts    string           // Comma-separated host globs the client cert may be presented to
+	ClientCertificate  *tls.Certificate `json:"-"` // Loaded from ClientCert/ClientKey
+	ClientCertHostList []string         // Parsed ClientCertHosts (empty = all hosts)
+	RootCAs            *x509.CertPool   `json:"-"` // System roots plus ClientCA (nil = system roots)
 	// TLS extraction options
-	ExtractTLS      bool   // Extract certificate details from TLS connections
-	ExtractTLSChain bool   // Include intermediate certificate chain
-	DiscoverDomains bool   // Discover domains from certificate SANs/CN and CSP headers
+	ExtractTLS        bool     // Extract certificate details from TLS connections
+	ExtractTLSChain   bool     // Include intermediate certificate chain
+	ExtractTLSHops    bool     // Capture the leaf certificate of every HTTPS hop of the redirect chain
+	DiscoverDomains   bool     // Discover domains from certificate SANs/CN and CSP headers
+	DDExpandWildcards bool     // Add candidate hostnames under wildcard SANs to new_domains (implies DiscoverDomains)
+	DDWordlist        string   // File of subdomain labels for DDExpandWildcards (default: built-in list)
+	DDWordlistLabels  []string `json:"-"` // Labels loaded from DDWordlist
+	VhostList         string   // File of candidate hostnames sent to every IP target (vhost discovery)
+	VhostHosts        []string `json:"-"` // Hostnames loaded from VhostList
+	PinFile           string   // "host sha256:fingerprint" lines; listed hosts report pin_match
+	PinStrict         bool     // A pin mismatch fails the probe instead of only setting error_type
 	// Storage options
-	StoreResponse         bool   // Store HTTP responses to disk
-	StoreResponseDir      string // Directory for stored responses
-	IncludeResponseHeader bool   // Include response headers in JSON output
-	IncludeResponse       bool   // Include full request/response in JSON output
-	Logger             *slog.Logger // NEW: Structured logger
-	DebugLogger        *slog.Logger // NEW: Debug file logger (if DebugLogFile is set)
-	debugFileHandle    *os.File // Track debug file handle for cleanup
+	StoreResponse         bool                // Store HTTP responses to disk
+	StoreResponseDir      string              // Directory for stored responses
+	StoreFinalBody        bool                // Also store the final hop's body as a standalone file (requires StoreResponse)
+	StoreLayout           string              // storage.LayoutHostDirs or storage.LayoutFlat
+	StoreShardDepth       int                 // Hash-prefix directory levels under the layout's directories
+	IncludeResponseHeader bool                // Include response headers in JSON output
+	IncludeResponse       bool                // Include full request/response in JSON output
+	NoTimestamp           bool                // Omit the timestamp fields so reruns are byte-identical
+	Manifest              string              // Write a JSON run manifest to this path
+	HostProfiles          string              // Write one aggregated JSON profile per probed hostname to this path
+	OpenMetrics           string              // Write an OpenMetrics exposition of per-target gauges to this path
+	OpenMetricsHostOnly   bool                // Label OpenMetrics samples by host/scheme/port instead of URL
+	CertReport            string              // Write expired, expiring and self-signed certificates of the run to this path (implies ExtractTLS)
+	CertReportDays        int                 // Expiry window of CertReport in days
+	AliveOutput           string              // Write the sorted, distinct scheme://host[:port] of live URLs to this path
+	AliveCodes            string              // Status classes counting as live wherever liveness matters (e.g. "2xx,3xx")
+	AliveStatus           output.StatusRanges `json:"-"` // Parsed AliveCodes, for output.IsAlive
+	SQLiteFile            string              // Write every result to this SQLite database (probes, chains and certificates tables)
+	RunID                 string              // Identifies the run in results, the manifest, the storage index and the debug log ("" = generated at startup)
+	SQLiteRunID           string              // Run ID of SQLiteFile rows; rerunning with the same ID replaces them ("" = RunID)
+	SQLiteBatch           int                 // Results per SQLiteFile transaction
+	ExitCodePolicy        string              // always (0 on completion) or outcome (2 = nothing reachable, 3 = interrupted)
+	Stats                 bool                // Print a run summary (counts, bytes transferred) to stderr
+	CorrelateSchemes      bool                // Link http/https results of the same input, host and port (sibling_scheme_probed, converged)
+	MaxTitleLength        int                 // Maximum title length in runes (0 = unlimited)
+	TitleFallback         bool                // Synthesize a title from the first <h1>, the path's file name or the host for untitled pages
+	FirstAlive            bool                // Stop probing an input after its first live URL
+	FirstAliveStatus      string              // Status classes counting as live for -first-alive ("" = AliveCodes)
+	SuppressSkipped       bool                // Drop skipped_first_alive results instead of emitting them
+	Routes                []string            // Route specs ("field:value:path") writing result subsets to files
+	Logger                *slog.Logger        `json:"-"` // NEW: Structured logger
+	DebugLogger           *slog.Logger        `json:"-"` // NEW: Debug file logger (if DebugLogFile is set)
+	debugFileHandle       io.Closer           // Track debug log writer for cleanup
 }
 
+// DefaultAliveCodes is the status set that counts as live by default.
+const DefaultAliveCodes = "2xx,3xx"
+
+// DefaultDoctorTargets are the well-known endpoints -doctor probes when no
+// -doctor-target is given. Both answer over HTTP/3.
+var DefaultDoctorTargets = []string{"https://www.google.com/", "https://www.cloudflare.com/"}
+
+// DoctorTargetNone as the only -doctor-target skips the network checks,
+// for air-gapped hosts.
+const DoctorTargetNone = "none"
+
+// Input line formats accepted by -input-format.
+const (
+	InputFormatPlain = "plain" // first whitespace-separated token is the target
+	InputFormatHints = "hints" // target followed by an optional [http]/[https] scheme hint
+
+	InputFormatMasscanJSON  = "masscan-json"  // masscan -oJ output, open TCP ports only
+	InputFormatNmapGrepable = "nmap-grepable" // nmap -oG output, open TCP ports only
+)
+
+// Exit code policies accepted by -exit-code-policy.
+const (
+	ExitCodePolicyAlways  = "always"  // exit 0 whenever the run completes
+	ExitCodePolicyOutcome = "outcome" // exit code reflects what the scan found
+)
+
 // New creates a new Config with default values
 func New() *Config {
+	// DefaultAliveCodes is a constant known to parse
+	aliveStatus, _ := output.ParseStatusRanges(DefaultAliveCodes)
 	return &Config{
-		FollowRedirects:    true,
-		MaxRedirects:       10,
-		Timeout:            10,
-		Concurrency:        20,
-		Silent:             false,
-		Debug:              false,
-		SameHostOnly:       false,
-		RandomUserAgent:    false,
-		AllSchemes:         false,
-		IgnorePorts:        false,
-		InsecureSkipVerify: false,
-		AllowPrivateIPs:    false,
-		MaxBodySize:        10 * 1024 * 1024, // 10 MB default
-		MaxRetries:         0,                // No retries by default
-		TLSHandshakeTimeout: 10,              // 10 seconds default
-		RateLimitTimeout:   60,               // 60 seconds default
-		RateLimitPerHost:   10,               // 10 req/s per host default
-		RateLimitBurst:     1,                // burst of 1 default
-		DisableHTTP3:       false,            // HTTP/3 enabled by default
-		Version:            false,
-		StoreResponse:      false,            // Response storage disabled by default
-		StoreResponseDir:   "output",         // Default storage directory
-		IncludeResponseHeader: false,         // Response headers not included by default
-		IncludeResponse:    false,            // Full request/response not included by default
+		FollowRedirects:       true,
+		MaxRedirects:          10,
+		Timeout:               10,
+		Concurrency:           20,
+		AdaptiveFloor:         5,
+		Silent:                false,
+		Debug:                 false,
+		SameHostOnly:          false,
+		RandomUserAgent:       false,
+		AllSchemes:            false,
+		IgnorePorts:           false,
+		DefaultHTTPPort:       "80",
+		DefaultHTTPSPort:      "443",
+		InsecureSkipVerify:    false,
+		AllowPrivateIPs:       false,
+		MaxBodySize:           10 * 1024 * 1024, // 10 MB default
+		MaxBodySizeBinary:     -1,               // Same as MaxBodySize unless overridden
+		ReadAhead:             256 * 1024,       // -early-exit prefix limit
+		AttachmentReadLimit:   64 * 1024,        // Enough to hash and sniff a download
+		CompressionMinSize:    50 * 1024,        // Text bodies worth compressing
+		MaxRetries:            0,                // No retries by default
+		TLSHandshakeTimeout:   10,               // 10 seconds default
+		HTTP3Timeout:          3 * time.Second,  // No UDP listener means waiting out the deadline
+		RateLimitTimeout:      60,               // 60 seconds default
+		RateLimitPerHost:      10,               // 10 req/s per host default
+		RateLimitBurst:        1,                // burst of 1 default
+		DNSCacheTTL:           60,               // DNS answers cached for a minute
+		PrefetchDNSTimeout:    2,
+		DeadlistThreshold:     3,
+		DisableHTTP3:          false, // HTTP/3 enabled by default
+		Version:               false,
+		StoreResponse:         false,    // Response storage disabled by default
+		StoreResponseDir:      "output", // Default storage directory
+		StoreLayout:           storage.LayoutHostDirs,
+		IncludeResponseHeader: false,            // Response headers not included by default
+		IncludeResponse:       false,            // Full request/response not included by default
+		MaxTitleLength:        300,              // Titles capped at 300 runes by default
+		DebugLogBackups:       3,                // Keep 3 rotated debug logs
+		MaxDebugSize:          16 * 1024,        // 16 KB of debug transcript per failed result
+		InputMaxSize:          50 * 1024 * 1024, // 50 MB remote input cap
+		MaxTotalProbes:        5000000,          // 5M expanded URLs before -force is needed
+		CertReportDays:        30,
+		AliveCodes:            DefaultAliveCodes,
+		AliveStatus:           aliveStatus,
+		SQLiteBatch:           output.DefaultSQLiteBatch,
+		InputFormat:           InputFormatPlain,
+		PageClassifier:        fingerprint.Default(),
+		ExitCodePolicy:        ExitCodePolicyAlways,
 	}
 }
 
@@ -97,24 +295,351 @@ func ParseFlags() (*Config, error) {
 
 	flag.Parse()
 
+	// Presets apply first; explicitly set flags are re-applied over them
+	if cfg.Preset != "" {
+		if err := applyPreset(flag.CommandLine, cfg, cfg.Preset); err != nil {
+			return nil, err
+		}
+	}
+
 	// Handle version flag
 	if cfg.Version {
 		fmt.Println(version.GetVersion())
 		os.Exit(0)
 	}
+	if cfg.ListPortGroups {
+		printPortGroups(os.Stdout)
+		os.Exit(0)
+	}
 
 	// Validate mutually exclusive flags
 	if cfg.UserAgent != "" && cfg.RandomUserAgent {
 		return nil, fmt.Errorf("-ua/--user-agent and -rua/--random-user-agent are mutually exclusive")
 	}
 
+	if cfg.CustomPorts != "" {
+		if _, err := parser.CountPortList(cfg.CustomPorts); err != nil {
+			return nil, fmt.Errorf("-p/--ports: %v", err)
+		}
+	}
+
+	if cfg.InputAuthHeader != "" && !strings.Contains(cfg.InputAuthHeader, ":") {
+		return nil, fmt.Errorf("-input-auth-header must be in \"Name: value\" form")
+	}
+	if cfg.InputMaxSizeValue != "" {
+		size, err := ParseByteSize(cfg.InputMaxSizeValue)
+		if err != nil {
+			return nil, fmt.Errorf("-input-max-size: %v", err)
+		}
+		cfg.InputMaxSize = size
+	}
+
+	switch cfg.InputFormat {
+	case InputFormatPlain, InputFormatHints, InputFormatMasscanJSON, InputFormatNmapGrepable:
+	default:
+		return nil, fmt.Errorf("-input-format must be %q, %q, %q or %q",
+			InputFormatPlain, InputFormatHints, InputFormatMasscanJSON, InputFormatNmapGrepable)
+	}
+
+	if cfg.ExitCodePolicy != ExitCodePolicyAlways && cfg.ExitCodePolicy != ExitCodePolicyOutcome {
+		return nil, fmt.Errorf("-exit-code-policy must be %q or %q", ExitCodePolicyAlways, ExitCodePolicyOutcome)
+	}
+
+	if cfg.MetaKV && cfg.MetaDelim == "" {
+		return nil, fmt.Errorf("-meta-kv requires -meta-delim")
+	}
+
+	if cfg.CertReportDays < 0 {
+		return nil, fmt.Errorf("-cert-report-days must be 0 or greater")
+	}
+
+	if cfg.SQLiteBatch < 1 {
+		return nil, fmt.Errorf("-sqlite-batch must be at least 1")
+	}
+	if cfg.SQLiteRunID != "" && cfg.SQLiteFile == "" {
+		return nil, fmt.Errorf("-sqlite-run-id requires -sqlite")
+	}
+	if strings.IndexFunc(cfg.RunID, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return nil, fmt.Errorf("-run-id must not contain whitespace or control characters")
+	}
+
+	if cfg.MaxTotalProbes < 0 {
+		return nil, fmt.Errorf("-max-total-probes must be 0 (unlimited) or greater")
+	}
+
+	if cfg.StoreFinalBody && !cfg.StoreResponse {
+		return nil, fmt.Errorf("-store-final-body requires -sr/--store-response")
+	}
+	for _, target := range cfg.DoctorTargets {
+		if target == DoctorTargetNone {
+			if len(cfg.DoctorTargets) > 1 {
+				return nil, fmt.Errorf("-doctor-target none cannot be combined with other targets")
+			}
+			continue
+		}
+		if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("-doctor-target %q must be an http:// or https:// URL, or none", target)
+		}
+	}
+	if cfg.StoreLayout != storage.LayoutHostDirs && cfg.StoreLayout != storage.LayoutFlat {
+		return nil, fmt.Errorf("-store-layout must be %s or %s", storage.LayoutHostDirs, storage.LayoutFlat)
+	}
+	if cfg.StoreShardDepth < 0 || cfg.StoreShardDepth > storage.MaxShardDepth {
+		return nil, fmt.Errorf("-store-shard-depth must be between 0 and %d", storage.MaxShardDepth)
+	}
+	if cfg.PinStrict && cfg.PinFile == "" {
+		return nil, fmt.Errorf("-pin-strict requires -pin-file")
+	}
+	if cfg.DDWordlist != "" && !cfg.DDExpandWildcards {
+		return nil, fmt.Errorf("-dd-wordlist requires -dd-expand-wildcards")
+	}
+
+	if cfg.FirstAliveStatus != "" {
+		if _, err := output.ParseStatusRanges(cfg.FirstAliveStatus); err != nil {
+			return nil, fmt.Errorf("-first-alive-status: %v", err)
+		}
+	}
+	aliveStatus, err := output.ParseStatusRanges(cfg.AliveCodes)
+	if err != nil {
+		return nil, fmt.Errorf("-alive-codes: %v", err)
+	}
+	cfg.AliveStatus = aliveStatus
+
 	// Validate numeric constraints
 	if cfg.Concurrency <= 0 {
 		return nil, fmt.Errorf("-c/--concurrency must be greater than 0")
 	}
+	if cfg.AdaptiveConcurrency && (cfg.AdaptiveFloor < 1 || cfg.AdaptiveFloor > cfg.Concurrency) {
+		return nil, fmt.Errorf("-adaptive-floor must be between 1 and -c (%d)", cfg.Concurrency)
+	}
+	if cfg.MaxTLSHandshakes < 0 {
+		return nil, fmt.Errorf("-max-tls-handshakes must be 0 (2× concurrency) or greater")
+	}
+	if cfg.DNSCacheTTL <= 0 && !cfg.NoDNSCache {
+		return nil, fmt.Errorf("-dns-cache-ttl must be greater than 0 (use -no-dns-cache to disable caching)")
+	}
+	if cfg.PrefetchDNS && cfg.PrefetchDNSTimeout <= 0 {
+		return nil, fmt.Errorf("-prefetch-dns-timeout must be greater than 0")
+	}
+	if cfg.DeadlistThreshold <= 0 {
+		return nil, fmt.Errorf("-deadlist-threshold must be greater than 0")
+	}
+	if cfg.DeadlistRetryEvery < 0 {
+		return nil, fmt.Errorf("-deadlist-retry-every must be 0 (never) or greater")
+	}
+	if cfg.MaxTLSHandshakes == 0 {
+		cfg.MaxTLSHandshakes = 2 * cfg.Concurrency
+	}
+	if cfg.MaxTitleLength < 0 {
+		return nil, fmt.Errorf("-max-title-length must be 0 (unlimited) or greater")
+	}
+
+	// Validate default port overrides
+	for _, dp := range []struct{ name, port string }{
+		{"-default-http-port", cfg.DefaultHTTPPort},
+		{"-default-https-port", cfg.DefaultHTTPSPort},
+	} {
+		if n, err := strconv.Atoi(dp.port); err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("%s must be a port number between 1 and 65535, got %q", dp.name, dp.port)
+		}
+	}
+
+	// Parse body size limits (0 = headers only)
+	if cfg.MaxBodySizeValue != "" {
+		size, err := ParseByteSize(cfg.MaxBodySizeValue)
+		if err != nil {
+			return nil, fmt.Errorf("-max-body-size: %v", err)
+		}
+		cfg.MaxBodySize = size
+	}
+	if cfg.MaxBodySizeBinaryValue != "" {
+		size, err := ParseByteSize(cfg.MaxBodySizeBinaryValue)
+		if err != nil {
+			return nil, fmt.Errorf("-max-body-size-binary: %v", err)
+		}
+		cfg.MaxBodySizeBinary = size
+	}
+	if cfg.AttachmentReadLimitValue != "" {
+		size, err := ParseByteSize(cfg.AttachmentReadLimitValue)
+		if err != nil {
+			return nil, fmt.Errorf("-attachment-read-limit: %v", err)
+		}
+		cfg.AttachmentReadLimit = size
+	}
+	if cfg.CompressionMinSizeValue != "" {
+		size, err := ParseByteSize(cfg.CompressionMinSizeValue)
+		if err != nil {
+			return nil, fmt.Errorf("-compression-min-size: %v", err)
+		}
+		cfg.CompressionMinSize = size
+	}
+	if cfg.ReadAheadValue != "" {
+		size, err := ParseByteSize(cfg.ReadAheadValue)
+		if err != nil {
+			return nil, fmt.Errorf("-read-ahead: %v", err)
+		}
+		if size < 1 {
+			return nil, fmt.Errorf("-read-ahead must be at least 1 byte")
+		}
+		cfg.ReadAhead = size
+	}
+
+	disabled, err := ParseAnalysisFeatures(cfg.DisableAnalysis)
+	if err != nil {
+		return nil, fmt.Errorf("-disable-analysis: %v", err)
+	}
+	cfg.DisabledAnalysis = disabled
+
+	if cfg.DebugLogMaxSizeValue != "" {
+		size, err := ParseByteSize(cfg.DebugLogMaxSizeValue)
+		if err != nil {
+			return nil, fmt.Errorf("-debug-log-max-size: %v", err)
+		}
+		cfg.DebugLogMaxSize = size
+	}
+	if cfg.MaxDebugSizeValue != "" {
+		size, err := ParseByteSize(cfg.MaxDebugSizeValue)
+		if err != nil {
+			return nil, fmt.Errorf("-max-debug-size: %v", err)
+		}
+		cfg.MaxDebugSize = size
+	}
+	if cfg.DebugLogBackups < 0 {
+		return nil, fmt.Errorf("-debug-log-backups must be 0 or greater")
+	}
+	if cfg.HealthIntervalValue != "" {
+		interval, err := time.ParseDuration(cfg.HealthIntervalValue)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("-health-interval must be a positive duration (e.g. 30s)")
+		}
+		cfg.HealthInterval = interval
+	}
+
+	if cfg.HTTP3TimeoutValue != "" {
+		timeout, err := time.ParseDuration(cfg.HTTP3TimeoutValue)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("-http3-timeout must be a positive duration (e.g. 3s)")
+		}
+		cfg.HTTP3Timeout = timeout
+	}
 
-	// --extract-tls-chain implies --extract-tls
-	if cfg.ExtractTLSChain {
+	if cfg.HedgeValue != "" {
+		delay, err := time.ParseDuration(cfg.HedgeValue)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("-hedge must be a positive duration (e.g. 2s)")
+		}
+		cfg.Hedge = delay
+	}
+
+	// Build the scope exclusion matcher
+	excludes := cfg.Excludes
+	if cfg.ExcludeFile != "" {
+		entries, err := scope.LoadFile(cfg.ExcludeFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read exclude file: %v", err)
+		}
+		excludes = append(append([]string(nil), excludes...), entries...)
+	}
+	matcher, err := scope.NewMatcher(excludes)
+	if err != nil {
+		return nil, fmt.Errorf("-exclude: %v", err)
+	}
+	if !matcher.Empty() {
+		cfg.ExcludeMatcher = matcher
+	}
+
+	// An empty scope file allows nothing rather than everything
+	if cfg.ScopeFile != "" {
+		entries, err := scope.LoadFile(cfg.ScopeFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read scope file: %v", err)
+		}
+		allowed, err := scope.NewScope(entries, cfg.ExcludeMatcher)
+		if err != nil {
+			return nil, fmt.Errorf("-scope-file: %v", err)
+		}
+		cfg.Scope = allowed
+	}
+
+	if cfg.Shard != "" {
+		shard, err := scope.ParseShard(cfg.Shard)
+		if err != nil {
+			return nil, fmt.Errorf("-shard: %v", err)
+		}
+		cfg.ShardSpec = shard
+	}
+
+	if err := loadClientCert(cfg); err != nil {
+		return nil, err
+	}
+
+	// Merge keywords from flag and file
+	keywords, err := loadKeywords(cfg.Keywords, cfg.KeywordsFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.KeywordList = keywords
+
+	if cfg.DDWordlist != "" {
+		labels, err := loadWildcardLabels(cfg.DDWordlist)
+		if err != nil {
+			return nil, fmt.Errorf("-dd-wordlist: %v", err)
+		}
+		cfg.DDWordlistLabels = labels
+	}
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil || (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Host == "" {
+			return nil, fmt.Errorf("-proxy must be an http:// or https:// URL")
+		}
+		// Neither -vhost-list pinning nor the -http10 raw request dial
+		// through the proxy
+		if cfg.VhostList != "" {
+			return nil, fmt.Errorf("-vhost-list cannot be combined with -proxy")
+		}
+		if cfg.HTTP10Fallback {
+			return nil, fmt.Errorf("-http10 cannot be combined with -proxy")
+		}
+		cfg.ProxyURL = proxyURL
+		// QUIC can't be tunneled through an HTTP proxy
+		cfg.DisableHTTP3 = true
+	}
+	if cfg.ProxyErrorSignature != "" && cfg.Proxy == "" {
+		return nil, fmt.Errorf("-proxy-error-signature requires -proxy")
+	}
+
+	if cfg.VhostList != "" {
+		hosts, err := loadVhostHosts(cfg.VhostList)
+		if err != nil {
+			return nil, fmt.Errorf("-vhost-list: %v", err)
+		}
+		cfg.VhostHosts = hosts
+		// Pooled QUIC connections are keyed by hostname, not by the IP a
+		// vhost probe is pinned to
+		cfg.DisableHTTP3 = true
+	}
+
+	if cfg.ParkedSignaturesFile != "" {
+		classifier, err := loadPageClassifier(cfg.ParkedSignaturesFile)
+		if err != nil {
+			return nil, fmt.Errorf("-parked-signatures-file: %v", err)
+		}
+		cfg.PageClassifier = classifier
+	}
+
+	// -dd-expand-wildcards implies -dd
+	if cfg.DDExpandWildcards {
+		cfg.DiscoverDomains = true
+	}
+
+	// -ptr-always implies -ptr
+	if cfg.ReverseDNSAlways {
+		cfg.ReverseDNS = true
+	}
+
+	// --extract-tls-chain, -xtls-per-hop and -cert-report imply --extract-tls
+	if cfg.ExtractTLSChain || cfg.ExtractTLSHops || cfg.CertReport != "" {
 		cfg.ExtractTLS = true
 	}
 
@@ -131,14 +656,15 @@ func ParseFlags() (*Config, error) {
 		Level: logLevel,
 	}))
 
-	// Set up debug file logger if specified
+	// Set up debug file logger if specified. An unwritable path fails startup
+	// rather than silently probing without debug logs.
 	if cfg.DebugLogFile != "" {
-		debugFile, err := os.Create(cfg.DebugLogFile)
+		debugFile, err := newRotatingWriter(cfg.DebugLogFile, cfg.DebugLogMaxSize, cfg.DebugLogBackups)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create debug log file: %v", err)
+			return nil, fmt.Errorf("failed to open debug log file: %v", err)
 		}
 		cfg.debugFileHandle = debugFile // Track handle for cleanup
-		cfg.DebugLogger = slog.New(slog.NewTextHandler(debugFile, &slog.HandlerOptions{
+		cfg.DebugLogger = slog.New(slog.NewJSONHandler(debugFile, &slog.HandlerOptions{
 			Level: slog.LevelDebug,
 		}))
 		cfg.Logger.Info("debug logging enabled", "file", cfg.DebugLogFile)
@@ -147,10 +673,166 @@ func ParseFlags() (*Config, error) {
 	return cfg, nil
 }
 
+// loadKeywords merges a comma-separated keyword list with the lines of an
+// optional keywords file, skipping blanks and # comments.
+func loadKeywords(list, file string) ([]string, error) {
+	var keywords []string
+	for _, kw := range strings.Split(list, ",") {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			keywords = append(keywords, kw)
+		}
+	}
+	if file == "" {
+		return keywords, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keywords file: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			keywords = append(keywords, line)
+		}
+	}
+	return keywords, nil
+}
+
+// loadWildcardLabels reads one subdomain label per line for
+// -dd-wordlist, lowercased and without duplicates. Empty lines and lines
+// starting with # are skipped.
+func loadWildcardLabels(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var labels []string
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		label := strings.ToLower(strings.TrimSpace(line))
+		if label == "" || strings.HasPrefix(label, "#") || seen[label] {
+			continue
+		}
+		if !isDNSLabel(label) {
+			return nil, fmt.Errorf("line %d: invalid subdomain label %q", i+1, label)
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("no labels in %s", file)
+	}
+	return labels, nil
+}
+
+// loadVhostHosts reads one candidate hostname per line for -vhost-list,
+// lowercased, without a trailing dot and without duplicates. Empty lines and
+// lines starting with # are skipped.
+func loadVhostHosts(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []string
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		host := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(line)), ".")
+		if host == "" || strings.HasPrefix(host, "#") || seen[host] {
+			continue
+		}
+		if !isHostname(host) {
+			return nil, fmt.Errorf("line %d: invalid hostname %q", i+1, host)
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hostnames in %s", file)
+	}
+	return hosts, nil
+}
+
+// isHostname reports whether s is a lowercase DNS name such as
+// "intranet.example.com"; single labels ("jenkins") are accepted since
+// internal vhosts often have them.
+func isHostname(s string) bool {
+	if len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if !isDNSLabel(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDNSLabel reports whether s is a single lowercase hostname label: 1-63
+// letters, digits and inner hyphens.
+func isDNSLabel(s string) bool {
+	if len(s) == 0 || len(s) > 63 || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// loadPageClassifier builds a Classifier from the built-in signatures and
+// those in file.
+func loadPageClassifier(file string) (*fingerprint.Classifier, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sigs, err := fingerprint.ParseSignatures(f)
+	if err != nil {
+		return nil, err
+	}
+	return fingerprint.New(sigs)
+}
+
+// printPortGroups writes each named port group with its size, description
+// and ports, for -list-port-groups.
+func printPortGroups(w io.Writer) {
+	for _, group := range parser.PortGroups() {
+		n, _ := parser.CountPortList(group.Ports)
+		fmt.Fprintf(w, "@%-12s %5d ports  %s\n", group.Name, n, group.Description)
+		fmt.Fprintf(w, "    %s\n", group.Ports)
+	}
+}
+
+// BinaryBodyLimit returns the body limit for non-text content types:
+// MaxBodySizeBinary, or MaxBodySize while that is unset.
+func (c *Config) BinaryBodyLimit() int64 {
+	if c.MaxBodySizeBinary < 0 {
+		return c.MaxBodySize
+	}
+	return c.MaxBodySizeBinary
+}
+
+// Redacted returns a copy of c for the run manifest, without the
+// credentials of URLs: the userinfo of -proxy and of a remote -i list.
+// InputAuthHeader is never serialized.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Proxy = parser.StripUserinfo(c.Proxy)
+	if strings.Contains(c.InputFile, "://") {
+		redacted.InputFile = parser.StripUserinfo(c.InputFile)
+	}
+	return &redacted
+}
+
 // Close cleans up the config's resources
 func (c *Config) Close() error {
 	if c.debugFileHandle != nil {
-		return c.debugFileHandle.Close()
+		err := c.debugFileHandle.Close()
+		c.debugFileHandle = nil
+		return err
 	}
 	return nil
 }
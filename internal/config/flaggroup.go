@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"strings"
 	"text/tabwriter"
 )
 
@@ -14,6 +15,7 @@ const (
 	BoolType FlagType = iota
 	StringType
 	IntType
+	StringSliceType
 )
 
 // FlagDef holds metadata for a single flag (short + long names, type, default, description)
@@ -89,6 +91,39 @@ func addIntFlag(group *FlagGroup, p *int, short, long string, value int, usage s
 	})
 }
 
+// stringSliceValue is a flag.Value that collects every occurrence of a repeatable flag
+type stringSliceValue struct {
+	p *[]string
+}
+
+func (s stringSliceValue) String() string {
+	if s.p == nil {
+		return ""
+	}
+	return strings.Join(*s.p, ",")
+}
+
+func (s stringSliceValue) Set(value string) error {
+	*s.p = append(*s.p, value)
+	return nil
+}
+
+// addStringSliceFlag registers a repeatable string flag with both short and long names and appends it to the group
+func addStringSliceFlag(group *FlagGroup, p *[]string, short, long string, usage string) {
+	if short != "" {
+		flag.Var(stringSliceValue{p}, short, usage)
+	}
+	if long != "" {
+		flag.Var(stringSliceValue{p}, long, usage)
+	}
+	group.Flags = append(group.Flags, FlagDef{
+		Short:       short,
+		Long:        long,
+		Type:        StringSliceType,
+		Description: usage,
+	})
+}
+
 // RegisterFlags creates all flag groups, registers every flag with the standard flag package,
 // and returns a populated HelpFormatter.
 func RegisterFlags(cfg *Config) *HelpFormatter {
@@ -99,7 +134,24 @@ func RegisterFlags(cfg *Config) *HelpFormatter {
 
 	// INPUT
 	input := &FlagGroup{Name: "INPUT"}
-	addStringFlag(input, &cfg.InputFile, "i", "input", "", "Input file (default: stdin)")
+	addStringFlag(input, &cfg.InputFile, "i", "input", "", "Input file or http(s) URL (default: stdin)")
+	addStringFlag(input, &cfg.InputAuthHeader, "", "input-auth-header", "", "Header sent when fetching a remote input URL (\"Name: value\"), not on redirects to other hosts")
+	addStringFlag(input, &cfg.InputMaxSizeValue, "", "input-max-size", "50m", "Maximum size of a remote input list")
+	addBoolFlag(input, &cfg.InputUseProbeClient, "", "input-use-probe-client", false, "Fetch remote input with the probing client (honours -insecure etc.)")
+	addStringSliceFlag(input, &cfg.Excludes, "", "exclude", "Exclude a host, *.glob, CIDR or URL prefix from probing and redirects (repeatable)")
+	addStringFlag(input, &cfg.ExcludeFile, "", "exclude-file", "", "File with exclusions (one host, glob, CIDR or URL prefix per line)")
+	addStringFlag(input, &cfg.ScopeFile, "", "scope-file", "", "Only probe and follow redirects to targets in this allowlist (apex domains, globs, CIDRs; -exclude still wins)")
+	addStringFlag(input, &cfg.InputFormat, "", "input-format", InputFormatPlain, "Input format: plain, hints for \"host:port [https]\" lines with a scheme hint, or masscan-json/nmap-grepable scan output")
+	addBoolFlag(input, &cfg.UseURLCredentials, "", "use-url-credentials", false, "Send user:pass@ from input URLs as Basic Authorization to that target only (always stripped from URLs and output)")
+	addBoolFlag(input, &cfg.NormalizePath, "", "normalize-path", false, "Collapse duplicate slashes in input paths (example.com//a -> example.com/a)")
+	addStringFlag(input, &cfg.MetaDelim, "", "meta-delim", "", "Delimiter after which input line text is passed through to the meta field")
+	addBoolFlag(input, &cfg.MetaKV, "", "meta-kv", false, "Parse k=v annotations into a meta object (requires -meta-delim)")
+	addIntFlag(input, &cfg.MaxTotalProbes, "", "max-total-probes", 5000000, "Abort if inputs expand to more probes than this (schemes × ports × path variants per input, plus -vhost-list candidates of IP targets; 0 = unlimited)")
+	addStringFlag(input, &cfg.DeadlistFile, "", "deadlist", "", "TSV of hosts failing at the connection level across runs; hosts past -deadlist-threshold are skipped as skipped_deadlisted")
+	addIntFlag(input, &cfg.DeadlistThreshold, "", "deadlist-threshold", 3, "Skip -deadlist hosts after this many consecutive runs with only connection failures")
+	addIntFlag(input, &cfg.DeadlistRetryEvery, "", "deadlist-retry-every", 0, "Probe skipped -deadlist hosts again once every N runs (0 = never)")
+	addStringFlag(input, &cfg.Shard, "", "shard", "", "Probe only shard i of n (i/n, e.g. 2/5) of the deduplicated URLs, for splitting a scan across machines")
+	addBoolFlag(input, &cfg.Force, "", "force", false, "Continue past -max-total-probes with a warning")
 	formatter.Groups = append(formatter.Groups, input)
 
 	// OUTPUT
@@ -107,45 +159,129 @@ func RegisterFlags(cfg *Config) *HelpFormatter {
 	addStringFlag(output, &cfg.OutputFile, "o", "output", "", "Output file (default: stdout)")
 	addBoolFlag(output, &cfg.StoreResponse, "sr", "store-response", false, "Store HTTP responses to output directory")
 	addStringFlag(output, &cfg.StoreResponseDir, "srd", "store-response-dir", "output", "Directory to store HTTP responses")
+	addStringFlag(output, &cfg.StoreLayout, "", "store-layout", "host-dirs", "Arrangement of stored responses: host-dirs ({host}/{hash}.txt) or flat ({hash}.txt, hosts kept in the index)")
+	addIntFlag(output, &cfg.StoreShardDepth, "", "store-shard-depth", 0, "Levels of hash-prefix directories under the layout's directories (e.g. 2: ab/cd/abcd...txt)")
+	addBoolFlag(output, &cfg.StoreFinalBody, "", "store-final-body", false, "Also store the final response body as {hash}.body.{html,json,txt,bin} (requires -sr)")
 	addBoolFlag(output, &cfg.IncludeResponseHeader, "irh", "include-response-header", false, "Include response headers in JSON output")
 	addBoolFlag(output, &cfg.IncludeResponse, "irr", "include-response", false, "Include full request/response in JSON output")
+	addBoolFlag(output, &cfg.NoTimestamp, "", "no-timestamp", false, "Omit the timestamp, started_at and completed_at fields and a generated run_id from results (for diffing reruns)")
+	addIntFlag(output, &cfg.MaxTitleLength, "", "max-title-length", 300, "Maximum title length in runes before truncation (0 = unlimited)")
+	addBoolFlag(output, &cfg.TitleFallback, "", "title-fallback", false, "Title untitled pages after their first <h1>, the final path's file name or the host (title_source h1, path, host)")
+	addStringSliceFlag(output, &cfg.Routes, "", "route", "Write results matching expr to a file, as \"field:value:path\" (fields: status, error_type, scheme, cdn)")
+	addBoolFlag(output, &cfg.Stats, "", "stats", false, "Print a run summary with counts and bytes transferred (top hosts) to stderr")
+	addBoolFlag(output, &cfg.CorrelateSchemes, "", "correlate-schemes", false, "Mark http/https results of the same target as siblings and flag converged final URLs (holds results until their sibling arrives)")
+	addStringFlag(output, &cfg.RunID, "", "run-id", "", "ID recorded as run_id in every result, the manifest, the storage index and the debug log (default: random UUID)")
+	addStringFlag(output, &cfg.Manifest, "", "manifest", "", "Write a JSON run manifest (config, input checksum, counts, timing) to file")
+	addStringFlag(output, &cfg.HostProfiles, "", "host-profiles", "", "Write one JSON line per hostname aggregating ports, titles, tech, domains and certificates across its probes")
+	addStringFlag(output, &cfg.OpenMetrics, "", "openmetrics", "", "Write OpenMetrics gauges per target (up, status, duration, cert expiry) to file at the end of the run")
+	addBoolFlag(output, &cfg.OpenMetricsHostOnly, "", "openmetrics-host-only", false, "Drop the url label from -openmetrics samples (host, scheme and port only) to bound cardinality")
+	addStringFlag(output, &cfg.CertReport, "", "cert-report", "", "Write expired, soon-expiring and self-signed certificates with their hosts to file at the end of the run (implies -xtls)")
+	addIntFlag(output, &cfg.CertReportDays, "", "cert-report-days", 30, "Days ahead -cert-report counts a certificate as expiring soon")
+	addStringFlag(output, &cfg.AliveOutput, "", "alive-output", "", "Write the sorted, deduplicated scheme://host[:port] of every live probed URL to file at the end of the run")
+	addStringFlag(output, &cfg.AliveCodes, "", "alive-codes", DefaultAliveCodes, "Status classes/codes that count as live: -alive-output, -first-alive, host profiles, the console summary and -exit-code-policy outcome (e.g. 2xx,401)")
+	addStringFlag(output, &cfg.SQLiteFile, "", "sqlite", "", "Also write every result to a SQLite database (probes, chains and certificates tables) for ad-hoc queries")
+	addStringFlag(output, &cfg.SQLiteRunID, "", "sqlite-run-id", "", "Run ID of -sqlite rows; a rerun with the same ID replaces its rows (default: -run-id)")
+	addIntFlag(output, &cfg.SQLiteBatch, "", "sqlite-batch", 500, "Results written per -sqlite transaction")
+	addStringFlag(output, &cfg.ExitCodePolicy, "", "exit-code-policy", ExitCodePolicyAlways, "Exit code policy: always (0 on completion) or outcome (0 = something alive, 2 = nothing reachable, 3 = interrupted)")
 	formatter.Groups = append(formatter.Groups, output)
 
 	// PROBES
 	probes := &FlagGroup{Name: "PROBES"}
 	addBoolFlag(probes, &cfg.ResolveIP, "rip", "resolve-ip", false, "Resolve and include IP address in output")
 	addBoolFlag(probes, &cfg.DetectHSTS, "hsts", "detect-hsts", false, "Detect and report HSTS headers")
+	addBoolFlag(probes, &cfg.CSP, "", "csp", false, "Report Content-Security-Policy(-Report-Only) presence, directives, unsafe script sources and domains")
 	addBoolFlag(probes, &cfg.TechDetect, "td", "tech-detect", false, "Enable technology detection using wappalyzer")
 	addBoolFlag(probes, &cfg.DetectCDN, "cdn", "detect-cdn", false, "Detect CDN from response headers")
+	addBoolFlag(probes, &cfg.CacheInfo, "", "cache-info", false, "Report cache status (hit/miss/dynamic), Age and Via of the final response")
+	addBoolFlag(probes, &cfg.CompressionInfo, "", "compression-info", false, "Report content_encoding and compression_assessment: wasteful for encoded images, video and archives, missing for large unencoded text")
+	addStringFlag(probes, &cfg.CompressionMinSizeValue, "", "compression-min-size", "50k", "Size from which an unencoded text response is assessed as missing compression (-compression-info)")
+	addBoolFlag(probes, &cfg.CookieInfo, "", "cookie-info", false, "Report Secure/HttpOnly/SameSite of cookies set along the redirect chain (values omitted)")
+	addBoolFlag(probes, &cfg.MethodsCheck, "", "methods-check", false, "Send OPTIONS to the final URL and report allowed_methods and cors_allow_methods")
+	addBoolFlag(probes, &cfg.CORSCheck, "", "cors-check", false, "Send a CORS preflight from a foreign origin and report cors_allow_methods and cors_allow_origin")
+	addBoolFlag(probes, &cfg.DetectWAF, "", "waf-detect", false, "Detect WAFs from response headers, cookies and block pages")
 	addBoolFlag(probes, &cfg.DetectCNAME, "cname", "detect-cname", false, "Resolve and report CNAME records")
+	addBoolFlag(probes, &cfg.Timing, "", "timing", false, "Report dns/connect/tls/ttfb/transfer timings per result and redirect hop")
+	addBoolFlag(probes, &cfg.JSONCanonicalHash, "", "json-canonical-hash", false, "Add json_canonical_mmh3: hash of JSON bodies with sorted keys and no whitespace")
+	addBoolFlag(probes, &cfg.Forms, "", "forms", false, "Report forms_count, login_form (password input), form_actions and cross_origin_form of HTML pages")
+	addBoolFlag(probes, &cfg.ReverseDNS, "ptr", "reverse-dns", false, "Reverse DNS (PTR) lookup for IP targets")
+	addBoolFlag(probes, &cfg.ReverseDNSAlways, "", "ptr-always", false, "Also perform PTR lookups for hostname targets (implies -ptr)")
+	addStringFlag(probes, &cfg.Keywords, "kw", "keywords", "", "Comma-separated keywords to match in response bodies (case-insensitive)")
+	addStringFlag(probes, &cfg.KeywordsFile, "", "keywords-file", "", "File with keywords to match in response bodies (one per line)")
+	addStringFlag(probes, &cfg.ParkedSignaturesFile, "", "parked-signatures-file", "", "File with extra page_category signatures, one \"category kind value\" per line (kind: title, title-regex, body, hash)")
 	addBoolFlag(probes, &cfg.ExtractTLS, "xtls", "extract-tls", false, "Extract TLS certificate details (subject, SANs, issuer, validity)")
 	addBoolFlag(probes, &cfg.ExtractTLSChain, "", "extract-tls-chain", false, "Include intermediate certificate chain (implies --extract-tls)")
+	addBoolFlag(probes, &cfg.ExtractTLSHops, "", "xtls-per-hop", false, "Capture the leaf certificate of every HTTPS redirect hop as chain_certificates (implies --extract-tls)")
+	addStringFlag(probes, &cfg.PinFile, "", "pin-file", "", "File of \"host sha256:fingerprint\" lines; HTTPS probes of listed hosts report pin_match")
+	addBoolFlag(probes, &cfg.PinStrict, "", "pin-strict", false, "Fail probes whose certificate matches none of the host's -pin-file pins")
 	addBoolFlag(probes, &cfg.DiscoverDomains, "dd", "discover-domains", false, "Discover domains from certificate SANs/CN and CSP headers")
+	addBoolFlag(probes, &cfg.DDExpandWildcards, "", "dd-expand-wildcards", false, "Add common subdomains of wildcard SANs (*.example.com) to new_domains with source wildcard-expansion (implies -dd)")
+	addStringFlag(probes, &cfg.DDWordlist, "", "dd-wordlist", "", "File of subdomain labels for -dd-expand-wildcards, one per line (default: built-in list)")
+	addStringFlag(probes, &cfg.VhostList, "", "vhost-list", "", "File of candidate hostnames, one per line, each sent as Host and SNI to every IP target; responses unlike the IP's own are marked vhost_distinct (disables HTTP/3)")
 	formatter.Groups = append(formatter.Groups, probes)
 
 	// CONFIGURATION
 	configuration := &FlagGroup{Name: "CONFIGURATION"}
+	addStringFlag(configuration, &cfg.Preset, "", "preset", "", "Apply a flag bundle: fast, thorough or stealth (explicit flags override it)")
 	addBoolFlag(configuration, &cfg.FollowRedirects, "fr", "follow-redirects", true, "Follow redirects")
 	addIntFlag(configuration, &cfg.MaxRedirects, "maxr", "max-redirects", 10, "Max redirects")
+	addBoolFlag(configuration, &cfg.Analyze3xxBody, "", "analyze-3xx-body", false, "Extract title/hash from bodies of redirects without a Location header")
+	addBoolFlag(configuration, &cfg.AnalyzeBestHop, "", "analyze-best-hop", false, "Extract title/hash of the best hop (best_hop) when a redirect chain ends in 4xx/5xx; reads every hop's body")
 	addBoolFlag(configuration, &cfg.SameHostOnly, "sho", "same-host-only", false, "Only follow redirects to same hostname")
+	addBoolFlag(configuration, &cfg.SameOriginOnly, "", "same-origin-only", false, "Only follow redirects that keep scheme, host and port (stricter than -sho)")
+	addBoolFlag(configuration, &cfg.SendReferer, "", "send-referer", false, "Send the previous hop's URL as Referer on redirects, reduced per Referrer-Policy (never https to http)")
+	addBoolFlag(configuration, &cfg.FirstAlive, "", "first-alive", false, "Stop probing an input's remaining URLs once one of them answers")
+	addStringFlag(configuration, &cfg.FirstAliveStatus, "", "first-alive-status", "", "Status classes/codes that count as live for -first-alive (default: -alive-codes)")
+	addBoolFlag(configuration, &cfg.SuppressSkipped, "", "suppress-skipped", false, "Omit URLs skipped by -first-alive from output")
 	addBoolFlag(configuration, &cfg.AllSchemes, "as", "all-schemes", false, "Test both HTTP and HTTPS schemes")
 	addBoolFlag(configuration, &cfg.IgnorePorts, "ip", "ignore-ports", false, "Ignore input ports and test common HTTP/HTTPS ports")
-	addStringFlag(configuration, &cfg.CustomPorts, "p", "ports", "", "Custom port list (comma-separated, supports ranges)")
+	addStringFlag(configuration, &cfg.CustomPorts, "p", "ports", "", "Custom port list (comma-separated, supports ranges and groups like @web-common)")
+	addBoolFlag(configuration, &cfg.ListPortGroups, "", "list-port-groups", false, "List the named port groups usable in -p and exit")
+	addBoolFlag(configuration, &cfg.PathVariants, "", "path-variants", false, "Also probe the trailing-slash-toggled and first-letter-case-toggled variant of each path other than /")
+	addStringFlag(configuration, &cfg.DefaultHTTPPort, "", "default-http-port", "80", "Port assumed for http:// targets without an explicit port")
+	addStringFlag(configuration, &cfg.DefaultHTTPSPort, "", "default-https-port", "443", "Port assumed for https:// targets without an explicit port")
+	addStringFlag(configuration, &cfg.MaxBodySizeValue, "", "max-body-size", "10m", "Maximum response body size to read (e.g. 500k, 2m; 0 = headers only)")
+	addStringFlag(configuration, &cfg.MaxBodySizeBinaryValue, "", "max-body-size-binary", "", "Maximum body size for non-text content types (default: same as -max-body-size)")
+	addStringFlag(configuration, &cfg.AttachmentReadLimitValue, "", "attachment-read-limit", "64k", "Maximum body size read from Content-Disposition: attachment downloads, enough to hash and sniff them (0 = headers only)")
+	addStringFlag(configuration, &cfg.DisableAnalysis, "", "disable-analysis", "", "Comma-separated analysis steps to skip, leaving their fields empty: "+strings.Join(AnalysisFeatureNames(), ", "))
+	addBoolFlag(configuration, &cfg.EarlyExit, "", "early-exit", false, "Stop reading a final body once its title and -keywords are resolved or -read-ahead bytes were read (ignored with -sr)")
+	addStringFlag(configuration, &cfg.ReadAheadValue, "", "read-ahead", "256k", "Most body bytes read with -early-exit")
 	addBoolFlag(configuration, &cfg.InsecureSkipVerify, "k", "insecure", false, "Skip TLS certificate verification")
+	addStringFlag(configuration, &cfg.ClientCert, "", "client-cert", "", "PEM client certificate for servers requiring mTLS")
+	addStringFlag(configuration, &cfg.ClientKey, "", "client-key", "", "PEM private key for -client-cert")
+	addStringFlag(configuration, &cfg.ClientCA, "", "client-ca", "", "PEM CA bundle trusted in addition to system roots")
+	addStringFlag(configuration, &cfg.ClientCertHosts, "", "client-cert-hosts", "", "Only present the client cert to hosts matching these globs (comma-separated, e.g. *.corp.example)")
 	addBoolFlag(configuration, &cfg.AllowPrivateIPs, "", "allow-private", false, "Allow scanning private IP addresses")
 	addStringFlag(configuration, &cfg.UserAgent, "ua", "user-agent", "", "Custom User-Agent header")
 	addBoolFlag(configuration, &cfg.RandomUserAgent, "rua", "random-user-agent", false, "Use random User-Agent from pool")
+	addStringFlag(configuration, &cfg.Accept, "", "accept", "", "Accept header to send (e.g. application/json; JSON-shaped bodies are then analyzed as JSON)")
+	addStringFlag(configuration, &cfg.AcceptLanguage, "", "accept-language", "", "Accept-Language header to send (default: en-US,en;q=0.9)")
+	addBoolFlag(configuration, &cfg.RandomizeHeaders, "", "randomize-headers", false, "Send Accept, Accept-Language and browser headers matching the User-Agent's browser family, varied per probe")
 	addBoolFlag(configuration, &cfg.DisableHTTP3, "", "disable-http3", false, "Disable HTTP/3 (QUIC) support")
+	addBoolFlag(configuration, &cfg.HTTP10Fallback, "", "http10", false, "Retry http:// targets that fail with malformed responses using a raw HTTP/1.0 request")
+	addBoolFlag(configuration, &cfg.Coalesce, "", "coalesce", false, "Probe a normalized URL once while it is in flight; concurrent duplicates get a copy marked coalesced")
+	addBoolFlag(configuration, &cfg.SchemeFallback, "", "scheme-fallback", false, "Retry a host:port once with the opposite scheme when it speaks TLS to http:// or plain HTTP to https://")
+	addStringFlag(configuration, &cfg.Proxy, "", "proxy", "", "Send every probe through this http:// or https:// proxy (userinfo for Proxy-Authorization); its own failures are reported as proxy_error (disables HTTP/3)")
+	addStringFlag(configuration, &cfg.ProxyErrorSignature, "", "proxy-error-signature", "", "Text in the body or headers of the proxy's own error pages; 502/504 responses containing it are proxy_error")
 	formatter.Groups = append(formatter.Groups, configuration)
 
 	// RATE-LIMIT
 	rateLimit := &FlagGroup{Name: "RATE-LIMIT"}
 	addIntFlag(rateLimit, &cfg.Timeout, "t", "timeout", 10, "Request timeout in seconds")
 	addIntFlag(rateLimit, &cfg.Concurrency, "c", "concurrency", 20, "Concurrent requests")
+	addBoolFlag(rateLimit, &cfg.AdaptiveConcurrency, "", "adaptive-concurrency", false, "Start at -adaptive-floor and raise or lower the probes running at once up to -c from the recent timeout/reset rate and median latency")
+	addIntFlag(rateLimit, &cfg.AdaptiveFloor, "", "adaptive-floor", 5, "Starting and lowest concurrency of -adaptive-concurrency")
 	addIntFlag(rateLimit, &cfg.TLSHandshakeTimeout, "tls-timeout", "tls-handshake-timeout", 10, "TLS handshake timeout in seconds")
+	addStringFlag(rateLimit, &cfg.HTTP3TimeoutValue, "", "http3-timeout", "3s", "Timeout of the HTTP/3 (QUIC) attempt; hosts whose attempt times out skip HTTP/3 for the rest of the run")
+	addIntFlag(rateLimit, &cfg.MaxTLSHandshakes, "", "max-tls-handshakes", 0, "Maximum concurrent TLS handshakes across all workers (0 = 2× concurrency)")
+	addStringFlag(rateLimit, &cfg.HedgeValue, "", "hedge", "", "Send one duplicate request when a probe has no response headers after this delay (e.g. 2s); first answer wins")
 	addIntFlag(rateLimit, &cfg.RateLimitTimeout, "", "rate-limit-timeout", 60, "Rate limit wait timeout in seconds")
 	addIntFlag(rateLimit, &cfg.RateLimitPerHost, "", "rate-limit", 10, "Requests per second per host")
 	addIntFlag(rateLimit, &cfg.RateLimitBurst, "", "rate-burst", 1, "Burst size for rate limiter")
+	addBoolFlag(rateLimit, &cfg.DisableAdaptiveRate, "", "disable-adaptive-rate", false, "Don't halve a host's rate after 429/503 Retry-After responses")
+	addIntFlag(rateLimit, &cfg.DNSCacheTTL, "", "dns-cache-ttl", 60, "Seconds to cache DNS answers shared by all workers (NXDOMAIN: at most 10s)")
+	addBoolFlag(rateLimit, &cfg.NoDNSCache, "", "no-dns-cache", false, "Disable the shared DNS cache")
+	addBoolFlag(rateLimit, &cfg.PrefetchDNS, "", "prefetch-dns", false, "Resolve all hostnames before probing and report URLs of nonexistent hosts as dns_nxdomain without probing them")
+	addIntFlag(rateLimit, &cfg.PrefetchDNSTimeout, "", "prefetch-dns-timeout", 2, "Per-lookup timeout in seconds for -prefetch-dns")
 	addIntFlag(rateLimit, &cfg.MaxRetries, "", "retries", 0, "Maximum number of retries for failed requests")
 	formatter.Groups = append(formatter.Groups, rateLimit)
 
@@ -153,12 +289,20 @@ func RegisterFlags(cfg *Config) *HelpFormatter {
 	debug := &FlagGroup{Name: "DEBUG"}
 	addBoolFlag(debug, &cfg.Debug, "d", "debug", false, "Debug mode (show all requests and responses to stderr)")
 	addBoolFlag(debug, &cfg.Silent, "", "silent", false, "Silent mode (no errors to stderr)")
-	addStringFlag(debug, &cfg.DebugLogFile, "", "debug-log", "", "Write detailed debug logs to file")
+	addStringFlag(debug, &cfg.DebugLogFile, "", "debug-log", "", "Append detailed JSON debug logs to file")
+	addStringFlag(debug, &cfg.DebugLogMaxSizeValue, "", "debug-log-max-size", "0", "Rotate the debug log at this size (e.g. 50m; 0 = no rotation)")
+	addIntFlag(debug, &cfg.DebugLogBackups, "", "debug-log-backups", 3, "Number of rotated debug logs to keep")
+	addBoolFlag(debug, &cfg.DebugOnError, "", "debug-on-error", false, "Attach the request/response debug transcript to failed results as the debug field")
+	addStringFlag(debug, &cfg.MaxDebugSizeValue, "", "max-debug-size", "16k", "Truncate the debug field of -debug-on-error at this size (0 = unlimited)")
+	addBoolFlag(debug, &cfg.PanicFatal, "", "panic-fatal", false, "Crash on a panic while probing instead of reporting it as an internal_panic result (for development)")
+	addStringFlag(debug, &cfg.HealthIntervalValue, "", "health-interval", "", "Log goroutine, connection and in-flight probe counts at this interval (e.g. 30s)")
 	formatter.Groups = append(formatter.Groups, debug)
 
 	// MISCELLANEOUS
 	misc := &FlagGroup{Name: "MISCELLANEOUS"}
 	addBoolFlag(misc, &cfg.Version, "v", "version", false, "Show version information")
+	addBoolFlag(misc, &cfg.Doctor, "", "doctor", false, "Check DNS, egress, TLS strategies, HTTP/3, the open file limit and output paths without probing any input, then exit (1 when a critical check fails)")
+	addStringSliceFlag(misc, &cfg.DoctorTargets, "", "doctor-target", "Endpoint probed by -doctor's network checks (default: "+strings.Join(DefaultDoctorTargets, ", ")+"; none = skip them)")
 	formatter.Groups = append(formatter.Groups, misc)
 
 	return formatter
@@ -207,6 +351,8 @@ func formatFlagType(f FlagDef) string {
 		return " string"
 	case IntType:
 		return " int"
+	case StringSliceType:
+		return " string (repeatable)"
 	default:
 		return ""
 	}
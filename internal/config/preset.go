@@ -0,0 +1,82 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// presets are the named flag bundles selectable with -preset. Each one sets
+// config fields as if the matching flags had been passed; flags given
+// explicitly on the command line still win (see applyPreset).
+var presets = map[string]func(cfg *Config){
+	// fast: a quick liveness sweep
+	"fast": func(cfg *Config) {
+		cfg.Timeout = 5
+		cfg.TLSHandshakeTimeout = 5
+		cfg.MaxRetries = 0
+		cfg.MaxRedirects = 3
+		cfg.Analyze3xxBody = false
+		cfg.DisableHTTP3 = true
+		cfg.MaxBodySizeValue = "256k"
+	},
+	// thorough: every passive probe, with retries and timings
+	"thorough": func(cfg *Config) {
+		cfg.MaxRetries = 2
+		cfg.ResolveIP = true
+		cfg.DetectHSTS = true
+		cfg.TechDetect = true
+		cfg.DetectCDN = true
+		cfg.DetectWAF = true
+		cfg.DetectCNAME = true
+		cfg.ExtractTLS = true
+		cfg.DiscoverDomains = true
+		cfg.Timing = true
+	},
+	// stealth: low and slow, one TLS attempt at a time
+	"stealth": func(cfg *Config) {
+		cfg.Concurrency = 2
+		cfg.RateLimitPerHost = 1
+		cfg.RateLimitBurst = 1
+		cfg.MaxTLSHandshakes = 1
+		// An explicit -ua takes precedence over the random pool
+		cfg.RandomUserAgent = cfg.UserAgent == ""
+	},
+}
+
+// PresetNames returns the names accepted by -preset, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyPreset applies the named preset to cfg after fs has been parsed, then
+// re-applies every flag that was set explicitly so it overrides the preset.
+// Repeatable flags are not re-applied: Set appends, and no preset touches them.
+func applyPreset(fs *flag.FlagSet, cfg *Config, name string) error {
+	preset, ok := presets[name]
+	if !ok {
+		return fmt.Errorf("-preset must be one of %s", strings.Join(PresetNames(), ", "))
+	}
+
+	explicit := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if _, repeatable := f.Value.(stringSliceValue); !repeatable {
+			explicit[f.Name] = f.Value.String()
+		}
+	})
+
+	preset(cfg)
+
+	for name, value := range explicit {
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("re-applying -%s after -preset: %v", name, err)
+		}
+	}
+	return nil
+}
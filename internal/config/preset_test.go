@@ -0,0 +1,95 @@
+package config
+
+import "testing"
+
+func TestParseFlags_Preset(t *testing.T) {
+	withFlagSet(t, []string{"probehttp", "-preset", "fast"}, func() {
+		cfg, err := ParseFlags()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Timeout != 5 || cfg.MaxRedirects != 3 || !cfg.DisableHTTP3 || cfg.MaxBodySize != 256*1024 {
+			t.Errorf("fast preset not applied: timeout=%d max-redirects=%d disable-http3=%v max-body-size=%d",
+				cfg.Timeout, cfg.MaxRedirects, cfg.DisableHTTP3, cfg.MaxBodySize)
+		}
+	})
+	withFlagSet(t, []string{"probehttp", "-preset", "turbo"}, func() {
+		if _, err := ParseFlags(); err == nil {
+			t.Error("expected error for unknown -preset")
+		}
+	})
+}
+
+func TestParseFlags_PresetOverrides(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		check func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "fast timeout overridden",
+			args: []string{"-preset", "fast", "-t", "20", "-max-body-size", "1m"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Timeout != 20 {
+					t.Errorf("Timeout = %d, want explicit 20", cfg.Timeout)
+				}
+				if cfg.MaxBodySize != 1024*1024 {
+					t.Errorf("MaxBodySize = %d, want explicit 1m", cfg.MaxBodySize)
+				}
+				if cfg.MaxRedirects != 3 {
+					t.Errorf("MaxRedirects = %d, want preset 3", cfg.MaxRedirects)
+				}
+			},
+		},
+		{
+			name: "flag before preset still wins",
+			args: []string{"-maxr", "7", "-preset", "fast"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.MaxRedirects != 7 {
+					t.Errorf("MaxRedirects = %d, want explicit 7", cfg.MaxRedirects)
+				}
+			},
+		},
+		{
+			name: "thorough probe turned off",
+			args: []string{"-preset", "thorough", "-dd=false", "-retries", "0"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.DiscoverDomains {
+					t.Error("DiscoverDomains = true, want explicit false")
+				}
+				if cfg.MaxRetries != 0 {
+					t.Errorf("MaxRetries = %d, want explicit 0", cfg.MaxRetries)
+				}
+				if !cfg.ExtractTLS || !cfg.DetectCDN || !cfg.Timing {
+					t.Error("thorough probes not applied")
+				}
+			},
+		},
+		{
+			name: "stealth with explicit user agent",
+			args: []string{"-preset", "stealth", "-ua", "scanner/1.0", "-c", "4"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.RandomUserAgent {
+					t.Error("RandomUserAgent = true, want false with explicit -ua")
+				}
+				if cfg.Concurrency != 4 {
+					t.Errorf("Concurrency = %d, want explicit 4", cfg.Concurrency)
+				}
+				if cfg.RateLimitPerHost != 1 || cfg.MaxTLSHandshakes != 1 {
+					t.Errorf("stealth limits not applied: rate-limit=%d max-tls-handshakes=%d", cfg.RateLimitPerHost, cfg.MaxTLSHandshakes)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withFlagSet(t, append([]string{"probehttp"}, tt.args...), func() {
+				cfg, err := ParseFlags()
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				tt.check(t, cfg)
+			})
+		})
+	}
+}
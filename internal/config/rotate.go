@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingWriter is an append-only file writer with size-based rotation.
+// When a write would push the file past maxSize, the file is rotated to
+// path.1 (shifting older backups to path.2 ... path.N) and a fresh file is
+// started. A maxSize of 0 disables rotation.
+type rotatingWriter struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+// newRotatingWriter opens path for appending, creating it if needed.
+func newRotatingWriter(path string, maxSize int64, backups int) (*rotatingWriter, error) {
+	w := &rotatingWriter{path: path, maxSize: maxSize, backups: backups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *rotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p would exceed the size limit.
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, fmt.Errorf("failed to rotate debug log: %v", err)
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate shifts backups and starts a new file. Must be called with mu held.
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	if w.backups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", w.path, w.backups))
+		for i := w.backups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(w.path); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Close syncs and closes the current file.
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	syncErr := w.file.Sync()
+	err := w.file.Close()
+	w.file = nil
+	if err == nil {
+		err = syncErr
+	}
+	return err
+}
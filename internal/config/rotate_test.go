@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingWriter_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	if err := os.WriteFile(path, []byte("existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := newRotatingWriter(path, 0, 3)
+	if err != nil {
+		t.Fatalf("newRotatingWriter: %v", err)
+	}
+	w.Write([]byte("new\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "existing\nnew\n" {
+		t.Errorf("file = %q, want existing content preserved", data)
+	}
+}
+
+func TestRotatingWriter_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	w, err := newRotatingWriter(path, 10, 2)
+	if err != nil {
+		t.Fatalf("newRotatingWriter: %v", err)
+	}
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	w.Close()
+
+	for file, want := range map[string]string{
+		path:        "dddddddd\n",
+		path + ".1": "cccccccc\n",
+		path + ".2": "bbbbbbbb\n",
+	} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Errorf("read %s: %v", file, err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(file), data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("only 2 backups should be kept")
+	}
+}
+
+func TestRotatingWriter_WriteAfterClose(t *testing.T) {
+	w, err := newRotatingWriter(filepath.Join(t.TempDir(), "debug.log"), 0, 0)
+	if err != nil {
+		t.Fatalf("newRotatingWriter: %v", err)
+	}
+	w.Close()
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Error("expected error writing to closed writer")
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close should be a no-op, got %v", err)
+	}
+}
+
+func TestParseFlags_DebugLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	withFlagSet(t, []string{"probehttp", "-debug-log", path, "-debug-log-max-size", "1k", "-debug-log-backups", "1"}, func() {
+		cfg, err := ParseFlags()
+		if err != nil {
+			t.Fatalf("ParseFlags: %v", err)
+		}
+		cfg.DebugLogger.Debug("probe detail", "url", "http://example.com")
+		if err := cfg.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if cfg.DebugLogMaxSize != 1024 {
+			t.Errorf("DebugLogMaxSize = %d, want 1024", cfg.DebugLogMaxSize)
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), `"msg":"probe detail"`) {
+			t.Errorf("debug log should contain JSON debug record, got %q", data)
+		}
+	})
+}
+
+func TestParseFlags_DebugLogUnwritable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing-dir", "debug.log")
+	withFlagSet(t, []string{"probehttp", "-debug-log", path}, func() {
+		if _, err := ParseFlags(); err == nil {
+			t.Error("expected error for unwritable debug log path")
+		}
+	})
+}
@@ -0,0 +1,263 @@
+// Package deadlist remembers hosts that failed at the connection level in
+// consecutive runs (-deadlist), so scheduled scans of the same scope can
+// skip them instead of waiting on them every run.
+package deadlist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"probeHTTP/internal/output"
+)
+
+// header is the first line of a saved deadlist.
+const header = "# host\tstreak\tskipped\tlast_failure"
+
+// Entry is the state of one deadlisted host.
+type Entry struct {
+	Host        string    // Lowercase hostname or IP
+	Streak      int       // Consecutive probed runs in which every URL failed at the connection level
+	Skipped     int       // Runs skipped since the host was last probed
+	LastFailure time.Time // End of the last run the host failed in
+}
+
+// outcome is what a run learned about a host.
+type outcome int
+
+const (
+	outcomeNone  outcome = iota // only cancelled or skipped URLs
+	outcomeDead                 // every probed URL failed at the connection level
+	outcomeAlive                // at least one URL got past connecting
+)
+
+// List is a loaded deadlist plus what the current run observed. Its
+// methods are safe for concurrent use.
+type List struct {
+	mu       sync.Mutex
+	entries  map[string]*Entry
+	outcomes map[string]outcome
+	skipped  map[string]bool
+}
+
+// New returns an empty list.
+func New() *List {
+	return &List{
+		entries:  make(map[string]*Entry),
+		outcomes: make(map[string]outcome),
+		skipped:  make(map[string]bool),
+	}
+}
+
+// Load reads a deadlist saved by Save. A missing file is an empty list, so
+// the first scheduled run can point at a path that doesn't exist yet.
+func Load(path string) (*List, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deadlist: %w", err)
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Parse reads "host streak skipped last_failure" lines separated by tabs,
+// last_failure in RFC 3339. Empty lines and lines starting with # are
+// skipped; a host listed twice keeps its last line.
+func Parse(r io.Reader) (*List, error) {
+	l := New()
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("line %d: want host, streak, skipped and last_failure separated by tabs", lineNo)
+		}
+		streak, err := strconv.Atoi(fields[1])
+		if err != nil || streak < 1 {
+			return nil, fmt.Errorf("line %d: invalid streak %q", lineNo, fields[1])
+		}
+		skipped, err := strconv.Atoi(fields[2])
+		if err != nil || skipped < 0 {
+			return nil, fmt.Errorf("line %d: invalid skipped count %q", lineNo, fields[2])
+		}
+		lastFailure, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid last_failure %q", lineNo, fields[3])
+		}
+		host := normalizeHost(fields[0])
+		l.entries[host] = &Entry{Host: host, Streak: streak, Skipped: skipped, LastFailure: lastFailure}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Entries returns a copy of the entries, sorted by host.
+func (l *List) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := make([]Entry, 0, len(l.entries))
+	for _, e := range l.entries {
+		entries = append(entries, *e)
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.Host, b.Host) })
+	return entries
+}
+
+// Skip reports whether the URLs of host are skipped this run: its streak
+// has reached threshold and, with retryEvery > 0, this is not its periodic
+// retry run (one probed run per retryEvery runs). Skipped hosts are
+// remembered for Merge.
+func (l *List) Skip(host string, threshold, retryEvery int) bool {
+	host = normalizeHost(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[host]
+	if !ok || e.Streak < threshold {
+		return false
+	}
+	if retryEvery > 0 && e.Skipped+1 >= retryEvery {
+		return false
+	}
+	l.skipped[host] = true
+	return true
+}
+
+// Observe records one probe result of the current run. A host is dead for
+// the run only if all of its results failed at the connection level;
+// cancelled and skipped results say nothing about it.
+func (l *List) Observe(result output.ProbeResult) {
+	host := resultHost(result)
+	if host == "" {
+		return
+	}
+	var o outcome
+	switch {
+	case result.Error == "":
+		o = outcomeAlive
+	case result.ErrorType == output.ErrorTypeCancelled,
+		result.ErrorType == output.ErrorTypeSkippedFirstAlive,
+		result.ErrorType == output.ErrorTypeSkippedDeadlisted:
+		return
+	case ConnectionLevel(result.ErrorType):
+		o = outcomeDead
+	default:
+		o = outcomeAlive
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.outcomes[host] != outcomeAlive {
+		l.outcomes[host] = o
+	}
+}
+
+// Merge folds the observed run into the entries: dead hosts extend their
+// streak and record now as their last failure, hosts that came back alive
+// are removed, and skipped hosts count one more skipped run. The observed
+// state is cleared.
+func (l *List) Merge(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for host := range l.skipped {
+		if e, ok := l.entries[host]; ok {
+			e.Skipped++
+		}
+	}
+	for host, o := range l.outcomes {
+		switch o {
+		case outcomeDead:
+			e, ok := l.entries[host]
+			if !ok {
+				e = &Entry{Host: host}
+				l.entries[host] = e
+			}
+			e.Streak++
+			e.Skipped = 0
+			e.LastFailure = now.UTC()
+		case outcomeAlive:
+			delete(l.entries, host)
+		}
+	}
+	clear(l.outcomes)
+	clear(l.skipped)
+}
+
+// Write writes the entries as a deadlist file.
+func (l *List) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, header)
+	for _, e := range l.Entries() {
+		fmt.Fprintf(bw, "%s\t%d\t%d\t%s\n", e.Host, e.Streak, e.Skipped, e.LastFailure.Format(time.RFC3339))
+	}
+	return bw.Flush()
+}
+
+// Save writes the entries to path through a temporary file in the same
+// directory, so a run reading the deadlist never sees a partial file.
+func (l *List) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write deadlist: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := l.Write(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write deadlist: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write deadlist: %w", err)
+	}
+	// CreateTemp files are private; match the other files a run writes
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write deadlist: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write deadlist: %w", err)
+	}
+	return nil
+}
+
+// ConnectionLevel reports whether errorType means the host never answered:
+// DNS failures, refused or reset connections, timeouts and failed TLS
+// handshakes.
+func ConnectionLevel(errorType string) bool {
+	switch errorType {
+	case output.ErrorTypeDNS, output.ErrorTypeDNSNXDomain, output.ErrorTypeConnectionRefused,
+		output.ErrorTypeConnectionReset, output.ErrorTypeTimeout, output.ErrorTypeTLSHandshake:
+		return true
+	}
+	return false
+}
+
+// resultHost returns the normalized hostname a result probed. Failed
+// probes leave url and host empty but always carry normalized_url.
+func resultHost(result output.ProbeResult) string {
+	for _, rawURL := range []string{result.NormalizedURL, result.URL} {
+		if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+			return normalizeHost(u.Hostname())
+		}
+	}
+	return normalizeHost(result.Host)
+}
+
+// normalizeHost lowercases host and drops a trailing dot.
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
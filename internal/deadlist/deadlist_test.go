@@ -0,0 +1,230 @@
+package deadlist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/output"
+)
+
+var (
+	run1 = time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC)
+	run2 = run1.Add(24 * time.Hour)
+)
+
+// failed is a failed result as the prober reports it: url and host are
+// empty, normalized_url identifies the target.
+func failed(rawURL, errorType string) output.ProbeResult {
+	return output.ProbeResult{NormalizedURL: rawURL, Error: "failed", ErrorType: errorType}
+}
+
+func succeeded(rawURL string) output.ProbeResult {
+	return output.ProbeResult{URL: rawURL, StatusCode: 200}
+}
+
+func entry(t *testing.T, l *List, host string) (Entry, bool) {
+	t.Helper()
+	for _, e := range l.Entries() {
+		if e.Host == host {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+func TestLoad_MissingFileIsEmpty(t *testing.T) {
+	l, err := Load(filepath.Join(t.TempDir(), "deadlist.tsv"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if n := len(l.Entries()); n != 0 {
+		t.Errorf("entries = %d, want 0", n)
+	}
+}
+
+func TestParse(t *testing.T) {
+	l, err := Parse(strings.NewReader(header + "\n\nDead.Example.com.\t4\t1\t2026-10-01T02:00:00Z\n# note\nother.example.com\t1\t0\t2026-09-30T02:00:00Z\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	e, ok := entry(t, l, "dead.example.com")
+	if !ok || e.Streak != 4 || e.Skipped != 1 || !e.LastFailure.Equal(run1) {
+		t.Errorf("dead.example.com = %+v, %v", e, ok)
+	}
+	if n := len(l.Entries()); n != 2 {
+		t.Errorf("entries = %d, want 2", n)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, line := range []string{
+		"dead.example.com 4 1 2026-10-01T02:00:00Z",
+		"dead.example.com\tfour\t1\t2026-10-01T02:00:00Z",
+		"dead.example.com\t0\t1\t2026-10-01T02:00:00Z",
+		"dead.example.com\t4\t-1\t2026-10-01T02:00:00Z",
+		"dead.example.com\t4\t1\tyesterday",
+	} {
+		if _, err := Parse(strings.NewReader(line)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("Parse(%q) error = %v, want a line 1 error", line, err)
+		}
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deadlist.tsv")
+	l := New()
+	l.Observe(failed("https://b.example.com/", output.ErrorTypeTimeout))
+	l.Observe(failed("http://a.example.com:8080/", output.ErrorTypeConnectionRefused))
+	l.Merge(run1)
+	if err := l.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := header + "\na.example.com\t1\t0\t2026-10-01T02:00:00Z\nb.example.com\t1\t0\t2026-10-01T02:00:00Z\n"
+	if string(data) != want {
+		t.Errorf("saved deadlist:\n%s\nwant:\n%s", data, want)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, want := fmt.Sprint(loaded.Entries()), fmt.Sprint(l.Entries()); got != want {
+		t.Errorf("loaded %s, want %s", got, want)
+	}
+	matches, _ := filepath.Glob(path + ".tmp*")
+	if len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestMerge_StreakAndReset(t *testing.T) {
+	l := New()
+	for i := 0; i < 3; i++ {
+		l.Observe(failed("https://dead.example.com/", output.ErrorTypeConnectionRefused))
+		l.Observe(failed("http://dead.example.com/", output.ErrorTypeTimeout))
+		l.Merge(run1.Add(time.Duration(i) * 24 * time.Hour))
+	}
+	e, ok := entry(t, l, "dead.example.com")
+	if !ok || e.Streak != 3 || !e.LastFailure.Equal(run1.Add(48*time.Hour)) {
+		t.Fatalf("after three dead runs: %+v, %v", e, ok)
+	}
+
+	// The host answers again: its streak is gone
+	l.Observe(failed("https://dead.example.com/", output.ErrorTypeConnectionRefused))
+	l.Observe(succeeded("http://dead.example.com/"))
+	l.Merge(run2)
+	if e, ok := entry(t, l, "dead.example.com"); ok {
+		t.Errorf("host that came back alive still listed: %+v", e)
+	}
+}
+
+func TestObserve_Outcomes(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []output.ProbeResult
+		listed  bool
+	}{
+		{"all connection failures", []output.ProbeResult{
+			failed("https://h.example.com/", output.ErrorTypeDNS),
+			failed("https://h.example.com:8443/", output.ErrorTypeTLSHandshake),
+		}, true},
+		{"nxdomain", []output.ProbeResult{failed("https://h.example.com/", output.ErrorTypeDNSNXDomain)}, true},
+		{"one URL answered", []output.ProbeResult{
+			succeeded("https://h.example.com/"),
+			failed("http://h.example.com/", output.ErrorTypeConnectionReset),
+		}, false},
+		{"answered with an error", []output.ProbeResult{failed("https://h.example.com/", output.ErrorTypeBodyRead)}, false},
+		{"cancelled only", []output.ProbeResult{failed("https://h.example.com/", output.ErrorTypeCancelled)}, false},
+		{"cancelled and dead", []output.ProbeResult{
+			failed("https://h.example.com/", output.ErrorTypeCancelled),
+			failed("http://h.example.com/", output.ErrorTypeTimeout),
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New()
+			for _, r := range tt.results {
+				l.Observe(r)
+			}
+			l.Merge(run1)
+			if _, listed := entry(t, l, "h.example.com"); listed != tt.listed {
+				t.Errorf("listed = %v, want %v", listed, tt.listed)
+			}
+		})
+	}
+}
+
+func TestObserve_SkippedKeepsStreak(t *testing.T) {
+	l, _ := Parse(strings.NewReader("dead.example.com\t5\t0\t2026-10-01T02:00:00Z\n"))
+	if !l.Skip("DEAD.example.com", 3, 0) {
+		t.Fatal("host past the threshold not skipped")
+	}
+	l.Observe(failed("https://dead.example.com/", output.ErrorTypeSkippedDeadlisted))
+	l.Merge(run2)
+	e, ok := entry(t, l, "dead.example.com")
+	if !ok || e.Streak != 5 || e.Skipped != 1 || !e.LastFailure.Equal(run1) {
+		t.Errorf("after a skipped run: %+v, %v", e, ok)
+	}
+}
+
+func TestSkip_Threshold(t *testing.T) {
+	l, _ := Parse(strings.NewReader("two.example.com\t2\t0\t2026-10-01T02:00:00Z\nthree.example.com\t3\t0\t2026-10-01T02:00:00Z\n"))
+	if l.Skip("two.example.com", 3, 0) {
+		t.Error("host below the threshold skipped")
+	}
+	if !l.Skip("three.example.com", 3, 0) {
+		t.Error("host at the threshold not skipped")
+	}
+	if l.Skip("unknown.example.com", 3, 0) {
+		t.Error("unlisted host skipped")
+	}
+}
+
+// With -deadlist-retry-every 3 a dead host is skipped twice, then probed.
+func TestSkip_RetryEvery(t *testing.T) {
+	l, _ := Parse(strings.NewReader("dead.example.com\t3\t0\t2026-10-01T02:00:00Z\n"))
+	var probed []int
+	for run := 1; run <= 6; run++ {
+		if !l.Skip("dead.example.com", 3, 3) {
+			probed = append(probed, run)
+			l.Observe(failed("https://dead.example.com/", output.ErrorTypeTimeout))
+		}
+		l.Merge(run1.Add(time.Duration(run) * 24 * time.Hour))
+	}
+	if fmt.Sprint(probed) != "[3 6]" {
+		t.Errorf("probed in runs %v, want [3 6]", probed)
+	}
+	if e, _ := entry(t, l, "dead.example.com"); e.Streak != 5 {
+		t.Errorf("streak = %d, want 5", e.Streak)
+	}
+}
+
+// Run with -race: results of a run arrive from many goroutines.
+func TestObserve_Concurrent(t *testing.T) {
+	l := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			host := fmt.Sprintf("h%d.example.com", i%10)
+			l.Observe(failed("https://"+host+"/", output.ErrorTypeTimeout))
+			l.Skip(host, 1, 0)
+		}(i)
+	}
+	wg.Wait()
+	l.Merge(run1)
+	if n := len(l.Entries()); n != 10 {
+		t.Errorf("entries = %d, want 10", n)
+	}
+}
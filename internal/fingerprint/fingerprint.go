@@ -0,0 +1,227 @@
+package fingerprint
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Page categories of the built-in signatures. User signatures may use any
+// other category name.
+const (
+	CategoryDefaultNginx  = "default-nginx"
+	CategoryDefaultApache = "default-apache"
+	CategoryDefaultIIS    = "default-iis"
+	CategoryDefaultTomcat = "default-tomcat"
+	CategoryDefaultCaddy  = "default-caddy"
+	CategoryCPanelDefault = "cpanel-default"
+	CategoryPleskDefault  = "plesk-default"
+	CategoryParked        = "parked"
+	CategoryErrorPage     = "error-page"
+)
+
+// Signature kinds, as written in a signatures file.
+const (
+	KindTitle      = "title"       // exact title, case-insensitive
+	KindTitleRegex = "title-regex" // regular expression matched against the title
+	KindBody       = "body"        // substring of the body, case-insensitive
+	KindBodyHash   = "hash"        // body_mmh3 of the whole body
+)
+
+// maxClassifyBody bounds how much of a body is searched for body
+// signatures; default and parking pages are small.
+const maxClassifyBody = 64 << 10
+
+// Signature identifies a page category by one property of the page.
+type Signature struct {
+	Category string
+	Kind     string
+	Value    string
+}
+
+// builtinSignatures are default server pages, hosting placeholders,
+// registrar parking pages and bare server error pages.
+var builtinSignatures = []Signature{
+	{CategoryDefaultNginx, KindTitle, "Welcome to nginx!"},
+	{CategoryDefaultNginx, KindTitle, "Welcome to nginx on Debian!"},
+	{CategoryDefaultNginx, KindTitleRegex, `(?i)^Test Page for the Nginx HTTP Server`},
+	{CategoryDefaultApache, KindTitle, "Apache2 Ubuntu Default Page: It works"},
+	{CategoryDefaultApache, KindTitle, "Apache2 Debian Default Page: It works"},
+	{CategoryDefaultApache, KindTitleRegex, `(?i)^(Apache HTTP Server Test Page|Test Page for the Apache HTTP Server)`},
+	{CategoryDefaultApache, KindBodyHash, "2268354357"}, // <html><body><h1>It works!</h1></body></html>
+	{CategoryDefaultIIS, KindTitle, "IIS Windows Server"},
+	{CategoryDefaultIIS, KindTitle, "IIS Windows"},
+	{CategoryDefaultIIS, KindTitle, "IIS7"},
+	{CategoryDefaultIIS, KindTitle, "IIS8"},
+	{CategoryDefaultIIS, KindTitle, "Microsoft Internet Information Services 8"},
+	{CategoryDefaultTomcat, KindTitleRegex, `^Apache Tomcat/\d+(\.\d+)*$`},
+	{CategoryDefaultCaddy, KindTitle, "Caddy works!"},
+	{CategoryCPanelDefault, KindTitle, "Default Web Site Page"},
+	{CategoryCPanelDefault, KindBody, "Future home of something quite cool"},
+	{CategoryPleskDefault, KindTitle, "Default Parallels Plesk Panel Page"},
+	{CategoryPleskDefault, KindTitle, "Default Plesk Page"},
+	{CategoryParked, KindTitleRegex, `(?i)\b(domain( name)?|[a-z0-9-]+\.[a-z]{2,}) (is |may be )?for sale\b`},
+	{CategoryParked, KindTitleRegex, `(?i)^parked (domain|by)\b`},
+	{CategoryParked, KindBody, "sedoparking.com"},
+	{CategoryParked, KindBody, "parkingcrew.net"},
+	{CategoryParked, KindBody, "img1.wsimg.com/parking-lander"},
+	{CategoryParked, KindBody, "bodis.com/parking"},
+	{CategoryParked, KindBody, "This domain is parked free of charge"},
+	{CategoryErrorPage, KindTitleRegex, `^(400|401|403|404|405|500|502|503|504) [A-Z][A-Za-z -]+$`},
+}
+
+type titleRegex struct {
+	category string
+	re       *regexp.Regexp
+}
+
+type bodySubstring struct {
+	category string
+	needle   []byte // lowercased
+}
+
+// Classifier matches pages against signatures, cheapest first: exact titles
+// and body hashes are map lookups, title regexes and body substrings are
+// tried in order after that.
+type Classifier struct {
+	titles  map[string]string // lowercased title -> category
+	hashes  map[string]string // body_mmh3 -> category
+	regexes []titleRegex
+	bodies  []bodySubstring
+}
+
+// defaultClassifier holds the built-in signatures only.
+var defaultClassifier = sync.OnceValue(func() *Classifier {
+	c, err := New(nil)
+	if err != nil {
+		panic(err) // built-in signatures are static
+	}
+	return c
+})
+
+// Default returns the Classifier of the built-in signatures.
+func Default() *Classifier {
+	return defaultClassifier()
+}
+
+// New builds a Classifier from the built-in signatures and extra. Extra
+// signatures win over built-in ones that match the same page.
+func New(extra []Signature) (*Classifier, error) {
+	c := &Classifier{titles: make(map[string]string), hashes: make(map[string]string)}
+	for _, sig := range append(append([]Signature{}, extra...), builtinSignatures...) {
+		if err := validate(sig); err != nil {
+			return nil, err
+		}
+		switch sig.Kind {
+		case KindTitle:
+			key := strings.ToLower(sig.Value)
+			if _, exists := c.titles[key]; !exists {
+				c.titles[key] = sig.Category
+			}
+		case KindBodyHash:
+			if _, exists := c.hashes[sig.Value]; !exists {
+				c.hashes[sig.Value] = sig.Category
+			}
+		case KindTitleRegex:
+			c.regexes = append(c.regexes, titleRegex{category: sig.Category, re: regexp.MustCompile(sig.Value)})
+		case KindBody:
+			c.bodies = append(c.bodies, bodySubstring{category: sig.Category, needle: bytes.ToLower([]byte(sig.Value))})
+		}
+	}
+	return c, nil
+}
+
+// validate checks a signature's kind and, for title-regex, its expression.
+func validate(sig Signature) error {
+	switch sig.Kind {
+	case KindTitle, KindBody, KindBodyHash:
+	case KindTitleRegex:
+		if _, err := regexp.Compile(sig.Value); err != nil {
+			return fmt.Errorf("invalid title regex %q: %v", sig.Value, err)
+		}
+	default:
+		return fmt.Errorf("unknown signature kind %q", sig.Kind)
+	}
+	if sig.Category == "" || sig.Value == "" {
+		return fmt.Errorf("signature needs a category and a value")
+	}
+	return nil
+}
+
+// BodyPrefix returns how many leading body bytes Classify searches for body
+// signatures, or 0 when c has none.
+func (c *Classifier) BodyPrefix() int {
+	if c == nil || len(c.bodies) == 0 {
+		return 0
+	}
+	return maxClassifyBody
+}
+
+// Classify returns the category of a page from its title, body and
+// body_mmh3, or "" when no signature matches. A nil Classifier matches
+// nothing.
+func (c *Classifier) Classify(title string, body []byte, bodyHash string) string {
+	if c == nil {
+		return ""
+	}
+	title = strings.TrimSpace(title)
+	if category, ok := c.titles[strings.ToLower(title)]; ok && title != "" {
+		return category
+	}
+	if category, ok := c.hashes[bodyHash]; ok && bodyHash != "" {
+		return category
+	}
+	if title != "" {
+		for _, r := range c.regexes {
+			if r.re.MatchString(title) {
+				return r.category
+			}
+		}
+	}
+	if len(c.bodies) == 0 || len(body) == 0 {
+		return ""
+	}
+	if len(body) > maxClassifyBody {
+		body = body[:maxClassifyBody]
+	}
+	lower := bytes.ToLower(body)
+	for _, b := range c.bodies {
+		if bytes.Contains(lower, b.needle) {
+			return b.category
+		}
+	}
+	return ""
+}
+
+// ParseSignatures reads a signatures file: one "category kind value" line
+// per signature, where kind is title, title-regex, body or hash and value is
+// the rest of the line. Empty lines and lines starting with # are skipped.
+func ParseSignatures(r io.Reader) ([]Signature, error) {
+	var sigs []Signature
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: want \"category kind value\"", lineNo)
+		}
+		// The value is the rest of the line, inner spacing included
+		rest := strings.TrimSpace(line[len(fields[0]):])
+		sig := Signature{Category: fields[0], Kind: fields[1], Value: strings.TrimSpace(rest[len(fields[1]):])}
+		if err := validate(sig); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		sigs = append(sigs, sig)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sigs, nil
+}
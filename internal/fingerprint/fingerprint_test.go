@@ -0,0 +1,132 @@
+package fingerprint
+
+import (
+	"strings"
+	"testing"
+
+	"probeHTTP/internal/hash"
+)
+
+func TestClassify_DefaultPages(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		body  string
+		want  string
+	}{
+		{"nginx", "Welcome to nginx!", "<h1>Welcome to nginx!</h1><p>If you see this page, the nginx web server is successfully installed", CategoryDefaultNginx},
+		{"nginx rhel", "Test Page for the Nginx HTTP Server on Red Hat Enterprise Linux", "", CategoryDefaultNginx},
+		{"apache ubuntu", "Apache2 Ubuntu Default Page: It works", "", CategoryDefaultApache},
+		{"apache centos", "Apache HTTP Server Test Page powered by CentOS", "", CategoryDefaultApache},
+		{"apache bare", "", "<html><body><h1>It works!</h1></body></html>\n", CategoryDefaultApache},
+		{"iis 10", "IIS Windows Server", `<img src="iisstart.png" alt="IIS" width="960" height="600" />`, CategoryDefaultIIS},
+		{"iis 7", "IIS7", "", CategoryDefaultIIS},
+		{"tomcat", "Apache Tomcat/9.0.65", "", CategoryDefaultTomcat},
+		{"caddy", "Caddy works!", "", CategoryDefaultCaddy},
+		{"cpanel", "", "<h1>Future home of something quite cool.</h1>", CategoryCPanelDefault},
+		{"plesk", "Default Parallels Plesk Panel Page", "", CategoryPleskDefault},
+		{"sedo", "example.com", `<script src="https://www.sedoparking.com/frmpark/example.com/js"></script>`, CategoryParked},
+		{"godaddy", "", `<script src="https://img1.wsimg.com/parking-lander/static/js/main.js"></script>`, CategoryParked},
+		{"hugedomains", "example.com is for sale | HugeDomains", "", CategoryParked},
+		{"domain for sale", "This domain name may be for sale", "", CategoryParked},
+		{"parked", "Parked Domain name on Hostinger DNS system", "", CategoryParked},
+		{"nginx 502", "502 Bad Gateway", "<center><h1>502 Bad Gateway</h1></center><hr><center>nginx</center>", CategoryErrorPage},
+		{"real site", "Example Domain", "<p>This domain is for use in illustrative examples in documents.</p>", ""},
+		{"empty", "", "", ""},
+	}
+
+	classifier := Default()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(tt.body)
+			if got := classifier.Classify(tt.title, body, hash.CalculateMMH3(body)); got != tt.want {
+				t.Errorf("Classify(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassify_TitleCaseInsensitive(t *testing.T) {
+	if got := Default().Classify("  welcome to NGINX!  ", nil, ""); got != CategoryDefaultNginx {
+		t.Errorf("got %q, want %q", got, CategoryDefaultNginx)
+	}
+}
+
+func TestClassify_NilClassifier(t *testing.T) {
+	var c *Classifier
+	if got := c.Classify("Welcome to nginx!", nil, ""); got != "" {
+		t.Errorf("nil classifier matched %q", got)
+	}
+}
+
+func TestClassify_BodyBeyondLimitIgnored(t *testing.T) {
+	body := []byte(strings.Repeat("a", maxClassifyBody) + "sedoparking.com")
+	if got := Default().Classify("", body, ""); got != "" {
+		t.Errorf("got %q, want no match past the first %d bytes", got, maxClassifyBody)
+	}
+}
+
+func TestParseSignatures(t *testing.T) {
+	input := `# custom signatures
+internal-default   title        Company Web Server  Placeholder
+parked             body         parking.example.net
+error-page         title-regex  ^Maintenance
+internal-default   hash         12345
+`
+	sigs, err := ParseSignatures(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sigs) != 4 {
+		t.Fatalf("got %d signatures, want 4: %+v", len(sigs), sigs)
+	}
+	if sigs[0] != (Signature{"internal-default", KindTitle, "Company Web Server  Placeholder"}) {
+		t.Errorf("first signature = %+v", sigs[0])
+	}
+
+	classifier, err := New(sigs)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	tests := []struct {
+		title, body, hash, want string
+	}{
+		{"company web server  placeholder", "", "", "internal-default"},
+		{"", "<a href=https://parking.example.net/>", "", CategoryParked},
+		{"Maintenance window", "", "", CategoryErrorPage},
+		{"", "", "12345", "internal-default"},
+		{"Welcome to nginx!", "", "", CategoryDefaultNginx}, // built-ins still apply
+	}
+	for _, tt := range tests {
+		if got := classifier.Classify(tt.title, []byte(tt.body), tt.hash); got != tt.want {
+			t.Errorf("Classify(%q, %q, %q) = %q, want %q", tt.title, tt.body, tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestParseSignatures_UserOverridesBuiltin(t *testing.T) {
+	sigs, err := ParseSignatures(strings.NewReader("lab-nginx title Welcome to nginx!\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	classifier, err := New(sigs)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got := classifier.Classify("Welcome to nginx!", nil, ""); got != "lab-nginx" {
+		t.Errorf("got %q, want the user category", got)
+	}
+}
+
+func TestParseSignatures_Errors(t *testing.T) {
+	tests := []string{
+		"parked title",
+		"parked footer Buy this domain",
+		"parked title-regex (unclosed",
+	}
+	for _, input := range tests {
+		if _, err := ParseSignatures(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("ParseSignatures(%q) error = %v, want a line 1 error", input, err)
+		}
+	}
+}
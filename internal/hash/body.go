@@ -0,0 +1,169 @@
+package hash
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/twmb/murmur3"
+)
+
+// bodyChunkSize is how much of the body is hashed and histogrammed at a time;
+// small enough that the second look at each chunk stays in cache.
+const bodyChunkSize = 32 * 1024
+
+// maxWordSize mirrors bufio.MaxScanTokenSize: a bufio.ScanWords scanner, which
+// word counts have always been defined by, stops at the first word that
+// doesn't fit its buffer together with the space ending it.
+const maxWordSize = 64 * 1024
+
+// BodyDigest holds the metrics derived from a single pass over a body
+type BodyDigest struct {
+	MMH3    string  // Same value CalculateMMH3 returns
+	Entropy float64 // Shannon entropy of the byte distribution in bits per byte (0-8)
+	Words   int     // With counts: same value parser.CountWordsAndLines returns
+	Lines   int     // With counts: newlines + 1, 0 for an empty body
+}
+
+// CalculateBodyDigest computes the MMH3 hash and the byte entropy of data in
+// one pass. Entropy is rounded to three decimals so output is stable across
+// runs; empty data has an entropy of 0.
+func CalculateBodyDigest(data []byte) BodyDigest {
+	return DigestBody(data, false)
+}
+
+// DigestBody is CalculateBodyDigest that, with counts, also counts the words
+// and lines of a textual body in the same pass.
+func DigestBody(data []byte, counts bool) BodyDigest {
+	return DigestBodyParts(data, true, counts)
+}
+
+// DigestBodyParts is DigestBody that skips the MMH3 hash unless mmh3 is
+// set, leaving MMH3 empty. Entropy is always computed.
+func DigestBodyParts(data []byte, mmh3, counts bool) BodyDigest {
+	var histogram [256]int
+	words := wordCounter{wordStart: -1}
+	h := murmur3.New32()
+	for start := 0; start < len(data); start += bodyChunkSize {
+		end := min(start+bodyChunkSize, len(data))
+		chunk := data[start:end]
+		if mmh3 {
+			h.Write(chunk)
+		}
+		for _, b := range chunk {
+			histogram[b]++
+		}
+		if counts {
+			words.scan(data, end)
+		}
+	}
+
+	digest := BodyDigest{Entropy: shannonEntropy(&histogram, len(data))}
+	if mmh3 {
+		digest.MMH3 = fmt.Sprintf("%d", h.Sum32())
+	}
+	if counts && len(data) > 0 {
+		digest.Words = words.finish(len(data))
+		digest.Lines = histogram['\n'] + 1
+	}
+	return digest
+}
+
+// wordCounter counts runs of non-space runes incrementally, as chunks of
+// the same body are scanned.
+type wordCounter struct {
+	next      int // Offset of the next rune to decode
+	wordStart int // Offset where the current word started, or -1 between words
+	words     int
+	stopped   bool // A word was too long for bufio.ScanWords; later words don't count
+}
+
+// asciiSpace marks the ASCII bytes isSpace accepts.
+var asciiSpace = [utf8.RuneSelf]bool{' ': true, '\t': true, '\n': true, '\v': true, '\f': true, '\r': true}
+
+// scan consumes the runes of data starting before end. A rune crossing end is
+// decoded whole, from data.
+func (c *wordCounter) scan(data []byte, end int) {
+	i, wordStart := c.next, c.wordStart
+	for i < end && !c.stopped {
+		b := data[i]
+		if b < utf8.RuneSelf {
+			// ASCII fast path, the bulk of any text body
+			if !asciiSpace[b] {
+				if wordStart < 0 {
+					wordStart = i
+				}
+			} else if wordStart >= 0 {
+				c.endWord(i-wordStart+1 <= maxWordSize)
+				wordStart = -1
+			}
+			i++
+			continue
+		}
+		r, width := utf8.DecodeRune(data[i:])
+		if !isSpace(r) {
+			if wordStart < 0 {
+				wordStart = i
+			}
+		} else if wordStart >= 0 {
+			c.endWord(i-wordStart+width <= maxWordSize)
+			wordStart = -1
+		}
+		i += width
+	}
+	c.next, c.wordStart = i, wordStart
+}
+
+// endWord counts a finished word if it fit.
+func (c *wordCounter) endWord(fits bool) {
+	if fits {
+		c.words++
+	} else {
+		c.stopped = true
+	}
+}
+
+// finish closes a word running to the end of a body of length n and
+// returns the count.
+func (c *wordCounter) finish(n int) int {
+	if !c.stopped && c.wordStart >= 0 {
+		c.endWord(n-c.wordStart < maxWordSize)
+	}
+	return c.words
+}
+
+// isSpace is bufio's word separator: unicode.IsSpace without the table lookup.
+func isSpace(r rune) bool {
+	if r <= '\u00FF' {
+		switch r {
+		case ' ', '\t', '\n', '\v', '\f', '\r', '\u0085', '\u00A0':
+			return true
+		}
+		return false
+	}
+	if '\u2000' <= r && r <= '\u200A' {
+		return true
+	}
+	switch r {
+	case '\u1680', '\u2028', '\u2029', '\u202F', '\u205F', '\u3000':
+		return true
+	}
+	return false
+}
+
+// shannonEntropy returns the entropy in bits per byte of a byte histogram
+func shannonEntropy(counts *[256]int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	n := float64(total)
+	var e float64
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		e -= p * math.Log2(p)
+	}
+	return math.Round(e*1000) / 1000
+}
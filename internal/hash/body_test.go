@@ -0,0 +1,112 @@
+package hash
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestCalculateBodyDigest_MatchesMMH3(t *testing.T) {
+	// Larger than one chunk so the incremental hash is exercised
+	data := bytes.Repeat([]byte("probe body "), bodyChunkSize/5)
+	if got, want := CalculateBodyDigest(data).MMH3, CalculateMMH3(data); got != want {
+		t.Errorf("MMH3 = %s, want %s", got, want)
+	}
+	if got, want := CalculateBodyDigest(nil).MMH3, CalculateMMH3(nil); got != want {
+		t.Errorf("empty MMH3 = %s, want %s", got, want)
+	}
+}
+
+func TestCalculateBodyDigest_Entropy(t *testing.T) {
+	allBytes := make([]byte, 256)
+	for i := range allBytes {
+		allBytes[i] = byte(i)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want float64
+	}{
+		{"empty", nil, 0},
+		{"single symbol", bytes.Repeat([]byte("a"), 100), 0},
+		{"two symbols", []byte("abababab"), 1},
+		{"four symbols", []byte("abcdabcd"), 2},
+		{"uniform", bytes.Repeat(allBytes, 4), 8},
+		{"skewed", []byte("aaab"), 0.811},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalculateBodyDigest(tt.data).Entropy; got != tt.want {
+				t.Errorf("Entropy = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// referenceCounts is the word and line count that parser.CountWordsAndLines
+// has always computed, with a bufio.ScanWords scanner.
+func referenceCounts(data []byte) (words, lines int) {
+	if len(data) > 0 {
+		lines = bytes.Count(data, []byte("\n")) + 1
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		words++
+	}
+	return words, lines
+}
+
+func TestDigestBody_CountsMatchScanner(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("x", n) }
+	tests := map[string]string{
+		"empty":              "",
+		"spaces only":        " \t\r\n ",
+		"plain":              "one two\nthree",
+		"trailing newline":   "a b c\n",
+		"unicode spaces":     "a\u00A0b\u2003c\u3000d\u0085e\u2028f",
+		"non-breaking only":  "\u00A0\u00A0",
+		"invalid utf8":       "a\xff b\xc3 \xe3\x80",
+		"cjk":                "日本語 テキスト",
+		"across chunks":      strings.Repeat("word\u3000", bodyChunkSize/2),
+		"longest word":       "a " + long(maxWordSize-1) + " b",
+		"word too long":      "a " + long(maxWordSize) + " b c",
+		"long word at eof":   "a " + long(maxWordSize-1),
+		"too long at eof":    "a " + long(maxWordSize),
+		"wide space fits":    "a " + long(maxWordSize-3) + "\u3000b",
+		"wide space too far": "a " + long(maxWordSize-2) + "\u3000b",
+		"long leading space": strings.Repeat(" ", 3*maxWordSize) + "a b",
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			data := []byte(body)
+			wantWords, wantLines := referenceCounts(data)
+			digest := DigestBody(data, true)
+			if digest.Words != wantWords || digest.Lines != wantLines {
+				t.Errorf("words/lines = %d/%d, want %d/%d", digest.Words, digest.Lines, wantWords, wantLines)
+			}
+			if plain := CalculateBodyDigest(data); plain.MMH3 != digest.MMH3 || plain.Entropy != digest.Entropy {
+				t.Errorf("counting changed the digest: %+v vs %+v", digest, plain)
+			}
+		})
+	}
+}
+
+func TestDigestBody_CountsMatchScannerRandom(t *testing.T) {
+	alphabet := []string{"a", "bc", " ", "\n", "\t", "\u00A0", "\u3000", "\xff", "é"}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 200; i++ {
+		var b strings.Builder
+		for n := rng.IntN(2 * bodyChunkSize); b.Len() < n; {
+			b.WriteString(alphabet[rng.IntN(len(alphabet))])
+		}
+		data := []byte(b.String())
+		wantWords, wantLines := referenceCounts(data)
+		if digest := DigestBody(data, true); digest.Words != wantWords || digest.Lines != wantLines {
+			t.Fatalf("body %d: words/lines = %d/%d, want %d/%d", i, digest.Words, digest.Lines, wantWords, wantLines)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package hash
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Limits guarding the JSON canonicalizer against pathological inputs.
+const (
+	maxCanonicalJSONSize  = 2 * 1024 * 1024
+	maxCanonicalJSONDepth = 64
+)
+
+// CanonicalizeJSON re-serializes a JSON document with object keys sorted and
+// no insignificant whitespace, so semantically equal documents produce equal
+// bytes. Array order is preserved and numbers keep their original text.
+// Documents larger than 2MB or nested deeper than 64 levels are rejected.
+func CanonicalizeJSON(data []byte) ([]byte, error) {
+	if len(data) > maxCanonicalJSONSize {
+		return nil, fmt.Errorf("json document too large (%d bytes)", len(data))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	// Reject trailing data after the first value
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after json value")
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	if err := writeCanonical(&buf, v, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v interface{}, depth int) error {
+	if depth > maxCanonicalJSONDepth {
+		return fmt.Errorf("json nesting exceeds %d levels", maxCanonicalJSONDepth)
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, k, depth+1); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, val[k], depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, elem, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case json.Number:
+		buf.WriteString(val.String())
+	default:
+		// strings, bools and null
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+	return nil
+}
+
+// CalculateJSONCanonicalMMH3 returns the MMH3 hash of the canonical form of a
+// JSON document. ok is false when the body is not valid JSON or exceeds the
+// canonicalizer limits.
+func CalculateJSONCanonicalMMH3(data []byte) (hash string, ok bool) {
+	canonical, err := CanonicalizeJSON(data)
+	if err != nil {
+		return "", false
+	}
+	return CalculateMMH3(canonical), true
+}
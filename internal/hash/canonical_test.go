@@ -0,0 +1,76 @@
+package hash
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalizeJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"sorted keys", `{"b":1,"a":2}`, `{"a":2,"b":1}`},
+		{"whitespace", "{ \"a\" : [ 1 , 2 ] ,\n \"b\" : null }", `{"a":[1,2],"b":null}`},
+		{"nested", `{"z":{"y":true,"x":false},"a":"s"}`, `{"a":"s","z":{"x":false,"y":true}}`},
+		{"array order kept", `[3,1,2]`, `[3,1,2]`},
+		{"number text kept", `{"n":1.50,"big":12345678901234567890}`, `{"big":12345678901234567890,"n":1.50}`},
+		{"scalar", `"hello"`, `"hello"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalizeJSON([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("CanonicalizeJSON: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CanonicalizeJSON(%s) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeJSON_Invalid(t *testing.T) {
+	for _, input := range []string{
+		``,
+		`{"a":`,
+		`<html></html>`,
+		`{"a":1} {"b":2}`,
+		strings.Repeat("[", 100) + strings.Repeat("]", 100),
+		`"` + strings.Repeat("x", maxCanonicalJSONSize) + `"`,
+	} {
+		if _, err := CanonicalizeJSON([]byte(input)); err == nil {
+			t.Errorf("CanonicalizeJSON(%.40q) expected error", input)
+		}
+	}
+}
+
+func TestCalculateJSONCanonicalMMH3_EqualDocuments(t *testing.T) {
+	a := []byte(`{"user":{"id":7,"roles":["admin","dev"]},"ok":true}`)
+	b := []byte("{\n  \"ok\": true,\n  \"user\": {\"roles\": [\"admin\", \"dev\"], \"id\": 7}\n}")
+
+	hashA, okA := CalculateJSONCanonicalMMH3(a)
+	hashB, okB := CalculateJSONCanonicalMMH3(b)
+	if !okA || !okB {
+		t.Fatal("expected both documents to canonicalize")
+	}
+	if hashA != hashB {
+		t.Errorf("canonical hashes differ: %s vs %s", hashA, hashB)
+	}
+	if CalculateMMH3(a) == CalculateMMH3(b) {
+		t.Error("plain body hashes should differ for differently formatted documents")
+	}
+
+	c := []byte(`{"user":{"id":7,"roles":["dev","admin"]},"ok":true}`)
+	if hashC, _ := CalculateJSONCanonicalMMH3(c); hashC == hashA {
+		t.Error("reordered array should change the canonical hash")
+	}
+}
+
+func TestCalculateJSONCanonicalMMH3_Invalid(t *testing.T) {
+	if hash, ok := CalculateJSONCanonicalMMH3([]byte("not json")); ok || hash != "" {
+		t.Errorf("invalid JSON = (%q, %v), want (\"\", false)", hash, ok)
+	}
+}
@@ -11,8 +11,10 @@ import (
 
 // Hash contains MMH3 hashes
 type Hash struct {
-	BodyMMH3   string `json:"body_mmh3"`
-	HeaderMMH3 string `json:"header_mmh3"`
+	BodyMMH3          string `json:"body_mmh3"`
+	HeaderMMH3        string `json:"header_mmh3"`
+	JSONCanonicalMMH3 string `json:"json_canonical_mmh3,omitempty"` // Hash of the canonicalized JSON body (opt-in)
+	PrefixMMH3        string `json:"prefix_mmh3,omitempty"`         // Hash of the body prefix read by -early-exit, instead of body_mmh3
 }
 
 // CalculateMMH3 calculates the MMH3 hash of the data
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// standardPorts are the ports left out of -alive-output lines, so the list
+// reads the way other tools expect base URLs.
+var standardPorts = map[string]string{"http": "80", "https": "443"}
+
+// AliveSet collects the distinct base URLs (scheme://host[:port]) of probed
+// URLs that answered with a live status, for -alive-output. It is safe for
+// concurrent use.
+type AliveSet struct {
+	mu          sync.Mutex
+	codes       StatusRanges
+	defaultPort func(scheme string) string // Port of probe URLs that have none
+	bases       map[string]struct{}
+}
+
+// NewAliveSet creates an empty AliveSet counting results whose status is in
+// codes as live.
+func NewAliveSet(codes StatusRanges, defaultPort func(scheme string) string) *AliveSet {
+	return &AliveSet{codes: codes, defaultPort: defaultPort, bases: make(map[string]struct{})}
+}
+
+// Record adds the base URL of a result's probed URL when it succeeded with a
+// live status. The probed URL counts, not the redirect target.
+func (s *AliveSet) Record(result ProbeResult) {
+	if !IsAlive(result, s.codes) {
+		return
+	}
+	base, ok := s.baseURL(result.URL)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	s.bases[base] = struct{}{}
+	s.mu.Unlock()
+}
+
+// baseURL returns rawURL as scheme://host[:port], lowercased, with the
+// standard port of the scheme left out.
+func (s *AliveSet) baseURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "", false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = s.defaultPort(scheme)
+	}
+	if port == standardPorts[scheme] {
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		return scheme + "://" + host, true
+	}
+	return scheme + "://" + net.JoinHostPort(host, port), true
+}
+
+// Lines returns the collected base URLs, sorted.
+func (s *AliveSet) Lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := make([]string, 0, len(s.bases))
+	for base := range s.bases {
+		lines = append(lines, base)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// WriteFile writes the collected base URLs to path, one per line.
+func (s *AliveSet) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create alive output: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range s.Lines() {
+		w.WriteString(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write alive output: %w", err)
+	}
+	return f.Close()
+}
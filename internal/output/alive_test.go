@@ -0,0 +1,110 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestAliveSet_DedupAndSort(t *testing.T) {
+	codes, _ := ParseStatusRanges("2xx,3xx")
+	s := NewAliveSet(codes, standardPort)
+
+	for _, r := range []ProbeResult{
+		{URL: "https://www.example.com/", StatusCode: 200},
+		{URL: "https://www.example.com/admin", StatusCode: 302},
+		{URL: "https://WWW.example.com:443/login", StatusCode: 200},
+		{URL: "http://www.example.com:80/", StatusCode: 301},
+		{URL: "https://www.example.com:8443/", StatusCode: 200},
+		{URL: "https://api.example.com/", StatusCode: 204},
+		{URL: "http://[2001:db8::1]/", StatusCode: 200},
+		{URL: "http://[2001:db8::1]:8080/", StatusCode: 200},
+		{URL: "http://10.0.0.1:8080/", StatusCode: 200},
+		{URL: "https://down.example.com/", StatusCode: 503},
+		{URL: "https://gone.example.com/", StatusCode: 404},
+		{URL: "https://broken.example.com/", StatusCode: 200, Error: "error reading body"},
+		{URL: "https://timeout.example.com/", Error: "timeout"},
+	} {
+		s.Record(r)
+	}
+
+	want := []string{
+		"http://10.0.0.1:8080",
+		"http://[2001:db8::1]",
+		"http://[2001:db8::1]:8080",
+		"http://www.example.com",
+		"https://api.example.com",
+		"https://www.example.com",
+		"https://www.example.com:8443",
+	}
+	if got := s.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %v\nwant %v", got, want)
+	}
+}
+
+func TestAliveSet_CustomCodesAndDefaultPort(t *testing.T) {
+	codes, _ := ParseStatusRanges("2xx,401")
+	// A configured HTTPS default of 4443 leaves probe URLs without the port
+	s := NewAliveSet(codes, func(scheme string) string {
+		if scheme == "https" {
+			return "4443"
+		}
+		return "80"
+	})
+	s.Record(ProbeResult{URL: "https://vpn.example.com/", StatusCode: 401})
+	s.Record(ProbeResult{URL: "https://redirect.example.com/", StatusCode: 302})
+
+	if got, want := s.Lines(), []string{"https://vpn.example.com:4443"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %v, want %v", got, want)
+	}
+}
+
+func TestIsAlive_CustomCodes(t *testing.T) {
+	codes, _ := ParseStatusRanges("200-299,401,403")
+	tests := []struct {
+		result ProbeResult
+		want   bool
+	}{
+		{ProbeResult{StatusCode: 200}, true},
+		{ProbeResult{StatusCode: 401}, true},
+		{ProbeResult{StatusCode: 403}, true},
+		{ProbeResult{StatusCode: 302}, false}, // 3xx not in the custom set
+		{ProbeResult{StatusCode: 404}, false},
+		{ProbeResult{StatusCode: 401, Error: "error reading body"}, false},
+	}
+	for _, tt := range tests {
+		if got := IsAlive(tt.result, codes); got != tt.want {
+			t.Errorf("IsAlive(%d, %q) = %v, want %v", tt.result.StatusCode, tt.result.Error, got, tt.want)
+		}
+	}
+}
+
+func TestAliveSet_WriteFile(t *testing.T) {
+	codes, _ := ParseStatusRanges("2xx")
+	s := NewAliveSet(codes, standardPort)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.Record(ProbeResult{URL: fmt.Sprintf("https://h%d.example.com/path%d", i%5, i), StatusCode: 200})
+		}(i)
+	}
+	wg.Wait()
+
+	path := filepath.Join(t.TempDir(), "alive.txt")
+	if err := s.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://h0.example.com\nhttps://h1.example.com\nhttps://h2.example.com\nhttps://h3.example.com\nhttps://h4.example.com\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,169 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCertReportHosts caps the hostnames kept per certificate; a wildcard
+// certificate can front thousands of hosts.
+const maxCertReportHosts = 100
+
+// certEntry is one unique certificate and the hosts that presented it.
+type certEntry struct {
+	info      CertificateInfo
+	hosts     map[string]struct{}
+	hostCount int // distinct hosts, including ones past maxCertReportHosts
+}
+
+// CertRegistry collects the unique certificates of a run, keyed by SHA-256
+// fingerprint, for -cert-report. It is safe for concurrent use.
+type CertRegistry struct {
+	mu    sync.Mutex
+	certs map[string]*certEntry
+}
+
+// NewCertRegistry creates an empty CertRegistry.
+func NewCertRegistry() *CertRegistry {
+	return &CertRegistry{certs: make(map[string]*certEntry)}
+}
+
+// Record adds the leaf certificate of a result and, with -xtls-per-hop, the
+// certificate of every HTTPS redirect hop.
+func (r *CertRegistry) Record(result ProbeResult) {
+	if result.TLS != nil {
+		r.Add(result.TLS.Certificate, result.Host)
+	}
+	for i, cert := range result.ChainCertificates {
+		if i < len(result.ChainHosts) {
+			r.Add(cert, result.ChainHosts[i])
+		}
+	}
+}
+
+// Add records that host presented cert. Certificates without a fingerprint
+// are ignored.
+func (r *CertRegistry) Add(cert *CertificateInfo, host string) {
+	if cert == nil || cert.Fingerprint == "" {
+		return
+	}
+	host = strings.ToLower(host)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.certs[cert.Fingerprint]
+	if !ok {
+		entry = &certEntry{info: *cert, hosts: make(map[string]struct{})}
+		r.certs[cert.Fingerprint] = entry
+	}
+	if host == "" {
+		return
+	}
+	if _, seen := entry.hosts[host]; seen {
+		return
+	}
+	if len(entry.hosts) < maxCertReportHosts {
+		entry.hosts[host] = struct{}{}
+	}
+	entry.hostCount++
+}
+
+// CertReportEntry is one certificate in a CertReport.
+type CertReportEntry struct {
+	Fingerprint string   `json:"fingerprint_sha256"`
+	SubjectCN   string   `json:"subject_cn,omitempty"`
+	IssuerCN    string   `json:"issuer_cn,omitempty"`
+	NotAfter    string   `json:"not_after,omitempty"`
+	DaysLeft    int      `json:"days_left"` // Whole days until NotAfter, negative once expired
+	Hosts       []string `json:"hosts"`     // Sorted, at most 100
+	HostCount   int      `json:"host_count"`
+}
+
+// CertReport lists the certificates of a run that need attention. Each list
+// is sorted by NotAfter, soonest first.
+type CertReport struct {
+	GeneratedAt  string            `json:"generated_at"`
+	WindowDays   int               `json:"window_days"`
+	Certificates int               `json:"certificates"` // Unique certificates seen
+	Expired      []CertReportEntry `json:"expired"`
+	ExpiringSoon []CertReportEntry `json:"expiring_soon"` // Not yet expired, NotAfter within the window
+	SelfSigned   []CertReportEntry `json:"self_signed"`
+}
+
+// Report builds the report as of now for certificates expiring within
+// windowDays.
+func (r *CertRegistry) Report(now time.Time, windowDays int) CertReport {
+	report := CertReport{
+		GeneratedAt:  now.UTC().Format(time.RFC3339),
+		WindowDays:   windowDays,
+		Expired:      []CertReportEntry{},
+		ExpiringSoon: []CertReportEntry{},
+		SelfSigned:   []CertReportEntry{},
+	}
+	deadline := now.AddDate(0, 0, windowDays)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report.Certificates = len(r.certs)
+	for fingerprint, entry := range r.certs {
+		notAfter, err := time.Parse(time.RFC3339, entry.info.NotAfter)
+		item := CertReportEntry{
+			Fingerprint: fingerprint,
+			SubjectCN:   entry.info.SubjectCN,
+			IssuerCN:    entry.info.IssuerCN,
+			NotAfter:    entry.info.NotAfter,
+			HostCount:   entry.hostCount,
+			Hosts:       make([]string, 0, len(entry.hosts)),
+		}
+		for host := range entry.hosts {
+			item.Hosts = append(item.Hosts, host)
+		}
+		sort.Strings(item.Hosts)
+
+		switch {
+		case err != nil:
+			// Unknown expiry: trust the flag computed at probe time
+			if entry.info.IsExpired {
+				report.Expired = append(report.Expired, item)
+			}
+		case !now.Before(notAfter):
+			item.DaysLeft = -int(now.Sub(notAfter).Hours() / 24)
+			report.Expired = append(report.Expired, item)
+		case !notAfter.After(deadline):
+			item.DaysLeft = int(notAfter.Sub(now).Hours() / 24)
+			report.ExpiringSoon = append(report.ExpiringSoon, item)
+		default:
+			item.DaysLeft = int(notAfter.Sub(now).Hours() / 24)
+		}
+		if entry.info.IsSelfSigned {
+			report.SelfSigned = append(report.SelfSigned, item)
+		}
+	}
+
+	for _, list := range [][]CertReportEntry{report.Expired, report.ExpiringSoon, report.SelfSigned} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].NotAfter != list[j].NotAfter {
+				return list[i].NotAfter < list[j].NotAfter
+			}
+			return list[i].Fingerprint < list[j].Fingerprint
+		})
+	}
+	return report
+}
+
+// WriteCertReport writes the report as indented JSON to path.
+func WriteCertReport(path string, report CertReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal certificate report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write certificate report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCertRegistry_Report(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	r := NewCertRegistry()
+
+	expired := &CertificateInfo{Fingerprint: "aa", SubjectCN: "old.example.com", NotAfter: "2026-05-01T00:00:00Z"}
+	soon := &CertificateInfo{Fingerprint: "bb", SubjectCN: "*.example.com", NotAfter: "2026-06-15T00:00:00Z"}
+	sooner := &CertificateInfo{Fingerprint: "cc", SubjectCN: "api.example.com", NotAfter: "2026-06-05T00:00:00Z"}
+	fine := &CertificateInfo{Fingerprint: "dd", SubjectCN: "ok.example.com", NotAfter: "2027-01-01T00:00:00Z"}
+	selfSigned := &CertificateInfo{Fingerprint: "ee", SubjectCN: "router.local", NotAfter: "2030-01-01T00:00:00Z", IsSelfSigned: true}
+
+	r.Add(expired, "old.example.com")
+	r.Add(soon, "WWW.example.com")
+	r.Add(soon, "shop.example.com")
+	r.Add(soon, "www.example.com")
+	r.Add(sooner, "api.example.com")
+	r.Add(fine, "ok.example.com")
+	r.Add(selfSigned, "192.168.1.1")
+	r.Add(&CertificateInfo{SubjectCN: "no fingerprint"}, "x.example.com")
+	r.Add(nil, "nil.example.com")
+
+	report := r.Report(now, 30)
+	if report.Certificates != 5 || report.WindowDays != 30 {
+		t.Errorf("certificates=%d window=%d, want 5 and 30", report.Certificates, report.WindowDays)
+	}
+
+	if len(report.Expired) != 1 || report.Expired[0].Fingerprint != "aa" || report.Expired[0].DaysLeft != -31 {
+		t.Errorf("expired = %+v, want aa with days_left -31", report.Expired)
+	}
+
+	var soonFingerprints []string
+	for _, e := range report.ExpiringSoon {
+		soonFingerprints = append(soonFingerprints, e.Fingerprint)
+	}
+	if !reflect.DeepEqual(soonFingerprints, []string{"cc", "bb"}) {
+		t.Errorf("expiring_soon = %v, want [cc bb] sorted by not_after", soonFingerprints)
+	}
+	if bb := report.ExpiringSoon[1]; !reflect.DeepEqual(bb.Hosts, []string{"shop.example.com", "www.example.com"}) || bb.HostCount != 2 || bb.DaysLeft != 13 {
+		t.Errorf("bb = %+v, want two deduplicated hosts and days_left 13", bb)
+	}
+
+	if len(report.SelfSigned) != 1 || report.SelfSigned[0].Fingerprint != "ee" || report.SelfSigned[0].Hosts[0] != "192.168.1.1" {
+		t.Errorf("self_signed = %+v, want ee on 192.168.1.1", report.SelfSigned)
+	}
+}
+
+func TestCertRegistry_RecordAndBounds(t *testing.T) {
+	r := NewCertRegistry()
+	wildcard := &CertificateInfo{Fingerprint: "ff", NotAfter: "2026-06-02T00:00:00Z"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < maxCertReportHosts+50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Record(ProbeResult{Host: fmt.Sprintf("h%03d.example.com", i), TLS: &TLSInfo{Certificate: wildcard}})
+		}(i)
+	}
+	wg.Wait()
+	r.Record(ProbeResult{
+		ChainHosts:        []string{"plain.example.com", "hop.example.com"},
+		ChainCertificates: []*CertificateInfo{nil, {Fingerprint: "gg", NotAfter: "2026-06-03T00:00:00Z"}},
+	})
+
+	report := r.Report(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), 7)
+	if report.Certificates != 2 || len(report.ExpiringSoon) != 2 {
+		t.Fatalf("report = %+v, want 2 certificates expiring soon", report)
+	}
+	ff := report.ExpiringSoon[0]
+	if len(ff.Hosts) != maxCertReportHosts || ff.HostCount != maxCertReportHosts+50 {
+		t.Errorf("ff kept %d hosts of %d, want %d of %d", len(ff.Hosts), ff.HostCount, maxCertReportHosts, maxCertReportHosts+50)
+	}
+	if gg := report.ExpiringSoon[1]; gg.Fingerprint != "gg" || !reflect.DeepEqual(gg.Hosts, []string{"hop.example.com"}) {
+		t.Errorf("gg = %+v, want the per-hop certificate of hop.example.com", gg)
+	}
+}
+
+func TestWriteCertReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "certs.json")
+	report := NewCertRegistry().Report(time.Now(), 30)
+	if err := WriteCertReport(path, report); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	for _, key := range []string{"expired", "expiring_soon", "self_signed"} {
+		if list, ok := decoded[key].([]interface{}); !ok || len(list) != 0 {
+			t.Errorf("%s = %v, want an empty list", key, decoded[key])
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package output
+
+import (
+	"maps"
+	"slices"
+)
+
+// Clone returns a deep copy of r: no slice, map or pointer of the copy is
+// shared with r, so either can be modified without affecting the other.
+func (r ProbeResult) Clone() ProbeResult {
+	c := r
+	c.Meta = clonePtr(r.Meta)
+	if c.Meta != nil {
+		c.Meta.Fields = maps.Clone(r.Meta.Fields)
+	}
+	c.Expansion = clonePtr(r.Expansion)
+	c.SiblingSchemeProbed = clonePtr(r.SiblingSchemeProbed)
+	c.JSONValid = clonePtr(r.JSONValid)
+	c.JSONTopLevelKeys = slices.Clone(r.JSONTopLevelKeys)
+	c.FormActions = slices.Clone(r.FormActions)
+	c.ServerRaw = slices.Clone(r.ServerRaw)
+	c.PTR = slices.Clone(r.PTR)
+	c.ClockSkewMs = clonePtr(r.ClockSkewMs)
+	c.ChainStatusCodes = slices.Clone(r.ChainStatusCodes)
+	c.ChainHosts = slices.Clone(r.ChainHosts)
+	c.ChainOrigins = slices.Clone(r.ChainOrigins)
+	c.ChainMethods = slices.Clone(r.ChainMethods)
+	c.ChainProtocols = slices.Clone(r.ChainProtocols)
+	c.ChainContentTypes = slices.Clone(r.ChainContentTypes)
+	c.BestHopIndex = clonePtr(r.BestHopIndex)
+	c.BestHop = clonePtr(r.BestHop)
+	if r.ChainCertificates != nil {
+		c.ChainCertificates = make([]*CertificateInfo, len(r.ChainCertificates))
+		for i, cert := range r.ChainCertificates {
+			c.ChainCertificates[i] = cert.clone()
+		}
+	}
+	c.Timings = r.Timings.clone()
+	if r.ChainTimings != nil {
+		c.ChainTimings = make([]*Timings, len(r.ChainTimings))
+		for i, t := range r.ChainTimings {
+			c.ChainTimings[i] = t.clone()
+		}
+	}
+	c.BodyEntropy = clonePtr(r.BodyEntropy)
+	c.FramingAnomalies = slices.Clone(r.FramingAnomalies)
+	if r.TLS != nil {
+		tls := *r.TLS
+		tls.Certificate = r.TLS.Certificate.clone()
+		if r.TLS.Chain != nil {
+			tls.Chain = make([]CertificateInfo, len(r.TLS.Chain))
+			for i := range r.TLS.Chain {
+				tls.Chain[i] = *r.TLS.Chain[i].clone()
+			}
+		}
+		c.TLS = &tls
+	}
+	if r.CSP != nil {
+		csp := *r.CSP
+		csp.Directives = slices.Clone(r.CSP.Directives)
+		csp.Domains = slices.Clone(r.CSP.Domains)
+		c.CSP = &csp
+	}
+	c.Technologies = slices.Clone(r.Technologies)
+	c.MatchedKeywords = slices.Clone(r.MatchedKeywords)
+	if r.Cache != nil {
+		cache := *r.Cache
+		cache.AgeSeconds = clonePtr(r.Cache.AgeSeconds)
+		cache.Via = slices.Clone(r.Cache.Via)
+		c.Cache = &cache
+	}
+	c.Cookies = slices.Clone(r.Cookies)
+	c.AllowedMethods = slices.Clone(r.AllowedMethods)
+	c.CORSAllowMethods = slices.Clone(r.CORSAllowMethods)
+	c.Attempts = slices.Clone(r.Attempts)
+	c.AttemptStatusCodes = slices.Clone(r.AttemptStatusCodes)
+	c.PinMatch = clonePtr(r.PinMatch)
+	if r.DiscoveredDomains != nil {
+		c.DiscoveredDomains = &DiscoveredDomains{
+			Domains:         slices.Clone(r.DiscoveredDomains.Domains),
+			DomainSources:   maps.Clone(r.DiscoveredDomains.DomainSources),
+			NewDomains:      slices.Clone(r.DiscoveredDomains.NewDomains),
+			WildcardDomains: slices.Clone(r.DiscoveredDomains.WildcardDomains),
+		}
+	}
+	c.ResponseHeaders = maps.Clone(r.ResponseHeaders)
+	c.RequestHeaders = maps.Clone(r.RequestHeaders)
+	return c
+}
+
+// clone returns a deep copy of c, or nil.
+func (c *CertificateInfo) clone() *CertificateInfo {
+	if c == nil {
+		return nil
+	}
+	cert := *c
+	cert.SANs = slices.Clone(c.SANs)
+	return &cert
+}
+
+// clone returns a deep copy of t, or nil.
+func (t *Timings) clone() *Timings {
+	if t == nil {
+		return nil
+	}
+	return &Timings{
+		DNSMs:      clonePtr(t.DNSMs),
+		ConnectMs:  clonePtr(t.ConnectMs),
+		TLSMs:      clonePtr(t.TLSMs),
+		TTFBMs:     clonePtr(t.TTFBMs),
+		TransferMs: clonePtr(t.TransferMs),
+	}
+}
+
+// clonePtr returns a pointer to a copy of *p, or nil.
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
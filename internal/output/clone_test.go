@@ -0,0 +1,89 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fill sets every field reachable from v to a non-zero value, so a field
+// added to ProbeResult without a Clone update is caught below.
+func fill(t *testing.T, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Float64:
+		v.SetFloat(1)
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(t, v.Elem())
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(t, v.Index(0))
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(reflect.ValueOf("k"), reflect.ValueOf("v"))
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fill(t, v.Field(i))
+		}
+	default:
+		t.Fatalf("fill: unhandled kind %s", v.Kind())
+	}
+}
+
+// assertUnshared fails for every pointer, slice or map of a that b shares.
+func assertUnshared(t *testing.T, path string, a, b reflect.Value) {
+	switch a.Kind() {
+	case reflect.Pointer:
+		if a.Pointer() == b.Pointer() {
+			t.Errorf("%s: pointer shared with the original", path)
+			return
+		}
+		assertUnshared(t, path, a.Elem(), b.Elem())
+	case reflect.Slice:
+		if a.Pointer() == b.Pointer() {
+			t.Errorf("%s: slice shared with the original", path)
+			return
+		}
+		for i := 0; i < a.Len(); i++ {
+			assertUnshared(t, path+"[]", a.Index(i), b.Index(i))
+		}
+	case reflect.Map:
+		if a.Pointer() == b.Pointer() {
+			t.Errorf("%s: map shared with the original", path)
+		}
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			assertUnshared(t, path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+		}
+	}
+}
+
+func TestProbeResult_CloneIsDeep(t *testing.T) {
+	var original ProbeResult
+	fill(t, reflect.ValueOf(&original).Elem())
+
+	clone := original.Clone()
+	if !reflect.DeepEqual(original, clone) {
+		t.Fatal("clone differs from the original")
+	}
+	assertUnshared(t, "ProbeResult", reflect.ValueOf(original), reflect.ValueOf(clone))
+
+	clone.ChainHosts[0] = "changed"
+	clone.TLS.Chain[0].SANs[0] = "changed"
+	clone.ResponseHeaders["k"] = "changed"
+	if original.ChainHosts[0] != "x" || original.TLS.Chain[0].SANs[0] != "x" || original.ResponseHeaders["k"] != "v" {
+		t.Error("modifying the clone changed the original")
+	}
+}
+
+func TestProbeResult_CloneKeepsNil(t *testing.T) {
+	clone := ProbeResult{URL: "https://example.com"}.Clone()
+	if clone.TLS != nil || clone.ChainHosts != nil || clone.ResponseHeaders != nil || clone.ChainCertificates != nil {
+		t.Errorf("clone of an empty result allocated fields: %+v", clone)
+	}
+}
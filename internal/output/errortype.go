@@ -0,0 +1,26 @@
+package output
+
+// Error types reported in the error_type field of failed results.
+const (
+	ErrorTypeCancelled          = "cancelled"
+	ErrorTypeTimeout            = "timeout"
+	ErrorTypeDNS                = "dns"
+	ErrorTypeDNSNXDomain        = "dns_nxdomain" // -prefetch-dns: hostname doesn't exist, URL not probed
+	ErrorTypeConnectionRefused  = "connection_refused"
+	ErrorTypeConnectionReset    = "connection_reset"
+	ErrorTypeTLSHandshake       = "tls_handshake"
+	ErrorTypeRateLimit          = "rate_limit"
+	ErrorTypeInvalidURL         = "invalid_url"
+	ErrorTypeRedirect           = "redirect"
+	ErrorTypeBodyRead           = "body_read"
+	ErrorTypeSkippedFirstAlive  = "skipped_first_alive"
+	ErrorTypeSkippedDeadlisted  = "skipped_deadlisted" // -deadlist: host failed too many runs in a row, URL not probed
+	ErrorTypeRedirectExcluded   = "redirect_to_excluded"
+	ErrorTypeRedirectPrivate    = "redirect_to_private_blocked"
+	ErrorTypeRedirectOutOfScope = "redirect_out_of_scope"       // -scope-file: redirect target not in the allowlist
+	ErrorTypeCertPinMismatch    = "cert_pin_mismatch"           // -pin-file: leaf certificate matches none of the host's pins
+	ErrorTypeRedirectScheme     = "unsupported_redirect_scheme" // redirect to javascript:, data: or another non-HTTP scheme
+	ErrorTypeInternalPanic      = "internal_panic"              // the probe panicked; see the debug log for the stack
+	ErrorTypeProxy              = "proxy_error"                 // -proxy: the proxy refused or failed the request (407, its own 502/504, CONNECT failure)
+	ErrorTypeUnknown            = "unknown"
+)
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Manifest describes a completed (or interrupted) run for reproducibility.
+type Manifest struct {
+	Version           string      `json:"version"`
+	RunID             string      `json:"run_id"`
+	StartTime         string      `json:"start_time"`
+	EndTime           string      `json:"end_time"`
+	Duration          string      `json:"duration"`
+	DurationMs        int64       `json:"duration_ms"`
+	Interrupted       bool        `json:"interrupted"`
+	InputFile         string      `json:"input_file,omitempty"`
+	InputURL          string      `json:"input_url,omitempty"`
+	InputETag         string      `json:"input_etag,omitempty"`
+	InputLastModified string      `json:"input_last_modified,omitempty"`
+	InputSHA256       string      `json:"input_sha256"`
+	Inputs            int         `json:"inputs"`
+	Invalid           int         `json:"invalid"`
+	Excluded          int         `json:"excluded"`
+	OutOfScope        int         `json:"out_of_scope,omitempty"` // Refused by -scope-file
+	Deadlisted        int         `json:"deadlisted,omitempty"`   // URLs of -deadlist hosts skipped without probing
+	Expanded          int         `json:"expanded"`
+	Deduplicated      int         `json:"deduplicated"`
+	Shard             *ShardInfo  `json:"shard,omitempty"`
+	Counts            TallyCounts `json:"counts"`
+	Config            interface{} `json:"config"`
+}
+
+// ShardInfo records which slice of the deduplicated URLs a -shard run probed.
+type ShardInfo struct {
+	Spec  string `json:"spec"`  // "i/n"
+	Kept  int    `json:"kept"`  // URLs in this shard
+	Total int    `json:"total"` // Deduplicated URLs across all shards
+}
+
+// SetTiming fills the start/end timestamps and duration fields.
+func (m *Manifest) SetTiming(start, end time.Time) {
+	m.StartTime = start.Format(time.RFC3339Nano)
+	m.EndTime = end.Format(time.RFC3339Nano)
+	m.Duration = end.Sub(start).String()
+	m.DurationMs = end.Sub(start).Milliseconds()
+}
+
+// WriteManifest writes the manifest as indented JSON to path.
+func WriteManifest(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
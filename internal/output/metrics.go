@@ -0,0 +1,191 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricFamily describes one OpenMetrics gauge written by MetricsExporter.
+type metricFamily struct {
+	name, unit, help string
+	value            func(s *metricSeries) (float64, bool)
+}
+
+// metricFamilies are written in this order, each with all of its samples.
+var metricFamilies = []metricFamily{
+	{"probe_http_up", "", "Whether the target answered with an HTTP response (1) or failed (0).",
+		func(s *metricSeries) (float64, bool) {
+			if s.up {
+				return 1, true
+			}
+			return 0, true
+		}},
+	{"probe_http_status", "", "Status code of the final response.",
+		func(s *metricSeries) (float64, bool) { return float64(s.status), s.up }},
+	{"probe_http_duration_seconds", "seconds", "Duration of the probe including redirects.",
+		func(s *metricSeries) (float64, bool) { return s.duration, s.up && s.duration >= 0 }},
+	{"probe_tls_cert_expiry_seconds", "seconds", "Expiry of the leaf certificate as a Unix timestamp (requires -xtls).",
+		func(s *metricSeries) (float64, bool) { return float64(s.certExpiry), s.certExpiry != 0 }},
+}
+
+// metricSeries is the latest state of one target
+type metricSeries struct {
+	labels     string // rendered label set, e.g. {url="...",host="..."}
+	up         bool
+	status     int
+	duration   float64 // seconds, -1 when unknown
+	certExpiry int64   // Unix seconds, 0 when unknown
+}
+
+// MetricsExporter collects one gauge sample per target and writes them in
+// the OpenMetrics text format. A target probed more than once keeps its
+// latest result. It is safe for concurrent use.
+type MetricsExporter struct {
+	mu          sync.Mutex
+	hostOnly    bool
+	defaultPort func(scheme string) string
+	series      map[string]*metricSeries
+}
+
+// NewMetricsExporter creates a MetricsExporter. With hostOnly the url label
+// is dropped to bound cardinality; targets are then labelled by host, scheme
+// and port. defaultPort returns the port assumed for a portless URL.
+func NewMetricsExporter(hostOnly bool, defaultPort func(scheme string) string) *MetricsExporter {
+	return &MetricsExporter{hostOnly: hostOnly, defaultPort: defaultPort, series: make(map[string]*metricSeries)}
+}
+
+// Record updates the series of a result's target. Cancelled results are
+// ignored since they say nothing about the target.
+func (m *MetricsExporter) Record(result ProbeResult) {
+	if result.ErrorType == ErrorTypeCancelled {
+		return
+	}
+	// Failed probes may carry only the normalized URL
+	rawURL := result.URL
+	if rawURL == "" {
+		rawURL = result.NormalizedURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return
+	}
+
+	var labels string
+	host := strings.ToLower(u.Hostname())
+	if m.hostOnly {
+		port := u.Port()
+		if port == "" {
+			port = m.defaultPort(u.Scheme)
+		}
+		labels = formatLabels("host", host, "scheme", u.Scheme, "port", port)
+	} else {
+		labels = formatLabels("url", rawURL, "host", host)
+	}
+
+	series := &metricSeries{labels: labels, up: result.Error == "", status: result.StatusCode, duration: -1}
+	if d, err := time.ParseDuration(result.Time); err == nil {
+		series.duration = d.Seconds()
+	}
+	if result.TLS != nil && result.TLS.Certificate != nil {
+		if notAfter, err := time.Parse(time.RFC3339, result.TLS.Certificate.NotAfter); err == nil {
+			series.certExpiry = notAfter.Unix()
+		}
+	}
+
+	m.mu.Lock()
+	m.series[labels] = series
+	m.mu.Unlock()
+}
+
+// formatLabels renders name/value pairs as an OpenMetrics label set
+func formatLabels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelValueEscaper escapes the characters OpenMetrics requires in label values
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// WriteTo writes all series in the OpenMetrics text format, terminated by
+// "# EOF". Samples are sorted by label set so reruns diff cleanly.
+func (m *MetricsExporter) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	series := make([]*metricSeries, 0, len(m.series))
+	for _, s := range m.series {
+		series = append(series, s)
+	}
+	m.mu.Unlock()
+	sort.Slice(series, func(i, j int) bool { return series[i].labels < series[j].labels })
+
+	var b strings.Builder
+	for _, family := range metricFamilies {
+		header := false
+		for _, s := range series {
+			value, ok := family.value(s)
+			if !ok {
+				continue
+			}
+			if !header {
+				fmt.Fprintf(&b, "# TYPE %s gauge\n", family.name)
+				if family.unit != "" {
+					fmt.Fprintf(&b, "# UNIT %s %s\n", family.name, family.unit)
+				}
+				fmt.Fprintf(&b, "# HELP %s %s\n", family.name, family.help)
+				header = true
+			}
+			fmt.Fprintf(&b, "%s%s %s\n", family.name, s.labels, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	}
+	b.WriteString("# EOF\n")
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// WriteOpenMetrics writes the exposition to path. The file is replaced
+// atomically so a collector never reads a partial run.
+func (m *MetricsExporter) WriteOpenMetrics(path string) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create OpenMetrics file: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	if _, err := m.WriteTo(w); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write OpenMetrics: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write OpenMetrics: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write OpenMetrics: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace OpenMetrics file: %w", err)
+	}
+	return nil
+}
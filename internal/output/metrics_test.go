@@ -0,0 +1,87 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMetricsExporter_Exposition(t *testing.T) {
+	m := NewMetricsExporter(false, standardPort)
+	m.Record(ProbeResult{
+		URL: "https://example.com/", StatusCode: 200, Time: "250ms",
+		TLS: &TLSInfo{Certificate: &CertificateInfo{NotAfter: "2030-01-01T00:00:00Z"}},
+	})
+	m.Record(ProbeResult{NormalizedURL: "http://down.test:8080", Error: "refused", ErrorType: ErrorTypeConnectionRefused})
+	m.Record(ProbeResult{URL: "http://example.com:9090/", Error: "cancelled", ErrorType: ErrorTypeCancelled})
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# TYPE probe_http_up gauge
+# HELP probe_http_up Whether the target answered with an HTTP response (1) or failed (0).
+probe_http_up{url="http://down.test:8080",host="down.test"} 0
+probe_http_up{url="https://example.com/",host="example.com"} 1
+# TYPE probe_http_status gauge
+# HELP probe_http_status Status code of the final response.
+probe_http_status{url="https://example.com/",host="example.com"} 200
+# TYPE probe_http_duration_seconds gauge
+# UNIT probe_http_duration_seconds seconds
+# HELP probe_http_duration_seconds Duration of the probe including redirects.
+probe_http_duration_seconds{url="https://example.com/",host="example.com"} 0.25
+# TYPE probe_tls_cert_expiry_seconds gauge
+# UNIT probe_tls_cert_expiry_seconds seconds
+# HELP probe_tls_cert_expiry_seconds Expiry of the leaf certificate as a Unix timestamp (requires -xtls).
+probe_tls_cert_expiry_seconds{url="https://example.com/",host="example.com"} 1893456000
+# EOF
+`
+	if got := b.String(); got != want {
+		t.Errorf("exposition mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMetricsExporter_HostOnlyLabels(t *testing.T) {
+	m := NewMetricsExporter(true, standardPort)
+	m.Record(ProbeResult{URL: "https://Example.com/a", StatusCode: 200, Time: "1s"})
+	m.Record(ProbeResult{URL: "https://example.com/b", StatusCode: 404, Time: "1s"})
+
+	var b strings.Builder
+	m.WriteTo(&b)
+	out := b.String()
+	if strings.Contains(out, "url=") {
+		t.Errorf("host-only exposition has a url label:\n%s", out)
+	}
+	// Both URLs collapse into one series holding the latest result
+	if !strings.Contains(out, `probe_http_status{host="example.com",scheme="https",port="443"} 404`+"\n") ||
+		strings.Count(out, "probe_http_status{") != 1 {
+		t.Errorf("expected a single host series with the latest status:\n%s", out)
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	got := formatLabels("url", "http://x.test/a\"b\\c\nd")
+	want := `{url="http://x.test/a\"b\\c\nd"}`
+	if got != want {
+		t.Errorf("formatLabels = %s, want %s", got, want)
+	}
+}
+
+func TestMetricsExporter_WriteOpenMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probe.prom")
+	m := NewMetricsExporter(false, standardPort)
+	if err := m.WriteOpenMetrics(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "# EOF\n" {
+		t.Errorf("empty exposition = %q, want only the EOF marker", data)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary file left behind")
+	}
+}
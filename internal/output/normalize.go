@@ -0,0 +1,20 @@
+package output
+
+import "sort"
+
+// Normalize sorts the set-like arrays of r in place so the same response
+// always marshals to the same line. Arrays whose order carries meaning
+// (chain_*, via, cookies, certificate SANs) are left as they are. Map fields
+// need no work: encoding/json writes map keys in sorted order.
+func (r *ProbeResult) Normalize() {
+	sort.Strings(r.PTR)
+	sort.Strings(r.FramingAnomalies)
+	sort.Strings(r.Technologies)
+	sort.Strings(r.MatchedKeywords)
+	sort.Strings(r.AllowedMethods)
+	sort.Strings(r.CORSAllowMethods)
+	if r.DiscoveredDomains != nil {
+		sort.Strings(r.DiscoveredDomains.Domains)
+		sort.Strings(r.DiscoveredDomains.NewDomains)
+	}
+}
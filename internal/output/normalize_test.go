@@ -0,0 +1,34 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProbeResult_Normalize(t *testing.T) {
+	r := ProbeResult{
+		Technologies:      []string{"Nginx", "jQuery", "PHP"},
+		MatchedKeywords:   []string{"zeta", "alpha"},
+		PTR:               []string{"b.example", "a.example"},
+		ChainHosts:        []string{"z.example", "a.example"},
+		DiscoveredDomains: &DiscoveredDomains{Domains: []string{"b.com", "a.com"}},
+	}
+	r.Normalize()
+
+	if want := []string{"Nginx", "PHP", "jQuery"}; !reflect.DeepEqual(r.Technologies, want) {
+		t.Errorf("Technologies = %q, want %q", r.Technologies, want)
+	}
+	if want := []string{"alpha", "zeta"}; !reflect.DeepEqual(r.MatchedKeywords, want) {
+		t.Errorf("MatchedKeywords = %q, want %q", r.MatchedKeywords, want)
+	}
+	if want := []string{"a.example", "b.example"}; !reflect.DeepEqual(r.PTR, want) {
+		t.Errorf("PTR = %q, want %q", r.PTR, want)
+	}
+	if want := []string{"a.com", "b.com"}; !reflect.DeepEqual(r.DiscoveredDomains.Domains, want) {
+		t.Errorf("Domains = %q, want %q", r.DiscoveredDomains.Domains, want)
+	}
+	// Chain order is meaningful and must survive
+	if want := []string{"z.example", "a.example"}; !reflect.DeepEqual(r.ChainHosts, want) {
+		t.Errorf("ChainHosts = %q, want %q", r.ChainHosts, want)
+	}
+}
@@ -0,0 +1,255 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxProfileValues caps each per-host set (titles, ports, domains, ...) so a
+// host probed on many ports or paths can't grow its profile without bound.
+// Total memory is therefore proportional to the number of distinct hostnames.
+const maxProfileValues = 100
+
+// HostProfile summarizes everything learned about one hostname across its
+// probes. Hostnames are those of the probed URLs, before redirects.
+type HostProfile struct {
+	Host              string               `json:"host"`
+	Probes            int                  `json:"probes"` // Results seen for this host, including errors
+	Errors            int                  `json:"errors"`
+	Alive             int                  `json:"alive"`           // Results live under -alive-codes
+	Ports             []ProfilePort        `json:"ports,omitempty"` // Ports that answered
+	Titles            []string             `json:"titles,omitempty"`
+	WebServers        []string             `json:"webservers,omitempty"`
+	Technologies      []string             `json:"tech,omitempty"`
+	DiscoveredDomains []string             `json:"discovered_domains,omitempty"`
+	Certificates      []ProfileCertificate `json:"certificates,omitempty"` // Leaf certificates seen (requires -xtls)
+	RedirectsOffHost  bool                 `json:"redirects_off_host"`     // Some probe of this host redirected to another host
+	RedirectHosts     []string             `json:"redirect_hosts,omitempty"`
+}
+
+// ProfilePort is a port/scheme combination that answered.
+type ProfilePort struct {
+	Port        string `json:"port"`
+	Scheme      string `json:"scheme"`
+	StatusCodes []int  `json:"status_codes"` // Distinct first-hop status codes
+}
+
+// ProfileCertificate identifies a certificate seen on a host.
+type ProfileCertificate struct {
+	Fingerprint string `json:"fingerprint_sha256"`
+	SubjectCN   string `json:"subject_cn,omitempty"`
+	IssuerCN    string `json:"issuer_cn,omitempty"`
+	NotAfter    string `json:"not_after,omitempty"`
+}
+
+// hostAccumulator holds one host's profile while results stream in
+type hostAccumulator struct {
+	probes, errors    int
+	alive             int
+	ports             map[string]*portAccumulator // "scheme:port"
+	titles            map[string]bool
+	webServers        map[string]bool
+	technologies      map[string]bool
+	discoveredDomains map[string]bool
+	certificates      map[string]ProfileCertificate // fingerprint -> certificate
+	redirectHosts     map[string]bool
+}
+
+type portAccumulator struct {
+	port, scheme string
+	statuses     map[int]bool
+}
+
+// Profiler aggregates results into one HostProfile per hostname. It is safe
+// for concurrent use.
+type Profiler struct {
+	mu          sync.Mutex
+	alive       StatusRanges
+	defaultPort func(scheme string) string
+	hosts       map[string]*hostAccumulator
+}
+
+// NewProfiler creates a Profiler counting results with an alive status as
+// live. defaultPort returns the port assumed for a scheme when a probed URL
+// has none.
+func NewProfiler(alive StatusRanges, defaultPort func(scheme string) string) *Profiler {
+	return &Profiler{alive: alive, defaultPort: defaultPort, hosts: make(map[string]*hostAccumulator)}
+}
+
+// Record adds a result to its host's profile. Cancelled results are ignored
+// since they say nothing about the host.
+func (p *Profiler) Record(result ProbeResult) {
+	if result.ErrorType == ErrorTypeCancelled {
+		return
+	}
+	// Failed probes may carry only the normalized URL
+	rawURL := result.URL
+	if rawURL == "" {
+		rawURL = result.NormalizedURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return
+	}
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = p.defaultPort(u.Scheme)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	acc := p.hosts[host]
+	if acc == nil {
+		acc = &hostAccumulator{
+			ports:             make(map[string]*portAccumulator),
+			titles:            make(map[string]bool),
+			webServers:        make(map[string]bool),
+			technologies:      make(map[string]bool),
+			discoveredDomains: make(map[string]bool),
+			certificates:      make(map[string]ProfileCertificate),
+			redirectHosts:     make(map[string]bool),
+		}
+		p.hosts[host] = acc
+	}
+
+	acc.probes++
+	if result.Error != "" {
+		acc.errors++
+		return
+	}
+	if IsAlive(result, p.alive) {
+		acc.alive++
+	}
+
+	key := u.Scheme + ":" + port
+	pa := acc.ports[key]
+	if pa == nil && len(acc.ports) < maxProfileValues {
+		pa = &portAccumulator{port: port, scheme: u.Scheme, statuses: make(map[int]bool)}
+		acc.ports[key] = pa
+	}
+	if pa != nil && len(result.ChainStatusCodes) > 0 && len(pa.statuses) < maxProfileValues {
+		pa.statuses[result.ChainStatusCodes[0]] = true
+	}
+
+	addBounded(acc.titles, result.Title)
+	addBounded(acc.webServers, result.WebServer)
+	for _, tech := range result.Technologies {
+		addBounded(acc.technologies, tech)
+	}
+	if result.DiscoveredDomains != nil {
+		for _, domain := range result.DiscoveredDomains.Domains {
+			addBounded(acc.discoveredDomains, domain)
+		}
+	}
+	if result.TLS != nil && result.TLS.Certificate != nil && len(acc.certificates) < maxProfileValues {
+		cert := result.TLS.Certificate
+		acc.certificates[cert.Fingerprint] = ProfileCertificate{
+			Fingerprint: cert.Fingerprint,
+			SubjectCN:   cert.SubjectCN,
+			IssuerCN:    cert.IssuerCN,
+			NotAfter:    cert.NotAfter,
+		}
+	}
+	for _, chainHost := range result.ChainHosts {
+		if chainHost = strings.ToLower(chainHost); chainHost != host {
+			addBounded(acc.redirectHosts, chainHost)
+		}
+	}
+}
+
+// addBounded adds a non-empty value to set unless it is full
+func addBounded(set map[string]bool, value string) {
+	if value != "" && len(set) < maxProfileValues {
+		set[value] = true
+	}
+}
+
+// Profiles returns the profiles sorted by hostname, with every set sorted.
+func (p *Profiler) Profiles() []HostProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	profiles := make([]HostProfile, 0, len(p.hosts))
+	for host, acc := range p.hosts {
+		profile := HostProfile{
+			Host:              host,
+			Probes:            acc.probes,
+			Errors:            acc.errors,
+			Alive:             acc.alive,
+			Titles:            sortedKeys(acc.titles),
+			WebServers:        sortedKeys(acc.webServers),
+			Technologies:      sortedKeys(acc.technologies),
+			DiscoveredDomains: sortedKeys(acc.discoveredDomains),
+			RedirectHosts:     sortedKeys(acc.redirectHosts),
+			RedirectsOffHost:  len(acc.redirectHosts) > 0,
+		}
+		for _, pa := range acc.ports {
+			statuses := make([]int, 0, len(pa.statuses))
+			for status := range pa.statuses {
+				statuses = append(statuses, status)
+			}
+			sort.Ints(statuses)
+			profile.Ports = append(profile.Ports, ProfilePort{Port: pa.port, Scheme: pa.scheme, StatusCodes: statuses})
+		}
+		sort.Slice(profile.Ports, func(i, j int) bool {
+			a, b := profile.Ports[i], profile.Ports[j]
+			if a.Port != b.Port {
+				ai, _ := strconv.Atoi(a.Port)
+				bi, _ := strconv.Atoi(b.Port)
+				return ai < bi
+			}
+			return a.Scheme < b.Scheme
+		})
+		for _, cert := range acc.certificates {
+			profile.Certificates = append(profile.Certificates, cert)
+		}
+		sort.Slice(profile.Certificates, func(i, j int) bool {
+			return profile.Certificates[i].Fingerprint < profile.Certificates[j].Fingerprint
+		})
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Host < profiles[j].Host })
+	return profiles
+}
+
+// sortedKeys returns the keys of set in sorted order, nil when empty
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteProfiles writes the profiles to path as JSON lines, one host per line.
+func (p *Profiler) WriteProfiles(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create host profiles file: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, profile := range p.Profiles() {
+		if err := enc.Encode(profile); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write host profile: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write host profiles: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,90 @@
+package output
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func standardPort(scheme string) string {
+	if scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+func TestProfiler_AggregatesPerHost(t *testing.T) {
+	profiler := NewProfiler(StatusRanges{{200, 399}}, standardPort)
+	profiler.Record(ProbeResult{
+		URL: "https://Example.com/", Title: "Home", WebServer: "nginx", ChainStatusCodes: []int{200},
+		ChainHosts: []string{"example.com"}, Technologies: []string{"React"},
+		TLS: &TLSInfo{Certificate: &CertificateInfo{Fingerprint: "aa", SubjectCN: "example.com"}},
+	})
+	profiler.Record(ProbeResult{
+		URL: "http://example.com/", ChainStatusCodes: []int{301, 200},
+		ChainHosts: []string{"example.com", "www.example.com"}, Title: "Home", WebServer: "nginx",
+	})
+	profiler.Record(ProbeResult{URL: "http://example.com:8080/", Error: "refused", ErrorType: ErrorTypeConnectionRefused})
+	profiler.Record(ProbeResult{URL: "http://example.com:9090/", Error: "cancelled", ErrorType: ErrorTypeCancelled})
+	profiler.Record(ProbeResult{URL: "http://other.test/", ChainStatusCodes: []int{404}, ChainHosts: []string{"other.test"}})
+
+	profiles := profiler.Profiles()
+	if len(profiles) != 2 || profiles[0].Host != "example.com" || profiles[1].Host != "other.test" {
+		t.Fatalf("profiles = %+v, want example.com and other.test in order", profiles)
+	}
+	p := profiles[0]
+	if p.Probes != 3 || p.Errors != 1 {
+		t.Errorf("probes/errors = %d/%d, want 3/1 (cancelled ignored)", p.Probes, p.Errors)
+	}
+	wantPorts := []ProfilePort{
+		{Port: "80", Scheme: "http", StatusCodes: []int{301}},
+		{Port: "443", Scheme: "https", StatusCodes: []int{200}},
+	}
+	if !reflect.DeepEqual(p.Ports, wantPorts) {
+		t.Errorf("ports = %+v, want %+v", p.Ports, wantPorts)
+	}
+	if fmt.Sprint(p.Titles, p.WebServers, p.Technologies) != "[Home] [nginx] [React]" {
+		t.Errorf("titles/webservers/tech = %v %v %v", p.Titles, p.WebServers, p.Technologies)
+	}
+	if len(p.Certificates) != 1 || p.Certificates[0].Fingerprint != "aa" {
+		t.Errorf("certificates = %+v, want the one leaf", p.Certificates)
+	}
+	if !p.RedirectsOffHost || fmt.Sprint(p.RedirectHosts) != "[www.example.com]" {
+		t.Errorf("redirects = %v %v, want www.example.com", p.RedirectsOffHost, p.RedirectHosts)
+	}
+	if profiles[1].RedirectsOffHost {
+		t.Error("other.test never left its host")
+	}
+}
+
+func TestProfiler_AliveCodes(t *testing.T) {
+	codes, _ := ParseStatusRanges("200-299,401,403")
+	profiler := NewProfiler(codes, standardPort)
+	for _, status := range []int{200, 401, 403, 302, 404} {
+		profiler.Record(ProbeResult{URL: fmt.Sprintf("http://example.com/%d", status), StatusCode: status, ChainStatusCodes: []int{status}})
+	}
+	profiler.Record(ProbeResult{URL: "http://example.com:8080/", Error: "refused"})
+
+	profiles := profiler.Profiles()
+	if len(profiles) != 1 || profiles[0].Alive != 3 || profiles[0].Probes != 6 {
+		t.Errorf("profiles = %+v, want 3 of 6 probes alive", profiles)
+	}
+}
+
+func TestProfiler_SetsAreBounded(t *testing.T) {
+	profiler := NewProfiler(StatusRanges{{200, 399}}, standardPort)
+	for i := 0; i < maxProfileValues+50; i++ {
+		profiler.Record(ProbeResult{
+			URL:              fmt.Sprintf("http://example.com:%d/", 1000+i),
+			Title:            fmt.Sprintf("title %d", i),
+			ChainStatusCodes: []int{200},
+		})
+	}
+	p := profiler.Profiles()[0]
+	if len(p.Ports) != maxProfileValues || len(p.Titles) != maxProfileValues {
+		t.Errorf("ports/titles = %d/%d, want both capped at %d", len(p.Ports), len(p.Titles), maxProfileValues)
+	}
+	if p.Probes != maxProfileValues+50 {
+		t.Errorf("probes = %d, want every result counted", p.Probes)
+	}
+}
@@ -0,0 +1,96 @@
+package output
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// TopRedirectDestinations is the number of final hosts listed in a
+// RedirectSummary.
+const TopRedirectDestinations = 20
+
+// RedirectSummary aggregates where succeeded results were redirected, to
+// spot many inputs landing on the same parking or consolidation host.
+type RedirectSummary struct {
+	OffHostInputs   int                   `json:"off_host_inputs"`   // Distinct inputs whose final host differs from the input host
+	CrossHostChains int                   `json:"cross_host_chains"` // Results whose redirect chain visited more than one host
+	HTTPSDowngrades int                   `json:"https_downgrades"`  // Results with an https -> http hop
+	TopDestinations []RedirectDestination `json:"top_destinations"`  // Most common final hosts of off-host inputs
+}
+
+// RedirectDestination counts the distinct inputs that ended on Host.
+type RedirectDestination struct {
+	Host   string `json:"host"`
+	Inputs int    `json:"inputs"`
+}
+
+// redirectTally collects a RedirectSummary. It is not safe for concurrent
+// use on its own; Tally serializes access.
+type redirectTally struct {
+	destinations    map[string]map[string]struct{} // final host -> inputs
+	inputs          map[string]struct{}            // off-host inputs, any destination
+	crossHostChains int
+	httpsDowngrades int
+}
+
+// record adds a succeeded result's chain_hosts and chain_origins.
+func (r *redirectTally) record(result ProbeResult) {
+	hosts := result.ChainHosts
+	if len(hosts) < 2 {
+		return
+	}
+	for _, host := range hosts[1:] {
+		if !strings.EqualFold(host, hosts[0]) {
+			r.crossHostChains++
+			break
+		}
+	}
+	for i := 1; i < len(result.ChainOrigins); i++ {
+		if strings.HasPrefix(result.ChainOrigins[i-1], "https://") && strings.HasPrefix(result.ChainOrigins[i], "http://") {
+			r.httpsDowngrades++
+			break
+		}
+	}
+
+	final := strings.ToLower(hosts[len(hosts)-1])
+	if final == strings.ToLower(hosts[0]) {
+		return
+	}
+	if r.destinations == nil {
+		r.destinations = make(map[string]map[string]struct{})
+		r.inputs = make(map[string]struct{})
+	}
+	if r.destinations[final] == nil {
+		r.destinations[final] = make(map[string]struct{})
+	}
+	r.destinations[final][result.Input] = struct{}{}
+	r.inputs[result.Input] = struct{}{}
+}
+
+// summary returns the top destinations by input count, ties by host name,
+// or nil when no result was redirected.
+func (r *redirectTally) summary() *RedirectSummary {
+	if len(r.inputs) == 0 && r.crossHostChains == 0 && r.httpsDowngrades == 0 {
+		return nil
+	}
+	top := make([]RedirectDestination, 0, len(r.destinations))
+	for host, inputs := range r.destinations {
+		top = append(top, RedirectDestination{Host: host, Inputs: len(inputs)})
+	}
+	slices.SortFunc(top, func(a, b RedirectDestination) int {
+		if c := cmp.Compare(b.Inputs, a.Inputs); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Host, b.Host)
+	})
+	if len(top) > TopRedirectDestinations {
+		top = top[:TopRedirectDestinations]
+	}
+	return &RedirectSummary{
+		OffHostInputs:   len(r.inputs),
+		CrossHostChains: r.crossHostChains,
+		HTTPSDowngrades: r.httpsDowngrades,
+		TopDestinations: top,
+	}
+}
@@ -1,6 +1,8 @@
 package output
 
 import (
+	"encoding/json"
+
 	"probeHTTP/internal/hash"
 )
 
@@ -32,48 +34,200 @@ type TLSInfo struct {
 
 // DiscoveredDomains holds domains found via TLS certificates and CSP headers.
 type DiscoveredDomains struct {
-	Domains       []string          `json:"domains,omitempty"`
-	DomainSources map[string]string `json:"domain_sources,omitempty"`
-	NewDomains    []string          `json:"new_domains,omitempty"`
+	Domains         []string          `json:"domains,omitempty"`
+	DomainSources   map[string]string `json:"domain_sources,omitempty"`
+	NewDomains      []string          `json:"new_domains,omitempty"`
+	WildcardDomains []string          `json:"wildcard_domains,omitempty"` // Wildcard names (*.example.com), also in Domains
+}
+
+// CacheInfo describes how an intermediary cache (CDN, reverse proxy) handled
+// the final response.
+type CacheInfo struct {
+	Status     string   `json:"status"`                // hit, miss, dynamic or unknown
+	AgeSeconds *int     `json:"age_seconds,omitempty"` // Age header value
+	Via        []string `json:"via,omitempty"`         // Via header entries, in order
+}
+
+// Cookie describes the security attributes of a cookie set along the redirect
+// chain. The value is never recorded.
+type Cookie struct {
+	Name     string `json:"name"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure"`
+	HTTPOnly bool   `json:"http_only"`
+	SameSite string `json:"same_site,omitempty"` // As sent: Lax, Strict, None or an unrecognized value
+	Hop      int    `json:"hop"`                 // Index into chain_status_codes of the response that set it
+}
+
+// Attempt describes one try of a probe retried with -retries.
+type Attempt struct {
+	Attempt    int    `json:"attempt"`               // 1-based
+	StatusCode int    `json:"status_code,omitempty"` // Set when the attempt got a response
+	ErrorType  string `json:"error_type,omitempty"`  // Set when the attempt failed
+	DurationMs int64  `json:"duration_ms"`
+	BackoffMs  int64  `json:"backoff_ms"` // Wait before this attempt
+}
+
+// Expansion records why a URL was probed: whether its scheme, port and path
+// came from the input itself, a default or an expansion flag.
+type Expansion struct {
+	SchemeSource string `json:"scheme_source"` // input, default-both, all-schemes, port-heuristic, hint, scan
+	PortSource   string `json:"port_source"`   // input, default, ignore-ports, custom-ports, scan
+	PathSource   string `json:"path_source"`   // input, variant
+}
+
+// CSPInfo summarizes the Content-Security-Policy headers of the final
+// response (-csp).
+type CSPInfo struct {
+	Present            bool     `json:"present"`              // Content-Security-Policy sent
+	ReportOnlyPresent  bool     `json:"report_only_present"`  // Content-Security-Policy-Report-Only sent
+	Directives         []string `json:"directives,omitempty"` // Directive names of all policies, sorted
+	ScriptUnsafeInline bool     `json:"script_unsafe_inline"` // 'unsafe-inline' in an enforced script-src (or default-src without script-src)
+	ScriptUnsafeEval   bool     `json:"script_unsafe_eval"`   // 'unsafe-eval' likewise
+	Domains            []string `json:"domains,omitempty"`    // Source domains of all policies, as with -dd
+}
+
+// Meta is a passthrough annotation from the input line. It is emitted as the
+// raw string, or as an object when it was parsed into key=value pairs.
+type Meta struct {
+	Raw    string
+	Fields map[string]string
+}
+
+// MarshalJSON emits Fields when set, otherwise the raw annotation string.
+func (m Meta) MarshalJSON() ([]byte, error) {
+	if m.Fields != nil {
+		return json.Marshal(m.Fields)
+	}
+	return json.Marshal(m.Raw)
+}
+
+// BestHop is the body analysis of the best hop of a chain ending in an
+// error (-analyze-best-hop).
+type BestHop struct {
+	Title         string `json:"title,omitempty"`
+	BodyMMH3      string `json:"body_mmh3,omitempty"`
+	ContentType   string `json:"content_type,omitempty"`
+	ContentLength int    `json:"content_length"`
 }
 
 // ProbeResult represents the JSON output for each probed URL
 type ProbeResult struct {
-	Timestamp        string   `json:"timestamp"`
-	Hash             hash.Hash `json:"hash"`
-	Port             string   `json:"port"`
-	URL              string   `json:"url"`
-	Input            string   `json:"input"`
-	FinalURL         string   `json:"final_url"`
-	Title            string   `json:"title"`
-	Scheme           string   `json:"scheme"`
-	WebServer        string   `json:"webserver"`
-	ContentType      string   `json:"content_type"`
-	Method           string   `json:"method"`
-	Host             string   `json:"host"`
-	HostIP           string   `json:"host_ip,omitempty"`
-	Path             string   `json:"path"`
-	Time             string   `json:"time"`
-	ChainStatusCodes []int    `json:"chain_status_codes"`
-	ChainHosts       []string `json:"chain_hosts"`
-	Words            int      `json:"words"`
-	Lines            int      `json:"lines"`
-	StatusCode       int      `json:"status_code"`
-	ContentLength    int      `json:"content_length"`
-	TLSVersion       string   `json:"tls_version,omitempty"`
-	CipherSuite      string   `json:"cipher_suite,omitempty"`
-	Protocol         string   `json:"protocol,omitempty"`
-	TLSConfigStrategy string  `json:"tls_config_strategy,omitempty"`
-	HSTS             bool     `json:"hsts,omitempty"`
-	HSTSHeader       string   `json:"hsts_header,omitempty"`
-	TLS              *TLSInfo `json:"tls,omitempty"`
-	Technologies     []string `json:"tech,omitempty"`
-	CDN              bool     `json:"cdn,omitempty"`
-	CDNName          string   `json:"cdn_name,omitempty"`
-	CNAME            string   `json:"cname,omitempty"`
-	Error            string   `json:"error,omitempty"`
-	SNIRequired      bool     `json:"sni_required,omitempty"`
-	Diagnostic       string   `json:"diagnostic,omitempty"`
+	RunID                   string             `json:"run_id,omitempty"`       // Shared by every result and artifact of one run (-run-id)
+	Timestamp               string             `json:"timestamp,omitempty"`    // Omitted with -no-timestamp; started_at to the second
+	StartedAt               string             `json:"started_at,omitempty"`   // RFC3339Nano, first request of the first attempt
+	CompletedAt             string             `json:"completed_at,omitempty"` // RFC3339Nano, final body read (or when a failed probe gave up)
+	Hash                    hash.Hash          `json:"hash"`
+	Port                    string             `json:"port"`
+	URL                     string             `json:"url"`
+	NormalizedURL           string             `json:"normalized_url,omitempty"` // Canonical form of the probe URL, for joining runs
+	Input                   string             `json:"input"`
+	Coalesced               bool               `json:"coalesced,omitempty"` // -coalesce: copied from a concurrent probe of the same normalized URL under another input
+	Meta                    *Meta              `json:"meta,omitempty"`
+	Expansion               *Expansion         `json:"expansion,omitempty"`
+	VariantOf               string             `json:"variant_of,omitempty"`            // -path-variants: probe URL of the base path this result is a variant of
+	SiblingSchemeProbed     *bool              `json:"sibling_scheme_probed,omitempty"` // -correlate-schemes: the other scheme of this input/host/port was probed too
+	Converged               bool               `json:"converged,omitempty"`             // -correlate-schemes: http and https siblings reached the same final URL
+	FinalURL                string             `json:"final_url"`
+	Title                   string             `json:"title"`
+	TitleTruncated          bool               `json:"title_truncated,omitempty"`
+	TitleSource             string             `json:"title_source,omitempty"`            // Where the title came from: html, og, twitter, json, xml or pdf; h1, path or host with -title-fallback
+	PageCategory            string             `json:"page_category,omitempty"`           // Default, parking or error page signature that matched (e.g. default-nginx, parked)
+	CanonicalURL            string             `json:"canonical_url,omitempty"`           // <link rel="canonical"> resolved against the final URL
+	Generator               string             `json:"generator,omitempty"`               // <meta name="generator"> content
+	Lang                    string             `json:"lang,omitempty"`                    // <html lang> attribute
+	HTMLAnalysisTruncated   bool               `json:"html_analysis_truncated,omitempty"` // HTML page too large or deeply nested to analyze fully; metadata may be incomplete
+	JSONValid               *bool              `json:"json_valid,omitempty"`              // JSON bodies: whether the (possibly truncated) body parses
+	JSONTopLevelKeys        []string           `json:"json_top_level_keys,omitempty"`     // JSON objects: first 20 keys in document order
+	FormsCount              int                `json:"forms_count,omitempty"`             // -forms: number of <form> elements
+	LoginForm               bool               `json:"login_form,omitempty"`              // -forms: a form has a password input
+	FormActions             []string           `json:"form_actions,omitempty"`            // -forms: distinct resolved form actions (max 10)
+	CrossOriginForm         bool               `json:"cross_origin_form,omitempty"`       // -forms: a form posts to another host
+	Scheme                  string             `json:"scheme"`
+	WebServer               string             `json:"webserver"`            // Server header; repeated headers joined with ", "
+	ServerRaw               []string           `json:"server_raw,omitempty"` // Every Server header value, when more than one was sent
+	PoweredBy               string             `json:"powered_by,omitempty"` // X-Powered-By header; repeated headers joined with ", "
+	ContentType             string             `json:"content_type"`
+	DetectedContentType     string             `json:"detected_content_type,omitempty"`
+	ContentDisposition      string             `json:"content_disposition,omitempty"` // Disposition type ("attachment", "inline"), or the raw header when malformed
+	AttachmentFilename      string             `json:"attachment_filename,omitempty"` // Content-Disposition filename, filename* preferred
+	Method                  string             `json:"method"`
+	Host                    string             `json:"host"`
+	HostIP                  string             `json:"host_ip,omitempty"`
+	VhostCandidate          string             `json:"vhost_candidate,omitempty"` // -vhost-list hostname sent as Host and SNI to the IP in host_ip
+	VhostDistinct           bool               `json:"vhost_distinct,omitempty"`  // The candidate's response differs from the IP's default vhost
+	PTR                     []string           `json:"ptr,omitempty"`
+	Path                    string             `json:"path"`
+	Time                    string             `json:"time"`
+	ServerDate              string             `json:"server_date,omitempty"`
+	ClockSkewMs             *int64             `json:"clock_skew_ms,omitempty"` // Server Date minus local receive time; positive = server ahead
+	ChainStatusCodes        []int              `json:"chain_status_codes"`      // Redirect chain of the winning attempt only; see AttemptStatusCodes
+	ChainHosts              []string           `json:"chain_hosts"`
+	ChainOrigins            []string           `json:"chain_origins,omitempty"` // scheme://host:port of every hop, aligned with ChainHosts
+	ChainMethods            []string           `json:"chain_methods,omitempty"`
+	ChainProtocols          []string           `json:"chain_protocols,omitempty"`      // Negotiated protocol of every hop, aligned with ChainStatusCodes
+	ChainContentTypes       []string           `json:"chain_content_types,omitempty"`  // Content-Type of every hop ("" without the header), aligned with ChainStatusCodes
+	ContentTypeChanged      bool               `json:"content_type_changed,omitempty"` // First and last hop have different media types
+	BestStatusCode          int                `json:"best_status_code,omitempty"`     // Chain ending in 4xx/5xx: status of the last hop with the best class (2xx, then 3xx)
+	BestHopIndex            *int               `json:"best_hop_index,omitempty"`       // Index of that hop in chain_status_codes
+	BestHopURL              string             `json:"best_hop_url,omitempty"`         // URL requested at that hop
+	BestHop                 *BestHop           `json:"best_hop,omitempty"`             // -analyze-best-hop: title and hash of that hop's body
+	ChainCertificates       []*CertificateInfo `json:"chain_certificates,omitempty"`   // -xtls-per-hop: leaf certificate per ChainHosts entry, null for plain-HTTP hops
+	Timings                 *Timings           `json:"timings,omitempty"`
+	ChainTimings            []*Timings         `json:"chain_timings,omitempty"`
+	RedirectWithoutLocation bool               `json:"redirect_without_location,omitempty"`
+	BlockedRedirect         string             `json:"blocked_redirect,omitempty"` // Redirect target with a non-HTTP scheme that stopped the chain
+	Words                   int                `json:"words"`                      // Counted on the decoded body up to the read limit
+	Lines                   int                `json:"lines"`
+	BodyEntropy             *float64           `json:"body_entropy,omitempty"` // Shannon entropy of the decoded body in bits per byte (0-8)
+	StatusCode              int                `json:"status_code"`
+	ContentLength           int                `json:"content_length"`
+	FramingAnomalies        []string           `json:"framing_anomalies,omitempty"`       // Suspicious response framing observed on the final hop
+	BodyTruncated           bool               `json:"body_truncated,omitempty"`          // Body exceeded the read limit; hashes and counts cover the truncated body
+	DecompressionTruncated  bool               `json:"decompression_truncated,omitempty"` // Gzip body inflated past the read limit and was cut off while decoding
+	BodyPartiallyRead       bool               `json:"body_partially_read,omitempty"`     // -early-exit stopped reading the body; counts and prefix_mmh3 cover the prefix read
+	TLSVersion              string             `json:"tls_version,omitempty"`
+	CipherSuite             string             `json:"cipher_suite,omitempty"`
+	Protocol                string             `json:"protocol,omitempty"`              // Protocol of the final hop
+	LegacyParse             bool               `json:"legacy_parse,omitempty"`          // Response came from the -http10 raw fallback and was parsed leniently
+	SchemeFallbackUsed      bool               `json:"scheme_fallback_used,omitempty"`  // -scheme-fallback: the opposite scheme answered after a cross-protocol failure
+	SchemeFallbackError     string             `json:"scheme_fallback_error,omitempty"` // Error of the original scheme when SchemeFallbackUsed
+	Hedged                  bool               `json:"hedged,omitempty"`                // -hedge sent a duplicate of the initial request
+	HedgeWinner             string             `json:"hedge_winner,omitempty"`          // Attempt that answered first: primary or hedge
+	TLSConfigStrategy       string             `json:"tls_config_strategy,omitempty"`
+	HSTS                    bool               `json:"hsts,omitempty"`
+	HSTSHeader              string             `json:"hsts_header,omitempty"`
+	CSP                     *CSPInfo           `json:"csp,omitempty"` // -csp: Content-Security-Policy summary of the final response
+	TLS                     *TLSInfo           `json:"tls,omitempty"`
+	Technologies            []string           `json:"tech,omitempty"`
+	MatchedKeywords         []string           `json:"matched_keywords,omitempty"`
+	CDN                     bool               `json:"cdn,omitempty"`
+	CDNName                 string             `json:"cdn_name,omitempty"`
+	Cache                   *CacheInfo         `json:"cache,omitempty"`
+	ContentEncoding         string             `json:"content_encoding,omitempty"`       // -compression-info: Content-Encoding of the final response
+	CompressionAssessment   string             `json:"compression_assessment,omitempty"` // -compression-info: ok, wasteful or missing
+	Cookies                 []Cookie           `json:"cookies,omitempty"`                // -cookie-info: cookies set along the chain, values omitted
+	AllowedMethods          []string           `json:"allowed_methods,omitzero"`         // -methods-check; empty when OPTIONS is refused
+	CORSAllowMethods        []string           `json:"cors_allow_methods,omitzero"`
+	CORSAllowOrigin         string             `json:"cors_allow_origin,omitempty"` // -cors-check
+	WAF                     bool               `json:"waf,omitempty"`
+	WAFName                 string             `json:"waf_name,omitempty"`
+	WAFConfidence           string             `json:"waf_confidence,omitempty"`
+	CNAME                   string             `json:"cname,omitempty"`
+	Error                   string             `json:"error,omitempty"`
+	ErrorType               string             `json:"error_type,omitempty"`
+	ProxyError              bool               `json:"proxy_error,omitempty"`          // -proxy: the failure came from the proxy, not the target
+	Debug                   string             `json:"debug,omitempty"`                // -debug-on-error: request/response transcript of a failed probe
+	Attempts                []Attempt          `json:"attempts,omitempty"`             // One entry per try when a probe was retried
+	AttemptStatusCodes      []int              `json:"attempt_status_codes,omitempty"` // Final status of every try when retried, 0 for tries without a response
+	Throttled               bool               `json:"throttled,omitempty"`
+	BytesDownloaded         int64              `json:"bytes_downloaded,omitempty"`
+	BytesUploaded           int64              `json:"bytes_uploaded,omitempty"`
+	ClientCertUsed          bool               `json:"client_cert_used,omitempty"`
+	PinMatch                *bool              `json:"pin_match,omitempty"` // -pin-file hosts: leaf certificate matches a pin
+	SNIRequired             bool               `json:"sni_required,omitempty"`
+	Diagnostic              string             `json:"diagnostic,omitempty"`
 	// TLS extraction fields (optional, enabled via --extract-tls)
 	DiscoveredDomains *DiscoveredDomains `json:"discovered_domains,omitempty"`
 	// Storage-related fields (optional, based on flags)
@@ -82,4 +236,5 @@ type ProbeResult struct {
 	RawRequest         string            `json:"raw_request,omitempty"`
 	RawResponse        string            `json:"raw_response,omitempty"`
 	StoredResponsePath string            `json:"stored_response_path,omitempty"`
+	StoredBodyPath     string            `json:"stored_body_path,omitempty"` // Final hop body written by -store-final-body
 }
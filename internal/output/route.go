@@ -0,0 +1,169 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Route writes results matching a condition to a dedicated file.
+// Routes are parsed from "field:value:path" expressions where field is one of
+// status, error_type, scheme or cdn:
+//
+//	status:200-299:alive.jsonl        status code ranges, single codes or classes (2xx), comma-separated
+//	error_type:tls_handshake:tls.jsonl error type equality, comma-separated alternatives
+//	scheme:https:https.jsonl          URL scheme equality
+//	cdn:true:cdn.jsonl                CDN detection result
+type Route struct {
+	Field string
+	Value string
+	Path  string
+	match func(ProbeResult) bool
+}
+
+// ParseRoute parses a route expression. The path is everything after the
+// second colon, so it may itself contain colons.
+func ParseRoute(expr string) (*Route, error) {
+	parts := strings.SplitN(expr, ":", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid route %q: expected field:value:path", expr)
+	}
+	field := strings.TrimSpace(parts[0])
+	value := strings.TrimSpace(parts[1])
+	path := strings.TrimSpace(parts[2])
+	if value == "" {
+		return nil, fmt.Errorf("invalid route %q: empty value", expr)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("invalid route %q: empty path", expr)
+	}
+
+	route := &Route{Field: field, Value: value, Path: path}
+	switch field {
+	case "status":
+		ranges, err := ParseStatusRanges(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %q: %v", expr, err)
+		}
+		route.match = func(r ProbeResult) bool {
+			return ranges.Contains(r.StatusCode)
+		}
+	case "error_type":
+		allowed := splitValues(value)
+		route.match = func(r ProbeResult) bool {
+			return r.ErrorType != "" && allowed[r.ErrorType]
+		}
+	case "scheme":
+		allowed := splitValues(strings.ToLower(value))
+		route.match = func(r ProbeResult) bool {
+			return allowed[strings.ToLower(r.Scheme)]
+		}
+	case "cdn":
+		want, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %q: cdn value must be true or false", expr)
+		}
+		route.match = func(r ProbeResult) bool {
+			return r.CDN == want
+		}
+	default:
+		return nil, fmt.Errorf("invalid route %q: unknown field %q (want status, error_type, scheme or cdn)", expr, field)
+	}
+	return route, nil
+}
+
+// Match reports whether the result satisfies the route's condition.
+func (r *Route) Match(result ProbeResult) bool {
+	return r.match(result)
+}
+
+// splitValues turns a comma-separated value list into a lookup set.
+func splitValues(value string) map[string]bool {
+	set := make(map[string]bool)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
+
+// Router fans results out to every matching route's file.
+// It is not safe for concurrent use; results are written from main's single
+// consumer loop.
+type Router struct {
+	routes   []*Route
+	writerOf []int // index into files/writers for each route
+	files    []*os.File
+	writers  []*bufio.Writer
+}
+
+// NewRouter parses the route expressions and creates their output files.
+// Routes sharing a path share a single file.
+func NewRouter(exprs []string) (*Router, error) {
+	// Parse everything first so a bad expression doesn't leave files behind
+	routes := make([]*Route, 0, len(exprs))
+	for _, expr := range exprs {
+		route, err := ParseRoute(expr)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+
+	router := &Router{}
+	byPath := make(map[string]int)
+	for _, route := range routes {
+		if _, ok := byPath[route.Path]; !ok {
+			file, err := os.Create(route.Path)
+			if err != nil {
+				router.Close()
+				return nil, fmt.Errorf("failed to create route file: %v", err)
+			}
+			byPath[route.Path] = len(router.files)
+			router.files = append(router.files, file)
+			router.writers = append(router.writers, bufio.NewWriter(file))
+		}
+		router.routes = append(router.routes, route)
+		router.writerOf = append(router.writerOf, byPath[route.Path])
+	}
+	return router, nil
+}
+
+// Write appends line to the file of every route matching result. A result
+// matching several routes that share a file is written there once.
+func (r *Router) Write(result ProbeResult, line []byte) error {
+	written := make(map[int]bool)
+	for i, route := range r.routes {
+		w := r.writerOf[i]
+		if written[w] || !route.Match(result) {
+			continue
+		}
+		written[w] = true
+		if _, err := r.writers[w].Write(line); err != nil {
+			return err
+		}
+		if err := r.writers[w].WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes all route files, returning the first error.
+func (r *Router) Close() error {
+	var firstErr error
+	for i, file := range r.files {
+		if err := r.writers[i].Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	r.files = nil
+	r.writers = nil
+	return firstErr
+}
@@ -0,0 +1,112 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		expr   string
+		result ProbeResult
+		want   bool
+	}{
+		{"status:200-299:alive.jsonl", ProbeResult{StatusCode: 204}, true},
+		{"status:200-299:alive.jsonl", ProbeResult{StatusCode: 301}, false},
+		{"status:2xx:alive.jsonl", ProbeResult{StatusCode: 200}, true},
+		{"status:401,403:auth.jsonl", ProbeResult{StatusCode: 403}, true},
+		{"status:401,403:auth.jsonl", ProbeResult{StatusCode: 404}, false},
+		{"status:200:ok.jsonl", ProbeResult{Error: "Request failed"}, false},
+		{"error_type:tls_handshake:tls.jsonl", ProbeResult{ErrorType: "tls_handshake"}, true},
+		{"error_type:tls_handshake:tls.jsonl", ProbeResult{ErrorType: "timeout"}, false},
+		{"error_type:timeout,dns:net.jsonl", ProbeResult{ErrorType: "dns"}, true},
+		{"scheme:https:https.jsonl", ProbeResult{Scheme: "https"}, true},
+		{"scheme:HTTPS:https.jsonl", ProbeResult{Scheme: "http"}, false},
+		{"cdn:true:cdn.jsonl", ProbeResult{CDN: true}, true},
+		{"cdn:false:origin.jsonl", ProbeResult{CDN: true}, false},
+	}
+
+	for _, tt := range tests {
+		route, err := ParseRoute(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseRoute(%q): %v", tt.expr, err)
+		}
+		if got := route.Match(tt.result); got != tt.want {
+			t.Errorf("ParseRoute(%q).Match(%+v) = %v, want %v", tt.expr, tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestParseRoute_PathWithColon(t *testing.T) {
+	route, err := ParseRoute(`status:200:C:\results\alive.jsonl`)
+	if err != nil {
+		t.Fatalf("ParseRoute: %v", err)
+	}
+	if route.Path != `C:\results\alive.jsonl` {
+		t.Errorf("Path = %q, want %q", route.Path, `C:\results\alive.jsonl`)
+	}
+}
+
+func TestParseRoute_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"status:200",
+		"status::alive.jsonl",
+		"status:200:",
+		"status:abc:alive.jsonl",
+		"status:299-200:alive.jsonl",
+		"status:99:alive.jsonl",
+		"status:6xx:alive.jsonl",
+		"cdn:maybe:cdn.jsonl",
+		"title:admin:admin.jsonl",
+	} {
+		if _, err := ParseRoute(expr); err == nil {
+			t.Errorf("ParseRoute(%q) expected error", expr)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	dir := t.TempDir()
+	alive := filepath.Join(dir, "alive.jsonl")
+	tls := filepath.Join(dir, "tls.jsonl")
+
+	router, err := NewRouter([]string{
+		"status:200-299:" + alive,
+		"scheme:https:" + alive,
+		"error_type:tls_handshake:" + tls,
+	})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	router.Write(ProbeResult{StatusCode: 200, Scheme: "https"}, []byte(`{"a":1}`))
+	router.Write(ProbeResult{StatusCode: 500, Scheme: "http"}, []byte(`{"b":2}`))
+	router.Write(ProbeResult{Error: "x509", ErrorType: "tls_handshake"}, []byte(`{"c":3}`))
+	if err := router.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, _ := os.ReadFile(alive)
+	if got := strings.TrimSpace(string(data)); got != `{"a":1}` {
+		t.Errorf("alive file = %q, want a single line for the matching result", got)
+	}
+	data, _ = os.ReadFile(tls)
+	if got := strings.TrimSpace(string(data)); got != `{"c":3}` {
+		t.Errorf("tls file = %q, want %q", got, `{"c":3}`)
+	}
+}
+
+func TestNewRouter_InvalidCreatesNoFiles(t *testing.T) {
+	dir := t.TempDir()
+	alive := filepath.Join(dir, "alive.jsonl")
+
+	if _, err := NewRouter([]string{"status:200:" + alive, "bogus:1:" + alive}); err == nil {
+		t.Fatal("NewRouter expected error for invalid expression")
+	}
+	if _, err := os.Stat(alive); !os.IsNotExist(err) {
+		t.Errorf("route file should not be created when an expression is invalid")
+	}
+}
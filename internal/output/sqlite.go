@@ -0,0 +1,249 @@
+package output
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	// Pure Go SQLite driver: -sqlite works in the CGO_ENABLED=0 static builds
+	_ "modernc.org/sqlite"
+)
+
+// DefaultSQLiteBatch is how many results -sqlite writes per transaction.
+const DefaultSQLiteBatch = 500
+
+// sqliteSchema creates the -sqlite tables. probes holds one row per result
+// with its main scalar fields and the full JSON; chains one row per redirect
+// hop; certificates one row per unique certificate, referenced by the
+// cert_fingerprint columns of the other two.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS probes (
+	run_id           TEXT NOT NULL,
+	normalized_url   TEXT NOT NULL,
+	input            TEXT,
+	url              TEXT,
+	final_url        TEXT,
+	scheme           TEXT,
+	host             TEXT,
+	port             TEXT,
+	path             TEXT,
+	host_ip          TEXT,
+	cname            TEXT,
+	status_code      INTEGER,
+	content_length   INTEGER,
+	content_type     TEXT,
+	title            TEXT,
+	webserver        TEXT,
+	words            INTEGER,
+	lines            INTEGER,
+	body_mmh3        TEXT,
+	header_mmh3      TEXT,
+	tls_version      TEXT,
+	protocol         TEXT,
+	cert_fingerprint TEXT,
+	error            TEXT,
+	error_type       TEXT,
+	timestamp        TEXT,
+	result_json      TEXT NOT NULL,
+	PRIMARY KEY (run_id, normalized_url)
+);
+CREATE TABLE IF NOT EXISTS chains (
+	run_id           TEXT NOT NULL,
+	normalized_url   TEXT NOT NULL,
+	hop              INTEGER NOT NULL,
+	status_code      INTEGER,
+	host             TEXT,
+	origin           TEXT,
+	method           TEXT,
+	protocol         TEXT,
+	content_type     TEXT,
+	cert_fingerprint TEXT,
+	PRIMARY KEY (run_id, normalized_url, hop)
+);
+CREATE TABLE IF NOT EXISTS certificates (
+	fingerprint    TEXT PRIMARY KEY,
+	subject_cn     TEXT,
+	issuer_cn      TEXT,
+	sans           TEXT,
+	not_before     TEXT,
+	not_after      TEXT,
+	serial_number  TEXT,
+	is_expired     INTEGER,
+	is_self_signed INTEGER,
+	key_algorithm  TEXT,
+	key_size       INTEGER,
+	sig_algorithm  TEXT,
+	first_run_id   TEXT,
+	last_run_id    TEXT
+);
+CREATE INDEX IF NOT EXISTS probes_host ON probes (host);
+`
+
+const (
+	sqliteInsertProbe = `INSERT OR REPLACE INTO probes (run_id, normalized_url, input, url, final_url, scheme, host, port, path,
+	host_ip, cname, status_code, content_length, content_type, title, webserver, words, lines, body_mmh3, header_mmh3,
+	tls_version, protocol, cert_fingerprint, error, error_type, timestamp, result_json)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	sqliteDeleteChain = `DELETE FROM chains WHERE run_id = ? AND normalized_url = ?`
+	sqliteInsertHop   = `INSERT INTO chains (run_id, normalized_url, hop, status_code, host, origin, method, protocol, content_type, cert_fingerprint)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	sqliteUpsertCert = `INSERT INTO certificates (fingerprint, subject_cn, issuer_cn, sans, not_before, not_after, serial_number,
+	is_expired, is_self_signed, key_algorithm, key_size, sig_algorithm, first_run_id, last_run_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (fingerprint) DO UPDATE SET is_expired = excluded.is_expired, last_run_id = excluded.last_run_id`
+)
+
+// SQLiteWriter writes results to a SQLite database for -sqlite. Results
+// are written in transactions of batch results; a result written again
+// under the same run ID replaces the earlier row and its chain. It is not
+// safe for concurrent use.
+type SQLiteWriter struct {
+	db      *sql.DB
+	runID   string
+	batch   int
+	pending int
+	tx      *sql.Tx
+	probe   *sql.Stmt
+	unchain *sql.Stmt
+	hop     *sql.Stmt
+	cert    *sql.Stmt
+}
+
+// NewSQLiteWriter opens or creates the database at path and its tables.
+// batch < 1 selects DefaultSQLiteBatch.
+func NewSQLiteWriter(path, runID string, batch int) (*SQLiteWriter, error) {
+	if batch < 1 {
+		batch = DefaultSQLiteBatch
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// One connection: SQLite has a single writer, and the transaction
+	// must stay on the connection that began it
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA journal_mode = WAL; PRAGMA busy_timeout = 5000;" + sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+	return &SQLiteWriter{db: db, runID: runID, batch: batch}, nil
+}
+
+// Write adds one result. jsonData is its JSON encoding, stored as is.
+func (w *SQLiteWriter) Write(result ProbeResult, jsonData []byte) error {
+	if w.tx == nil {
+		if err := w.begin(); err != nil {
+			return err
+		}
+	}
+	if err := w.insert(result, jsonData); err != nil {
+		return fmt.Errorf("failed to write %s to sqlite: %w", result.NormalizedURL, err)
+	}
+	w.pending++
+	if w.pending >= w.batch {
+		return w.commit()
+	}
+	return nil
+}
+
+// Close commits the pending batch and closes the database. Called after
+// the results of an interrupted run have drained, it keeps every result
+// written so far.
+func (w *SQLiteWriter) Close() error {
+	err := w.commit()
+	if closeErr := w.db.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close sqlite database: %w", closeErr)
+	}
+	return err
+}
+
+func (w *SQLiteWriter) begin() error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin sqlite transaction: %w", err)
+	}
+	stmts := make([]*sql.Stmt, 4)
+	for i, query := range []string{sqliteInsertProbe, sqliteDeleteChain, sqliteInsertHop, sqliteUpsertCert} {
+		if stmts[i], err = tx.Prepare(query); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to prepare sqlite statement: %w", err)
+		}
+	}
+	w.tx = tx
+	w.probe, w.unchain, w.hop, w.cert = stmts[0], stmts[1], stmts[2], stmts[3]
+	return nil
+}
+
+// commit ends the current transaction, if any. Its statements are closed
+// with it.
+func (w *SQLiteWriter) commit() error {
+	if w.tx == nil {
+		return nil
+	}
+	err := w.tx.Commit()
+	w.tx, w.pending = nil, 0
+	if err != nil {
+		return fmt.Errorf("failed to commit sqlite batch: %w", err)
+	}
+	return nil
+}
+
+func (w *SQLiteWriter) insert(r ProbeResult, jsonData []byte) error {
+	key := r.NormalizedURL
+	if key == "" {
+		key = r.URL
+	}
+
+	var tlsVersion, leaf string
+	if r.TLS != nil {
+		tlsVersion = r.TLS.Version
+		if r.TLS.Certificate != nil {
+			leaf = r.TLS.Certificate.Fingerprint
+			if err := w.upsertCert(r.TLS.Certificate); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := w.probe.Exec(w.runID, key, r.Input, r.URL, r.FinalURL, r.Scheme, r.Host, r.Port, r.Path,
+		r.HostIP, r.CNAME, r.StatusCode, r.ContentLength, r.ContentType, r.Title, r.WebServer, r.Words, r.Lines,
+		r.Hash.BodyMMH3, r.Hash.HeaderMMH3, tlsVersion, r.Protocol, leaf, r.Error, r.ErrorType, r.Timestamp,
+		string(jsonData)); err != nil {
+		return err
+	}
+
+	// A rerun may follow a different number of hops
+	if _, err := w.unchain.Exec(w.runID, key); err != nil {
+		return err
+	}
+	for i, status := range r.ChainStatusCodes {
+		var fingerprint string
+		if i < len(r.ChainCertificates) && r.ChainCertificates[i] != nil {
+			fingerprint = r.ChainCertificates[i].Fingerprint
+			if err := w.upsertCert(r.ChainCertificates[i]); err != nil {
+				return err
+			}
+		}
+		if _, err := w.hop.Exec(w.runID, key, i, status, valueAt(r.ChainHosts, i), valueAt(r.ChainOrigins, i),
+			valueAt(r.ChainMethods, i), valueAt(r.ChainProtocols, i), valueAt(r.ChainContentTypes, i), fingerprint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *SQLiteWriter) upsertCert(c *CertificateInfo) error {
+	if c.Fingerprint == "" {
+		return nil
+	}
+	_, err := w.cert.Exec(c.Fingerprint, c.SubjectCN, c.IssuerCN, strings.Join(c.SANs, ","), c.NotBefore, c.NotAfter,
+		c.SerialNumber, c.IsExpired, c.IsSelfSigned, c.KeyAlgorithm, c.KeySize, c.SigAlgorithm, w.runID, w.runID)
+	return err
+}
+
+// valueAt returns values[i], or "" past its end.
+func valueAt(values []string, i int) string {
+	if i < len(values) {
+		return values[i]
+	}
+	return ""
+}
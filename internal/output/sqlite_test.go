@@ -0,0 +1,133 @@
+package output
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func sqliteResult(url string, status int) ProbeResult {
+	cert := &CertificateInfo{SubjectCN: "example.com", SANs: []string{"example.com", "www.example.com"}, Fingerprint: "ab:cd"}
+	return ProbeResult{
+		URL:               url,
+		NormalizedURL:     url,
+		Input:             "example.com",
+		FinalURL:          url + "home",
+		Host:              "example.com",
+		StatusCode:        status,
+		Title:             "Home",
+		TLS:               &TLSInfo{Version: "TLS1.3", Certificate: cert},
+		ChainStatusCodes:  []int{301, status},
+		ChainHosts:        []string{"example.com", "example.com"},
+		ChainContentTypes: []string{"text/html", "text/html"},
+		ChainCertificates: []*CertificateInfo{cert, cert},
+	}
+}
+
+func openSQLite(t *testing.T, path, runID string, batch int) *SQLiteWriter {
+	t.Helper()
+	w, err := NewSQLiteWriter(path, runID, batch)
+	if err != nil {
+		t.Fatalf("NewSQLiteWriter: %v", err)
+	}
+	return w
+}
+
+func queryInt(t *testing.T, db *sql.DB, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestSQLiteWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probes.db")
+	w := openSQLite(t, path, "run1", 2)
+	results := []ProbeResult{
+		sqliteResult("https://example.com/", 200),
+		sqliteResult("https://example.com:8443/", 403),
+		{NormalizedURL: "https://down.example.com/", Input: "down.example.com", Error: "refused", ErrorType: ErrorTypeConnectionRefused},
+	}
+	for _, r := range results {
+		if err := w.Write(r, []byte(`{"url":"`+r.URL+`"}`)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if n := queryInt(t, db, "SELECT COUNT(*) FROM probes WHERE run_id = 'run1'"); n != 3 {
+		t.Errorf("probes = %d, want 3 (the last one committed by Close)", n)
+	}
+	var title, tlsVersion, fingerprint string
+	var status int
+	if err := db.QueryRow("SELECT status_code, title, tls_version, cert_fingerprint FROM probes WHERE normalized_url = ?", "https://example.com/").
+		Scan(&status, &title, &tlsVersion, &fingerprint); err != nil {
+		t.Fatal(err)
+	}
+	if status != 200 || title != "Home" || tlsVersion != "TLS1.3" || fingerprint != "ab:cd" {
+		t.Errorf("probe row = %d %q %q %q", status, title, tlsVersion, fingerprint)
+	}
+	if n := queryInt(t, db, "SELECT COUNT(*) FROM probes WHERE error_type = ?", ErrorTypeConnectionRefused); n != 1 {
+		t.Errorf("failed probes = %d, want 1", n)
+	}
+	if n := queryInt(t, db, "SELECT COUNT(*) FROM chains WHERE normalized_url = ? AND cert_fingerprint = 'ab:cd'", "https://example.com/"); n != 2 {
+		t.Errorf("chain hops = %d, want 2", n)
+	}
+	var sans string
+	if err := db.QueryRow("SELECT sans FROM certificates WHERE fingerprint = 'ab:cd'").Scan(&sans); err != nil || sans != "example.com,www.example.com" {
+		t.Errorf("certificate sans = %q, %v", sans, err)
+	}
+	if n := queryInt(t, db, "SELECT COUNT(*) FROM certificates"); n != 1 {
+		t.Errorf("certificates = %d, want 1", n)
+	}
+}
+
+// Writing a URL again under the same run ID replaces its rows; another run
+// ID adds rows next to them.
+func TestSQLiteWriter_UpsertOnRerun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probes.db")
+	for _, run := range []struct {
+		id     string
+		result ProbeResult
+	}{
+		{"nightly", sqliteResult("https://example.com/", 200)},
+		{"nightly", ProbeResult{NormalizedURL: "https://example.com/", StatusCode: 500, ChainStatusCodes: []int{500}}},
+		{"weekly", sqliteResult("https://example.com/", 200)},
+	} {
+		w := openSQLite(t, path, run.id, 0)
+		if err := w.Write(run.result, []byte("{}")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if n := queryInt(t, db, "SELECT status_code FROM probes WHERE run_id = 'nightly'"); n != 500 {
+		t.Errorf("rerun status = %d, want the replacing 500", n)
+	}
+	if n := queryInt(t, db, "SELECT COUNT(*) FROM chains WHERE run_id = 'nightly'"); n != 1 {
+		t.Errorf("rerun chain hops = %d, want 1", n)
+	}
+	if n := queryInt(t, db, "SELECT COUNT(*) FROM probes"); n != 2 {
+		t.Errorf("probes = %d, want one per run ID", n)
+	}
+	var first, last string
+	if err := db.QueryRow("SELECT first_run_id, last_run_id FROM certificates").Scan(&first, &last); err != nil || first != "nightly" || last != "weekly" {
+		t.Errorf("certificate runs = %q..%q, %v", first, last, err)
+	}
+}
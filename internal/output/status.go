@@ -0,0 +1,59 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusRanges is a set of inclusive [lo, hi] status code ranges.
+type StatusRanges [][2]int
+
+// Contains reports whether code falls in any of the ranges.
+func (s StatusRanges) Contains(code int) bool {
+	for _, sr := range s {
+		if code >= sr[0] && code <= sr[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAlive reports whether result counts as a live service under alive,
+// the -alive-codes set: the probe got a response and its final status is in
+// alive. Every liveness-derived output uses it, so 401 or 403 can count as
+// live everywhere at once.
+func IsAlive(result ProbeResult, alive StatusRanges) bool {
+	return result.Error == "" && alive.Contains(result.StatusCode)
+}
+
+// ParseStatusRanges parses comma-separated status codes, ranges (200-299) and
+// classes (2xx) into inclusive ranges.
+func ParseStatusRanges(value string) (StatusRanges, error) {
+	var ranges StatusRanges
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if len(part) == 3 && strings.HasSuffix(part, "xx") && part[0] >= '1' && part[0] <= '5' {
+			base := int(part[0]-'0') * 100
+			ranges = append(ranges, [2]int{base, base + 99})
+			continue
+		}
+		lo, hi := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			lo, hi = part[:i], part[i+1:]
+		}
+		loN, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status %q", part)
+		}
+		hiN, err := strconv.Atoi(hi)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status %q", part)
+		}
+		if loN < 100 || hiN > 599 || loN > hiN {
+			return nil, fmt.Errorf("status range %q out of bounds (100-599)", part)
+		}
+		ranges = append(ranges, [2]int{loN, hiN})
+	}
+	return ranges, nil
+}
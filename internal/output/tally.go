@@ -0,0 +1,86 @@
+package output
+
+import (
+	"maps"
+	"sync"
+)
+
+// Tally counts result outcomes for a run. It is safe for concurrent use.
+type Tally struct {
+	mu         sync.Mutex
+	succeeded  int
+	failed     int
+	cancelled  int
+	filtered   int
+	errorTypes map[string]int
+	categories map[string]int
+	redirects  redirectTally
+}
+
+// TallyCounts is a point-in-time copy of a Tally.
+type TallyCounts struct {
+	Succeeded      int              `json:"succeeded"`
+	Failed         int              `json:"failed"`
+	Cancelled      int              `json:"cancelled"`
+	Filtered       int              `json:"filtered"`
+	ErrorTypes     map[string]int   `json:"failed_by_error_type"`
+	PageCategories map[string]int   `json:"page_categories,omitempty"` // Succeeded results by page_category
+	Redirects      *RedirectSummary `json:"redirects,omitempty"`       // Where succeeded results were redirected
+}
+
+// NewTally creates an empty Tally.
+func NewTally() *Tally {
+	return &Tally{errorTypes: make(map[string]int), categories: make(map[string]int)}
+}
+
+// Record counts a result as succeeded (and under its page_category, if
+// any, and its redirect destination), as cancelled by shutdown or, if it
+// carries any other error, as failed under its error type.
+func (t *Tally) Record(result ProbeResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if result.Error == "" {
+		t.succeeded++
+		if result.PageCategory != "" {
+			t.categories[result.PageCategory]++
+		}
+		t.redirects.record(result)
+		return
+	}
+	if result.ErrorType == ErrorTypeCancelled {
+		t.cancelled++
+		return
+	}
+	t.failed++
+	errorType := result.ErrorType
+	if errorType == "" {
+		errorType = ErrorTypeUnknown
+	}
+	t.errorTypes[errorType]++
+}
+
+// RecordFiltered counts a result that was dropped before being written.
+func (t *Tally) RecordFiltered() {
+	t.mu.Lock()
+	t.filtered++
+	t.mu.Unlock()
+}
+
+// Counts returns a copy of the current counts.
+func (t *Tally) Counts() TallyCounts {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	errorTypes := make(map[string]int, len(t.errorTypes))
+	for k, v := range t.errorTypes {
+		errorTypes[k] = v
+	}
+	return TallyCounts{
+		Succeeded:      t.succeeded,
+		Failed:         t.failed,
+		Cancelled:      t.cancelled,
+		Filtered:       t.filtered,
+		ErrorTypes:     errorTypes,
+		PageCategories: maps.Clone(t.categories),
+		Redirects:      t.redirects.summary(),
+	}
+}
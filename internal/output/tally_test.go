@@ -0,0 +1,112 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTally_CancelledCountedSeparately(t *testing.T) {
+	tally := NewTally()
+	tally.Record(ProbeResult{})
+	tally.Record(ProbeResult{Error: "dial tcp: connection refused", ErrorType: ErrorTypeConnectionRefused})
+	tally.Record(ProbeResult{Error: "cancelled", ErrorType: ErrorTypeCancelled})
+	tally.Record(ProbeResult{Error: "cancelled", ErrorType: ErrorTypeCancelled})
+
+	counts := tally.Counts()
+	if counts.Succeeded != 1 || counts.Failed != 1 || counts.Cancelled != 2 {
+		t.Errorf("counts = %+v, want 1 succeeded, 1 failed, 2 cancelled", counts)
+	}
+	if _, ok := counts.ErrorTypes[ErrorTypeCancelled]; ok {
+		t.Error("cancelled results should not appear in failed_by_error_type")
+	}
+}
+
+func TestTally_PageCategories(t *testing.T) {
+	tally := NewTally()
+	tally.Record(ProbeResult{PageCategory: "parked"})
+	tally.Record(ProbeResult{PageCategory: "parked"})
+	tally.Record(ProbeResult{PageCategory: "default-nginx"})
+	tally.Record(ProbeResult{})
+	tally.Record(ProbeResult{Error: "timeout", ErrorType: ErrorTypeTimeout, PageCategory: "parked"})
+
+	counts := tally.Counts()
+	if counts.PageCategories["parked"] != 2 || counts.PageCategories["default-nginx"] != 1 || len(counts.PageCategories) != 2 {
+		t.Errorf("page categories = %v, want parked 2, default-nginx 1", counts.PageCategories)
+	}
+}
+
+// redirected is a succeeded result of input whose chain ran through origins
+// (scheme://host:port).
+func redirected(input string, origins ...string) ProbeResult {
+	result := ProbeResult{Input: input, ChainOrigins: origins}
+	for _, origin := range origins {
+		_, hostPort, _ := strings.Cut(origin, "://")
+		host, _, _ := strings.Cut(hostPort, ":")
+		result.ChainHosts = append(result.ChainHosts, host)
+	}
+	return result
+}
+
+func TestTally_RedirectDestinations(t *testing.T) {
+	tally := NewTally()
+	for _, input := range []string{"a.example", "b.example", "c.example"} {
+		tally.Record(redirected(input, "https://"+input+":443", "https://parking.example:443"))
+	}
+	// One input reaching the same destination over two schemes counts once
+	tally.Record(redirected("a.example", "http://a.example:80", "https://parking.example:443"))
+	tally.Record(redirected("d.example", "https://d.example:443", "http://Consolidated.example:80"))
+	tally.Record(redirected("e.example", "https://e.example:443", "https://consolidated.example:443"))
+	tally.Record(redirected("f.example", "http://f.example:80", "https://f.example:443"))                            // same host
+	tally.Record(redirected("g.example", "https://g.example:443", "https://cdn.example:443", "http://g.example:80")) // back home
+	tally.Record(redirected("h.example", "https://h.example:443"))                                                   // no redirect
+	failed := redirected("i.example", "https://i.example:443", "https://parking.example:443")
+	failed.Error = "partial body read: unexpected EOF"
+	tally.Record(failed)
+
+	got := tally.Counts().Redirects
+	if got == nil {
+		t.Fatal("no redirect summary")
+	}
+	want := []RedirectDestination{{"parking.example", 3}, {"consolidated.example", 2}}
+	if !slices.Equal(got.TopDestinations, want) {
+		t.Errorf("top destinations = %v, want %v", got.TopDestinations, want)
+	}
+	if got.OffHostInputs != 5 || got.CrossHostChains != 7 || got.HTTPSDowngrades != 2 {
+		t.Errorf("off-host inputs %d, cross-host chains %d, downgrades %d; want 5, 7, 2",
+			got.OffHostInputs, got.CrossHostChains, got.HTTPSDowngrades)
+	}
+}
+
+func TestTally_RedirectDestinationsTopN(t *testing.T) {
+	tally := NewTally()
+	for i := 0; i < TopRedirectDestinations+5; i++ {
+		dest := fmt.Sprintf("dest%02d.example", i)
+		// dest00 gets the most inputs, then ties ordered by host
+		inputs := 1
+		if i == 0 {
+			inputs = 3
+		}
+		for j := 0; j < inputs; j++ {
+			input := fmt.Sprintf("in%d-%d.example", i, j)
+			tally.Record(redirected(input, "https://"+input+":443", "https://"+dest+":443"))
+		}
+	}
+
+	top := tally.Counts().Redirects.TopDestinations
+	if len(top) != TopRedirectDestinations {
+		t.Fatalf("got %d destinations, want %d", len(top), TopRedirectDestinations)
+	}
+	if top[0] != (RedirectDestination{"dest00.example", 3}) || top[1].Host != "dest01.example" || top[len(top)-1].Host != "dest19.example" {
+		t.Errorf("top destinations = %v, want dest00 first, then by host", top)
+	}
+}
+
+func TestTally_NoRedirectSummaryWithoutRedirects(t *testing.T) {
+	tally := NewTally()
+	tally.Record(ProbeResult{Input: "a.example", ChainHosts: []string{"a.example"}})
+	if got := tally.Counts().Redirects; got != nil {
+		t.Errorf("redirects = %+v, want none", got)
+	}
+}
@@ -0,0 +1,12 @@
+package output
+
+// Timings breaks a request down into phases, in milliseconds. A nil phase
+// was not observed: reused connections skip DNS, connect and TLS, and HTTP/3
+// reports only some phases.
+type Timings struct {
+	DNSMs      *float64 `json:"dns_ms"`
+	ConnectMs  *float64 `json:"connect_ms"`
+	TLSMs      *float64 `json:"tls_ms"`
+	TTFBMs     *float64 `json:"ttfb_ms"`     // request start to first response byte
+	TransferMs *float64 `json:"transfer_ms"` // first byte to end of body (final hop only)
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"probeHTTP/internal/hash"
+)
+
+// maxJSONTopLevelKeys caps how many top-level object keys are reported.
+const maxJSONTopLevelKeys = 20
+
+// BodyKindText marks text bodies without a dedicated analyzer.
+const BodyKindText = "text"
+
+// BodyAnalysis is everything derived from a response body: the byte-level
+// metrics of every body, and the content-type-specific analysis of textual
+// and PDF bodies.
+type BodyAnalysis struct {
+	MMH3     string        // body_mmh3, same as hash.CalculateMMH3
+	Entropy  float64       // Byte entropy in bits per byte, see hash.CalculateBodyDigest
+	Kind     string        // TitleSource kind the body was analyzed as (html, json, xml, pdf), BodyKindText, or "" when skipped
+	Meta     HTMLMeta      // Page metadata for HTML; only Title and TitleSource for JSON, XML and PDF
+	Forms    []HTMLForm    // <form> elements of HTML bodies, with HTMLOptions.Forms
+	JSON     *JSONAnalysis // Set for JSON bodies
+	Text     bool          // Textual content type; Words and Lines are counted
+	Words    int
+	Lines    int
+	Keywords []string // Configured keywords found in textual bodies, with AnalysisOptions.Keywords
+}
+
+// AnalysisOptions selects the optional parts of AnalyzeBody. The No fields
+// skip parts that run by default (-disable-analysis).
+type AnalysisOptions struct {
+	HTML     HTMLOptions     // Optional HTML extractions
+	Keywords *KeywordMatcher // Keywords to search textual bodies for (nil = none)
+	NoHash   bool            // Leave MMH3 empty
+	NoMeta   bool            // Leave Meta empty; HTML is still parsed for HTML.Forms
+	NoCounts bool            // Leave Words and Lines 0
+}
+
+// JSONAnalysis describes a JSON body.
+type JSONAnalysis struct {
+	Valid        bool     // The whole body parses as JSON (false for truncated bodies)
+	TopLevelKeys []string // First maxJSONTopLevelKeys keys of an object, in document order
+}
+
+// AnalyzeBody derives all body fields in a bounded number of passes: one
+// over the bytes for the hash, entropy and, for text, words and lines; one
+// through the analyzer for the content type; and one keyword search. An
+// empty contentType is decided from the body like ExtractMeta. Binary types
+// other than PDF only get the byte-level metrics.
+func AnalyzeBody(body []byte, contentType string, opts AnalysisOptions) BodyAnalysis {
+	isText := IsTextContentType(contentType)
+	digest := hash.DigestBodyParts(body, !opts.NoHash, isText && !opts.NoCounts)
+	analysis := BodyAnalysis{MMH3: digest.MMH3, Entropy: digest.Entropy}
+	if !isText && !IsPDFContentType(contentType) {
+		return analysis
+	}
+
+	bodyStr := string(body)
+	analysis.Text = isText
+	analysis.Kind = titleKind(bodyStr, contentType)
+	switch analysis.Kind {
+	case TitleSourceJSON:
+		analysis.JSON = AnalyzeJSON(body)
+		if !opts.NoMeta {
+			analysis.Meta = ExtractMeta(bodyStr, contentType)
+		}
+	case "":
+		analysis.Kind = BodyKindText
+	case TitleSourceHTML:
+		if !opts.NoMeta {
+			analysis.Meta, analysis.Forms = extractHTMLMeta(bodyStr, opts.HTML)
+		} else if opts.HTML.Forms {
+			_, analysis.Forms = extractHTMLMeta(bodyStr, HTMLOptions{Forms: true})
+		}
+	default:
+		if !opts.NoMeta {
+			analysis.Meta = ExtractMeta(bodyStr, contentType)
+		}
+	}
+	if isText {
+		analysis.Words, analysis.Lines = digest.Words, digest.Lines
+		analysis.Keywords = opts.Keywords.Match(body)
+	}
+	return analysis
+}
+
+// AnalyzeJSON reports whether body is valid JSON and, for objects, its
+// first top-level keys. Keys are read from the start of the body even when
+// it is truncated.
+func AnalyzeJSON(body []byte) *JSONAnalysis {
+	analysis := &JSONAnalysis{Valid: json.Valid(body)}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return analysis
+	}
+	for dec.More() && len(analysis.TopLevelKeys) < maxJSONTopLevelKeys {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		key, ok := tok.(string)
+		if !ok {
+			break
+		}
+		analysis.TopLevelKeys = append(analysis.TopLevelKeys, SanitizeString(key))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			break
+		}
+	}
+	return analysis
+}
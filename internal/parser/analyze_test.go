@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"probeHTTP/internal/hash"
+)
+
+func TestAnalyzeBody_HTML(t *testing.T) {
+	analysis := AnalyzeBody([]byte(`<html lang="en"><title>Home</title><body>hello world</body></html>`), "text/html; charset=utf-8", AnalysisOptions{})
+	if analysis.Kind != TitleSourceHTML || analysis.JSON != nil {
+		t.Fatalf("kind = %q json = %v, want html without JSON analysis", analysis.Kind, analysis.JSON)
+	}
+	if analysis.Meta.Title != "Home" || analysis.Meta.Lang != "en" {
+		t.Errorf("meta = %+v, want title Home, lang en", analysis.Meta)
+	}
+	if !analysis.Text || analysis.Words == 0 {
+		t.Errorf("text = %v words = %d, want counted words", analysis.Text, analysis.Words)
+	}
+}
+
+func TestAnalyzeBody_SkippedParts(t *testing.T) {
+	body := []byte(`<html lang="en"><title>Home</title><body><form action="/a"></form>hello world</body></html>`)
+	analysis := AnalyzeBody(body, "text/html", AnalysisOptions{HTML: HTMLOptions{Forms: true}, NoHash: true, NoMeta: true, NoCounts: true})
+	if analysis.MMH3 != "" || analysis.Meta != (HTMLMeta{}) || analysis.Words != 0 || analysis.Lines != 0 {
+		t.Errorf("analysis = %+v, want no hash, meta or counts", analysis)
+	}
+	if analysis.Entropy == 0 || len(analysis.Forms) != 1 {
+		t.Errorf("entropy = %v, forms = %+v; want both kept", analysis.Entropy, analysis.Forms)
+	}
+}
+
+func TestAnalyzeBody_JSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantValid   bool
+		wantKeys    []string
+		wantTitle   string
+	}{
+		{"object", "application/json", `{"title":"API","b":{"nested":1},"a":[1,2]}`, true, []string{"title", "b", "a"}, "API"},
+		{"problem+json", "application/problem+json", `{"type":"about:blank","status":404}`, true, []string{"type", "status"}, ""},
+		{"array", "application/json", `[{"id":1},{"id":2}]`, true, nil, ""},
+		{"sniffed array", "", ` [1, 2, 3]`, true, nil, ""},
+		{"truncated", "application/json", `{"status":"ok","items":[1,2`, false, []string{"status", "items"}, ""},
+		{"garbage", "application/json", `not json`, false, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := AnalyzeBody([]byte(tt.body), tt.contentType, AnalysisOptions{})
+			if analysis.Kind != TitleSourceJSON || analysis.JSON == nil {
+				t.Fatalf("kind = %q json = %v, want json analysis", analysis.Kind, analysis.JSON)
+			}
+			if analysis.JSON.Valid != tt.wantValid {
+				t.Errorf("Valid = %v, want %v", analysis.JSON.Valid, tt.wantValid)
+			}
+			if !reflect.DeepEqual(analysis.JSON.TopLevelKeys, tt.wantKeys) {
+				t.Errorf("TopLevelKeys = %v, want %v", analysis.JSON.TopLevelKeys, tt.wantKeys)
+			}
+			if analysis.Meta.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", analysis.Meta.Title, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestAnalyzeJSON_KeyCap(t *testing.T) {
+	var fields []string
+	for i := 0; i < 30; i++ {
+		fields = append(fields, fmt.Sprintf(`"k%02d":%d`, i, i))
+	}
+	analysis := AnalyzeJSON([]byte("{" + strings.Join(fields, ",") + "}"))
+	if len(analysis.TopLevelKeys) != maxJSONTopLevelKeys {
+		t.Fatalf("got %d keys, want %d", len(analysis.TopLevelKeys), maxJSONTopLevelKeys)
+	}
+	if analysis.TopLevelKeys[0] != "k00" || analysis.TopLevelKeys[19] != "k19" {
+		t.Errorf("keys = %v, want k00..k19 in document order", analysis.TopLevelKeys)
+	}
+}
+
+func TestAnalyzeBody_PlainText(t *testing.T) {
+	analysis := AnalyzeBody([]byte("one two\nthree"), "text/plain", AnalysisOptions{})
+	if analysis.Kind != BodyKindText || analysis.JSON != nil || analysis.Meta != (HTMLMeta{}) {
+		t.Errorf("analysis = %+v, want plain text without title or JSON", analysis)
+	}
+	if analysis.Words != 3 || analysis.Lines != 2 {
+		t.Errorf("words/lines = %d/%d, want 3/2", analysis.Words, analysis.Lines)
+	}
+}
+
+func TestAnalyzeBody_BinarySkipped(t *testing.T) {
+	body := []byte("\x89PNG\r\n\x1a\n")
+	analysis := AnalyzeBody(body, "image/png", AnalysisOptions{Keywords: NewKeywordMatcher([]string{"PNG"})})
+	digest := hash.CalculateBodyDigest(body)
+	want := BodyAnalysis{MMH3: digest.MMH3, Entropy: digest.Entropy}
+	if !reflect.DeepEqual(analysis, want) {
+		t.Errorf("analysis = %+v, want only the byte-level metrics for binary content", analysis)
+	}
+}
+
+// goldenBodies covers each analyzer, sniffing, multi-chunk bodies and HTML
+// past the prefix the tokenizer tries first.
+var goldenBodies = []struct {
+	name        string
+	contentType string
+	body        string
+}{
+	{"html", "text/html; charset=utf-8", `<html lang="de"><head><title>Admin Login</title><link rel="canonical" href="/login"><meta name="generator" content="WordPress 6.4"></head><body><form action="/auth"><input type="password"></form>secret password</body></html>`},
+	{"html og title only", "text/html", `<html><head><meta property="og:title" content="Shop"></head><body>` + strings.Repeat("<p>item\u00A0price</p>\n", 5000) + `</body></html>`},
+	{"html late title", "text/html", strings.Repeat("<!-- padding -->\n", HTMLPrefixSize/16+10) + "<title>Late</title>"},
+	{"sniffed html", "", "<!doctype html><title>Sniffed</title>"},
+	{"json object", "application/json", `{"title":"API","error":"token expired","items":[1,2,3]}`},
+	{"json truncated", "application/json", `{"status":"ok","items":[1,2`},
+	{"sniffed json", "", ` [{"id":1}]`},
+	{"xml", "application/xml", `<?xml version="1.0"?><rss><channel><title>Feed</title></channel></rss>`},
+	{"pdf", "application/pdf", "%PDF-1.4\n1 0 obj << /Title (Quarterly Report) >> endobj\n%%EOF"},
+	{"plain", "text/plain", "line one\nline two\tword\u3000wide\n\n"},
+	{"plain multi chunk", "text/plain", strings.Repeat("lorem ipsum dolor sit amet\n", 10000)},
+	{"javascript", "application/javascript", `var apiKey = "x"; function login() { return "admin"; }`},
+	{"empty", "text/html", ""},
+	{"binary", "image/png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"},
+}
+
+// TestAnalyzeBody_Golden checks that the single analysis reports exactly
+// what the separate per-field functions report.
+func TestAnalyzeBody_Golden(t *testing.T) {
+	keywords := NewKeywordMatcher([]string{"password", "admin", "token", "Lorem"})
+	for _, tt := range goldenBodies {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(tt.body)
+			got := AnalyzeBody(body, tt.contentType, AnalysisOptions{HTML: HTMLOptions{Forms: true}, Keywords: keywords})
+
+			digest := hash.CalculateBodyDigest(body)
+			if got.MMH3 != hash.CalculateMMH3(body) || got.MMH3 != digest.MMH3 || got.Entropy != digest.Entropy {
+				t.Errorf("mmh3/entropy = %s/%v, want %s/%v", got.MMH3, got.Entropy, digest.MMH3, digest.Entropy)
+			}
+			isText := IsTextContentType(tt.contentType)
+			if !isText && !IsPDFContentType(tt.contentType) {
+				if got.Kind != "" || got.Text || got.Keywords != nil {
+					t.Errorf("binary body analyzed: %+v", got)
+				}
+				return
+			}
+
+			var wantWords, wantLines int
+			var wantKeywords []string
+			if isText {
+				wantWords, wantLines = CountWordsAndLines(tt.body)
+				wantKeywords = keywords.Match(body)
+			}
+			if got.Words != wantWords || got.Lines != wantLines {
+				t.Errorf("words/lines = %d/%d, want %d/%d", got.Words, got.Lines, wantWords, wantLines)
+			}
+			if !reflect.DeepEqual(got.Keywords, wantKeywords) {
+				t.Errorf("keywords = %v, want %v", got.Keywords, wantKeywords)
+			}
+
+			var wantMeta HTMLMeta
+			var wantForms []HTMLForm
+			if got.Kind == TitleSourceHTML {
+				wantMeta, wantForms = extractHTMLMeta(tt.body, HTMLOptions{Forms: true})
+			} else {
+				wantMeta = ExtractMeta(tt.body, tt.contentType)
+			}
+			if got.Meta != wantMeta {
+				t.Errorf("meta = %+v, want %+v", got.Meta, wantMeta)
+			}
+			if !reflect.DeepEqual(got.Forms, wantForms) {
+				t.Errorf("forms = %+v, want %+v", got.Forms, wantForms)
+			}
+			if got.Kind == TitleSourceJSON && !reflect.DeepEqual(got.JSON, AnalyzeJSON(body)) {
+				t.Errorf("json = %+v, want %+v", got.JSON, AnalyzeJSON(body))
+			}
+		})
+	}
+}
+
+// BenchmarkAnalyzeBody compares the separate per-field passes the prober
+// used to make over a 1 MB HTML body with the single analysis. Keywords are
+// left out: their search is one regexp pass either way.
+func BenchmarkAnalyzeBody(b *testing.B) {
+	body := []byte("<html><head><title>Bench</title></head><body>" +
+		strings.Repeat("<div class=\"row\"><p>some words of page content here</p></div>\n", 16000) + "</body></html>")
+
+	b.Run("separate", func(b *testing.B) {
+		b.SetBytes(int64(len(body)))
+		for i := 0; i < b.N; i++ {
+			hash.CalculateBodyDigest(body)
+			text := string(body)
+			extractHTMLMeta(text, HTMLOptions{})
+			CountWordsAndLines(text)
+		}
+	})
+	b.Run("single", func(b *testing.B) {
+		b.SetBytes(int64(len(body)))
+		for i := 0; i < b.N; i++ {
+			AnalyzeBody(body, "text/html", AnalysisOptions{})
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package parser
+
+import "strings"
+
+// SplitAnnotation splits an input line into the probe target and a passthrough
+// annotation following the first occurrence of delim. With an empty delim the
+// whole line is the target. Both parts are trimmed.
+func SplitAnnotation(line, delim string) (target, annotation string) {
+	if delim == "" {
+		return strings.TrimSpace(line), ""
+	}
+	target, annotation, _ = strings.Cut(line, delim)
+	return strings.TrimSpace(target), strings.TrimSpace(annotation)
+}
+
+// ParseAnnotationKV parses an annotation made of whitespace- or comma-separated
+// key=value pairs. It returns false if any token is not a k=v pair, in which
+// case the annotation should be passed through as a plain string.
+func ParseAnnotationKV(annotation string) (map[string]string, bool) {
+	tokens := strings.FieldsFunc(annotation, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	kv := make(map[string]string, len(tokens))
+	for _, token := range tokens {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok || key == "" {
+			return nil, false
+		}
+		kv[key] = value
+	}
+	return kv, true
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitAnnotation(t *testing.T) {
+	tests := []struct {
+		line, delim           string
+		wantTarget, wantAnnot string
+	}{
+		{"example.com # asset-id=1234", "#", "example.com", "asset-id=1234"},
+		{"example.com # asset-id=1234", "", "example.com # asset-id=1234", ""},
+		{"https://example.com/a#frag|owner=team-a", "|", "https://example.com/a#frag", "owner=team-a"},
+		{"example.com", "#", "example.com", ""},
+		{"example.com ## a # b", "##", "example.com", "a # b"},
+	}
+
+	for _, tt := range tests {
+		target, annot := SplitAnnotation(tt.line, tt.delim)
+		if target != tt.wantTarget || annot != tt.wantAnnot {
+			t.Errorf("SplitAnnotation(%q, %q) = (%q, %q), want (%q, %q)",
+				tt.line, tt.delim, target, annot, tt.wantTarget, tt.wantAnnot)
+		}
+	}
+}
+
+func TestParseAnnotationKV(t *testing.T) {
+	tests := []struct {
+		annotation string
+		want       map[string]string
+		wantOK     bool
+	}{
+		{"asset-id=1234", map[string]string{"asset-id": "1234"}, true},
+		{"asset-id=1234 owner=team-a", map[string]string{"asset-id": "1234", "owner": "team-a"}, true},
+		{"a=1,b=2", map[string]string{"a": "1", "b": "2"}, true},
+		{"empty=", map[string]string{"empty": ""}, true},
+		{"production web server", nil, false},
+		{"a=1 note", nil, false},
+		{"=1", nil, false},
+		{"", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseAnnotationKV(tt.annotation)
+		if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAnnotationKV(%q) = (%v, %v), want (%v, %v)", tt.annotation, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
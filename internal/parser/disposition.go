@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContentDisposition is a parsed Content-Disposition header (RFC 6266).
+type ContentDisposition struct {
+	// Type is the lowercased disposition type ("attachment", "inline"), or
+	// the sanitized raw header when it could not be parsed
+	Type string
+	// Filename is the decoded filename* parameter, or filename when
+	// filename* is missing or uses an unknown charset
+	Filename string
+}
+
+// IsAttachment reports whether the response is a download.
+func (d ContentDisposition) IsAttachment() bool {
+	return d.Type == "attachment"
+}
+
+// ParseContentDisposition parses a Content-Disposition header value. The
+// filename* extended syntax (RFC 8187) is decoded from UTF-8 or ISO-8859-1
+// and takes precedence over filename. Unquoted filenames with spaces, which
+// servers commonly send, are accepted. A value that doesn't parse degrades
+// to its raw text in Type, without a filename; an empty value gives the zero
+// ContentDisposition.
+func ParseContentDisposition(header string) ContentDisposition {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return ContentDisposition{}
+	}
+	raw := ContentDisposition{Type: SanitizeString(header)}
+
+	dispType, rest, _ := strings.Cut(header, ";")
+	dispType = strings.ToLower(strings.TrimSpace(dispType))
+	if !isToken(dispType) {
+		return raw
+	}
+
+	var filename, extFilename string
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		name, value, next, ok := nextDispositionParam(rest)
+		if !ok {
+			return raw
+		}
+		rest = next
+		switch name {
+		case "filename":
+			filename = value
+		case "filename*":
+			if decoded, ok := decodeExtValue(value); ok {
+				extFilename = decoded
+			}
+		}
+	}
+	if extFilename != "" {
+		filename = extFilename
+	}
+	return ContentDisposition{Type: dispType, Filename: SanitizeString(filename)}
+}
+
+// nextDispositionParam splits the first "name=value" parameter off s and
+// returns the lowercased name, the unquoted value and what follows the
+// next ';'. ok is false for a parameter without '=' or with an
+// unterminated quoted string.
+func nextDispositionParam(s string) (name, value, rest string, ok bool) {
+	name, s, found := strings.Cut(s, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !found || !isToken(name) {
+		return "", "", "", false
+	}
+	s = strings.TrimLeft(s, " \t")
+	if !strings.HasPrefix(s, `"`) {
+		value, rest, _ = strings.Cut(s, ";")
+		return name, strings.TrimSpace(value), rest, true
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			// Anything between the closing quote and the next ';' is ignored
+			_, rest, _ = strings.Cut(s[i+1:], ";")
+			return name, b.String(), rest, true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", "", false
+}
+
+// decodeExtValue decodes an RFC 8187 ext-value: charset'language'pct-encoded.
+func decodeExtValue(value string) (string, bool) {
+	charset, rest, ok := strings.Cut(value, "'")
+	if !ok {
+		return "", false
+	}
+	_, encoded, ok := strings.Cut(rest, "'")
+	if !ok {
+		return "", false
+	}
+	decoded, err := url.PathUnescape(encoded)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(charset) {
+	case "utf-8":
+		if !utf8.ValidString(decoded) {
+			return "", false
+		}
+		return decoded, true
+	case "iso-8859-1":
+		// Latin-1 bytes are the first 256 code points
+		runes := make([]rune, len(decoded))
+		for i := 0; i < len(decoded); i++ {
+			runes[i] = rune(decoded[i])
+		}
+		return string(runes), true
+	default:
+		return "", false
+	}
+}
+
+// isToken reports whether s is a non-empty RFC 7230 token.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`()<>@,;:\"/[]?={}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package parser
+
+import "testing"
+
+func TestParseContentDisposition(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantType string
+		wantFile string
+	}{
+		{"quoted filename", `attachment; filename="report.pdf"`, "attachment", "report.pdf"},
+		{"escaped quote", `attachment; filename="q3 \"final\".xlsx"`, "attachment", `q3 "final".xlsx`},
+		{"semicolon in quotes", `attachment; filename="a;b.txt"; size=10`, "attachment", "a;b.txt"},
+		{"unquoted with spaces", `Attachment; FileName=annual report.pdf`, "attachment", "annual report.pdf"},
+		{"utf-8 filename*", `attachment; filename*=UTF-8''%E2%82%AC%20rates.csv`, "attachment", "€ rates.csv"},
+		{"filename* wins", `attachment; filename="EURO rates.csv"; filename*=utf-8'en'%E2%82%AC%20rates.csv`, "attachment", "€ rates.csv"},
+		{"latin-1 filename*", `attachment; filename*=iso-8859-1''%E4rger.txt`, "attachment", "ärger.txt"},
+		{"unknown charset falls back", `attachment; filename="plain.txt"; filename*=koi8-r''%C1.txt`, "attachment", "plain.txt"},
+		{"invalid utf-8 falls back", `attachment; filename="plain.txt"; filename*=UTF-8''%FF.txt`, "attachment", "plain.txt"},
+		{"inline", `inline`, "inline", ""},
+		{"trailing semicolon", `attachment;`, "attachment", ""},
+		{"control characters", "attachment; filename=\"evil\x1b[31m\nname.sh\"", "attachment", "evil[31m name.sh"},
+		{"empty", ``, "", ""},
+		{"malformed type", `"attachment"; filename=x.pdf`, `"attachment"; filename=x.pdf`, ""},
+		{"unterminated quote", `attachment; filename="report.pdf`, `attachment; filename="report.pdf`, ""},
+		{"parameter without value", `attachment; filename`, `attachment; filename`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseContentDisposition(tt.header)
+			if got.Type != tt.wantType || got.Filename != tt.wantFile {
+				t.Errorf("ParseContentDisposition(%q) = %+v, want type %q, filename %q", tt.header, got, tt.wantType, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestContentDisposition_IsAttachment(t *testing.T) {
+	if !ParseContentDisposition(`ATTACHMENT; filename=a.zip`).IsAttachment() {
+		t.Error("attachment not recognized")
+	}
+	for _, header := range []string{`inline; filename=a.pdf`, `attachment; filename="a.zip`, ``} {
+		if ParseContentDisposition(header).IsAttachment() {
+			t.Errorf("%q treated as an attachment", header)
+		}
+	}
+}
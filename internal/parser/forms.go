@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+)
+
+// MaxFormActions caps how many distinct form actions are reported.
+const MaxFormActions = 10
+
+// HTMLForm is a <form> element found during the HTML traversal.
+type HTMLForm struct {
+	Action   string // action attribute, unresolved ("" when absent)
+	Password bool   // Contains an <input type="password">
+}
+
+// FormSummary is the attack-surface view of a page's forms.
+type FormSummary struct {
+	Count       int      // Number of <form> elements
+	Login       bool     // Some form has a password input
+	Actions     []string // Distinct resolved http(s) actions, in document order, at most MaxFormActions
+	CrossOrigin bool     // Some action points to another host than the page
+}
+
+// formCollector gathers forms from the start tags of a traversal. Inputs
+// belong to the form whose start tag was seen last and not yet closed.
+type formCollector struct {
+	forms []HTMLForm
+	open  bool
+}
+
+// element records a start tag; attr returns the value of one of its attributes.
+func (c *formCollector) element(tag string, attr func(string) string) {
+	switch tag {
+	case "form":
+		c.forms = append(c.forms, HTMLForm{Action: strings.TrimSpace(attr("action"))})
+		c.open = true
+	case "input":
+		if c.open && strings.EqualFold(strings.TrimSpace(attr("type")), "password") {
+			c.forms[len(c.forms)-1].Password = true
+		}
+	}
+}
+
+// end records a </form>.
+func (c *formCollector) end() {
+	c.open = false
+}
+
+// SummarizeForms resolves form actions against pageURL. A form without an
+// action submits to the page itself. javascript: and other non-HTTP actions
+// are skipped; they still count towards Count and Login.
+func SummarizeForms(forms []HTMLForm, pageURL string) FormSummary {
+	summary := FormSummary{Count: len(forms)}
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		page = nil
+	}
+
+	seen := make(map[string]bool)
+	for _, form := range forms {
+		if form.Password {
+			summary.Login = true
+		}
+		ref, err := url.Parse(SanitizeString(form.Action))
+		if err != nil {
+			continue
+		}
+		if page != nil {
+			ref = page.ResolveReference(ref)
+		}
+		if ref.Scheme != "http" && ref.Scheme != "https" {
+			continue
+		}
+		if page != nil && !strings.EqualFold(ref.Hostname(), page.Hostname()) {
+			summary.CrossOrigin = true
+		}
+		action := ref.String()
+		if !seen[action] && len(summary.Actions) < MaxFormActions {
+			seen[action] = true
+			summary.Actions = append(summary.Actions, action)
+		}
+	}
+	return summary
+}
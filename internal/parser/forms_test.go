@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const formsFixture = `<!DOCTYPE html>
+<html><head><title>Sign in</title></head><body>
+<form action="/search" method="get"><input type="text" name="q"></form>
+<form method="post">
+  <input type="text" name="user">
+  <input type="PASSWORD" name="pass">
+</form>
+<form action="javascript:void(0)"><input type="password"></form>
+<form action="https://sso.other.example/login"><input type="email"></form>
+<form action="mailto:admin@example.com"></form>
+<input type="password" name="orphan">
+</body></html>`
+
+func TestAnalyzeBody_Forms(t *testing.T) {
+	analysis := AnalyzeBody([]byte(formsFixture), "text/html", AnalysisOptions{HTML: HTMLOptions{Forms: true}})
+	want := []HTMLForm{
+		{Action: "/search"},
+		{Password: true},
+		{Action: "javascript:void(0)", Password: true},
+		{Action: "https://sso.other.example/login"},
+		{Action: "mailto:admin@example.com"},
+	}
+	if !reflect.DeepEqual(analysis.Forms, want) {
+		t.Errorf("forms = %+v, want %+v", analysis.Forms, want)
+	}
+
+	if forms := AnalyzeBody([]byte(formsFixture), "text/html", AnalysisOptions{}).Forms; forms != nil {
+		t.Errorf("forms without HTMLOptions.Forms = %+v, want nil", forms)
+	}
+}
+
+func TestAnalyzeBody_FormsPastPrefix(t *testing.T) {
+	body := "<html><head><title>Big</title></head><body>" +
+		strings.Repeat("<p>filler</p>", HTMLPrefixSize/10) +
+		`<form action="/late"><input type="password"></form></body></html>`
+	analysis := AnalyzeBody([]byte(body), "text/html", AnalysisOptions{HTML: HTMLOptions{Forms: true}})
+	if analysis.Meta.Title != "Big" {
+		t.Errorf("title = %q, want Big", analysis.Meta.Title)
+	}
+	if want := []HTMLForm{{Action: "/late", Password: true}}; !reflect.DeepEqual(analysis.Forms, want) {
+		t.Errorf("forms = %+v, want %+v", analysis.Forms, want)
+	}
+}
+
+func TestSummarizeForms(t *testing.T) {
+	forms := AnalyzeBody([]byte(formsFixture), "text/html", AnalysisOptions{HTML: HTMLOptions{Forms: true}}).Forms
+	got := SummarizeForms(forms, "https://example.com/account/login?next=%2F")
+	want := FormSummary{
+		Count: 5,
+		Login: true,
+		Actions: []string{
+			"https://example.com/search",
+			"https://example.com/account/login?next=%2F",
+			"https://sso.other.example/login",
+		},
+		CrossOrigin: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SummarizeForms = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummarizeForms_SameOriginAndCap(t *testing.T) {
+	var forms []HTMLForm
+	for i := 0; i < MaxFormActions+5; i++ {
+		forms = append(forms, HTMLForm{Action: fmt.Sprintf("/submit/%d", i)}, HTMLForm{Action: fmt.Sprintf("/submit/%d", i)})
+	}
+	got := SummarizeForms(forms, "http://Example.com/")
+	if got.Count != len(forms) || got.Login || got.CrossOrigin {
+		t.Errorf("got %+v, want %d same-origin forms without login", got, len(forms))
+	}
+	if len(got.Actions) != MaxFormActions || got.Actions[1] != "http://Example.com/submit/1" {
+		t.Errorf("actions = %v, want %d distinct actions", got.Actions, MaxFormActions)
+	}
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseInputLine extracts the target from an input line: its first
+// whitespace-separated token, so trailing columns of inventory exports are
+// ignored. With hints enabled, a bracketed scheme in the second column
+// ("example.com:443 [https]") is returned as hint. A malformed hint is
+// reported as an error alongside the target, which is still usable.
+func ParseInputLine(line string, hints bool) (target, hint string, err error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", "", nil
+	}
+	target = fields[0]
+	if !hints || len(fields) < 2 || !strings.HasPrefix(fields[1], "[") {
+		return target, "", nil
+	}
+
+	raw := fields[1]
+	inner, ok := strings.CutSuffix(raw[1:], "]")
+	if !ok {
+		return target, "", fmt.Errorf("unterminated scheme hint %q", raw)
+	}
+	switch scheme := strings.ToLower(inner); scheme {
+	case "http", "https":
+		return target, scheme, nil
+	default:
+		return target, "", fmt.Errorf("unknown scheme hint %q", raw)
+	}
+}
+
+// WithSchemeHint appends hint to target in the form ParseInputURL
+// recognizes, or returns target unchanged when there is no hint.
+func WithSchemeHint(target, hint string) string {
+	if hint == "" {
+		return target
+	}
+	return target + " [" + hint + "]"
+}
+
+// splitSchemeHint strips a trailing " [http]" or " [https]" from input
+func splitSchemeHint(input string) (target, hint string) {
+	idx := strings.LastIndexAny(input, " \t")
+	if idx < 0 {
+		return input, ""
+	}
+	switch tail := strings.ToLower(input[idx+1:]); tail {
+	case "[http]", "[https]":
+		return strings.TrimSpace(input[:idx]), tail[1 : len(tail)-1]
+	}
+	return input, ""
+}
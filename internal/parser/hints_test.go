@@ -0,0 +1,78 @@
+package parser
+
+import "testing"
+
+func TestParseInputLine(t *testing.T) {
+	tests := []struct {
+		line       string
+		hints      bool
+		wantTarget string
+		wantHint   string
+		wantErr    bool
+	}{
+		{"example.com:443 [https]", true, "example.com:443", "https", false},
+		{"1.2.3.4:8080 [http]", true, "1.2.3.4:8080", "http", false},
+		{"example.com:8443\t[HTTPS]  extra column", true, "example.com:8443", "https", false},
+		{"example.com:443", true, "example.com:443", "", false},
+		{"example.com:443 200 nginx", true, "example.com:443", "", false},
+		{"example.com:443 [https]", false, "example.com:443", "", false},
+		{"example.com  extra columns  ", false, "example.com", "", false},
+		{"example.com:443 [ftp]", true, "example.com:443", "", true},
+		{"example.com:443 [https", true, "example.com:443", "", true},
+		{"example.com:443 []", true, "example.com:443", "", true},
+		{"   ", true, "", "", false},
+	}
+
+	for _, tt := range tests {
+		target, hint, err := ParseInputLine(tt.line, tt.hints)
+		if target != tt.wantTarget || hint != tt.wantHint || (err != nil) != tt.wantErr {
+			t.Errorf("ParseInputLine(%q, %v) = (%q, %q, %v), want (%q, %q, err=%v)",
+				tt.line, tt.hints, target, hint, err, tt.wantTarget, tt.wantHint, tt.wantErr)
+		}
+	}
+}
+
+func TestParseInputURL_SchemeHint(t *testing.T) {
+	parsed := ParseInputURL(WithSchemeHint("1.2.3.4:8080", "https"))
+	if parsed.Host != "1.2.3.4" || parsed.Port != "8080" || parsed.SchemeHint != "https" {
+		t.Errorf("ParseInputURL = %+v, want host 1.2.3.4 port 8080 hint https", parsed)
+	}
+	if got := ParseInputURL("1.2.3.4:8080").SchemeHint; got != "" {
+		t.Errorf("SchemeHint without hint = %q, want empty", got)
+	}
+}
+
+func TestExpandURLs_SchemeHint(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		allSchemes bool
+		want       []string
+		wantSource string
+	}{
+		{"hint replaces default-both", "example.com:8443 [https]", false, []string{"https://example.com:8443/"}, SchemeSourceHint},
+		{"hint beats port heuristic", "example.com:443 [http]", false, []string{"http://example.com:443/"}, SchemeSourceHint},
+		{"-as overrides hint", "example.com:8080 [http]", true, []string{"http://example.com:8080/", "https://example.com:8080/"}, SchemeSourceAllSchemes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded := ExpandURLs(tt.input, tt.allSchemes, false, "", StandardPorts)
+			got := expanded.URLs()
+			if len(got) != len(tt.want) {
+				t.Fatalf("ExpandURLs(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ExpandURLs(%q)[%d] = %q, want %q", tt.input, i, got[i], tt.want[i])
+				}
+				if src := expanded[i].Expansion.SchemeSource; src != tt.wantSource {
+					t.Errorf("scheme_source = %q, want %q", src, tt.wantSource)
+				}
+			}
+			if n := CountExpansions(tt.input, tt.allSchemes, false, "", StandardPorts); n != len(got) {
+				t.Errorf("CountExpansions = %d, want %d", n, len(got))
+			}
+		})
+	}
+}
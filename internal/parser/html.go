@@ -37,79 +37,401 @@ func decodeTitleString(s string) string {
 	return s
 }
 
-// ExtractTitle extracts the HTML title from the body with fallbacks.
-// Priority: 1) <title> tag, 2) og:title meta tag, 3) twitter:title meta tag
-// If contentType is non-empty and does not contain "text/html", returns "" without parsing.
+// ExtractTitle extracts the title from the body using the extractor chosen
+// by contentType. See ExtractTitleWithSource.
 func ExtractTitle(body string, contentType string) string {
-	if contentType != "" && !strings.Contains(strings.ToLower(contentType), "text/html") {
-		return ""
+	title, _ := ExtractTitleWithSource(body, contentType)
+	return title
+}
+
+// ExtractTitleWithSource extracts the title from the body and reports where
+// it came from (one of the TitleSource constants, "" when no title was found).
+// See ExtractMeta.
+func ExtractTitleWithSource(body string, contentType string) (string, string) {
+	meta := ExtractMeta(body, contentType)
+	return meta.Title, meta.TitleSource
+}
+
+// HTMLMeta holds the page metadata gathered in a single pass over a document.
+type HTMLMeta struct {
+	Title        string // First non-empty of <title>, og:title, twitter:title
+	TitleSource  string // TitleSource constant for Title, "" when no title was found
+	CanonicalURL string // href of the first <link rel="canonical">, unresolved
+	Generator    string // content of the first <meta name="generator">
+	Lang         string // lang attribute of the <html> element
+	H1           string // Text of the first non-empty <h1>, with HTMLOptions.H1
+	Truncated    bool   // The parsed document was not fully analyzed, see maxHTMLNodes
+}
+
+// HTMLOptions selects optional extractions of the HTML traversal.
+type HTMLOptions struct {
+	Forms bool // Collect <form> elements in document order
+	H1    bool // Capture the first non-empty <h1> text, a title fallback
+}
+
+// ExtractMeta extracts metadata from the body using the extractor chosen by
+// contentType. HTML (and an empty contentType) is parsed once with
+// ExtractHTMLMeta; JSON, XML and PDF bodies use their own lightweight title
+// extractors and only fill Title and TitleSource. Other content types return
+// an empty HTMLMeta without parsing.
+func ExtractMeta(body string, contentType string) HTMLMeta {
+	var title string
+	kind := titleKind(body, contentType)
+	switch kind {
+	case TitleSourceHTML:
+		return ExtractHTMLMeta(body)
+	case TitleSourceJSON:
+		title = extractJSONTitle(body)
+	case TitleSourceXML:
+		title = extractXMLTitle(body)
+	case TitleSourcePDF:
+		title = extractPDFTitle(body)
 	}
-	doc, err := htmlparser.Parse(strings.NewReader(body))
-	if err != nil {
-		return ""
+	if title == "" {
+		return HTMLMeta{}
 	}
+	return HTMLMeta{Title: title, TitleSource: kind}
+}
 
-	var htmlTitle string
-	var ogTitle string
-	var twitterTitle string
+// HTMLPrefixSize is how much of an HTML body ExtractHTMLMeta tokenizes
+// before falling back to parsing the whole document. 0 disables the fast path.
+var HTMLPrefixSize = 64 * 1024
 
-	var traverse func(*htmlparser.Node)
-	traverse = func(n *htmlparser.Node) {
-		if n.Type == htmlparser.ElementNode {
-			// Check for <title> tag
-			if n.Data == "title" && htmlTitle == "" {
-				if n.FirstChild != nil {
-					htmlTitle = n.FirstChild.Data
+// maxHTMLNodes caps how many nodes of a parsed document are visited, so a
+// hostile page with millions of elements can't stall a worker. Analysis
+// stops at the budget and the metadata found so far is kept.
+const maxHTMLNodes = 200_000
+
+// headEndRegex matches where the <head> of an HTML document ends.
+var headEndRegex = regexp.MustCompile(`(?i)</head[\s>]|<body[\s>]`)
+
+// HeadComplete reports whether an HTML body prefix reaches the end of the
+// <head>, which holds the title candidates, canonical link and generator
+// ExtractHTMLMeta looks for.
+func HeadComplete(prefix []byte) bool {
+	return headEndRegex.Match(prefix)
+}
+
+// ExtractHTMLMeta extracts the title with fallbacks, the canonical link, the
+// generator meta tag and the document language from an HTML document.
+// Title priority: 1) <title> tag, 2) og:title meta tag, 3) twitter:title meta tag
+//
+// Only the first HTMLPrefixSize bytes are tokenized when they hold a title
+// candidate, which covers the <head> of practically every page. Documents
+// without one there are parsed in full, so late titles are still found.
+// Metadata past the prefix is missed on the fast path.
+func ExtractHTMLMeta(body string) HTMLMeta {
+	meta, _ := extractHTMLMeta(body, HTMLOptions{})
+	return meta
+}
+
+// extractHTMLMeta is ExtractHTMLMeta with optional extractions. Forms can
+// appear anywhere in a page, so with opts.Forms the fast path is only taken
+// when the prefix is the whole body.
+func extractHTMLMeta(body string, opts HTMLOptions) (HTMLMeta, []HTMLForm) {
+	if !opts.Forms || len(body) <= HTMLPrefixSize {
+		if meta, forms, ok := extractHTMLMetaPrefix(body, HTMLPrefixSize, opts); ok {
+			return meta, forms
+		}
+	}
+	return parseHTMLMeta(body, opts)
+}
+
+// extractHTMLMetaPrefix tokenizes the first limit bytes of body. ok is false
+// when no <title>, og:title or twitter:title was found there.
+func extractHTMLMetaPrefix(body string, limit int, opts HTMLOptions) (meta HTMLMeta, forms []HTMLForm, ok bool) {
+	if limit <= 0 {
+		return meta, nil, false
+	}
+	if len(body) > limit {
+		body = body[:limit]
+	}
+
+	var htmlTitle, ogTitle, twitterTitle string
+	var haveCanonical, haveGenerator, haveLang bool
+	inTitle := false
+	var collector formCollector
+	var h1 h1Collector
+
+	z := htmlparser.NewTokenizer(strings.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == htmlparser.ErrorToken {
+			break
+		}
+		switch tt {
+		case htmlparser.TextToken:
+			if inTitle && htmlTitle == "" {
+				htmlTitle = string(z.Text())
+			}
+			if opts.H1 {
+				h1.text(z.Text())
+			}
+		case htmlparser.EndTagToken:
+			inTitle = false
+			if opts.Forms || opts.H1 {
+				name, _ := z.TagName()
+				if opts.Forms && string(name) == "form" {
+					collector.end()
+				}
+				if opts.H1 && string(name) == "h1" {
+					h1.end()
 				}
 			}
-
-			// Check for <meta> tags with property or name attributes
-			if n.Data == "meta" {
-				var property, name, content string
-				for _, attr := range n.Attr {
-					switch attr.Key {
-					case "property":
-						property = attr.Val
-					case "name":
-						name = attr.Val
-					case "content":
-						content = attr.Val
+		case htmlparser.StartTagToken, htmlparser.SelfClosingTagToken:
+			token := z.Token()
+			inTitle = false
+			if opts.Forms {
+				collector.element(token.Data, func(key string) string { return tokenAttr(token, key) })
+			}
+			switch token.Data {
+			case "title":
+				inTitle = tt == htmlparser.StartTagToken
+			case "h1":
+				if opts.H1 && tt == htmlparser.StartTagToken {
+					h1.start()
+				}
+			case "html":
+				if !haveLang {
+					meta.Lang = strings.TrimSpace(tokenAttr(token, "lang"))
+					haveLang = true
+				}
+			case "link":
+				if !haveCanonical && hasRelToken(tokenAttr(token, "rel"), "canonical") {
+					if href := strings.TrimSpace(tokenAttr(token, "href")); href != "" {
+						meta.CanonicalURL = href
+						haveCanonical = true
 					}
 				}
-
-				// Check for Open Graph title
+			case "meta":
+				property, name, content := tokenAttr(token, "property"), tokenAttr(token, "name"), tokenAttr(token, "content")
 				if property == "og:title" && ogTitle == "" {
 					ogTitle = content
 				}
-
-				// Check for Twitter Card title
 				if name == "twitter:title" && twitterTitle == "" {
 					twitterTitle = content
 				}
+				if strings.EqualFold(name, "generator") && !haveGenerator && strings.TrimSpace(content) != "" {
+					meta.Generator = decodeTitleString(strings.TrimSpace(content))
+					haveGenerator = true
+				}
 			}
 		}
+	}
+
+	if htmlTitle == "" && ogTitle == "" && twitterTitle == "" {
+		return HTMLMeta{}, nil, false
+	}
+	meta.setTitle(htmlTitle, ogTitle, twitterTitle)
+	meta.H1 = h1.found
+	return meta, collector.forms, true
+}
+
+// h1Collector keeps the text of the first non-empty <h1> seen by the
+// tokenizer, including text of nested inline elements.
+type h1Collector struct {
+	inside bool
+	buf    strings.Builder
+	found  string
+}
+
+func (c *h1Collector) start() {
+	if c.found == "" {
+		c.inside = true
+		c.buf.Reset()
+	}
+}
+
+func (c *h1Collector) text(data []byte) {
+	if c.inside {
+		c.buf.Write(data)
+	}
+}
+
+func (c *h1Collector) end() {
+	if c.inside {
+		c.inside = false
+		c.found = SanitizeString(c.buf.String())
+	}
+}
 
-		// Continue traversing child nodes
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			traverse(c)
+// tokenAttr returns the value of the named attribute of t, or "".
+func tokenAttr(t htmlparser.Token, key string) string {
+	for _, attr := range t.Attr {
+		if attr.Key == key {
+			return attr.Val
 		}
 	}
-	traverse(doc)
+	return ""
+}
+
+// parseHTMLMeta is extractHTMLMeta over the whole parsed document. The tree
+// is walked iteratively and at most maxHTMLNodes nodes are visited. Documents
+// nested deeper than the HTML parser accepts are tokenized instead; both
+// cases set Truncated.
+func parseHTMLMeta(body string, opts HTMLOptions) (HTMLMeta, []HTMLForm) {
+	var meta HTMLMeta
+	doc, err := htmlparser.Parse(strings.NewReader(body))
+	if err != nil {
+		meta, forms, _ := extractHTMLMetaPrefix(body, len(body), opts)
+		meta.Truncated = true
+		return meta, forms
+	}
+
+	var htmlTitle string
+	var ogTitle string
+	var twitterTitle string
+	var haveCanonical, haveGenerator bool
+	var collector formCollector
+
+	visit := func(n *htmlparser.Node) {
+		if opts.Forms {
+			collector.element(n.Data, func(key string) string { return attrValue(n, key) })
+		}
+
+		// Check for <title> tag
+		if n.Data == "title" && htmlTitle == "" {
+			if n.FirstChild != nil {
+				htmlTitle = n.FirstChild.Data
+			}
+		}
+
+		// First non-empty <h1>, a title fallback
+		if opts.H1 && n.Data == "h1" && meta.H1 == "" {
+			meta.H1 = SanitizeString(nodeText(n))
+		}
+
+		// Document language from <html lang>
+		if n.Data == "html" && meta.Lang == "" {
+			meta.Lang = strings.TrimSpace(attrValue(n, "lang"))
+		}
+
+		// Check for <link rel="canonical">
+		if n.Data == "link" && !haveCanonical && hasRelToken(attrValue(n, "rel"), "canonical") {
+			if href := strings.TrimSpace(attrValue(n, "href")); href != "" {
+				meta.CanonicalURL = href
+				haveCanonical = true
+			}
+		}
+
+		// Check for <meta> tags with property or name attributes
+		if n.Data == "meta" {
+			var property, name, content string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "property":
+					property = attr.Val
+				case "name":
+					name = attr.Val
+				case "content":
+					content = attr.Val
+				}
+			}
+
+			// Check for Open Graph title
+			if property == "og:title" && ogTitle == "" {
+				ogTitle = content
+			}
 
-	// Return first non-empty title in priority order, decoded
-	if htmlTitle != "" {
-		return decodeTitleString(strings.TrimSpace(htmlTitle))
+			// Check for Twitter Card title
+			if name == "twitter:title" && twitterTitle == "" {
+				twitterTitle = content
+			}
+
+			// Check for generator (CMS / site builder)
+			if strings.EqualFold(name, "generator") && !haveGenerator && strings.TrimSpace(content) != "" {
+				meta.Generator = decodeTitleString(strings.TrimSpace(content))
+				haveGenerator = true
+			}
+		}
 	}
-	if ogTitle != "" {
-		return decodeTitleString(strings.TrimSpace(ogTitle))
+
+	// Depth-first in document order. A <form> is pushed a second time as
+	// leaving so the collector sees where it ends.
+	type step struct {
+		node    *htmlparser.Node
+		leaving bool
 	}
-	if twitterTitle != "" {
-		return decodeTitleString(strings.TrimSpace(twitterTitle))
+	stack := []step{{node: doc}}
+	visited := 0
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if s.leaving {
+			collector.end()
+			continue
+		}
+		if visited++; visited > maxHTMLNodes {
+			meta.Truncated = true
+			break
+		}
+		n := s.node
+		if n.Type == htmlparser.ElementNode {
+			visit(n)
+			if opts.Forms && n.Data == "form" {
+				stack = append(stack, step{node: n, leaving: true})
+			}
+		}
+		// Push children last to first so the first child is visited next
+		for c := n.LastChild; c != nil; c = c.PrevSibling {
+			stack = append(stack, step{node: c})
+		}
 	}
 
+	meta.setTitle(htmlTitle, ogTitle, twitterTitle)
+	return meta, collector.forms
+}
+
+// setTitle sets the first non-empty title in priority order, decoded.
+func (m *HTMLMeta) setTitle(htmlTitle, ogTitle, twitterTitle string) {
+	switch {
+	case htmlTitle != "":
+		m.Title, m.TitleSource = decodeTitleString(strings.TrimSpace(htmlTitle)), TitleSourceHTML
+	case ogTitle != "":
+		m.Title, m.TitleSource = decodeTitleString(strings.TrimSpace(ogTitle)), TitleSourceOG
+	case twitterTitle != "":
+		m.Title, m.TitleSource = decodeTitleString(strings.TrimSpace(twitterTitle)), TitleSourceTwitter
+	}
+}
+
+// nodeText returns the concatenated text of n's descendants, walking at
+// most maxHTMLNodes nodes.
+func nodeText(n *htmlparser.Node) string {
+	var b strings.Builder
+	stack := []*htmlparser.Node{n}
+	for visited := 0; len(stack) > 0 && visited < maxHTMLNodes; visited++ {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n.Type == htmlparser.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.LastChild; c != nil; c = c.PrevSibling {
+			stack = append(stack, c)
+		}
+	}
+	return b.String()
+}
+
+// attrValue returns the value of the named attribute of n, or "".
+func attrValue(n *htmlparser.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
 	return ""
 }
 
+// hasRelToken reports whether a space-separated rel attribute contains token.
+func hasRelToken(rel string, token string) bool {
+	for _, t := range strings.Fields(rel) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
 // CountWordsAndLines counts words and lines in the text
 func CountWordsAndLines(text string) (words int, lines int) {
 	lines = strings.Count(text, "\n") + 1
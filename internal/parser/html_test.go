@@ -1,6 +1,9 @@
 package parser
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestExtractTitle_TitleTag(t *testing.T) {
 	tests := []struct {
@@ -106,7 +109,7 @@ func TestExtractTitle_NonHTMLContentType(t *testing.T) {
 		{"application/json", ""},
 		{"image/png", ""},
 		{"text/plain", ""},
-		{"application/xml", ""},
+		{"application/xml", "Should Not Parse"}, // parsed as XML
 		{"text/html", "Should Not Parse"},
 		{"text/html; charset=utf-8", "Should Not Parse"},
 		{"", "Should Not Parse"},
@@ -172,3 +175,199 @@ func TestCountWordsAndLines(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractHTMLMeta(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want HTMLMeta
+	}{
+		{
+			name: "all fields",
+			body: `<html lang="de-AT"><head><title>Start</title>
+				<link rel="stylesheet" href="/style.css">
+				<link rel="canonical" href="https://example.com/start">
+				<meta name="generator" content="WordPress 6.4.2">
+				</head></html>`,
+			want: HTMLMeta{Title: "Start", TitleSource: TitleSourceHTML, CanonicalURL: "https://example.com/start", Generator: "WordPress 6.4.2", Lang: "de-AT"},
+		},
+		{
+			name: "relative canonical kept unresolved",
+			body: `<html><head><link rel="Canonical" href=" /en/home "></head></html>`,
+			want: HTMLMeta{CanonicalURL: "/en/home"},
+		},
+		{
+			name: "rel token list and first wins",
+			body: `<link rel="alternate canonical" href="/a"><link rel="canonical" href="/b">`,
+			want: HTMLMeta{CanonicalURL: "/a"},
+		},
+		{
+			name: "generator name case and entities",
+			body: `<meta name="Generator" content="Joomla! - Open Source Content Management &amp; more"><meta name="generator" content="second">`,
+			want: HTMLMeta{Generator: "Joomla! - Open Source Content Management & more"},
+		},
+		{
+			name: "og title fallback with lang",
+			body: `<html lang="en"><meta property="og:title" content="OG"></html>`,
+			want: HTMLMeta{Title: "OG", TitleSource: TitleSourceOG, Lang: "en"},
+		},
+		{
+			name: "empty values ignored",
+			body: `<html lang=""><link rel="canonical" href=""><meta name="generator" content=" "></html>`,
+			want: HTMLMeta{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractHTMLMeta(tt.body); got != tt.want {
+				t.Errorf("ExtractHTMLMeta() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMeta_NonHTMLHasTitleOnly(t *testing.T) {
+	got := ExtractMeta(`{"title":"API","generator":"x"}`, "application/json")
+	if got != (HTMLMeta{Title: "API", TitleSource: TitleSourceJSON}) {
+		t.Errorf("ExtractMeta() = %+v", got)
+	}
+}
+
+// largeHTMLFixture is a ~2MB page with its metadata in the <head>
+func largeHTMLFixture() string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html lang="en"><head><title>Big &amp; Slow</title>`)
+	b.WriteString(`<link rel="canonical" href="https://example.com/big"><meta name="generator" content="WordPress 6.4">`)
+	b.WriteString(`</head><body>`)
+	for b.Len() < 2<<20 {
+		b.WriteString(`<div class="row"><p>Lorem ipsum <a href="/x">dolor</a> sit amet.</p><ul><li>one</li><li>two</li></ul></div>`)
+	}
+	b.WriteString(`</body></html>`)
+	return b.String()
+}
+
+func TestExtractHTMLMeta_PrefixMatchesFullParse(t *testing.T) {
+	body := largeHTMLFixture()
+	got, _, ok := extractHTMLMetaPrefix(body, HTMLPrefixSize, HTMLOptions{})
+	if !ok {
+		t.Fatal("fast path found no title in the prefix")
+	}
+	want, _ := parseHTMLMeta(body, HTMLOptions{})
+	if !want.Truncated {
+		t.Error("full parse of the fixture should stop at maxHTMLNodes")
+	}
+	// The <head> comes before the node budget runs out
+	want.Truncated = false
+	if got != want {
+		t.Errorf("fast path = %+v, full parse = %+v", got, want)
+	}
+}
+
+func TestExtractHTMLMeta_LateTitleFallsBack(t *testing.T) {
+	body := "<html><body>" + strings.Repeat("<p>filler</p>", HTMLPrefixSize/10) + "<title>Late</title></body></html>"
+	if _, _, ok := extractHTMLMetaPrefix(body, HTMLPrefixSize, HTMLOptions{}); ok {
+		t.Fatal("fast path should not find a title past the prefix")
+	}
+	if meta := ExtractHTMLMeta(body); meta.Title != "Late" || meta.TitleSource != TitleSourceHTML {
+		t.Errorf("ExtractHTMLMeta = %+v, want the late <title> from the full parse", meta)
+	}
+}
+
+func BenchmarkExtractHTMLMeta_Prefix(b *testing.B) {
+	body := largeHTMLFixture()
+	b.SetBytes(int64(len(body)))
+	for b.Loop() {
+		extractHTMLMetaPrefix(body, HTMLPrefixSize, HTMLOptions{})
+	}
+}
+
+func BenchmarkExtractHTMLMeta_FullParse(b *testing.B) {
+	body := largeHTMLFixture()
+	b.SetBytes(int64(len(body)))
+	for b.Loop() {
+		parseHTMLMeta(body, HTMLOptions{})
+	}
+}
+
+func TestExtractHTMLMeta_H1(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"untitled page", `<html><body><h1>Router <b>Login</b></h1><h1>Second</h1></body></html>`, "Router Login"},
+		{"empty h1 skipped", `<html><body><h1> </h1><div><h1>
+			Status &amp; Health</h1></div></body></html>`, "Status & Health"},
+		{"titled page", `<html><head><title>Home</title></head><body><h1>Welcome</h1></body></html>`, "Welcome"},
+		{"no h1", `<html><body><h2>Sub</h2></body></html>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			full, _ := parseHTMLMeta(tt.body, HTMLOptions{H1: true})
+			if full.H1 != tt.want {
+				t.Errorf("full parse H1 = %q, want %q", full.H1, tt.want)
+			}
+			if prefix, _, ok := extractHTMLMetaPrefix(tt.body, HTMLPrefixSize, HTMLOptions{H1: true}); ok && prefix.H1 != tt.want {
+				t.Errorf("fast path H1 = %q, want %q", prefix.H1, tt.want)
+			}
+			if meta, _ := extractHTMLMeta(tt.body, HTMLOptions{}); meta.H1 != "" {
+				t.Errorf("H1 = %q without HTMLOptions.H1", meta.H1)
+			}
+		})
+	}
+}
+
+// 100k nested elements exceed what the HTML parser builds a tree for; the
+// body is tokenized instead and reported as truncated, without panicking.
+func TestParseHTMLMeta_DeeplyNested(t *testing.T) {
+	const depth = 100_000
+	body := "<html lang=\"en\"><head><meta name=\"generator\" content=\"Nest\"></head><body>" +
+		strings.Repeat("<div>", depth) + "<title>Deep</title>" + strings.Repeat("</div>", depth) + "</body></html>"
+	meta, _ := parseHTMLMeta(body, HTMLOptions{H1: true, Forms: true})
+	if !meta.Truncated {
+		t.Error("Truncated = false for a document nested 100k deep")
+	}
+	if meta.Title != "Deep" || meta.Generator != "Nest" || meta.Lang != "en" {
+		t.Errorf("meta = %+v, want the tokenized metadata", meta)
+	}
+	if meta := ExtractHTMLMeta(body); !meta.Truncated || meta.Title != "Deep" {
+		t.Errorf("ExtractHTMLMeta = %+v", meta)
+	}
+}
+
+func TestParseHTMLMeta_NodeBudget(t *testing.T) {
+	filler := strings.Repeat("<p>x</p>", maxHTMLNodes/2)
+	body := "<html><body><form action=\"/early\"></form>" + filler +
+		"<form action=\"/late\"></form><title>Late</title></body></html>"
+	meta, forms := parseHTMLMeta(body, HTMLOptions{Forms: true})
+	if !meta.Truncated {
+		t.Error("Truncated = false past the node budget")
+	}
+	if meta.Title != "" || len(forms) != 1 || forms[0].Action != "/early" {
+		t.Errorf("title %q, forms %+v; want only what precedes the budget", meta.Title, forms)
+	}
+
+	small, forms := parseHTMLMeta("<html><body><form action=\"/a\"><input type=\"password\"></form><title>T</title></body></html>", HTMLOptions{Forms: true})
+	if small.Truncated || small.Title != "T" || len(forms) != 1 || !forms[0].Password {
+		t.Errorf("small document: %+v, forms %+v", small, forms)
+	}
+}
+
+func TestHeadComplete(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   bool
+	}{
+		{"<html><head><title>t</title></head>", true},
+		{"<html><HEAD><title>t</title></HEAD >", true},
+		{"<title>t</title><body class=x>", true},
+		{"<html><head><title>t</title>", false},
+		{"<html><head><meta name=headless><bodyguard>", false},
+	}
+	for _, tt := range tests {
+		if got := HeadComplete([]byte(tt.prefix)); got != tt.want {
+			t.Errorf("HeadComplete(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package parser
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	"probeHTTP/internal/output"
+)
+
+// Reasons a port scanner record is not probed, counted in ScanSkips.
+const (
+	ScanSkipMalformed = "malformed" // line or port entry that doesn't parse
+	ScanSkipNotTCP    = "not-tcp"   // UDP or SCTP port
+	ScanSkipNotOpen   = "not-open"  // closed, filtered or open|filtered port
+	ScanSkipNotHTTP   = "not-http"  // service or well-known port of a non-HTTP protocol
+)
+
+// ScanSkips counts skipped scanner records by reason.
+type ScanSkips map[string]int
+
+// Total returns the number of skipped records.
+func (s ScanSkips) Total() int {
+	total := 0
+	for _, n := range s {
+		total += n
+	}
+	return total
+}
+
+// ScanTarget is an open TCP port read from port scanner output.
+type ScanTarget struct {
+	Host   string // IP address as scanned
+	Port   string
+	Scheme string // From the reported service when it identifies HTTP or HTTPS, else ""
+}
+
+// HostPort returns the target as host:port, the form recorded as a result's input.
+func (t ScanTarget) HostPort() string {
+	return net.JoinHostPort(t.Host, t.Port)
+}
+
+// nonHTTPServices are scanner service names of protocols that don't speak HTTP.
+var nonHTTPServices = map[string]bool{
+	"ssh": true, "ftp": true, "ftps": true, "telnet": true, "smtp": true, "smtps": true,
+	"submission": true, "pop3": true, "pop3s": true, "imap": true, "imaps": true,
+	"domain": true, "dns": true, "ldap": true, "ldaps": true, "kerberos-sec": true,
+	"msrpc": true, "netbios-ssn": true, "microsoft-ds": true, "smb": true,
+	"ms-wbt-server": true, "rdp": true, "vnc": true, "mysql": true, "postgresql": true,
+	"ms-sql-s": true, "oracle-tns": true, "redis": true, "mongodb": true, "rpcbind": true,
+	"sip": true, "bgp": true, "ntp": true, "snmp": true,
+}
+
+// nonHTTPPorts are well-known ports of non-HTTP protocols, consulted when the
+// scanner reported no service for a port.
+var nonHTTPPorts = map[string]bool{
+	"21": true, "22": true, "23": true, "25": true, "53": true, "110": true, "111": true,
+	"135": true, "139": true, "143": true, "389": true, "445": true, "465": true, "587": true,
+	"636": true, "993": true, "995": true, "1433": true, "1521": true, "3306": true,
+	"3389": true, "5432": true, "5900": true, "6379": true, "27017": true,
+}
+
+// serviceScheme maps a scanner service name to the scheme to probe. skip is
+// set for services, or unidentified well-known ports, that aren't HTTP.
+// nmap reports TLS-wrapped services as "ssl|http", masscan banners as "ssl"
+// or "X509" next to the protocol banner.
+func serviceScheme(service, port string) (scheme string, skip bool) {
+	name := strings.ToLower(strings.TrimSpace(service))
+	tls := false
+	for _, prefix := range []string{"ssl|", "ssl/", "tls|", "tls/"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			name, tls = rest, true
+			break
+		}
+	}
+
+	switch {
+	case name == "https" || strings.HasPrefix(name, "https-"):
+		return "https", false
+	case name == "http" || strings.HasPrefix(name, "http-") || strings.HasPrefix(name, "http.") || name == "title":
+		if tls {
+			return "https", false
+		}
+		return "http", false
+	case name == "ssl" || name == "tls" || name == "x509":
+		return "https", false
+	case nonHTTPServices[name]:
+		return "", true
+	case name == "" || name == "unknown" || name == "tcpwrapped":
+		return "", nonHTTPPorts[port]
+	}
+	return "", false
+}
+
+// scanCollector merges the port records of a scan into one target per
+// host:port, in the order they were first seen.
+type scanCollector struct {
+	targets []ScanTarget
+	index   map[string]int
+	nonHTTP map[string]bool
+	skips   ScanSkips
+}
+
+func newScanCollector() *scanCollector {
+	return &scanCollector{index: make(map[string]int), nonHTTP: make(map[string]bool), skips: ScanSkips{}}
+}
+
+// add records an open TCP port. A later record naming HTTPS upgrades the
+// scheme; HTTP evidence from any record outweighs a non-HTTP service.
+func (c *scanCollector) add(host, port, service string) {
+	scheme, skip := serviceScheme(service, port)
+	target := ScanTarget{Host: host, Port: port, Scheme: scheme}
+	key := target.HostPort()
+	i, seen := c.index[key]
+	if !seen {
+		c.index[key] = len(c.targets)
+		c.targets = append(c.targets, target)
+		c.nonHTTP[key] = skip
+		return
+	}
+	c.nonHTTP[key] = c.nonHTTP[key] || skip
+	if scheme == "https" || scheme != "" && c.targets[i].Scheme == "" {
+		c.targets[i].Scheme = scheme
+	}
+}
+
+// result returns the merged targets, dropping non-HTTP ones into the skips.
+func (c *scanCollector) result() ([]ScanTarget, ScanSkips) {
+	targets := make([]ScanTarget, 0, len(c.targets))
+	for _, target := range c.targets {
+		if target.Scheme == "" && c.nonHTTP[target.HostPort()] {
+			c.skips[ScanSkipNotHTTP]++
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets, c.skips
+}
+
+// validScanPort returns the port as a decimal string when it is in 1-65535.
+func validScanPort(port string) (string, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(port))
+	if err != nil || n < 1 || n > 65535 {
+		return "", false
+	}
+	return strconv.Itoa(n), true
+}
+
+// masscanRecord is one line of masscan -oJ output. Port records carry a
+// status; banner records (--banners) carry a service instead.
+type masscanRecord struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port    int    `json:"port"`
+		Proto   string `json:"proto"`
+		Status  string `json:"status"`
+		Service struct {
+			Name string `json:"name"`
+		} `json:"service"`
+	} `json:"ports"`
+}
+
+// ParseMasscanJSON reads masscan -oJ output, one record per line as masscan
+// writes it. The enclosing brackets and the commas between records are
+// tolerated, so both the old (leading comma) and new (trailing comma)
+// layouts parse.
+func ParseMasscanJSON(lines []string) ([]ScanTarget, ScanSkips) {
+	c := newScanCollector()
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, ","), ","))
+		if line == "" || line == "[" || line == "]" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var record masscanRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil || net.ParseIP(record.IP) == nil || len(record.Ports) == 0 {
+			c.skips[ScanSkipMalformed]++
+			continue
+		}
+		for _, p := range record.Ports {
+			port, ok := validScanPort(strconv.Itoa(p.Port))
+			switch {
+			case !ok:
+				c.skips[ScanSkipMalformed]++
+			case !strings.EqualFold(p.Proto, "tcp"):
+				c.skips[ScanSkipNotTCP]++
+			case p.Status != "" && p.Status != "open":
+				c.skips[ScanSkipNotOpen]++
+			default:
+				c.add(record.IP, port, p.Service.Name)
+			}
+		}
+	}
+	return c.result()
+}
+
+// ParseNmapGrepable reads nmap -oG output. Only "Host:" lines with a
+// "Ports:" field describe ports; status-only host lines and comments are
+// ignored. Each port entry is port/state/protocol/owner/service/rpc/version.
+func ParseNmapGrepable(lines []string) ([]ScanTarget, ScanSkips) {
+	c := newScanCollector()
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rest, ok := strings.CutPrefix(line, "Host:")
+		if !ok {
+			c.skips[ScanSkipMalformed]++
+			continue
+		}
+
+		var host, ports string
+		for i, field := range strings.Split(rest, "\t") {
+			field = strings.TrimSpace(field)
+			if i == 0 {
+				// "10.0.0.1 (name.example.com)"
+				if tokens := strings.Fields(field); len(tokens) > 0 {
+					host = tokens[0]
+				}
+				continue
+			}
+			if value, ok := strings.CutPrefix(field, "Ports:"); ok {
+				ports = strings.TrimSpace(value)
+			}
+		}
+		if net.ParseIP(host) == nil {
+			c.skips[ScanSkipMalformed]++
+			continue
+		}
+		if ports == "" {
+			continue
+		}
+
+		for _, entry := range strings.Split(ports, ",") {
+			parts := strings.Split(strings.TrimSpace(entry), "/")
+			if len(parts) < 5 {
+				c.skips[ScanSkipMalformed]++
+				continue
+			}
+			port, ok := validScanPort(parts[0])
+			switch {
+			case !ok:
+				c.skips[ScanSkipMalformed]++
+			case parts[2] != "tcp":
+				c.skips[ScanSkipNotTCP]++
+			case parts[1] != "open":
+				c.skips[ScanSkipNotOpen]++
+			default:
+				c.add(host, port, parts[4])
+			}
+		}
+	}
+	return c.result()
+}
+
+// ExpandScanTarget returns the URLs to probe for an open port. The port is
+// taken as found, so -ports and -ignore-ports don't apply. The scheme comes
+// from the scanner's service name when it named one, else from the usual
+// port heuristic: 80 is HTTP, 443 is HTTPS and any other port gets both.
+func ExpandScanTarget(target ScanTarget, allSchemes bool, defaults DefaultPorts) ExpandedURLs {
+	schemes, schemeSource := []string{target.Scheme}, SchemeSourceScan
+	if allSchemes || target.Scheme == "" {
+		schemes, schemeSource = getSchemesToTest(ParsedURL{Host: target.Host, Port: target.Port}, allSchemes)
+	}
+
+	urls := make(ExpandedURLs, 0, len(schemes))
+	for _, scheme := range schemes {
+		urls = append(urls, ExpandedURL{
+			URL: buildProbeURL(scheme, target.Host, target.Port, "/", target.Port != defaults.ForScheme(scheme)),
+			Expansion: output.Expansion{
+				SchemeSource: schemeSource,
+				PortSource:   PortSourceScan,
+				PathSource:   PathSourceInput,
+			},
+		})
+	}
+	return urls
+}
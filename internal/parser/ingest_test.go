@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readFixture returns the lines of a testdata file.
+func readFixture(t *testing.T, name string) []string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestParseMasscanJSON(t *testing.T) {
+	targets, skips := ParseMasscanJSON(readFixture(t, "masscan.json"))
+
+	want := []ScanTarget{
+		{Host: "10.0.0.1", Port: "80"},
+		{Host: "10.0.0.1", Port: "8443", Scheme: "https"}, // ssl banner
+		{Host: "10.0.0.3", Port: "9000"},
+		{Host: "10.0.0.3", Port: "8080", Scheme: "http"}, // banner record only
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("targets = %+v, want %+v", targets, want)
+	}
+	wantSkips := ScanSkips{ScanSkipMalformed: 3, ScanSkipNotTCP: 1, ScanSkipNotOpen: 1, ScanSkipNotHTTP: 2}
+	if !reflect.DeepEqual(skips, wantSkips) {
+		t.Errorf("skips = %v, want %v", skips, wantSkips)
+	}
+}
+
+func TestParseMasscanJSON_LeadingCommas(t *testing.T) {
+	lines := []string{
+		"[",
+		`{ "ip": "10.0.0.1", "ports": [ {"port": 443, "proto": "tcp", "status": "open"} ] }`,
+		`,{ "ip": "10.0.0.2", "ports": [ {"port": 80, "proto": "tcp", "status": "open"} ] }`,
+		"]",
+	}
+	targets, skips := ParseMasscanJSON(lines)
+	if len(targets) != 2 || skips.Total() != 0 {
+		t.Errorf("targets = %+v, skips = %v; want 2 targets and no skips", targets, skips)
+	}
+}
+
+func TestParseNmapGrepable(t *testing.T) {
+	targets, skips := ParseNmapGrepable(readFixture(t, "nmap.gnmap"))
+
+	want := []ScanTarget{
+		{Host: "10.0.0.1", Port: "80", Scheme: "http"},
+		{Host: "10.0.0.1", Port: "443", Scheme: "https"}, // ssl|http
+		{Host: "10.0.0.1", Port: "8443", Scheme: "https"},
+		{Host: "10.0.0.1", Port: "9000"},
+		{Host: "10.0.0.2", Port: "8888"},
+		{Host: "10.0.0.3", Port: "8000", Scheme: "http"},
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("targets = %+v, want %+v", targets, want)
+	}
+	wantSkips := ScanSkips{ScanSkipMalformed: 3, ScanSkipNotTCP: 1, ScanSkipNotOpen: 1, ScanSkipNotHTTP: 2}
+	if !reflect.DeepEqual(skips, wantSkips) {
+		t.Errorf("skips = %v, want %v", skips, wantSkips)
+	}
+}
+
+func TestExpandScanTarget(t *testing.T) {
+	defaults := DefaultPorts{HTTP: "80", HTTPS: "443"}
+	tests := []struct {
+		target       ScanTarget
+		allSchemes   bool
+		wantURLs     []string
+		schemeSource string
+	}{
+		{ScanTarget{Host: "10.0.0.1", Port: "80"}, false, []string{"http://10.0.0.1/"}, SchemeSourcePortHeuristic},
+		{ScanTarget{Host: "10.0.0.1", Port: "9000"}, false, []string{"http://10.0.0.1:9000/", "https://10.0.0.1:9000/"}, SchemeSourceDefaultBoth},
+		{ScanTarget{Host: "10.0.0.1", Port: "8443", Scheme: "https"}, false, []string{"https://10.0.0.1:8443/"}, SchemeSourceScan},
+		{ScanTarget{Host: "10.0.0.1", Port: "8443", Scheme: "https"}, true, []string{"http://10.0.0.1:8443/", "https://10.0.0.1:8443/"}, SchemeSourceAllSchemes},
+		{ScanTarget{Host: "2001:db8::1", Port: "443", Scheme: "https"}, false, []string{"https://[2001:db8::1]/"}, SchemeSourceScan},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target.HostPort(), func(t *testing.T) {
+			expanded := ExpandScanTarget(tt.target, tt.allSchemes, defaults)
+			if got := expanded.URLs(); !reflect.DeepEqual(got, tt.wantURLs) {
+				t.Fatalf("URLs = %v, want %v", got, tt.wantURLs)
+			}
+			for _, e := range expanded {
+				if e.Expansion.SchemeSource != tt.schemeSource || e.Expansion.PortSource != PortSourceScan {
+					t.Errorf("expansion = %+v, want scheme source %q and port source %q", e.Expansion, tt.schemeSource, PortSourceScan)
+				}
+			}
+		})
+	}
+}
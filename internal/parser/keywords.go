@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// KeywordMatcher matches a fixed set of keywords case-insensitively using a
+// single compiled alternation.
+type KeywordMatcher struct {
+	re       *regexp.Regexp
+	keywords []string          // configured keywords in input order (deduplicated)
+	lookup   map[string]string // lowercased keyword -> configured keyword
+	maxMatch int               // most bytes a case-insensitive match can span
+}
+
+// NewKeywordMatcher builds a matcher for the given keywords.
+// Empty entries and case-insensitive duplicates are ignored.
+// Returns nil when no usable keywords remain.
+func NewKeywordMatcher(keywords []string) *KeywordMatcher {
+	m := &KeywordMatcher{lookup: make(map[string]string)}
+	var alternatives []string
+	for _, kw := range keywords {
+		kw = strings.TrimSpace(kw)
+		if kw == "" {
+			continue
+		}
+		lower := strings.ToLower(kw)
+		if _, exists := m.lookup[lower]; exists {
+			continue
+		}
+		m.lookup[lower] = kw
+		m.keywords = append(m.keywords, kw)
+		m.maxMatch = max(m.maxMatch, utf8.RuneCountInString(kw)*utf8.UTFMax)
+		alternatives = append(alternatives, regexp.QuoteMeta(lower))
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	// Longest alternatives first so overlapping keywords ("log", "login")
+	// report the most specific match at each position
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		return len(alternatives[i]) > len(alternatives[j])
+	})
+	m.re = regexp.MustCompile("(?i)(?:" + strings.Join(alternatives, "|") + ")")
+	return m
+}
+
+// Match returns the configured keywords found in body, in configuration order.
+// Returns nil when nothing matched.
+func (m *KeywordMatcher) Match(body []byte) []string {
+	if m == nil || len(body) == 0 {
+		return nil
+	}
+	// Search again one character after each match start, so keywords
+	// overlapping a match ("login", "ginpanel" in "loginpanel") are found too
+	found := make(map[string]bool)
+	for pos := 0; pos < len(body) && len(found) < len(m.keywords); {
+		loc := m.re.FindIndex(body[pos:])
+		if loc == nil {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+		if kw, ok := m.lookup[strings.ToLower(string(body[start:end]))]; ok {
+			found[kw] = true
+		}
+		_, size := utf8.DecodeRune(body[start:])
+		pos = start + size
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	// Keywords nested inside a longer matched keyword are also present
+	if len(found) < len(m.keywords) {
+		for _, kw := range m.keywords {
+			if found[kw] {
+				continue
+			}
+			lower := strings.ToLower(kw)
+			for other := range found {
+				if strings.Contains(strings.ToLower(other), lower) {
+					found[kw] = true
+					break
+				}
+			}
+		}
+	}
+	matched := make([]string, 0, len(found))
+	for _, kw := range m.keywords {
+		if found[kw] {
+			matched = append(matched, kw)
+		}
+	}
+	return matched
+}
+
+// MaxMatchLen returns the most bytes a match of m can span, so a body read
+// in chunks can be searched for keywords across chunk boundaries.
+func (m *KeywordMatcher) MaxMatchLen() int {
+	if m == nil {
+		return 0
+	}
+	return m.maxMatch
+}
+
+// Len returns the number of distinct keywords m matches, 0 for a nil matcher.
+func (m *KeywordMatcher) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.keywords)
+}
+
+// IsTextContentType reports whether a Content-Type value denotes textual
+// content worth analyzing (HTML, XML, JSON, JavaScript, plain text).
+// An empty content type is treated as text since many servers omit it.
+func IsTextContentType(contentType string) bool {
+	ct := strings.ToLower(strings.TrimSpace(contentType))
+	if ct == "" {
+		return true
+	}
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+	if strings.HasPrefix(ct, "text/") {
+		return true
+	}
+	for _, marker := range []string{"json", "xml", "javascript", "ecmascript", "x-www-form-urlencoded"} {
+		if strings.Contains(ct, marker) {
+			return true
+		}
+	}
+	return false
+}
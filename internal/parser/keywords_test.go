@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewKeywordMatcher_Empty(t *testing.T) {
+	if m := NewKeywordMatcher(nil); m != nil {
+		t.Error("expected nil matcher for no keywords")
+	}
+	if m := NewKeywordMatcher([]string{"", "  "}); m != nil {
+		t.Error("expected nil matcher for blank keywords")
+	}
+	var m *KeywordMatcher
+	if got := m.Match([]byte("login")); got != nil {
+		t.Errorf("nil matcher Match = %v, want nil", got)
+	}
+}
+
+func TestKeywordMatcher_Match(t *testing.T) {
+	m := NewKeywordMatcher([]string{"login", "Password", "dashboard", "LOGIN"})
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"no match", "<html>hello</html>", nil},
+		{"case-insensitive", "<h1>LOGIN</h1><input name=PASSWORD>", []string{"login", "Password"}},
+		{"configuration order", "dashboard ... login", []string{"login", "dashboard"}},
+		{"all keywords", "Login password Dashboard", []string{"login", "Password", "dashboard"}},
+		{"empty body", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Match([]byte(tt.body))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match(%q) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeywordMatcher_OverlappingKeywords(t *testing.T) {
+	m := NewKeywordMatcher([]string{"log", "login", "admin"})
+
+	got := m.Match([]byte("please login"))
+	want := []string{"log", "login"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Match = %v, want %v", got, want)
+	}
+}
+
+func TestKeywordMatcher_PartiallyOverlappingKeywords(t *testing.T) {
+	m := NewKeywordMatcher([]string{"login", "ginpanel", "panel"})
+
+	got := m.Match([]byte("<a>LoginPanel</a>"))
+	want := []string{"login", "ginpanel", "panel"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Match = %v, want %v", got, want)
+	}
+}
+
+func TestKeywordMatcher_RegexMetaCharacters(t *testing.T) {
+	m := NewKeywordMatcher([]string{"a.b", "(admin)"})
+
+	if got := m.Match([]byte("axb admin")); got != nil {
+		t.Errorf("metacharacters should be literal, got %v", got)
+	}
+	got := m.Match([]byte("a.b (admin)"))
+	if !reflect.DeepEqual(got, []string{"a.b", "(admin)"}) {
+		t.Errorf("Match = %v", got)
+	}
+}
+
+func TestIsTextContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"", true},
+		{"text/html; charset=utf-8", true},
+		{"text/plain", true},
+		{"application/json", true},
+		{"application/problem+json", true},
+		{"application/xhtml+xml", true},
+		{"application/javascript", true},
+		{"image/png", false},
+		{"application/octet-stream", false},
+		{"application/zip", false},
+		{"application/pdf", false},
+	}
+	for _, tt := range tests {
+		if got := IsTextContentType(tt.contentType); got != tt.want {
+			t.Errorf("IsTextContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Origin returns the origin of u as "scheme://host:port", with the port
+// always written (the scheme's default from defaults when u has none) and the
+// host lowercased, so origins compare as plain strings.
+func Origin(u *url.URL, defaults DefaultPorts) string {
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port == "" {
+		port = defaults.ForScheme(scheme)
+	}
+	return scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+// OriginChange names the origin components that differ between from and to,
+// in scheme, host, port order ("scheme", "host, port", ...). It returns ""
+// for same-origin URLs.
+func OriginChange(from, to *url.URL, defaults DefaultPorts) string {
+	var changed []string
+	fromScheme, toScheme := strings.ToLower(from.Scheme), strings.ToLower(to.Scheme)
+	if fromScheme != toScheme {
+		changed = append(changed, "scheme")
+	}
+	if !strings.EqualFold(from.Hostname(), to.Hostname()) {
+		changed = append(changed, "host")
+	}
+	fromPort, toPort := from.Port(), to.Port()
+	if fromPort == "" {
+		fromPort = defaults.ForScheme(fromScheme)
+	}
+	if toPort == "" {
+		toPort = defaults.ForScheme(toScheme)
+	}
+	if fromPort != toPort {
+		changed = append(changed, "port")
+	}
+	return strings.Join(changed, ", ")
+}
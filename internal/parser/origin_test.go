@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestOrigin(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://Example.com/path", "http://example.com:80"},
+		{"https://example.com:8443/", "https://example.com:8443"},
+		{"https://[2001:db8::1]/", "https://[2001:db8::1]:443"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := Origin(u, StandardPorts); got != tt.want {
+			t.Errorf("Origin(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestOriginChange(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"http://example.com/", "http://EXAMPLE.com:80/login", ""},
+		{"http://example.com:8080/", "http://example.com:8443/", "port"},
+		{"http://example.com/", "https://example.com/", "scheme, port"},
+		{"http://example.com:8080/", "https://example.com:8080/", "scheme"},
+		{"https://example.com/", "https://www.example.com/", "host"},
+		{"http://a.test/", "https://b.test:8443/", "scheme, host, port"},
+	}
+	for _, tt := range tests {
+		from, _ := url.Parse(tt.from)
+		to, _ := url.Parse(tt.to)
+		if got := OriginChange(from, to, StandardPorts); got != tt.want {
+			t.Errorf("OriginChange(%q, %q) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
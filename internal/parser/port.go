@@ -13,17 +13,91 @@ var DefaultHTTPPorts = []string{"80", "8000", "8080", "8888"}
 // Default common HTTPS ports for probing
 var DefaultHTTPSPorts = []string{"443", "8443", "10443", "8444"}
 
+// PortGroup is a named port list usable in -p as @name
+type PortGroup struct {
+	Name        string
+	Description string
+	Ports       string // Port list in -p syntax, without groups
+}
+
+// portGroups are the named port lists, in -list-port-groups order
+var portGroups = []PortGroup{
+	{
+		Name:        "web-common",
+		Description: "Ports commonly serving HTTP(S): web servers, proxies, panels and app servers",
+		Ports:       "80,81,443,591,2082,2083,2086,2087,2095,2096,3000,3128,4443,5000,5601,7001,8000,8001,8008,8080,8081,8088,8443,8888,9000,9090,9200,9443,10000,10443",
+	},
+	{
+		Name:        "top100",
+		Description: "nmap's 100 most frequently open TCP ports",
+		Ports: "7,9,13,21-23,25-26,37,53,79-81,88,106,110-111,113,119,135,139,143-144,179,199,389,427," +
+			"443-445,465,513-515,543-544,548,554,587,631,646,873,990,993,995,1025-1029,1110,1433," +
+			"1720,1723,1755,1900,2000-2001,2049,2121,2717,3000,3128,3306,3389,3986,4899,5000,5009," +
+			"5051,5060,5101,5190,5357,5432,5631,5666,5800,5900,6000-6001,6646,7070,8000,8008-8009," +
+			"8080-8081,8443,8888,9100,9999-10000,32768,49152-49157",
+	},
+	{
+		Name:        "all",
+		Description: "Every TCP port",
+		Ports:       "1-65535",
+	},
+}
+
+// PortGroups returns the named port groups usable in a port list
+func PortGroups() []PortGroup {
+	return append([]PortGroup(nil), portGroups...)
+}
+
+// lookupPortGroup returns the group named name (without the leading @)
+func lookupPortGroup(name string) (PortGroup, bool) {
+	for _, group := range portGroups {
+		if strings.EqualFold(group.Name, name) {
+			return group, true
+		}
+	}
+	return PortGroup{}, false
+}
+
 // ParsePortList parses a comma-separated port list with support for ranges
+// and named groups (see PortGroups)
 // Examples:
 //   "80,443,8080" → [80, 443, 8080]
 //   "8000-8005" → [8000, 8001, 8002, 8003, 8004, 8005]
 //   "80,443,8000-8010" → [80, 443, 8000, 8001, ..., 8010]
+//   "@web-common,9000-9010" → the web-common ports plus 9000-9010
 func ParsePortList(portStr string) ([]string, error) {
+	spans, err := parsePortSpans(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make([]string, 0, countSpans(spans))
+	for _, span := range spans {
+		for port := span[0]; port <= span[1]; port++ {
+			ports = append(ports, strconv.Itoa(port))
+		}
+	}
+	return ports, nil
+}
+
+// CountPortList returns how many ports ParsePortList would return for
+// portStr without building the list
+func CountPortList(portStr string) (int, error) {
+	spans, err := parsePortSpans(portStr)
+	if err != nil {
+		return 0, err
+	}
+	return countSpans(spans), nil
+}
+
+// parsePortSpans parses a port list into sorted, non-overlapping inclusive
+// [start, end] ranges; single ports are one-port ranges
+func parsePortSpans(portStr string) ([][2]int, error) {
 	if portStr == "" {
 		return nil, fmt.Errorf("empty port list")
 	}
 
-	portMap := make(map[string]bool) // Use map for deduplication
+	var spans [][2]int
 	parts := strings.Split(portStr, ",")
 
 	for _, part := range parts {
@@ -32,6 +106,20 @@ func ParsePortList(portStr string) ([]string, error) {
 			continue
 		}
 
+		// Named group (e.g., "@web-common")
+		if name, ok := strings.CutPrefix(part, "@"); ok {
+			group, found := lookupPortGroup(name)
+			if !found {
+				return nil, fmt.Errorf("unknown port group %s (known: %s)", part, portGroupNames())
+			}
+			groupSpans, err := parsePortSpans(group.Ports)
+			if err != nil {
+				return nil, fmt.Errorf("port group %s: %v", part, err)
+			}
+			spans = append(spans, groupSpans...)
+			continue
+		}
+
 		// Check if it's a range (e.g., "8000-8010")
 		if strings.Contains(part, "-") {
 			rangeParts := strings.Split(part, "-")
@@ -60,10 +148,7 @@ func ParsePortList(portStr string) ([]string, error) {
 				return nil, fmt.Errorf("invalid range %s: start port > end port", part)
 			}
 
-			// Add all ports in range
-			for port := start; port <= end; port++ {
-				portMap[strconv.Itoa(port)] = true
-			}
+			spans = append(spans, [2]int{start, end})
 		} else {
 			// Single port
 			port, err := strconv.Atoi(part)
@@ -76,27 +161,43 @@ func ParsePortList(portStr string) ([]string, error) {
 				return nil, fmt.Errorf("port out of range (1-65535): %d", port)
 			}
 
-			portMap[part] = true
+			spans = append(spans, [2]int{port, port})
 		}
 	}
 
 	// Check if we got any valid ports
-	if len(portMap) == 0 {
+	if len(spans) == 0 {
 		return nil, fmt.Errorf("no valid ports found in port list")
 	}
 
-	// Convert map to sorted slice
-	ports := make([]string, 0, len(portMap))
-	for port := range portMap {
-		ports = append(ports, port)
+	// Sort and merge overlapping or adjacent ranges (deduplicates ports)
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span[0] <= last[1]+1 {
+			last[1] = max(last[1], span[1])
+			continue
+		}
+		merged = append(merged, span)
 	}
+	return merged, nil
+}
 
-	// Sort ports numerically
-	sort.Slice(ports, func(i, j int) bool {
-		pi, _ := strconv.Atoi(ports[i])
-		pj, _ := strconv.Atoi(ports[j])
-		return pi < pj
-	})
+// portGroupNames lists the group names as written in a port list
+func portGroupNames() string {
+	names := make([]string, len(portGroups))
+	for i, group := range portGroups {
+		names[i] = "@" + group.Name
+	}
+	return strings.Join(names, ", ")
+}
 
-	return ports, nil
+// countSpans returns the number of ports covered by non-overlapping spans
+func countSpans(spans [][2]int) int {
+	n := 0
+	for _, span := range spans {
+		n += span[1] - span[0] + 1
+	}
+	return n
 }
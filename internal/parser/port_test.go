@@ -148,3 +148,77 @@ func TestParsePortList_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePortList_Groups(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"@web-common", 30},
+		{"@top100", 100},
+		{"@all", 65535},
+		{"@WEB-COMMON", 30},
+		{"@web-common,9000-9010", 30 + 10},     // 9000 is in the group
+		{"@web-common,@top100", 30 + 100 - 13}, // 13 ports are in both
+		{"@top100,@all,80", 65535},             // everything overlaps @all
+		{"8443, @web-common ,8443", 30},        // spaces and duplicate literals
+		{"@web-common,@web-common", 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ports, err := ParsePortList(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(ports) != tt.want {
+				t.Errorf("got %d ports, want %d", len(ports), tt.want)
+			}
+			if n, _ := CountPortList(tt.input); n != len(ports) {
+				t.Errorf("CountPortList = %d, want %d", n, len(ports))
+			}
+		})
+	}
+}
+
+func TestParsePortList_GroupMixedWithRange(t *testing.T) {
+	ports, err := ParsePortList("8005-8009,@web-common")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "80,81,443,591,2082,2083,2086,2087,2095,2096,3000,3128,4443,5000,5601,7001,8000,8001,8005,8006,8007,8008,8009,8080,8081,8088,8443,8888,9000,9090,9200,9443,10000,10443"
+	if got := strings.Join(ports, ","); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestParsePortList_UnknownGroup(t *testing.T) {
+	for _, input := range []string{"@webcommon", "80,@", "@top-100,443"} {
+		_, err := ParsePortList(input)
+		if err == nil {
+			t.Fatalf("ParsePortList(%q): expected error, got nil", input)
+		}
+		if !strings.Contains(err.Error(), "unknown port group") || !strings.Contains(err.Error(), "@web-common") {
+			t.Errorf("ParsePortList(%q) error = %q, want the unknown group and the known names", input, err)
+		}
+	}
+}
+
+func TestPortGroups(t *testing.T) {
+	groups := PortGroups()
+	if len(groups) == 0 {
+		t.Fatal("no port groups")
+	}
+	for _, group := range groups {
+		if strings.Contains(group.Ports, "@") {
+			t.Errorf("group %s refers to another group", group.Name)
+		}
+		if _, err := ParsePortList(group.Ports); err != nil {
+			t.Errorf("group %s: %v", group.Name, err)
+		}
+	}
+	// The returned slice is a copy
+	groups[0].Ports = "1"
+	if PortGroups()[0].Ports == "1" {
+		t.Error("PortGroups exposed the internal table")
+	}
+}
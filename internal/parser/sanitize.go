@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ellipsis marks strings shortened by TruncateRunes.
+const ellipsis = "…"
+
+// SanitizeString makes an extracted string safe for single-line JSONL output:
+// whitespace control characters (\n, \r, \t, ...) become spaces, all other
+// control characters (NUL, ESC, DEL, C1) are dropped, and runs of whitespace
+// are collapsed to a single space.
+func SanitizeString(s string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(cleaned), " ")
+}
+
+// TruncateRunes shortens s to at most max runes, ending in an ellipsis when
+// truncated. A max of 0 or less disables truncation.
+func TruncateRunes(s string, max int) (string, bool) {
+	if max <= 0 {
+		return s, false
+	}
+	count := 0
+	for i := range s {
+		if count == max-1 {
+			// Only truncate if more than one rune remains
+			if utf8.RuneCountInString(s[i:]) <= 1 {
+				return s, false
+			}
+			return strings.TrimRight(s[:i], " ") + ellipsis, true
+		}
+		count++
+	}
+	return s, false
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeString(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "Admin Login", "Admin Login"},
+		{"newlines", "Admin\nLogin\r\nPage", "Admin Login Page"},
+		{"tabs and runs", "  Admin\t\t  Login  ", "Admin Login"},
+		{"nul bytes", "Admin\x00Login\x00", "AdminLogin"},
+		{"escape sequence", "\x1b[31mRed\x1b[0m Title", "[31mRed[0m Title"},
+		{"del and c1", "A\x7fB\u0085C\u009bD", "AB CD"},
+		{"emoji kept", "🔥 Hot\n🚀 Deals", "🔥 Hot 🚀 Deals"},
+		{"unicode space", "Admin  Login", "Admin Login"},
+		{"only control", "\x00\x01\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeString(tt.input); got != tt.want {
+				t.Errorf("SanitizeString(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		max           int
+		want          string
+		wantTruncated bool
+	}{
+		{"short", "hello", 10, "hello", false},
+		{"exact", "hello", 5, "hello", false},
+		{"ascii", "hello world", 6, "hello…", true},
+		{"trailing space trimmed", "hello world", 7, "hello…", true},
+		{"emoji rune-safe", "🔥🔥🔥🔥🔥", 3, "🔥🔥…", true},
+		{"multibyte", "日本語のタイトル", 4, "日本語…", true},
+		{"disabled", "hello world", 0, "hello world", false},
+		{"max one", "hello", 1, "…", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := TruncateRunes(tt.input, tt.max)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("TruncateRunes(%q, %d) = (%q, %v), want (%q, %v)",
+					tt.input, tt.max, got, truncated, tt.want, tt.wantTruncated)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("TruncateRunes produced invalid UTF-8: %q", got)
+			}
+		})
+	}
+}
+
+func TestTruncateRunes_MinifiedJSTitle(t *testing.T) {
+	title := SanitizeString("<script>" + strings.Repeat("var a=1;\n", 10000))
+	got, truncated := TruncateRunes(title, 300)
+	if !truncated {
+		t.Fatal("expected truncation")
+	}
+	if n := utf8.RuneCountInString(got); n > 300 {
+		t.Errorf("truncated title has %d runes, want <= 300", n)
+	}
+	if strings.ContainsAny(got, "\n\x00") {
+		t.Errorf("truncated title contains control characters: %q", got)
+	}
+}
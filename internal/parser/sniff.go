@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is the number of body bytes considered by http.DetectContentType.
+const sniffLen = 512
+
+// SniffContentType detects the content type of body from its first 512 bytes.
+// Returns "" for an empty body.
+func SniffContentType(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if len(body) > sniffLen {
+		body = body[:sniffLen]
+	}
+	return http.DetectContentType(body)
+}
+
+// AnalysisContentType decides which content type should drive body analysis
+// (title extraction, word counting, keyword matching).
+//
+// When the Content-Type header is missing or application/octet-stream, the
+// sniffed type is used and also returned as detected so it can be reported.
+// When the header claims a textual type but the body sniffs as binary (e.g. a
+// PDF or gzip blob served as text/html), the sniffed type is used for analysis
+// but detected is left empty since the header value is still reported as-is.
+func AnalysisContentType(headerType string, body []byte) (analysisType, detected string) {
+	sniffed := SniffContentType(body)
+	if sniffed == "" {
+		return headerType, ""
+	}
+
+	if isUninformativeContentType(headerType) {
+		return sniffed, sniffed
+	}
+	if IsTextContentType(headerType) && !IsTextContentType(sniffed) {
+		return sniffed, ""
+	}
+	return headerType, ""
+}
+
+// isUninformativeContentType reports whether a Content-Type header value says
+// nothing useful about the body.
+func isUninformativeContentType(contentType string) bool {
+	ct := MediaType(contentType)
+	return ct == "" || ct == "application/octet-stream"
+}
+
+// MediaType returns the lowercase media type of a Content-Type header value
+// without its parameters: "Text/HTML; charset=utf-8" is "text/html".
+// Malformed values are cut at the first semicolon.
+func MediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	ct, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(ct))
+}
@@ -0,0 +1,86 @@
+package parser
+
+import "testing"
+
+var (
+	pdfFixture  = []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	pngFixture  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+	gzipFixture = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\x03\xcbH\xcd\xc9\xc9\x07\x00")
+	jsonFixture = []byte(`{"status":"ok","items":[1,2,3]}`)
+	htmlFixture = []byte("<!DOCTYPE html><html><head><title>Device Login</title></head><body>Welcome</body></html>")
+)
+
+func TestSniffContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"empty", nil, ""},
+		{"pdf", pdfFixture, "application/pdf"},
+		{"png", pngFixture, "image/png"},
+		{"gzip", gzipFixture, "application/x-gzip"},
+		{"json", jsonFixture, "text/plain; charset=utf-8"},
+		{"html", htmlFixture, "text/html; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffContentType(tt.body); got != tt.want {
+				t.Errorf("SniffContentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalysisContentType(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		body         []byte
+		wantAnalysis string
+		wantDetected string
+	}{
+		{"missing header html", "", htmlFixture, "text/html; charset=utf-8", "text/html; charset=utf-8"},
+		{"missing header png", "", pngFixture, "image/png", "image/png"},
+		{"octet-stream pdf", "application/octet-stream", pdfFixture, "application/pdf", "application/pdf"},
+		{"octet-stream json", "application/octet-stream", jsonFixture, "text/plain; charset=utf-8", "text/plain; charset=utf-8"},
+		{"honest html", "text/html; charset=utf-8", htmlFixture, "text/html; charset=utf-8", ""},
+		{"honest json", "application/json", jsonFixture, "application/json", ""},
+		{"html header pdf body", "text/html", pdfFixture, "application/pdf", ""},
+		{"html header gzip body", "text/html", gzipFixture, "application/x-gzip", ""},
+		{"binary header kept", "image/png", pngFixture, "image/png", ""},
+		{"empty body", "", nil, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, detected := AnalysisContentType(tt.header, tt.body)
+			if analysis != tt.wantAnalysis {
+				t.Errorf("analysis type = %q, want %q", analysis, tt.wantAnalysis)
+			}
+			if detected != tt.wantDetected {
+				t.Errorf("detected type = %q, want %q", detected, tt.wantDetected)
+			}
+		})
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"text/html", "text/html"},
+		{"Text/HTML; charset=UTF-8", "text/html"},
+		{"application/json;charset=utf-8", "application/json"},
+		{" application/octet-stream ", "application/octet-stream"},
+		{"text/html; charset", "text/html"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := MediaType(tt.contentType); got != tt.want {
+			t.Errorf("MediaType(%q) = %q, want %q", tt.contentType, got, tt.want)
+		}
+	}
+}
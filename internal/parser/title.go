@@ -0,0 +1,288 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"unicode/utf16"
+)
+
+// Title sources reported in title_source.
+const (
+	TitleSourceHTML    = "html"
+	TitleSourceOG      = "og"
+	TitleSourceTwitter = "twitter"
+	TitleSourceJSON    = "json"
+	TitleSourceXML     = "xml"
+	TitleSourcePDF     = "pdf"
+
+	// Synthesized by FallbackTitle for pages without a title (-title-fallback)
+	TitleSourceH1   = "h1"
+	TitleSourcePath = "path"
+	TitleSourceHost = "host"
+)
+
+// pdfTitleScanLen is how much of a PDF is searched for the info dictionary's
+// /Title entry. Documents that keep it further in are not parsed.
+const pdfTitleScanLen = 8 * 1024
+
+// titleKind picks the title extractor for a content type. An empty content
+// type is decided from the body: PDF magic, a JSON object or array, or an XML
+// declaration select those extractors, anything else is parsed as HTML.
+func titleKind(body string, contentType string) string {
+	ct := strings.ToLower(strings.TrimSpace(contentType))
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+
+	switch {
+	case ct == "":
+		trimmed := strings.TrimLeft(body, " \t\r\n\ufeff")
+		switch {
+		case strings.HasPrefix(trimmed, "%PDF-"):
+			return TitleSourcePDF
+		case strings.HasPrefix(trimmed, "{"), strings.HasPrefix(trimmed, "["):
+			return TitleSourceJSON
+		case strings.HasPrefix(trimmed, "<?xml") && !strings.Contains(strings.ToLower(trimmed[:min(len(trimmed), 1024)]), "<html"):
+			return TitleSourceXML
+		}
+		return TitleSourceHTML
+	case ct == "text/html", strings.Contains(ct, "xhtml"):
+		return TitleSourceHTML
+	case strings.Contains(ct, "json"):
+		return TitleSourceJSON
+	case strings.Contains(ct, "xml"):
+		return TitleSourceXML
+	case ct == "application/pdf":
+		return TitleSourcePDF
+	}
+	return ""
+}
+
+// FallbackTitle synthesizes a title for a page that has none: the first
+// <h1> text, else the last segment of the final URL's path (URL-decoded, so
+// "/files/Annual%20Report.pdf" gives "Annual Report.pdf"), else the
+// hostname. Candidates are sanitized; source is one of TitleSourceH1,
+// TitleSourcePath and TitleSourceHost, or "" when nothing is left.
+func FallbackTitle(h1 string, finalURL *url.URL) (title string, source string) {
+	if title = SanitizeString(h1); title != "" {
+		return title, TitleSourceH1
+	}
+	if finalURL == nil {
+		return "", ""
+	}
+	escaped := strings.TrimRight(finalURL.EscapedPath(), "/")
+	segment := escaped[strings.LastIndex(escaped, "/")+1:]
+	if decoded, err := url.PathUnescape(segment); err == nil {
+		segment = decoded
+	}
+	if title = SanitizeString(segment); title != "" {
+		return title, TitleSourcePath
+	}
+	if title = SanitizeString(finalURL.Hostname()); title != "" {
+		return title, TitleSourceHost
+	}
+	return "", ""
+}
+
+// IsPDFContentType reports whether a Content-Type value denotes a PDF document.
+func IsPDFContentType(contentType string) bool {
+	ct := strings.ToLower(strings.TrimSpace(contentType))
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+	return ct == "application/pdf"
+}
+
+// extractJSONTitle returns the top-level "title" or "name" string of a JSON object.
+func extractJSONTitle(body string) string {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		return ""
+	}
+	for _, key := range []string{"title", "name"} {
+		var s string
+		if raw, ok := doc[key]; ok && json.Unmarshal(raw, &s) == nil && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// extractXMLTitle returns the text of the first <title> element in an XML
+// document (the channel title for RSS, the feed title for Atom).
+func extractXMLTitle(body string) string {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || !strings.EqualFold(start.Name.Local, "title") {
+			continue
+		}
+		var title strings.Builder
+		for depth := 1; depth > 0; {
+			tok, err := decoder.Token()
+			if err != nil {
+				break
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				depth++
+			case xml.EndElement:
+				depth--
+			case xml.CharData:
+				title.Write(t)
+			}
+		}
+		return strings.TrimSpace(title.String())
+	}
+}
+
+// extractPDFTitle returns the /Title entry of a PDF's document info dictionary
+// if it appears within the first pdfTitleScanLen bytes. Both literal and hex
+// strings are supported, including UTF-16BE strings with a byte order mark.
+func extractPDFTitle(body string) string {
+	data := []byte(body[:min(len(body), pdfTitleScanLen)])
+	for {
+		i := bytes.Index(data, []byte("/Title"))
+		if i < 0 {
+			return ""
+		}
+		data = data[i+len("/Title"):]
+		rest := bytes.TrimLeft(data, " \t\r\n")
+		if len(rest) == 0 {
+			return ""
+		}
+
+		var raw []byte
+		var ok bool
+		switch rest[0] {
+		case '(':
+			raw, ok = pdfLiteralString(rest[1:])
+		case '<':
+			raw, ok = pdfHexString(rest[1:])
+		}
+		if ok {
+			return strings.TrimSpace(pdfTextString(raw))
+		}
+	}
+}
+
+// pdfLiteralString decodes a PDF literal string whose opening parenthesis has
+// already been consumed.
+func pdfLiteralString(data []byte) ([]byte, bool) {
+	var out []byte
+	depth := 1
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, true
+			}
+		case '\\':
+			i++
+			if i >= len(data) {
+				return nil, false
+			}
+			switch e := data[i]; e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// Escaped line break continues the string
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for n := 0; n < 2 && i+1 < len(data) && data[i+1] >= '0' && data[i+1] <= '7'; n++ {
+						i++
+						v = v*8 + int(data[i]-'0')
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return nil, false
+}
+
+// pdfHexString decodes a PDF hex string whose opening angle bracket has
+// already been consumed.
+func pdfHexString(data []byte) ([]byte, bool) {
+	end := bytes.IndexByte(data, '>')
+	if end < 0 {
+		return nil, false
+	}
+	var digits []byte
+	for _, c := range data[:end] {
+		if unhex(c) >= 0 {
+			digits = append(digits, c)
+		} else if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return nil, false
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		out[i] = byte(unhex(digits[2*i])<<4 | unhex(digits[2*i+1]))
+	}
+	return out, true
+}
+
+func unhex(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+// pdfTextString converts a PDF text string to UTF-8. Strings starting with a
+// UTF-16BE byte order mark are decoded as such; anything else is treated as
+// Latin-1, which matches PDFDocEncoding for printable characters.
+func pdfTextString(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
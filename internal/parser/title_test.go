@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestExtractTitleWithSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		wantTitle   string
+		wantSource  string
+	}{
+		{"html title", "<html><head><title>Home</title></head></html>", "text/html", "Home", TitleSourceHTML},
+		{"og title", `<html><head><meta property="og:title" content="OG Home"></head></html>`, "text/html", "OG Home", TitleSourceOG},
+		{"twitter title", `<html><head><meta name="twitter:title" content="TW Home"></head></html>`, "", "TW Home", TitleSourceTwitter},
+		{"json title", `{"name":"fallback","title":"API Gateway"}`, "application/json", "API Gateway", TitleSourceJSON},
+		{"json name", `{"name":"elasticsearch-node-1","version":{"number":"7.10.2"}}`, "application/json; charset=UTF-8", "elasticsearch-node-1", TitleSourceJSON},
+		{"json non-string title", `{"title":42,"name":"svc"}`, "application/problem+json", "svc", TitleSourceJSON},
+		{"json nested only", `{"info":{"title":"Swagger Petstore"}}`, "application/json", "", ""},
+		{"json array", `[{"title":"x"}]`, "application/json", "", ""},
+		{"json sniffed", `  {"title":"Sniffed"}`, "", "Sniffed", TitleSourceJSON},
+		{"rss", `<?xml version="1.0"?><rss version="2.0"><channel><title>Example Feed</title><item><title>Post</title></item></channel></rss>`, "application/rss+xml", "Example Feed", TitleSourceXML},
+		{"atom", `<feed xmlns="http://www.w3.org/2005/Atom"><title type="text">Atom &amp; Feed</title></feed>`, "application/atom+xml", "Atom & Feed", TitleSourceXML},
+		{"xml no title", `<?xml version="1.0"?><Error><Code>AccessDenied</Code></Error>`, "application/xml", "", ""},
+		{"xml sniffed", `<?xml version="1.0"?><config><title>Sniffed XML</title></config>`, "", "Sniffed XML", TitleSourceXML},
+		{"xhtml", `<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"><head><title>XHTML</title></head></html>`, "application/xhtml+xml", "XHTML", TitleSourceHTML},
+		{"pdf literal", "%PDF-1.4\n1 0 obj\n<< /Title (Annual \\(2023\\) Report) /Author (ACME) >>\nendobj\n", "application/pdf", "Annual (2023) Report", TitleSourcePDF},
+		{"pdf hex utf16", "%PDF-1.7\n<</Title<FEFF00500072006900630065>>>", "application/pdf", "Price", TitleSourcePDF},
+		{"pdf octal escape", "%PDF-1.3\n<</Title(Caf\\351)>>", "application/pdf", "Café", TitleSourcePDF},
+		{"pdf sniffed", "%PDF-1.5\n<</Title (Sniffed PDF)>>", "", "Sniffed PDF", TitleSourcePDF},
+		{"pdf no title", "%PDF-1.4\n<</Producer (Ghostscript)>>", "application/pdf", "", ""},
+		{"plain text", "<title>Not HTML</title>", "text/plain", "", ""},
+		{"image", "\x89PNG", "image/png", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, source := ExtractTitleWithSource(tt.body, tt.contentType)
+			if title != tt.wantTitle || source != tt.wantSource {
+				t.Errorf("ExtractTitleWithSource() = (%q, %q), want (%q, %q)", title, source, tt.wantTitle, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestExtractPDFTitle_ScanLimit(t *testing.T) {
+	body := "%PDF-1.4\n"
+	for len(body) < pdfTitleScanLen {
+		body += "% padding padding padding padding\n"
+	}
+	body += "<</Title (Too Late)>>"
+	if got := extractPDFTitle(body); got != "" {
+		t.Errorf("extractPDFTitle() = %q, want empty beyond the scan limit", got)
+	}
+}
+
+func TestIsPDFContentType(t *testing.T) {
+	for ct, want := range map[string]bool{
+		"application/pdf":          true,
+		"Application/PDF; q=1":     true,
+		"application/pdf-template": false,
+		"text/html":                false,
+		"":                         false,
+	} {
+		if got := IsPDFContentType(ct); got != want {
+			t.Errorf("IsPDFContentType(%q) = %v, want %v", ct, got, want)
+		}
+	}
+}
+
+func TestFallbackTitle(t *testing.T) {
+	tests := []struct {
+		name       string
+		h1         string
+		url        string
+		wantTitle  string
+		wantSource string
+	}{
+		{"h1", "  Welcome\n  back ", "https://example.com/login", "Welcome back", TitleSourceH1},
+		{"blank h1 falls through", " \t ", "https://example.com/login", "login", TitleSourcePath},
+		{"decoded file name", "", "https://example.com/files/Annual%20Report%202023.pdf", "Annual Report 2023.pdf", TitleSourcePath},
+		{"encoded slash stays in segment", "", "https://example.com/a/b%2Fc.zip", "b/c.zip", TitleSourcePath},
+		{"control characters sanitized", "", "https://example.com/x%0Ay%00.bin", "x y.bin", TitleSourcePath},
+		{"trailing slash", "", "https://example.com/docs/", "docs", TitleSourcePath},
+		{"query ignored", "", "https://example.com/?page=1", "example.com", TitleSourceHost},
+		{"bare ip", "", "http://10.0.0.1:8080/", "10.0.0.1", TitleSourceHost},
+		{"ipv6", "", "http://[2001:db8::1]/", "2001:db8::1", TitleSourceHost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("url.Parse: %v", err)
+			}
+			title, source := FallbackTitle(tt.h1, u)
+			if title != tt.wantTitle || source != tt.wantSource {
+				t.Errorf("FallbackTitle(%q, %s) = (%q, %q), want (%q, %q)", tt.h1, tt.url, title, source, tt.wantTitle, tt.wantSource)
+			}
+		})
+	}
+	if title, source := FallbackTitle("", nil); title != "" || source != "" {
+		t.Errorf("FallbackTitle without URL = (%q, %q), want nothing", title, source)
+	}
+}
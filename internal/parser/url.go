@@ -1,28 +1,54 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/url"
 	"strconv"
 	"strings"
+
+	"probeHTTP/internal/output"
 )
 
 // ParsedURL holds the components of a parsed input URL
 type ParsedURL struct {
-	Original string // Original input string
-	Scheme   string // http, https, or empty if not specified
-	Host     string // hostname only (no port)
-	Port     string // port number or empty
-	Path     string // path component (default "/")
+	Original   string        // Original input string
+	Scheme     string        // http, https, or empty if not specified
+	Host       string        // hostname only (no port)
+	Port       string        // port number or empty
+	Path       string        // path and query (default "/"); fragments are dropped
+	SchemeHint string        // scheme from a trailing [http]/[https] hint, or empty
+	User       *url.Userinfo // credentials from the input's userinfo, never part of probe URLs
+}
+
+// DefaultPorts holds the port assumed for each scheme when a URL has no explicit port.
+type DefaultPorts struct {
+	HTTP  string
+	HTTPS string
+}
+
+// StandardPorts are the protocol-standard default ports (80 for HTTP, 443 for HTTPS).
+var StandardPorts = DefaultPorts{HTTP: "80", HTTPS: "443"}
+
+// ForScheme returns the default port for the given scheme.
+func (d DefaultPorts) ForScheme(scheme string) string {
+	if scheme == "https" {
+		return d.HTTPS
+	}
+	return d.HTTP
 }
 
-// ParseInputURL parses an input URL string and extracts its components
+// ParseInputURL parses an input URL string and extracts its components.
+// A trailing " [http]" or " [https]" (see WithSchemeHint) sets SchemeHint.
+// Userinfo ("user:pass@") is split off into User and the fragment, which is
+// client-side only, is dropped.
 func ParseInputURL(inputURL string) ParsedURL {
 	parsed := ParsedURL{
 		Original: inputURL,
 		Path:     "/",
 	}
+	inputURL, parsed.SchemeHint = splitSchemeHint(inputURL)
 
 	// Check if input has a scheme
 	hasScheme := strings.HasPrefix(inputURL, "http://") || strings.HasPrefix(inputURL, "https://")
@@ -42,17 +68,15 @@ func ParseInputURL(inputURL string) ParsedURL {
 		parsed.Scheme = u.Scheme
 		parsed.Host = u.Hostname()
 		parsed.Port = u.Port()
+		parsed.User = u.User
 
-		// Preserve full path including query and fragment
+		// Preserve path and query
 		if u.Path != "" {
 			parsed.Path = u.Path
 		}
 		if u.RawQuery != "" {
 			parsed.Path += "?" + u.RawQuery
 		}
-		if u.Fragment != "" {
-			parsed.Path += "#" + u.Fragment
-		}
 	} else {
 		// No scheme - could be "host", "host:port", "host/path", or "host:port/path"
 		// First, check if there's a path separator
@@ -77,6 +101,15 @@ func ParseInputURL(inputURL string) ParsedURL {
 		if hostPortEnd < len(inputURL) {
 			rest = inputURL[hostPortEnd:]
 		}
+		if i := strings.Index(rest, "#"); i >= 0 {
+			rest = rest[:i]
+		}
+
+		// Split off userinfo; the last "@" ends it, as in url.Parse
+		if at := strings.LastIndex(hostPort, "@"); at >= 0 {
+			parsed.User = parseUserinfo(hostPort[:at])
+			hostPort = hostPort[at+1:]
+		}
 
 		// Parse host:port
 		if strings.Contains(hostPort, ":") {
@@ -103,6 +136,65 @@ func ParseInputURL(inputURL string) ParsedURL {
 	return parsed
 }
 
+// parseUserinfo decodes a percent-encoded "user[:password]" userinfo.
+// Undecodable parts are kept as written.
+func parseUserinfo(userinfo string) *url.Userinfo {
+	unescape := func(s string) string {
+		if u, err := url.PathUnescape(s); err == nil {
+			return u
+		}
+		return s
+	}
+	name, password, hasPassword := strings.Cut(userinfo, ":")
+	if hasPassword {
+		return url.UserPassword(unescape(name), unescape(password))
+	}
+	return url.User(unescape(name))
+}
+
+// StripUserinfo removes a "user:pass@" userinfo from an input URL, with or
+// without scheme, leaving the rest of the input (fragment included) as is.
+// It is applied to inputs before they are logged or reported.
+func StripUserinfo(input string) string {
+	start := 0
+	if i := strings.Index(input, "://"); i >= 0 {
+		start = i + 3
+	}
+	end := len(input)
+	if i := strings.IndexAny(input[start:], "/?#"); i >= 0 {
+		end = start + i
+	}
+	at := strings.LastIndex(input[start:end], "@")
+	if at < 0 {
+		return input
+	}
+	return input[:start] + input[start+at+1:]
+}
+
+// CollapsePathSlashes collapses runs of "/" in the path of an input URL
+// ("example.com//a///b" becomes "example.com/a/b"). The query and fragment
+// are left alone. Used with -normalize-path.
+func CollapsePathSlashes(input string) string {
+	start := 0
+	if i := strings.Index(input, "://"); i >= 0 {
+		start = i + 3
+	}
+	pathStart := strings.IndexAny(input[start:], "/?#")
+	if pathStart < 0 || input[start+pathStart] != '/' {
+		return input
+	}
+	pathStart += start
+	pathEnd := len(input)
+	if i := strings.IndexAny(input[pathStart:], "?#"); i >= 0 {
+		pathEnd = pathStart + i
+	}
+	path := input[pathStart:pathEnd]
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	return input[:pathStart] + path + input[pathEnd:]
+}
+
 // ValidateURL validates a URL for safety and correctness
 // Returns an error if the URL is invalid or potentially dangerous
 func ValidateURL(input string, allowPrivateIPs bool) error {
@@ -123,6 +215,9 @@ func ValidateURL(input string, allowPrivateIPs bool) error {
 	if parsed.Host == "" {
 		return fmt.Errorf("empty hostname")
 	}
+	if strings.ContainsAny(parsed.Host, " \t@\\") {
+		return fmt.Errorf("invalid hostname %q", parsed.Host)
+	}
 
 	// Check for localhost/private IPs if not allowed
 	if !allowPrivateIPs {
@@ -152,20 +247,119 @@ func ValidateURL(input string, allowPrivateIPs bool) error {
 	return nil
 }
 
-// ExpandURLs takes an input URL and returns all URLs to probe based on configuration
-func ExpandURLs(inputURL string, allSchemes bool, ignorePorts bool, customPorts string) []string {
+// metadataIPs are cloud instance metadata endpoints outside the ranges
+// covered by net.IP's classification methods.
+var metadataIPs = []net.IP{
+	net.ParseIP("100.100.100.200"), // Alibaba Cloud
+	net.ParseIP("fd00:ec2::254"),   // AWS IPv6
+}
+
+// IsPrivateIP reports whether ip is loopback, private (RFC 1918 / ULA),
+// link-local (including 169.254.169.254), unspecified or a known cloud
+// metadata address.
+func IsPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return true
+	}
+	for _, m := range metadataIPs {
+		if ip.Equal(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrivateTarget reports whether host is, or resolves to, a private address
+// (see IsPrivateIP). Literal IPs are checked directly; hostnames are resolved
+// with lookup and count as private if any returned address is. The offending
+// address is returned alongside, or for a public host the first address it
+// resolved to, so the caller can connect to the address it checked.
+// Resolution failures are returned as errors.
+func IsPrivateTarget(ctx context.Context, host string, lookup func(ctx context.Context, host string) ([]string, error)) (bool, string, error) {
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if ip := net.ParseIP(host); ip != nil {
+		return IsPrivateIP(ip), host, nil
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return true, host, nil
+	}
+
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return false, "", err
+	}
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && IsPrivateIP(ip) {
+			return true, addr, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return false, "", &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	return false, addrs[0], nil
+}
+
+// Expansion provenance values reported in a result's expansion object.
+const (
+	SchemeSourceInput         = "input"          // scheme given in the input
+	SchemeSourceDefaultBoth   = "default-both"   // no scheme in the input, both are probed
+	SchemeSourceAllSchemes    = "all-schemes"    // -all-schemes
+	SchemeSourcePortHeuristic = "port-heuristic" // input port 80/443 decided the scheme
+	SchemeSourceHint          = "hint"           // [http]/[https] hint on the input line (-input-format hints)
+	SchemeSourceScan          = "scan"           // service reported by masscan/nmap (-input-format masscan-json, nmap-grepable)
+
+	PortSourceInput       = "input"        // port given in the input
+	PortSourceDefault     = "default"      // scheme's default port
+	PortSourceIgnorePorts = "ignore-ports" // -ignore-ports common port list
+	PortSourceCustomPorts = "custom-ports" // -ports
+	PortSourceScan        = "scan"         // open port from masscan/nmap output
+
+	PathSourceInput   = "input"   // path given in the input (or "/")
+	PathSourceVariant = "variant" // trailing-slash or case variant of an input path (-path-variants)
+)
+
+// ExpandedURL is a URL to probe together with the reason it was generated.
+type ExpandedURL struct {
+	URL       string
+	Expansion output.Expansion
+	VariantOf string // base URL when Expansion.PathSource is PathSourceVariant
+}
+
+// ExpandedURLs is the result of expanding one input.
+type ExpandedURLs []ExpandedURL
+
+// URLs returns the bare URL strings.
+func (e ExpandedURLs) URLs() []string {
+	urls := make([]string, len(e))
+	for i, u := range e {
+		urls[i] = u.URL
+	}
+	return urls
+}
+
+// ExpandURLs takes an input URL and returns all URLs to probe based on
+// configuration, each with the provenance of its scheme, port and path.
+//
+// The URLs are emitted in normalized form so equivalent inputs expand to
+// identical strings and DeduplicateURLs can compare them exactly: the host is
+// lowercased (IPv6 literals bracketed), and the port is written only when it
+// differs from the scheme's default in defaults or when -ignore-ports or
+// -ports force explicit ports. "example.com", "example.com:80" and
+// "http://EXAMPLE.com:80/" therefore all yield "http://example.com/".
+func ExpandURLs(inputURL string, allSchemes bool, ignorePorts bool, customPorts string, defaults DefaultPorts) ExpandedURLs {
 	parsed := ParseInputURL(inputURL)
-	schemes := getSchemesToTest(parsed, allSchemes)
+	schemes, schemeSource := getSchemesToTest(parsed, allSchemes)
 
 	urlMap := make(map[string]bool) // For deduplication
-	var urls []string
+	var urls ExpandedURLs
 
 	for _, scheme := range schemes {
-		ports := getPortsToTest(parsed, scheme, ignorePorts, customPorts)
+		ports, portSource := getPortsToTest(parsed, scheme, ignorePorts, customPorts, defaults)
 
 		for _, port := range ports {
 			// Determine if port should be included in URL
-			includePort := shouldIncludePortInURL(parsed, port, scheme, ignorePorts, customPorts)
+			includePort := shouldIncludePortInURL(port, scheme, ignorePorts, customPorts, defaults)
 
 			// Build the URL
 			urlStr := buildProbeURL(scheme, parsed.Host, port, parsed.Path, includePort)
@@ -173,7 +367,14 @@ func ExpandURLs(inputURL string, allSchemes bool, ignorePorts bool, customPorts
 			// Deduplicate
 			if !urlMap[urlStr] {
 				urlMap[urlStr] = true
-				urls = append(urls, urlStr)
+				urls = append(urls, ExpandedURL{
+					URL: urlStr,
+					Expansion: output.Expansion{
+						SchemeSource: schemeSource,
+						PortSource:   portSource,
+						PathSource:   PathSourceInput,
+					},
+				})
 			}
 		}
 	}
@@ -181,40 +382,162 @@ func ExpandURLs(inputURL string, allSchemes bool, ignorePorts bool, customPorts
 	return urls
 }
 
-// getSchemesToTest returns the list of schemes to test based on configuration and input
-func getSchemesToTest(parsed ParsedURL, allSchemes bool) []string {
+// WithPathVariants returns urls with the path variants of each URL inserted
+// after it (see PathVariants). Variants already present in urls are skipped,
+// so expanding an input list that holds both /admin and /admin/ probes each
+// once, with its own provenance.
+func WithPathVariants(urls ExpandedURLs) ExpandedURLs {
+	planned := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		planned[u.URL] = true
+	}
+
+	withVariants := make(ExpandedURLs, 0, len(urls)*(1+maxPathVariants))
+	for _, base := range urls {
+		withVariants = append(withVariants, base)
+		if base.Expansion.PathSource == PathSourceVariant {
+			continue
+		}
+		u, err := url.Parse(base.URL)
+		if err != nil {
+			continue
+		}
+		prefix := u.Scheme + "://" + u.Host
+		for _, path := range PathVariants(strings.TrimPrefix(base.URL, prefix)) {
+			variantURL := prefix + path
+			if planned[variantURL] {
+				continue
+			}
+			planned[variantURL] = true
+			expansion := base.Expansion
+			expansion.PathSource = PathSourceVariant
+			withVariants = append(withVariants, ExpandedURL{URL: variantURL, Expansion: expansion, VariantOf: base.URL})
+		}
+	}
+	return withVariants
+}
+
+// maxPathVariants bounds how many variants PathVariants returns per path.
+const maxPathVariants = 2
+
+// PathVariants returns the variants of path probed by -path-variants: the
+// trailing slash toggled and the first letter of the last segment with its
+// case toggled ("/Admin" gives "/Admin/" and "/admin"). Variants are not
+// combined, keeping the expansion to at most maxPathVariants per path. The
+// root path and paths whose last segment doesn't start with a letter have
+// fewer or no variants. A query or fragment is kept on every variant.
+func PathVariants(path string) []string {
+	suffix := ""
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path, suffix = path[:i], path[i:]
+	}
+	if path == "" || path == "/" {
+		return nil
+	}
+
+	var variants []string
+	if strings.HasSuffix(path, "/") {
+		variants = append(variants, strings.TrimSuffix(path, "/")+suffix)
+	} else {
+		variants = append(variants, path+"/"+suffix)
+	}
+
+	trimmed := strings.TrimSuffix(path, "/")
+	start := strings.LastIndex(trimmed, "/") + 1
+	if start < len(path) {
+		if c := path[start]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			variants = append(variants, path[:start]+string(c^0x20)+path[start+1:]+suffix)
+		}
+	}
+	return variants
+}
+
+// CountPathVariants returns how many path variants -path-variants adds for
+// each URL inputURL expands to.
+func CountPathVariants(inputURL string) int {
+	return len(PathVariants(ParseInputURL(inputURL).Path))
+}
+
+// CountExpansions returns how many URLs ExpandURLs would return for inputURL
+// with the same options, without building them. Used to budget expansion
+// before materializing URLs.
+func CountExpansions(inputURL string, allSchemes bool, ignorePorts bool, customPorts string, defaults DefaultPorts) int {
+	parsed := ParseInputURL(inputURL)
+	schemes, _ := getSchemesToTest(parsed, allSchemes)
+
+	count := 0
+	for _, scheme := range schemes {
+		count += countPortsToTest(parsed, scheme, ignorePorts, customPorts)
+	}
+	return count
+}
+
+// countPortsToTest mirrors getPortsToTest, returning only the number of ports
+func countPortsToTest(parsed ParsedURL, scheme string, ignorePorts bool, customPorts string) int {
+	if customPorts != "" {
+		if n, err := CountPortList(customPorts); err == nil {
+			return n
+		}
+	}
+	if ignorePorts {
+		if scheme == "https" {
+			return len(DefaultHTTPSPorts)
+		}
+		return len(DefaultHTTPPorts)
+	}
+	return 1
+}
+
+// getSchemesToTest returns the list of schemes to test based on configuration
+// and input, and what decided them (a SchemeSource value)
+func getSchemesToTest(parsed ParsedURL, allSchemes bool) ([]string, string) {
 	// If AllSchemes flag is set, always test both — this is an explicit user
 	// override that takes priority over port-inferred scheme defaults.
 	if allSchemes {
-		return []string{"http", "https"}
+		return []string{"http", "https"}, SchemeSourceAllSchemes
+	}
+
+	// An input hint names the scheme the inventory saw; it beats the port heuristics
+	if parsed.SchemeHint != "" {
+		return []string{parsed.SchemeHint}, SchemeSourceHint
 	}
 
 	// If port 443 is specified, force HTTPS (port 443 is HTTPS-only)
 	if parsed.Port == "443" {
-		return []string{"https"}
+		return []string{"https"}, portHeuristicSource(parsed, "https")
 	}
 
 	// If port 80 is specified, prefer HTTP
 	if parsed.Port == "80" {
-		return []string{"http"}
+		return []string{"http"}, portHeuristicSource(parsed, "http")
 	}
 
 	// If no scheme in input, test both by default
 	if parsed.Scheme == "" {
-		return []string{"http", "https"}
+		return []string{"http", "https"}, SchemeSourceDefaultBoth
 	}
 
 	// Use the scheme from input
-	return []string{parsed.Scheme}
+	return []string{parsed.Scheme}, SchemeSourceInput
 }
 
-// getPortsToTest returns the list of ports to test based on configuration and input
-func getPortsToTest(parsed ParsedURL, scheme string, ignorePorts bool, customPorts string) []string {
+// portHeuristicSource attributes a port-forced scheme to the input when the
+// input already named that scheme.
+func portHeuristicSource(parsed ParsedURL, scheme string) string {
+	if parsed.Scheme == scheme {
+		return SchemeSourceInput
+	}
+	return SchemeSourcePortHeuristic
+}
+
+// getPortsToTest returns the list of ports to test based on configuration and
+// input, and what decided them (a PortSource value)
+func getPortsToTest(parsed ParsedURL, scheme string, ignorePorts bool, customPorts string, defaults DefaultPorts) ([]string, string) {
 	// Custom ports override everything
 	if customPorts != "" {
 		ports, err := ParsePortList(customPorts)
 		if err == nil {
-			return ports
+			return ports, PortSourceCustomPorts
 		}
 		// Invalid port spec — fall through to default behavior
 	}
@@ -222,81 +545,83 @@ func getPortsToTest(parsed ParsedURL, scheme string, ignorePorts bool, customPor
 	// If IgnorePorts is set, use default common ports for the scheme
 	if ignorePorts {
 		if scheme == "https" {
-			return DefaultHTTPSPorts
+			return DefaultHTTPSPorts, PortSourceIgnorePorts
 		}
-		return DefaultHTTPPorts
+		return DefaultHTTPPorts, PortSourceIgnorePorts
 	}
 
 	// If port is specified in input, use it
 	if parsed.Port != "" {
-		return []string{parsed.Port}
+		return []string{parsed.Port}, PortSourceInput
 	}
 
-	// Default: use standard port for the scheme
-	if scheme == "https" {
-		return []string{"443"}
-	}
-	return []string{"80"}
+	// Default: use the configured default port for the scheme
+	return []string{defaults.ForScheme(scheme)}, PortSourceDefault
 }
 
-// shouldIncludePortInURL determines if the port should be explicitly included in the URL
-func shouldIncludePortInURL(parsed ParsedURL, port string, scheme string, ignorePorts bool, customPorts string) bool {
-	// Always include port if port-related flags are active
+// shouldIncludePortInURL determines if the port should be explicitly included
+// in the URL: always when a port flag is active, otherwise only when it isn't
+// the scheme's default
+func shouldIncludePortInURL(port string, scheme string, ignorePorts bool, customPorts string, defaults DefaultPorts) bool {
 	if ignorePorts || customPorts != "" {
 		return true
 	}
-
-	// Always include port if it was explicitly specified in the original input
-	if parsed.Port != "" {
-		return true
-	}
-
-	// Include port if it's non-standard for the scheme
-	standardPort := "80"
-	if scheme == "https" {
-		standardPort = "443"
-	}
-
-	return port != standardPort
+	return port != defaults.ForScheme(scheme)
 }
 
-// buildProbeURL builds a URL string with optional port inclusion
+// buildProbeURL builds a normalized URL string with optional port inclusion
 func buildProbeURL(scheme string, host string, port string, path string, includePort bool) string {
+	host = strings.ToLower(host)
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
 	if includePort {
-		return fmt.Sprintf("%s://%s:%s%s", scheme, host, port, path)
+		host += ":" + port
 	}
-	return fmt.Sprintf("%s://%s%s", scheme, host, path)
+	return scheme + "://" + host + path
 }
 
-// NormalizeURL normalizes a URL by removing default ports to create a canonical form
-// This allows deduplication of URLs like http://host and http://host:80
-func NormalizeURL(urlStr string) string {
+// NormalizeURL normalizes a URL to a canonical form: lowercase scheme and
+// host, default ports removed and an empty path written as "/".
+// This allows deduplication of URLs like http://host, http://host:80/ and
+// HTTP://HOST (or https://host and https://host:4443 when 4443 is the
+// configured HTTPS default). Only the host is case-folded; paths are
+// case-sensitive and non-empty paths are left as they are.
+func NormalizeURL(urlStr string, defaults DefaultPorts) string {
 	parsed, err := url.Parse(urlStr)
 	if err != nil {
 		return urlStr // Return original if parsing fails
 	}
 
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+
 	// Remove default ports
-	if parsed.Port() == "80" && parsed.Scheme == "http" {
+	if (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Port() == defaults.ForScheme(parsed.Scheme) {
 		parsed.Host = parsed.Hostname()
+		if strings.Contains(parsed.Host, ":") {
+			parsed.Host = "[" + parsed.Host + "]" // keep IPv6 literals bracketed
+		}
 	}
-	if parsed.Port() == "443" && parsed.Scheme == "https" {
-		parsed.Host = parsed.Hostname()
+
+	// http://host and http://host/ request the same resource
+	if parsed.Host != "" && parsed.Path == "" && parsed.Opaque == "" {
+		parsed.Path = "/"
 	}
 
 	return parsed.String()
 }
 
-// DeduplicateURLs removes duplicate URLs that resolve to the same endpoint
-// by normalizing URLs (removing default ports) before comparison
+// DeduplicateURLs removes duplicate URLs, keeping the first occurrence.
+// ExpandURLs emits normalized URLs, so an exact string comparison is enough
+// to merge equivalent targets from different inputs.
 func DeduplicateURLs(urls []string) []string {
-	seen := make(map[string]bool)
+	seen := make(map[string]bool, len(urls))
 	var deduplicated []string
 
 	for _, urlStr := range urls {
-		normalized := NormalizeURL(urlStr)
-		if !seen[normalized] {
-			seen[normalized] = true
+		if !seen[urlStr] {
+			seen[urlStr] = true
 			deduplicated = append(deduplicated, urlStr)
 		}
 	}
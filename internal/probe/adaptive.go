@@ -0,0 +1,287 @@
+package probe
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"probeHTTP/internal/output"
+)
+
+// -adaptive-concurrency policy. Every adaptiveInterval the controller looks
+// at the probes completed since its last decision: more than
+// adaptiveMaxErrorRate of them failing with a timeout or connection reset,
+// or a median latency above adaptiveLatencyFactor times the best median
+// seen so far, halves the concurrency; otherwise it grows by one step.
+const (
+	adaptiveInterval      = 5 * time.Second
+	adaptiveMinProbes     = 5 // Fewer completed probes in a window decide nothing
+	adaptiveMaxErrorRate  = 0.10
+	adaptiveLatencyFactor = 2
+	adaptiveSteps         = 10 // Additive steps from floor to ceiling
+)
+
+// Decisions of aimdController.decide.
+const (
+	adaptiveIncrease = "increase"
+	adaptiveDecrease = "decrease"
+	adaptiveHold     = "hold"
+)
+
+// adaptiveWindow is what the workers observed between two decisions.
+type adaptiveWindow struct {
+	probes    int
+	failures  int             // Timeouts and connection resets
+	latencies []time.Duration // Probes that got an answer
+}
+
+// adaptiveDecision is one decision of aimdController, as logged.
+type adaptiveDecision struct {
+	action    string
+	reason    string
+	previous  int
+	limit     int
+	errorRate float64
+	median    time.Duration
+}
+
+// aimdController decides the effective concurrency within [floor, ceiling]
+// from metric windows: additive increase, multiplicative decrease. It holds
+// no clock or network state, so it can be driven by synthetic windows.
+type aimdController struct {
+	floor, ceiling int
+	limit          int
+	step           int
+	baseline       time.Duration // Lowest median latency of a healthy window
+}
+
+func newAIMDController(floor, ceiling int) *aimdController {
+	floor = max(1, min(floor, ceiling))
+	return &aimdController{
+		floor:   floor,
+		ceiling: ceiling,
+		limit:   floor,
+		step:    max(1, (ceiling-floor)/adaptiveSteps),
+	}
+}
+
+// decide applies one window and returns the resulting decision.
+func (c *aimdController) decide(w adaptiveWindow) adaptiveDecision {
+	d := adaptiveDecision{action: adaptiveHold, previous: c.limit, limit: c.limit}
+	if w.probes < adaptiveMinProbes {
+		d.reason = "too few probes"
+		return d
+	}
+	d.errorRate = float64(w.failures) / float64(w.probes)
+	d.median = medianDuration(w.latencies)
+
+	switch {
+	case d.errorRate > adaptiveMaxErrorRate:
+		d.reason = "error rate"
+		c.limit = max(c.floor, c.limit/2)
+	case c.baseline > 0 && d.median > adaptiveLatencyFactor*c.baseline:
+		d.reason = "latency"
+		c.limit = max(c.floor, c.limit/2)
+	default:
+		d.reason = "healthy"
+		if d.median > 0 && (c.baseline == 0 || d.median < c.baseline) {
+			c.baseline = d.median
+		}
+		c.limit = min(c.ceiling, c.limit+c.step)
+	}
+	d.limit = c.limit
+	switch {
+	case d.limit > d.previous:
+		d.action = adaptiveIncrease
+	case d.limit < d.previous:
+		d.action = adaptiveDecrease
+	}
+	return d
+}
+
+// medianDuration returns the median of latencies, 0 when empty.
+func medianDuration(latencies []time.Duration) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := slices.Sorted(slices.Values(latencies))
+	return sorted[len(sorted)/2]
+}
+
+// concurrencyGate is a semaphore whose size can change while it is held.
+// Workers take a slot per URL; lowering the limit lets running probes
+// finish and only holds back new ones.
+type concurrencyGate struct {
+	mu     sync.Mutex
+	limit  int
+	active int
+	freed  chan struct{} // Closed and replaced whenever a slot may have become free
+}
+
+func newConcurrencyGate(limit int) *concurrencyGate {
+	return &concurrencyGate{limit: limit, freed: make(chan struct{})}
+}
+
+// acquire blocks until a slot is free or ctx is done. A slot is only held
+// when acquire returns nil; the caller must then release it.
+func (g *concurrencyGate) acquire(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		if g.active < g.limit {
+			g.active++
+			g.mu.Unlock()
+			return nil
+		}
+		freed := g.freed
+		g.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release returns a slot taken by acquire.
+func (g *concurrencyGate) release() {
+	g.mu.Lock()
+	g.active--
+	g.wake()
+	g.mu.Unlock()
+}
+
+// setLimit changes the number of slots.
+func (g *concurrencyGate) setLimit(limit int) {
+	g.mu.Lock()
+	g.limit = limit
+	g.wake()
+	g.mu.Unlock()
+}
+
+// wake lets waiting acquires recheck; g.mu must be held.
+func (g *concurrencyGate) wake() {
+	close(g.freed)
+	g.freed = make(chan struct{})
+}
+
+// AdaptiveStats summarizes -adaptive-concurrency for the -stats summary.
+// Ceiling is 0 when adaptive concurrency was off.
+type AdaptiveStats struct {
+	Floor, Ceiling int
+	Current        int // Effective concurrency when the run ended
+	Min, Max       int // Lowest and highest effective concurrency of the run
+	Increases      int
+	Decreases      int
+}
+
+// adaptiveConcurrency ties the gate the workers acquire to the controller
+// and the window the probes are recorded in.
+type adaptiveConcurrency struct {
+	gate       *concurrencyGate
+	controller *aimdController
+
+	mu     sync.Mutex
+	window adaptiveWindow
+	stats  AdaptiveStats
+}
+
+// adaptiveKey carries the adaptiveConcurrency of a ProcessURLs run in the
+// contexts of its probes, so runs sharing a Prober record separately.
+type adaptiveKey struct{}
+
+func newAdaptiveConcurrency(floor, ceiling int) *adaptiveConcurrency {
+	controller := newAIMDController(floor, ceiling)
+	return &adaptiveConcurrency{
+		gate:       newConcurrencyGate(controller.limit),
+		controller: controller,
+		stats: AdaptiveStats{
+			Floor:   controller.floor,
+			Ceiling: ceiling,
+			Current: controller.limit,
+			Min:     controller.limit,
+			Max:     controller.limit,
+		},
+	}
+}
+
+// record adds a completed probe to the current window. Cancelled probes
+// say nothing about the targets and are left out. Refused connections are
+// not failures: closed ports refuse at once whatever the load.
+func (a *adaptiveConcurrency) record(result output.ProbeResult, elapsed time.Duration) {
+	if result.ErrorType == output.ErrorTypeCancelled {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.window.probes++
+	switch result.ErrorType {
+	case output.ErrorTypeTimeout, output.ErrorTypeConnectionReset:
+		a.window.failures++
+	case "":
+		a.window.latencies = append(a.window.latencies, elapsed)
+	}
+}
+
+// adjust decides on the window recorded since the last call and resizes
+// the gate.
+func (a *adaptiveConcurrency) adjust() adaptiveDecision {
+	a.mu.Lock()
+	window := a.window
+	a.window = adaptiveWindow{}
+	d := a.controller.decide(window)
+	a.stats.Current = d.limit
+	a.stats.Min = min(a.stats.Min, d.limit)
+	a.stats.Max = max(a.stats.Max, d.limit)
+	switch d.action {
+	case adaptiveIncrease:
+		a.stats.Increases++
+	case adaptiveDecrease:
+		a.stats.Decreases++
+	}
+	a.mu.Unlock()
+
+	a.gate.setLimit(d.limit)
+	return d
+}
+
+// run adjusts the concurrency every interval until ctx or done ends, and
+// logs every change.
+func (a *adaptiveConcurrency) run(ctx context.Context, done <-chan struct{}, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			d := a.adjust()
+			if d.action == adaptiveHold {
+				continue
+			}
+			logger.Info("adaptive concurrency",
+				"decision", d.action,
+				"reason", d.reason,
+				"concurrency", d.limit,
+				"previous", d.previous,
+				"error_rate", d.errorRate,
+				"median_ms", d.median.Milliseconds(),
+			)
+		}
+	}
+}
+
+// AdaptiveStats returns the -adaptive-concurrency summary of the last
+// ProcessURLs run, zero when adaptive concurrency was off.
+func (p *Prober) AdaptiveStats() AdaptiveStats {
+	a := p.adaptive.Load()
+	if a == nil {
+		return AdaptiveStats{}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
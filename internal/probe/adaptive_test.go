@@ -0,0 +1,219 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+// healthyWindow is a window of n answered probes of latency each.
+func healthyWindow(n int, latency time.Duration) adaptiveWindow {
+	w := adaptiveWindow{probes: n}
+	for range n {
+		w.latencies = append(w.latencies, latency)
+	}
+	return w
+}
+
+func TestAIMDController(t *testing.T) {
+	c := newAIMDController(5, 45) // Step of 4
+	failing := healthyWindow(18, 100*time.Millisecond)
+	failing.probes, failing.failures = 20, 2+1 // 15% timeouts
+
+	steps := []struct {
+		window adaptiveWindow
+		action string
+		reason string
+		limit  int
+	}{
+		{adaptiveWindow{probes: 4}, adaptiveHold, "too few probes", 5},
+		{healthyWindow(20, 100*time.Millisecond), adaptiveIncrease, "healthy", 9},
+		{healthyWindow(20, 120*time.Millisecond), adaptiveIncrease, "healthy", 13},
+		{healthyWindow(20, 150*time.Millisecond), adaptiveIncrease, "healthy", 17},
+		{failing, adaptiveDecrease, "error rate", 8},
+		{healthyWindow(20, 90*time.Millisecond), adaptiveIncrease, "healthy", 12},
+		// Median more than twice the best healthy median (90ms)
+		{healthyWindow(20, 200*time.Millisecond), adaptiveDecrease, "latency", 6},
+		{failing, adaptiveDecrease, "error rate", 5},
+		{failing, adaptiveHold, "error rate", 5}, // At the floor
+	}
+	for i, step := range steps {
+		d := c.decide(step.window)
+		if d.action != step.action || d.reason != step.reason || d.limit != step.limit {
+			t.Fatalf("step %d: %s (%s) to %d, want %s (%s) to %d", i, d.action, d.reason, d.limit, step.action, step.reason, step.limit)
+		}
+	}
+
+	for range 20 {
+		c.decide(healthyWindow(20, 90*time.Millisecond))
+	}
+	if c.limit != 45 {
+		t.Errorf("limit after healthy windows = %d, want the ceiling 45", c.limit)
+	}
+}
+
+func TestAIMDController_FloorAboveCeiling(t *testing.T) {
+	c := newAIMDController(10, 4)
+	if c.floor != 4 || c.limit != 4 || c.step != 1 {
+		t.Errorf("floor %d, limit %d, step %d; want 4, 4, 1", c.floor, c.limit, c.step)
+	}
+}
+
+func TestConcurrencyGate(t *testing.T) {
+	g := newConcurrencyGate(2)
+	ctx := context.Background()
+	if g.acquire(ctx) != nil || g.acquire(ctx) != nil {
+		t.Fatal("acquire within the limit failed")
+	}
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := g.acquire(short); err == nil {
+		t.Fatal("acquire beyond the limit succeeded")
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if g.acquire(ctx) == nil {
+			close(acquired)
+		}
+	}()
+	g.setLimit(3)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("raising the limit did not admit a waiting acquire")
+	}
+
+	// Lowering the limit holds back new acquires until enough slots are released
+	g.setLimit(1)
+	g.release()
+	g.release()
+	short2, cancel2 := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel2()
+	if err := g.acquire(short2); err == nil {
+		t.Fatal("acquire succeeded with all slots of the lowered limit in use")
+	}
+	g.release()
+	if err := g.acquire(ctx); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+func TestAdaptiveConcurrency_Adjust(t *testing.T) {
+	a := newAdaptiveConcurrency(2, 20)
+	for range 10 {
+		a.record(output.ProbeResult{StatusCode: 200}, 50*time.Millisecond)
+	}
+	// Neither refused connections nor cancellations count as failures
+	a.record(output.ProbeResult{Error: "refused", ErrorType: output.ErrorTypeConnectionRefused}, time.Millisecond)
+	a.record(output.ProbeResult{Error: "cancelled", ErrorType: output.ErrorTypeCancelled}, time.Millisecond)
+	if d := a.adjust(); d.action != adaptiveIncrease || d.limit != 3 {
+		t.Fatalf("decision %+v, want an increase to 3", d)
+	}
+
+	for range 5 {
+		a.record(output.ProbeResult{Error: "timeout", ErrorType: output.ErrorTypeTimeout}, 10*time.Second)
+	}
+	if d := a.adjust(); d.action != adaptiveDecrease || d.limit != 2 {
+		t.Fatalf("decision %+v, want a decrease to 2", d)
+	}
+	if d := a.adjust(); d.action != adaptiveHold {
+		t.Fatalf("empty window decided %+v", d)
+	}
+
+	want := AdaptiveStats{Floor: 2, Ceiling: 20, Current: 2, Min: 2, Max: 3, Increases: 1, Decreases: 1}
+	if a.stats != want {
+		t.Errorf("stats = %+v, want %+v", a.stats, want)
+	}
+	if a.gate.limit != 2 {
+		t.Errorf("gate limit = %d, want 2", a.gate.limit)
+	}
+}
+
+// Workers beyond the floor wait until the controller raises the limit.
+func TestProcessURLs_AdaptiveConcurrencyStartsAtFloor(t *testing.T) {
+	var running, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.AdaptiveConcurrency = true
+		cfg.AdaptiveFloor = 2
+	})
+	var urls []string
+	inputs := map[string]string{}
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/e", "/f", "/g", "/h"} {
+		urls = append(urls, server.URL+path)
+		inputs[server.URL+path] = server.URL
+	}
+	var n int
+	for range prober.ProcessURLs(context.Background(), urls, inputs, 8) {
+		n++
+	}
+	if n != len(urls) || peak.Load() > 2 {
+		t.Errorf("%d results, peak concurrency %d; want %d results at most 2 at once", n, peak.Load(), len(urls))
+	}
+	if stats := prober.AdaptiveStats(); stats.Floor != 2 || stats.Ceiling != 8 || stats.Current != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+// Concurrent runs on one Prober each gate and record with their own
+// controller; run with -race.
+func TestProcessURLs_AdaptiveConcurrencyConcurrentRuns(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.AdaptiveConcurrency = true
+		cfg.AdaptiveFloor = 1
+	})
+	var wg sync.WaitGroup
+	counts := make([]int, 2)
+	for run := range counts {
+		var urls []string
+		inputs := map[string]string{}
+		for i := range 6 {
+			u := fmt.Sprintf("%s/run%d/%d", server.URL, run, i)
+			urls = append(urls, u)
+			inputs[u] = server.URL
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range prober.ProcessURLs(context.Background(), urls, inputs, 4) {
+				counts[run]++
+			}
+		}()
+	}
+	wg.Wait()
+	for run, n := range counts {
+		if n != 6 {
+			t.Errorf("run %d: %d results, want 6", run, n)
+		}
+	}
+	if stats := prober.AdaptiveStats(); stats.Floor != 1 || stats.Ceiling != 4 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,56 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+// Each -disable-analysis feature empties its own fields and no others.
+func TestProbeURL_DisableAnalysis(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Security-Policy", "script-src 'self' https://cdn.example.net")
+		w.Write([]byte(`<html lang="en"><head><title>Page</title></head><body><form action="/login"><input type="password"></form> three words here</body></html>`))
+	}))
+	defer server.Close()
+
+	fields := map[config.AnalysisFeatures]func(r output.ProbeResult) bool{
+		config.AnalysisHash:        func(r output.ProbeResult) bool { return r.Hash.BodyMMH3 != "" },
+		config.AnalysisTitle:       func(r output.ProbeResult) bool { return r.Title != "" || r.TitleSource != "" || r.Lang != "" },
+		config.AnalysisWords:       func(r output.ProbeResult) bool { return r.Words != 0 || r.Lines != 0 },
+		config.AnalysisCSP:         func(r output.ProbeResult) bool { return r.CSP != nil || r.DiscoveredDomains != nil },
+		config.AnalysisHeadersHash: func(r output.ProbeResult) bool { return r.Hash.HeaderMMH3 != "" },
+	}
+	for _, name := range append(config.AnalysisFeatureNames(), "") {
+		t.Run("disable "+name, func(t *testing.T) {
+			disabled, err := config.ParseAnalysisFeatures(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prober := newHeaderProber(t, func(cfg *config.Config) {
+				cfg.CSP = true
+				cfg.DiscoverDomains = true
+				cfg.Forms = true
+				cfg.TitleFallback = true
+				cfg.DisabledAnalysis = disabled
+			})
+			result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+			if result.Error != "" {
+				t.Fatalf("unexpected error: %s", result.Error)
+			}
+			for feature, present := range fields {
+				if got, want := present(result), !disabled.Has(feature); got != want {
+					t.Errorf("fields of feature %d present = %v, want %v", feature, got, want)
+				}
+			}
+			if result.FormsCount != 1 || !result.LoginForm {
+				t.Errorf("forms_count = %d, login_form = %v; -forms is not an analysis feature", result.FormsCount, result.LoginForm)
+			}
+		})
+	}
+}
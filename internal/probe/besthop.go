@@ -0,0 +1,92 @@
+package probe
+
+import (
+	"net/http"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/parser"
+	"probeHTTP/internal/storage"
+)
+
+// bestHop returns the hop of statusChain with the lowest status class, 2xx
+// before 3xx, when the chain ends in a 4xx or 5xx and that hop's class is
+// better than the final one. Of several hops in that class the last wins:
+// only 3xx responses are followed, so the hops before an error are
+// redirects, and the last of them sent the client to the error.
+func bestHop(statusChain []int) (int, bool) {
+	last := len(statusChain) - 1
+	if last < 1 || statusChain[last] < 400 {
+		return 0, false
+	}
+	best := last
+	for i := last - 1; i >= 0; i-- {
+		if status := statusChain[i]; status >= 200 && status/100 < statusChain[best]/100 {
+			best = i
+		}
+	}
+	return best, best != last
+}
+
+// chainHopURL returns the URL requested at hop of the redirect chain
+// starting at resp, or "" when the chain is shorter.
+func chainHopURL(resp *http.Response, hop int) string {
+	rt := requestTraceFrom(resp.Request)
+	if rt == nil {
+		if hop == 0 {
+			return resp.Request.URL.String()
+		}
+		return ""
+	}
+	for i := 0; rt != nil; i++ {
+		rt.mu.Lock()
+		u, next := rt.url, rt.next
+		rt.mu.Unlock()
+		if i == hop {
+			return u.String()
+		}
+		rt = next
+	}
+	return ""
+}
+
+// setBestHop fills the best_* fields of a result whose chain ends in an
+// error. With -analyze-best-hop the hop's body is analyzed too: hop 0 is
+// initialBody, later hops the bodies followRedirects kept in entries.
+func (p *Prober) setBestHop(result *output.ProbeResult, resp *http.Response, initialBody []byte, entries []storage.ChainEntry) {
+	best, ok := bestHop(result.ChainStatusCodes)
+	if !ok {
+		return
+	}
+	result.BestStatusCode = result.ChainStatusCodes[best]
+	result.BestHopIndex = &best
+	result.BestHopURL = chainHopURL(resp, best)
+	if !p.config.AnalyzeBestHop {
+		return
+	}
+
+	body := initialBody
+	if best > 0 {
+		if best > len(entries) {
+			return
+		}
+		body = entries[best-1].Body
+	}
+	var contentType string
+	if best < len(result.ChainContentTypes) {
+		contentType = result.ChainContentTypes[best]
+	}
+	disabled := p.config.DisabledAnalysis
+	analysis := parser.AnalyzeBody(body, contentType, parser.AnalysisOptions{
+		NoHash:   disabled.Has(config.AnalysisHash),
+		NoMeta:   disabled.Has(config.AnalysisTitle),
+		NoCounts: true,
+	})
+	title, _ := parser.TruncateRunes(parser.SanitizeString(analysis.Meta.Title), p.config.MaxTitleLength)
+	result.BestHop = &output.BestHop{
+		Title:         title,
+		BodyMMH3:      analysis.MMH3,
+		ContentType:   contentType,
+		ContentLength: len(body),
+	}
+}
@@ -0,0 +1,86 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func TestBestHop(t *testing.T) {
+	tests := []struct {
+		chain []int
+		want  int
+		ok    bool
+	}{
+		{[]int{200, 302, 404}, 0, true},
+		{[]int{301, 302, 404}, 1, true}, // Last redirect before the error
+		{[]int{302, 503}, 0, true},
+		{[]int{301, 200}, 0, false},
+		{[]int{301, 302}, 0, false}, // Stopped at -max-redirects
+		{[]int{404}, 0, false},
+		{[]int{404, 404}, 0, false},
+		{nil, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := bestHop(tt.chain)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("bestHop(%v) = %d, %v; want %d, %v", tt.chain, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestProbeURL_BestHop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Location", "/app")
+			w.WriteHeader(http.StatusMovedPermanently)
+			fmt.Fprint(w, "<html><head><title>Moved</title></head></html>")
+		case "/app":
+			// Misconfigured redirect appending a bad path
+			w.Header().Set("Location", "/app/app/login")
+			w.WriteHeader(http.StatusFound)
+			fmt.Fprint(w, "<html><head><title>App Portal</title></head><body>redirecting</body></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, analyze := range []bool{false, true} {
+		prober := newHeaderProber(t, func(cfg *config.Config) {
+			cfg.FollowRedirects = true
+			cfg.AnalyzeBestHop = analyze
+		})
+		result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL+"/")
+		if result.Error != "" || result.StatusCode != http.StatusNotFound {
+			t.Fatalf("analyze=%v: status %d, error %q", analyze, result.StatusCode, result.Error)
+		}
+		if result.BestStatusCode != http.StatusFound || result.BestHopIndex == nil || *result.BestHopIndex != 1 ||
+			result.BestHopURL != server.URL+"/app" {
+			t.Errorf("analyze=%v: best status %d, index %v, url %q; want 302 at hop 1, %s/app",
+				analyze, result.BestStatusCode, result.BestHopIndex, result.BestHopURL, server.URL)
+		}
+		if !analyze {
+			if result.BestHop != nil {
+				t.Errorf("best_hop %+v without -analyze-best-hop", result.BestHop)
+			}
+			continue
+		}
+		if result.BestHop == nil || result.BestHop.Title != "App Portal" || result.BestHop.BodyMMH3 == "" ||
+			result.BestHop.ContentType != "text/html" {
+			t.Errorf("best_hop = %+v, want the /app page", result.BestHop)
+		}
+	}
+
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.FollowRedirects = true })
+	result := prober.ProbeURL(context.Background(), server.URL+"/missing", server.URL+"/missing")
+	if result.BestStatusCode != 0 || result.BestHopIndex != nil || result.BestHopURL != "" {
+		t.Errorf("single-hop 404 has a best hop: %d %v %q", result.BestStatusCode, result.BestHopIndex, result.BestHopURL)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -40,6 +41,42 @@ func ExtractCertificateChain(connState *tls.ConnectionState) []output.Certificat
 	return chain
 }
 
+// chainTLSStates returns the TLS connection state of each of the first hops
+// requests of the chain starting at resp's request, nil for plain-HTTP hops.
+// Responses without a trace (the raw HTTP/1.0 fallback) are a single hop.
+func chainTLSStates(resp *http.Response, hops int) []*tls.ConnectionState {
+	if resp == nil || hops <= 0 {
+		return nil
+	}
+	rt := requestTraceFrom(resp.Request)
+	if rt == nil {
+		return []*tls.ConnectionState{resp.TLS}
+	}
+
+	states := make([]*tls.ConnectionState, 0, hops)
+	for rt != nil && len(states) < hops {
+		rt.mu.Lock()
+		state, next := rt.tlsState, rt.next
+		rt.mu.Unlock()
+		states = append(states, state)
+		rt = next
+	}
+	return states
+}
+
+// chainCertificates returns the leaf certificate of each hop's connection
+// state, nil for hops without one.
+func chainCertificates(states []*tls.ConnectionState) []*output.CertificateInfo {
+	if len(states) == 0 {
+		return nil
+	}
+	certs := make([]*output.CertificateInfo, len(states))
+	for i, state := range states {
+		certs[i] = ExtractCertificateInfo(state)
+	}
+	return certs
+}
+
 // parseCertificate converts an x509.Certificate into a CertificateInfo struct.
 func parseCertificate(cert *x509.Certificate) *output.CertificateInfo {
 	info := &output.CertificateInfo{
@@ -1,10 +1,12 @@
 package probe
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/quic-go/quic-go/http3"
@@ -14,15 +16,25 @@ import (
 )
 
 // limiterEntry wraps a rate limiter with its last-access time for eviction.
+// It also holds the adaptive throttling state: the limiter's rate is lowered
+// on 429/503 responses and restored towards baseRate after clean responses.
 type limiterEntry struct {
 	limiter    *rate.Limiter
 	lastAccess time.Time
+	baseRate   rate.Limit // configured rate; adaptive recovery never exceeds it
+	cleanCount int        // clean responses since the last adjustment
 }
 
 // maxLimiters is the maximum number of per-host rate limiters kept in memory.
 // When exceeded, the least-recently-used entries are evicted.
 const maxLimiters = 10000
 
+// Adaptive throttling parameters
+const (
+	minAdaptiveRate       = rate.Limit(0.5) // floor when halving a host's rate (1 request per 2s)
+	adaptiveRecoveryCount = 20              // clean responses before the rate is doubled again
+)
+
 // Client wraps an HTTP client with rate limiting capabilities
 type Client struct {
 	httpClient     *http.Client
@@ -31,6 +43,60 @@ type Client struct {
 	config         *config.Config
 	http3Transport *http3.Transport // Track HTTP/3 transport for cleanup
 	ipTracker      *IPTracker
+	dns            *dnsCache   // shared DNS cache; nil with -no-dns-cache
+	conns          connTracker // connections opened through the client's dialers
+}
+
+// ConnStats reports the connections opened through a Client's dialers
+type ConnStats struct {
+	Open   int64 // Not yet closed, whether pooled idle or in use
+	Dialed int64 // Opened since the client was created
+}
+
+// connTracker counts connections so leaked sockets show up in
+// -health-interval reports.
+type connTracker struct {
+	open   atomic.Int64
+	dialed atomic.Int64
+}
+
+// wrap counts the connections dial opens until they are closed
+func (t *connTracker) wrap(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		t.opened()
+		return &trackedConn{Conn: conn, tracker: t}, nil
+	}
+}
+
+// trackQUIC counts a QUIC connection until its context ends with the connection
+func (t *connTracker) trackQUIC(conn *quic.Conn) {
+	t.opened()
+	context.AfterFunc(conn.Context(), func() { t.open.Add(-1) })
+}
+
+func (t *connTracker) opened() {
+	t.open.Add(1)
+	t.dialed.Add(1)
+}
+
+func (t *connTracker) stats() ConnStats {
+	return ConnStats{Open: t.open.Load(), Dialed: t.dialed.Load()}
+}
+
+// trackedConn decrements its tracker's open count on the first Close
+type trackedConn struct {
+	net.Conn
+	tracker *connTracker
+	once    sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { c.tracker.open.Add(-1) })
+	return c.Conn.Close()
 }
 
 // NewClient creates a new HTTP client with optimized settings
@@ -43,6 +109,7 @@ func NewClient(cfg *config.Config) *Client {
 		// Let Go choose cipher suites automatically for better compatibility
 		// This allows connections to servers that don't support the restricted set
 	}
+	applyClientAuth(tlsConfig, cfg)
 
 	// PERFORMANCE FIX: Configure connection pooling
 	transport := &http.Transport{
@@ -59,6 +126,7 @@ func NewClient(cfg *config.Config) *Client {
 		// Enable HTTP/2 support
 		ForceAttemptHTTP2: true,
 	}
+	applyProxy(transport, cfg)
 
 	httpClient := &http.Client{
 		Timeout:   time.Duration(cfg.Timeout) * time.Second,
@@ -69,11 +137,13 @@ func NewClient(cfg *config.Config) *Client {
 		},
 	}
 
-	return &Client{
+	c := &Client{
 		httpClient: httpClient,
 		limiters:   make(map[string]*limiterEntry),
 		config:     cfg,
 	}
+	c.updateDialContext()
+	return c
 }
 
 // GetHTTPClient returns the underlying HTTP client
@@ -87,10 +157,15 @@ func (c *Client) GetHTTPClient() *http.Client {
 func (c *Client) GetLimiter(host string) *rate.Limiter {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.limiterEntry(host).limiter
+}
 
+// limiterEntry returns the entry for host, creating it if needed.
+// Caller must hold c.mu.
+func (c *Client) limiterEntry(host string) *limiterEntry {
 	if entry, exists := c.limiters[host]; exists {
 		entry.lastAccess = time.Now()
-		return entry.limiter
+		return entry
 	}
 
 	// Evict oldest entries if at capacity
@@ -107,8 +182,61 @@ func (c *Client) GetLimiter(host string) *rate.Limiter {
 		burst = 1
 	}
 	limiter := rate.NewLimiter(rate.Limit(ratePerSec), burst)
-	c.limiters[host] = &limiterEntry{limiter: limiter, lastAccess: time.Now()}
-	return limiter
+	entry := &limiterEntry{limiter: limiter, lastAccess: time.Now(), baseRate: rate.Limit(ratePerSec)}
+	c.limiters[host] = entry
+	return entry
+}
+
+// ReportStatus feeds a response status for host into the adaptive rate
+// controller. A 429, or a 503 with Retry-After, halves the host's rate (down
+// to minAdaptiveRate); every adaptiveRecoveryCount clean responses double it
+// again up to the configured rate. It reports whether the host is throttled,
+// i.e. whether the response's timing was affected by a reduced rate.
+func (c *Client) ReportStatus(host string, status int, retryAfter bool) bool {
+	if c.config.DisableAdaptiveRate {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.limiterEntry(host)
+	current := entry.limiter.Limit()
+
+	if status == http.StatusTooManyRequests || (status == http.StatusServiceUnavailable && retryAfter) {
+		entry.cleanCount = 0
+		next := current / 2
+		if next < minAdaptiveRate {
+			next = minAdaptiveRate
+		}
+		if next < current {
+			entry.limiter.SetLimit(next)
+			c.logRateChange("rate limit reduced", host, status, current, next)
+		}
+		return true
+	}
+
+	if current >= entry.baseRate {
+		return false
+	}
+	entry.cleanCount++
+	if entry.cleanCount >= adaptiveRecoveryCount {
+		entry.cleanCount = 0
+		next := current * 2
+		if next > entry.baseRate {
+			next = entry.baseRate
+		}
+		entry.limiter.SetLimit(next)
+		c.logRateChange("rate limit restored", host, status, current, next)
+	}
+	return true
+}
+
+// logRateChange records an adaptive rate adjustment in the debug log.
+func (c *Client) logRateChange(msg, host string, status int, from, to rate.Limit) {
+	if c.config.DebugLogger != nil {
+		c.config.DebugLogger.Info(msg, "host", host, "status", status, "from_rps", float64(from), "to_rps", float64(to))
+	}
 }
 
 // evictOldest removes the 10% oldest entries from the limiter map.
@@ -151,14 +279,60 @@ func (c *Client) evictOldest() {
 // SetIPTracker sets the IP tracker for recording resolved IPs
 func (c *Client) SetIPTracker(tracker *IPTracker) {
 	c.ipTracker = tracker
-	// Update the default transport's DialContext
+	c.updateDialContext()
+}
+
+// SetDNSCache makes the client resolve hostnames through cache
+func (c *Client) SetDNSCache(cache *dnsCache) {
+	c.dns = cache
+	c.updateDialContext()
+}
+
+// updateDialContext rebuilds the default transport's DialContext
+func (c *Client) updateDialContext() {
 	if transport, ok := c.httpClient.Transport.(*http.Transport); ok {
 		dialer := &net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
 		}
-		transport.DialContext = tracker.DialContext(dialer)
+		transport.DialContext = c.dialContext(dialer)
+	}
+}
+
+// dialContext wraps dialer with the client's DNS cache, IP tracker,
+// -vhost-list pinning and connection counter, so every transport of a prober shares lookups, records
+// connected IPs and is counted.
+func (c *Client) dialContext(dialer *net.Dialer) dialFunc {
+	dial := dialFunc(dialer.DialContext)
+	if c.dns != nil {
+		dial = c.dns.dialContext(dial)
+	}
+	if c.ipTracker != nil {
+		dial = c.ipTracker.wrap(dial)
 	}
+	// Outside the tracker, which records IPs by the hostname dialed
+	dial = pinnedDial(dial)
+	return c.conns.wrap(dial)
+}
+
+// dialQUIC is an http3.Transport Dial function resolving through the DNS
+// cache when enabled, pinning and counting connections like dialContext.
+func (c *Client) dialQUIC(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+	dial := quic.DialAddrEarly
+	if c.dns != nil {
+		dial = c.dns.dialQUIC
+	}
+	conn, err := dial(ctx, pinnedAddr(ctx, addr), tlsCfg, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.conns.trackQUIC(conn)
+	return conn, nil
+}
+
+// ConnStats returns the open and total dialed connection counts
+func (c *Client) ConnStats() ConnStats {
+	return c.conns.stats()
 }
 
 // Note: Rate limiting is done directly in prober.go using limiter.Wait(ctx)
@@ -178,6 +352,7 @@ func NewClientWithTLSConfig(cfg *config.Config, tlsConfig *tls.Config) *Client {
 		DisableCompression:    false,
 		ForceAttemptHTTP2:     true,
 	}
+	applyProxy(transport, cfg)
 
 	httpClient := &http.Client{
 		Timeout:   time.Duration(cfg.Timeout) * time.Second,
@@ -240,6 +415,7 @@ func NewHTTP2Client(cfg *config.Config, tlsConfig *tls.Config) *http.Client {
 		DisableCompression:    false,
 		ForceAttemptHTTP2:     true, // Force HTTP/2
 	}
+	applyProxy(transport, cfg)
 
 	httpClient := &http.Client{
 		Timeout:   time.Duration(cfg.Timeout) * time.Second,
@@ -266,6 +442,7 @@ func NewHTTP11Client(cfg *config.Config, tlsConfig *tls.Config) *http.Client {
 		DisableCompression:    false,
 		ForceAttemptHTTP2:     false, // Disable HTTP/2, use HTTP/1.1 only
 	}
+	applyProxy(transport, cfg)
 
 	httpClient := &http.Client{
 		Timeout:   time.Duration(cfg.Timeout) * time.Second,
@@ -1,8 +1,15 @@
 package probe
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"probeHTTP/internal/config"
 )
@@ -104,3 +111,152 @@ func TestGetLimiter_EvictionWhenOverCapacity(t *testing.T) {
 		t.Error("GetLimiter should work after eviction")
 	}
 }
+
+func TestReportStatus_HalvesAndRestoresRate(t *testing.T) {
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.RateLimitPerHost = 8
+	client := NewClient(cfg)
+	lim := client.GetLimiter("example.com")
+
+	if client.ReportStatus("example.com", 200, false) {
+		t.Error("clean response on an unthrottled host should not be throttled")
+	}
+	if !client.ReportStatus("example.com", 429, false) {
+		t.Error("429 should mark the host throttled")
+	}
+	if lim.Limit() != 4 {
+		t.Errorf("rate after 429 = %v, want 4", lim.Limit())
+	}
+	client.ReportStatus("example.com", 503, true)
+	if lim.Limit() != 2 {
+		t.Errorf("rate after 503 with Retry-After = %v, want 2", lim.Limit())
+	}
+	client.ReportStatus("example.com", 503, false)
+	if lim.Limit() != 2 {
+		t.Errorf("503 without Retry-After should not change the rate, got %v", lim.Limit())
+	}
+
+	for i := 0; i < adaptiveRecoveryCount; i++ {
+		if !client.ReportStatus("example.com", 200, false) {
+			t.Fatal("responses while the rate is reduced should be marked throttled")
+		}
+	}
+	if lim.Limit() != 4 {
+		t.Errorf("rate after recovery = %v, want 4", lim.Limit())
+	}
+	for i := 0; i < adaptiveRecoveryCount; i++ {
+		client.ReportStatus("example.com", 200, false)
+	}
+	if lim.Limit() != 8 {
+		t.Errorf("rate should be restored to the configured 8, got %v", lim.Limit())
+	}
+	if client.ReportStatus("example.com", 200, false) {
+		t.Error("host should no longer be throttled after full recovery")
+	}
+}
+
+func TestReportStatus_FloorsAtMinimum(t *testing.T) {
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.RateLimitPerHost = 1
+	client := NewClient(cfg)
+
+	for i := 0; i < 5; i++ {
+		client.ReportStatus("example.com", 429, false)
+	}
+	if got := client.GetLimiter("example.com").Limit(); got != minAdaptiveRate {
+		t.Errorf("rate = %v, want floor %v", got, minAdaptiveRate)
+	}
+}
+
+func TestReportStatus_Disabled(t *testing.T) {
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.DisableAdaptiveRate = true
+	client := NewClient(cfg)
+
+	if client.ReportStatus("example.com", 429, false) {
+		t.Error("throttling should be off with DisableAdaptiveRate")
+	}
+	if got := client.GetLimiter("example.com").Limit(); got != 10 {
+		t.Errorf("rate = %v, want unchanged 10", got)
+	}
+}
+
+func TestConnTracker_CountsUntilClose(t *testing.T) {
+	var tracker connTracker
+	dial := tracker.wrap(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	})
+
+	first, _ := dial(context.Background(), "tcp", "a:80")
+	second, _ := dial(context.Background(), "tcp", "b:80")
+	if got := tracker.stats(); got.Open != 2 || got.Dialed != 2 {
+		t.Fatalf("after two dials: %+v, want 2 open, 2 dialed", got)
+	}
+
+	first.Close()
+	first.Close() // a second Close must not count twice
+	if got := tracker.stats(); got.Open != 1 || got.Dialed != 2 {
+		t.Errorf("after one close: %+v, want 1 open, 2 dialed", got)
+	}
+	second.Close()
+	if got := tracker.stats(); got.Open != 0 {
+		t.Errorf("after closing all: %+v, want 0 open", got)
+	}
+}
+
+func TestConnTracker_FailedDialNotCounted(t *testing.T) {
+	var tracker connTracker
+	dial := tracker.wrap(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, fmt.Errorf("refused")
+	})
+	if _, err := dial(context.Background(), "tcp", "a:80"); err == nil {
+		t.Fatal("expected dial error")
+	}
+	if got := tracker.stats(); got.Open != 0 || got.Dialed != 0 {
+		t.Errorf("stats = %+v, want nothing counted", got)
+	}
+}
+
+func TestProber_ConnStatsAroundProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	if got := prober.HealthStats(); got.OpenConns != 0 || got.InFlight != 0 {
+		t.Fatalf("before probing: %+v, want no connections or probes", got)
+	}
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	stats := prober.HealthStats()
+	if stats.OpenConns != 1 || stats.DialedConns != 1 {
+		t.Errorf("after probe: %+v, want the pooled connection open", stats)
+	}
+	if stats.InFlight != 0 {
+		t.Errorf("in flight after probe = %d, want 0", stats.InFlight)
+	}
+
+	prober.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for prober.HealthStats().OpenConns != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := prober.HealthStats(); got.OpenConns != 0 || got.DialedConns != 1 {
+		t.Errorf("after Close: %+v, want 0 open, 1 dialed", got)
+	}
+}
@@ -0,0 +1,135 @@
+package probe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+)
+
+// newClientCertPair creates a CA and a client certificate signed by it.
+func newClientCertPair(t *testing.T) (*x509.CertPool, tls.Certificate) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test client CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "probehttp client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTmpl, caCert, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return pool, tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}
+}
+
+func newMTLSServer(t *testing.T, clientCAs *x509.CertPool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("<title>" + r.TLS.PeerCertificates[0].Subject.CommonName + "</title>"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: clientCAs}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClientCertProber(t *testing.T, cert *tls.Certificate, hosts []string) *Prober {
+	t.Helper()
+	return newTestProber(t, func(cfg *config.Config) {
+		cfg.InsecureSkipVerify = true
+		cfg.DisableHTTP3 = true
+		cfg.ClientCertificate = cert
+		cfg.ClientCertHostList = hosts
+	})
+}
+
+func TestProbeURL_ClientCertificate(t *testing.T) {
+	pool, cert := newClientCertPair(t)
+	server := newMTLSServer(t, pool)
+
+	prober := newClientCertProber(t, &cert, nil)
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if result.StatusCode != http.StatusOK || result.Title != "probehttp client" {
+		t.Errorf("server did not see the client cert: status=%d title=%q", result.StatusCode, result.Title)
+	}
+	if !result.ClientCertUsed {
+		t.Error("ClientCertUsed should be true when the handshake presented the cert")
+	}
+}
+
+func TestProbeURL_ClientCertificateHostRestriction(t *testing.T) {
+	pool, cert := newClientCertPair(t)
+	server := newMTLSServer(t, pool)
+
+	prober := newClientCertProber(t, &cert, []string{"*.corp.example"})
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.StatusCode != http.StatusUnauthorized {
+		t.Errorf("cert should not be presented to a non-matching host, status=%d error=%q", result.StatusCode, result.Error)
+	}
+	if result.ClientCertUsed {
+		t.Error("ClientCertUsed should be false for a non-matching host")
+	}
+
+	prober = newClientCertProber(t, &cert, []string{"127.0.0.*"})
+	result = prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.StatusCode != http.StatusOK || !result.ClientCertUsed {
+		t.Errorf("cert should be presented to a matching host, status=%d used=%v", result.StatusCode, result.ClientCertUsed)
+	}
+}
+
+func TestProbeURL_NoClientCertificateConfigured(t *testing.T) {
+	pool, _ := newClientCertPair(t)
+	server := newMTLSServer(t, pool)
+
+	prober := newClientCertProber(t, nil, nil)
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.StatusCode != http.StatusUnauthorized || result.ClientCertUsed {
+		t.Errorf("status=%d used=%v, want 401 without client cert", result.StatusCode, result.ClientCertUsed)
+	}
+}
@@ -0,0 +1,79 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"probeHTTP/internal/output"
+)
+
+// probeClock records when a probe's network phase began and when its
+// final body was read, across retries, TLS attempts and redirect hops.
+type probeClock struct {
+	mu        sync.Mutex
+	created   time.Time // ProbeURL entry, used when no request was sent
+	started   time.Time // first request of the first attempt
+	completed time.Time // final body read of the latest attempt
+}
+
+type probeClockKey struct{}
+
+// withProbeClock attaches a per-probe probeClock to ctx.
+func withProbeClock(ctx context.Context) (context.Context, *probeClock) {
+	clock := &probeClock{created: time.Now()}
+	return context.WithValue(ctx, probeClockKey{}, clock), clock
+}
+
+// markRequestStart records t as the start of the probe unless an earlier
+// attempt already sent a request.
+func markRequestStart(ctx context.Context, t time.Time) {
+	if clock, ok := ctx.Value(probeClockKey{}).(*probeClock); ok {
+		clock.mu.Lock()
+		if clock.started.IsZero() {
+			clock.started = t
+		}
+		clock.mu.Unlock()
+	}
+}
+
+// markBodyRead records t as the end of the probe's network phase; the
+// latest attempt wins.
+func markBodyRead(ctx context.Context, t time.Time) {
+	if clock, ok := ctx.Value(probeClockKey{}).(*probeClock); ok {
+		clock.mu.Lock()
+		clock.completed = t
+		clock.mu.Unlock()
+	}
+}
+
+// probeStartedAt returns when the probe of ctx sent its first request, or
+// the zero time.
+func probeStartedAt(ctx context.Context) time.Time {
+	clock, ok := ctx.Value(probeClockKey{}).(*probeClock)
+	if !ok {
+		return time.Time{}
+	}
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.started
+}
+
+// stamp sets the result's started_at, completed_at and timestamp. A failed
+// probe completes when it gave up, not at the body read of an earlier
+// attempt.
+func (c *probeClock) stamp(result *output.ProbeResult, now time.Time) {
+	c.mu.Lock()
+	started, completed := c.started, c.completed
+	c.mu.Unlock()
+
+	if started.IsZero() {
+		started = c.created
+	}
+	if completed.IsZero() || result.Error != "" || completed.Before(started) {
+		completed = now
+	}
+	result.StartedAt = started.Format(time.RFC3339Nano)
+	result.CompletedAt = completed.Format(time.RFC3339Nano)
+	result.Timestamp = started.Format(time.RFC3339)
+}
@@ -0,0 +1,145 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+// probeWindow parses a result's started_at and completed_at.
+func probeWindow(t *testing.T, result output.ProbeResult) (time.Time, time.Time) {
+	t.Helper()
+	started, err := time.Parse(time.RFC3339Nano, result.StartedAt)
+	if err != nil {
+		t.Fatalf("started_at %q: %v", result.StartedAt, err)
+	}
+	completed, err := time.Parse(time.RFC3339Nano, result.CompletedAt)
+	if err != nil {
+		t.Fatalf("completed_at %q: %v", result.CompletedAt, err)
+	}
+	if completed.Before(started) {
+		t.Fatalf("completed_at %s is before started_at %s", result.CompletedAt, result.StartedAt)
+	}
+	if want := started.Format(time.RFC3339); result.Timestamp != want {
+		t.Errorf("Timestamp = %q, want started_at to the second (%q)", result.Timestamp, want)
+	}
+	return started, completed
+}
+
+func TestProbeURL_StartedCompletedMatchDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("slow"))
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {})
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+
+	started, completed := probeWindow(t, result)
+	elapsed, err := time.ParseDuration(result.Time)
+	if err != nil {
+		t.Fatalf("time %q: %v", result.Time, err)
+	}
+	window := completed.Sub(started)
+	if window < 100*time.Millisecond {
+		t.Errorf("completed_at - started_at = %s, want at least the 100ms response delay", window)
+	}
+	if diff := elapsed - window; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+		t.Errorf("completed_at - started_at = %s, time = %s; want them within 50ms", window, elapsed)
+	}
+}
+
+func TestProbeURL_StartedCompletedSpanRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.FollowRedirects = true
+	})
+	result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if result.Error != "" || len(result.ChainStatusCodes) != 2 {
+		t.Fatalf("error = %q, chain = %v; want a two-hop chain", result.Error, result.ChainStatusCodes)
+	}
+
+	started, completed := probeWindow(t, result)
+	if window := completed.Sub(started); window < 100*time.Millisecond {
+		t.Errorf("completed_at - started_at = %s, want both hops (at least 100ms)", window)
+	}
+}
+
+func TestProbeURL_StartedAtFromFirstAttempt(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.MaxRetries = 1
+	})
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.StatusCode != http.StatusOK || len(result.Attempts) != 2 {
+		t.Fatalf("status = %d, attempts = %d; want 200 after a retry", result.StatusCode, len(result.Attempts))
+	}
+
+	// The retry waits a 1s backoff after the first attempt
+	started, completed := probeWindow(t, result)
+	if window := completed.Sub(started); window < time.Second {
+		t.Errorf("completed_at - started_at = %s, want the span from the first attempt (over 1s)", window)
+	}
+}
+
+func TestProbeClock_Stamp(t *testing.T) {
+	base := time.Date(2024, 11, 26, 7, 58, 48, 123456789, time.UTC)
+	now := base.Add(3 * time.Second)
+
+	tests := []struct {
+		name          string
+		clock         *probeClock
+		err           string
+		wantStarted   time.Time
+		wantCompleted time.Time
+	}{
+		{"success", &probeClock{created: base, started: base.Add(time.Millisecond), completed: base.Add(2 * time.Second)}, "", base.Add(time.Millisecond), base.Add(2 * time.Second)},
+		{"failed after a body read", &probeClock{created: base, started: base, completed: base.Add(time.Second)}, "Request failed: timeout", base, now},
+		{"no request sent", &probeClock{created: base}, "rate limit wait timeout after 60s", base, now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := output.ProbeResult{Error: tt.err}
+			tt.clock.stamp(&result, now)
+			if result.StartedAt != tt.wantStarted.Format(time.RFC3339Nano) {
+				t.Errorf("started_at = %q, want %q", result.StartedAt, tt.wantStarted.Format(time.RFC3339Nano))
+			}
+			if result.CompletedAt != tt.wantCompleted.Format(time.RFC3339Nano) {
+				t.Errorf("completed_at = %q, want %q", result.CompletedAt, tt.wantCompleted.Format(time.RFC3339Nano))
+			}
+			if result.Timestamp != tt.wantStarted.Format(time.RFC3339) {
+				t.Errorf("timestamp = %q, want %q", result.Timestamp, tt.wantStarted.Format(time.RFC3339))
+			}
+		})
+	}
+}
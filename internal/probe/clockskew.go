@@ -0,0 +1,30 @@
+package probe
+
+import (
+	"net/http"
+	"time"
+)
+
+// clockSkew compares the response Date header with the local time at which
+// the first response byte arrived. The skew is signed: positive means the
+// server clock is ahead of ours, negative means it is behind. Date has
+// one-second resolution, so skews under a second are noise.
+// ok is false when Date is missing or unparseable.
+func clockSkew(resp *http.Response) (serverDate time.Time, skewMs int64, ok bool) {
+	dateHeader := resp.Header.Get("Date")
+	if dateHeader == "" {
+		return time.Time{}, 0, false
+	}
+	serverDate, err := http.ParseTime(dateHeader)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+
+	received := time.Now()
+	if rt := requestTraceFrom(resp.Request); rt != nil {
+		if t := rt.firstByteTime(); !t.IsZero() {
+			received = t
+		}
+	}
+	return serverDate, serverDate.Sub(received).Milliseconds(), true
+}
@@ -0,0 +1,87 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+)
+
+func TestProbeURL_ClockSkew(t *testing.T) {
+	past := time.Now().Add(-1 * time.Hour).UTC().Format(http.TimeFormat)
+	future := time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/past":
+			w.Header().Set("Date", past)
+		case "/future":
+			w.Header().Set("Date", future)
+		case "/missing":
+			// Suppress the Date header net/http would add
+			w.Header()["Date"] = nil
+		case "/garbage":
+			w.Header().Set("Date", "yesterday-ish")
+		case "/redirect":
+			w.Header().Set("Date", past)
+			http.Redirect(w, r, "/future", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.AllowPrivateIPs = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	// Date has one-second resolution; allow a couple of seconds of slack
+	const slackMs = 2000
+	tests := []struct {
+		path     string
+		wantSkew int64 // approximate; ignored when wantNone
+		wantNone bool
+	}{
+		{"/past", -time.Hour.Milliseconds(), false},
+		{"/future", 24 * time.Hour.Milliseconds(), false},
+		{"/redirect", 24 * time.Hour.Milliseconds(), false}, // final hop wins
+		{"/missing", 0, true},
+		{"/garbage", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := prober.ProbeURL(context.Background(), server.URL+tt.path, server.URL)
+			if result.Error != "" {
+				t.Fatalf("ProbeURL error: %s", result.Error)
+			}
+			if tt.wantNone {
+				if result.ClockSkewMs != nil {
+					t.Errorf("ClockSkewMs = %d, want unset", *result.ClockSkewMs)
+				}
+				if result.ServerDate != "" {
+					t.Errorf("ServerDate = %q, want empty", result.ServerDate)
+				}
+				return
+			}
+			if result.ClockSkewMs == nil {
+				t.Fatal("ClockSkewMs not set")
+			}
+			if diff := *result.ClockSkewMs - tt.wantSkew; diff < -slackMs || diff > slackMs {
+				t.Errorf("ClockSkewMs = %d, want about %d (positive = server ahead)", *result.ClockSkewMs, tt.wantSkew)
+			}
+			if _, err := time.Parse(time.RFC3339, result.ServerDate); err != nil {
+				t.Errorf("ServerDate %q is not RFC3339: %v", result.ServerDate, err)
+			}
+		})
+	}
+}
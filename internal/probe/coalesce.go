@@ -0,0 +1,70 @@
+package probe
+
+import (
+	"context"
+	"sync"
+
+	"probeHTTP/internal/output"
+)
+
+// coalesceGroup tracks the in-flight probes of -coalesce by normalized URL.
+// The zero value is ready to use.
+type coalesceGroup struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedProbe
+}
+
+// coalescedProbe is one in-flight probe; result and ok are set before done
+// closes and only read afterwards. ok is false when the probe panicked.
+type coalescedProbe struct {
+	done   chan struct{}
+	result output.ProbeResult
+	ok     bool
+}
+
+// probeCoalesced probes probeURL unless a probe of the same normalized URL
+// is already in flight, in which case it waits for that probe and returns a
+// copy of its result with originalInput and coalesced set. A probe cut short
+// by the first caller's context (-first-alive, shutdown) or by a panic is
+// not shared: waiters whose own context is still live probe for themselves.
+func (p *Prober) probeCoalesced(ctx context.Context, probeURL string, originalInput string) output.ProbeResult {
+	key := p.normalizeURL(probeURL)
+
+	p.coalesce.mu.Lock()
+	if call, ok := p.coalesce.calls[key]; ok {
+		p.coalesce.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return p.cancelledResult(probeURL, originalInput)
+		}
+		if !call.ok || call.result.ErrorType == output.ErrorTypeCancelled && ctx.Err() == nil {
+			return p.probeURLWithRetries(ctx, probeURL, originalInput)
+		}
+		result := call.result.Clone()
+		result.Input = originalInput
+		result.Coalesced = true
+		return result
+	}
+	call := &coalescedProbe{done: make(chan struct{})}
+	if p.coalesce.calls == nil {
+		p.coalesce.calls = make(map[string]*coalescedProbe)
+	}
+	p.coalesce.calls[key] = call
+	p.coalesce.mu.Unlock()
+
+	// Released even if the probe panics, so waiters never block on it
+	defer func() {
+		p.coalesce.mu.Lock()
+		delete(p.coalesce.calls, key)
+		p.coalesce.mu.Unlock()
+		close(call.done)
+	}()
+
+	result := p.probeURLWithRetries(ctx, probeURL, originalInput)
+
+	// Waiters copy from their own clone; the caller is free to modify result
+	call.result = result.Clone()
+	call.ok = true
+	return result
+}
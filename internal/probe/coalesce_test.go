@@ -0,0 +1,140 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+// blockingServer counts requests and holds each one until release is
+// closed; arrived receives a value per request.
+func blockingServer(t *testing.T) (server *httptest.Server, requests *atomic.Int32, arrived chan struct{}, release chan struct{}) {
+	t.Helper()
+	requests = new(atomic.Int32)
+	arrived = make(chan struct{}, 16)
+	release = make(chan struct{})
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		arrived <- struct{}{}
+		<-release
+		w.Write([]byte("<title>shared</title>"))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests, arrived, release
+}
+
+func TestProbeURL_CoalesceSharesInFlightProbe(t *testing.T) {
+	server, requests, arrived, release := blockingServer(t)
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.Coalesce = true })
+
+	const callers = 8
+	results := make([]output.ProbeResult, callers)
+	var wg sync.WaitGroup
+	probe := func(i int, target string) {
+		defer wg.Done()
+		results[i] = prober.ProbeURL(context.Background(), target, fmt.Sprintf("input-%d", i))
+	}
+
+	wg.Add(1)
+	go probe(0, server.URL+"/")
+	<-arrived
+	for i := 1; i < callers; i++ {
+		wg.Add(1)
+		// Same URL after normalization with or without the trailing slash
+		target := server.URL + "/"
+		if i%2 == 1 {
+			target = server.URL
+		}
+		go probe(i, target)
+	}
+	time.Sleep(100 * time.Millisecond) // let the duplicates join the probe in flight
+	close(release)
+	wg.Wait()
+
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("server saw %d requests, want 1", n)
+	}
+	coalesced := 0
+	for i, result := range results {
+		if result.Error != "" || result.Title != "shared" {
+			t.Errorf("result %d: error %q, title %q", i, result.Error, result.Title)
+		}
+		if result.Input != fmt.Sprintf("input-%d", i) {
+			t.Errorf("result %d: input = %q, want its own input", i, result.Input)
+		}
+		if result.Coalesced {
+			coalesced++
+		}
+	}
+	if results[0].Coalesced || coalesced != callers-1 {
+		t.Errorf("coalesced on %d results (first: %v), want all but the first", coalesced, results[0].Coalesced)
+	}
+
+	// Downstream edits of one copy must not show up in another
+	results[1].ChainHosts[0] = "edited"
+	for i, result := range results {
+		if i != 1 && result.ChainHosts[0] == "edited" {
+			t.Errorf("result %d shares chain_hosts with result 1", i)
+		}
+	}
+}
+
+func TestProbeURL_CoalesceOnlyWhileInFlight(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.Coalesce = true })
+	first := prober.ProbeURL(context.Background(), server.URL, "a")
+	second := prober.ProbeURL(context.Background(), server.URL, "b")
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("server saw %d requests, want one per sequential probe", n)
+	}
+	if first.Coalesced || second.Coalesced {
+		t.Error("sequential probes marked coalesced")
+	}
+}
+
+func TestProbeURL_CoalesceWaiterOutlivesCancelledFirst(t *testing.T) {
+	server, requests, arrived, release := blockingServer(t)
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.Coalesce = true })
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	var first, waiter output.ProbeResult
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		first = prober.ProbeURL(firstCtx, server.URL, "first")
+	}()
+	<-arrived
+	go func() {
+		defer wg.Done()
+		waiter = prober.ProbeURL(context.Background(), server.URL, "waiter")
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancelFirst()
+	close(release)
+	wg.Wait()
+
+	if first.ErrorType != output.ErrorTypeCancelled {
+		t.Errorf("first: error type %q, want cancelled", first.ErrorType)
+	}
+	if waiter.Error != "" || waiter.Coalesced || waiter.Input != "waiter" {
+		t.Errorf("waiter: error %q, coalesced %v, input %q; want its own successful probe", waiter.Error, waiter.Coalesced, waiter.Input)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("server saw %d requests, want the waiter's own request after the cancellation", n)
+	}
+}
@@ -0,0 +1,101 @@
+package probe
+
+import (
+	"net/http"
+	"strings"
+)
+
+// compression_assessment verdicts of -compression-info.
+const (
+	compressionOK       = "ok"
+	compressionWasteful = "wasteful" // Content-Encoding on an already compressed media type
+	compressionMissing  = "missing"  // Large text response sent without Content-Encoding
+)
+
+// compressedMediaTypes are media types whose content is compressed already;
+// encoding them again costs server CPU for next to no saving. Prefixes end
+// in '/'.
+var compressedMediaTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/vnd.rar",
+	"application/zstd",
+}
+
+// compressibleMediaTypes are non-text/* media types that compress well.
+var compressibleMediaTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+}
+
+// responseContentEncoding returns the lowercased Content-Encoding resp was
+// sent with, "" for none. doRequest removes the gzip coding it decodes and
+// marks the response Uncompressed.
+func responseContentEncoding(resp *http.Response) string {
+	if resp.Uncompressed {
+		return "gzip"
+	}
+	ce := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if ce == "identity" {
+		return ""
+	}
+	return ce
+}
+
+// assessCompression returns the compression_assessment of a response sent
+// with contentEncoding and a decoded body of size bytes. Text types, and
+// image/svg+xml among them, are checked first: "missing" when at least
+// minSize bytes (and at least one) came unencoded although the request offered gzip.
+// "wasteful" is an encoded already compressed type; anything else is "ok".
+func assessCompression(contentType, contentEncoding string, size, minSize int64) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	switch {
+	case isCompressibleMediaType(mediaType):
+		if contentEncoding == "" && size > 0 && size >= minSize {
+			return compressionMissing
+		}
+	case contentEncoding != "" && isCompressedMediaType(mediaType):
+		return compressionWasteful
+	}
+	return compressionOK
+}
+
+func isCompressibleMediaType(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	for _, t := range compressibleMediaTypes {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func isCompressedMediaType(mediaType string) bool {
+	for _, t := range compressedMediaTypes {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// uncompressedSize is the size of an unencoded body: Content-Length when
+// the server sent one, since the body may have been cut at the read limit,
+// else the bytes read.
+func uncompressedSize(resp *http.Response, read int) int64 {
+	return max(resp.ContentLength, int64(read))
+}
@@ -0,0 +1,120 @@
+package probe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func TestAssessCompression(t *testing.T) {
+	tests := []struct {
+		contentType, encoding string
+		size                  int64
+		want                  string
+	}{
+		{"image/jpeg", "gzip", 1000, compressionWasteful},
+		{"video/mp4", "br", 1000, compressionWasteful},
+		{"application/zip", "gzip", 1000, compressionWasteful},
+		{"image/jpeg", "", 1 << 20, compressionOK},
+		{"text/html; charset=utf-8", "", 60 * 1024, compressionMissing},
+		{"application/json", "", 50 * 1024, compressionMissing},
+		{"application/problem+json", "", 50 * 1024, compressionMissing},
+		{"image/svg+xml", "", 50 * 1024, compressionMissing}, // Text, not an image format
+		{"image/svg+xml", "gzip", 50 * 1024, compressionOK},
+		{"text/html", "gzip", 1 << 20, compressionOK},
+		{"text/html", "", 50*1024 - 1, compressionOK},
+		{"application/octet-stream", "", 1 << 20, compressionOK},
+		{"", "gzip", 1000, compressionOK},
+	}
+	for _, tt := range tests {
+		if got := assessCompression(tt.contentType, tt.encoding, tt.size, 50*1024); got != tt.want {
+			t.Errorf("assessCompression(%q, %q, %d) = %q, want %q", tt.contentType, tt.encoding, tt.size, got, tt.want)
+		}
+	}
+	if got := assessCompression("text/plain", "", 0, 0); got != compressionOK {
+		t.Errorf("empty body with -compression-min-size 0 = %q, want ok", got)
+	}
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProbeURL_CompressionInfo(t *testing.T) {
+	jpeg := append([]byte{0xff, 0xd8, 0xff, 0xe0}, bytes.Repeat([]byte{0x42}, 4096)...)
+	largeHTML := "<html><head><title>Big</title></head><body>" + strings.Repeat("<p>lorem ipsum</p>", 4096) + "</body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipBytes(t, jpeg))
+		case "/clip.mp4":
+			// A coding probeHTTP does not decode
+			w.Header().Set("Content-Type", "video/mp4")
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte("not really brotli"))
+		case "/large":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(largeHTML))
+		case "/large-gzip":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipBytes(t, []byte(largeHTML)))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>Small</title></head></html>"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path, encoding, assessment string
+	}{
+		{"/photo.jpg", "gzip", compressionWasteful},
+		{"/clip.mp4", "br", compressionWasteful},
+		{"/large", "", compressionMissing},
+		{"/large-gzip", "gzip", compressionOK},
+		{"/small", "", compressionOK},
+	}
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.CompressionInfo = true })
+	for _, tt := range tests {
+		result := prober.ProbeURL(context.Background(), server.URL+tt.path, server.URL+tt.path)
+		if result.Error != "" {
+			t.Fatalf("%s: %s", tt.path, result.Error)
+		}
+		if result.ContentEncoding != tt.encoding || result.CompressionAssessment != tt.assessment {
+			t.Errorf("%s: content_encoding %q, compression_assessment %q; want %q, %q",
+				tt.path, result.ContentEncoding, result.CompressionAssessment, tt.encoding, tt.assessment)
+		}
+	}
+
+	// The threshold is configurable
+	prober = newHeaderProber(t, func(cfg *config.Config) {
+		cfg.CompressionInfo = true
+		cfg.CompressionMinSize = 1 << 20
+	})
+	if result := prober.ProbeURL(context.Background(), server.URL+"/large", server.URL+"/large"); result.CompressionAssessment != compressionOK {
+		t.Errorf("/large under a 1m threshold: %q, want ok", result.CompressionAssessment)
+	}
+
+	prober = newHeaderProber(t, func(*config.Config) {})
+	if result := prober.ProbeURL(context.Background(), server.URL+"/large", server.URL+"/large"); result.ContentEncoding != "" || result.CompressionAssessment != "" {
+		t.Errorf("compression fields without -compression-info: %q, %q", result.ContentEncoding, result.CompressionAssessment)
+	}
+}
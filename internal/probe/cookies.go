@@ -0,0 +1,75 @@
+package probe
+
+import (
+	"net/http"
+	"strings"
+
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/parser"
+)
+
+// chainCookies returns the cookies set by every hop starting at resp's
+// request, in chain order. Responses without a trace (the raw HTTP/1.0
+// fallback) are treated as a single hop.
+func chainCookies(resp *http.Response) []output.Cookie {
+	if resp == nil {
+		return nil
+	}
+	rt := requestTraceFrom(resp.Request)
+	if rt == nil {
+		return parseSetCookies(resp.Header.Values("Set-Cookie"), 0)
+	}
+
+	var cookies []output.Cookie
+	for hop := 0; rt != nil; hop++ {
+		rt.mu.Lock()
+		setCookies, next := rt.setCookies, rt.next
+		rt.mu.Unlock()
+		cookies = append(cookies, parseSetCookies(setCookies, hop)...)
+		rt = next
+	}
+	return cookies
+}
+
+// parseSetCookies parses one hop's Set-Cookie headers with net/http's parser.
+// SameSite is read from the raw header instead, since net/http collapses
+// unknown values into SameSiteDefaultMode.
+func parseSetCookies(values []string, hop int) []output.Cookie {
+	if len(values) == 0 {
+		return nil
+	}
+	parsed := (&http.Response{Header: http.Header{"Set-Cookie": values}}).Cookies()
+	cookies := make([]output.Cookie, 0, len(parsed))
+	for _, c := range parsed {
+		cookies = append(cookies, output.Cookie{
+			Name:     c.Name,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+			SameSite: rawSameSite(c.Raw),
+			Hop:      hop,
+		})
+	}
+	return cookies
+}
+
+// rawSameSite returns the SameSite attribute of a raw Set-Cookie line.
+// Known values are canonicalized (Lax, Strict, None); others are returned as sent.
+func rawSameSite(raw string) string {
+	attrs := strings.Split(raw, ";")
+	for _, attr := range attrs[1:] {
+		key, value, _ := strings.Cut(attr, "=")
+		if !strings.EqualFold(strings.TrimSpace(key), "samesite") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		for _, known := range []string{"Lax", "Strict", "None"} {
+			if strings.EqualFold(value, known) {
+				return known
+			}
+		}
+		return parser.SanitizeString(value)
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+func TestParseSetCookies(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   output.Cookie
+	}{
+		{
+			name:   "no attributes",
+			header: "sid=abc",
+			want:   output.Cookie{Name: "sid"},
+		},
+		{
+			name:   "all attributes",
+			header: "sid=abc; Domain=example.com; Path=/app; Secure; HttpOnly; SameSite=strict",
+			want:   output.Cookie{Name: "sid", Domain: "example.com", Path: "/app", Secure: true, HTTPOnly: true, SameSite: "Strict"},
+		},
+		{
+			name:   "samesite none without secure",
+			header: "track=1; SameSite=None",
+			want:   output.Cookie{Name: "track", SameSite: "None"},
+		},
+		{
+			name:   "unknown samesite kept as sent",
+			header: "pref=x; samesite=Relaxed; HttpOnly",
+			want:   output.Cookie{Name: "pref", HTTPOnly: true, SameSite: "Relaxed"},
+		},
+		{
+			name:   "samesite without value",
+			header: "pref=x; SameSite",
+			want:   output.Cookie{Name: "pref"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSetCookies([]string{tt.header}, 2)
+			tt.want.Hop = 2
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Errorf("parseSetCookies(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+
+	if got := parseSetCookies([]string{"=novalue", "bad name=1"}, 0); len(got) != 0 {
+		t.Errorf("invalid cookies should be dropped, got %+v", got)
+	}
+}
+
+func TestProbeURL_CookieInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Add("Set-Cookie", "sid=first")
+			http.Redirect(w, r, "/final", http.StatusFound)
+			return
+		}
+		w.Header().Add("Set-Cookie", "sid=second; Path=/; Secure; HttpOnly; SameSite=Lax")
+		w.Header().Add("Set-Cookie", "track=1; SameSite=None")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.AllowPrivateIPs = true
+	cfg.CookieInfo = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	want := []output.Cookie{
+		{Name: "sid", Hop: 0},
+		{Name: "sid", Path: "/", Secure: true, HTTPOnly: true, SameSite: "Lax", Hop: 1},
+		{Name: "track", SameSite: "None", Hop: 1},
+	}
+	if !reflect.DeepEqual(result.Cookies, want) {
+		t.Errorf("Cookies = %+v, want %+v", result.Cookies, want)
+	}
+
+	cfg.CookieInfo = false
+	if plain := prober.ProbeURL(context.Background(), server.URL, server.URL); plain.Cookies != nil {
+		t.Errorf("Cookies = %+v, want none without -cookie-info", plain.Cookies)
+	}
+}
@@ -0,0 +1,67 @@
+package probe
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"probeHTTP/internal/parser"
+)
+
+// redactedValue replaces credential header values in raw requests, debug
+// output and request_headers.
+const redactedValue = "[REDACTED]"
+
+// urlCredentials is a -use-url-credentials Basic Authorization, sent only
+// on requests to the origin of the URL it was given for.
+type urlCredentials struct {
+	origin        string
+	authorization string
+}
+
+// SetURLCredentials sets the userinfo of inputs run with -use-url-credentials,
+// keyed by expanded probe URL. It must be called before ProcessURLs.
+func (p *Prober) SetURLCredentials(creds map[string]*url.Userinfo) {
+	p.urlCredentials = creds
+}
+
+// credentialsFor returns the Basic Authorization for probeURL, or nil.
+func (p *Prober) credentialsFor(probeURL string) *urlCredentials {
+	user, ok := p.urlCredentials[probeURL]
+	if !ok || user == nil {
+		return nil
+	}
+	u, err := url.Parse(probeURL)
+	if err != nil {
+		return nil
+	}
+	password, _ := user.Password()
+	return &urlCredentials{
+		origin:        parser.Origin(u, p.defaultPorts()),
+		authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)),
+	}
+}
+
+// setAuthorization sets the probe's Authorization on req when it goes to the
+// credentials' origin and removes it otherwise, so redirects elsewhere never
+// carry it.
+func (p *Prober) setAuthorization(req *http.Request) {
+	req.Header.Del("Authorization")
+	headers, ok := req.Context().Value(probeHeadersKey{}).(*probeHeaders)
+	if !ok || headers.credentials == nil {
+		return
+	}
+	// Request URLs without a port go to the standard one, unlike inputs
+	if parser.Origin(req.URL, parser.StandardPorts) == headers.credentials.origin {
+		req.Header.Set("Authorization", headers.credentials.authorization)
+	}
+}
+
+// redactHeaderValue hides the value of credential headers.
+func redactHeaderValue(name, value string) string {
+	if strings.EqualFold(name, "Authorization") || strings.EqualFold(name, "Proxy-Authorization") {
+		return redactedValue
+	}
+	return value
+}
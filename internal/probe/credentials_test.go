@@ -0,0 +1,100 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func TestProbeURL_URLCredentialsOnlyForTarget(t *testing.T) {
+	var mu sync.Mutex
+	auth := make(map[string]string) // server -> Authorization seen
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth["other"] = r.Header.Get("Authorization")
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer other.Close()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth[r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/again", http.StatusFound)
+		default:
+			http.Redirect(w, r, other.URL+"/", http.StatusFound)
+		}
+	}))
+	defer target.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.AllowPrivateIPs = true
+	cfg.IncludeResponse = true
+	cfg.IncludeResponseHeader = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	probeURL := target.URL + "/"
+	prober.SetURLCredentials(map[string]*url.Userinfo{probeURL: url.UserPassword("alice", "s3cret")})
+
+	result := prober.ProbeURL(context.Background(), probeURL, target.URL)
+	if result.Error != "" {
+		t.Fatalf("probe error: %s", result.Error)
+	}
+
+	const want = "Basic YWxpY2U6czNjcmV0" // alice:s3cret
+	mu.Lock()
+	defer mu.Unlock()
+	if auth["/"] != want || auth["/again"] != want {
+		t.Errorf("target saw Authorization %q and %q, want %q on both same-origin hops", auth["/"], auth["/again"], want)
+	}
+	if got, ok := auth["other"]; !ok || got != "" {
+		t.Errorf("other origin saw Authorization %q (requested: %v), want none", got, ok)
+	}
+
+	if got := result.RequestHeaders["authorization"]; got != "" && got != redactedValue {
+		t.Errorf("request_headers authorization = %q, want it redacted", got)
+	}
+	if strings.Contains(result.RawRequest, "YWxpY2U6czNjcmV0") || strings.Contains(result.RawRequest, "s3cret") {
+		t.Errorf("raw_request leaks credentials:\n%s", result.RawRequest)
+	}
+	if !strings.Contains(result.RawRequest, "Authorization: "+redactedValue) {
+		t.Errorf("raw_request = %q, want a redacted Authorization header", result.RawRequest)
+	}
+}
+
+func TestProbeURL_NoCredentialsWithoutSetURLCredentials(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.AllowPrivateIPs = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	prober.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if got != "" {
+		t.Errorf("Authorization = %q, want none", got)
+	}
+}
@@ -3,7 +3,10 @@ package probe
 import (
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+
+	"probeHTTP/internal/output"
 )
 
 // cspDirectives lists CSP directives that can contain domain sources.
@@ -46,49 +49,120 @@ var cspKeywords = map[string]bool{
 	"*":                   true,
 }
 
-// ExtractCSPDomains parses the Content-Security-Policy header and extracts
-// domain names from all directives that can contain source lists.
-func ExtractCSPDomains(headers http.Header) []string {
-	csp := headers.Get("Content-Security-Policy")
-	if csp == "" {
-		return nil
-	}
-
-	seen := make(map[string]bool)
-	var domains []string
+// CSP response headers. Report-only policies are not enforced but list the
+// same kind of sources.
+const (
+	cspHeader           = "Content-Security-Policy"
+	cspReportOnlyHeader = "Content-Security-Policy-Report-Only"
+)
 
-	// CSP directives are separated by semicolons
-	directives := strings.Split(csp, ";")
-	for _, directive := range directives {
-		directive = strings.TrimSpace(directive)
-		if directive == "" {
-			continue
+// cspPolicies returns the policies of every value of the named header. A
+// single value may carry several comma-separated policies.
+func cspPolicies(headers http.Header, name string) []string {
+	var policies []string
+	for _, value := range headers.Values(name) {
+		for _, policy := range strings.Split(value, ",") {
+			if policy = strings.TrimSpace(policy); policy != "" {
+				policies = append(policies, policy)
+			}
 		}
+	}
+	return policies
+}
 
+// forEachCSPDirective calls fn with the lowercased name and the source list
+// of each directive of policy, in order. Directives are separated by
+// semicolons; a directive without sources gets an empty list.
+func forEachCSPDirective(policy string, fn func(name string, sources []string)) {
+	for _, directive := range strings.Split(policy, ";") {
 		parts := strings.Fields(directive)
-		if len(parts) < 2 {
+		if len(parts) == 0 {
 			continue
 		}
+		fn(strings.ToLower(parts[0]), parts[1:])
+	}
+}
 
-		directiveName := strings.ToLower(parts[0])
-		if !isRelevantDirective(directiveName) {
-			continue
-		}
+// ExtractCSPDomains extracts domain names from the source lists of all
+// Content-Security-Policy and Content-Security-Policy-Report-Only policies,
+// in order of first appearance and without duplicates.
+func ExtractCSPDomains(headers http.Header) []string {
+	seen := make(map[string]bool)
+	var domains []string
 
-		// Extract domains from source values (skip directive name)
-		for _, source := range parts[1:] {
-			source = strings.TrimSpace(source)
-			domain := extractDomainFromCSPSource(source)
-			if domain != "" && !seen[domain] {
-				seen[domain] = true
-				domains = append(domains, domain)
+	policies := append(cspPolicies(headers, cspHeader), cspPolicies(headers, cspReportOnlyHeader)...)
+	for _, policy := range policies {
+		forEachCSPDirective(policy, func(name string, sources []string) {
+			if !isRelevantDirective(name) {
+				return
 			}
-		}
+			for _, source := range sources {
+				domain := extractDomainFromCSPSource(source)
+				if domain != "" && !seen[domain] {
+					seen[domain] = true
+					domains = append(domains, domain)
+				}
+			}
+		})
 	}
 
 	return domains
 }
 
+// AnalyzeCSP summarizes the CSP headers of a response for -csp, or returns
+// nil when it has neither header. The unsafe-inline/unsafe-eval flags
+// describe enforced policies: script-src, or default-src when a policy has
+// no script-src. Directives and domains come from both headers.
+func AnalyzeCSP(headers http.Header) *output.CSPInfo {
+	enforced := cspPolicies(headers, cspHeader)
+	reportOnly := cspPolicies(headers, cspReportOnlyHeader)
+	if len(enforced) == 0 && len(reportOnly) == 0 {
+		return nil
+	}
+
+	info := &output.CSPInfo{
+		Present:           len(enforced) > 0,
+		ReportOnlyPresent: len(reportOnly) > 0,
+		Domains:           ExtractCSPDomains(headers),
+	}
+	seen := make(map[string]bool)
+	for _, policy := range append(enforced, reportOnly...) {
+		forEachCSPDirective(policy, func(name string, sources []string) {
+			if !seen[name] {
+				seen[name] = true
+				info.Directives = append(info.Directives, name)
+			}
+		})
+	}
+	sort.Strings(info.Directives)
+
+	for _, policy := range enforced {
+		// The first occurrence of a directive wins within a policy
+		var scriptSrc, defaultSrc []string
+		var hasScript, hasDefault bool
+		forEachCSPDirective(policy, func(name string, sources []string) {
+			switch {
+			case name == "script-src" && !hasScript:
+				scriptSrc, hasScript = sources, true
+			case name == "default-src" && !hasDefault:
+				defaultSrc, hasDefault = sources, true
+			}
+		})
+		if !hasScript {
+			scriptSrc = defaultSrc
+		}
+		for _, source := range scriptSrc {
+			switch strings.ToLower(source) {
+			case "'unsafe-inline'":
+				info.ScriptUnsafeInline = true
+			case "'unsafe-eval'":
+				info.ScriptUnsafeEval = true
+			}
+		}
+	}
+	return info
+}
+
 // isRelevantDirective checks if a CSP directive name can contain domain sources.
 func isRelevantDirective(name string) bool {
 	for _, d := range cspDirectives {
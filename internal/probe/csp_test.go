@@ -1,9 +1,14 @@
 package probe
 
 import (
+	"context"
 	"net/http"
+	"net/http/httptest"
+	"slices"
 	"sort"
 	"testing"
+
+	"probeHTTP/internal/config"
 )
 
 func TestExtractCSPDomains_EmptyHeader(t *testing.T) {
@@ -105,6 +110,152 @@ func TestExtractCSPDomains_IrrelevantDirectives(t *testing.T) {
 	}
 }
 
+func TestExtractCSPDomains_MultipleHeadersAndPolicies(t *testing.T) {
+	headers := http.Header{}
+	headers.Add("Content-Security-Policy", "script-src a.example.com, img-src b.example.com")
+	headers.Add("Content-Security-Policy", "script-src a.example.com c.example.com")
+	headers.Add("Content-Security-Policy-Report-Only", "connect-src d.example.com b.example.com")
+
+	got := ExtractCSPDomains(headers)
+	want := []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ExtractCSPDomains = %v, want %v", got, want)
+	}
+}
+
+func TestExtractCSPDomains_ReportOnly(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Security-Policy-Report-Only", "default-src 'self' report.example.com")
+
+	got := ExtractCSPDomains(headers)
+	if !slices.Equal(got, []string{"report.example.com"}) {
+		t.Errorf("ExtractCSPDomains = %v, want [report.example.com]", got)
+	}
+}
+
+func TestAnalyzeCSP(t *testing.T) {
+	tests := []struct {
+		name       string
+		enforced   []string
+		reportOnly []string
+		wantNil    bool
+		wantInline bool
+		wantEval   bool
+		wantDirs   []string
+	}{
+		{name: "no headers", wantNil: true},
+		{name: "empty header", enforced: []string{" "}, wantNil: true},
+		{
+			name:     "strict script-src",
+			enforced: []string{"default-src 'self'; script-src 'self' cdn.example.com"},
+			wantDirs: []string{"default-src", "script-src"},
+		},
+		{
+			name:       "unsafe-inline in script-src",
+			enforced:   []string{"script-src 'self' 'UNSAFE-INLINE'"},
+			wantInline: true,
+			wantDirs:   []string{"script-src"},
+		},
+		{
+			name:     "default-src fallback",
+			enforced: []string{"default-src 'unsafe-eval'; upgrade-insecure-requests"},
+			wantEval: true,
+			wantDirs: []string{"default-src", "upgrade-insecure-requests"},
+		},
+		{
+			name:     "script-src overrides default-src",
+			enforced: []string{"default-src 'unsafe-inline'; script-src 'self'"},
+			wantDirs: []string{"default-src", "script-src"},
+		},
+		{
+			name:       "first script-src wins",
+			enforced:   []string{"script-src 'unsafe-inline'; script-src 'self'"},
+			wantInline: true,
+			wantDirs:   []string{"script-src"},
+		},
+		{
+			name:     "second header and comma-joined policy",
+			enforced: []string{"img-src 'self'", "frame-ancestors 'none', script-src 'unsafe-eval'"},
+			wantEval: true,
+			wantDirs: []string{"frame-ancestors", "img-src", "script-src"},
+		},
+		{
+			name:       "report-only is not enforced",
+			reportOnly: []string{"script-src 'unsafe-inline' 'unsafe-eval'"},
+			wantDirs:   []string{"script-src"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			for _, v := range tt.enforced {
+				headers.Add("Content-Security-Policy", v)
+			}
+			for _, v := range tt.reportOnly {
+				headers.Add("Content-Security-Policy-Report-Only", v)
+			}
+
+			info := AnalyzeCSP(headers)
+			if tt.wantNil {
+				if info != nil {
+					t.Fatalf("AnalyzeCSP = %+v, want nil", info)
+				}
+				return
+			}
+			if info == nil {
+				t.Fatal("AnalyzeCSP = nil")
+			}
+			if info.Present != (len(tt.enforced) > 0) || info.ReportOnlyPresent != (len(tt.reportOnly) > 0) {
+				t.Errorf("present = %v, report_only_present = %v", info.Present, info.ReportOnlyPresent)
+			}
+			if info.ScriptUnsafeInline != tt.wantInline || info.ScriptUnsafeEval != tt.wantEval {
+				t.Errorf("script_unsafe_inline = %v, script_unsafe_eval = %v; want %v, %v",
+					info.ScriptUnsafeInline, info.ScriptUnsafeEval, tt.wantInline, tt.wantEval)
+			}
+			if !slices.Equal(info.Directives, tt.wantDirs) {
+				t.Errorf("directives = %v, want %v", info.Directives, tt.wantDirs)
+			}
+		})
+	}
+}
+
+func TestProbeURL_CSP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/none" {
+			return
+		}
+		w.Header().Add("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline' cdn.example.com")
+		w.Header().Add("Content-Security-Policy-Report-Only", "script-src report.example.com")
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.CSP = true })
+	result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if result.Error != "" {
+		t.Fatalf("probe failed: %s", result.Error)
+	}
+	csp := result.CSP
+	if csp == nil {
+		t.Fatal("csp not reported")
+	}
+	if !csp.Present || !csp.ReportOnlyPresent || !csp.ScriptUnsafeInline || csp.ScriptUnsafeEval {
+		t.Errorf("csp = %+v", csp)
+	}
+	if want := []string{"cdn.example.com", "report.example.com"}; !slices.Equal(csp.Domains, want) {
+		t.Errorf("csp.domains = %v, want %v", csp.Domains, want)
+	}
+
+	if result := prober.ProbeURL(context.Background(), server.URL+"/none", server.URL); result.CSP != nil {
+		t.Errorf("csp = %+v for a response without CSP headers", result.CSP)
+	}
+
+	prober = newHeaderProber(t, func(*config.Config) {})
+	if result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL); result.CSP != nil {
+		t.Errorf("csp reported without -csp: %+v", result.CSP)
+	}
+}
+
 func TestStripPort(t *testing.T) {
 	tests := []struct {
 		input, want string
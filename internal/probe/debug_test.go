@@ -0,0 +1,139 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+)
+
+func TestProbeURL_DebugOnError_TLSFailure(t *testing.T) {
+	// A server demanding a client certificate fails every TLS strategy
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.DebugOnError = true
+		cfg.DisableHTTP3 = true
+		cfg.InsecureSkipVerify = true
+	})
+	target := server.URL + "/login"
+	result := prober.ProbeURL(context.Background(), target, server.URL)
+
+	if !strings.Contains(result.Error, "All TLS attempts failed") {
+		t.Fatalf("Error = %q, want every TLS attempt to fail", result.Error)
+	}
+	for _, want := range []string{"[1] REQUEST: GET " + target, "ERROR: Request failed:", "tls: "} {
+		if !strings.Contains(result.Debug, want) {
+			t.Errorf("debug does not contain %q:\n%s", want, result.Debug)
+		}
+	}
+	// One transcript per failed attempt
+	if attempts := strings.Count(result.Debug, "[1] REQUEST:"); attempts < 2 {
+		t.Errorf("debug holds %d attempt transcripts, want all of them:\n%s", attempts, result.Debug)
+	}
+}
+
+func TestProbeURL_DebugOnError_Truncated(t *testing.T) {
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.DebugOnError = true
+		cfg.MaxDebugSize = 64
+	})
+	result := prober.ProbeURL(context.Background(), "http://127.0.0.1:1/", "127.0.0.1:1")
+
+	if result.Error == "" {
+		t.Fatal("expected a connection error")
+	}
+	if !strings.HasSuffix(result.Debug, "more bytes truncated)\n") || len(result.Debug) > 128 {
+		t.Errorf("debug not truncated at 64 bytes:\n%s", result.Debug)
+	}
+}
+
+func TestProbeURL_DebugOnError_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.DebugOnError = true
+		cfg.Timeout = 1
+	})
+	result := prober.ProbeURL(context.Background(), server.URL+"/slow", server.URL)
+
+	if result.Error == "" {
+		t.Fatalf("expected a timeout, got status %d", result.StatusCode)
+	}
+	for _, want := range []string{"[1] REQUEST: GET " + server.URL + "/slow", "ERROR: Request failed:", "Timeout"} {
+		if !strings.Contains(result.Debug, want) {
+			t.Errorf("debug does not contain %q:\n%s", want, result.Debug)
+		}
+	}
+}
+
+func TestProbeURL_DebugOnError_SuccessClean(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.DebugOnError = true
+	})
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if result.Debug != "" {
+		t.Errorf("successful result carries debug output:\n%s", result.Debug)
+	}
+}
+
+func TestProbeURL_DebugOffByDefault(t *testing.T) {
+	prober := newHeaderProber(t, func(cfg *config.Config) {})
+	result := prober.ProbeURL(context.Background(), "http://127.0.0.1:1/", "127.0.0.1:1")
+
+	if result.Error == "" {
+		t.Fatal("expected a connection error")
+	}
+	if result.Debug != "" {
+		t.Errorf("debug field set without -debug-on-error:\n%s", result.Debug)
+	}
+}
+
+func TestTruncateDebug(t *testing.T) {
+	tests := []struct {
+		transcript string
+		max        int64
+		want       string
+	}{
+		{"short", 16, "short"},
+		{"exactly", 7, "exactly"},
+		{"unlimited transcript", 0, "unlimited transcript"},
+		{"0123456789", 4, "0123\n... (6 more bytes truncated)\n"},
+		{"ab\u00e9cd", 3, "ab\n... (4 more bytes truncated)\n"}, // no split inside the two-byte rune
+	}
+	for _, tt := range tests {
+		if got := truncateDebug(tt.transcript, tt.max); got != tt.want {
+			t.Errorf("truncateDebug(%q, %d) = %q, want %q", tt.transcript, tt.max, got, tt.want)
+		}
+	}
+}
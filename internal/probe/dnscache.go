@@ -0,0 +1,230 @@
+package probe
+
+import (
+	"container/list"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"golang.org/x/sync/singleflight"
+)
+
+// dialFunc matches http.Transport.DialContext.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// maxDNSCacheEntries bounds the DNS cache; the least recently used hostnames
+// are evicted first.
+const maxDNSCacheEntries = 10000
+
+// dnsNegativeTTL is how long a "no such host" answer is cached, so a dead
+// domain fails fast across its port expansion without pinning it for long.
+const dnsNegativeTTL = 10 * time.Second
+
+// DNSCacheStats reports how often the DNS cache answered lookups.
+type DNSCacheStats struct {
+	Hits   int64 `json:"dns_cache_hits"`
+	Misses int64 `json:"dns_cache_misses"`
+}
+
+// dnsCacheEntry is one cached answer. err is set for negative entries.
+type dnsCacheEntry struct {
+	host    string
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// dnsCache caches A/AAAA answers per hostname for all dialers of a Prober.
+// The standard resolver does not expose record TTLs, so entries live for a
+// fixed TTL (-dns-cache-ttl). Concurrent misses for the same hostname share
+// one lookup.
+type dnsCache struct {
+	lookup     func(ctx context.Context, host string) ([]net.IPAddr, error)
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // host -> element holding *dnsCacheEntry
+	lru     *list.List               // front = most recently used
+	flight  singleflight.Group
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// newDNSCache creates a cache resolving through lookup.
+func newDNSCache(lookup func(ctx context.Context, host string) ([]net.IPAddr, error), ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		lookup:     lookup,
+		ttl:        ttl,
+		maxEntries: maxDNSCacheEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// lookupIPAddr returns the addresses of host, from the cache when possible.
+func (c *dnsCache) lookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if entry, ok := c.get(host); ok {
+		c.hits.Add(1)
+		return entry.addrs, entry.err
+	}
+	c.misses.Add(1)
+
+	// The shared lookup must not die with the first caller's context
+	v, err, _ := c.flight.Do(host, func() (interface{}, error) {
+		addrs, err := c.lookup(context.WithoutCancel(ctx), host)
+		c.put(host, addrs, err)
+		return addrs, err
+	})
+	addrs, _ := v.([]net.IPAddr)
+	return addrs, err
+}
+
+// LookupHost resolves host to address strings, like net.Resolver.LookupHost.
+func (c *dnsCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{host}, nil
+	}
+	addrs, err := c.lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		hosts[i] = addr.String()
+	}
+	return hosts, nil
+}
+
+// get returns the unexpired entry for host and marks it recently used.
+func (c *dnsCache) get(host string) (*dnsCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[host]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*dnsCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, host)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+// put caches a lookup result. Only "no such host" failures are cached;
+// timeouts and other transient errors are retried on the next lookup.
+func (c *dnsCache) put(host string, addrs []net.IPAddr, err error) {
+	ttl := c.ttl
+	if err != nil {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			return
+		}
+		ttl = min(ttl, dnsNegativeTTL)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &dnsCacheEntry{host: host, addrs: addrs, err: err, expires: c.now().Add(ttl)}
+	if elem, ok := c.entries[host]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[host] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*dnsCacheEntry).host)
+	}
+}
+
+// stats returns the hit and miss counters.
+func (c *dnsCache) stats() DNSCacheStats {
+	return DNSCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// resolveAddr returns host:port candidates for addr with the hostname
+// replaced by its cached addresses, filtered by network family.
+func (c *dnsCache) resolveAddr(ctx context.Context, network, addr string) ([]string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return []string{addr}, nil
+	}
+	ips, err := c.lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []string
+	for _, ip := range ips {
+		is4 := ip.IP.To4() != nil
+		if (network == "tcp4" || network == "udp4") && !is4 || (network == "tcp6" || network == "udp6") && is4 {
+			continue
+		}
+		candidates = append(candidates, net.JoinHostPort(ip.String(), port))
+	}
+	if len(candidates) == 0 {
+		return nil, &net.DNSError{Err: "no suitable address found", Name: host, IsNotFound: true}
+	}
+	return candidates, nil
+}
+
+// dialContext wraps dial so hostnames are resolved through the cache. The
+// resolved addresses are tried in order until one connects.
+func (c *dnsCache) dialContext(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		candidates, err := c.resolveAddr(ctx, network, addr)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+		}
+		var firstErr error
+		for _, candidate := range candidates {
+			conn, err := dial(ctx, network, candidate)
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, firstErr
+	}
+}
+
+// dialQUIC is an http3.Transport Dial function resolving through the cache.
+// The TLS ServerName is already set by the transport, so dialing an IP keeps SNI.
+func (c *dnsCache) dialQUIC(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+	candidates, err := c.resolveAddr(ctx, "udp", addr)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: "udp", Err: err}
+	}
+	var firstErr error
+	for _, candidate := range candidates {
+		conn, err := quic.DialAddrEarly(ctx, candidate, tlsCfg, cfg)
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,167 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeResolver answers every hostname with 127.0.0.1 except those in missing
+type fakeResolver struct {
+	calls   atomic.Int64
+	missing map[string]bool
+	fail    error
+}
+
+func (f *fakeResolver) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	f.calls.Add(1)
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	if f.missing[host] {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+}
+
+func TestDNSCache_CachesAnswers(t *testing.T) {
+	resolver := &fakeResolver{}
+	cache := newDNSCache(resolver.lookup, time.Minute)
+
+	for i := 0; i < 5; i++ {
+		addrs, err := cache.LookupHost(context.Background(), "example.com")
+		if err != nil || len(addrs) != 1 || addrs[0] != "127.0.0.1" {
+			t.Fatalf("LookupHost = %v, %v", addrs, err)
+		}
+	}
+	if calls := resolver.calls.Load(); calls != 1 {
+		t.Errorf("resolver called %d times, want 1", calls)
+	}
+	if stats := cache.stats(); stats.Hits != 4 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want 4 hits, 1 miss", stats)
+	}
+
+	// IP literals never hit the resolver
+	if _, err := cache.LookupHost(context.Background(), "10.0.0.1"); err != nil || resolver.calls.Load() != 1 {
+		t.Errorf("IP literal should not be resolved (err=%v, calls=%d)", err, resolver.calls.Load())
+	}
+}
+
+func TestDNSCache_Expiry(t *testing.T) {
+	resolver := &fakeResolver{missing: map[string]bool{"dead.example": true}}
+	cache := newDNSCache(resolver.lookup, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.lookupIPAddr(context.Background(), "example.com")
+	if _, err := cache.lookupIPAddr(context.Background(), "dead.example"); err == nil {
+		t.Fatal("expected error for missing host")
+	}
+
+	// Negative answers expire first
+	now = now.Add(dnsNegativeTTL + time.Second)
+	cache.lookupIPAddr(context.Background(), "example.com")
+	cache.lookupIPAddr(context.Background(), "dead.example")
+	if calls := resolver.calls.Load(); calls != 3 {
+		t.Errorf("resolver called %d times, want 3 (negative entry expired)", calls)
+	}
+
+	now = now.Add(time.Minute)
+	cache.lookupIPAddr(context.Background(), "example.com")
+	if calls := resolver.calls.Load(); calls != 4 {
+		t.Errorf("resolver called %d times, want 4 (positive entry expired)", calls)
+	}
+}
+
+func TestDNSCache_TransientErrorsNotCached(t *testing.T) {
+	resolver := &fakeResolver{fail: &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}}
+	cache := newDNSCache(resolver.lookup, time.Minute)
+
+	cache.lookupIPAddr(context.Background(), "example.com")
+	cache.lookupIPAddr(context.Background(), "example.com")
+	if calls := resolver.calls.Load(); calls != 2 {
+		t.Errorf("resolver called %d times, want 2", calls)
+	}
+}
+
+func TestDNSCache_LRUEviction(t *testing.T) {
+	resolver := &fakeResolver{}
+	cache := newDNSCache(resolver.lookup, time.Minute)
+	cache.maxEntries = 2
+
+	cache.lookupIPAddr(context.Background(), "a.example")
+	cache.lookupIPAddr(context.Background(), "b.example")
+	cache.lookupIPAddr(context.Background(), "a.example") // a is now most recent
+	cache.lookupIPAddr(context.Background(), "c.example") // evicts b
+
+	if _, ok := cache.get("b.example"); ok {
+		t.Error("b.example should have been evicted")
+	}
+	if _, ok := cache.get("a.example"); !ok {
+		t.Error("a.example should still be cached")
+	}
+	if len(cache.entries) != 2 || cache.lru.Len() != 2 {
+		t.Errorf("cache holds %d/%d entries, want 2", len(cache.entries), cache.lru.Len())
+	}
+}
+
+func TestDNSCache_ConcurrentMissesShareLookup(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int64
+	cache := newDNSCache(func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		calls.Add(1)
+		<-release
+		return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+	}, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.lookupIPAddr(context.Background(), "example.com")
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("resolver called %d times, want 1", n)
+	}
+}
+
+func TestDNSCache_DialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+	resolver := &fakeResolver{missing: map[string]bool{"dead.example": true}}
+	cache := newDNSCache(resolver.lookup, time.Minute)
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	client := &http.Client{Transport: &http.Transport{DialContext: cache.dialContext(dialer.DialContext)}}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get("http://cached.example:" + port + "/")
+		if err != nil {
+			t.Fatalf("request through cache failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if calls := resolver.calls.Load(); calls != 1 {
+		t.Errorf("resolver called %d times, want 1", calls)
+	}
+
+	_, err := client.Get("http://dead.example:" + port + "/")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound || !strings.Contains(err.Error(), "no such host") {
+		t.Errorf("expected a no such host error, got %v", err)
+	}
+}
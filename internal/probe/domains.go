@@ -2,6 +2,7 @@ package probe
 
 import (
 	"crypto/tls"
+	"fmt"
 	"net/http"
 	"sort"
 	"strings"
@@ -13,19 +14,34 @@ import (
 // CSP headers, deduplicates them, and identifies domains not matching the
 // input hostname.
 func DiscoverDomains(connState *tls.ConnectionState, headers http.Header, inputHost string) *output.DiscoveredDomains {
+	return DiscoverChainDomains([]*tls.ConnectionState{connState}, headers, inputHost)
+}
+
+// DiscoverChainDomains is DiscoverDomains over the connection states of every
+// hop of a redirect chain (-xtls-per-hop). Names first seen on a later hop
+// are attributed to it, e.g. "san:hop2" for the second hop's certificate.
+func DiscoverChainDomains(connStates []*tls.ConnectionState, headers http.Header, inputHost string) *output.DiscoveredDomains {
 	sources := make(map[string]string) // domain -> source
 	seen := make(map[string]bool)
 
 	// Extract from certificate SANs
-	if connState != nil && len(connState.PeerCertificates) > 0 {
+	for i, connState := range connStates {
+		if connState == nil || len(connState.PeerCertificates) == 0 {
+			continue
+		}
 		cert := connState.PeerCertificates[0]
+		sanSource, cnSource := "san", "cn"
+		if i > 0 {
+			sanSource = fmt.Sprintf("san:hop%d", i+1)
+			cnSource = fmt.Sprintf("cn:hop%d", i+1)
+		}
 
 		// SANs (primary source)
 		for _, san := range cert.DNSNames {
 			san = strings.ToLower(san)
 			if !seen[san] {
 				seen[san] = true
-				sources[san] = "san"
+				sources[san] = sanSource
 			}
 		}
 
@@ -33,7 +49,7 @@ func DiscoverDomains(connState *tls.ConnectionState, headers http.Header, inputH
 		cn := strings.ToLower(cert.Subject.CommonName)
 		if cn != "" && !seen[cn] {
 			seen[cn] = true
-			sources[cn] = "cn"
+			sources[cn] = cnSource
 		}
 	}
 
@@ -60,16 +76,72 @@ func DiscoverDomains(connState *tls.ConnectionState, headers http.Header, inputH
 
 	// Identify new domains (not matching the input hostname)
 	inputLower := strings.ToLower(inputHost)
-	var newDomains []string
+	var newDomains, wildcards []string
 	for _, d := range domains {
 		if d != inputLower {
 			newDomains = append(newDomains, d)
 		}
+		if strings.HasPrefix(d, "*.") {
+			wildcards = append(wildcards, d)
+		}
 	}
 
 	return &output.DiscoveredDomains{
-		Domains:       domains,
-		DomainSources: sources,
-		NewDomains:    newDomains,
+		Domains:         domains,
+		DomainSources:   sources,
+		NewDomains:      newDomains,
+		WildcardDomains: wildcards,
+	}
+}
+
+// WildcardExpansionSource is the domain_sources value of candidates added by
+// ExpandWildcards.
+const WildcardExpansionSource = "wildcard-expansion"
+
+// maxWildcardCandidates caps the candidates ExpandWildcards adds to one
+// result, however many wildcards and labels there are.
+const maxWildcardCandidates = 50
+
+// defaultWildcardLabels are the subdomain labels tried under wildcard SANs
+// without -dd-wordlist.
+var defaultWildcardLabels = []string{
+	"www", "api", "mail", "dev", "staging", "test", "admin", "portal",
+	"app", "vpn", "remote", "login", "auth", "sso", "cdn", "static",
+}
+
+// ExpandWildcards adds label.example.com for every label and wildcard
+// *.example.com of dd to its new_domains, with source wildcard-expansion
+// (-dd-expand-wildcards). Names dd already knows, from SANs, CSP or the
+// input host itself, are not added again. Wildcards are taken in order and
+// at most maxWildcardCandidates names are added. Candidates are not part of
+// domains, which only lists names that were seen.
+func ExpandWildcards(dd *output.DiscoveredDomains, labels []string, inputHost string) {
+	if dd == nil || len(dd.WildcardDomains) == 0 {
+		return
+	}
+	if len(labels) == 0 {
+		labels = defaultWildcardLabels
+	}
+	inputLower := strings.ToLower(inputHost)
+	added := 0
+expand:
+	for _, wildcard := range dd.WildcardDomains {
+		base := strings.TrimPrefix(wildcard, "*.")
+		if strings.Contains(base, "*") {
+			continue
+		}
+		for _, label := range labels {
+			if added == maxWildcardCandidates {
+				break expand
+			}
+			candidate := label + "." + base
+			if _, known := dd.DomainSources[candidate]; known || candidate == inputLower {
+				continue
+			}
+			dd.DomainSources[candidate] = WildcardExpansionSource
+			dd.NewDomains = append(dd.NewDomains, candidate)
+			added++
+		}
 	}
+	sort.Strings(dd.NewDomains)
 }
@@ -3,10 +3,15 @@ package probe
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"net/http"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 	"time"
+
+	"probeHTTP/internal/output"
 )
 
 func TestDiscoverDomains_NilInputs(t *testing.T) {
@@ -147,3 +152,129 @@ func TestDiscoverDomains_SortedOutput(t *testing.T) {
 		t.Errorf("domains not sorted: %v", result.Domains)
 	}
 }
+
+func TestDiscoverChainDomains_HopSources(t *testing.T) {
+	now := time.Now()
+	first, _ := newSelfSignedCert(t, "a.example.com", []string{"a.example.com", "shared.example.com"},
+		now.Add(-time.Hour), now.Add(time.Hour))
+	second, _ := newSelfSignedCert(t, "b.example.net", []string{"b.example.net", "shared.example.com"},
+		now.Add(-time.Hour), now.Add(time.Hour))
+
+	states := []*tls.ConnectionState{
+		{PeerCertificates: []*x509.Certificate{first}},
+		{PeerCertificates: []*x509.Certificate{second}},
+		nil, // plain-HTTP hop
+	}
+	result := DiscoverChainDomains(states, http.Header{}, "a.example.com")
+	if result == nil {
+		t.Fatal("expected non-nil result")
+	}
+
+	want := map[string]string{
+		"a.example.com":      "san",
+		"shared.example.com": "san", // first hop wins
+		"b.example.net":      "san:hop2",
+	}
+	for domain, source := range want {
+		if got := result.DomainSources[domain]; got != source {
+			t.Errorf("source of %s = %q, want %q", domain, got, source)
+		}
+	}
+	if len(result.Domains) != 3 {
+		t.Errorf("domains = %v, want 3 entries", result.Domains)
+	}
+}
+
+// wildcardDomains discovers the domains of a certificate with sans and a
+// CSP allowing cspDomain.
+func wildcardDomains(t *testing.T, sans []string, cspDomain string) *output.DiscoveredDomains {
+	t.Helper()
+	now := time.Now()
+	cert, _ := newSelfSignedCert(t, sans[0], sans, now.Add(-time.Hour), now.Add(time.Hour))
+	headers := http.Header{}
+	if cspDomain != "" {
+		headers.Set("Content-Security-Policy", "script-src "+cspDomain)
+	}
+	return DiscoverDomains(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}, headers, "example.com")
+}
+
+func TestDiscoverDomains_WildcardDomains(t *testing.T) {
+	dd := wildcardDomains(t, []string{"example.com", "*.example.com", "*.EU.example.com"}, "")
+	if want := []string{"*.eu.example.com", "*.example.com"}; !slices.Equal(dd.WildcardDomains, want) {
+		t.Errorf("wildcard_domains = %v, want %v", dd.WildcardDomains, want)
+	}
+	// Wildcards stay in domains verbatim
+	if !slices.Contains(dd.Domains, "*.example.com") {
+		t.Errorf("domains = %v, want the wildcard kept", dd.Domains)
+	}
+}
+
+func TestExpandWildcards_SkipsKnownNames(t *testing.T) {
+	// api is a SAN and cdn comes from CSP: neither is added again
+	dd := wildcardDomains(t, []string{"example.com", "*.example.com", "api.example.com"}, "cdn.example.com")
+	ExpandWildcards(dd, []string{"www", "api", "cdn", "example", "www"}, "example.com")
+
+	for _, name := range []string{"www.example.com", "example.example.com"} {
+		if dd.DomainSources[name] != WildcardExpansionSource {
+			t.Errorf("%s source = %q, want %s", name, dd.DomainSources[name], WildcardExpansionSource)
+		}
+	}
+	if dd.DomainSources["api.example.com"] != "san" || dd.DomainSources["cdn.example.com"] != "csp" {
+		t.Errorf("sources of known names changed: %v", dd.DomainSources)
+	}
+	want := []string{"*.example.com", "api.example.com", "cdn.example.com", "example.example.com", "www.example.com"}
+	if !slices.Equal(dd.NewDomains, want) {
+		t.Errorf("new_domains = %v, want %v", dd.NewDomains, want)
+	}
+	if slices.Contains(dd.Domains, "www.example.com") {
+		t.Errorf("candidate listed in domains: %v", dd.Domains)
+	}
+}
+
+func TestExpandWildcards_InputHostNotAdded(t *testing.T) {
+	dd := wildcardDomains(t, []string{"*.example.com"}, "")
+	ExpandWildcards(dd, []string{"www", "mail"}, "WWW.example.com")
+	if _, ok := dd.DomainSources["www.example.com"]; ok {
+		t.Errorf("input host added as a candidate: %v", dd.NewDomains)
+	}
+	if dd.DomainSources["mail.example.com"] != WildcardExpansionSource {
+		t.Errorf("new_domains = %v, want mail.example.com", dd.NewDomains)
+	}
+}
+
+func TestExpandWildcards_Cap(t *testing.T) {
+	dd := wildcardDomains(t, []string{"*.a.example.com", "*.b.example.com"}, "")
+	var labels []string
+	for i := 0; i < maxWildcardCandidates; i++ {
+		labels = append(labels, fmt.Sprintf("host%02d", i))
+	}
+	ExpandWildcards(dd, labels, "example.com")
+
+	expanded := 0
+	for name, source := range dd.DomainSources {
+		if source == WildcardExpansionSource {
+			expanded++
+			// The first wildcard uses up the cap
+			if !strings.HasSuffix(name, ".a.example.com") {
+				t.Errorf("candidate %s past the cap", name)
+			}
+		}
+	}
+	if expanded != maxWildcardCandidates {
+		t.Errorf("added %d candidates, want the cap of %d", expanded, maxWildcardCandidates)
+	}
+	if !sort.StringsAreSorted(dd.NewDomains) {
+		t.Error("new_domains not sorted")
+	}
+}
+
+func TestExpandWildcards_DefaultLabels(t *testing.T) {
+	dd := wildcardDomains(t, []string{"*.example.com"}, "")
+	ExpandWildcards(dd, nil, "example.com")
+	for _, label := range defaultWildcardLabels {
+		if dd.DomainSources[label+".example.com"] != WildcardExpansionSource {
+			t.Errorf("default label %s not expanded", label)
+		}
+	}
+	ExpandWildcards(nil, nil, "example.com") // no domains discovered
+}
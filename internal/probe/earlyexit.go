@@ -0,0 +1,116 @@
+package probe
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/parser"
+)
+
+// earlyExitChunk is how much of a body -early-exit reads between checks of
+// its matchers.
+const earlyExitChunk = 32 * 1024
+
+// prefixMatchers are the -early-exit matchers of one final response: the
+// analyses that can be decided from a body prefix.
+type prefixMatchers struct {
+	readAhead int64
+	title     bool // The title and head metadata are wanted from an HTML body
+	keywords  *parser.KeywordMatcher
+	// Forms, the <h1> title fallback, technologies, WAF signatures and
+	// the canonical JSON hash need the whole page, so with them the body
+	// is read up to readAhead
+	fullPage bool
+	// classify is how much of the body page_category body signatures
+	// search
+	classify int
+
+	// What the prefix decided so far. scanned bytes were searched already;
+	// later calls search only the new bytes and an overlap for matches
+	// across the boundary
+	scanned  int
+	headDone bool
+	found    map[string]bool // Keywords found
+}
+
+// headEndOverlap is the longest match of parser.HeadComplete, "</head>".
+const headEndOverlap = len("</head>")
+
+// earlyExit returns the -early-exit matchers for a final response with
+// header, or nil when its body is read in full: -early-exit is off, or
+// -store-response stores the whole body.
+func (p *Prober) earlyExit(header http.Header) *prefixMatchers {
+	if !p.config.EarlyExit || p.config.StoreResponse {
+		return nil
+	}
+	return &prefixMatchers{
+		readAhead: p.config.ReadAhead,
+		title:     !p.config.DisabledAnalysis.Has(config.AnalysisTitle) && parser.IsTextContentType(header.Get("Content-Type")),
+		keywords:  p.keywords,
+		fullPage: p.config.Forms || p.config.TitleFallback || p.config.TechDetect ||
+			p.config.DetectWAF || p.config.JSONCanonicalHash,
+		classify: p.config.PageClassifier.BodyPrefix(),
+	}
+}
+
+// resolved reports whether prefix decides every matcher: the HTML head is
+// complete, every keyword was found and the page classifier has its prefix.
+// Without any matcher nothing is decided early. prefix must extend the
+// prefix of the previous call.
+func (m *prefixMatchers) resolved(prefix []byte) bool {
+	if m.fullPage || (!m.title && m.keywords == nil) {
+		return false
+	}
+	from := m.scanned
+	m.scanned = len(prefix)
+	if m.title && !m.headDone {
+		m.headDone = parser.HeadComplete(prefix[max(0, from-headEndOverlap):])
+	}
+	if m.keywords != nil && len(m.found) < m.keywords.Len() {
+		if m.found == nil {
+			m.found = make(map[string]bool)
+		}
+		for _, kw := range m.keywords.Match(prefix[max(0, from-m.keywords.MaxMatchLen()):]) {
+			m.found[kw] = true
+		}
+	}
+	return len(prefix) >= m.classify && (!m.title || m.headDone) && len(m.found) == m.keywords.Len()
+}
+
+// readBodyPrefixInto is readLimitedBodyInto for -early-exit: r is read in
+// chunks until m resolves, readAhead bytes were read or the body ends.
+// partial reports that reading stopped before the end of the body.
+func readBodyPrefixInto(buf *bytes.Buffer, r io.Reader, limit int64, m *prefixMatchers) (body []byte, truncated, partial bool, err error) {
+	if m == nil {
+		body, truncated, err = readLimitedBodyInto(buf, r, limit)
+		return body, truncated, false, err
+	}
+	if limit <= 0 {
+		return nil, false, false, nil
+	}
+	for {
+		want := min(earlyExitChunk, limit+1-int64(buf.Len()))
+		if m.readAhead < limit {
+			want = min(want, m.readAhead-int64(buf.Len()))
+		}
+		n, err := buf.ReadFrom(io.LimitReader(r, want))
+		body = buf.Bytes()
+		if int64(len(body)) > limit {
+			return body[:limit], true, false, err
+		}
+		if err != nil || n < want {
+			// End of the body
+			return body, false, false, err
+		}
+		if int64(len(body)) >= m.readAhead || m.resolved(body) {
+			// Stop early only if the body goes on past the prefix
+			read := len(body)
+			if _, err := buf.ReadFrom(io.LimitReader(r, 1)); err != nil || buf.Len() == read {
+				return buf.Bytes(), false, false, err
+			}
+			return buf.Bytes()[:read], false, true, nil
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/fingerprint"
+	"probeHTTP/internal/hash"
+	"probeHTTP/internal/parser"
+)
+
+// jenkinsPage is a 4 MB page whose head and keyword fit in the first chunk.
+var jenkinsPage = []byte(`<html><head><title>Dashboard [Jenkins]</title></head><body data-x="X-Jenkins">` +
+	strings.Repeat("<p>build log line</p>\n", 200_000) + "</body></html>")
+
+func TestProbeURL_EarlyExit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		case "/small":
+			w.Write([]byte("<html><head><title>Small</title></head><body>X-Jenkins</body></html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(jenkinsPage)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		keywords  []string
+		configure func(cfg *config.Config)
+		wantRead  int // Body bytes read, 0 = the page classifier's prefix
+		partial   bool
+	}{
+		{name: "resolved in the first chunk", path: "/", keywords: []string{"x-jenkins"}, partial: true},
+		{name: "followed redirect", path: "/redirect", keywords: []string{"x-jenkins"}, partial: true},
+		{name: "missing keyword reads ahead", path: "/", keywords: []string{"x-jenkins", "hudson"}, wantRead: 256 * 1024, partial: true},
+		{name: "forms read ahead", path: "/", configure: func(cfg *config.Config) { cfg.Forms = true }, wantRead: 256 * 1024, partial: true},
+		{name: "tech detection reads ahead", path: "/", configure: func(cfg *config.Config) { cfg.TechDetect = true }, wantRead: 256 * 1024, partial: true},
+		{name: "waf detection reads ahead", path: "/", configure: func(cfg *config.Config) { cfg.DetectWAF = true }, wantRead: 256 * 1024, partial: true},
+		{name: "canonical json hash reads ahead", path: "/", configure: func(cfg *config.Config) { cfg.JSONCanonicalHash = true }, wantRead: 256 * 1024, partial: true},
+		{name: "no classifier stops at the first chunk", path: "/", keywords: []string{"x-jenkins"}, configure: func(cfg *config.Config) { cfg.PageClassifier = nil }, wantRead: earlyExitChunk, partial: true},
+		{name: "small body", path: "/small", keywords: []string{"x-jenkins"}, wantRead: -1},
+		{name: "store response reads in full", path: "/", configure: func(cfg *config.Config) {
+			cfg.StoreResponse = true
+			cfg.StoreResponseDir = t.TempDir()
+		}, wantRead: len(jenkinsPage)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prober := newHeaderProber(t, func(cfg *config.Config) {
+				cfg.EarlyExit = true
+				cfg.FollowRedirects = true
+				cfg.KeywordList = tt.keywords
+				if tt.configure != nil {
+					tt.configure(cfg)
+				}
+			})
+			result := prober.ProbeURL(context.Background(), server.URL+tt.path, server.URL+tt.path)
+			if result.Error != "" {
+				t.Fatalf("unexpected error: %s", result.Error)
+			}
+			if result.BodyPartiallyRead != tt.partial {
+				t.Errorf("body_partially_read = %v, want %v", result.BodyPartiallyRead, tt.partial)
+			}
+			switch tt.wantRead {
+			case 0:
+				if want := config.New().PageClassifier.BodyPrefix(); result.ContentLength != want {
+					t.Errorf("read %d bytes, want the classifier's %d", result.ContentLength, want)
+				}
+			case -1:
+			default:
+				if result.ContentLength != tt.wantRead {
+					t.Errorf("read %d bytes, want %d", result.ContentLength, tt.wantRead)
+				}
+			}
+			if tt.partial != (result.Hash.PrefixMMH3 != "") || tt.partial == (result.Hash.BodyMMH3 != "") {
+				t.Errorf("body_mmh3 %q, prefix_mmh3 %q; want only the %s hash", result.Hash.BodyMMH3, result.Hash.PrefixMMH3,
+					map[bool]string{true: "prefix", false: "body"}[tt.partial])
+			}
+			if !strings.Contains(result.Title, "Jenkins") && tt.path != "/small" {
+				t.Errorf("title = %q", result.Title)
+			}
+			if len(result.FramingAnomalies) != 0 {
+				t.Errorf("framing anomalies %v for a body not read to its end", result.FramingAnomalies)
+			}
+		})
+	}
+}
+
+func TestReadBodyPrefixInto_Limits(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 100_000)
+	matchers := &prefixMatchers{readAhead: 50_000}
+
+	got, truncated, partial, err := readBodyPrefixInto(new(bytes.Buffer), bytes.NewReader(body), 40_000, matchers)
+	if err != nil || len(got) != 40_000 || !truncated || partial {
+		t.Errorf("limit below read-ahead: %d bytes, truncated %v, partial %v, err %v", len(got), truncated, partial, err)
+	}
+	got, truncated, partial, err = readBodyPrefixInto(new(bytes.Buffer), bytes.NewReader(body), 1<<20, matchers)
+	if err != nil || len(got) != 50_000 || truncated || !partial {
+		t.Errorf("read-ahead below limit: %d bytes, truncated %v, partial %v, err %v", len(got), truncated, partial, err)
+	}
+	got, truncated, partial, err = readBodyPrefixInto(new(bytes.Buffer), bytes.NewReader(body[:45_000]), 1<<20, matchers)
+	if err != nil || len(got) != 45_000 || truncated || partial {
+		t.Errorf("body below read-ahead: %d bytes, truncated %v, partial %v, err %v", len(got), truncated, partial, err)
+	}
+	got, truncated, partial, err = readBodyPrefixInto(new(bytes.Buffer), bytes.NewReader(body[:50_000]), 1<<20, matchers)
+	if err != nil || len(got) != 50_000 || truncated || partial {
+		t.Errorf("body of exactly read-ahead: %d bytes, truncated %v, partial %v, err %v", len(got), truncated, partial, err)
+	}
+}
+
+// page_category hash signatures need body_mmh3, which a body cut short by
+// -early-exit does not have; title and body signatures still match.
+func TestProbeURL_EarlyExitSkipsHashClassification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(jenkinsPage)
+	}))
+	defer server.Close()
+
+	classifier, err := fingerprint.New([]fingerprint.Signature{
+		{Category: "ci-dashboard", Kind: fingerprint.KindBodyHash, Value: hash.CalculateMMH3(jenkinsPage)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, earlyExit := range []bool{false, true} {
+		prober := newHeaderProber(t, func(cfg *config.Config) {
+			cfg.EarlyExit = earlyExit
+			cfg.KeywordList = []string{"x-jenkins"}
+			cfg.PageClassifier = classifier
+		})
+		result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+		if result.BodyPartiallyRead != earlyExit {
+			t.Fatalf("early exit %v: body_partially_read = %v", earlyExit, result.BodyPartiallyRead)
+		}
+		want := map[bool]string{false: "ci-dashboard", true: ""}[earlyExit]
+		if result.PageCategory != want {
+			t.Errorf("early exit %v: page_category = %q, want %q", earlyExit, result.PageCategory, want)
+		}
+	}
+}
+
+// A keyword and the end of the head split across chunks are found, and each
+// call searches only the bytes added since the last one.
+func TestPrefixMatchers_Incremental(t *testing.T) {
+	m := &prefixMatchers{title: true, keywords: parser.NewKeywordMatcher([]string{"X-Jenkins"})}
+	page := []byte("<html><head><title>t</title></he" + "ad><body>x-jen" + "kins</body>")
+	cuts := []int{len("<html><head><title>t</title></he"), len("<html><head><title>t</title></head><body>x-jen"), len(page)}
+	for i, cut := range cuts {
+		if got, want := m.resolved(page[:cut]), i == len(cuts)-1; got != want {
+			t.Errorf("prefix of %d bytes: resolved = %v, want %v", cut, got, want)
+		}
+		if m.scanned != cut {
+			t.Errorf("scanned = %d after a prefix of %d bytes", m.scanned, cut)
+		}
+	}
+	if !m.headDone {
+		t.Error("head end split across chunks not found")
+	}
+}
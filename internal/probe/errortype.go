@@ -0,0 +1,48 @@
+package probe
+
+import (
+	"strings"
+
+	"probeHTTP/internal/output"
+)
+
+// errorTypeRules maps error message fragments to error types.
+// Rules are evaluated in order; the first match wins, so more specific
+// fragments (e.g. rate limit timeouts) must precede generic ones.
+var errorTypeRules = []struct {
+	errorType string
+	fragments []string
+}{
+	{output.ErrorTypeRateLimit, []string{"rate limit"}},
+	{output.ErrorTypeInvalidURL, []string{"invalid url", "failed to create request"}},
+	{output.ErrorTypeRedirectExcluded, []string{"redirect to excluded"}},
+	{output.ErrorTypeRedirectPrivate, []string{"redirect to private address blocked"}},
+	{output.ErrorTypeRedirectOutOfScope, []string{"redirect out of scope"}},
+	{output.ErrorTypeCertPinMismatch, []string{"certificate pin mismatch"}},
+	{output.ErrorTypeRedirectScheme, []string{"unsupported redirect scheme"}},
+	{output.ErrorTypeProxy, []string{"proxy error", "proxyconnect"}},
+	{output.ErrorTypeRedirect, []string{"redirect error"}},
+	{output.ErrorTypeBodyRead, []string{"error reading body", "partial body read"}},
+	{output.ErrorTypeCancelled, []string{"cancelled", "context canceled"}},
+	{output.ErrorTypeTimeout, []string{"timeout", "deadline exceeded", "timed out"}},
+	{output.ErrorTypeDNS, []string{"no such host", "server misbehaving", "lookup "}},
+	{output.ErrorTypeConnectionRefused, []string{"connection refused"}},
+	{output.ErrorTypeConnectionReset, []string{"connection reset", "broken pipe", "eof"}},
+	{output.ErrorTypeTLSHandshake, []string{"tls:", "x509:", "handshake", "certificate"}},
+}
+
+// classifyError maps a result error message to a coarse error type.
+func classifyError(errMsg string) string {
+	if errMsg == "" {
+		return ""
+	}
+	lower := strings.ToLower(errMsg)
+	for _, rule := range errorTypeRules {
+		for _, fragment := range rule.fragments {
+			if strings.Contains(lower, fragment) {
+				return rule.errorType
+			}
+		}
+	}
+	return output.ErrorTypeUnknown
+}
@@ -0,0 +1,39 @@
+package probe
+
+import (
+	"testing"
+
+	"probeHTTP/internal/output"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"", ""},
+		{"cancelled", output.ErrorTypeCancelled},
+		{"Redirect error: redirect to excluded target https://admin.example.com/", output.ErrorTypeRedirectExcluded},
+		{"Redirect error: redirect out of scope: https://cdn.example.net/", output.ErrorTypeRedirectOutOfScope},
+		{"certificate pin mismatch: example.com presented 0a:1b", output.ErrorTypeCertPinMismatch},
+		{"Redirect error: unsupported redirect scheme \"javascript\": javascript:alert(1)", output.ErrorTypeRedirectScheme},
+		{"Redirect error: redirect to private address blocked: http://169.254.169.254/latest/meta-data (169.254.169.254)", output.ErrorTypeRedirectPrivate},
+		{"rate limit wait timeout after 60s", output.ErrorTypeRateLimit},
+		{"Invalid URL: parse \"http://[::1\": missing ']' in host", output.ErrorTypeInvalidURL},
+		{"failed after 2 attempts: Request failed: dial tcp: lookup nx.invalid: no such host", output.ErrorTypeDNS},
+		{"Request failed: dial tcp 127.0.0.1:1: connect: connection refused", output.ErrorTypeConnectionRefused},
+		{"Request failed: context deadline exceeded (Client.Timeout exceeded while awaiting headers)", output.ErrorTypeTimeout},
+		{"Request failed: read tcp 10.0.0.1:5555->10.0.0.2:443: read: connection reset by peer", output.ErrorTypeConnectionReset},
+		{"Request failed: remote error: tls: handshake failure", output.ErrorTypeTLSHandshake},
+		{"Request failed: x509: certificate signed by unknown authority", output.ErrorTypeTLSHandshake},
+		{"Redirect error: stopped after 10 redirects", output.ErrorTypeRedirect},
+		{"partial body read: unexpected EOF", output.ErrorTypeBodyRead},
+		{"something unexpected", output.ErrorTypeUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := classifyError(tt.msg); got != tt.want {
+			t.Errorf("classifyError(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Framing anomalies reported in the framing_anomalies field
+const (
+	anomalyContentLengthMismatch = "content_length_mismatch"
+	anomalyTEWithContentLength   = "transfer_encoding_with_content_length"
+	anomalyInterimResponse       = "interim_1xx_response"
+)
+
+// maxHeaderCapture bounds the raw header bytes kept per response.
+const maxHeaderCapture = 16 * 1024
+
+// framingConn records the raw header block of each response read on a
+// plaintext HTTP/1.x connection. Go's transport drops Content-Length when
+// Transfer-Encoding is present, so the raw bytes are the only place the
+// conflicting pair is visible. TLS connections carry ciphertext at this layer
+// and are ignored since their bytes never start with "HTTP/".
+type framingConn struct {
+	net.Conn
+	mu        sync.Mutex
+	capturing bool
+	buf       []byte
+	header    string // raw final (non-1xx) header block of the last response
+}
+
+// Write starts capturing the response to the request being written.
+func (c *framingConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	c.capturing = true
+	c.buf = c.buf[:0]
+	c.header = ""
+	c.mu.Unlock()
+	return c.Conn.Write(b)
+}
+
+func (c *framingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.mu.Lock()
+		if c.capturing {
+			c.capture(b[:n])
+		}
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+// capture appends response bytes until the final header block is complete.
+// Must be called with mu held.
+func (c *framingConn) capture(b []byte) {
+	c.buf = append(c.buf, b...)
+	if !bytes.HasPrefix(c.buf, []byte("HTTP/")) && len(c.buf) >= len("HTTP/") {
+		c.capturing = false
+		return
+	}
+	for {
+		end := bytes.Index(c.buf, []byte("\r\n\r\n"))
+		if end < 0 {
+			if len(c.buf) > maxHeaderCapture {
+				c.capturing = false
+			}
+			return
+		}
+		block := string(c.buf[:end])
+		c.buf = c.buf[end+4:]
+		// Skip interim 1xx blocks; the final response follows
+		if !strings.HasPrefix(statusCodeOf(block), "1") {
+			c.header = block
+			c.capturing = false
+			return
+		}
+	}
+}
+
+// lastHeader returns the raw final header block of the last response.
+func (c *framingConn) lastHeader() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.header
+}
+
+// statusCodeOf returns the status code from a raw "HTTP/1.1 200 OK" block.
+func statusCodeOf(block string) string {
+	fields := strings.Fields(strings.SplitN(block, "\r\n", 2)[0])
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// framingDialContext wraps dial so plaintext connections record raw response headers.
+func framingDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		dial = dialer.DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &framingConn{Conn: conn}, nil
+	}
+}
+
+// framingAnomalies lists the framing anomalies observed on resp.
+// bodyLen is the number of body bytes actually received; complete is false
+// when the body was not fully read (truncated by our limit or skipped), in
+// which case Content-Length can't be checked.
+func framingAnomalies(resp *http.Response, bodyLen int, complete bool) []string {
+	var anomalies []string
+
+	// Chunked responses legitimately lack Content-Length and are skipped here
+	if complete && resp.Header.Get("Content-Length") != "" && resp.ContentLength >= 0 &&
+		bodyAllowed(resp) && int64(bodyLen) != resp.ContentLength {
+		anomalies = append(anomalies, anomalyContentLengthMismatch)
+	}
+
+	rt := requestTraceFrom(resp.Request)
+	if rt == nil {
+		return anomalies
+	}
+	rt.mu.Lock()
+	interim, conn := rt.interim, rt.conn
+	rt.mu.Unlock()
+
+	if conn != nil && hasTEAndContentLength(conn.lastHeader()) {
+		anomalies = append(anomalies, anomalyTEWithContentLength)
+	}
+	if interim {
+		anomalies = append(anomalies, anomalyInterimResponse)
+	}
+	return anomalies
+}
+
+// isShortBody reports whether a body read failed only because the server sent
+// fewer bytes than its Content-Length announced.
+func isShortBody(resp *http.Response, err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) && resp.ContentLength > 0
+}
+
+// bodyAllowed reports whether the response status permits a body.
+func bodyAllowed(resp *http.Response) bool {
+	if resp.StatusCode < 200 || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+	return resp.Request == nil || resp.Request.Method != http.MethodHead
+}
+
+// hasTEAndContentLength reports whether a raw header block carries both
+// Transfer-Encoding and Content-Length.
+func hasTEAndContentLength(block string) bool {
+	var te, cl bool
+	for _, line := range strings.Split(block, "\r\n")[1:] {
+		name, _, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "transfer-encoding":
+			te = true
+		case "content-length":
+			cl = true
+		}
+	}
+	return te && cl
+}
@@ -0,0 +1,110 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func TestProbeURL_FramingAnomalies(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    []string
+	}{
+		{
+			name: "well-formed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte("hello"))
+			},
+			want: nil,
+		},
+		{
+			name: "chunked without content-length",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte("hello "))
+				w.(http.Flusher).Flush()
+				w.Write([]byte("world"))
+			},
+			want: nil,
+		},
+		{
+			name: "short body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Content-Length", "100")
+				w.Write([]byte("only ten b"))
+			},
+			want: []string{anomalyContentLengthMismatch},
+		},
+		{
+			name: "transfer-encoding with content-length",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				conn, buf, err := w.(http.Hijacker).Hijack()
+				if err != nil {
+					t.Errorf("hijack: %v", err)
+					return
+				}
+				defer conn.Close()
+				buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nTransfer-Encoding: chunked\r\nContent-Length: 5\r\n\r\n3\r\nabc\r\n0\r\n\r\n")
+				buf.Flush()
+			},
+			want: []string{anomalyTEWithContentLength},
+		},
+		{
+			name: "interim response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Link", "</style.css>; rel=preload")
+				w.WriteHeader(http.StatusEarlyHints)
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("hello"))
+			},
+			want: []string{anomalyInterimResponse},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			cfg := config.New()
+			cfg.Silent = true
+			cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			cfg.Timeout = 5
+			prober := NewProber(cfg)
+			defer prober.Close()
+
+			result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+			if result.Error != "" {
+				t.Fatalf("ProbeURL error: %s", result.Error)
+			}
+			if strings.Join(result.FramingAnomalies, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("FramingAnomalies = %v, want %v", result.FramingAnomalies, tt.want)
+			}
+		})
+	}
+}
+
+func TestFramingAnomalies_TruncatedBodyNotFlagged(t *testing.T) {
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Length": []string{"1000"}},
+		ContentLength: 1000,
+	}
+	if got := framingAnomalies(resp, 100, false); got != nil {
+		t.Errorf("truncated body should not be flagged, got %v", got)
+	}
+	if got := framingAnomalies(resp, 100, true); len(got) != 1 || got[0] != anomalyContentLengthMismatch {
+		t.Errorf("complete short body = %v, want [%s]", got, anomalyContentLengthMismatch)
+	}
+}
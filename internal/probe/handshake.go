@@ -0,0 +1,140 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+
+	"github.com/quic-go/quic-go"
+)
+
+// handshakeLimiter is a counting semaphore bounding concurrent TLS attempts
+// across all workers. Every attempt may open a fresh connection (and, for a
+// new strategy, a fresh transport), so at high concurrency an unbounded
+// number of in-flight handshakes exhausts file descriptors and ephemeral ports.
+// A permit is held from the connect until the handshake is done, not while
+// the request and body are exchanged.
+type handshakeLimiter chan struct{}
+
+// newHandshakeLimiter returns a limiter allowing n concurrent TLS attempts.
+func newHandshakeLimiter(n int) handshakeLimiter {
+	if n < 1 {
+		n = 1
+	}
+	return make(handshakeLimiter, n)
+}
+
+// acquire blocks until a permit is free or ctx is done. A permit is only
+// held when acquire returns nil; the caller must then release it.
+func (l handshakeLimiter) acquire(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release returns a permit taken by acquire.
+func (l handshakeLimiter) release() {
+	<-l
+}
+
+// inUse returns the number of permits currently held.
+func (l handshakeLimiter) inUse() int {
+	return len(l)
+}
+
+// errTLSHandshakeTimeout matches the error http.Transport reports when
+// TLSHandshakeTimeout expires.
+var errTLSHandshakeTimeout = errors.New("net/http: TLS handshake timeout")
+
+// dialTLS returns a DialTLSContext for transport that connects with dial and
+// does the handshake the transport would do with its TLSClientConfig and
+// TLSHandshakeTimeout, holding a permit of l until the handshake is done.
+func (l handshakeLimiter) dialTLS(transport *http.Transport, dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := withRequestCancel(ctx)
+		defer cancel()
+		if err := l.acquire(ctx); err != nil {
+			return nil, err
+		}
+		defer l.release()
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		// Read per dial: the transport adds its ALPN protocols on first use
+		cfg := transport.TLSClientConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = addr
+			if host, _, err := net.SplitHostPort(addr); err == nil {
+				cfg.ServerName = host
+			}
+		}
+		hsCtx := ctx
+		if timeout := transport.TLSHandshakeTimeout; timeout > 0 {
+			var cancel context.CancelFunc
+			hsCtx, cancel = context.WithTimeoutCause(ctx, timeout, errTLSHandshakeTimeout)
+			defer cancel()
+		}
+
+		// The transport reports the handshake to the trace again once it
+		// gets the connection; tlsStart keeps this, the real, start
+		trace := httptrace.ContextClientTrace(ctx)
+		if trace != nil && trace.TLSHandshakeStart != nil {
+			trace.TLSHandshakeStart()
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(hsCtx); err != nil {
+			conn.Close()
+			if ctx.Err() == nil && errors.Is(context.Cause(hsCtx), errTLSHandshakeTimeout) {
+				err = errTLSHandshakeTimeout
+			}
+			if trace != nil && trace.TLSHandshakeDone != nil {
+				trace.TLSHandshakeDone(tls.ConnectionState{}, err)
+			}
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// withRequestCancel makes ctx, a transport dial context, end with the traced
+// request that started the dial. The transport lets dials outlive their
+// request so a later one can use the connection, but a probe that gave up
+// must not keep a permit, or wait for one, on its behalf.
+func withRequestCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	rt, ok := ctx.Value(requestTraceKey{}).(*requestTrace)
+	if !ok {
+		return ctx, cancel
+	}
+	stop := context.AfterFunc(rt.base, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// quicDialFunc matches http3.Transport.Dial.
+type quicDialFunc func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error)
+
+// dialQUIC wraps an HTTP/3 dial, which includes the handshake, so it holds a
+// permit of l.
+func (l handshakeLimiter) dialQUIC(dial quicDialFunc) quicDialFunc {
+	return func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+		if err := l.acquire(ctx); err != nil {
+			return nil, err
+		}
+		defer l.release()
+		return dial(ctx, addr, tlsCfg, cfg)
+	}
+}
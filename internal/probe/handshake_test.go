@@ -0,0 +1,223 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+)
+
+func TestHandshakeLimiter_CancelledAcquireTakesNoPermit(t *testing.T) {
+	l := newHandshakeLimiter(1)
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx); err == nil {
+		t.Fatal("acquire on a full limiter should fail once ctx is done")
+	}
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	l.release()
+	if err := l.acquire(cancelled); err == nil {
+		t.Fatal("acquire with a cancelled ctx should fail even when a permit is free")
+	}
+	if l.inUse() != 0 {
+		t.Errorf("inUse = %d, want 0", l.inUse())
+	}
+}
+
+// TestProbeURL_MaxTLSHandshakes runs many probes against a server that never
+// completes the TLS handshake and checks that only MaxTLSHandshakes attempts
+// connect at once and that cancelling the probes leaks no permits.
+func TestProbeURL_MaxTLSHandshakes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go func() {
+				// Slow fake handshake: read the ClientHello, never answer
+				io.Copy(io.Discard, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 30
+	cfg.TLSHandshakeTimeout = 30
+	cfg.DisableHTTP3 = true
+	cfg.RateLimitPerHost = 1000
+	cfg.RateLimitBurst = 100
+	cfg.MaxTLSHandshakes = 2
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const probes = 20
+	target := "https://" + ln.Addr().String() + "/"
+	var wg sync.WaitGroup
+	for i := 0; i < probes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prober.ProbeURL(ctx, target, target)
+		}()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for accepted.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if got := accepted.Load(); got != 2 {
+		t.Errorf("accepted %d concurrent handshakes, want 2", got)
+	}
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("probes did not return after cancellation")
+	}
+	// Dials run on the transport's goroutines and let go shortly after
+	deadline = time.Now().Add(2 * time.Second)
+	for prober.handshakes.inUse() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := prober.handshakes.inUse(); n != 0 {
+		t.Errorf("%d handshake permits leaked after cancellation", n)
+	}
+}
+
+// A permit covers the handshake only: probes waiting on a slow response
+// don't hold up other handshakes.
+func TestProbeURL_HandshakePermitReleasedAfterHandshake(t *testing.T) {
+	var inHandler atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inHandler.Add(1)
+		<-release
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.InsecureSkipVerify = true
+		cfg.DisableHTTP3 = true
+		cfg.MaxTLSHandshakes = 1
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			target := fmt.Sprintf("%s/%d", server.URL, i)
+			prober.ProbeURL(context.Background(), target, target)
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for inHandler.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := inHandler.Load(); got != 3 {
+		t.Errorf("%d of 3 probes reached the server with one permit, want all", got)
+	}
+	if n := prober.handshakes.inUse(); n != 0 {
+		t.Errorf("%d permits held while waiting for responses", n)
+	}
+	release <- struct{}{}
+	release <- struct{}{}
+	release <- struct{}{}
+	wg.Wait()
+}
+
+// Redirect hops from http to https handshake on the default transport, which
+// must hold a permit too.
+func TestProbeURL_MaxTLSHandshakesOnRedirect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go func() {
+				io.Copy(io.Discard, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://"+ln.Addr().String()+r.URL.Path, http.StatusFound)
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.Timeout = 30
+		cfg.DisableHTTP3 = true
+		cfg.FollowRedirects = true
+		cfg.MaxTLSHandshakes = 1
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			target := fmt.Sprintf("%s/%d", server.URL, i)
+			prober.ProbeURL(ctx, target, target)
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for accepted.Load() < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if got := accepted.Load(); got != 1 {
+		t.Errorf("accepted %d concurrent redirect handshakes, want 1", got)
+	}
+	cancel()
+	wg.Wait()
+}
@@ -0,0 +1,119 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"probeHTTP/internal/parser"
+	"probeHTTP/pkg/useragent"
+)
+
+// defaultAcceptLanguage is the Accept-Language sent without -accept-language
+const defaultAcceptLanguage = "en-US,en;q=0.9"
+
+// probeHeaders is the header set chosen for one probe. It is picked once so
+// retries, TLS fallbacks and redirect hops all present the same client.
+type probeHeaders struct {
+	family      useragent.Family // set with -randomize-headers
+	headers     []useragent.Header
+	credentials *urlCredentials // -use-url-credentials; nil for most targets
+}
+
+type probeHeadersKey struct{}
+
+// withProbeHeaders picks the probe's headers and attaches them to ctx.
+func (p *Prober) withProbeHeaders(ctx context.Context, probeURL string) context.Context {
+	headers := p.newProbeHeaders()
+	headers.credentials = p.credentialsFor(probeURL)
+	if p.config.DebugLogger != nil && headers.family != "" {
+		p.config.DebugLogger.Info("randomized request headers",
+			"url", probeURL,
+			"family", headers.family,
+			"headers", headers.headers,
+		)
+	}
+	return context.WithValue(ctx, probeHeadersKey{}, headers)
+}
+
+// newProbeHeaders builds the header set for a probe: the configured or
+// default values, or with -randomize-headers a browser-consistent set for
+// the chosen User-Agent. -accept and -accept-language always win.
+func (p *Prober) newProbeHeaders() *probeHeaders {
+	ua := useragent.Get(p.config.UserAgent, p.config.RandomUserAgent)
+	acceptLanguage := p.config.AcceptLanguage
+	if acceptLanguage == "" {
+		acceptLanguage = defaultAcceptLanguage
+	}
+
+	if !p.config.RandomizeHeaders {
+		return &probeHeaders{headers: []useragent.Header{
+			{Name: "User-Agent", Value: ua},
+			{Name: "Accept", Value: p.acceptHeader()},
+			{Name: "Accept-Language", Value: acceptLanguage},
+		}}
+	}
+
+	family, headers := useragent.RandomHeaders(ua)
+	for i, h := range headers {
+		switch {
+		case h.Name == "Accept" && p.config.Accept != "":
+			headers[i].Value = p.config.Accept
+		case h.Name == "Accept-Language" && p.config.AcceptLanguage != "":
+			headers[i].Value = p.config.AcceptLanguage
+		}
+	}
+	return &probeHeaders{
+		family:  family,
+		headers: append([]useragent.Header{{Name: "User-Agent", Value: ua}}, headers...),
+	}
+}
+
+// setProbeHeaders sets the probe's headers on req, picking a fresh set when
+// the request's context carries none.
+func (p *Prober) setProbeHeaders(req *http.Request) {
+	headers, ok := req.Context().Value(probeHeadersKey{}).(*probeHeaders)
+	if !ok {
+		headers = p.newProbeHeaders()
+	}
+	for _, h := range headers.headers {
+		req.Header.Set(h.Name, h.Value)
+	}
+	p.setAuthorization(req)
+}
+
+// responseHeaderValues returns the sanitized, non-empty values of a response
+// header in the order they were sent. The HTTP client already unfolds
+// obsolete line folding into a single value; sanitizing collapses the
+// whitespace the continuation leaves behind.
+func responseHeaderValues(h http.Header, name string) []string {
+	var values []string
+	for _, v := range h.Values(name) {
+		if v = parser.SanitizeString(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// joinHeaderValues joins the distinct values with ", ", as if the header had
+// been sent once as a list.
+func joinHeaderValues(values []string) string {
+	var distinct []string
+	for _, v := range values {
+		if !slices.Contains(distinct, v) {
+			distinct = append(distinct, v)
+		}
+	}
+	return strings.Join(distinct, ", ")
+}
+
+// lastHeaderValue returns the last value, which browsers go by when a
+// single-valued header such as Content-Type is repeated.
+func lastHeaderValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
@@ -0,0 +1,183 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/pkg/useragent"
+)
+
+// headerRecorder serves a redirect to /final and records the headers of
+// every request it receives
+type headerRecorder struct {
+	mu       sync.Mutex
+	requests []http.Header
+}
+
+func (h *headerRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.requests = append(h.requests, r.Header.Clone())
+	h.mu.Unlock()
+	if r.URL.Path == "/" {
+		http.Redirect(w, r, "/final", http.StatusFound)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+func newHeaderProber(t *testing.T, configure func(cfg *config.Config)) *Prober {
+	t.Helper()
+	return newTestProber(t, func(cfg *config.Config) {
+		cfg.RateLimitPerHost = 1000
+		configure(cfg)
+	})
+}
+
+func TestProbeURL_DefaultHeaders(t *testing.T) {
+	recorder := &headerRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.AcceptLanguage = "de-DE,de;q=0.9" })
+	prober.ProbeURL(context.Background(), server.URL, server.URL)
+
+	if len(recorder.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(recorder.requests))
+	}
+	for i, h := range recorder.requests {
+		if got := h.Get("Accept-Language"); got != "de-DE,de;q=0.9" {
+			t.Errorf("request %d Accept-Language = %q, want -accept-language value", i, got)
+		}
+		if got := h.Get("Accept"); got != defaultAccept {
+			t.Errorf("request %d Accept = %q, want default", i, got)
+		}
+		if h.Get("Sec-Fetch-Mode") != "" {
+			t.Errorf("request %d has browser headers without -randomize-headers", i)
+		}
+	}
+}
+
+func TestProbeURL_RandomizedHeaders(t *testing.T) {
+	recorder := &headerRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.RandomUserAgent = true
+		cfg.RandomizeHeaders = true
+	})
+	const probes = 20
+	for i := 0; i < probes; i++ {
+		prober.ProbeURL(context.Background(), server.URL, server.URL)
+	}
+	if len(recorder.requests) != 2*probes {
+		t.Fatalf("got %d requests, want %d", len(recorder.requests), 2*probes)
+	}
+
+	distinct := make(map[string]bool)
+	for i := 0; i < len(recorder.requests); i += 2 {
+		first, hop := recorder.requests[i], recorder.requests[i+1]
+		for _, name := range []string{"User-Agent", "Accept", "Accept-Language", "DNT", "Cache-Control"} {
+			if first.Get(name) != hop.Get(name) {
+				t.Errorf("probe %d: %s differs between hops: %q vs %q", i/2, name, first.Get(name), hop.Get(name))
+			}
+		}
+		family := useragent.FamilyOf(first.Get("User-Agent"))
+		if first.Get("Accept") != useragent.Profiles[family].Accept[0] {
+			t.Errorf("probe %d: %s UA got Accept %q", i/2, family, first.Get("Accept"))
+		}
+		distinct[first.Get("User-Agent")+"|"+first.Get("Accept-Language")+"|"+first.Get("DNT")+"|"+first.Get("Cache-Control")] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("all %d probes sent identical headers with -randomize-headers", probes)
+	}
+}
+
+func TestProbeURL_MultiValueServerHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Server", "nginx")
+		w.Header().Add("Server", "PHP/7.4")
+		w.Header().Add("Server", "nginx")
+		w.Header().Add("X-Powered-By", "PHP/7.4.33")
+		w.Header().Add("X-Powered-By", "Express")
+		w.Header().Add("Content-Type", "text/plain")
+		w.Header().Add("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<title>Layers</title>"))
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {})
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("probe error: %s", result.Error)
+	}
+	if result.WebServer != "nginx, PHP/7.4" {
+		t.Errorf("webserver = %q, want the distinct Server values joined", result.WebServer)
+	}
+	if want := []string{"nginx", "PHP/7.4", "nginx"}; !reflect.DeepEqual(result.ServerRaw, want) {
+		t.Errorf("server_raw = %v, want %v", result.ServerRaw, want)
+	}
+	if result.PoweredBy != "PHP/7.4.33, Express" {
+		t.Errorf("powered_by = %q, want both X-Powered-By values", result.PoweredBy)
+	}
+	if result.ContentType != "text/html; charset=utf-8" || result.Title != "Layers" {
+		t.Errorf("content_type = %q title = %q, want the last Content-Type to apply", result.ContentType, result.Title)
+	}
+}
+
+func TestProbeURL_SingleServerHeaderHasNoRaw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Apache")
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {})
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.WebServer != "Apache" || result.ServerRaw != nil || result.PoweredBy != "" {
+		t.Errorf("webserver = %q server_raw = %v powered_by = %q, want Apache only", result.WebServer, result.ServerRaw, result.PoweredBy)
+	}
+}
+
+func TestProbeURL_FoldedServerHeader(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				bufio.NewReader(conn).ReadString('\n')
+				io.WriteString(conn, "HTTP/1.1 200 OK\r\n"+
+					"Server: Apache/2.4.41\r\n"+
+					"\t(Ubuntu)\r\n"+
+					"X-Powered-By: PHP/7.4\r\n"+
+					"Content-Length: 2\r\n"+
+					"Connection: close\r\n\r\nok")
+			}()
+		}
+	}()
+
+	target := "http://" + ln.Addr().String()
+	prober := newHeaderProber(t, func(cfg *config.Config) {})
+	result := prober.ProbeURL(context.Background(), target, target)
+	if result.Error != "" {
+		t.Fatalf("probe error: %s", result.Error)
+	}
+	if result.WebServer != "Apache/2.4.41 (Ubuntu)" || result.PoweredBy != "PHP/7.4" {
+		t.Errorf("webserver = %q powered_by = %q, want the unfolded values", result.WebServer, result.PoweredBy)
+	}
+}
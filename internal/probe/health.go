@@ -0,0 +1,89 @@
+package probe
+
+import (
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"probeHTTP/internal/config"
+)
+
+// goroutineLeakThreshold is how many goroutines above the pre-run baseline
+// may outlive a run before it is reported as a leak.
+const goroutineLeakThreshold = 50
+
+// goroutineSettleTime is how long goroutines get to wind down after the
+// prober is closed (idle connection loops, HTTP/3 sessions) before counting.
+const goroutineSettleTime = 2 * time.Second
+
+// HealthStats is a snapshot of the process and prober resources that grow
+// when goroutines or sockets leak.
+type HealthStats struct {
+	Goroutines  int
+	OpenConns   int64
+	DialedConns int64
+	InFlight    int64
+}
+
+// HealthStats returns the current goroutine, connection and in-flight probe counts.
+func (p *Prober) HealthStats() HealthStats {
+	conns := p.client.ConnStats()
+	return HealthStats{
+		Goroutines:  runtime.NumGoroutine(),
+		OpenConns:   conns.Open,
+		DialedConns: conns.Dialed,
+		InFlight:    p.inFlight.Load(),
+	}
+}
+
+// MonitorHealth logs HealthStats every interval until ctx is done.
+func (p *Prober) MonitorHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := p.HealthStats()
+			p.config.Logger.Info("health",
+				"goroutines", stats.Goroutines,
+				"open_conns", stats.OpenConns,
+				"dialed_conns", stats.DialedConns,
+				"in_flight", stats.InFlight,
+			)
+		}
+	}
+}
+
+// ReportGoroutineLeak warns when more than goroutineLeakThreshold goroutines
+// above baseline are still running after the prober was closed, and writes a
+// goroutine dump to the debug log when one is configured. Returns whether a
+// leak was reported.
+func ReportGoroutineLeak(cfg *config.Config, baseline int) bool {
+	limit := baseline + goroutineLeakThreshold
+	deadline := time.Now().Add(goroutineSettleTime)
+	count := runtime.NumGoroutine()
+	for count > limit && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		count = runtime.NumGoroutine()
+	}
+	if count <= limit {
+		return false
+	}
+
+	cfg.Logger.Warn("goroutines outlived the run, possible leak",
+		"goroutines", count,
+		"baseline", baseline,
+		"threshold", goroutineLeakThreshold,
+		"dump", cfg.DebugLogger != nil,
+	)
+	if cfg.DebugLogger != nil {
+		var dump strings.Builder
+		pprof.Lookup("goroutine").WriteTo(&dump, 1)
+		cfg.DebugLogger.Warn("goroutine dump", "goroutines", count, "baseline", baseline, "dump", dump.String())
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Hedge winners reported in hedge_winner.
+const (
+	hedgeWinnerPrimary = "primary"
+	hedgeWinnerHedge   = "hedge"
+)
+
+// hedgeAttempt is the outcome of one request of a hedged pair.
+type hedgeAttempt struct {
+	resp   *http.Response
+	head   exchangeHead
+	err    error
+	cancel context.CancelFunc
+	hedge  bool
+}
+
+// cancelOnClose cancels the winning attempt's context once its body is
+// closed, so the connection is released only after the body was read.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// doHedgedRequest sends req like doRequest. With -hedge, a GET that has not
+// produced response headers within the hedge delay gets one duplicate on the
+// same client, provided the host's rate limiter has a token for it. The first
+// response wins and the other request is cancelled and discarded unread;
+// none of its bytes reach the byte accounting. winner is "" when no duplicate
+// was sent, else hedgeWinnerPrimary or hedgeWinnerHedge.
+func (p *Prober) doHedgedRequest(client *http.Client, req *http.Request) (resp *http.Response, winner string, err error) {
+	if p.config.Hedge <= 0 || req.Method != http.MethodGet {
+		resp, err = p.doRequest(client, req)
+		return resp, "", err
+	}
+
+	// The duplicate gets its own trace; the primary's trace hooks live on
+	// req's context. Cloned before the primary starts mutating req.Header.
+	base := req.Context()
+	if rt := requestTraceFrom(req); rt != nil {
+		base = rt.base
+	}
+	hedgeReq := withRequestTrace(req.Clone(base))
+
+	attempts := make(chan hedgeAttempt, 2)
+	launch := func(r *http.Request, hedge bool) context.CancelFunc {
+		ctx, cancel := context.WithCancel(r.Context())
+		r = r.WithContext(ctx)
+		go func() {
+			resp, head, err := p.sendRequest(client, r)
+			attempts <- hedgeAttempt{resp: resp, head: head, err: err, cancel: cancel, hedge: hedge}
+		}()
+		return cancel
+	}
+	cancelPrimary := launch(req, false)
+
+	timer := time.NewTimer(p.config.Hedge)
+	defer timer.Stop()
+	select {
+	case first := <-attempts:
+		return first.win(&p.transfer), "", first.err
+	case <-timer.C:
+	}
+
+	if !p.client.GetLimiter(rateLimitHost(req.Context(), req.URL.Hostname())).Allow() {
+		if p.config.DebugLogger != nil {
+			p.config.DebugLogger.Debug("hedge skipped, no rate limit token", "url", req.URL.String())
+		}
+		first := <-attempts
+		return first.win(&p.transfer), "", first.err
+	}
+	if p.config.DebugLogger != nil {
+		p.config.DebugLogger.Debug("sending hedged request", "url", req.URL.String(), "after", p.config.Hedge)
+	}
+	cancelHedge := launch(hedgeReq, true)
+
+	// Prefer the first response; fall back to the first error when both fail
+	first := <-attempts
+	if first.err != nil {
+		second := <-attempts
+		if second.err == nil {
+			first.discard()
+			return second.win(&p.transfer), second.winner(), nil
+		}
+		second.discard()
+		return first.win(&p.transfer), first.winner(), first.err
+	}
+	if first.hedge {
+		cancelPrimary()
+	} else {
+		cancelHedge()
+	}
+	go func() {
+		loser := <-attempts
+		loser.discard()
+	}()
+	return first.win(&p.transfer), first.winner(), nil
+}
+
+// win records the attempt's heads in transfer and hands its response to the
+// caller, cancelling its context once the body is closed (or right away when
+// the request failed).
+func (a hedgeAttempt) win(transfer *transferAccounting) *http.Response {
+	a.head.record(transfer)
+	if a.err != nil || a.resp == nil {
+		a.cancel()
+		return a.resp
+	}
+	a.resp.Body = &cancelOnClose{ReadCloser: a.resp.Body, cancel: a.cancel}
+	return a.resp
+}
+
+// discard cancels a losing attempt and closes any response it got.
+func (a hedgeAttempt) discard() {
+	a.cancel()
+	if a.resp != nil && a.resp.Body != nil {
+		a.resp.Body.Close()
+	}
+}
+
+func (a hedgeAttempt) winner() string {
+	if a.hedge {
+		return hedgeWinnerHedge
+	}
+	return hedgeWinnerPrimary
+}
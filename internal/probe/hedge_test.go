@@ -0,0 +1,151 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+)
+
+// stallFirstServer stalls its first request for stall or until the client
+// gives up on it, and answers every later one immediately.
+func stallFirstServer(t *testing.T, stall time.Duration) (*httptest.Server, *atomic.Int32, chan struct{}) {
+	t.Helper()
+	var requests atomic.Int32
+	cancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+				close(cancelled)
+			case <-time.After(stall):
+			}
+			return
+		}
+		w.Write([]byte("<html><title>fast</title></html>"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, cancelled
+}
+
+func newHedgeProber(t *testing.T, hedge time.Duration, ratePerHost int) *Prober {
+	t.Helper()
+	return newTestProber(t, func(cfg *config.Config) {
+		cfg.Timeout = 10
+		cfg.RateLimitPerHost = ratePerHost
+		cfg.Hedge = hedge
+	})
+}
+
+func TestProbeURL_HedgeWinsOverStalledRequest(t *testing.T) {
+	server, requests, cancelled := stallFirstServer(t, 5*time.Second)
+
+	prober := newHedgeProber(t, 100*time.Millisecond, 1000)
+
+	start := time.Now()
+	result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("hedged probe took %v, want well under the 5s stall", elapsed)
+	}
+	if result.Error != "" || result.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d error %q, want 200", result.StatusCode, result.Error)
+	}
+	if !result.Hedged || result.HedgeWinner != hedgeWinnerHedge {
+		t.Errorf("hedged=%v winner=%q, want true and %q", result.Hedged, result.HedgeWinner, hedgeWinnerHedge)
+	}
+	if result.Title != "fast" {
+		t.Errorf("title = %q, want the hedge's response", result.Title)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("server saw %d requests, want 2", got)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("stalled primary request was not cancelled")
+	}
+
+	// Only the winner's bytes count
+	plain := newHedgeProber(t, 0, 1000)
+	baseline := plain.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if result.BytesDownloaded != baseline.BytesDownloaded || result.BytesUploaded != baseline.BytesUploaded {
+		t.Errorf("hedged bytes %d/%d, want %d/%d as for a single request",
+			result.BytesDownloaded, result.BytesUploaded, baseline.BytesDownloaded, baseline.BytesUploaded)
+	}
+}
+
+// When both attempts get a response, the loser's heads are not counted
+// either, in the result or in the run totals.
+func TestProbeURL_HedgeCountsOnlyWinnerBytes(t *testing.T) {
+	var hedged atomic.Bool
+	arrived := make(chan struct{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hedged.Load() {
+			// Hold both attempts until the hedge arrives, then answer both
+			arrived <- struct{}{}
+			for len(arrived) < 2 {
+				time.Sleep(5 * time.Millisecond)
+			}
+		}
+		w.Header().Set("X-Padding", strings.Repeat("x", 512))
+		w.Write([]byte("<html><title>both</title></html>"))
+	}))
+	defer server.Close()
+
+	plain := newHedgeProber(t, 0, 1000)
+	baseline := plain.ProbeURL(context.Background(), server.URL+"/", server.URL)
+
+	hedged.Store(true)
+	prober := newHedgeProber(t, 50*time.Millisecond, 1000)
+	result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if result.Error != "" || !result.Hedged {
+		t.Fatalf("error %q hedged=%v, want a hedged success", result.Error, result.Hedged)
+	}
+	if result.BytesDownloaded != baseline.BytesDownloaded || result.BytesUploaded != baseline.BytesUploaded {
+		t.Errorf("result bytes %d/%d, want %d/%d as for a single request",
+			result.BytesDownloaded, result.BytesUploaded, baseline.BytesDownloaded, baseline.BytesUploaded)
+	}
+	got, want := prober.TransferStats(1), plain.TransferStats(1)
+	if got.Downloaded != want.Downloaded || got.Uploaded != want.Uploaded {
+		t.Errorf("run totals %d/%d, want %d/%d", got.Downloaded, got.Uploaded, want.Downloaded, want.Uploaded)
+	}
+}
+
+func TestProbeURL_HedgeNotSentForFastResponse(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	prober := newHedgeProber(t, time.Second, 1000)
+
+	result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if result.Hedged || result.HedgeWinner != "" {
+		t.Errorf("hedged=%v winner=%q, want no hedge", result.Hedged, result.HedgeWinner)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server saw %d requests, want 1", got)
+	}
+}
+
+func TestProbeURL_HedgeNeedsRateLimitToken(t *testing.T) {
+	server, requests, _ := stallFirstServer(t, 500*time.Millisecond)
+
+	// One request per second: the primary took the only token
+	prober := newHedgeProber(t, 100*time.Millisecond, 1)
+
+	result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if result.Hedged {
+		t.Error("hedge sent without a rate limit token")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server saw %d requests, want 1", got)
+	}
+}
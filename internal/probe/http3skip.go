@@ -0,0 +1,33 @@
+package probe
+
+import (
+	"strings"
+	"sync"
+)
+
+// http3SkipCache remembers, for one run, the hostnames whose HTTP/3 attempt
+// timed out. Without a UDP listener a QUIC handshake gets no refusal and
+// only ends at its deadline, so later URLs of those hosts (other ports and
+// paths included) skip HTTP/3. The zero value is ready to use.
+type http3SkipCache struct {
+	mu    sync.Mutex
+	hosts map[string]struct{}
+}
+
+// add marks host as not answering HTTP/3.
+func (c *http3SkipCache) add(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hosts == nil {
+		c.hosts = make(map[string]struct{})
+	}
+	c.hosts[strings.ToLower(host)] = struct{}{}
+}
+
+// has reports whether host's HTTP/3 attempt timed out earlier in the run.
+func (c *http3SkipCache) has(host string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.hosts[strings.ToLower(host)]
+	return ok
+}
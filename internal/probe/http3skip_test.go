@@ -0,0 +1,102 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+)
+
+func TestHTTP3SkipCache(t *testing.T) {
+	var cache http3SkipCache
+	if cache.has("example.com") {
+		t.Fatal("empty cache reports a host")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cache.add(fmt.Sprintf("host%d.example.com", i))
+			cache.has("example.com")
+		}(i)
+	}
+	wg.Wait()
+
+	if !cache.has("HOST7.example.com") {
+		t.Error("host lookups should ignore case")
+	}
+	if cache.has("example.com") {
+		t.Error("unrelated host reported")
+	}
+}
+
+// newTLS13Server starts an HTTPS server refusing TLS 1.2, so the TLS 1.2
+// strategies fail and the TLS 1.3 strategy decides the probe. Nothing
+// listens on its UDP port.
+func newTLS13Server(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS13}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProbeURL_HTTP3TimeoutSkipsHostAfterwards(t *testing.T) {
+	server := newTLS13Server(t)
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.InsecureSkipVerify = true
+		cfg.HTTP3Timeout = 300 * time.Millisecond
+	})
+
+	start := time.Now()
+	first := prober.ProbeURL(context.Background(), server.URL+"/a", server.URL)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("first probe took %s, want the HTTP/3 attempt bounded by -http3-timeout", elapsed)
+	}
+	if !strings.Contains(first.Error, "deadline exceeded") {
+		t.Fatalf("Error = %q, want the HTTP/3 attempt to time out", first.Error)
+	}
+	if !prober.http3Skip.has("127.0.0.1") {
+		t.Fatal("timed-out HTTP/3 host not cached")
+	}
+
+	// Same host, other path: TLS 1.3 over HTTP/2 instead of another QUIC wait
+	second := prober.ProbeURL(context.Background(), server.URL+"/b", server.URL)
+	if second.Error != "" {
+		t.Fatalf("second probe error = %q, want HTTP/3 skipped", second.Error)
+	}
+	if second.Protocol == "HTTP/3" {
+		t.Errorf("second probe used %s", second.Protocol)
+	}
+}
+
+func TestProbeURL_DisableHTTP3BypassesSkipCache(t *testing.T) {
+	server := newTLS13Server(t)
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.InsecureSkipVerify = true
+		cfg.DisableHTTP3 = true
+	})
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if prober.http3Skip.has("127.0.0.1") {
+		t.Error("-disable-http3 probe touched the HTTP/3 skip cache")
+	}
+}
@@ -0,0 +1,96 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"probeHTTP/internal/output"
+)
+
+// inputTracker keeps per-input state shared across workers. It is used by
+// -first-alive to stop probing an input's remaining expanded URLs once one of
+// them answers: queued URLs are skipped and in-flight probes are cancelled
+// through their per-probe contexts.
+type inputTracker struct {
+	mu     sync.Mutex
+	inputs map[string]*inputState
+}
+
+type inputState struct {
+	done     bool
+	nextID   int
+	inFlight map[int]context.CancelFunc
+}
+
+func newInputTracker() *inputTracker {
+	return &inputTracker{inputs: make(map[string]*inputState)}
+}
+
+func (t *inputTracker) state(input string) *inputState {
+	st, ok := t.inputs[input]
+	if !ok {
+		st = &inputState{inFlight: make(map[int]context.CancelFunc)}
+		t.inputs[input] = st
+	}
+	return st
+}
+
+// begin registers a probe for input and returns a context that is cancelled
+// once another probe of the same input succeeds. ok is false if the input is
+// already done and the probe should be skipped.
+func (t *inputTracker) begin(ctx context.Context, input string) (probeCtx context.Context, end func(), ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st := t.state(input)
+	if st.done {
+		return nil, nil, false
+	}
+	probeCtx, cancel := context.WithCancel(ctx)
+	id := st.nextID
+	st.nextID++
+	st.inFlight[id] = cancel
+
+	end = func() {
+		t.mu.Lock()
+		delete(st.inFlight, id)
+		t.mu.Unlock()
+		cancel()
+	}
+	return probeCtx, end, true
+}
+
+// markDone records that input has answered and cancels its other in-flight probes.
+func (t *inputTracker) markDone(input string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st := t.state(input)
+	st.done = true
+	for id, cancel := range st.inFlight {
+		cancel()
+		delete(st.inFlight, id)
+	}
+}
+
+// isDone reports whether input has already answered.
+func (t *inputTracker) isDone(input string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.inputs[input]
+	return ok && st.done
+}
+
+// skippedFirstAliveResult is emitted for URLs not probed (or cancelled)
+// because another URL of the same input already answered.
+func skippedFirstAliveResult(probeURL, originalInput string) output.ProbeResult {
+	return output.ProbeResult{
+		Timestamp: time.Now().Format(time.RFC3339),
+		URL:       probeURL,
+		Input:     originalInput,
+		Method:    "GET",
+		Error:     "skipped: another URL for this input answered first",
+		ErrorType: output.ErrorTypeSkippedFirstAlive,
+	}
+}
@@ -26,13 +26,18 @@ func (t *IPTracker) GetIP(hostname string) string {
 
 // DialContext returns a custom DialContext function that records resolved IPs
 func (t *IPTracker) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return t.wrap(dialer.DialContext)
+}
+
+// wrap returns dial with the resolved IP of each connection recorded
+func (t *IPTracker) wrap(dial dialFunc) dialFunc {
 	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 		host, port, err := net.SplitHostPort(addr)
 		if err != nil {
 			host = addr
 		}
 
-		conn, err := dialer.DialContext(ctx, network, addr)
+		conn, err := dial(ctx, network, addr)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,62 @@
+package probe
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// maxLookupCacheSize bounds a lookupCache; past it the cache is cleared.
+const maxLookupCacheSize = 10000
+
+// lookupCache memoizes a DNS lookup per key for the lifetime of a Prober
+// (CNAMEs by hostname, PTR names by IP). Concurrent misses for one key share
+// a single lookup without blocking lookups of other keys. The zero value is
+// ready to use.
+type lookupCache[V any] struct {
+	entries sync.Map           // key -> V
+	size    atomic.Int64       // approximate size for eviction
+	mu      sync.Mutex         // serializes eviction to avoid concurrent Range/Delete
+	flight  singleflight.Group // per-key dedup of lookups
+}
+
+// get returns the cached value of key, or the value of lookup, run once
+// for all concurrent callers. The value is cached unless lookup reports
+// it must not be.
+func (c *lookupCache[V]) get(key string, lookup func() (value V, cache bool)) V {
+	if cached, ok := c.entries.Load(key); ok {
+		return cached.(V)
+	}
+	v, _, _ := c.flight.Do(key, func() (interface{}, error) {
+		// Double-check the cache after winning the flight
+		if cached, ok := c.entries.Load(key); ok {
+			return cached.(V), nil
+		}
+		value, cache := lookup()
+		if cache {
+			c.store(key, value)
+		}
+		return value, nil
+	})
+	return v.(V)
+}
+
+// store caches value for key. Once the cache exceeds maxLookupCacheSize it
+// is cleared to bound memory. Eviction is serialized via mu so concurrent
+// callers for different keys cannot run Range/Delete simultaneously and
+// delete entries stored by others.
+func (c *lookupCache[V]) store(key string, value V) {
+	if c.size.Add(1) > maxLookupCacheSize {
+		c.mu.Lock()
+		if c.size.Load() > maxLookupCacheSize {
+			c.entries.Range(func(k, _ interface{}) bool {
+				c.entries.Delete(k)
+				return true
+			})
+			c.size.Store(0)
+		}
+		c.mu.Unlock()
+	}
+	c.entries.Store(key, value)
+}
@@ -0,0 +1,39 @@
+package probe
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLookupCache(t *testing.T) {
+	var c lookupCache[string]
+	lookups := 0
+	lookup := func(value string, cache bool) func() (string, bool) {
+		return func() (string, bool) {
+			lookups++
+			return value, cache
+		}
+	}
+
+	if got := c.get("a", lookup("", false)); got != "" || lookups != 1 {
+		t.Fatalf("get = %q after %d lookups", got, lookups)
+	}
+	// An uncached value is looked up again
+	if got := c.get("a", lookup("x", true)); got != "x" || lookups != 2 {
+		t.Fatalf("get = %q after %d lookups, want x after 2", got, lookups)
+	}
+	if got := c.get("a", lookup("y", true)); got != "x" || lookups != 2 {
+		t.Fatalf("cached get = %q after %d lookups, want x after 2", got, lookups)
+	}
+
+	// Past its bound the cache starts over
+	for i := range maxLookupCacheSize {
+		c.get(strconv.Itoa(i), lookup("", true))
+	}
+	if _, ok := c.entries.Load("a"); ok {
+		t.Error("cache not cleared past maxLookupCacheSize")
+	}
+	if n := c.size.Load(); n > maxLookupCacheSize {
+		t.Errorf("size = %d, want at most %d", n, maxLookupCacheSize)
+	}
+}
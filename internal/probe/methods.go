@@ -0,0 +1,122 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/parser"
+)
+
+// corsProbeOrigin is the foreign origin sent with -cors-check preflights.
+// A server echoing it back in Access-Control-Allow-Origin trusts any origin.
+const corsProbeOrigin = "https://probehttp.example"
+
+// maxOptionsBody caps how much of an OPTIONS response body is drained so the
+// connection can be reused.
+const maxOptionsBody = 64 * 1024
+
+// checkMethods sends the -methods-check and -cors-check OPTIONS requests to
+// the final URL with the client that served the primary probe. A check that
+// can't be sent leaves its fields unset; one the server refuses (405/501)
+// reports empty method lists.
+func (p *Prober) checkMethods(ctx context.Context, client *http.Client, finalReq *http.Request, result *output.ProbeResult) {
+	if p.config.MethodsCheck {
+		if resp := p.sendOptions(ctx, client, finalReq, nil); resp != nil {
+			result.AllowedMethods = []string{}
+			result.CORSAllowMethods = []string{}
+			if optionsAccepted(resp.StatusCode) {
+				result.AllowedMethods = parseMethodList(resp.Header.Values("Allow"))
+				result.CORSAllowMethods = parseMethodList(resp.Header.Values("Access-Control-Allow-Methods"))
+			}
+		}
+	}
+
+	if p.config.CORSCheck {
+		preflight := http.Header{
+			"Origin":                        {corsProbeOrigin},
+			"Access-Control-Request-Method": {http.MethodGet},
+		}
+		if resp := p.sendOptions(ctx, client, finalReq, preflight); resp != nil {
+			result.CORSAllowMethods = []string{}
+			if optionsAccepted(resp.StatusCode) {
+				result.CORSAllowMethods = parseMethodList(resp.Header.Values("Access-Control-Allow-Methods"))
+				result.CORSAllowOrigin = parser.SanitizeString(resp.Header.Get("Access-Control-Allow-Origin"))
+			}
+		}
+	}
+}
+
+// sendOptions sends an OPTIONS request for finalReq's URL, carrying over its
+// headers plus extra, and returns the response with its body already drained.
+// Returns nil if the request could not be made.
+func (p *Prober) sendOptions(ctx context.Context, client *http.Client, finalReq *http.Request, extra http.Header) *http.Response {
+	target := finalReq.URL
+	if err := p.waitRateLimit(ctx, target); err != nil {
+		p.debugOptionsFailure(target, err)
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodOptions, target.String(), nil)
+	if err != nil {
+		p.debugOptionsFailure(target, err)
+		return nil
+	}
+	req.Header = finalReq.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	for name, values := range extra {
+		req.Header[name] = values
+	}
+
+	resp, err := p.doRequest(client, req)
+	if err != nil {
+		p.debugOptionsFailure(target, err)
+		return nil
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxOptionsBody))
+	resp.Body.Close()
+	return resp
+}
+
+// waitRateLimit waits for the per-host limiter like any primary request
+func (p *Prober) waitRateLimit(ctx context.Context, target *url.URL) error {
+	waitCtx, cancel := context.WithTimeout(ctx, time.Duration(p.config.RateLimitTimeout)*time.Second)
+	defer cancel()
+	return p.client.GetLimiter(rateLimitHost(ctx, target.Hostname())).Wait(waitCtx)
+}
+
+func (p *Prober) debugOptionsFailure(target *url.URL, err error) {
+	if p.config.DebugLogger != nil {
+		p.config.DebugLogger.Debug("OPTIONS request failed", "url", target.String(), "error", err)
+	}
+}
+
+// optionsAccepted reports whether an OPTIONS status carries meaningful
+// method headers; 405 and 501 mean the server doesn't support OPTIONS.
+func optionsAccepted(status int) bool {
+	return status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented
+}
+
+// parseMethodList splits comma-separated method header values into an
+// uppercased, deduplicated list in first-seen order. Never returns nil.
+func parseMethodList(values []string) []string {
+	methods := []string{}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, method := range strings.Split(value, ",") {
+			method = strings.ToUpper(parser.SanitizeString(strings.TrimSpace(method)))
+			if method == "" || seen[method] {
+				continue
+			}
+			seen[method] = true
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
@@ -0,0 +1,110 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func TestParseMethodList(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{"empty", nil, []string{}},
+		{"blank header", []string{""}, []string{}},
+		{"single", []string{"GET, head,OPTIONS"}, []string{"GET", "HEAD", "OPTIONS"}},
+		{"repeated headers", []string{"GET, POST", "post, PUT"}, []string{"GET", "POST", "PUT"}},
+		{"wildcard", []string{"*"}, []string{"*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMethodList(tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMethodList(%q) = %q, want %q", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeURL_MethodsCheck(t *testing.T) {
+	var sawPreflight bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			w.Write([]byte("ok"))
+			return
+		}
+		if r.URL.Path == "/refused" {
+			w.Header().Set("Allow", "GET")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Origin") != "" {
+			sawPreflight = r.Header.Get("Access-Control-Request-Method") == http.MethodGet
+			w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Allow", "get, HEAD, OPTIONS, GET")
+		w.Header().Set("Access-Control-Allow-Methods", "GET")
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.MethodsCheck = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if want := []string{"GET", "HEAD", "OPTIONS"}; !reflect.DeepEqual(result.AllowedMethods, want) {
+		t.Errorf("AllowedMethods = %q, want %q", result.AllowedMethods, want)
+	}
+	if want := []string{"GET"}; !reflect.DeepEqual(result.CORSAllowMethods, want) {
+		t.Errorf("CORSAllowMethods = %q, want %q", result.CORSAllowMethods, want)
+	}
+
+	refused := prober.ProbeURL(context.Background(), server.URL+"/refused", server.URL)
+	if refused.Error != "" {
+		t.Fatalf("refused OPTIONS should not fail the probe: %s", refused.Error)
+	}
+	data, _ := json.Marshal(refused)
+	if !strings.Contains(string(data), `"allowed_methods":[]`) {
+		t.Errorf("refused OPTIONS should report an empty allowed_methods array, got %s", data)
+	}
+
+	cfg.MethodsCheck = false
+	cfg.CORSCheck = true
+	cors := prober.ProbeURL(context.Background(), server.URL+"/", server.URL)
+	if !sawPreflight {
+		t.Error("CORS preflight headers were not sent")
+	}
+	if cors.AllowedMethods != nil {
+		t.Errorf("AllowedMethods = %q, want unset without -methods-check", cors.AllowedMethods)
+	}
+	if want := []string{"GET", "POST"}; !reflect.DeepEqual(cors.CORSAllowMethods, want) {
+		t.Errorf("CORSAllowMethods = %q, want %q", cors.CORSAllowMethods, want)
+	}
+	if cors.CORSAllowOrigin != corsProbeOrigin {
+		t.Errorf("CORSAllowOrigin = %q, want %q", cors.CORSAllowOrigin, corsProbeOrigin)
+	}
+
+	data, _ = json.Marshal(cors)
+	if strings.Contains(string(data), "allowed_methods") {
+		t.Errorf("allowed_methods should be omitted without -methods-check, got %s", data)
+	}
+}
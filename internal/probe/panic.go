@@ -0,0 +1,46 @@
+package probe
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"probeHTTP/internal/output"
+)
+
+// maxPanicStack bounds the stack trace of a recovered panic in the debug log.
+const maxPanicStack = 8 << 10
+
+// probeRecovered runs probe and turns a panic into an internal_panic result
+// for probeURL, so one pathological response doesn't end the run. With
+// -panic-fatal the panic is left to crash the process. Panics in goroutines
+// a probe starts itself are not caught.
+func (p *Prober) probeRecovered(probeURL, originalInput string, probe func() output.ProbeResult) (result output.ProbeResult) {
+	if p.config.PanicFatal {
+		return probe()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			result = p.panicResult(probeURL, originalInput, v, debug.Stack())
+		}
+	}()
+	return probe()
+}
+
+// panicResult logs a recovered panic, with its stack in the debug log, and
+// returns the failed result reported in place of the probe's.
+func (p *Prober) panicResult(probeURL, originalInput string, v any, stack []byte) output.ProbeResult {
+	msg := fmt.Sprint(v)
+	p.logError("recovered panic while probing", "url", probeURL, "panic", msg)
+	if p.config.DebugLogger != nil {
+		if len(stack) > maxPanicStack {
+			stack = append(stack[:maxPanicStack:maxPanicStack], "\n... (truncated)"...)
+		}
+		p.config.DebugLogger.Error("recovered panic while probing",
+			"url", probeURL,
+			"input", originalInput,
+			"panic", msg,
+			"stack", string(stack),
+		)
+	}
+	return p.unprobedResult(probeURL, originalInput, "internal panic: "+msg, output.ErrorTypeInternalPanic)
+}
@@ -0,0 +1,182 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+// techDetectorFunc is a technologyDetector that calls itself, standing in
+// for a detector that panics on a page.
+type techDetectorFunc func(headers http.Header, body []byte) []string
+
+func (f techDetectorFunc) Detect(headers http.Header, body []byte) []string {
+	return f(headers, body)
+}
+
+func TestProcessURLs_RecoversPanic(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/bad" {
+			w.Write([]byte("<html><title>pathological body</title></html>"))
+			return
+		}
+		w.Write([]byte("<html><title>page</title></html>"))
+	}))
+	defer server.Close()
+
+	var debugLog syncBuffer
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.InsecureSkipVerify = true
+		cfg.DisableHTTP3 = true
+		cfg.DebugLogger = slog.New(slog.NewJSONHandler(&debugLog, nil))
+	})
+	prober.techDetector = techDetectorFunc(func(_ http.Header, body []byte) []string {
+		if bytes.Contains(body, []byte("pathological body")) {
+			panic("pathological body")
+		}
+		return nil
+	})
+
+	urls := []string{server.URL + "/a", server.URL + "/bad", server.URL + "/b", server.URL + "/c"}
+	inputs := map[string]string{}
+	for _, u := range urls {
+		inputs[u] = u
+	}
+
+	got := map[string]output.ProbeResult{}
+	for result := range prober.ProcessURLs(context.Background(), urls, inputs, 2) {
+		got[result.Input] = result
+	}
+	if len(got) != len(urls) {
+		t.Fatalf("got %d results, want %d", len(got), len(urls))
+	}
+	for _, u := range urls {
+		result := got[u]
+		if strings.HasSuffix(u, "/bad") {
+			if result.ErrorType != output.ErrorTypeInternalPanic || !strings.Contains(result.Error, "pathological body") {
+				t.Errorf("%s: error %q (%s), want internal_panic with the panic message", u, result.Error, result.ErrorType)
+			}
+			if result.URL != u || result.NormalizedURL == "" {
+				t.Errorf("%s: panic result does not identify the URL: %+v", u, result)
+			}
+			continue
+		}
+		if result.Error != "" || result.Title != "page" {
+			t.Errorf("%s: error %q, title %q; want an intact result", u, result.Error, result.Title)
+		}
+	}
+
+	if n := prober.handshakes.inUse(); n != 0 {
+		t.Errorf("%d TLS handshake permits still held after the panic", n)
+	}
+	if log := debugLog.String(); !strings.Contains(log, "recovered panic while probing") || !strings.Contains(log, "panic_test.go") {
+		t.Errorf("debug log has no panic stack:\n%s", log)
+	}
+}
+
+func TestProbeRecovered_PanicFatal(t *testing.T) {
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.PanicFatal = true })
+	defer func() {
+		if v := recover(); v != "boom" {
+			t.Errorf("recovered %v, want the probe's panic to propagate", v)
+		}
+	}()
+	prober.probeRecovered("http://example.com/", "example.com", func() output.ProbeResult {
+		panic("boom")
+	})
+	t.Error("probeRecovered returned with -panic-fatal")
+}
+
+func TestProbeRecovered_TruncatesStack(t *testing.T) {
+	var debugLog syncBuffer
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.DebugLogger = slog.New(slog.NewTextHandler(&debugLog, nil))
+	})
+	result := prober.panicResult("http://example.com/", "example.com", "boom", bytes.Repeat([]byte("x"), 3*maxPanicStack))
+	if result.ErrorType != output.ErrorTypeInternalPanic || result.Error != "internal panic: boom" {
+		t.Errorf("error %q (%s)", result.Error, result.ErrorType)
+	}
+	if log := debugLog.String(); len(log) > 2*maxPanicStack || !strings.Contains(log, "(truncated)") {
+		t.Errorf("stack not truncated: %d bytes logged", len(log))
+	}
+}
+
+// A waiter of a -coalesce probe that panicked probes for itself instead of
+// blocking on the leader forever.
+func TestProbeCoalesced_LeaderPanic(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.Coalesce = true })
+	var panicked atomic.Bool
+	prober.techDetector = techDetectorFunc(func(http.Header, []byte) []string {
+		if panicked.CompareAndSwap(false, true) {
+			time.Sleep(200 * time.Millisecond) // let the waiter join
+			panic("leader")
+		}
+		return nil
+	})
+
+	results := make([]output.ProbeResult, 2)
+	var wg sync.WaitGroup
+	for i, target := range []string{server.URL + "/", server.URL} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = prober.probeRecovered(target, target, func() output.ProbeResult {
+				return prober.ProbeURL(context.Background(), target, target)
+			})
+		}()
+		time.Sleep(50 * time.Millisecond)
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter blocked on a leader that panicked")
+	}
+
+	if results[0].ErrorType != output.ErrorTypeInternalPanic {
+		t.Errorf("leader: error %q (%s), want internal_panic", results[0].Error, results[0].ErrorType)
+	}
+	if results[1].Error != "" || results[1].Coalesced {
+		t.Errorf("waiter: error %q, coalesced %v; want its own probe", results[1].Error, results[1].Coalesced)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("server saw %d requests, want 2", n)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,93 @@
+package probe
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// PinSet maps lowercase hostnames to the SHA-256 fingerprints (lowercase
+// hex, no colons) their leaf certificate may have. Several pins per host
+// allow for certificate rotation.
+type PinSet map[string][]string
+
+// LoadPinFile reads a -pin-file. See ParsePins.
+func LoadPinFile(path string) (PinSet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pin file: %w", err)
+	}
+	defer file.Close()
+	return ParsePins(file)
+}
+
+// ParsePins reads "host sha256:ab:cd:..." lines. The sha256: prefix and the
+// colons are optional and hex digits may be in any case. A host may be
+// listed more than once. Empty lines and lines starting with # are skipped.
+func ParsePins(r io.Reader) (PinSet, error) {
+	pins := PinSet{}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want \"host sha256:fingerprint\"", lineNo)
+		}
+		fingerprint, ok := normalizeFingerprint(fields[1])
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid SHA-256 fingerprint %q", lineNo, fields[1])
+		}
+		host := strings.ToLower(strings.TrimSuffix(fields[0], "."))
+		pins[host] = append(pins[host], fingerprint)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// normalizeFingerprint returns a SHA-256 fingerprint as 64 lowercase hex
+// digits. ok is false for anything that isn't 32 bytes of hex.
+func normalizeFingerprint(s string) (string, bool) {
+	if len(s) >= 7 && strings.EqualFold(s[:7], "sha256:") {
+		s = s[7:]
+	}
+	s = strings.ToLower(strings.ReplaceAll(s, ":", ""))
+	if b, err := hex.DecodeString(s); err != nil || len(b) != sha256.Size {
+		return "", false
+	}
+	return s, true
+}
+
+// check compares the leaf certificate of state with the pins of host.
+// listed is false for hosts without pins and for connections without a
+// certificate; fingerprint is the leaf's, colon-separated like
+// fingerprint_sha256.
+func (s PinSet) check(host string, state *tls.ConnectionState) (match, listed bool, fingerprint string) {
+	expected, ok := s[strings.ToLower(strings.TrimSuffix(host, "."))]
+	if !ok || state == nil || len(state.PeerCertificates) == 0 {
+		return false, false, ""
+	}
+	sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+	actual := hex.EncodeToString(sum[:])
+	for _, pin := range expected {
+		if pin == actual {
+			return true, true, formatFingerprint(sum)
+		}
+	}
+	return false, true, formatFingerprint(sum)
+}
+
+// SetPins sets the -pin-file pins checked on HTTPS probes. It must be
+// called before ProcessURLs.
+func (p *Prober) SetPins(pins PinSet) {
+	p.pins = pins
+}
@@ -0,0 +1,171 @@
+package probe
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+func TestParsePins(t *testing.T) {
+	input := `# production certificates
+example.com  sha256:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89
+Example.COM. abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789
+
+api.example.com SHA256:0000000000000000000000000000000000000000000000000000000000000000
+`
+	pins, err := ParsePins(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789"
+	if got := pins["example.com"]; len(got) != 2 || got[0] != want || got[1] != want {
+		t.Errorf("example.com pins = %v, want the same fingerprint twice", got)
+	}
+	if got := pins["api.example.com"]; len(got) != 1 || got[0] != strings.Repeat("0", 64) {
+		t.Errorf("api.example.com pins = %v", got)
+	}
+}
+
+func TestParsePins_Errors(t *testing.T) {
+	tests := []string{
+		"example.com",
+		"example.com sha256:ab extra",
+		"example.com sha256:abcd",
+		"example.com sha1:" + strings.Repeat("ab", 20),
+		"example.com " + strings.Repeat("zz", 32),
+	}
+	for _, input := range tests {
+		if _, err := ParsePins(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("ParsePins(%q) error = %v, want a line 1 error", input, err)
+		}
+	}
+}
+
+func TestPinSetCheck(t *testing.T) {
+	now := time.Now()
+	cert, _ := newSelfSignedCert(t, "example.com", []string{"example.com"}, now.Add(-time.Hour), now.Add(time.Hour))
+	other, _ := newSelfSignedCert(t, "example.com", []string{"example.com"}, now.Add(-time.Hour), now.Add(time.Hour))
+	sum := sha256.Sum256(cert.Raw)
+	pins := PinSet{"example.com": {hex.EncodeToString(sum[:])}}
+
+	tests := []struct {
+		name       string
+		host       string
+		leaf       *x509.Certificate
+		wantMatch  bool
+		wantListed bool
+	}{
+		{"pinned certificate", "example.com", cert, true, true},
+		{"host case and trailing dot", "EXAMPLE.com.", cert, true, true},
+		{"rotated certificate", "example.com", other, false, true},
+		{"unlisted host", "www.example.com", other, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tt.leaf}}
+			match, listed, fingerprint := pins.check(tt.host, state)
+			if match != tt.wantMatch || listed != tt.wantListed {
+				t.Errorf("check = (%v, %v), want (%v, %v)", match, listed, tt.wantMatch, tt.wantListed)
+			}
+			if listed && fingerprint != formatFingerprint(sha256.Sum256(tt.leaf.Raw)) {
+				t.Errorf("fingerprint = %q, want the leaf's", fingerprint)
+			}
+		})
+	}
+
+	if _, listed, _ := pins.check("example.com", nil); listed {
+		t.Error("plain HTTP connection reported as pinned")
+	}
+	var none PinSet
+	if _, listed, _ := none.check("example.com", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}); listed {
+		t.Error("nil PinSet reported a pinned host")
+	}
+}
+
+// serverPin returns the colon-separated fingerprint of a TLS test server's
+// certificate, in the -pin-file format.
+func serverPin(server *httptest.Server) string {
+	return "sha256:" + formatFingerprint(sha256.Sum256(server.Certificate().Raw))
+}
+
+func TestProbeURL_Pins(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	wrongPin := "sha256:" + strings.Repeat("00", 32)
+	tests := []struct {
+		name          string
+		pins          string
+		strict        bool
+		wantMatch     *bool
+		wantErrorType string
+		wantError     bool
+	}{
+		{"match", "127.0.0.1 " + strings.ToUpper(serverPin(server)), false, boolPtr(true), "", false},
+		{"mismatch reported", "127.0.0.1 " + wrongPin, false, boolPtr(false), output.ErrorTypeCertPinMismatch, false},
+		{"mismatch strict", "127.0.0.1 " + wrongPin, true, boolPtr(false), output.ErrorTypeCertPinMismatch, true},
+		{"rotation", "127.0.0.1 " + wrongPin + "\n127.0.0.1 " + serverPin(server), true, boolPtr(true), "", false},
+		{"unlisted host", "localhost " + wrongPin, true, nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pins, err := ParsePins(strings.NewReader(tt.pins))
+			if err != nil {
+				t.Fatalf("ParsePins: %v", err)
+			}
+			prober := newHeaderProber(t, func(cfg *config.Config) {
+				cfg.InsecureSkipVerify = true
+				cfg.DisableHTTP3 = true
+				cfg.PinStrict = tt.strict
+				cfg.MaxRetries = 2
+			})
+			prober.SetPins(pins)
+			requests.Store(0)
+
+			result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+			if (tt.wantMatch == nil) != (result.PinMatch == nil) || tt.wantMatch != nil && *tt.wantMatch != *result.PinMatch {
+				t.Errorf("pin_match = %v, want %v", fmtBoolPtr(result.PinMatch), fmtBoolPtr(tt.wantMatch))
+			}
+			if result.ErrorType != tt.wantErrorType {
+				t.Errorf("error_type = %q, want %q", result.ErrorType, tt.wantErrorType)
+			}
+			if gotError := result.Error != ""; gotError != tt.wantError {
+				t.Errorf("error = %q, want an error: %v", result.Error, tt.wantError)
+			}
+			if tt.wantError && !strings.HasPrefix(result.Error, "certificate pin mismatch: 127.0.0.1 presented ") {
+				t.Errorf("error = %q, want the presented fingerprint", result.Error)
+			}
+			// A mismatch is not retried and doesn't fall back to other TLS strategies
+			if n := requests.Load(); n != 1 {
+				t.Errorf("server saw %d requests, want 1", n)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func fmtBoolPtr(b *bool) string {
+	if b == nil {
+		return "<nil>"
+	}
+	if *b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,37 @@
+package probe
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer keeps buffers that grew on an unusually large body or
+// transcript out of the pools so they don't pin memory between probes.
+const maxPooledBuffer = 1 << 20
+
+// bodyBufferPool holds the buffers response bodies are read into. Bytes
+// read into them must be copied before they outlive processResponse.
+var bodyBufferPool = sync.Pool{
+	New: func() any { return bytes.NewBuffer(make([]byte, 0, 64<<10)) },
+}
+
+// debugBufferPool holds the per-probe -debug transcripts.
+var debugBufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// getBuffer takes an empty buffer from pool.
+func getBuffer(pool *sync.Pool) *bytes.Buffer {
+	buf := pool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns buf to pool unless it grew past maxPooledBuffer.
+func putBuffer(pool *sync.Pool, buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	pool.Put(buf)
+}
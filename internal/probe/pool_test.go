@@ -0,0 +1,122 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/hash"
+)
+
+// pageBody is the distinct page /n serves; sizes straddle the pooled
+// buffers' initial capacity so some probes grow them.
+func pageBody(n int) []byte {
+	return []byte(fmt.Sprintf("<html><head><title>page %d</title></head><body>%s</body></html>",
+		n, strings.Repeat(strconv.Itoa(n%10), (n%4)*40<<10)))
+}
+
+// Run with -race: concurrent probes, half of them through a redirect that
+// reads the final body into a second pooled buffer, must each report their
+// own page.
+func TestProbeURL_PooledBuffersNotShared(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rest, ok := strings.CutPrefix(r.URL.Path, "/r/"); ok {
+			http.Redirect(w, r, "/"+rest, http.StatusFound)
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(pageBody(n))
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.FollowRedirects = true
+		cfg.DebugOnError = true
+	})
+
+	const probes = 64
+	var wg sync.WaitGroup
+	errs := make(chan string, probes)
+	for n := 0; n < probes; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			target := fmt.Sprintf("%s/%d", server.URL, n)
+			if n%2 == 1 {
+				target = fmt.Sprintf("%s/r/%d", server.URL, n)
+			}
+			result := prober.ProbeURL(context.Background(), target, server.URL)
+			body := pageBody(n)
+			switch {
+			case result.Error != "":
+				errs <- fmt.Sprintf("%s: %s", target, result.Error)
+			case result.Title != fmt.Sprintf("page %d", n):
+				errs <- fmt.Sprintf("%s: title %q", target, result.Title)
+			case result.ContentLength != len(body) || result.Hash.BodyMMH3 != hash.CalculateMMH3(body):
+				errs <- fmt.Sprintf("%s: content_length %d, body_mmh3 %s; want %d, %s",
+					target, result.ContentLength, result.Hash.BodyMMH3, len(body), hash.CalculateMMH3(body))
+			case result.Debug != "":
+				errs <- fmt.Sprintf("%s: debug transcript on success", target)
+			}
+		}(n)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestReadLimitedBodyInto(t *testing.T) {
+	tests := []struct {
+		body          string
+		limit         int64
+		want          string
+		wantTruncated bool
+	}{
+		{"hello", 16, "hello", false},
+		{"hello", 5, "hello", false},
+		{"hello world", 5, "hello", true},
+		{"hello", 0, "", false},
+	}
+	for _, tt := range tests {
+		buf := getBuffer(&bodyBufferPool)
+		buf.WriteString("stale bytes from an earlier probe")
+		buf.Reset()
+		body, truncated, err := readLimitedBodyInto(buf, strings.NewReader(tt.body), tt.limit)
+		if err != nil || string(body) != tt.want || truncated != tt.wantTruncated {
+			t.Errorf("readLimitedBodyInto(%q, %d) = %q, %v, %v; want %q, %v",
+				tt.body, tt.limit, body, truncated, err, tt.want, tt.wantTruncated)
+		}
+		putBuffer(&bodyBufferPool, buf)
+	}
+}
+
+func TestPutBuffer_ResetsAndDropsOversized(t *testing.T) {
+	var pool sync.Pool
+	buf := bytes.NewBufferString("leftover")
+	putBuffer(&pool, buf)
+	if buf.Len() != 0 {
+		t.Errorf("putBuffer left %d bytes in a pooled buffer", buf.Len())
+	}
+
+	big := bytes.NewBuffer(make([]byte, 0, maxPooledBuffer+1))
+	putBuffer(&pool, big)
+	for i := 0; i < 4; i++ {
+		if got, _ := pool.Get().(*bytes.Buffer); got == big {
+			t.Fatal("buffer over maxPooledBuffer was pooled")
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"probeHTTP/internal/output"
+)
+
+// prefetchDNSWorkers is the size of the -prefetch-dns resolver pool. Lookups
+// are cheap compared to probes, so it doesn't follow -c.
+const prefetchDNSWorkers = 64
+
+// PrefetchStats summarizes the -prefetch-dns stage.
+type PrefetchStats struct {
+	Hosts      int           // Unique hostnames looked up
+	IPLiterals int           // Hosts given as IP literals, not looked up
+	Resolved   int           // Hosts with at least one address
+	NXDomain   int           // Hosts that don't exist; their URLs are not probed
+	Errors     int           // Other lookup failures (timeouts, SERVFAIL); their URLs are probed
+	Duration   time.Duration // Wall time of the stage
+}
+
+// PrefetchDNS resolves the unique hostnames of urls concurrently before
+// probing, each lookup bounded by timeout. Answers seed the DNS cache so
+// probes don't resolve again. It returns the hostnames that don't exist;
+// only definite "no such host" answers count, so transient failures are
+// left for the probe itself to retry.
+func (p *Prober) PrefetchDNS(ctx context.Context, urls []string, timeout time.Duration) (map[string]error, PrefetchStats) {
+	start := time.Now()
+	var stats PrefetchStats
+
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, rawURL := range urls {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		host := u.Hostname()
+		if seen[host] {
+			continue
+		}
+		seen[host] = true
+		if net.ParseIP(host) != nil {
+			stats.IPLiterals++
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	stats.Hosts = len(hosts)
+
+	lookup := net.DefaultResolver.LookupIPAddr
+	if p.dns != nil {
+		lookup = p.dns.lookupIPAddr
+	}
+
+	var mu sync.Mutex
+	nxdomain := make(map[string]error)
+	hostChan := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(prefetchDNSWorkers, len(hosts)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range hostChan {
+				lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+				_, err := lookup(lookupCtx, host)
+				cancel()
+
+				var dnsErr *net.DNSError
+				mu.Lock()
+				switch {
+				case err == nil:
+					stats.Resolved++
+				case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+					stats.NXDomain++
+					nxdomain[host] = err
+				default:
+					stats.Errors++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, host := range hosts {
+		select {
+		case hostChan <- host:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(hostChan)
+	wg.Wait()
+
+	stats.Duration = time.Since(start)
+	return nxdomain, stats
+}
+
+// NXDomainResult is the result of a URL whose hostname the -prefetch-dns
+// stage found not to exist. It is reported without being probed.
+func (p *Prober) NXDomainResult(probeURL, originalInput string, lookupErr error) output.ProbeResult {
+	return p.unprobedResult(probeURL, originalInput, fmt.Sprintf("DNS prefetch failed: %v", lookupErr), output.ErrorTypeDNSNXDomain)
+}
+
+// DeadlistedResult is the result of a URL whose host -deadlist skips
+// because it failed at the connection level in too many runs in a row.
+func (p *Prober) DeadlistedResult(probeURL, originalInput string) output.ProbeResult {
+	return p.unprobedResult(probeURL, originalInput, "skipped: host is deadlisted after repeated connection failures", output.ErrorTypeSkippedDeadlisted)
+}
+
+// unprobedResult is a failed result for a URL reported without probing it.
+func (p *Prober) unprobedResult(probeURL, originalInput, errMsg, errorType string) output.ProbeResult {
+	result := output.ProbeResult{
+		RunID:     p.config.RunID,
+		Timestamp: time.Now().Format(time.RFC3339),
+		URL:       probeURL,
+		Input:     originalInput,
+		Method:    "GET",
+		Error:     errMsg,
+		ErrorType: errorType,
+	}
+	if u, err := url.Parse(probeURL); err == nil {
+		result.Scheme = u.Scheme
+		result.Host = u.Hostname()
+		result.Port = u.Port()
+		if result.Port == "" {
+			result.Port = p.defaultPorts().ForScheme(u.Scheme)
+		}
+	}
+	result.NormalizedURL = p.normalizeURL(probeURL)
+	return result
+}
@@ -0,0 +1,90 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+func TestPrefetchDNS(t *testing.T) {
+	cfg := config.New()
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	var lookups atomic.Int32
+	prober.dns = newDNSCache(func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups.Add(1)
+		switch host {
+		case "gone.test":
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		case "slow.test":
+			return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+		}
+		return []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}}, nil
+	}, time.Minute)
+
+	urls := []string{
+		"http://alive.test/", "https://alive.test:8443/",
+		"http://gone.test/", "https://gone.test/",
+		"http://slow.test/",
+		"http://192.0.2.7/",
+	}
+	nxdomain, stats := prober.PrefetchDNS(context.Background(), urls, time.Second)
+
+	if len(nxdomain) != 1 || nxdomain["gone.test"] == nil {
+		t.Errorf("nxdomain = %v, want only gone.test", nxdomain)
+	}
+	want := PrefetchStats{Hosts: 3, IPLiterals: 1, Resolved: 1, NXDomain: 1, Errors: 1}
+	stats.Duration = 0
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	// The answer seeds the cache: probing alive.test doesn't look it up again
+	before := lookups.Load()
+	if _, err := prober.dns.lookupIPAddr(context.Background(), "alive.test"); err != nil {
+		t.Fatal(err)
+	}
+	if lookups.Load() != before {
+		t.Error("prefetched host was resolved again")
+	}
+}
+
+func TestNXDomainResult(t *testing.T) {
+	cfg := config.New()
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.NXDomainResult("https://gone.test/", "gone.test", errors.New("lookup gone.test: no such host"))
+	if result.ErrorType != output.ErrorTypeDNSNXDomain || result.Error == "" {
+		t.Errorf("error = %q (%s), want a dns_nxdomain error", result.Error, result.ErrorType)
+	}
+	if result.Host != "gone.test" || result.Port != "443" || result.Scheme != "https" || result.NormalizedURL == "" {
+		t.Errorf("result does not identify the URL: %+v", result)
+	}
+}
+
+func TestDeadlistedResult(t *testing.T) {
+	cfg := config.New()
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.DeadlistedResult("http://dead.test:8080/", "dead.test:8080")
+	if result.ErrorType != output.ErrorTypeSkippedDeadlisted || result.Error == "" {
+		t.Errorf("error = %q (%s), want a skipped_deadlisted error", result.Error, result.ErrorType)
+	}
+	if result.Host != "dead.test" || result.Port != "8080" || result.Input != "dead.test:8080" || result.NormalizedURL == "" {
+		t.Errorf("result does not identify the URL: %+v", result)
+	}
+}
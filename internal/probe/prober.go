@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -15,8 +16,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
-
-	"golang.org/x/sync/singleflight"
+	"unicode/utf8"
 
 	"probeHTTP/internal/cdn"
 	"probeHTTP/internal/config"
@@ -25,29 +25,47 @@ import (
 	"probeHTTP/internal/parser"
 	"probeHTTP/internal/storage"
 	"probeHTTP/internal/tech"
-	"probeHTTP/pkg/useragent"
+	"probeHTTP/internal/waf"
 )
 
 // cachedClient wraps an HTTP client with its cleanup function for the client cache.
 type cachedClient struct {
-	client  *http.Client
-	cleanup func()
+	client   *http.Client
+	cleanup  func()
+	strategy TLSStrategy
+	protocol string
 }
 
-const maxCnameCacheSize = 10000
+// technologyDetector detects the technologies of a page for -td
+// (tech.Detector).
+type technologyDetector interface {
+	Detect(headers http.Header, body []byte) []string
+}
 
 // Prober handles HTTP probing operations
 type Prober struct {
-	client        *Client
-	config        *config.Config
-	ipTracker     *IPTracker
-	techDetector  *tech.Detector
-	cnameCache    sync.Map            // hostname -> CNAME string
-	cnameCacheSz  atomic.Int64        // approximate size for eviction
-	cnameCacheMu  sync.Mutex          // serializes eviction to avoid concurrent Range/Delete
-	cnameFlight   singleflight.Group  // per-hostname dedup for CNAME lookups
-	clientCache   map[string]*cachedClient // strategy:protocol -> cached client
-	clientCacheMu sync.Mutex
+	client           *Client
+	config           *config.Config
+	ipTracker        *IPTracker
+	techDetector     technologyDetector
+	keywords         *parser.KeywordMatcher
+	cnameCache       lookupCache[string]   // hostname -> CNAME
+	ptrCache         lookupCache[[]string] // IP -> PTR names
+	lookupAddr       func(ctx context.Context, addr string) ([]string, error)
+	lookupHost       func(ctx context.Context, host string) ([]string, error) // redirect target checks
+	inputs           *inputTracker                                            // -first-alive per-input state; nil when disabled
+	transfer         transferAccounting                                       // global and per-host byte counters
+	handshakes       handshakeLimiter                                         // bounds concurrent TLS attempts (-max-tls-handshakes)
+	dns              *dnsCache                                                // shared A/AAAA cache; nil with -no-dns-cache
+	inFlight         atomic.Int64                                             // ProbeURL calls currently running (-health-interval)
+	adaptive         atomic.Pointer[adaptiveConcurrency]                      // -adaptive-concurrency state of the last ProcessURLs, for AdaptiveStats
+	urlCredentials   map[string]*url.Userinfo                                 // -use-url-credentials userinfo by probe URL
+	pins             PinSet                                                   // -pin-file leaf fingerprints by host; nil when unset
+	coalesce         coalesceGroup                                            // -coalesce in-flight probes by normalized URL
+	http3Skip        http3SkipCache                                           // hosts whose HTTP/3 attempt timed out this run
+	firstAliveStatus output.StatusRanges
+	clientCache      map[string]*cachedClient // strategy:protocol -> cached client
+	clientCacheMu    sync.Mutex
 	// Mutex for atomic stderr writes when flushing debug buffers
 	stderrMutex  sync.Mutex
 	cleanupFuncs []func() error
@@ -61,11 +79,42 @@ func NewProber(cfg *config.Config) *Prober {
 		config:       cfg,
 		cleanupFuncs: make([]func() error, 0),
 		clientCache:  make(map[string]*cachedClient),
-	}
-	if cfg.ResolveIP {
+		lookupAddr:   net.DefaultResolver.LookupAddr,
+		lookupHost:   net.DefaultResolver.LookupHost,
+		keywords:     parser.NewKeywordMatcher(cfg.KeywordList),
+	}
+	maxHandshakes := cfg.MaxTLSHandshakes
+	if maxHandshakes <= 0 {
+		maxHandshakes = 2 * cfg.Concurrency
+	}
+	p.handshakes = newHandshakeLimiter(maxHandshakes)
+	// One DNS cache for every dialer, including redirect target checks
+	if !cfg.NoDNSCache {
+		p.dns = newDNSCache(net.DefaultResolver.LookupIPAddr, time.Duration(cfg.DNSCacheTTL)*time.Second)
+		p.client.SetDNSCache(p.dns)
+		p.lookupHost = p.dns.LookupHost
+	}
+	// PTR lookups for hostnames need the connected IP from the tracker
+	if cfg.ResolveIP || cfg.ReverseDNSAlways {
 		p.ipTracker = NewIPTracker()
 		p.client.SetIPTracker(p.ipTracker)
 	}
+	// Capture raw response headers on plaintext connections for framing checks
+	if transport, ok := p.client.GetHTTPClient().Transport.(*http.Transport); ok {
+		transport.DialContext = framingDialContext(transport.DialContext)
+		// Redirect hops from http to https handshake on the default transport
+		transport.DialTLSContext = p.handshakes.dialTLS(transport, transport.DialContext)
+	}
+	if cfg.FirstAlive {
+		p.inputs = newInputTracker()
+		// Validated by ParseFlags; fall back to -alive-codes when unset or bad
+		p.firstAliveStatus = cfg.AliveStatus
+		if cfg.FirstAliveStatus != "" {
+			if ranges, err := output.ParseStatusRanges(cfg.FirstAliveStatus); err == nil {
+				p.firstAliveStatus = ranges
+			}
+		}
+	}
 	if cfg.TechDetect {
 		detector, err := tech.NewDetector()
 		if err != nil {
@@ -77,6 +126,20 @@ func NewProber(cfg *config.Config) *Prober {
 	return p
 }
 
+// DNSCacheStats returns the DNS cache hit and miss counts (zero when the
+// cache is disabled).
+func (p *Prober) DNSCacheStats() DNSCacheStats {
+	if p.dns == nil {
+		return DNSCacheStats{}
+	}
+	return p.dns.stats()
+}
+
+// HTTPClient returns the prober's default HTTP client.
+func (p *Prober) HTTPClient() *http.Client {
+	return p.client.GetHTTPClient()
+}
+
 // Close cleans up all resources used by the prober
 func (p *Prober) Close() error {
 	p.cleanupMutex.Lock()
@@ -129,15 +192,15 @@ func (p *Prober) getOrCreateClient(strategy TLSStrategy, protocol string) *http.
 	switch protocol {
 	case "HTTP/3":
 		client, transport := NewHTTP3Client(p.config, tlsConfig)
+		transport.Dial = p.handshakes.dialQUIC(p.client.dialQUIC)
 		httpClient = client
 		cleanup = func() { transport.Close() }
 	case "HTTP/2":
 		httpClient = NewHTTP2Client(p.config, tlsConfig)
-		if p.ipTracker != nil {
-			if transport, ok := httpClient.Transport.(*http.Transport); ok {
-				dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
-				transport.DialContext = p.ipTracker.DialContext(dialer)
-			}
+		if transport, ok := httpClient.Transport.(*http.Transport); ok {
+			dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+			transport.DialContext = p.client.dialContext(dialer)
+			transport.DialTLSContext = p.handshakes.dialTLS(transport, transport.DialContext)
 		}
 		cleanup = func() {
 			if transport, ok := httpClient.Transport.(*http.Transport); ok {
@@ -146,11 +209,10 @@ func (p *Prober) getOrCreateClient(strategy TLSStrategy, protocol string) *http.
 		}
 	default: // HTTP/1.1
 		httpClient = NewHTTP11Client(p.config, tlsConfig)
-		if p.ipTracker != nil {
-			if transport, ok := httpClient.Transport.(*http.Transport); ok {
-				dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
-				transport.DialContext = p.ipTracker.DialContext(dialer)
-			}
+		if transport, ok := httpClient.Transport.(*http.Transport); ok {
+			dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+			transport.DialContext = p.client.dialContext(dialer)
+			transport.DialTLSContext = p.handshakes.dialTLS(transport, transport.DialContext)
 		}
 		cleanup = func() {
 			if transport, ok := httpClient.Transport.(*http.Transport); ok {
@@ -159,7 +221,7 @@ func (p *Prober) getOrCreateClient(strategy TLSStrategy, protocol string) *http.
 		}
 	}
 
-	p.clientCache[key] = &cachedClient{client: httpClient, cleanup: cleanup}
+	p.clientCache[key] = &cachedClient{client: httpClient, cleanup: cleanup, strategy: strategy, protocol: protocol}
 
 	if p.config.DebugLogger != nil {
 		p.config.DebugLogger.Debug("created and cached client", "strategy", strategy.Name, "protocol", protocol)
@@ -168,89 +230,167 @@ func (p *Prober) getOrCreateClient(strategy TLSStrategy, protocol string) *http.
 	return httpClient
 }
 
+// http11Fallback returns the HTTP/1.1 client of the TLS strategy client was
+// built for, and the protocol client speaks. It returns a nil client when
+// client already is an HTTP/1.1 client or not a strategy client at all
+// (plain HTTP).
+func (p *Prober) http11Fallback(client *http.Client) (*http.Client, string) {
+	p.clientCacheMu.Lock()
+	var from *cachedClient
+	for _, cached := range p.clientCache {
+		if cached.client == client {
+			from = cached
+			break
+		}
+	}
+	p.clientCacheMu.Unlock()
+
+	if from == nil || from.protocol == "HTTP/1.1" {
+		return nil, ""
+	}
+	return p.getOrCreateClient(from.strategy, "HTTP/1.1"), from.protocol
+}
+
 // resolveCNAME resolves and caches the CNAME record for the given hostname.
-// Uses singleflight to deduplicate concurrent lookups for the same hostname
-// without blocking lookups for different hostnames.
+// Failed lookups are cached as no CNAME.
 func (p *Prober) resolveCNAME(hostname string, result *output.ProbeResult) {
 	if !p.config.DetectCNAME {
 		return
 	}
 
-	if cachedCNAME, ok := p.cnameCache.Load(hostname); ok {
-		if cname := cachedCNAME.(string); cname != "" && cname != hostname+"." {
-			result.CNAME = strings.TrimSuffix(cname, ".")
-		}
-		return
-	}
-
-	// singleflight deduplicates concurrent lookups for the same hostname
-	// while allowing different hostnames to resolve concurrently.
-	v, _, _ := p.cnameFlight.Do(hostname, func() (interface{}, error) {
-		// Double-check cache after winning the flight
-		if cachedCNAME, ok := p.cnameCache.Load(hostname); ok {
-			return cachedCNAME.(string), nil
-		}
+	cname := p.cnameCache.get(hostname, func() (string, bool) {
 		cname, err := net.LookupCNAME(hostname)
 		if err != nil {
 			cname = "" // cache empty string for failed lookups
 		}
-		// Evict if cache exceeds max size to bound memory.
-		// Eviction is serialized via cnameCacheMu so concurrent resolveCNAME callers
-		// (different hostnames) cannot run Range/Delete simultaneously and delete
-		// entries stored by others.
-		if p.cnameCacheSz.Add(1) > maxCnameCacheSize {
-			p.cnameCacheMu.Lock()
-			if p.cnameCacheSz.Load() > maxCnameCacheSize {
-				p.cnameCache.Range(func(k, _ interface{}) bool {
-					p.cnameCache.Delete(k)
-					return true
-				})
-				p.cnameCacheSz.Store(0)
-			}
-			p.cnameCacheMu.Unlock()
-		}
-		p.cnameCache.Store(hostname, cname)
-		return cname, nil
+		return cname, true
 	})
-
-	if cname := v.(string); cname != "" && cname != hostname+"." {
+	if cname != "" && cname != hostname+"." {
 		result.CNAME = strings.TrimSuffix(cname, ".")
 	}
 }
 
 // ProbeURL performs the HTTP probe for a single URL with retry support
 func (p *Prober) ProbeURL(ctx context.Context, probeURL string, originalInput string) output.ProbeResult {
-	// Try with retries
+	start := time.Now()
 	var result output.ProbeResult
+	if p.config.Coalesce {
+		result = p.probeCoalesced(ctx, probeURL, originalInput)
+	} else {
+		result = p.probeURLWithRetries(ctx, probeURL, originalInput)
+	}
+	result.RunID = p.config.RunID
+	if adaptive, ok := ctx.Value(adaptiveKey{}).(*adaptiveConcurrency); ok {
+		adaptive.record(result, time.Since(start))
+	}
+	return result
+}
+
+// probeURLWithRetries is ProbeURL without -coalesce.
+func (p *Prober) probeURLWithRetries(ctx context.Context, probeURL string, originalInput string) (result output.ProbeResult) {
+	p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+
+	// Bytes are accounted across all attempts of this probe
+	ctx, transfer := withTransferCounter(ctx)
+	// Start and end of the network phase, across attempts and hops
+	ctx, clock := withProbeClock(ctx)
+	// Headers are chosen once so every attempt and hop looks alike
+	ctx = p.withProbeHeaders(ctx, probeURL)
+
+	// Classify failures once, after retries have settled the final error
+	defer func() {
+		if result.Error != "" && result.ErrorType == "" {
+			result.ErrorType = classifyError(result.Error)
+		}
+		result.ProxyError = result.ErrorType == output.ErrorTypeProxy
+		result.BytesDownloaded = transfer.downloaded.Load()
+		result.BytesUploaded = transfer.uploaded.Load()
+		clock.stamp(&result, time.Now())
+		result.NormalizedURL = p.normalizeURL(probeURL)
+		result.Normalize()
+	}()
+
+	// Try with retries
 	var lastErr error
 
 	maxAttempts := p.config.MaxRetries + 1
 	backoff := 1 * time.Second
 
+	// Per-attempt log, reported once a retry has been made or interrupted
+	attempts := make([]output.Attempt, 0, maxAttempts)
+	retried := false
+	withAttempts := func(r output.ProbeResult) output.ProbeResult {
+		if retried {
+			r.Attempts = attempts
+			r.AttemptStatusCodes = make([]int, len(attempts))
+			for i, a := range attempts {
+				r.AttemptStatusCodes[i] = a.StatusCode
+			}
+		}
+		return r
+	}
+
 	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return withAttempts(p.cancelledResult(probeURL, originalInput))
+		}
+		var waited time.Duration
 		if attempt > 0 {
+			retried = true
 			p.config.Logger.Debug("retrying request",
 				"url", probeURL,
 				"attempt", attempt+1,
 				"backoff", backoff,
 			)
+			timer := time.NewTimer(backoff)
 			select {
-			case <-time.After(backoff):
+			case <-timer.C:
 			case <-ctx.Done():
-				result.Error = "cancelled"
-				return result
+				timer.Stop()
+				return withAttempts(p.cancelledResult(probeURL, originalInput))
 			}
+			waited = backoff
 			backoff *= 2 // Exponential backoff
 			if backoff > 30*time.Second {
 				backoff = 30 * time.Second
 			}
 		}
 
+		attemptStart := time.Now()
 		result = p.probeURLOnce(ctx, probeURL, originalInput)
+		entry := output.Attempt{
+			Attempt:    attempt + 1,
+			StatusCode: result.StatusCode,
+			DurationMs: time.Since(attemptStart).Milliseconds(),
+			BackoffMs:  waited.Milliseconds(),
+		}
 
-		// Don't retry on success or 4xx/5xx status codes (only retry network errors)
-		if result.Error == "" || result.StatusCode >= 400 {
-			return result
+		// A failure caused by shutdown is not the target's fault
+		if result.Error != "" && ctx.Err() != nil {
+			entry.ErrorType = output.ErrorTypeCancelled
+			attempts = append(attempts, entry)
+			return withAttempts(p.cancelledResult(probeURL, originalInput))
+		}
+		if result.Error != "" {
+			entry.ErrorType = result.ErrorType
+			if entry.ErrorType == "" {
+				entry.ErrorType = classifyError(result.Error)
+			}
+		}
+		attempts = append(attempts, entry)
+
+		// 429 and 503 from the initial hop are worth another try while
+		// attempts remain; the last one is reported as is
+		if retryableStatus(result) && attempt+1 < maxAttempts {
+			lastErr = nil
+			continue
+		}
+
+		// Don't retry on success, other 4xx/5xx status codes or a pin
+		// mismatch (only retry network errors)
+		if result.Error == "" || result.StatusCode >= 400 || result.ErrorType == output.ErrorTypeCertPinMismatch {
+			return withAttempts(result)
 		}
 
 		lastErr = fmt.Errorf("%s", result.Error)
@@ -258,9 +398,51 @@ func (p *Prober) ProbeURL(ctx context.Context, probeURL string, originalInput st
 
 	// All retries failed
 	if lastErr != nil {
+		// -http10: a device that can't answer HTTP/1.1 gets one bare HTTP/1.0 request
+		if p.config.HTTP10Fallback && legacyFallbackError(result.Error) {
+			if legacy := p.probeURLHTTP10(ctx, probeURL, originalInput); legacy.Error == "" {
+				return withAttempts(legacy)
+			}
+		}
 		result.Error = fmt.Sprintf("failed after %d attempts: %v", maxAttempts, lastErr)
 	}
 
+	return withAttempts(result)
+}
+
+// retryableStatus reports whether a successful attempt answered 429 or 503
+// on its initial hop. The same codes after a redirect are part of the
+// target's chain and are not retried.
+func retryableStatus(result output.ProbeResult) bool {
+	if result.Error != "" || len(result.ChainStatusCodes) > 1 {
+		return false
+	}
+	return result.StatusCode == http.StatusTooManyRequests || result.StatusCode == http.StatusServiceUnavailable
+}
+
+// cancelledResult is the result of a probe cut short by context cancellation.
+// It identifies the URL like any other result so interrupted runs can be
+// resumed, and is counted apart from real errors.
+func (p *Prober) cancelledResult(probeURL, originalInput string) output.ProbeResult {
+	result := output.ProbeResult{
+		Timestamp: time.Now().Format(time.RFC3339),
+		URL:       probeURL,
+		Input:     originalInput,
+		Method:    "GET",
+		Error:     "cancelled",
+		ErrorType: output.ErrorTypeCancelled,
+	}
+	if !strings.HasPrefix(probeURL, "http://") && !strings.HasPrefix(probeURL, "https://") {
+		probeURL = "http://" + probeURL
+	}
+	if u, err := url.Parse(probeURL); err == nil {
+		result.Scheme = u.Scheme
+		result.Host = u.Hostname()
+		result.Port = u.Port()
+		if result.Port == "" {
+			result.Port = p.defaultPorts().ForScheme(u.Scheme)
+		}
+	}
 	return result
 }
 
@@ -284,29 +466,43 @@ func (p *Prober) probeURLOnce(ctx context.Context, probeURL string, originalInpu
 		return result
 	}
 
+	// Portless URLs target the configured default port for their scheme
+	parsedURL = p.withDefaultPort(parsedURL)
+
 	// Strip default ports so Go sends the correct Host header.
 	// Servers may reject requests with "Host: example.com:443" for HTTPS
 	// or "Host: example.com:80" for HTTP.
-	probeURL = stripDefaultPort(parsedURL)
+	probeURL = stripDefaultPort(parsedURL, p.defaultPorts())
 
 	// For HTTPS URLs, use sequential TLS fallback
 	if parsedURL.Scheme == "https" {
 		if p.config.DebugLogger != nil {
 			p.config.DebugLogger.Info("probing HTTPS URL with TLS fallback", "url", probeURL)
 		}
-		return p.probeURLWithTLSFallback(ctx, probeURL, originalInput)
+		result := p.probeURLWithTLSFallback(ctx, probeURL, originalInput)
+		if p.config.SchemeFallback && result.Error != "" && ctx.Err() == nil && crossProtocolError(result.Error) {
+			return p.probeAlternateScheme(ctx, result, parsedURL, originalInput)
+		}
+		return result
 	}
 
 	// For HTTP URLs, use the standard probe method
 	if p.config.DebugLogger != nil {
 		p.config.DebugLogger.Info("probing HTTP URL", "url", probeURL)
 	}
-	return p.probeURLHTTP(ctx, probeURL, originalInput)
+	result := p.probeURLHTTP(ctx, probeURL, originalInput)
+
+	// -scheme-fallback: a TLS port answering plain HTTP is retried as https://
+	if p.config.SchemeFallback && result.Error != "" && ctx.Err() == nil && crossProtocolError(result.Error) {
+		return p.probeAlternateScheme(ctx, result, parsedURL, originalInput)
+	}
+	return result
 }
 
 // probeURLHTTP performs a standard HTTP probe (no TLS)
 func (p *Prober) probeURLHTTP(ctx context.Context, probeURL string, originalInput string) output.ProbeResult {
-	var debugBuf strings.Builder
+	debugBuf := getBuffer(&debugBufferPool)
+	defer putBuffer(&debugBufferPool, debugBuf)
 
 	result := output.ProbeResult{
 		Timestamp: time.Now().Format(time.RFC3339),
@@ -330,46 +526,48 @@ func (p *Prober) probeURLHTTP(ctx context.Context, probeURL string, originalInpu
 	p.resolveCNAME(hostname, &result)
 
 	// Apply rate limiting per host with timeout
-	limiter := p.client.GetLimiter(hostname)
+	limiter := p.client.GetLimiter(rateLimitHost(ctx, hostname))
 	waitCtx, waitCancel := context.WithTimeout(ctx, time.Duration(p.config.RateLimitTimeout)*time.Second)
 	defer waitCancel()
 
 	if err := limiter.Wait(waitCtx); err != nil {
-		if err == context.DeadlineExceeded {
-			result.Error = fmt.Sprintf("rate limit wait timeout after %ds", p.config.RateLimitTimeout)
-		} else {
-			result.Error = fmt.Sprintf("rate limit wait cancelled: %v", err)
+		if ctx.Err() != nil {
+			return p.cancelledResult(probeURL, originalInput)
 		}
+		// Either the deadline passed or the limiter knew it would
+		result.Error = fmt.Sprintf("rate limit wait timeout after %ds", p.config.RateLimitTimeout)
 		if p.config.DebugLogger != nil {
 			p.config.DebugLogger.Warn("rate limit wait failed", "url", probeURL, "error", err)
 		}
 		return result
 	}
 
-	p.debugPrintSeparator(&debugBuf)
+	p.debugPrintSeparator(debugBuf)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", probeURL, nil)
 	if err != nil {
 		result.Error = fmt.Sprintf("Failed to create request: %v", err)
 		p.logError("failed to create request", "url", probeURL, "error", err)
-		p.flushDebugBuffer(&debugBuf)
+		p.flushDebugBuffer(debugBuf, &result)
 		return result
 	}
 
-	req.Header.Set("User-Agent", useragent.Get(p.config.UserAgent, p.config.RandomUserAgent))
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req = withRequestTrace(req)
+	p.setProbeHeaders(req)
 
 	var rawRequest string
 	if p.config.StoreResponse || p.config.IncludeResponse {
 		rawRequest = formatRawRequest(req)
 	}
 
-	p.debugRequest(req, 1, &debugBuf)
+	p.debugRequest(req, 1, debugBuf)
 
 	startTime := time.Now()
-	resp, err := p.client.GetHTTPClient().Do(req)
+	markRequestStart(ctx, startTime)
+	resp, hedgeWinner, err := p.doHedgedRequest(p.client.GetHTTPClient(), req)
 	elapsed := time.Since(startTime)
+	result.Hedged = hedgeWinner != ""
+	result.HedgeWinner = hedgeWinner
 
 	if err != nil {
 		result.Error = fmt.Sprintf("Request failed: %v", err)
@@ -377,8 +575,7 @@ func (p *Prober) probeURLHTTP(ctx context.Context, probeURL string, originalInpu
 		if p.config.DebugLogger != nil {
 			p.config.DebugLogger.Error("HTTP request failed", "url", probeURL, "error", err, "duration", elapsed)
 		}
-		p.debugPrintSeparator(&debugBuf)
-		p.flushDebugBuffer(&debugBuf)
+		p.flushDebugBuffer(debugBuf, &result)
 		return result
 	}
 
@@ -386,6 +583,11 @@ func (p *Prober) probeURLHTTP(ctx context.Context, probeURL string, originalInpu
 		p.config.DebugLogger.Info("HTTP request succeeded", "url", probeURL, "status_code", resp.StatusCode, "duration", elapsed)
 	}
 
+	// The duplicate's request carries the trace of the hop that answered
+	if hedgeWinner == hedgeWinnerHedge {
+		req = resp.Request
+	}
+
 	state := &probeState{
 		probeURL:   probeURL,
 		parsedURL:  parsedURL,
@@ -394,7 +596,7 @@ func (p *Prober) probeURLHTTP(ctx context.Context, probeURL string, originalInpu
 		httpClient: p.client.GetHTTPClient(),
 		elapsed:    elapsed,
 		probeStart: startTime,
-		debugBuf:   &debugBuf,
+		debugBuf:   debugBuf,
 	}
 	p.processResponse(ctx, resp, state, &result)
 	return result
@@ -407,38 +609,70 @@ type probeState struct {
 	req        *http.Request
 	rawRequest string
 	httpClient *http.Client
-	elapsed    time.Duration    // initial request duration (for debug logging)
-	probeStart time.Time        // start of entire probe (for result.Time)
-	debugBuf   *strings.Builder
+	elapsed    time.Duration // initial request duration (for debug logging)
+	probeStart time.Time     // start of entire probe (for result.Time)
+	debugBuf   *bytes.Buffer
 	tlsState   *tls.ConnectionState // nil for plain HTTP
 }
 
 // processResponse reads the response body, follows redirects, extracts metadata,
 // and populates the ProbeResult. The caller must set protocol-specific fields
-// (Protocol, TLSConfigStrategy, TLS info) on result before calling.
+// (Protocol, TLSConfigStrategy, TLS info) on result before calling; Protocol
+// is then replaced by what the final hop negotiated.
 // The response body is consumed and closed by this method.
 func (p *Prober) processResponse(ctx context.Context, resp *http.Response, state *probeState, result *output.ProbeResult) {
-	// Read body with optional debug tee and size limit
+	// Adaptive per-host throttling on 429/503
+	result.Throttled = p.client.ReportStatus(rateLimitHost(ctx, state.parsedURL.Hostname()), resp.StatusCode, resp.Header.Get("Retry-After") != "")
+
+	// Read body with optional debug tee and a size limit chosen from the response headers
 	var bodyBuffer bytes.Buffer
 	var bodyReader io.Reader = resp.Body
 	if p.config.Debug {
 		bodyReader = io.TeeReader(resp.Body, &bodyBuffer)
 	}
-	limitedReader := io.LimitReader(bodyReader, p.config.MaxBodySize)
-	initialBody, err := io.ReadAll(limitedReader)
+	bodyLimit := p.bodyLimit(resp.Header)
+	readBuf := getBuffer(&bodyBufferPool)
+	defer putBuffer(&bodyBufferPool, readBuf)
+	// A redirect about to be followed is not the final body
+	var matchers *prefixMatchers
+	if !p.config.FollowRedirects || resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		matchers = p.earlyExit(resp.Header)
+	}
+	initialBody, truncated, partial, err := readBodyPrefixInto(readBuf, bodyReader, bodyLimit, matchers)
 	resp.Body.Close() // Explicitly close transport body (fixes connection leak)
+	markBodyDone(resp)
+	// A gzip body stops inflating at the limit; it was still cut short
+	truncated = truncated || decompressionTruncated(resp)
+	if isShortBody(resp, err) {
+		// Fewer bytes than Content-Length promised: reported as a framing anomaly
+		err = nil
+	}
 
 	if err != nil {
 		result.Error = fmt.Sprintf("Error reading body: %v", err)
 		p.logError("failed to read body", "url", state.probeURL, "error", err)
-		p.flushDebugBuffer(state.debugBuf)
+		p.flushDebugBuffer(state.debugBuf, result)
 		return
 	}
 
-	if int64(len(initialBody)) >= p.config.MaxBodySize {
+	// -proxy: the proxy's own failure page is not the target's answer
+	if msg := p.proxyErrorMessage(resp, initialBody); msg != "" {
+		result.Error = msg
+		p.flushDebugBuffer(state.debugBuf, result)
+		return
+	}
+
+	// -scheme-fallback: a TLS server's 400 for plain HTTP counts as a failed probe
+	if p.config.SchemeFallback && state.tlsState == nil && crossProtocolResponse(resp.StatusCode, initialBody) {
+		result.Error = fmt.Sprintf("Request failed: %s (status %d)", errPlainHTTPToTLS, resp.StatusCode)
+		p.flushDebugBuffer(state.debugBuf, result)
+		return
+	}
+
+	if truncated && !parser.ParseContentDisposition(resp.Header.Get("Content-Disposition")).IsAttachment() {
 		p.config.Logger.Warn("response body truncated",
 			"url", state.probeURL,
-			"max_size", p.config.MaxBodySize,
+			"max_size", bodyLimit,
 		)
 	}
 
@@ -474,17 +708,32 @@ func (p *Prober) processResponse(ctx context.Context, resp *http.Response, state
 			result.Error = fmt.Sprintf("Redirect error: %v", err)
 			result.ChainStatusCodes = statusChain
 			result.ChainHosts = hostChain
+			result.ChainOrigins = p.chainOrigins(resp, len(hostChain))
+			result.ChainMethods = redirectMethodChain(resp.Request.Method, statusChain)
+			result.ChainProtocols = chainProtocols(resp, len(hostChain))
+			result.ChainContentTypes = chainContentTypes(resp, len(hostChain))
+			result.ContentTypeChanged = contentTypeChanged(result.ChainContentTypes)
+			var schemeErr *redirectSchemeError
+			if errors.As(err, &schemeErr) {
+				result.BlockedRedirect = schemeErr.target
+			}
 			if finalResp != nil && finalResp.Body != nil {
 				finalResp.Body.Close()
 			}
 			p.logError("redirect error", "url", state.probeURL, "error", err)
-			p.debugPrintSeparator(state.debugBuf)
-			p.flushDebugBuffer(state.debugBuf)
+			p.flushDebugBuffer(state.debugBuf, result)
 			return
 		}
 		// Read final response body
-		finalResp.Body = io.NopCloser(io.LimitReader(finalResp.Body, p.config.MaxBodySize))
-		initialBody, err = io.ReadAll(finalResp.Body)
+		bodyLimit = p.bodyLimit(finalResp.Header)
+		finalBuf := getBuffer(&bodyBufferPool)
+		defer putBuffer(&bodyBufferPool, finalBuf)
+		initialBody, truncated, partial, err = readBodyPrefixInto(finalBuf, finalResp.Body, bodyLimit, p.earlyExit(finalResp.Header))
+		markBodyDone(finalResp)
+		truncated = truncated || decompressionTruncated(finalResp)
+		if isShortBody(finalResp, err) {
+			err = nil
+		}
 		if err != nil {
 			p.config.Logger.Warn("error reading final response body",
 				"url", state.probeURL,
@@ -493,28 +742,80 @@ func (p *Prober) processResponse(ctx context.Context, resp *http.Response, state
 			result.Error = fmt.Sprintf("partial body read: %v", err)
 		}
 		finalResp.Body.Close()
+		if msg := p.proxyErrorMessage(finalResp, initialBody); msg != "" {
+			result.Error = msg
+			result.ChainStatusCodes = statusChain
+			result.ChainHosts = hostChain
+			p.flushDebugBuffer(state.debugBuf, result)
+			return
+		}
 	} else {
 		finalResp = resp
 		statusChain = []int{resp.StatusCode}
 		hostChain = []string{initialHostname}
 	}
+	markBodyRead(ctx, time.Now())
+
+	result.BodyTruncated = truncated
+	result.DecompressionTruncated = decompressionTruncated(finalResp)
+	result.BodyPartiallyRead = partial
+	if p.config.Timing {
+		result.ChainTimings = chainTimings(resp)
+		if n := len(result.ChainTimings); n > 0 {
+			result.Timings = result.ChainTimings[n-1]
+		}
+	}
+	result.ClientCertUsed = clientCertUsedFor(resp) || clientCertUsedFor(finalResp)
+	result.FramingAnomalies = framingAnomalies(finalResp, len(initialBody), !truncated && !partial && bodyLimit > 0 && err == nil)
+
+	// Clock skew of the final hop (positive = server ahead)
+	if serverDate, skewMs, ok := clockSkew(finalResp); ok {
+		result.ServerDate = serverDate.UTC().Format(time.RFC3339)
+		result.ClockSkewMs = &skewMs
+	}
 
 	// Debug: print separator
 	p.debugPrintSeparator(state.debugBuf)
 
-	// Extract final URL
-	finalURL := finalResp.Request.URL.String()
+	// Extract final URL; a portless request went to the standard port
 	finalParsedURL := finalResp.Request.URL
+	finalURL := p.reportedURL(finalParsedURL)
+
+	// A redirect with nothing to follow is not final content unless asked;
+	// some servers do put meaningful HTML in such bodies
+	result.RedirectWithoutLocation = isRedirectWithoutLocation(finalResp)
+	analyzeBody := !result.RedirectWithoutLocation || p.config.Analyze3xxBody
+	analysisBody := initialBody
+	if !analyzeBody {
+		analysisBody = nil
+	}
 
-	// Calculate hashes
-	result.Hash.BodyMMH3 = hash.CalculateMMH3(initialBody)
-	result.Hash.HeaderMMH3 = hash.CalculateHeaderMMH3(finalResp.Header)
+	// Body metrics (hash, entropy, words, lines) describe the decoded body up
+	// to the read limit; bytes still carrying a Content-Encoding we don't
+	// decode get a hash but no content metrics
+	decoded := isDecodedBody(finalResp)
+
+	disabled := p.config.DisabledAnalysis
+	if !disabled.Has(config.AnalysisHeadersHash) {
+		result.Hash.HeaderMMH3 = hash.CalculateHeaderMMH3(finalResp.Header)
+	}
 
 	// Extract metadata
 	result.URL = state.probeURL
 	result.FinalURL = finalURL
 	result.ChainStatusCodes = statusChain
 	result.ChainHosts = hostChain
+	result.ChainOrigins = p.chainOrigins(resp, len(hostChain))
+	result.ChainMethods = redirectMethodChain(resp.Request.Method, statusChain)
+	result.ChainProtocols = chainProtocols(resp, len(hostChain))
+	result.ChainContentTypes = chainContentTypes(resp, len(hostChain))
+	result.ContentTypeChanged = contentTypeChanged(result.ChainContentTypes)
+	// A chain ending in an error may have passed a hop that served content
+	p.setBestHop(result, resp, initialResponseBody, chainEntries)
+	if n := len(result.ChainProtocols); n == len(statusChain) && result.ChainProtocols[n-1] != "" {
+		// Protocol describes the final hop like the other top-level fields
+		result.Protocol, _, _ = strings.Cut(result.ChainProtocols[n-1], " ")
+	}
 	result.StatusCode = finalResp.StatusCode
 	result.ContentLength = len(initialBody)
 	if !state.probeStart.IsZero() {
@@ -522,8 +823,18 @@ func (p *Prober) processResponse(ctx context.Context, resp *http.Response, state
 	} else {
 		result.Time = state.elapsed.String()
 	}
-	result.WebServer = finalResp.Header.Get("Server")
-	result.ContentType = finalResp.Header.Get("Content-Type")
+	// Repeated Server headers often name separate layers (nginx in front of
+	// PHP); report them all
+	servers := responseHeaderValues(finalResp.Header, "Server")
+	result.WebServer = joinHeaderValues(servers)
+	if len(servers) > 1 {
+		result.ServerRaw = servers
+	}
+	result.PoweredBy = joinHeaderValues(responseHeaderValues(finalResp.Header, "X-Powered-By"))
+	result.ContentType = lastHeaderValue(responseHeaderValues(finalResp.Header, "Content-Type"))
+	disposition := parser.ParseContentDisposition(finalResp.Header.Get("Content-Disposition"))
+	result.ContentDisposition = disposition.Type
+	result.AttachmentFilename = disposition.Filename
 
 	// Parse URL components
 	result.Scheme = finalParsedURL.Scheme
@@ -533,30 +844,99 @@ func (p *Prober) processResponse(ctx context.Context, resp *http.Response, state
 		result.Path = "/"
 	}
 
-	// Extract port
+	// Extract port: the one dialed, the standard port when the URL has none
 	port := finalParsedURL.Port()
 	if port == "" {
-		if result.Scheme == "https" {
-			port = "443"
+		port = parser.StandardPorts.ForScheme(result.Scheme)
+	}
+	result.Port = port
+
+	// Sniff the body when Content-Type is missing, useless or lying
+	analysisType, detectedType := parser.AnalysisContentType(result.ContentType, analysisBody)
+	result.DetectedContentType = detectedType
+	bodyType := p.bodyAnalysisType(analysisType, detectedType, analysisBody)
+	if disposition.IsAttachment() {
+		// A download is not a page: hash it, skip titles, counts and keywords
+		bodyType = attachmentAnalysisType
+	}
+
+	// Hash, entropy, title, page metadata, JSON structure, words/lines and
+	// keywords, from one analysis of the body
+	var h1 string
+	if analyzeBody {
+		analysis := parser.AnalyzeBody(analysisBody, bodyType,
+			parser.AnalysisOptions{
+				HTML:     parser.HTMLOptions{Forms: p.config.Forms, H1: p.config.TitleFallback},
+				Keywords: p.keywords,
+				NoHash:   disabled.Has(config.AnalysisHash),
+				NoMeta:   disabled.Has(config.AnalysisTitle),
+				NoCounts: disabled.Has(config.AnalysisWords),
+			})
+		h1 = analysis.Meta.H1
+		if partial {
+			result.Hash.PrefixMMH3 = analysis.MMH3
 		} else {
-			port = "80"
+			result.Hash.BodyMMH3 = analysis.MMH3
+		}
+		if decoded {
+			result.BodyEntropy = &analysis.Entropy
+		}
+		result.MatchedKeywords = analysis.Keywords
+		meta := analysis.Meta
+		result.Title, result.TitleTruncated = parser.TruncateRunes(
+			parser.SanitizeString(meta.Title), p.config.MaxTitleLength)
+		if result.Title != "" {
+			result.TitleSource = meta.TitleSource
+		}
+		result.CanonicalURL = resolveCanonicalURL(result.FinalURL, meta.CanonicalURL)
+		// Default, parking and error pages, from the untruncated title. A
+		// body cut short by -early-exit has no body_mmh3 for hash signatures
+		result.PageCategory = p.config.PageClassifier.Classify(meta.Title, analysisBody, result.Hash.BodyMMH3)
+		result.Generator = parser.SanitizeString(meta.Generator)
+		result.Lang = parser.SanitizeString(meta.Lang)
+		result.HTMLAnalysisTruncated = meta.Truncated
+		if p.config.Forms {
+			forms := parser.SummarizeForms(analysis.Forms, result.FinalURL)
+			result.FormsCount = forms.Count
+			result.LoginForm = forms.Login
+			result.FormActions = forms.Actions
+			result.CrossOriginForm = forms.CrossOrigin
+		}
+		if analysis.Text && decoded {
+			result.Words, result.Lines = analysis.Words, analysis.Lines
+		}
+		if analysis.JSON != nil {
+			result.JSONValid = &analysis.JSON.Valid
+			result.JSONTopLevelKeys = analysis.JSON.TopLevelKeys
+			// Canonical JSON hash so key order and whitespace don't register as changes
+			if p.config.JSONCanonicalHash && analysis.JSON.Valid && !disabled.Has(config.AnalysisHash) {
+				if h, ok := hash.CalculateJSONCanonicalMMH3(analysisBody); ok {
+					result.Hash.JSONCanonicalMMH3 = h
+				}
+			}
 		}
 	}
-	result.Port = port
 
-	// Extract title and count words/lines
-	bodyStr := string(initialBody)
-	result.Title = parser.ExtractTitle(bodyStr, finalResp.Header.Get("Content-Type"))
-	result.Words, result.Lines = parser.CountWordsAndLines(bodyStr)
+	// -title-fallback: untitled pages are named after their <h1>, file or host
+	if p.config.TitleFallback && result.Title == "" && !disabled.Has(config.AnalysisTitle) {
+		title, source := parser.FallbackTitle(h1, finalParsedURL)
+		result.Title, result.TitleTruncated = parser.TruncateRunes(title, p.config.MaxTitleLength)
+		if result.Title != "" {
+			result.TitleSource = source
+		}
+	}
 
 	// Resolve IP address
-	if p.ipTracker != nil {
+	if p.ipTracker != nil && p.config.ResolveIP {
 		ip := p.ipTracker.GetIP(result.Host)
 		if ip != "" {
 			result.HostIP = ip
 		}
 	}
 
+	// Reverse DNS for the connected IP
+	result.PTR = p.resolvePTR(ctx, result.Host)
+
 	// HSTS detection
 	if p.config.DetectHSTS {
 		if hstsHeader := finalResp.Header.Get("Strict-Transport-Security"); hstsHeader != "" {
@@ -565,9 +945,14 @@ func (p *Prober) processResponse(ctx context.Context, resp *http.Response, state
 		}
 	}
 
+	// Content-Security-Policy summary
+	if p.config.CSP && !disabled.Has(config.AnalysisCSP) {
+		result.CSP = AnalyzeCSP(finalResp.Header)
+	}
+
 	// Technology detection
 	if p.techDetector != nil {
-		result.Technologies = p.techDetector.Detect(finalResp.Header, initialBody)
+		result.Technologies = p.techDetector.Detect(finalResp.Header, analysisBody)
 	}
 
 	// CDN detection
@@ -577,9 +962,61 @@ func (p *Prober) processResponse(ctx context.Context, resp *http.Response, state
 		result.CDNName = cdnName
 	}
 
+	// Intermediary cache behavior
+	if p.config.CacheInfo {
+		result.Cache = cdn.InspectCache(finalResp.Header)
+	}
+
+	// WAF detection on the final response, falling back to the first hop
+	// when a block page redirected elsewhere
+	if p.config.DetectWAF {
+		isWAF, wafName, confidence := waf.DetectWAF(finalResp.StatusCode, finalResp.Header, initialBody)
+		if !isWAF && finalResp != resp {
+			isWAF, wafName, confidence = waf.DetectWAF(resp.StatusCode, resp.Header, initialResponseBody)
+		}
+		result.WAF = isWAF
+		result.WAFName = wafName
+		result.WAFConfidence = confidence
+	}
+
+	// Content-Encoding of the final response and whether it fits the content
+	if p.config.CompressionInfo {
+		result.ContentEncoding = responseContentEncoding(finalResp)
+		result.CompressionAssessment = assessCompression(result.ContentType, result.ContentEncoding,
+			uncompressedSize(finalResp, len(initialBody)), p.config.CompressionMinSize)
+	}
+
+	// Cookie security attributes across the chain
+	if p.config.CookieInfo {
+		result.Cookies = chainCookies(resp)
+	}
+
+	// Allowed methods and CORS preflight via OPTIONS on the final URL
+	if p.config.MethodsCheck || p.config.CORSCheck {
+		p.checkMethods(ctx, state.httpClient, finalResp.Request, result)
+	}
+
+	// Leaf certificate of every hop, aligned with ChainHosts
+	var hopStates []*tls.ConnectionState
+	if p.config.ExtractTLSHops {
+		hopStates = chainTLSStates(resp, len(hostChain))
+		result.ChainCertificates = chainCertificates(hopStates)
+	}
+
 	// Domain discovery from certificate SANs/CN and CSP headers
 	if p.config.DiscoverDomains {
-		result.DiscoveredDomains = DiscoverDomains(state.tlsState, finalResp.Header, state.parsedURL.Hostname())
+		cspHeaders := finalResp.Header
+		if disabled.Has(config.AnalysisCSP) {
+			cspHeaders = nil
+		}
+		if len(hopStates) > 0 {
+			result.DiscoveredDomains = DiscoverChainDomains(hopStates, cspHeaders, state.parsedURL.Hostname())
+		} else {
+			result.DiscoveredDomains = DiscoverDomains(state.tlsState, cspHeaders, state.parsedURL.Hostname())
+		}
+		if p.config.DDExpandWildcards {
+			ExpandWildcards(result.DiscoveredDomains, p.config.DDWordlistLabels, state.parsedURL.Hostname())
+		}
 	}
 
 	// Response headers in JSON output
@@ -606,7 +1043,7 @@ func (p *Prober) processResponse(ctx context.Context, resp *http.Response, state
 		}
 
 		storedData := storage.FormatStoredResponse(fullChain, finalURL)
-		storagePath, storeErr := storage.StoreResponse(p.config.StoreResponseDir, finalParsedURL, storedData)
+		storagePath, storeErr := storage.StoreResponse(p.config.StoreResponseDir, finalParsedURL, storedData, p.storeLayout())
 		if storeErr != nil {
 			p.config.Logger.Warn("failed to store response",
 				"url", state.probeURL,
@@ -614,13 +1051,43 @@ func (p *Prober) processResponse(ctx context.Context, resp *http.Response, state
 			)
 		} else {
 			result.StoredResponsePath = storagePath
-			storage.AppendToIndex(p.config.StoreResponseDir, storagePath, state.probeURL,
-				result.StatusCode, http.StatusText(result.StatusCode))
+			if p.config.StoreFinalBody {
+				bodyPath, bodyErr := storage.StoreFinalBody(p.config.StoreResponseDir, finalParsedURL, analysisType, initialBody, p.storeLayout())
+				if bodyErr != nil {
+					p.config.Logger.Warn("failed to store final body",
+						"url", state.probeURL,
+						"error", bodyErr,
+					)
+				} else {
+					result.StoredBodyPath = bodyPath
+				}
+			}
+			entry := storage.IndexEntry{
+				Path:             storagePath,
+				BodyPath:         result.StoredBodyPath,
+				URL:              state.probeURL,
+				FinalURL:         finalURL,
+				StatusCode:       result.StatusCode,
+				ChainStatusCodes: result.ChainStatusCodes,
+				ContentType:      result.ContentType,
+				BodyMMH3:         result.Hash.BodyMMH3,
+				StoredAt:         time.Now().Format(time.RFC3339Nano),
+				RunID:            p.config.RunID,
+			}
+			if started := probeStartedAt(ctx); !started.IsZero() {
+				entry.StartedAt = started.Format(time.RFC3339Nano)
+			}
+			if err := storage.AppendIndex(p.config.StoreResponseDir, entry, http.StatusText(result.StatusCode)); err != nil {
+				p.config.Logger.Warn("failed to update storage index",
+					"url", state.probeURL,
+					"error", err,
+				)
+			}
 		}
 	}
 
 	// Flush debug buffer
-	p.flushDebugBuffer(state.debugBuf)
+	p.flushDebugBuffer(state.debugBuf, result)
 
 	p.config.Logger.Debug("probe completed",
 		"url", state.probeURL,
@@ -648,36 +1115,27 @@ func (p *Prober) probeURLWithTLSFallback(ctx context.Context, probeURL string, o
 	strategies := GetOrderedStrategies(p.config.DisableHTTP3)
 
 	var allErrors []string
+	var debugs []string // -debug-on-error transcripts of the failed attempts
 
 	for i, sp := range strategies {
-		// Check context before each attempt
+		// Check context before each attempt; no new handshakes after shutdown
 		if ctx.Err() != nil {
-			return output.ProbeResult{
-				Timestamp: time.Now().Format(time.RFC3339),
-				Input:     originalInput,
-				Method:    "GET",
-				Error:     "cancelled",
-			}
+			return p.cancelledResult(probeURL, originalInput)
 		}
 
 		// Rate limit each actual connection attempt
-		limiter := p.client.GetLimiter(hostname)
+		limiter := p.client.GetLimiter(rateLimitHost(ctx, hostname))
 		waitCtx, waitCancel := context.WithTimeout(ctx, time.Duration(p.config.RateLimitTimeout)*time.Second)
 		if err := limiter.Wait(waitCtx); err != nil {
 			waitCancel()
-			if err == context.DeadlineExceeded {
-				return output.ProbeResult{
-					Timestamp: time.Now().Format(time.RFC3339),
-					Input:     originalInput,
-					Method:    "GET",
-					Error:     fmt.Sprintf("rate limit wait timeout after %ds", p.config.RateLimitTimeout),
-				}
+			if ctx.Err() != nil {
+				return p.cancelledResult(probeURL, originalInput)
 			}
 			return output.ProbeResult{
 				Timestamp: time.Now().Format(time.RFC3339),
 				Input:     originalInput,
 				Method:    "GET",
-				Error:     fmt.Sprintf("rate limit wait cancelled: %v", err),
+				Error:     fmt.Sprintf("rate limit wait timeout after %ds", p.config.RateLimitTimeout),
 			}
 		}
 		waitCancel()
@@ -692,18 +1150,54 @@ func (p *Prober) probeURLWithTLSFallback(ctx context.Context, probeURL string, o
 			)
 		}
 
-		// Create a per-attempt timeout context
-		tlsCtx, tlsCancel := context.WithTimeout(ctx, time.Duration(p.config.TLSHandshakeTimeout)*time.Second)
-		result := p.probeURLWithConfig(tlsCtx, probeURL, originalInput, sp.Strategy, sp.Protocol)
+		// HTTP/3 gets its own, shorter deadline; hosts whose HTTP/3 attempt
+		// already timed out are tried over HTTP/2 as with -disable-http3
+		protocol := sp.Protocol
+		timeout := time.Duration(p.config.TLSHandshakeTimeout) * time.Second
+		if protocol == "HTTP/3" {
+			if p.http3Skip.has(hostname) {
+				protocol = "HTTP/2"
+				if p.config.DebugLogger != nil {
+					p.config.DebugLogger.Debug("skipping HTTP/3 attempt",
+						"url", probeURL,
+						"reason", "http3_skipped_cached",
+					)
+				}
+			} else if p.config.HTTP3Timeout > 0 {
+				timeout = p.config.HTTP3Timeout
+			}
+		}
+
+		// Create a per-attempt timeout context; the client's dialer bounds
+		// concurrent handshakes (-max-tls-handshakes)
+		tlsCtx, tlsCancel := context.WithTimeout(ctx, timeout)
+		result := p.probeURLWithConfig(tlsCtx, probeURL, originalInput, sp.Strategy, protocol)
 		tlsCancel()
+		// Err stays DeadlineExceeded after cancel once the deadline hit
+		timedOut := errors.Is(tlsCtx.Err(), context.DeadlineExceeded) || classifyError(result.Error) == output.ErrorTypeTimeout
+
+		if protocol == "HTTP/3" && result.Error != "" && timedOut && ctx.Err() == nil {
+			p.http3Skip.add(hostname)
+			if p.config.DebugLogger != nil {
+				p.config.DebugLogger.Debug("HTTP/3 attempt timed out, skipping HTTP/3 for host",
+					"url", probeURL,
+					"host", hostname,
+					"timeout", timeout,
+				)
+			}
+		}
 
 		// Any HTTP response (even 4xx/5xx) means the host is reachable
 		if result.Error == "" {
 			return result
 		}
+		if ctx.Err() != nil {
+			return p.cancelledResult(probeURL, originalInput)
+		}
 
-		// Non-connection error — no point trying other strategies
-		if !isConnectionError(result.Error) {
+		// Non-connection error — no point trying other strategies. A pin
+		// mismatch is a certificate the server did present
+		if !isConnectionError(result.Error) || result.ErrorType == output.ErrorTypeCertPinMismatch {
 			if p.config.DebugLogger != nil {
 				p.config.DebugLogger.Debug("non-retryable error, stopping fallback",
 					"url", probeURL,
@@ -715,12 +1209,15 @@ func (p *Prober) probeURLWithTLSFallback(ctx context.Context, probeURL string, o
 		}
 
 		// Connection error — record and try next strategy
-		allErrors = append(allErrors, fmt.Sprintf("%s/%s: %s", sp.Strategy.Name, sp.Protocol, result.Error))
+		allErrors = append(allErrors, fmt.Sprintf("%s/%s: %s", sp.Strategy.Name, protocol, result.Error))
+		if result.Debug != "" {
+			debugs = append(debugs, result.Debug)
+		}
 		if p.config.DebugLogger != nil {
 			p.config.DebugLogger.Debug("connection error, trying next strategy",
 				"url", probeURL,
 				"strategy", sp.Strategy.Name,
-				"protocol", sp.Protocol,
+				"protocol", protocol,
 				"error", result.Error,
 			)
 		}
@@ -741,6 +1238,7 @@ func (p *Prober) probeURLWithTLSFallback(ctx context.Context, probeURL string, o
 		Input:     originalInput,
 		Method:    "GET",
 		Error:     errorMsg,
+		Debug:     truncateDebug(strings.Join(debugs, ""), p.config.MaxDebugSize),
 	}
 
 	// Check if this looks like an SNI requirement (bare IP, TLS rejection)
@@ -752,7 +1250,7 @@ func (p *Prober) probeURLWithTLSFallback(ctx context.Context, probeURL string, o
 		result.Host = hostname
 		port := parsedURL.Port()
 		if port == "" {
-			port = "443"
+			port = parser.StandardPorts.HTTPS
 		}
 		result.Port = port
 	}
@@ -828,15 +1326,30 @@ func isConnectionError(errMsg string) bool {
 	return false
 }
 
+// ProbeStrategy makes a single attempt at probeURL with one TLS strategy and
+// protocol: no fallback, retries or rate limiting, so that -doctor can check
+// each strategy on its own. The attempt gets the deadline the fallback chain
+// would give it.
+func (p *Prober) ProbeStrategy(ctx context.Context, probeURL string, sp StrategyWithProtocol) output.ProbeResult {
+	timeout := time.Duration(p.config.TLSHandshakeTimeout) * time.Second
+	if sp.Protocol == "HTTP/3" && p.config.HTTP3Timeout > 0 {
+		timeout = p.config.HTTP3Timeout
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return p.probeURLWithConfig(attemptCtx, probeURL, probeURL, sp.Strategy, sp.Protocol)
+}
+
 // probeURLWithConfig performs a single probe attempt with a specific TLS config and protocol
 func (p *Prober) probeURLWithConfig(ctx context.Context, probeURL string, originalInput string, strategy TLSStrategy, protocol string) output.ProbeResult {
-	var debugBuf strings.Builder
+	debugBuf := getBuffer(&debugBufferPool)
+	defer putBuffer(&debugBufferPool, debugBuf)
 
 	result := output.ProbeResult{
-		Timestamp:          time.Now().Format(time.RFC3339),
-		Input:              originalInput,
-		Method:             "GET",
-		Protocol:           protocol,
+		Timestamp:         time.Now().Format(time.RFC3339),
+		Input:             originalInput,
+		Method:            "GET",
+		Protocol:          protocol,
 		TLSConfigStrategy: strategy.Name,
 	}
 
@@ -857,7 +1370,7 @@ func (p *Prober) probeURLWithConfig(ctx context.Context, probeURL string, origin
 
 	p.resolveCNAME(parsedURL.Hostname(), &result)
 
-	p.debugPrintSeparator(&debugBuf)
+	p.debugPrintSeparator(debugBuf)
 
 	// Get or create cached client for this strategy+protocol
 	httpClient := p.getOrCreateClient(strategy, protocol)
@@ -865,24 +1378,26 @@ func (p *Prober) probeURLWithConfig(ctx context.Context, probeURL string, origin
 	req, err := http.NewRequestWithContext(ctx, "GET", probeURL, nil)
 	if err != nil {
 		result.Error = fmt.Sprintf("Failed to create request: %v", err)
-		p.flushDebugBuffer(&debugBuf)
+		p.flushDebugBuffer(debugBuf, &result)
 		return result
 	}
 
-	req.Header.Set("User-Agent", useragent.Get(p.config.UserAgent, p.config.RandomUserAgent))
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req = withRequestTrace(req)
+	p.setProbeHeaders(req)
 
 	var rawRequest string
 	if p.config.StoreResponse || p.config.IncludeResponse {
 		rawRequest = formatRawRequest(req)
 	}
 
-	p.debugRequest(req, 1, &debugBuf)
+	p.debugRequest(req, 1, debugBuf)
 
 	startTime := time.Now()
-	resp, err := httpClient.Do(req)
+	markRequestStart(ctx, startTime)
+	resp, hedgeWinner, err := p.doHedgedRequest(httpClient, req)
 	elapsed := time.Since(startTime)
+	result.Hedged = hedgeWinner != ""
+	result.HedgeWinner = hedgeWinner
 
 	if err != nil {
 		result.Error = fmt.Sprintf("Request failed: %v", err)
@@ -891,7 +1406,7 @@ func (p *Prober) probeURLWithConfig(ctx context.Context, probeURL string, origin
 				"url", probeURL, "strategy", strategy.Name, "protocol", protocol,
 				"error", err, "duration", elapsed)
 		}
-		p.flushDebugBuffer(&debugBuf)
+		p.flushDebugBuffer(debugBuf, &result)
 		return result
 	}
 
@@ -922,6 +1437,11 @@ func (p *Prober) probeURLWithConfig(ctx context.Context, probeURL string, origin
 		}
 	}
 
+	// The duplicate's request carries the trace of the hop that answered
+	if hedgeWinner == hedgeWinnerHedge {
+		req = resp.Request
+	}
+
 	// Process response (shared logic handles body read, redirects, metadata)
 	state := &probeState{
 		probeURL:   probeURL,
@@ -931,13 +1451,31 @@ func (p *Prober) probeURLWithConfig(ctx context.Context, probeURL string, origin
 		httpClient: httpClient,
 		elapsed:    elapsed,
 		probeStart: startTime,
-		debugBuf:   &debugBuf,
+		debugBuf:   debugBuf,
 		tlsState:   resp.TLS,
 	}
 	p.processResponse(ctx, resp, state, &result)
+	p.checkPin(parsedURL.Hostname(), resp.TLS, &result)
 	return result
 }
 
+// checkPin records whether the leaf certificate of a -pin-file host matches
+// one of its pins. A mismatch fails the probe with -pin-strict.
+func (p *Prober) checkPin(host string, state *tls.ConnectionState, result *output.ProbeResult) {
+	match, listed, fingerprint := p.pins.check(host, state)
+	if !listed {
+		return
+	}
+	result.PinMatch = &match
+	if match {
+		return
+	}
+	result.ErrorType = output.ErrorTypeCertPinMismatch
+	if p.config.PinStrict && result.Error == "" {
+		result.Error = fmt.Sprintf("certificate pin mismatch: %s presented %s", host, fingerprint)
+	}
+}
+
 // getTLSVersionString converts TLS version to string
 func getTLSVersionString(version uint16) string {
 	switch version {
@@ -955,8 +1493,15 @@ func getTLSVersionString(version uint16) string {
 }
 
 // Helper methods for debugging
-func (p *Prober) debugPrintSeparator(buf *strings.Builder) {
-	if !p.config.Debug {
+
+// debugEnabled reports whether probes build a debug transcript: always with
+// -debug, and with -debug-on-error so that failed results can carry it.
+func (p *Prober) debugEnabled() bool {
+	return p.config.Debug || p.config.DebugOnError
+}
+
+func (p *Prober) debugPrintSeparator(buf *bytes.Buffer) {
+	if !p.debugEnabled() {
 		return
 	}
 	line := "========================================\n"
@@ -965,16 +1510,15 @@ func (p *Prober) debugPrintSeparator(buf *strings.Builder) {
 	}
 }
 
-func (p *Prober) debugRequest(req *http.Request, stepNum int, buf *strings.Builder) {
-	if !p.config.Debug {
+func (p *Prober) debugRequest(req *http.Request, stepNum int, buf *bytes.Buffer) {
+	if !p.debugEnabled() || buf == nil {
 		return
 	}
 
-	var out strings.Builder
-	fmt.Fprintf(&out, "[%d] REQUEST: %s %s\n", stepNum, req.Method, req.URL.String())
+	fmt.Fprintf(buf, "[%d] REQUEST: %s %s\n", stepNum, req.Method, req.URL.String())
 
 	if len(req.Header) > 0 {
-		fmt.Fprintln(&out, "Headers:")
+		fmt.Fprintln(buf, "Headers:")
 		var keys []string
 		for k := range req.Header {
 			keys = append(keys, k)
@@ -983,27 +1527,22 @@ func (p *Prober) debugRequest(req *http.Request, stepNum int, buf *strings.Build
 
 		for _, k := range keys {
 			for _, v := range req.Header[k] {
-				fmt.Fprintf(&out, "  %s: %s\n", k, v)
+				fmt.Fprintf(buf, "  %s: %s\n", k, redactHeaderValue(k, v))
 			}
 		}
 	}
-	fmt.Fprintln(&out, "")
-
-	if buf != nil {
-		buf.WriteString(out.String())
-	}
+	fmt.Fprintln(buf, "")
 }
 
-func (p *Prober) debugResponse(resp *http.Response, body []byte, elapsed time.Duration, stepNum int, buf *strings.Builder) {
-	if !p.config.Debug {
+func (p *Prober) debugResponse(resp *http.Response, body []byte, elapsed time.Duration, stepNum int, buf *bytes.Buffer) {
+	if !p.debugEnabled() || buf == nil {
 		return
 	}
 
-	var out strings.Builder
-	fmt.Fprintf(&out, "[%d] RESPONSE: %d %s (%s)\n", stepNum, resp.StatusCode, resp.Status, elapsed)
+	fmt.Fprintf(buf, "[%d] RESPONSE: %d %s (%s)\n", stepNum, resp.StatusCode, resp.Status, elapsed)
 
 	if len(resp.Header) > 0 {
-		fmt.Fprintln(&out, "Headers:")
+		fmt.Fprintln(buf, "Headers:")
 		var keys []string
 		for k := range resp.Header {
 			keys = append(keys, k)
@@ -1012,7 +1551,7 @@ func (p *Prober) debugResponse(resp *http.Response, body []byte, elapsed time.Du
 
 		for _, k := range keys {
 			for _, v := range resp.Header[k] {
-				fmt.Fprintf(&out, "  %s: %s\n", k, v)
+				fmt.Fprintf(buf, "  %s: %s\n", k, v)
 			}
 		}
 	}
@@ -1020,37 +1559,60 @@ func (p *Prober) debugResponse(resp *http.Response, body []byte, elapsed time.Du
 	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
 		location := resp.Header.Get("Location")
 		if location != "" {
-			fmt.Fprintf(&out, "  → Redirecting to: %s\n", location)
+			fmt.Fprintf(buf, "  → Redirecting to: %s\n", location)
 		}
 	}
 
-	if len(body) > 0 {
+	// Body previews are left out of -debug-on-error transcripts
+	if len(body) > 0 && p.config.Debug {
 		preview := body
 		maxPreview := 200
 		if len(preview) > maxPreview {
 			preview = preview[:maxPreview]
 		}
-		fmt.Fprintf(&out, "Body preview (first %d bytes):\n", len(preview))
-		fmt.Fprintf(&out, "  %s\n", string(preview))
+		fmt.Fprintf(buf, "Body preview (first %d bytes):\n", len(preview))
+		fmt.Fprintf(buf, "  %s\n", string(preview))
 		if len(body) > maxPreview {
-			fmt.Fprintf(&out, "  ... (%d more bytes)\n", len(body)-maxPreview)
+			fmt.Fprintf(buf, "  ... (%d more bytes)\n", len(body)-maxPreview)
 		}
 	}
-	fmt.Fprintln(&out, "")
-
-	if buf != nil {
-		buf.WriteString(out.String())
-	}
+	fmt.Fprintln(buf, "")
 }
 
-func (p *Prober) flushDebugBuffer(buf *strings.Builder) {
-	if buf.Len() > 0 {
+// flushDebugBuffer ends a probe's debug transcript. A failed probe's
+// transcript closes with its error; with -debug it is printed to stderr and
+// with -debug-on-error it is kept in the result's debug field.
+func (p *Prober) flushDebugBuffer(buf *bytes.Buffer, result *output.ProbeResult) {
+	if buf.Len() == 0 {
+		return
+	}
+	if result.Error != "" {
+		fmt.Fprintf(buf, "ERROR: %s\n", result.Error)
+		p.debugPrintSeparator(buf)
+		if p.config.DebugOnError {
+			result.Debug = truncateDebug(buf.String(), p.config.MaxDebugSize)
+		}
+	}
+	if p.config.Debug {
 		p.stderrMutex.Lock()
-		fmt.Fprint(os.Stderr, buf.String())
+		os.Stderr.Write(buf.Bytes())
 		p.stderrMutex.Unlock()
 	}
 }
 
+// truncateDebug cuts a debug transcript to max bytes (0 = unlimited) on a
+// UTF-8 boundary and notes how much was dropped.
+func truncateDebug(transcript string, max int64) string {
+	if max <= 0 || int64(len(transcript)) <= max {
+		return transcript
+	}
+	cut := int(max)
+	for cut > 0 && !utf8.RuneStart(transcript[cut]) {
+		cut--
+	}
+	return transcript[:cut] + fmt.Sprintf("\n... (%d more bytes truncated)\n", len(transcript)-cut)
+}
+
 func (p *Prober) logError(msg string, args ...interface{}) {
 	if !p.config.Silent {
 		p.config.Logger.Error(msg, args...)
@@ -1084,7 +1646,7 @@ func formatRawRequest(req *http.Request) string {
 	sort.Strings(keys)
 	for _, k := range keys {
 		for _, v := range req.Header[k] {
-			builder.WriteString(fmt.Sprintf("%s: %s\n", k, v))
+			builder.WriteString(fmt.Sprintf("%s: %s\n", k, redactHeaderValue(k, v)))
 		}
 	}
 
@@ -1122,18 +1684,173 @@ func normalizeHeaders(headers http.Header) map[string]string {
 		// Convert to lowercase and replace hyphens with underscores
 		key := strings.ReplaceAll(strings.ToLower(k), "-", "_")
 		// Join multiple values with comma
-		normalized[key] = strings.Join(v, ", ")
+		normalized[key] = redactHeaderValue(k, strings.Join(v, ", "))
 	}
 	return normalized
 }
 
+// defaultPorts returns the configured per-scheme default ports.
+func (p *Prober) defaultPorts() parser.DefaultPorts {
+	return parser.DefaultPorts{HTTP: p.config.DefaultHTTPPort, HTTPS: p.config.DefaultHTTPSPort}
+}
+
+// storeLayout returns the configured -sr storage layout.
+func (p *Prober) storeLayout() storage.Layout {
+	return storage.Layout{Mode: p.config.StoreLayout, ShardDepth: p.config.StoreShardDepth}
+}
+
+// resolveCanonicalURL resolves a canonical link href against the final URL.
+// Unparseable hrefs and non-HTTP schemes are dropped.
+func resolveCanonicalURL(finalURL string, href string) string {
+	if href == "" {
+		return ""
+	}
+	ref, err := url.Parse(parser.SanitizeString(href))
+	if err != nil {
+		return ""
+	}
+	if base, err := url.Parse(finalURL); err == nil {
+		ref = base.ResolveReference(ref)
+	}
+	if ref.Scheme != "http" && ref.Scheme != "https" {
+		return ""
+	}
+	return ref.String()
+}
+
+// normalizeURL returns the canonical form of probeURL used as a join key
+// across runs (see parser.NormalizeURL). Scheme-less URLs are treated as http,
+// as in probeURLOnce.
+func (p *Prober) normalizeURL(probeURL string) string {
+	if !strings.HasPrefix(probeURL, "http://") && !strings.HasPrefix(probeURL, "https://") {
+		probeURL = "http://" + probeURL
+	}
+	return parser.NormalizeURL(probeURL, p.defaultPorts())
+}
+
+// withDefaultPort makes the configured default port explicit on a portless URL
+// when it differs from the protocol-standard port, so requests reach the
+// configured port. Other URLs are returned unchanged; u is never modified.
+func (p *Prober) withDefaultPort(u *url.URL) *url.URL {
+	if u.Port() != "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return u
+	}
+	port := p.defaultPorts().ForScheme(u.Scheme)
+	if port == "" || port == parser.StandardPorts.ForScheme(u.Scheme) {
+		return u
+	}
+	withPort := *u
+	withPort.Host = net.JoinHostPort(u.Hostname(), port)
+	return &withPort
+}
+
+// attachmentAnalysisType is the content type download bodies are analyzed
+// as: parser.AnalyzeBody gives binary types the hash and entropy only.
+const attachmentAnalysisType = "application/octet-stream"
+
+// bodyAnalysisType picks the content type parser.AnalyzeBody dispatches on.
+// A sniffed type is left to the parser to decide from the body; with a JSON
+// -accept, text bodies shaped like a JSON object or array are analyzed as
+// JSON whatever their label, since APIs often mislabel responses.
+func (p *Prober) bodyAnalysisType(analysisType, detectedType string, body []byte) string {
+	if p.acceptsJSON() && parser.IsTextContentType(analysisType) {
+		trimmed := bytes.TrimLeft(body, " \t\r\n")
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			return "application/json"
+		}
+	}
+	if detectedType != "" && parser.IsTextContentType(analysisType) {
+		// Sniffing only recognizes HTML by its leading tag; let the parser decide
+		return ""
+	}
+	return analysisType
+}
+
+// defaultAccept is the browser-like Accept header sent without -accept
+const defaultAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8"
+
+// acceptHeader returns the Accept header value for probe requests
+func (p *Prober) acceptHeader() string {
+	if p.config.Accept != "" {
+		return p.config.Accept
+	}
+	return defaultAccept
+}
+
+// acceptsJSON reports whether -accept asks for JSON
+func (p *Prober) acceptsJSON() bool {
+	return strings.Contains(strings.ToLower(p.config.Accept), "json")
+}
+
+// bodyLimit selects the body read limit once response headers are known:
+// non-text content types use the binary limit, everything else MaxBodySize.
+// Downloads (Content-Disposition: attachment) are read no further than
+// AttachmentReadLimit, enough to hash and sniff them.
+func (p *Prober) bodyLimit(header http.Header) int64 {
+	limit := p.config.MaxBodySize
+	if !parser.IsTextContentType(header.Get("Content-Type")) {
+		limit = p.config.BinaryBodyLimit()
+	}
+	if parser.ParseContentDisposition(header.Get("Content-Disposition")).IsAttachment() {
+		limit = min(limit, p.config.AttachmentReadLimit)
+	}
+	return limit
+}
+
+// readLimitedBody reads at most limit bytes from r and reports whether the
+// body was longer than that. A limit of 0 skips the body entirely (headers-only
+// analysis); such bodies are not reported as truncated.
+func readLimitedBody(r io.Reader, limit int64) ([]byte, bool, error) {
+	if limit <= 0 {
+		return nil, false, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(body)) > limit {
+		return body[:limit], true, err
+	}
+	return body, false, err
+}
+
+// readLimitedBodyInto is readLimitedBody reading into buf, typically one
+// from bodyBufferPool. The returned body aliases buf.
+func readLimitedBodyInto(buf *bytes.Buffer, r io.Reader, limit int64) ([]byte, bool, error) {
+	if limit <= 0 {
+		return nil, false, nil
+	}
+	_, err := buf.ReadFrom(io.LimitReader(r, limit+1))
+	body := buf.Bytes()
+	if int64(len(body)) > limit {
+		return body[:limit], true, err
+	}
+	return body, false, err
+}
+
+// reportedURL returns the URL of a request as results report it. Requests
+// without a port went to the standard port; that port is written out when
+// -default-http(s)-port would have readers assume another one.
+func (p *Prober) reportedURL(u *url.URL) string {
+	if u.Port() != "" || p.defaultPorts().ForScheme(u.Scheme) == parser.StandardPorts.ForScheme(u.Scheme) {
+		return u.String()
+	}
+	standard := parser.StandardPorts.ForScheme(u.Scheme)
+	if standard == "" {
+		return u.String()
+	}
+	withPort := *u
+	withPort.Host = net.JoinHostPort(u.Hostname(), standard)
+	return withPort.String()
+}
+
 // stripDefaultPort returns the URL string with the port removed when it matches
-// the scheme's default (80 for HTTP, 443 for HTTPS). This prevents Go's net/http
-// from sending "Host: example.com:443" which some servers reject.
+// the scheme's standard port (80 for HTTP, 443 for HTTPS). This prevents Go's
+// net/http from sending "Host: example.com:443" which some servers reject.
+// The port is kept when defaults assume another port for the scheme, since
+// readers of the portless URL would take it for that one.
 // The original *url.URL is not modified.
-func stripDefaultPort(u *url.URL) string {
+func stripDefaultPort(u *url.URL, defaults parser.DefaultPorts) string {
 	port := u.Port()
-	if (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+	standard := parser.StandardPorts.ForScheme(u.Scheme)
+	if port != "" && port == standard && defaults.ForScheme(u.Scheme) == standard {
 		// Work on a shallow copy to avoid mutating the caller's URL
 		copy := *u
 		copy.Host = u.Hostname()
@@ -1141,4 +1858,3 @@ func stripDefaultPort(u *url.URL) string {
 	}
 	return u.String()
 }
-
@@ -1,16 +1,24 @@
 package probe
 
 import (
+	"compress/gzip"
 	"context"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/parser"
+	"probeHTTP/internal/storage"
 )
 
 func TestIsSNIRequired(t *testing.T) {
@@ -80,8 +88,8 @@ func TestIsSNIRequired(t *testing.T) {
 			want: false,
 		},
 		{
-			name:     "bare IP with no errors",
-			hostname: "10.0.0.1",
+			name:      "bare IP with no errors",
+			hostname:  "10.0.0.1",
 			allErrors: []string{},
 			want:      false,
 		},
@@ -191,16 +199,20 @@ func TestGetTLSVersionString(t *testing.T) {
 }
 
 func TestStripDefaultPort(t *testing.T) {
+	custom := parser.DefaultPorts{HTTP: "8081", HTTPS: "443"}
 	tests := []struct {
-		name string
-		raw  string
-		want string
+		name     string
+		raw      string
+		defaults parser.DefaultPorts
+		want     string
 	}{
-		{"https default port", "https://host:443/path", "https://host/path"},
-		{"http default port", "http://host:80/path", "http://host/path"},
-		{"https custom port", "https://host:8443/path", "https://host:8443/path"},
-		{"http custom port", "http://host:8080/path", "http://host:8080/path"},
-		{"https no port", "https://host/path", "https://host/path"},
+		{"https default port", "https://host:443/path", parser.StandardPorts, "https://host/path"},
+		{"http default port", "http://host:80/path", parser.StandardPorts, "http://host/path"},
+		{"https custom port", "https://host:8443/path", parser.StandardPorts, "https://host:8443/path"},
+		{"http custom port", "http://host:8080/path", parser.StandardPorts, "http://host:8080/path"},
+		{"https no port", "https://host/path", parser.StandardPorts, "https://host/path"},
+		{"http standard port under a custom default", "http://host:80/path", custom, "http://host:80/path"},
+		{"https standard port, standard default", "https://host:443/path", custom, "https://host/path"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -208,7 +220,7 @@ func TestStripDefaultPort(t *testing.T) {
 			if err != nil {
 				t.Fatalf("url.Parse(%q): %v", tt.raw, err)
 			}
-			got := stripDefaultPort(u)
+			got := stripDefaultPort(u, tt.defaults)
 			if got != tt.want {
 				t.Errorf("stripDefaultPort(%q) = %q, want %q", tt.raw, got, tt.want)
 			}
@@ -517,6 +529,52 @@ func TestProbeURL_WithStoreResponse(t *testing.T) {
 	if result.StatusCode != 200 {
 		t.Errorf("StatusCode = %d, want 200", result.StatusCode)
 	}
+
+	entries, err := storage.LoadIndex(cfg.StoreResponseDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("LoadIndex = %d entries, %v; want 1", len(entries), err)
+	}
+	entry := entries[0]
+	if entry.URL != server.URL || entry.StatusCode != 200 || entry.BodyMMH3 != result.Hash.BodyMMH3 ||
+		entry.StartedAt != result.StartedAt || entry.StoredAt == "" {
+		t.Errorf("index entry = %+v", entry)
+	}
+	if filepath.Join(cfg.StoreResponseDir, entry.Path) != result.StoredResponsePath {
+		t.Errorf("index path %q does not name %q", entry.Path, result.StoredResponsePath)
+	}
+}
+
+func TestProbeURL_StoreLayoutFlat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>stored</html>"))
+	}))
+	defer server.Close()
+
+	storeDir := t.TempDir()
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.StoreResponse = true
+		cfg.StoreFinalBody = true
+		cfg.StoreResponseDir = storeDir
+		cfg.StoreLayout = storage.LayoutFlat
+		cfg.StoreShardDepth = 1
+	})
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+
+	hash := storage.GenerateFilename(server.URL)
+	if want := filepath.Join(storeDir, hash[:2], hash+".txt"); result.StoredResponsePath != want {
+		t.Errorf("stored_response_path = %q, want %q", result.StoredResponsePath, want)
+	}
+	if want := filepath.Join(storeDir, hash[:2], hash+".body.html"); result.StoredBodyPath != want {
+		t.Errorf("stored_body_path = %q, want %q", result.StoredBodyPath, want)
+	}
+	entries, err := storage.LoadIndex(storeDir)
+	if err != nil || len(entries) != 1 || entries[0].URL != server.URL || entries[0].Path != filepath.Join(hash[:2], hash+".txt") {
+		t.Errorf("LoadIndex = %+v, %v", entries, err)
+	}
 }
 
 func TestProbeURL_HTTPS_Success(t *testing.T) {
@@ -579,6 +637,47 @@ func TestProbeURL_HTTPS_WithExtractTLS(t *testing.T) {
 	}
 }
 
+func TestProbeURL_ExtractTLSPerHop(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, plain.URL+"/final", http.StatusFound)
+	}))
+	defer secure.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.InsecureSkipVerify = true
+	cfg.AllowPrivateIPs = true
+	cfg.ExtractTLS = true
+	cfg.ExtractTLSHops = true
+	cfg.DiscoverDomains = true
+	cfg.Timeout = 5
+	cfg.TLSHandshakeTimeout = 5
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), secure.URL, secure.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if len(result.ChainCertificates) != len(result.ChainHosts) || len(result.ChainHosts) != 2 {
+		t.Fatalf("chain_certificates = %d entries for hosts %v, want 2", len(result.ChainCertificates), result.ChainHosts)
+	}
+	if result.ChainCertificates[0] == nil {
+		t.Error("HTTPS hop should carry its leaf certificate")
+	}
+	if result.ChainCertificates[1] != nil {
+		t.Error("plain-HTTP hop should have a null certificate")
+	}
+	if result.DiscoveredDomains == nil || result.DiscoveredDomains.DomainSources["example.com"] != "san" {
+		t.Errorf("discovered domains = %+v, want example.com from the first hop's SANs", result.DiscoveredDomains)
+	}
+}
+
 func TestProbeURL_ContextCancelled(t *testing.T) {
 	cfg := config.New()
 	cfg.Silent = true
@@ -768,3 +867,920 @@ func TestProcessURLs_ProcessesURLs(t *testing.T) {
 		t.Errorf("expected 2 results, got %d", count)
 	}
 }
+
+func TestProbeURL_WithKeywords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/image" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("login"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><form>Username <input type=password></form> Login</body></html>"))
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.KeywordList = []string{"login", "password", "dashboard"}
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if strings.Join(result.MatchedKeywords, ",") != "login,password" {
+		t.Errorf("MatchedKeywords = %v, want [login password]", result.MatchedKeywords)
+	}
+
+	result = prober.ProbeURL(context.Background(), server.URL+"/image", server.URL)
+	if result.MatchedKeywords != nil {
+		t.Errorf("non-text content should not be matched, got %v", result.MatchedKeywords)
+	}
+}
+
+func TestProbeURL_SniffsContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/lying":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("%PDF-1.4\n<title>not a title</title>\n"))
+		default:
+			// Suppress net/http's own sniffing so the header is really missing
+			w.Header()["Content-Type"] = nil
+			w.Write([]byte("<html><head><title>Router Admin</title></head><body>hello there</body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if result.ContentType != "" {
+		t.Errorf("ContentType = %q, want empty", result.ContentType)
+	}
+	if result.DetectedContentType != "text/html; charset=utf-8" {
+		t.Errorf("DetectedContentType = %q, want text/html", result.DetectedContentType)
+	}
+	if result.Title != "Router Admin" {
+		t.Errorf("Title = %q, want %q", result.Title, "Router Admin")
+	}
+
+	result = prober.ProbeURL(context.Background(), server.URL+"/lying", server.URL)
+	if result.ContentType != "text/html" {
+		t.Errorf("ContentType = %q, want header value text/html", result.ContentType)
+	}
+	if result.DetectedContentType != "" {
+		t.Errorf("DetectedContentType = %q, want empty when header is present", result.DetectedContentType)
+	}
+	if result.Title != "" || result.Words != 0 {
+		t.Errorf("binary body should not be analyzed, got title %q words %d", result.Title, result.Words)
+	}
+}
+
+func TestProbeURL_BodySizeLimits(t *testing.T) {
+	textBody := strings.Repeat("a", 2048)
+	binaryBody := strings.Repeat("\x00", 2048)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/archive.zip" {
+			w.Header().Set("Content-Type", "application/zip")
+			w.Write([]byte(binaryBody))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(textBody))
+	}))
+	defer server.Close()
+
+	newProber := func(limit, binaryLimit int64) *Prober {
+		cfg := config.New()
+		cfg.Silent = true
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		cfg.Timeout = 5
+		cfg.MaxBodySize = limit
+		cfg.MaxBodySizeBinary = binaryLimit
+		return NewProber(cfg)
+	}
+
+	prober := newProber(4096, 100)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.ContentLength != 2048 || result.BodyTruncated {
+		t.Errorf("text body: length %d truncated %v, want 2048 false", result.ContentLength, result.BodyTruncated)
+	}
+	result = prober.ProbeURL(context.Background(), server.URL+"/archive.zip", server.URL)
+	if result.ContentLength != 100 || !result.BodyTruncated {
+		t.Errorf("binary body: length %d truncated %v, want 100 true", result.ContentLength, result.BodyTruncated)
+	}
+
+	headersOnly := newProber(0, 0)
+	defer headersOnly.Close()
+
+	result = headersOnly.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if result.StatusCode != 200 || result.ContentLength != 0 || result.BodyTruncated {
+		t.Errorf("headers only: status %d length %d truncated %v, want 200 0 false",
+			result.StatusCode, result.ContentLength, result.BodyTruncated)
+	}
+}
+
+func TestProbeURL_SanitizesTitle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Server", "weird\tserver")
+		w.Write([]byte("<html><head><title>Line one\nLine\r\n two " + strings.Repeat("x", 50) + "</title></head></html>"))
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.MaxTitleLength = 20
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if result.Title != "Line one Line two x…" || !result.TitleTruncated {
+		t.Errorf("Title = %q (truncated %v), want %q (truncated true)", result.Title, result.TitleTruncated, "Line one Line two x…")
+	}
+	if result.WebServer != "weird server" {
+		t.Errorf("WebServer = %q, want %q", result.WebServer, "weird server")
+	}
+}
+
+func TestProbeURL_JSONCanonicalHash(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"status":"ok","count":2}`))
+		case "/b":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte("{\n  \"count\": 2,\n  \"status\": \"ok\"\n}"))
+		case "/sniffed":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(` {"count":2,"status":"ok"}`))
+		case "/broken":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"status":`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.JSONCanonicalHash = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	a := prober.ProbeURL(context.Background(), server.URL+"/a", server.URL)
+	b := prober.ProbeURL(context.Background(), server.URL+"/b", server.URL)
+	sniffed := prober.ProbeURL(context.Background(), server.URL+"/sniffed", server.URL)
+	if a.Hash.JSONCanonicalMMH3 == "" {
+		t.Fatal("JSONCanonicalMMH3 not set for JSON response")
+	}
+	if a.Hash.JSONCanonicalMMH3 != b.Hash.JSONCanonicalMMH3 || a.Hash.JSONCanonicalMMH3 != sniffed.Hash.JSONCanonicalMMH3 {
+		t.Errorf("canonical hashes differ: %s / %s / %s", a.Hash.JSONCanonicalMMH3, b.Hash.JSONCanonicalMMH3, sniffed.Hash.JSONCanonicalMMH3)
+	}
+	if a.Hash.BodyMMH3 == b.Hash.BodyMMH3 {
+		t.Error("plain body hashes should differ")
+	}
+
+	broken := prober.ProbeURL(context.Background(), server.URL+"/broken", server.URL)
+	if broken.Error != "" || broken.Hash.JSONCanonicalMMH3 != "" {
+		t.Errorf("invalid JSON: error %q canonical %q, want silent omission", broken.Error, broken.Hash.JSONCanonicalMMH3)
+	}
+}
+
+func TestProbeURL_AcceptJSON(t *testing.T) {
+	var gotAccept atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAccept.Store(r.Header.Get("Accept"))
+		// Mislabeled API response
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`{"name":"orders-api","version":"2.1","healthy":true}`))
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.Accept = "application/json"
+	cfg.JSONCanonicalHash = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if got := gotAccept.Load(); got != "application/json" {
+		t.Errorf("Accept sent = %v, want application/json", got)
+	}
+	if result.JSONValid == nil || !*result.JSONValid {
+		t.Errorf("json_valid = %v, want true", result.JSONValid)
+	}
+	if want := []string{"name", "version", "healthy"}; !reflect.DeepEqual(result.JSONTopLevelKeys, want) {
+		t.Errorf("json_top_level_keys = %v, want %v", result.JSONTopLevelKeys, want)
+	}
+	if result.Title != "orders-api" || result.TitleSource != "json" {
+		t.Errorf("title = %q (%s), want orders-api from json", result.Title, result.TitleSource)
+	}
+	if result.Hash.JSONCanonicalMMH3 == "" {
+		t.Error("JSONCanonicalMMH3 should be set for JSON analyzed bodies")
+	}
+}
+
+func TestProbeURL_DefaultAcceptLeavesHTMLLabelAlone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`{"name":"orders-api"}`))
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.JSONValid != nil || result.JSONTopLevelKeys != nil {
+		t.Errorf("json fields = %v / %v, want none for a text/html label without -accept", result.JSONValid, result.JSONTopLevelKeys)
+	}
+}
+
+func TestProbeURL_DetectsWAF(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blocked":
+			w.Header().Set("Cf-Ray", "7d1c2b3a4e5f6a7b-FRA")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("<title>Attention Required! | Cloudflare</title>"))
+		case "/plain":
+			w.Write([]byte("<title>ok</title>"))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.DetectWAF = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	blocked := prober.ProbeURL(context.Background(), server.URL+"/blocked", server.URL)
+	if !blocked.WAF || blocked.WAFName != "Cloudflare" || blocked.WAFConfidence != "high" {
+		t.Errorf("blocked: got waf=%v name=%q confidence=%q", blocked.WAF, blocked.WAFName, blocked.WAFConfidence)
+	}
+	plain := prober.ProbeURL(context.Background(), server.URL+"/plain", server.URL)
+	if plain.WAF || plain.WAFName != "" {
+		t.Errorf("plain: unexpected waf detection %q", plain.WAFName)
+	}
+}
+
+func TestProbeURL_CacheInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cached" {
+			w.Header().Set("Cf-Cache-Status", "HIT")
+			w.Header().Set("Age", "42")
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.CacheInfo = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	cached := prober.ProbeURL(context.Background(), server.URL+"/cached", server.URL)
+	if cached.Cache == nil || cached.Cache.Status != "hit" || cached.Cache.AgeSeconds == nil || *cached.Cache.AgeSeconds != 42 {
+		t.Errorf("cached: got cache %+v, want hit with age 42", cached.Cache)
+	}
+	plain := prober.ProbeURL(context.Background(), server.URL+"/plain", server.URL)
+	if plain.Cache != nil {
+		t.Errorf("plain: cache = %+v, want omitted", plain.Cache)
+	}
+}
+
+func TestProbeURL_BodyMetricsOnDecodedBody(t *testing.T) {
+	body := "one two three\nfour five\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(body))
+			zw.Close()
+		case "/br":
+			// Not decoded by the prober; the bytes are still encoded
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte(body))
+		default:
+			w.Write([]byte(body))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	plain := prober.ProbeURL(context.Background(), server.URL+"/plain", server.URL)
+	gz := prober.ProbeURL(context.Background(), server.URL+"/gzip", server.URL)
+	for name, r := range map[string]output.ProbeResult{"plain": plain, "gzip": gz} {
+		if r.Words != 5 || r.Lines != 3 {
+			t.Errorf("%s: words/lines = %d/%d, want 5/3", name, r.Words, r.Lines)
+		}
+		if r.BodyEntropy == nil || *r.BodyEntropy <= 0 || *r.BodyEntropy > 8 {
+			t.Errorf("%s: body_entropy = %v, want a value in (0, 8]", name, r.BodyEntropy)
+		}
+	}
+	if gz.Hash.BodyMMH3 != plain.Hash.BodyMMH3 || *gz.BodyEntropy != *plain.BodyEntropy {
+		t.Error("gzip and plain bodies should yield identical metrics")
+	}
+
+	br := prober.ProbeURL(context.Background(), server.URL+"/br", server.URL)
+	if br.Words != 0 || br.Lines != 0 || br.BodyEntropy != nil {
+		t.Errorf("br: got words=%d lines=%d entropy=%v, want no content metrics", br.Words, br.Lines, br.BodyEntropy)
+	}
+	if br.Hash.BodyMMH3 == "" {
+		t.Error("br: body hash should still be set")
+	}
+}
+
+func TestProbeURL_NormalizedURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if result.NormalizedURL != server.URL+"/" {
+		t.Errorf("NormalizedURL = %q, want %q", result.NormalizedURL, server.URL+"/")
+	}
+	if result.URL != server.URL {
+		t.Errorf("URL = %q, the requested form should be kept", result.URL)
+	}
+}
+
+func TestProbeURL_TitleSource(t *testing.T) {
+	bodies := map[string][2]string{
+		"/page": {"text/html", `<html><head><meta property="og:title" content="Shop"></head></html>`},
+		"/api":  {"application/json", `{"name":"inventory-service"}`},
+		"/feed": {"application/rss+xml", `<rss><channel><title>News</title></channel></rss>`},
+		"/doc":  {"application/pdf", "%PDF-1.4\n<</Title (Datasheet)>>\n"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := bodies[r.URL.Path]
+		w.Header().Set("Content-Type", b[0])
+		io.WriteString(w, b[1])
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	tests := []struct {
+		path, wantTitle, wantSource string
+	}{
+		{"/page", "Shop", "og"},
+		{"/api", "inventory-service", "json"},
+		{"/feed", "News", "xml"},
+		{"/doc", "Datasheet", "pdf"},
+	}
+	for _, tt := range tests {
+		result := prober.ProbeURL(context.Background(), server.URL+tt.path, server.URL+tt.path)
+		if result.Error != "" {
+			t.Fatalf("%s: ProbeURL error: %s", tt.path, result.Error)
+		}
+		if result.Title != tt.wantTitle || result.TitleSource != tt.wantSource {
+			t.Errorf("%s: title = (%q, %q), want (%q, %q)", tt.path, result.Title, result.TitleSource, tt.wantTitle, tt.wantSource)
+		}
+	}
+}
+
+func TestProbeURL_PageCategory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/default":
+			io.WriteString(w, "<html><head><title>Welcome to nginx!</title></head></html>")
+		case "/parked":
+			io.WriteString(w, `<html><head><title>example.com</title><script src="//www.sedoparking.com/frmpark/x.js"></script></head></html>`)
+		default:
+			io.WriteString(w, "<html><head><title>Inventory</title></head></html>")
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.MaxTitleLength = 5 // matched against the full title
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/default", "default-nginx"},
+		{"/parked", "parked"},
+		{"/app", ""},
+	}
+	for _, tt := range tests {
+		result := prober.ProbeURL(context.Background(), server.URL+tt.path, server.URL+tt.path)
+		if result.Error != "" {
+			t.Fatalf("%s: ProbeURL error: %s", tt.path, result.Error)
+		}
+		if result.PageCategory != tt.want {
+			t.Errorf("%s: page_category = %q, want %q", tt.path, result.PageCategory, tt.want)
+		}
+	}
+}
+
+func TestProbeURL_PageMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/blog/post?id=1", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html lang="fr"><head><title>Post</title>
+			<link rel="canonical" href="../articles/post-1">
+			<meta name="generator" content="Hugo 0.121.1"></head></html>`)
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.AllowPrivateIPs = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if want := server.URL + "/articles/post-1"; result.CanonicalURL != want {
+		t.Errorf("CanonicalURL = %q, want %q (resolved against the final URL)", result.CanonicalURL, want)
+	}
+	if result.Generator != "Hugo 0.121.1" {
+		t.Errorf("Generator = %q, want %q", result.Generator, "Hugo 0.121.1")
+	}
+	if result.Lang != "fr" {
+		t.Errorf("Lang = %q, want %q", result.Lang, "fr")
+	}
+}
+
+func TestResolveCanonicalURL(t *testing.T) {
+	tests := []struct {
+		final, href, want string
+	}{
+		{"https://example.com/a/b", "", ""},
+		{"https://example.com/a/b", "https://www.example.com/", "https://www.example.com/"},
+		{"https://example.com/a/b", "/c", "https://example.com/c"},
+		{"https://example.com/a/b", "//cdn.example.com/x", "https://cdn.example.com/x"},
+		{"https://example.com/a/b", "javascript:void(0)", ""},
+		{"https://example.com/a/b", "http://[::1", ""},
+	}
+	for _, tt := range tests {
+		if got := resolveCanonicalURL(tt.final, tt.href); got != tt.want {
+			t.Errorf("resolveCanonicalURL(%q, %q) = %q, want %q", tt.final, tt.href, got, tt.want)
+		}
+	}
+}
+
+func TestProbeURL_ConfiguredDefaultPort(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.DefaultHTTPPort = u.Port()
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	// Portless URL must reach the configured default port
+	portless := "http://" + u.Hostname() + "/"
+	result := prober.ProbeURL(context.Background(), portless, portless)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if result.Port != u.Port() {
+		t.Errorf("Port = %q, want %q", result.Port, u.Port())
+	}
+}
+
+// newTestProber returns a quiet prober for tests against local servers,
+// closed when the test ends. configure adjusts the config before the prober
+// is built.
+func newTestProber(t *testing.T, configure func(cfg *config.Config)) *Prober {
+	t.Helper()
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.AllowPrivateIPs = true
+	configure(cfg)
+	prober := NewProber(cfg)
+	t.Cleanup(func() { prober.Close() })
+	return prober
+}
+
+// flakyServer drops the connection of the first failures requests, then answers 200.
+func flakyServer(t *testing.T, failures int32) *httptest.Server {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProbeURL_AttemptsLog(t *testing.T) {
+	server := flakyServer(t, 2)
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.MaxRetries = 3
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" || result.StatusCode != http.StatusOK {
+		t.Fatalf("got error %q status %d, want success on the third attempt", result.Error, result.StatusCode)
+	}
+	if len(result.Attempts) != 3 {
+		t.Fatalf("attempts = %+v, want 3 entries", result.Attempts)
+	}
+	for i, a := range result.Attempts[:2] {
+		if a.Attempt != i+1 || a.ErrorType == "" || a.StatusCode != 0 {
+			t.Errorf("attempt %d = %+v, want a failure without status", i+1, a)
+		}
+	}
+	last := result.Attempts[2]
+	if last.Attempt != 3 || last.ErrorType != "" || last.StatusCode != http.StatusOK {
+		t.Errorf("attempt 3 = %+v, want status 200 without error", last)
+	}
+	if result.Attempts[0].BackoffMs != 0 || result.Attempts[1].BackoffMs != 1000 || last.BackoffMs != 2000 {
+		t.Errorf("backoffs = %d/%d/%d ms, want 0/1000/2000",
+			result.Attempts[0].BackoffMs, result.Attempts[1].BackoffMs, last.BackoffMs)
+	}
+}
+
+func TestProbeURL_AttemptsOmittedWithoutRetry(t *testing.T) {
+	server := flakyServer(t, 0)
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.MaxRetries = 3
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Attempts != nil {
+		t.Errorf("attempts = %+v, want none when the first attempt succeeds", result.Attempts)
+	}
+}
+
+// statusServer answers with codes in order, then 200 for every later request.
+func statusServer(t *testing.T, codes ...int) *httptest.Server {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := int(calls.Add(1)); n <= len(codes) {
+			w.WriteHeader(codes[n-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProbeURL_AttemptStatusCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		server     func(t *testing.T) *httptest.Server
+		maxRetries int
+		wantStatus int
+		wantCodes  []int
+	}{
+		{
+			name:       "success after 503",
+			server:     func(t *testing.T) *httptest.Server { return statusServer(t, http.StatusServiceUnavailable) },
+			maxRetries: 2,
+			wantStatus: http.StatusOK,
+			wantCodes:  []int{http.StatusServiceUnavailable, http.StatusOK},
+		},
+		{
+			name:       "success after network error",
+			server:     func(t *testing.T) *httptest.Server { return flakyServer(t, 1) },
+			maxRetries: 2,
+			wantStatus: http.StatusOK,
+			wantCodes:  []int{0, http.StatusOK},
+		},
+		{
+			name: "exhausted by 429",
+			server: func(t *testing.T) *httptest.Server {
+				return statusServer(t, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests)
+			},
+			maxRetries: 1,
+			wantStatus: http.StatusTooManyRequests,
+			wantCodes:  []int{http.StatusTooManyRequests, http.StatusTooManyRequests},
+		},
+		{
+			name:       "other 5xx not retried",
+			server:     func(t *testing.T) *httptest.Server { return statusServer(t, http.StatusInternalServerError) },
+			maxRetries: 2,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tt.server(t)
+
+			cfg := config.New()
+			cfg.Silent = true
+			cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			cfg.Timeout = 5
+			cfg.MaxRetries = tt.maxRetries
+			prober := NewProber(cfg)
+			defer prober.Close()
+
+			result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+			if result.Error != "" || result.StatusCode != tt.wantStatus {
+				t.Fatalf("got error %q status %d, want status %d", result.Error, result.StatusCode, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(result.AttemptStatusCodes, tt.wantCodes) {
+				t.Errorf("attempt_status_codes = %v, want %v", result.AttemptStatusCodes, tt.wantCodes)
+			}
+			// The chain only describes the attempt that was reported
+			if !reflect.DeepEqual(result.ChainStatusCodes, []int{tt.wantStatus}) {
+				t.Errorf("chain_status_codes = %v, want [%d]", result.ChainStatusCodes, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestProbeURL_RedirectedServiceUnavailableNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/down", http.StatusFound)
+			return
+		}
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.MaxRetries = 2
+	cfg.FollowRedirects = true
+	cfg.AllowPrivateIPs = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL+"/", server.URL+"/")
+	if result.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", result.StatusCode)
+	}
+	if calls.Load() != 1 || result.AttemptStatusCodes != nil {
+		t.Errorf("redirect target hit %d times, attempt_status_codes = %v; want a single attempt",
+			calls.Load(), result.AttemptStatusCodes)
+	}
+}
+
+func TestProbeURL_AttemptsTruncatedOnCancel(t *testing.T) {
+	server := flakyServer(t, 10)
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.MaxRetries = 3
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	// Cancel during the 1s backoff after the first failure
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	result := prober.ProbeURL(ctx, server.URL, server.URL)
+	if result.ErrorType != output.ErrorTypeCancelled {
+		t.Fatalf("error type = %q, want cancelled", result.ErrorType)
+	}
+	if len(result.Attempts) != 1 || result.Attempts[0].ErrorType == "" {
+		t.Errorf("attempts = %+v, want only the completed failed attempt", result.Attempts)
+	}
+}
+
+func TestProbeURL_TitleFallback(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panel", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><h1>NAS <em>Storage</em> Manager</h1></body></html>`))
+	})
+	mux.HandleFunc("/titled", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Real Title</title></head><body><h1>Heading</h1></body></html>`))
+	})
+	mux.HandleFunc("/downloads/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte{0x00, 0x01, 0x02, 0xff})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path       string
+		wantTitle  string
+		wantSource string
+	}{
+		{"/panel", "NAS Storage Manager", parser.TitleSourceH1},
+		{"/titled", "Real Title", parser.TitleSourceHTML},
+		{"/downloads/firmware%20v2.1.bin", "firmware v2.1.bin", parser.TitleSourcePath},
+		{"/", "127.0.0.1", parser.TitleSourceHost},
+	}
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.TitleFallback = true
+	})
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := prober.ProbeURL(context.Background(), server.URL+tt.path, server.URL)
+			if result.Error != "" {
+				t.Fatalf("ProbeURL error: %s", result.Error)
+			}
+			if result.Title != tt.wantTitle || result.TitleSource != tt.wantSource {
+				t.Errorf("title = (%q, %q), want (%q, %q)", result.Title, result.TitleSource, tt.wantTitle, tt.wantSource)
+			}
+		})
+	}
+
+	// Without the flag untitled pages stay untitled
+	plain := newHeaderProber(t, func(cfg *config.Config) {})
+	if result := plain.ProbeURL(context.Background(), server.URL+"/panel", server.URL); result.Title != "" || result.TitleSource != "" {
+		t.Errorf("title = (%q, %q) without -title-fallback, want none", result.Title, result.TitleSource)
+	}
+}
+
+func TestProbeURL_TitleFallbackTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<h1>` + strings.Repeat("long ", 20) + `</h1>`))
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.TitleFallback = true
+		cfg.MaxTitleLength = 10
+	})
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if !result.TitleTruncated || len([]rune(result.Title)) > 10 || result.TitleSource != parser.TitleSourceH1 {
+		t.Errorf("title = %q (truncated %v, source %q), want an h1 title cut to 10 runes", result.Title, result.TitleTruncated, result.TitleSource)
+	}
+}
+
+func TestProbeURL_DeeplyNestedHTML(t *testing.T) {
+	body := "<html><body>" + strings.Repeat("<div>", 100_000) + "<title>Deep</title></body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.Forms = true })
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if !result.HTMLAnalysisTruncated || result.Title != "Deep" {
+		t.Errorf("html_analysis_truncated = %v, title %q", result.HTMLAnalysisTruncated, result.Title)
+	}
+}
+
+func TestProbeURL_Attachment(t *testing.T) {
+	page := "<html><head><title>Quarterly Report</title></head><body>" + strings.Repeat("row ", 100_000) + "</body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/download":
+			w.Header().Set("Content-Disposition", `attachment; filename="report.html"; filename*=UTF-8''Q3%20%E2%82%AC%20report.html`)
+		case "/inline":
+			w.Header().Set("Content-Disposition", "inline")
+		case "/malformed":
+			w.Header().Set("Content-Disposition", `attachment; filename="report.html`)
+		}
+		io.WriteString(w, page)
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.KeywordList = []string{"quarterly"} })
+	tests := []struct {
+		path        string
+		disposition string
+		filename    string
+		attachment  bool
+	}{
+		{"/download", "attachment", "Q3 € report.html", true},
+		{"/inline", "inline", "", false},
+		{"/malformed", `attachment; filename="report.html`, "", false},
+	}
+	for _, tt := range tests {
+		result := prober.ProbeURL(context.Background(), server.URL+tt.path, server.URL+tt.path)
+		if result.Error != "" {
+			t.Fatalf("%s: unexpected error: %s", tt.path, result.Error)
+		}
+		if result.ContentDisposition != tt.disposition || result.AttachmentFilename != tt.filename {
+			t.Errorf("%s: content_disposition %q, attachment_filename %q; want %q, %q",
+				tt.path, result.ContentDisposition, result.AttachmentFilename, tt.disposition, tt.filename)
+		}
+		if result.Hash.BodyMMH3 == "" {
+			t.Errorf("%s: body not hashed", tt.path)
+		}
+		if tt.attachment {
+			if result.ContentLength != 64*1024 || !result.BodyTruncated {
+				t.Errorf("%s: read %d bytes (truncated %v), want the 64 KiB attachment limit", tt.path, result.ContentLength, result.BodyTruncated)
+			}
+			if result.Title != "" || result.Words != 0 || len(result.MatchedKeywords) != 0 {
+				t.Errorf("%s: download analyzed as a page: title %q, words %d, keywords %v", tt.path, result.Title, result.Words, result.MatchedKeywords)
+			}
+		} else if result.ContentLength != len(page) || result.Title != "Quarterly Report" {
+			t.Errorf("%s: read %d bytes, title %q; want the whole page analyzed", tt.path, result.ContentLength, result.Title)
+		}
+	}
+}
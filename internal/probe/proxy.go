@@ -0,0 +1,94 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"probeHTTP/internal/config"
+)
+
+// applyProxy sends the requests of transport through the -proxy, if any.
+// A refused CONNECT fails with a proxy error instead of Go's bare status text.
+func applyProxy(transport *http.Transport, cfg *config.Config) {
+	if cfg.ProxyURL == nil {
+		return
+	}
+	transport.Proxy = http.ProxyURL(cfg.ProxyURL)
+	transport.OnProxyConnectResponse = func(ctx context.Context, proxyURL *url.URL, connectReq *http.Request, connectRes *http.Response) error {
+		if connectRes.StatusCode != http.StatusOK {
+			return fmt.Errorf("proxy error: CONNECT %s answered %s", connectReq.Host, connectRes.Status)
+		}
+		return nil
+	}
+}
+
+// proxyErrorMessage returns the error of a response the -proxy generated
+// itself rather than relayed from the target, or "" for a target response.
+// A 407 or a Proxy-Authenticate header always comes from the proxy; a 502
+// or 504 does when its Via names the proxy or it carries
+// -proxy-error-signature. A target's own 502 relayed by a proxy that adds
+// Via is indistinguishable from the proxy's and is reported as a proxy error.
+func (p *Prober) proxyErrorMessage(resp *http.Response, body []byte) string {
+	proxyURL := p.config.ProxyURL
+	if proxyURL == nil {
+		return ""
+	}
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired || resp.Header.Get("Proxy-Authenticate") != "":
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout:
+		if !viaNamesProxy(resp.Header, proxyURL) && !hasProxySignature(resp, body, p.config.ProxyErrorSignature) {
+			return ""
+		}
+	default:
+		return ""
+	}
+	return fmt.Sprintf("proxy error: %s from %s", resp.Status, proxyURL.Host)
+}
+
+// viaNamesProxy reports whether a Via entry was added by the proxy: its
+// received-by is the proxy's host, with or without the port.
+func viaNamesProxy(header http.Header, proxyURL *url.URL) bool {
+	for _, value := range header.Values("Via") {
+		for _, entry := range strings.Split(value, ",") {
+			// "1.1 proxy.corp:3128 (squid/6.1)"
+			fields := strings.Fields(entry)
+			if len(fields) < 2 {
+				continue
+			}
+			receivedBy := fields[1]
+			if host, _, err := net.SplitHostPort(receivedBy); err == nil {
+				receivedBy = host
+			}
+			if strings.EqualFold(receivedBy, proxyURL.Hostname()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasProxySignature reports whether signature appears in the body read or
+// in a header value of resp.
+func hasProxySignature(resp *http.Response, body []byte, signature string) bool {
+	if signature == "" {
+		return false
+	}
+	if strings.Contains(string(body), signature) {
+		return true
+	}
+	for name, values := range resp.Header {
+		if strings.Contains(name, signature) {
+			return true
+		}
+		for _, value := range values {
+			if strings.Contains(value, signature) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+// forwardingProxy is a forward proxy relaying plain HTTP requests to their
+// target. reply, when it returns true, answers a request in the proxy's
+// own name instead.
+func forwardingProxy(t *testing.T, reply func(w http.ResponseWriter, r *http.Request) bool) *httptest.Server {
+	t.Helper()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reply(w, r) {
+			return
+		}
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for name, values := range resp.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func newProxyProber(t *testing.T, proxy *httptest.Server, signature string, retries int) *Prober {
+	t.Helper()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTestProber(t, func(cfg *config.Config) {
+		cfg.Proxy = proxy.URL
+		cfg.ProxyURL = proxyURL
+		cfg.ProxyErrorSignature = signature
+		cfg.DisableHTTP3 = true
+		cfg.InsecureSkipVerify = true
+		cfg.MaxRetries = retries
+	})
+}
+
+func TestProbeURL_Proxy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gateway" {
+			// The target's own 502 passes through the proxy unmarked
+			http.Error(w, "upstream down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("<html><head><title>target</title></head></html>"))
+	}))
+	defer target.Close()
+	targetHost := strings.TrimPrefix(target.URL, "http://")
+
+	tests := []struct {
+		name      string
+		path      string
+		signature string
+		reply     func(w http.ResponseWriter, r *http.Request) bool
+		wantProxy bool
+		wantCode  int
+	}{
+		{
+			name:     "relayed response",
+			path:     "/",
+			reply:    func(w http.ResponseWriter, r *http.Request) bool { return false },
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "relayed target 502",
+			path:     "/gateway",
+			reply:    func(w http.ResponseWriter, r *http.Request) bool { return false },
+			wantCode: http.StatusBadGateway,
+		},
+		{
+			name: "proxy authentication required",
+			path: "/",
+			reply: func(w http.ResponseWriter, r *http.Request) bool {
+				w.Header().Set("Proxy-Authenticate", `Basic realm="corp"`)
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return true
+			},
+			wantProxy: true,
+		},
+		{
+			name: "502 with the proxy in Via",
+			path: "/",
+			reply: func(w http.ResponseWriter, r *http.Request) bool {
+				w.Header().Set("Via", "1.1 127.0.0.1:3128 (squid/6.1)")
+				w.WriteHeader(http.StatusBadGateway)
+				return true
+			},
+			wantProxy: true,
+		},
+		{
+			name:      "504 with the error page signature",
+			path:      "/",
+			signature: "Generated by corp-proxy",
+			reply: func(w http.ResponseWriter, r *http.Request) bool {
+				w.WriteHeader(http.StatusGatewayTimeout)
+				w.Write([]byte("<html><body>Gateway Timeout<hr>Generated by corp-proxy</body></html>"))
+				return true
+			},
+			wantProxy: true,
+		},
+		{
+			name:      "504 without Via or signature",
+			path:      "/",
+			signature: "Generated by corp-proxy",
+			reply: func(w http.ResponseWriter, r *http.Request) bool {
+				w.WriteHeader(http.StatusGatewayTimeout)
+				return true
+			},
+			wantCode: http.StatusGatewayTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var proxied atomic.Int32
+			proxy := forwardingProxy(t, func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Host == targetHost {
+					proxied.Add(1)
+				}
+				return tt.reply(w, r)
+			})
+			prober := newProxyProber(t, proxy, tt.signature, 0)
+
+			result := prober.ProbeURL(context.Background(), target.URL+tt.path, target.URL)
+			if proxied.Load() == 0 {
+				t.Fatal("request did not go through the proxy")
+			}
+			if tt.wantProxy {
+				if result.ErrorType != output.ErrorTypeProxy || !result.ProxyError || result.StatusCode != 0 {
+					t.Errorf("error_type %q, proxy_error %v, status %d; want a proxy_error without target status (error %q)",
+						result.ErrorType, result.ProxyError, result.StatusCode, result.Error)
+				}
+				return
+			}
+			if result.Error != "" || result.ProxyError || result.StatusCode != tt.wantCode {
+				t.Errorf("error %q, proxy_error %v, status %d; want the target's %d",
+					result.Error, result.ProxyError, result.StatusCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestProbeURL_ProxyRefusesConnect(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	proxy := forwardingProxy(t, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodConnect {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="corp"`)
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return true
+		}
+		return false
+	})
+	prober := newProxyProber(t, proxy, "", 0)
+
+	result := prober.ProbeURL(context.Background(), target.URL, target.URL)
+	if result.ErrorType != output.ErrorTypeProxy || !result.ProxyError {
+		t.Fatalf("error_type %q, proxy_error %v; want proxy_error (error %q)", result.ErrorType, result.ProxyError, result.Error)
+	}
+	if !strings.Contains(result.Error, "407") {
+		t.Errorf("error %q does not name the CONNECT status", result.Error)
+	}
+}
+
+// Proxy errors are retried like network errors.
+func TestProbeURL_ProxyErrorRetried(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	var requests atomic.Int32
+	proxy := forwardingProxy(t, func(w http.ResponseWriter, r *http.Request) bool {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Via", "1.1 127.0.0.1")
+			w.WriteHeader(http.StatusBadGateway)
+			return true
+		}
+		return false
+	})
+	prober := newProxyProber(t, proxy, "", 1)
+
+	result := prober.ProbeURL(context.Background(), target.URL, target.URL)
+	if result.Error != "" || result.ProxyError || result.StatusCode != http.StatusOK {
+		t.Fatalf("error %q, status %d; want the retry's 200", result.Error, result.StatusCode)
+	}
+	if len(result.Attempts) != 2 || result.Attempts[0].ErrorType != output.ErrorTypeProxy {
+		t.Errorf("attempts = %+v, want a proxy_error then a success", result.Attempts)
+	}
+}
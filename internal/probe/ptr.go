@@ -0,0 +1,60 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// ptrLookupTimeout bounds each reverse DNS lookup independently of the probe
+// timeout so slow PTR servers don't hold up probing.
+const ptrLookupTimeout = 2 * time.Second
+
+// resolvePTR returns the PTR names for the connected IP of host.
+// IP literal hosts are looked up directly; hostnames are only looked up when
+// PTRAlways is set, using the IP recorded by the IP tracker.
+// Results (including failures) are cached per IP for the lifetime of the prober.
+func (p *Prober) resolvePTR(ctx context.Context, host string) []string {
+	if !p.config.ReverseDNS {
+		return nil
+	}
+
+	ip := host
+	if net.ParseIP(host) == nil {
+		if !p.config.ReverseDNSAlways || p.ipTracker == nil {
+			return nil
+		}
+		ip = p.ipTracker.GetIP(host)
+		if ip == "" {
+			return nil
+		}
+	}
+
+	return p.ptrCache.get(ip, func() ([]string, bool) {
+		lookupCtx, cancel := context.WithTimeout(ctx, ptrLookupTimeout)
+		defer cancel()
+
+		names, err := p.lookupAddr(lookupCtx, ip)
+		if err != nil {
+			if p.config.DebugLogger != nil {
+				p.config.DebugLogger.Debug("PTR lookup failed", "ip", ip, "error", err)
+			}
+			// Don't cache lookups cut short by probe cancellation
+			if ctx.Err() != nil {
+				return nil, false
+			}
+			names = nil
+		}
+		ptrs := make([]string, 0, len(names))
+		for _, name := range names {
+			if name = strings.TrimSuffix(name, "."); name != "" {
+				ptrs = append(ptrs, name)
+			}
+		}
+		if len(ptrs) == 0 {
+			ptrs = nil
+		}
+		return ptrs, true
+	})
+}
@@ -0,0 +1,119 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func newPTRTestProber(t *testing.T, always bool, lookups *atomic.Int32) *Prober {
+	t.Helper()
+	prober := newTestProber(t, func(cfg *config.Config) {
+		cfg.ReverseDNS = true
+		cfg.ReverseDNSAlways = always
+	})
+	prober.lookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		lookups.Add(1)
+		if addr == "127.0.0.1" {
+			return []string{"localhost."}, nil
+		}
+		return nil, errors.New("no PTR record")
+	}
+	return prober
+}
+
+func TestResolvePTR_IPLiteral(t *testing.T) {
+	var lookups atomic.Int32
+	prober := newPTRTestProber(t, false, &lookups)
+
+	got := prober.resolvePTR(context.Background(), "127.0.0.1")
+	if len(got) != 1 || got[0] != "localhost" {
+		t.Errorf("resolvePTR = %v, want [localhost]", got)
+	}
+}
+
+func TestResolvePTR_HostnameSkippedWithoutAlways(t *testing.T) {
+	var lookups atomic.Int32
+	prober := newPTRTestProber(t, false, &lookups)
+
+	if got := prober.resolvePTR(context.Background(), "example.com"); got != nil {
+		t.Errorf("resolvePTR for hostname = %v, want nil", got)
+	}
+	if lookups.Load() != 0 {
+		t.Errorf("lookups = %d, want 0", lookups.Load())
+	}
+}
+
+func TestResolvePTR_CachesPerIP(t *testing.T) {
+	var lookups atomic.Int32
+	prober := newPTRTestProber(t, false, &lookups)
+
+	for i := 0; i < 5; i++ {
+		prober.resolvePTR(context.Background(), "127.0.0.1")
+		prober.resolvePTR(context.Background(), "192.0.2.1")
+	}
+	if lookups.Load() != 2 {
+		t.Errorf("lookups = %d, want 2 (one per IP)", lookups.Load())
+	}
+	if got := prober.resolvePTR(context.Background(), "192.0.2.1"); got != nil {
+		t.Errorf("failed lookup should yield nil, got %v", got)
+	}
+}
+
+func TestResolvePTR_Disabled(t *testing.T) {
+	var lookups atomic.Int32
+	prober := newPTRTestProber(t, false, &lookups)
+	prober.config.ReverseDNS = false
+
+	if got := prober.resolvePTR(context.Background(), "127.0.0.1"); got != nil {
+		t.Errorf("resolvePTR with -ptr off = %v, want nil", got)
+	}
+}
+
+func TestProbeURL_WithReverseDNS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var lookups atomic.Int32
+	prober := newPTRTestProber(t, false, &lookups)
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if len(result.PTR) != 1 || result.PTR[0] != "localhost" {
+		t.Errorf("PTR = %v, want [localhost]", result.PTR)
+	}
+	if result.HostIP != "" {
+		t.Errorf("HostIP = %q, want empty without -rip", result.HostIP)
+	}
+}
+
+func TestProbeURL_WithReverseDNSAlways_Hostname(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var lookups atomic.Int32
+	prober := newPTRTestProber(t, true, &lookups)
+
+	// Hostname input is looked up via the IP tracker's connected address
+	hostURL := fmt.Sprintf("http://localhost:%d", server.Listener.Addr().(*net.TCPAddr).Port)
+	result := prober.ProbeURL(context.Background(), hostURL, hostURL)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if len(result.PTR) != 1 || result.PTR[0] != "localhost" {
+		t.Errorf("PTR = %v, want [localhost]", result.PTR)
+	}
+}
@@ -0,0 +1,306 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"probeHTTP/internal/output"
+	"probeHTTP/internal/parser"
+)
+
+// maxRawHeaderBytes bounds the response head read on the raw HTTP/1.0 path,
+// on top of the body limit.
+const maxRawHeaderBytes = 64 * 1024
+
+// legacyFallbackError reports whether a failed plain-HTTP probe looks like a
+// device that cannot cope with HTTP/1.1: a malformed response or a
+// connection closed before a response arrived.
+func legacyFallbackError(errMsg string) bool {
+	lower := strings.ToLower(errMsg)
+	for _, fragment := range []string{"malformed http", "transport connection broken", "eof"} {
+		if strings.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeURLHTTP10 probes an http:// URL over a raw TCP connection with a bare
+// "GET <path> HTTP/1.0" request and parses the reply leniently. It is the
+// -http10 fallback for embedded devices that choke on HTTP/1.1 requests and
+// is never used for HTTPS.
+func (p *Prober) probeURLHTTP10(ctx context.Context, probeURL string, originalInput string) output.ProbeResult {
+	debugBuf := getBuffer(&debugBufferPool)
+	defer putBuffer(&debugBufferPool, debugBuf)
+
+	result := output.ProbeResult{
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Input:       originalInput,
+		Method:      "GET",
+		Protocol:    "HTTP/1.0",
+		LegacyParse: true,
+	}
+
+	if !strings.HasPrefix(probeURL, "http://") && !strings.HasPrefix(probeURL, "https://") {
+		probeURL = "http://" + probeURL
+	}
+	parsedURL, err := url.Parse(probeURL)
+	if err != nil {
+		result.Error = fmt.Sprintf("Invalid URL: %v", err)
+		return result
+	}
+	if parsedURL.Scheme != "http" {
+		result.Error = "HTTP/1.0 fallback is only used for http:// URLs"
+		return result
+	}
+	// Same target as the HTTP/1.1 attempts: the default port for a portless
+	// input, else the port given; the standard port only when that is left
+	dialed := p.withDefaultPort(parsedURL)
+	addr := dialed.Host
+	if dialed.Port() == "" {
+		addr = net.JoinHostPort(dialed.Hostname(), parser.StandardPorts.HTTP)
+	}
+	probeURL = stripDefaultPort(dialed, p.defaultPorts())
+	if parsedURL, err = url.Parse(probeURL); err != nil {
+		result.Error = fmt.Sprintf("Invalid URL: %v", err)
+		return result
+	}
+
+	limiter := p.client.GetLimiter(rateLimitHost(ctx, parsedURL.Hostname()))
+	waitCtx, waitCancel := context.WithTimeout(ctx, time.Duration(p.config.RateLimitTimeout)*time.Second)
+	defer waitCancel()
+	if err := limiter.Wait(waitCtx); err != nil {
+		result.Error = fmt.Sprintf("rate limit wait failed: %v", err)
+		return result
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", probeURL, nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create request: %v", err)
+		return result
+	}
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/1.0", 1, 0
+	// Host is optional in HTTP/1.0 but name-based virtual hosts need it
+	rawRequest := "GET " + parsedURL.RequestURI() + " HTTP/1.0\r\nHost: " + req.Host + "\r\n\r\n"
+
+	timeout := time.Duration(p.config.Timeout) * time.Second
+	startTime := time.Now()
+	markRequestStart(ctx, startTime)
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := p.client.dialContext(dialer)(ctx, "tcp", addr)
+	if err != nil {
+		result.Error = fmt.Sprintf("Request failed: %v", err)
+		return result
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	conn.SetDeadline(startTime.Add(timeout))
+
+	if _, err := io.WriteString(conn, rawRequest); err != nil {
+		result.Error = fmt.Sprintf("Request failed: %v", err)
+		return result
+	}
+	// HTTP/1.0 responses end when the server closes the connection; a device
+	// that keeps it open is cut off by the deadline with what it sent so far
+	data, err := p.readRawResponse(conn)
+	elapsed := time.Since(startTime)
+	p.transfer.record(ctx, parsedURL.Hostname(), int64(len(data)), int64(len(rawRequest)))
+	var netErr net.Error
+	if err != nil && (len(data) == 0 || !errors.As(err, &netErr) || !netErr.Timeout()) {
+		result.Error = fmt.Sprintf("Request failed: %v", err)
+		return result
+	}
+
+	raw, err := parseLenientResponse(data)
+	if err != nil {
+		result.Error = fmt.Sprintf("Request failed: %v", err)
+		return result
+	}
+
+	resp := &http.Response{
+		Status:        raw.status,
+		StatusCode:    raw.statusCode,
+		Proto:         raw.proto,
+		ProtoMajor:    1,
+		Header:        raw.header,
+		Body:          io.NopCloser(bytes.NewReader(raw.body)),
+		ContentLength: -1,
+		Request:       req,
+	}
+	if n, err := strconv.ParseInt(raw.header.Get("Content-Length"), 10, 64); err == nil && n >= 0 {
+		resp.ContentLength = n
+	}
+
+	if p.config.DebugLogger != nil {
+		p.config.DebugLogger.Info("HTTP/1.0 fallback succeeded", "url", probeURL, "status_code", raw.statusCode, "duration", elapsed)
+	}
+
+	state := &probeState{
+		probeURL:   probeURL,
+		parsedURL:  parsedURL,
+		req:        req,
+		rawRequest: rawRequest,
+		httpClient: p.client.GetHTTPClient(),
+		elapsed:    elapsed,
+		probeStart: startTime,
+		debugBuf:   debugBuf,
+	}
+	p.processResponse(ctx, resp, state, &result)
+	return result
+}
+
+// readRawResponse reads a raw response: the head, up to maxRawHeaderBytes,
+// then no more body than p.bodyLimit allows for the head's headers, plus one
+// byte so a cut body reads as truncated like on the other paths. A closed
+// connection ends the response and is not an error.
+func (p *Prober) readRawResponse(r io.Reader) ([]byte, error) {
+	var data []byte
+	buf := make([]byte, 4096)
+	end := -1
+	var err error
+	for end < 0 && len(data) < maxRawHeaderBytes && err == nil {
+		var n int
+		n, err = r.Read(buf)
+		data = append(data, buf[:n]...)
+		end = rawHeadEnd(data)
+	}
+	if end < 0 {
+		end = len(data)
+	}
+	var header http.Header
+	if head, perr := parseLenientResponse(data[:end]); perr == nil {
+		header = head.header
+	}
+	limit := p.bodyLimit(header)
+	if err == nil {
+		var rest []byte
+		rest, err = io.ReadAll(io.LimitReader(r, limit+1-int64(len(data)-end)))
+		data = append(data, rest...)
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if int64(len(data)-end) > limit+1 {
+		data = data[:end+int(limit)+1]
+	}
+	return data, err
+}
+
+// rawHeadEnd returns where the body starts in a raw response: after the blank
+// line ending the head, or at the first byte of a headless HTTP/0.9 reply.
+// It returns -1 while the head is incomplete.
+func rawHeadEnd(data []byte) int {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	offset := len(data) - len(trimmed)
+	if len(trimmed) > 0 && trimmed[0] == '<' {
+		return offset
+	}
+	crlf, lf := bytes.Index(trimmed, []byte("\r\n\r\n")), bytes.Index(trimmed, []byte("\n\n"))
+	switch {
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		return offset + crlf + 4
+	case lf >= 0:
+		return offset + lf + 2
+	}
+	return -1
+}
+
+// rawResponse is a leniently parsed HTTP response.
+type rawResponse struct {
+	proto      string
+	statusCode int
+	status     string
+	header     http.Header
+	body       []byte
+}
+
+// parseLenientResponse parses a raw HTTP response as sent by old or broken
+// servers. It tolerates a missing reason phrase, LF-only line endings,
+// header lines without a space after the colon, junk header lines, a
+// missing header block and lowercase protocol names. A bare HTML body
+// without any status line is treated as an HTTP/0.9 "200" response.
+func parseLenientResponse(data []byte) (*rawResponse, error) {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+
+	if !bytes.HasPrefix(bytes.ToUpper(data[:min(len(data), 5)]), []byte("HTTP/")) {
+		if data[0] == '<' {
+			return &rawResponse{
+				proto:      "HTTP/0.9",
+				statusCode: http.StatusOK,
+				status:     "200 OK",
+				header:     make(http.Header),
+				body:       data,
+			}, nil
+		}
+		return nil, fmt.Errorf("malformed HTTP response: no status line")
+	}
+
+	statusLine, rest := nextLine(data)
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("malformed HTTP status line %q", statusLine)
+	}
+	code, err := strconv.Atoi(fields[1])
+	if err != nil || code < 100 || code > 999 {
+		return nil, fmt.Errorf("malformed HTTP status code %q", fields[1])
+	}
+	reason := strings.Join(fields[2:], " ")
+	if reason == "" {
+		reason = http.StatusText(code)
+	}
+
+	resp := &rawResponse{
+		proto:      strings.ToUpper(fields[0]),
+		statusCode: code,
+		status:     strings.TrimSpace(strconv.Itoa(code) + " " + reason),
+		header:     make(http.Header),
+	}
+
+	var lastKey string
+	for len(rest) > 0 {
+		var line string
+		line, rest = nextLine(rest)
+		if line == "" {
+			break // end of headers
+		}
+		if (line[0] == ' ' || line[0] == '\t') && lastKey != "" {
+			// Obsolete line folding: continue the previous header value
+			values := resp.header[lastKey]
+			values[len(values)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			lastKey = ""
+			continue // junk line
+		}
+		lastKey = textproto.CanonicalMIMEHeaderKey(key)
+		resp.header[lastKey] = append(resp.header[lastKey], strings.TrimSpace(value))
+	}
+	resp.body = rest
+	return resp, nil
+}
+
+// nextLine splits data at the first LF, dropping a trailing CR from the line.
+func nextLine(data []byte) (string, []byte) {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return strings.TrimSuffix(string(data), "\r"), nil
+	}
+	return strings.TrimSuffix(string(data[:i]), "\r"), data[i+1:]
+}
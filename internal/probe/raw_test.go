@@ -0,0 +1,304 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func TestParseLenientResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantProto  string
+		wantStatus int
+		wantReason string
+		wantHeader map[string]string
+		wantBody   string
+	}{
+		{
+			name:       "well formed",
+			raw:        "HTTP/1.0 200 OK\r\nServer: Boa/0.94.13\r\nContent-Type: text/html\r\n\r\n<html>ok</html>",
+			wantProto:  "HTTP/1.0",
+			wantStatus: 200,
+			wantReason: "200 OK",
+			wantHeader: map[string]string{"Server": "Boa/0.94.13", "Content-Type": "text/html"},
+			wantBody:   "<html>ok</html>",
+		},
+		{
+			name:       "missing reason phrase",
+			raw:        "HTTP/1.0 404\r\nContent-Type: text/plain\r\n\r\nnot found",
+			wantProto:  "HTTP/1.0",
+			wantStatus: 404,
+			wantReason: "404 Not Found",
+			wantHeader: map[string]string{"Content-Type": "text/plain"},
+			wantBody:   "not found",
+		},
+		{
+			name:       "LF-only line endings",
+			raw:        "HTTP/1.0 200 OK\nServer: GoAhead-Webs\n\n<title>Router</title>",
+			wantProto:  "HTTP/1.0",
+			wantStatus: 200,
+			wantReason: "200 OK",
+			wantHeader: map[string]string{"Server": "GoAhead-Webs"},
+			wantBody:   "<title>Router</title>",
+		},
+		{
+			name:       "status line only",
+			raw:        "HTTP/1.0 401 Unauthorized\r\n",
+			wantProto:  "HTTP/1.0",
+			wantStatus: 401,
+			wantReason: "401 Unauthorized",
+			wantHeader: map[string]string{},
+		},
+		{
+			name:       "no space after colon and junk line",
+			raw:        "HTTP/1.1 302 Found\r\nLocation:/login.htm\r\nthis is not a header\r\n\r\n",
+			wantProto:  "HTTP/1.1",
+			wantStatus: 302,
+			wantReason: "302 Found",
+			wantHeader: map[string]string{"Location": "/login.htm"},
+		},
+		{
+			name:       "folded header",
+			raw:        "HTTP/1.0 401 Unauthorized\r\nWWW-Authenticate: Basic\r\n realm=\"IP Camera\"\r\n\r\n",
+			wantProto:  "HTTP/1.0",
+			wantStatus: 401,
+			wantReason: "401 Unauthorized",
+			wantHeader: map[string]string{"Www-Authenticate": "Basic realm=\"IP Camera\""},
+		},
+		{
+			name:       "lowercase protocol",
+			raw:        "http/1.0 200 ok\r\nserver: micro_httpd\r\n\r\nhi",
+			wantProto:  "HTTP/1.0",
+			wantStatus: 200,
+			wantReason: "200 ok",
+			wantHeader: map[string]string{"Server": "micro_httpd"},
+			wantBody:   "hi",
+		},
+		{
+			name:       "HTTP/0.9 bare body",
+			raw:        "\r\n<HTML><TITLE>Printer</TITLE></HTML>",
+			wantProto:  "HTTP/0.9",
+			wantStatus: 200,
+			wantReason: "200 OK",
+			wantHeader: map[string]string{},
+			wantBody:   "<HTML><TITLE>Printer</TITLE></HTML>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := parseLenientResponse([]byte(tt.raw))
+			if err != nil {
+				t.Fatalf("parseLenientResponse() error = %v", err)
+			}
+			if resp.proto != tt.wantProto {
+				t.Errorf("proto = %q, want %q", resp.proto, tt.wantProto)
+			}
+			if resp.statusCode != tt.wantStatus {
+				t.Errorf("statusCode = %d, want %d", resp.statusCode, tt.wantStatus)
+			}
+			if resp.status != tt.wantReason {
+				t.Errorf("status = %q, want %q", resp.status, tt.wantReason)
+			}
+			if len(resp.header) != len(tt.wantHeader) {
+				t.Errorf("header = %v, want %v", resp.header, tt.wantHeader)
+			}
+			for k, v := range tt.wantHeader {
+				if got := resp.header.Get(k); got != v {
+					t.Errorf("header %s = %q, want %q", k, got, v)
+				}
+			}
+			if string(resp.body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", resp.body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestParseLenientResponse_Errors(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"\r\n\r\n",
+		"SSH-2.0-OpenSSH_7.4\r\n",
+		"HTTP/1.0\r\n\r\n",
+		"HTTP/1.0 abc OK\r\n\r\n",
+	} {
+		if _, err := parseLenientResponse([]byte(raw)); err == nil {
+			t.Errorf("parseLenientResponse(%q) expected error", raw)
+		}
+	}
+}
+
+func TestLegacyFallbackError(t *testing.T) {
+	tests := []struct {
+		errMsg string
+		want   bool
+	}{
+		{`Request failed: Get "http://x": net/http: HTTP/1.x transport connection broken: malformed HTTP response "\x00"`, true},
+		{`Request failed: Get "http://x": EOF`, true},
+		{`Request failed: Get "http://x": dial tcp: connection refused`, false},
+		{`Request failed: context deadline exceeded`, false},
+	}
+	for _, tt := range tests {
+		if got := legacyFallbackError(tt.errMsg); got != tt.want {
+			t.Errorf("legacyFallbackError(%q) = %v, want %v", tt.errMsg, got, tt.want)
+		}
+	}
+}
+
+// legacyServer answers HTTP/1.0 requests with an LF-only, reason-less
+// response and garbage to anything else, like an ancient embedded web server.
+// requests returns the protocol and Host of every request so far.
+func legacyServer(t *testing.T) (target string, requests func() []string) {
+	t.Helper()
+	return legacyServerWith(t, "HTTP/1.0 200\nServer: Boa/0.94.13\n\n<html><title>Camera</title></html>")
+}
+
+// legacyServerWith is legacyServer answering HTTP/1.0 requests with reply.
+func legacyServerWith(t *testing.T, reply string) (target string, requests func() []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var mu sync.Mutex
+	var seen []string
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				seen = append(seen, req.Proto+" "+req.Host)
+				mu.Unlock()
+				if req.Proto == "HTTP/1.0" {
+					io.WriteString(conn, reply)
+					return
+				}
+				io.WriteString(conn, "\x00\x01garbage\r\n\r\n")
+			}()
+		}
+	}()
+	return "http://" + ln.Addr().String() + "/", func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestProbeURL_HTTP10Fallback(t *testing.T) {
+	target, _ := legacyServer(t)
+
+	newProber := func(fallback bool) *Prober {
+		cfg := config.New()
+		cfg.Silent = true
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		cfg.Timeout = 5
+		cfg.AllowPrivateIPs = true
+		cfg.HTTP10Fallback = fallback
+		prober := NewProber(cfg)
+		t.Cleanup(func() { prober.Close() })
+		return prober
+	}
+
+	result := newProber(false).ProbeURL(context.Background(), target, target)
+	if result.Error == "" {
+		t.Fatalf("expected malformed response error without -http10, got status %d", result.StatusCode)
+	}
+
+	result = newProber(true).ProbeURL(context.Background(), target, target)
+	if result.Error != "" {
+		t.Fatalf("unexpected error with -http10: %s", result.Error)
+	}
+	if result.StatusCode != 200 || result.Protocol != "HTTP/1.0" || !result.LegacyParse {
+		t.Errorf("got status %d protocol %q legacy_parse %v, want 200 HTTP/1.0 true", result.StatusCode, result.Protocol, result.LegacyParse)
+	}
+	if result.Title != "Camera" {
+		t.Errorf("Title = %q, want Camera", result.Title)
+	}
+	if result.WebServer != "Boa/0.94.13" {
+		t.Errorf("WebServer = %q, want Boa/0.94.13", result.WebServer)
+	}
+}
+
+// The HTTP/1.0 request is sent once, after the HTTP/1.1 retries are used up,
+// and names the virtual host.
+func TestProbeURL_HTTP10FallbackAfterRetries(t *testing.T) {
+	target, requests := legacyServer(t)
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.HTTP10Fallback = true
+		cfg.MaxRetries = 1
+	})
+
+	result := prober.ProbeURL(context.Background(), target, target)
+	if result.Error != "" || result.Protocol != "HTTP/1.0" {
+		t.Fatalf("error %q protocol %q, want the HTTP/1.0 fallback", result.Error, result.Protocol)
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(target, "http://"), "/")
+	want := []string{"HTTP/1.1 " + host, "HTTP/1.1 " + host, "HTTP/1.0 " + host}
+	if got := requests(); !slices.Equal(got, want) {
+		t.Errorf("server saw %q, want %q", got, want)
+	}
+}
+
+// The raw path applies the body limits of the other paths once it has the
+// headers, and dials the port the HTTP/1.1 attempt did.
+func TestProbeURL_HTTP10BodyLimits(t *testing.T) {
+	body := strings.Repeat("x", 4096)
+	binary, _ := legacyServerWith(t, "HTTP/1.0 200 OK\r\nContent-Type: application/zip\r\n\r\n"+body)
+	attachment, _ := legacyServerWith(t, "HTTP/1.0 200 OK\r\nContent-Type: text/plain\r\nContent-Disposition: attachment; filename=a.txt\r\n\r\n"+body)
+	text, requests := legacyServerWith(t, "HTTP/1.0 200 OK\r\nContent-Type: text/plain\r\n\r\n"+body)
+	u, _ := url.Parse(text)
+
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.HTTP10Fallback = true
+		cfg.MaxBodySize = 1 << 20
+		cfg.MaxBodySizeBinary = 100
+		cfg.AttachmentReadLimit = 200
+		cfg.DefaultHTTPPort = u.Port()
+	})
+	for _, tt := range []struct {
+		target string
+		want   int
+	}{
+		{binary, 100},
+		{attachment, 200},
+		// Portless: the fallback dials -default-http-port like the HTTP/1.1 attempt
+		{"http://" + u.Hostname() + "/", 4096},
+	} {
+		result := prober.ProbeURL(context.Background(), tt.target, tt.target)
+		if result.Error != "" || result.Protocol != "HTTP/1.0" {
+			t.Fatalf("%s: error %q protocol %q, want the HTTP/1.0 fallback", tt.target, result.Error, result.Protocol)
+		}
+		if result.ContentLength != tt.want || result.BodyTruncated != (tt.want < 4096) {
+			t.Errorf("%s: length %d truncated %v, want %d", tt.target, result.ContentLength, result.BodyTruncated, tt.want)
+		}
+		// Nothing past the limit is read off the wire
+		if tt.want < 4096 && result.BytesDownloaded > 1024 {
+			t.Errorf("%s: downloaded %d bytes for a %d byte limit", tt.target, result.BytesDownloaded, tt.want)
+		}
+	}
+	if got := requests(); len(got) != 2 || got[1] != "HTTP/1.0 "+u.Host {
+		t.Errorf("default-port server saw %q, want an HTTP/1.1 attempt and the HTTP/1.0 fallback", got)
+	}
+}
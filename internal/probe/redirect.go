@@ -5,19 +5,21 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"probeHTTP/internal/parser"
 	"probeHTTP/internal/storage"
 )
 
 // followRedirects manually follows HTTP redirects and captures the status code and host chains.
 // Returns the final response, complete status code chain, host chain, per-hop ChainEntries, and any error.
-// ChainEntries are only populated when StoreResponse is enabled.
+// ChainEntries are only populated when StoreResponse or AnalyzeBestHop is enabled.
 // The httpClient parameter specifies which client to use for redirect requests.
-func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response, maxRedirects int, startStep int, initialHostname string, buf *strings.Builder, httpClient *http.Client) (*http.Response, []int, []string, []storage.ChainEntry, error) {
+func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response, maxRedirects int, startStep int, initialHostname string, buf *bytes.Buffer, httpClient *http.Client) (*http.Response, []int, []string, []storage.ChainEntry, error) {
 	statusChain := []int{initialResp.StatusCode}
 	hostChain := []string{initialHostname}
 	var chainEntries []storage.ChainEntry
@@ -30,6 +32,7 @@ func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response
 
 	redirectCount := 0
 	stepNum := startStep
+	policy := defaultReferrerPolicy // -send-referer: updated by each redirect's Referrer-Policy
 	for {
 		// Check context cancellation
 		select {
@@ -44,12 +47,19 @@ func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response
 		}
 
 		// Get redirect location
-		location := currentResp.Header.Get("Location")
+		location := redirectLocation(currentResp)
 		if location == "" {
-			// No location header, stop here
+			// No usable location header, stop here
 			return currentResp, statusChain, hostChain, chainEntries, nil
 		}
 
+		// Only http and https targets can be requested; javascript:, data:
+		// and the like end the chain at the hop that sent them
+		if scheme := redirectScheme(location); scheme != "" && scheme != "http" && scheme != "https" {
+			target, _ := parser.TruncateRunes(location, maxBlockedRedirect)
+			return currentResp, statusChain, hostChain, chainEntries, &redirectSchemeError{scheme: scheme, target: target}
+		}
+
 		// Close previous response body
 		currentResp.Body.Close()
 
@@ -59,16 +69,40 @@ func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response
 			return currentResp, statusChain, hostChain, chainEntries, fmt.Errorf("invalid redirect location: %v", err)
 		}
 
-		// Normalize port when scheme changes (e.g., http:80 -> https should use 443)
-		nextURL = normalizeRedirectURL(currentResp.Request.URL, nextURL)
+		// Normalize port when scheme changes (e.g., http:80 -> https should use 443).
+		// A portless Location still means the standard port of its scheme
+		nextURL = normalizeRedirectURL(currentResp.Request.URL, nextURL, p.defaultPorts())
 
 		// Extract hostname from next URL
 		nextHostname := nextURL.Hostname()
 
+		// Never request out-of-scope targets
+		if p.config.ExcludeMatcher.Excluded(nextURL.String()) {
+			return currentResp, statusChain, hostChain, chainEntries, fmt.Errorf("redirect to excluded target %s", nextURL.String())
+		}
+		if !p.config.Scope.Allows(nextURL.String()) {
+			return currentResp, statusChain, hostChain, chainEntries, fmt.Errorf("redirect out of scope: %s", nextURL.String())
+		}
+
+		// Don't let a public host bounce us into private address space. The
+		// hop connects to the address checked, not to a fresh lookup, and a
+		// target that cannot be checked is not requested.
+		hopCtx := ctx
+		if !p.config.AllowPrivateIPs {
+			private, ip, err := parser.IsPrivateTarget(ctx, nextHostname, p.lookupHost)
+			if err != nil {
+				return currentResp, statusChain, hostChain, chainEntries, fmt.Errorf("redirect target lookup failed: %s: %v", nextURL.String(), err)
+			}
+			if private {
+				return currentResp, statusChain, hostChain, chainEntries, fmt.Errorf("redirect to private address blocked: %s (%s)", nextURL.String(), ip)
+			}
+			hopCtx = withRedirectPin(ctx, nextHostname, ip)
+		}
+
 		// Check if same-host-only mode is enabled and hostname changed
 		if p.config.SameHostOnly && nextHostname != initialHostname {
 			// Cross-host redirect detected - stop following
-			if p.config.Debug {
+			if p.debugEnabled() {
 				warning := fmt.Sprintf("  ⚠ Cross-host redirect blocked: %s → %s (same-host-only mode)\n", initialHostname, nextHostname)
 				if buf != nil {
 					buf.WriteString(warning)
@@ -77,14 +111,35 @@ func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response
 			return currentResp, statusChain, hostChain, chainEntries, fmt.Errorf("cross-host redirect blocked: %s → %s", initialHostname, nextHostname)
 		}
 
+		// -same-origin-only: scheme, host and port must all stay the same
+		if p.config.SameOriginOnly {
+			if changed := parser.OriginChange(initialResp.Request.URL, nextURL, parser.StandardPorts); changed != "" {
+				from, to := parser.Origin(initialResp.Request.URL, parser.StandardPorts), parser.Origin(nextURL, parser.StandardPorts)
+				if p.debugEnabled() && buf != nil {
+					buf.WriteString(fmt.Sprintf("  ⚠ Cross-origin redirect blocked: %s → %s (%s changed, same-origin-only mode)\n", from, to, changed))
+				}
+				return currentResp, statusChain, hostChain, chainEntries, fmt.Errorf("cross-origin redirect blocked (%s changed): %s → %s", changed, from, to)
+			}
+		}
+
 		// Make request to next URL
-		req, err := http.NewRequestWithContext(ctx, "GET", nextURL.String(), nil)
+		req, err := nextRequestForRedirect(hopCtx, currentResp.Request, currentResp.StatusCode, nextURL)
 		if err != nil {
 			return currentResp, statusChain, hostChain, chainEntries, fmt.Errorf("failed to create redirect request: %v", err)
 		}
-
-		// Copy headers from original request
-		req.Header = currentResp.Request.Header
+		p.setAuthorization(req)
+		if p.config.SendReferer {
+			policy = referrerPolicy(currentResp.Header, policy)
+			if referer := computeReferer(currentResp.Request.URL, nextURL, policy, parser.StandardPorts); referer != "" {
+				req.Header.Set("Referer", referer)
+			} else {
+				req.Header.Del("Referer")
+			}
+		}
+		req = withRequestTrace(req)
+		if prev := requestTraceFrom(currentResp.Request); prev != nil {
+			prev.setNext(requestTraceFrom(req))
+		}
 
 		// Capture raw request for storage before sending
 		var rawReq string
@@ -95,7 +150,7 @@ func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response
 		// Debug: log redirect request with cross-host warning
 		stepNum++
 		p.debugRequest(req, stepNum, buf)
-		if p.config.Debug && nextHostname != initialHostname {
+		if p.debugEnabled() && nextHostname != initialHostname {
 			warning := fmt.Sprintf("  ⚠ Cross-host redirect: %s → %s\n", initialHostname, nextHostname)
 			if buf != nil {
 				buf.WriteString(warning)
@@ -104,19 +159,40 @@ func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response
 
 		// Execute request
 		requestStart := time.Now()
-		nextResp, err := httpClient.Do(req)
+		nextResp, err := p.doRequest(httpClient, req)
+		if err != nil && ctx.Err() == nil && isProtocolError(err) {
+			// The client's HTTP/2 or HTTP/3 may not be spoken by this hop's
+			// host; retry the hop once over HTTP/1.1 with the same TLS strategy
+			if fallback, from := p.http11Fallback(httpClient); fallback != nil {
+				if retry, cloneErr := cloneHopRequest(req); cloneErr == nil {
+					if p.config.DebugLogger != nil {
+						p.config.DebugLogger.Debug("retrying redirect hop over HTTP/1.1", "url", nextURL.String(), "protocol", from, "error", err)
+					}
+					rt := requestTraceFrom(retry)
+					rt.mu.Lock()
+					rt.fallbackFrom = from
+					rt.mu.Unlock()
+					if prev := requestTraceFrom(currentResp.Request); prev != nil {
+						prev.setNext(rt)
+					}
+					req = retry
+					nextResp, err = p.doRequest(fallback, req)
+				}
+			}
+		}
 		requestElapsed := time.Since(requestStart)
 		if err != nil {
 			return currentResp, statusChain, hostChain, chainEntries, fmt.Errorf("redirect request failed: %v", err)
 		}
 
-		// Always read body when storing responses or in debug mode
+		// Always read body when storing responses, in debug mode or for
+		// -analyze-best-hop
 		var nextBody []byte
-		if p.config.StoreResponse || p.config.Debug {
+		if p.config.StoreResponse || p.config.Debug || p.config.AnalyzeBestHop {
 			var bodyBuffer bytes.Buffer
 			bodyReader := io.TeeReader(nextResp.Body, &bodyBuffer)
 			var readErr error
-			nextBody, readErr = io.ReadAll(io.LimitReader(bodyReader, p.config.MaxBodySize))
+			nextBody, _, readErr = readLimitedBody(bodyReader, p.bodyLimit(nextResp.Header))
 			nextResp.Body.Close()
 			if readErr != nil {
 				p.config.Logger.Warn("redirect body read failed",
@@ -128,8 +204,8 @@ func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response
 			nextResp.Body = io.NopCloser(bytes.NewReader(nextBody))
 		}
 
-		// Build chain entry for storage
-		if p.config.StoreResponse {
+		// Build chain entry for storage and -analyze-best-hop
+		if p.config.StoreResponse || p.config.AnalyzeBestHop {
 			chainEntries = append(chainEntries, storage.ChainEntry{
 				RawRequest:  rawReq,
 				RawResponse: formatRawResponse(nextResp),
@@ -153,36 +229,389 @@ func (p *Prober) followRedirects(ctx context.Context, initialResp *http.Response
 	}
 }
 
+// chainOrigins returns the origin (scheme://host:port) of each hop of the
+// redirect chain starting at resp, up to hops entries. Request URLs without
+// a port went to the standard one.
+func (p *Prober) chainOrigins(resp *http.Response, hops int) []string {
+	if resp == nil || hops <= 0 {
+		return nil
+	}
+	rt := requestTraceFrom(resp.Request)
+	if rt == nil {
+		return []string{parser.Origin(resp.Request.URL, parser.StandardPorts)}
+	}
+
+	origins := make([]string, 0, hops)
+	for rt != nil && len(origins) < hops {
+		rt.mu.Lock()
+		u, next := rt.url, rt.next
+		rt.mu.Unlock()
+		origins = append(origins, parser.Origin(u, parser.StandardPorts))
+		rt = next
+	}
+	return origins
+}
+
+// chainProtocols returns the protocol each hop of the redirect chain
+// starting at resp was answered with ("HTTP/1.1", "HTTP/2", "HTTP/3"), up to
+// hops entries. A hop retried over HTTP/1.1 after a protocol error reads
+// "HTTP/1.1 (fallback from HTTP/3)".
+func chainProtocols(resp *http.Response, hops int) []string {
+	if resp == nil || hops <= 0 {
+		return nil
+	}
+	rt := requestTraceFrom(resp.Request)
+	if rt == nil {
+		return []string{protocolName(resp.Proto)}
+	}
+
+	protocols := make([]string, 0, hops)
+	for rt != nil && len(protocols) < hops {
+		rt.mu.Lock()
+		proto, from, next := rt.proto, rt.fallbackFrom, rt.next
+		rt.mu.Unlock()
+		proto = protocolName(proto)
+		if from != "" {
+			proto += " (fallback from " + from + ")"
+		}
+		protocols = append(protocols, proto)
+		rt = next
+	}
+	return protocols
+}
+
+// chainContentTypes returns the Content-Type of each hop of the redirect
+// chain starting at resp, "" for hops without one, up to hops entries.
+func chainContentTypes(resp *http.Response, hops int) []string {
+	if resp == nil || hops <= 0 {
+		return nil
+	}
+	rt := requestTraceFrom(resp.Request)
+	if rt == nil {
+		return []string{lastHeaderValue(responseHeaderValues(resp.Header, "Content-Type"))}
+	}
+
+	types := make([]string, 0, hops)
+	for rt != nil && len(types) < hops {
+		rt.mu.Lock()
+		contentType, next := rt.contentType, rt.next
+		rt.mu.Unlock()
+		types = append(types, contentType)
+		rt = next
+	}
+	return types
+}
+
+// contentTypeChanged reports whether the first and last entries of
+// chain_content_types name different media types. Parameters such as
+// charset are ignored, and so are chains where either end has no
+// Content-Type.
+func contentTypeChanged(types []string) bool {
+	if len(types) < 2 {
+		return false
+	}
+	first, last := parser.MediaType(types[0]), parser.MediaType(types[len(types)-1])
+	return first != "" && last != "" && first != last
+}
+
+// protocolName shortens resp.Proto to the names used in the protocol
+// field: "HTTP/2.0" is "HTTP/2", "HTTP/3.0" is "HTTP/3".
+func protocolName(proto string) string {
+	switch proto {
+	case "HTTP/2.0":
+		return "HTTP/2"
+	case "HTTP/3.0":
+		return "HTTP/3"
+	}
+	return proto
+}
+
+// protocolErrorPatterns mark failures of the HTTP/2 or HTTP/3 layer rather
+// than of the host itself.
+var protocolErrorPatterns = []string{
+	"http2:",
+	"http3",
+	"quic",
+	"no recent network activity",
+	"crypto_error",
+	"no application protocol",
+}
+
+// isProtocolError reports whether err is an HTTP/2 or HTTP/3 protocol
+// failure that an HTTP/1.1 request to the same host may not hit.
+func isProtocolError(err error) bool {
+	lower := strings.ToLower(err.Error())
+	for _, pattern := range protocolErrorPatterns {
+		if strings.Contains(lower, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneHopRequest copies a redirect hop's request for a second attempt, with
+// a fresh trace and a replayed body.
+func cloneHopRequest(req *http.Request) (*http.Request, error) {
+	base := req.Context()
+	if rt := requestTraceFrom(req); rt != nil {
+		base = rt.base
+	}
+	retry := req.Clone(base)
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("cannot replay request body")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return withRequestTrace(retry), nil
+}
+
+// redirectMethod returns the method of the request that follows a redirect
+// with the given status (RFC 9110 §15.4): 307 and 308 preserve the method,
+// 301, 302 and 303 switch to GET (HEAD stays HEAD).
+func redirectMethod(method string, status int) string {
+	switch status {
+	case http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return method
+	}
+	if method == http.MethodHead {
+		return method
+	}
+	return http.MethodGet
+}
+
+// redirectMethodChain returns the method used for each request of a chain,
+// given the first request's method and the chain's status codes.
+func redirectMethodChain(method string, statusChain []int) []string {
+	if len(statusChain) == 0 {
+		return nil
+	}
+	methods := make([]string, len(statusChain))
+	methods[0] = method
+	for i := 1; i < len(statusChain); i++ {
+		methods[i] = redirectMethod(methods[i-1], statusChain[i-1])
+	}
+	return methods
+}
+
+// nextRequestForRedirect builds the request following prevReq's redirect to
+// nextURL. 307/308 replay the method and body (which requires GetBody when a
+// body is present); 301/302/303 switch to GET and drop the body and its
+// headers. Headers are cloned so hops never share a header map.
+func nextRequestForRedirect(ctx context.Context, prevReq *http.Request, status int, nextURL *url.URL) (*http.Request, error) {
+	method := redirectMethod(prevReq.Method, status)
+	preserveBody := method == prevReq.Method && (status == http.StatusTemporaryRedirect || status == http.StatusPermanentRedirect)
+
+	var body io.ReadCloser
+	if preserveBody && prevReq.GetBody != nil {
+		var err error
+		if body, err = prevReq.GetBody(); err != nil {
+			return nil, fmt.Errorf("cannot replay request body: %v", err)
+		}
+	} else if preserveBody && prevReq.Body != nil && prevReq.Body != http.NoBody {
+		return nil, fmt.Errorf("cannot replay request body for %d redirect", status)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, nextURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = prevReq.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	if body != nil {
+		req.GetBody = prevReq.GetBody
+		req.ContentLength = prevReq.ContentLength
+	} else {
+		req.Header.Del("Content-Type")
+		req.Header.Del("Content-Length")
+	}
+	return req, nil
+}
+
+// Referrer policies (https://www.w3.org/TR/referrer-policy/).
+const (
+	referrerNoReferrer                  = "no-referrer"
+	referrerNoReferrerWhenDowngrade     = "no-referrer-when-downgrade"
+	referrerOrigin                      = "origin"
+	referrerOriginWhenCrossOrigin       = "origin-when-cross-origin"
+	referrerSameOrigin                  = "same-origin"
+	referrerStrictOrigin                = "strict-origin"
+	referrerStrictOriginWhenCrossOrigin = "strict-origin-when-cross-origin"
+	referrerUnsafeURL                   = "unsafe-url"
+
+	// defaultReferrerPolicy is what browsers apply without a Referrer-Policy
+	defaultReferrerPolicy = referrerStrictOriginWhenCrossOrigin
+)
+
+// referrerPolicy returns the policy a redirect response sets for the next
+// hop, or current when it sets none. As in browsers, the last recognized
+// token of the header wins.
+func referrerPolicy(h http.Header, current string) string {
+	policy := current
+	for _, value := range h.Values("Referrer-Policy") {
+		for _, token := range strings.Split(value, ",") {
+			switch token = strings.ToLower(strings.TrimSpace(token)); token {
+			case referrerNoReferrer, referrerNoReferrerWhenDowngrade, referrerOrigin,
+				referrerOriginWhenCrossOrigin, referrerSameOrigin, referrerStrictOrigin,
+				referrerStrictOriginWhenCrossOrigin, referrerUnsafeURL:
+				policy = token
+			}
+		}
+	}
+	return policy
+}
+
+// computeReferer returns the Referer header for a redirect from prevURL to
+// nextURL under policy, or "" for none. The full referrer drops userinfo and
+// fragment; the origin referrer is "scheme://host[:port]/", with the port
+// written unless it is 80/443. defaults decide whether the hops are
+// same-origin. An https URL is never sent to an http hop, whatever the
+// policy (unsafe-url included).
+func computeReferer(prevURL, nextURL *url.URL, policy string, defaults parser.DefaultPorts) string {
+	if prevURL.Scheme != "http" && prevURL.Scheme != "https" {
+		return ""
+	}
+	if prevURL.Scheme == "https" && nextURL.Scheme == "http" {
+		return ""
+	}
+
+	full := *prevURL
+	full.User = nil
+	full.Fragment, full.RawFragment = "", ""
+	if full.Path == "" {
+		full.Path = "/"
+	}
+	host := strings.ToLower(prevURL.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	if port := prevURL.Port(); port != "" && port != parser.StandardPorts.ForScheme(prevURL.Scheme) {
+		host += ":" + port
+	}
+	origin := prevURL.Scheme + "://" + host + "/"
+	sameOrigin := parser.OriginChange(prevURL, nextURL, defaults) == ""
+
+	switch policy {
+	case referrerNoReferrer:
+		return ""
+	case referrerOrigin, referrerStrictOrigin:
+		return origin
+	case referrerSameOrigin:
+		if sameOrigin {
+			return full.String()
+		}
+		return ""
+	case referrerOriginWhenCrossOrigin, referrerStrictOriginWhenCrossOrigin:
+		if sameOrigin {
+			return full.String()
+		}
+		return origin
+	default: // no-referrer-when-downgrade, unsafe-url
+		return full.String()
+	}
+}
+
+// redirectLocation returns the Location header of resp if it can be followed.
+// Fragment-only locations ("#section") point back at the current URL and
+// would cause a self-request loop, so they are treated as no redirect.
+func redirectLocation(resp *http.Response) string {
+	location := strings.TrimSpace(resp.Header.Get("Location"))
+	if strings.HasPrefix(location, "#") {
+		return ""
+	}
+	return location
+}
+
+// maxBlockedRedirect bounds blocked_redirect; data: targets can carry a
+// whole page.
+const maxBlockedRedirect = 512
+
+// redirectSchemeError stops a redirect chain at a Location whose scheme
+// isn't http or https. target is the Location, cut to maxBlockedRedirect
+// runes.
+type redirectSchemeError struct {
+	scheme string
+	target string
+}
+
+func (e *redirectSchemeError) Error() string {
+	return fmt.Sprintf("unsupported redirect scheme %q: %s", e.scheme, e.target)
+}
+
+// redirectScheme returns the lowercased RFC 3986 scheme of location, or ""
+// for relative references such as "/login" or "//host/path".
+func redirectScheme(location string) string {
+	for i, c := range location {
+		switch {
+		case c == ':':
+			if i == 0 {
+				return ""
+			}
+			return strings.ToLower(location[:i])
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// isRedirectWithoutLocation reports whether resp is a redirect status
+// (301, 302, 303, 307, 308) that carries no followable Location.
+func isRedirectWithoutLocation(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return redirectLocation(resp) == ""
+	}
+	return false
+}
+
 // normalizeRedirectURL fixes port issues when scheme changes during redirect
 // e.g., http://host:80 -> https://host:80 should become https://host:443
-// This prevents "http: server gave HTTP response to HTTPS client" errors
-func normalizeRedirectURL(currentURL, nextURL *url.URL) *url.URL {
+// This prevents "http: server gave HTTP response to HTTPS client" errors.
+// A port carried over from defaults (-default-http(s)-port) becomes the
+// new scheme's port in defaults the same way.
+func normalizeRedirectURL(currentURL, nextURL *url.URL, defaults parser.DefaultPorts) *url.URL {
 	// Only normalize if scheme changed
 	if currentURL.Scheme == nextURL.Scheme {
 		return nextURL
 	}
 
-	currentPort := currentURL.Port()
-	nextPort := nextURL.Port()
-
 	// If the next URL has no explicit port, nothing to normalize
+	nextPort := nextURL.Port()
 	if nextPort == "" {
 		return nextURL
 	}
 
-	// Get default port for current scheme
-	currentDefaultPort := "80"
-	if currentURL.Scheme == "https" {
-		currentDefaultPort = "443"
+	// A portless request URL went to the standard port
+	currentPort := currentURL.Port()
+	if currentPort == "" {
+		currentPort = parser.StandardPorts.ForScheme(currentURL.Scheme)
 	}
 
-	// If current URL was using default port (explicit or implicit)
-	// and next URL kept that same port number, normalize to new scheme's default
-	if (currentPort == "" || currentPort == currentDefaultPort) && nextPort == currentDefaultPort {
+	// If current URL was using a default port and next URL kept that same
+	// port number, normalize to the new scheme's port in the same defaults
+	for _, ports := range []parser.DefaultPorts{parser.StandardPorts, defaults} {
+		if currentPort != ports.ForScheme(currentURL.Scheme) || nextPort != currentPort {
+			continue
+		}
 		// Create a copy with normalized port
 		normalized := *nextURL
-		// Remove the port from Host (it will default to scheme's standard port)
-		normalized.Host = nextURL.Hostname()
+		port := ports.ForScheme(nextURL.Scheme)
+		if port == parser.StandardPorts.ForScheme(nextURL.Scheme) {
+			// Remove the port from Host (it will default to scheme's standard port)
+			normalized.Host = nextURL.Hostname()
+		} else {
+			normalized.Host = net.JoinHostPort(nextURL.Hostname(), port)
+		}
 		return &normalized
 	}
 
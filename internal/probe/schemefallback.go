@@ -0,0 +1,108 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+
+	"probeHTTP/internal/output"
+)
+
+// errPlainHTTPToTLS is the error recorded for a plain-HTTP probe answered by
+// a TLS server's complaint (see crossProtocolResponse).
+const errPlainHTTPToTLS = "plain HTTP sent to a TLS port"
+
+// tlsSignatureErrors are error fragments seen when one side of the
+// connection speaks TLS and the other plain HTTP. The escaped bytes are the
+// start of a TLS alert or handshake record echoed in a "malformed HTTP
+// response" error.
+var tlsSignatureErrors = []string{
+	"first record does not look like a tls handshake",
+	"server gave http response to https client",
+	`malformed http response "\x15\x03`,
+	`malformed http response "\x16\x03`,
+	strings.ToLower(errPlainHTTPToTLS),
+}
+
+// plainToTLSResponses are body fragments of the 400 responses TLS servers
+// send back to a plain-HTTP request (Go, nginx and Apache wording).
+var plainToTLSResponses = [][]byte{
+	[]byte("client sent an http request to an https server"),
+	[]byte("the plain http request was sent to https port"),
+	[]byte("speaking plain http to an ssl-enabled server port"),
+}
+
+// crossProtocolError reports whether a failed probe looks like a scheme
+// mismatch: https:// against a plain-HTTP port or http:// against a TLS port.
+func crossProtocolError(errMsg string) bool {
+	lower := strings.ToLower(errMsg)
+	for _, fragment := range tlsSignatureErrors {
+		if strings.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// crossProtocolResponse reports whether a plain-HTTP response is a TLS
+// server's complaint about receiving plain HTTP.
+func crossProtocolResponse(statusCode int, body []byte) bool {
+	if statusCode != 400 {
+		return false
+	}
+	lower := bytes.ToLower(body)
+	for _, fragment := range plainToTLSResponses {
+		if bytes.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// alternateScheme returns the URL for the same host:port with http and
+// https swapped. u must carry an explicit port so the swap doesn't move the
+// probe to the other scheme's default port.
+func (p *Prober) alternateScheme(u *url.URL) (string, bool) {
+	swapped := *u
+	switch u.Scheme {
+	case "http":
+		swapped.Scheme = "https"
+	case "https":
+		swapped.Scheme = "http"
+	default:
+		return "", false
+	}
+	return stripDefaultPort(&swapped, p.defaultPorts()), true
+}
+
+// probeAlternateScheme is the -scheme-fallback retry: the same host:port is
+// probed once with the opposite scheme after a cross-protocol failure. The
+// original error is kept on a successful result; otherwise the original
+// result is returned unchanged.
+func (p *Prober) probeAlternateScheme(ctx context.Context, original output.ProbeResult, parsedURL *url.URL, originalInput string) output.ProbeResult {
+	alternate, ok := p.alternateScheme(parsedURL)
+	if !ok {
+		return original
+	}
+	if p.config.DebugLogger != nil {
+		p.config.DebugLogger.Info("cross-protocol failure, retrying with alternate scheme",
+			"url", parsedURL.String(),
+			"alternate", alternate,
+			"error", original.Error,
+		)
+	}
+
+	var result output.ProbeResult
+	if strings.HasPrefix(alternate, "https://") {
+		result = p.probeURLWithTLSFallback(ctx, alternate, originalInput)
+	} else {
+		result = p.probeURLHTTP(ctx, alternate, originalInput)
+	}
+	if result.Error != "" {
+		return original
+	}
+	result.SchemeFallbackUsed = true
+	result.SchemeFallbackError = original.Error
+	return result
+}
@@ -0,0 +1,105 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func TestCrossProtocolError(t *testing.T) {
+	tests := []struct {
+		errMsg string
+		want   bool
+	}{
+		{"All TLS attempts failed: modern-tls/HTTP/2: Request failed: tls: first record does not look like a TLS handshake", true},
+		{`Request failed: Get "https://example.com:8080": http: server gave HTTP response to HTTPS client`, true},
+		{`Request failed: Get "http://example.com:8443": net/http: HTTP/1.x transport connection broken: malformed HTTP response "\x15\x03\x01\x00\x02\x02\n"`, true},
+		{"Request failed: plain HTTP sent to a TLS port (status 400)", true},
+		{`Request failed: malformed HTTP response "SSH-2.0-OpenSSH_9.6"`, false},
+		{"Request failed: dial tcp 127.0.0.1:1: connect: connection refused", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := crossProtocolError(tt.errMsg); got != tt.want {
+			t.Errorf("crossProtocolError(%q) = %v, want %v", tt.errMsg, got, tt.want)
+		}
+	}
+}
+
+func TestCrossProtocolResponse(t *testing.T) {
+	if !crossProtocolResponse(400, []byte("Client sent an HTTP request to an HTTPS server.\n")) {
+		t.Error("Go TLS server complaint not detected")
+	}
+	if !crossProtocolResponse(400, []byte("<center>The plain HTTP request was sent to HTTPS port</center>")) {
+		t.Error("nginx complaint not detected")
+	}
+	if crossProtocolResponse(200, []byte("Client sent an HTTP request to an HTTPS server.")) {
+		t.Error("non-400 status must not match")
+	}
+	if crossProtocolResponse(400, []byte("bad request")) {
+		t.Error("ordinary 400 must not match")
+	}
+}
+
+func newSchemeFallbackProber(t *testing.T, enabled bool) *Prober {
+	t.Helper()
+	return newTestProber(t, func(cfg *config.Config) {
+		cfg.TLSHandshakeTimeout = 5
+		cfg.InsecureSkipVerify = true
+		cfg.DisableHTTP3 = true
+		cfg.SchemeFallback = enabled
+	})
+}
+
+func TestProbeURL_SchemeFallbackHTTPToTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>tls only</title>"))
+	}))
+	defer server.Close()
+	plainURL := strings.Replace(server.URL, "https://", "http://", 1)
+
+	result := newSchemeFallbackProber(t, true).ProbeURL(context.Background(), plainURL, plainURL)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if !result.SchemeFallbackUsed || result.Scheme != "https" || result.Title != "tls only" {
+		t.Errorf("got fallback=%v scheme=%q title=%q, want https fallback", result.SchemeFallbackUsed, result.Scheme, result.Title)
+	}
+	if !strings.Contains(result.SchemeFallbackError, errPlainHTTPToTLS) {
+		t.Errorf("SchemeFallbackError = %q, want the original plain-HTTP error", result.SchemeFallbackError)
+	}
+
+	// Without the flag the TLS server's 400 is reported as is
+	result = newSchemeFallbackProber(t, false).ProbeURL(context.Background(), plainURL, plainURL)
+	if result.SchemeFallbackUsed || result.StatusCode != 400 {
+		t.Errorf("without -scheme-fallback got fallback=%v status=%d, want plain 400", result.SchemeFallbackUsed, result.StatusCode)
+	}
+}
+
+func TestProbeURL_SchemeFallbackHTTPSToPlain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>plain only</title>"))
+	}))
+	defer server.Close()
+	tlsURL := strings.Replace(server.URL, "http://", "https://", 1)
+
+	result := newSchemeFallbackProber(t, true).ProbeURL(context.Background(), tlsURL, tlsURL)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if !result.SchemeFallbackUsed || result.Scheme != "http" || result.Title != "plain only" {
+		t.Errorf("got fallback=%v scheme=%q title=%q, want http fallback", result.SchemeFallbackUsed, result.Scheme, result.Title)
+	}
+	if !crossProtocolError(result.SchemeFallbackError) {
+		t.Errorf("SchemeFallbackError = %q, want a cross-protocol error", result.SchemeFallbackError)
+	}
+
+	result = newSchemeFallbackProber(t, false).ProbeURL(context.Background(), tlsURL, tlsURL)
+	if result.Error == "" || result.SchemeFallbackUsed {
+		t.Errorf("without -scheme-fallback got error=%q fallback=%v, want a failed probe", result.Error, result.SchemeFallbackUsed)
+	}
+}
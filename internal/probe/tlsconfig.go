@@ -2,6 +2,7 @@ package probe
 
 import (
 	"crypto/tls"
+	"path"
 
 	"probeHTTP/internal/config"
 )
@@ -120,9 +121,48 @@ func BuildTLSConfig(strategy TLSStrategy, cfg *config.Config) *tls.Config {
 		tlsConfig.CipherSuites = strategy.CipherSuites
 	}
 
+	applyClientAuth(tlsConfig, cfg)
 	return tlsConfig
 }
 
+// applyClientAuth sets the configured root CAs and client certificate on
+// tlsConfig. The certificate is offered through GetClientCertificate so it is
+// only presented when the server asks for one and the request's host matches
+// -client-cert-hosts; presenting it is recorded on the request trace.
+func applyClientAuth(tlsConfig *tls.Config, cfg *config.Config) {
+	if cfg.RootCAs != nil {
+		tlsConfig.RootCAs = cfg.RootCAs
+	}
+	if cfg.ClientCertificate == nil {
+		return
+	}
+	cert := cfg.ClientCertificate
+	hosts := cfg.ClientCertHostList
+	tlsConfig.GetClientCertificate = func(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		rt, _ := cri.Context().Value(requestTraceKey{}).(*requestTrace)
+		if len(hosts) > 0 && (rt == nil || !matchHostGlobs(hosts, rt.host)) {
+			// An empty certificate means none is sent
+			return &tls.Certificate{}, nil
+		}
+		if rt != nil {
+			rt.mu.Lock()
+			rt.clientCertUsed = true
+			rt.mu.Unlock()
+		}
+		return cert, nil
+	}
+}
+
+// matchHostGlobs reports whether host matches any of the (lowercase) globs.
+func matchHostGlobs(globs []string, host string) bool {
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // StrategyWithProtocol pairs a TLS strategy with its target HTTP protocol
 type StrategyWithProtocol struct {
 	Strategy TLSStrategy
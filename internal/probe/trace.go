@@ -0,0 +1,257 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"probeHTTP/internal/output"
+)
+
+// requestTrace collects low-level observations for a single request:
+// the connection used, interim 1xx responses, phase timestamps and whether a
+// client certificate was presented during the handshake. Redirect hops are
+// linked through next so a chain can be walked from its first request.
+type requestTrace struct {
+	mu             sync.Mutex
+	host           string          // lowercase request hostname, for -client-cert-hosts
+	url            *url.URL        // request URL, for chain_origins
+	base           context.Context // request context before tracing, for -hedge duplicates and dials
+	interim        bool
+	conn           *framingConn
+	clientCertUsed bool
+	setCookies     []string             // Set-Cookie header values of the response (-cookie-info)
+	tlsState       *tls.ConnectionState // TLS state of the response (-xtls-per-hop); nil for plain HTTP
+	proto          string               // Protocol of the response (resp.Proto), for chain_protocols
+	contentType    string               // Content-Type of the response, for chain_content_types
+	gzipCapped     bool                 // The gzip body inflated past the read limit, see gzipBody
+	fallbackFrom   string               // Protocol whose client failed before this hop was retried over HTTP/1.1
+	next           *requestTrace        // trace of the following redirect hop
+
+	// Phase timestamps; zero when the phase did not happen (e.g. reused
+	// connections skip DNS/connect/TLS, HTTP/3 reports few phases)
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	bodyDone     time.Time
+}
+
+type requestTraceKey struct{}
+
+// withRequestTrace attaches a requestTrace to the request's context.
+func withRequestTrace(req *http.Request) *http.Request {
+	rt := &requestTrace{host: strings.ToLower(req.URL.Hostname()), url: req.URL, base: req.Context(), start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			rt.mark(&rt.start)
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			rt.markFirst(&rt.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			rt.mark(&rt.dnsDone)
+		},
+		// Dual-stack dialing may start several connects; time from the
+		// first start to the successful one
+		ConnectStart: func(network, addr string) {
+			rt.markFirst(&rt.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				rt.mark(&rt.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() {
+			rt.markFirst(&rt.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			rt.mark(&rt.tlsDone)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			rt.mark(&rt.wroteRequest)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if fc, ok := info.Conn.(*framingConn); ok {
+				rt.mu.Lock()
+				rt.conn = fc
+				rt.mu.Unlock()
+			}
+		},
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			rt.mu.Lock()
+			rt.interim = true
+			rt.mu.Unlock()
+			return nil
+		},
+		GotFirstResponseByte: func() {
+			rt.mark(&rt.firstByte)
+		},
+	}
+	ctx := context.WithValue(req.Context(), requestTraceKey{}, rt)
+	return req.WithContext(httptrace.WithClientTrace(ctx, trace))
+}
+
+// requestTraceFrom returns the requestTrace attached to req, if any.
+func requestTraceFrom(req *http.Request) *requestTrace {
+	if req == nil {
+		return nil
+	}
+	rt, _ := req.Context().Value(requestTraceKey{}).(*requestTrace)
+	return rt
+}
+
+// clientCertUsedFor reports whether the handshake for resp's request
+// presented the client certificate.
+func clientCertUsedFor(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	rt := requestTraceFrom(resp.Request)
+	if rt == nil {
+		return false
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.clientCertUsed
+}
+
+// decompressionTruncated reports whether resp's gzip body was cut off at
+// the read limit while decoding.
+func decompressionTruncated(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	rt := requestTraceFrom(resp.Request)
+	if rt == nil {
+		return false
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.gzipCapped
+}
+
+// firstByteTime returns when the first response byte arrived, or the zero time.
+func (rt *requestTrace) firstByteTime() time.Time {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.firstByte
+}
+
+// mark sets *field to the current time.
+func (rt *requestTrace) mark(field *time.Time) {
+	rt.mu.Lock()
+	*field = time.Now()
+	rt.mu.Unlock()
+}
+
+// markFirst sets *field to the current time unless it is already set.
+func (rt *requestTrace) markFirst(field *time.Time) {
+	rt.mu.Lock()
+	if field.IsZero() {
+		*field = time.Now()
+	}
+	rt.mu.Unlock()
+}
+
+// setNext links the trace of the following redirect hop.
+func (rt *requestTrace) setNext(next *requestTrace) {
+	rt.mu.Lock()
+	rt.next = next
+	rt.mu.Unlock()
+}
+
+// recordSetCookies keeps the Set-Cookie headers of resp on its request's trace.
+func recordSetCookies(resp *http.Response) {
+	if rt := requestTraceFrom(resp.Request); rt != nil {
+		rt.mu.Lock()
+		rt.setCookies = resp.Header.Values("Set-Cookie")
+		rt.mu.Unlock()
+	}
+}
+
+// recordTLSState keeps the TLS connection state of resp on its request's trace.
+func recordTLSState(resp *http.Response) {
+	if rt := requestTraceFrom(resp.Request); rt != nil {
+		rt.mu.Lock()
+		rt.tlsState = resp.TLS
+		rt.mu.Unlock()
+	}
+}
+
+// recordProto keeps the protocol and Content-Type of resp on its request's
+// trace.
+func recordProto(resp *http.Response) {
+	if rt := requestTraceFrom(resp.Request); rt != nil {
+		contentType := lastHeaderValue(responseHeaderValues(resp.Header, "Content-Type"))
+		rt.mu.Lock()
+		rt.proto = resp.Proto
+		rt.contentType = contentType
+		rt.mu.Unlock()
+	}
+}
+
+// markBodyDone records that the response body has been read.
+func markBodyDone(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	if rt := requestTraceFrom(resp.Request); rt != nil {
+		rt.mark(&rt.bodyDone)
+	}
+}
+
+// timings converts the trace into output timings. transfer_ms is only
+// reported for the final hop, whose body is read to completion.
+func (rt *requestTrace) timings(final bool) *output.Timings {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	t := &output.Timings{
+		DNSMs:     phaseMs(rt.dnsStart, rt.dnsDone),
+		ConnectMs: phaseMs(rt.connectStart, rt.connectDone),
+		TLSMs:     phaseMs(rt.tlsStart, rt.tlsDone),
+		TTFBMs:    phaseMs(rt.start, rt.firstByte),
+	}
+	if final {
+		t.TransferMs = phaseMs(rt.firstByte, rt.bodyDone)
+	}
+	return t
+}
+
+// chainTimings returns the timings of every hop starting at resp's request.
+func chainTimings(resp *http.Response) []*output.Timings {
+	if resp == nil {
+		return nil
+	}
+	var chain []*output.Timings
+	for rt := requestTraceFrom(resp.Request); rt != nil; {
+		rt.mu.Lock()
+		next := rt.next
+		rt.mu.Unlock()
+		chain = append(chain, rt.timings(next == nil))
+		rt = next
+	}
+	return chain
+}
+
+// phaseMs returns the milliseconds between start and end (microsecond
+// precision), or nil if either end of the phase was not observed.
+func phaseMs(start, end time.Time) *float64 {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return nil
+	}
+	ms := math.Round(float64(end.Sub(start).Microseconds())) / 1000
+	return &ms
+}
@@ -0,0 +1,84 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"probeHTTP/internal/config"
+)
+
+func TestPhaseMs(t *testing.T) {
+	start := time.Now()
+	if got := phaseMs(start, start.Add(1500*time.Microsecond)); got == nil || *got != 1.5 {
+		t.Errorf("phaseMs = %v, want 1.5", got)
+	}
+	if phaseMs(time.Time{}, start) != nil || phaseMs(start, time.Time{}) != nil {
+		t.Error("unobserved phase should be nil")
+	}
+	if phaseMs(start, start.Add(-time.Millisecond)) != nil {
+		t.Error("negative phase should be nil")
+	}
+}
+
+func TestProbeURL_Timings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/final", http.StatusFound)
+			return
+		}
+		w.Write([]byte("<title>done</title>"))
+	}))
+	defer server.Close()
+	// A hostname (rather than the IP literal) makes the DNS phase happen
+	target := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	newProber := func(timing bool) *Prober {
+		cfg := config.New()
+		cfg.Silent = true
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		cfg.Timeout = 5
+		cfg.InsecureSkipVerify = true
+		cfg.DisableHTTP3 = true
+		cfg.Timing = timing
+		cfg.AllowPrivateIPs = true
+		return NewProber(cfg)
+	}
+
+	prober := newProber(true)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), target, target)
+	if result.Error != "" {
+		t.Fatalf("ProbeURL error: %s", result.Error)
+	}
+	if len(result.ChainTimings) != len(result.ChainStatusCodes) || len(result.ChainTimings) != 2 {
+		t.Fatalf("ChainTimings has %d entries for chain %v", len(result.ChainTimings), result.ChainStatusCodes)
+	}
+
+	first := result.ChainTimings[0]
+	if first.DNSMs == nil || first.ConnectMs == nil || first.TLSMs == nil || first.TTFBMs == nil {
+		t.Errorf("first hop should report dns/connect/tls/ttfb, got %+v", first)
+	}
+	if first.TransferMs != nil {
+		t.Error("transfer_ms is only reported for the final hop")
+	}
+	if result.Timings != result.ChainTimings[1] {
+		t.Error("timings should be the final hop's entry")
+	}
+	if result.Timings.TTFBMs == nil || result.Timings.TransferMs == nil {
+		t.Errorf("final hop should report ttfb and transfer, got %+v", result.Timings)
+	}
+
+	plain := newProber(false)
+	defer plain.Close()
+	result = plain.ProbeURL(context.Background(), target, target)
+	if result.Timings != nil || result.ChainTimings != nil {
+		t.Error("timings should only be reported with -timing")
+	}
+}
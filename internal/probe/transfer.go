@@ -0,0 +1,257 @@
+package probe
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// transferCounter accumulates approximate bytes sent and received.
+type transferCounter struct {
+	downloaded atomic.Int64
+	uploaded   atomic.Int64
+}
+
+func (c *transferCounter) add(down, up int64) {
+	c.downloaded.Add(down)
+	c.uploaded.Add(up)
+}
+
+type transferCounterKey struct{}
+
+// withTransferCounter attaches a per-probe transferCounter to ctx so every
+// request of the probe (retries, TLS fallbacks, redirect hops) is accounted.
+func withTransferCounter(ctx context.Context) (context.Context, *transferCounter) {
+	counter := &transferCounter{}
+	return context.WithValue(ctx, transferCounterKey{}, counter), counter
+}
+
+// HostTransfer is the traffic exchanged with a single host.
+type HostTransfer struct {
+	Host       string `json:"host"`
+	Downloaded int64  `json:"bytes_downloaded"`
+	Uploaded   int64  `json:"bytes_uploaded"`
+}
+
+// TransferStats summarizes the traffic of a run.
+type TransferStats struct {
+	Downloaded int64          `json:"bytes_downloaded"`
+	Uploaded   int64          `json:"bytes_uploaded"`
+	TopHosts   []HostTransfer `json:"top_hosts,omitempty"`
+}
+
+// transferAccounting keeps global and per-host byte counters for a Prober.
+type transferAccounting struct {
+	total transferCounter
+	hosts sync.Map // host -> *transferCounter
+}
+
+// record adds traffic for host to the global, per-host and per-probe counters.
+func (a *transferAccounting) record(ctx context.Context, host string, down, up int64) {
+	if down == 0 && up == 0 {
+		return
+	}
+	a.total.add(down, up)
+	hc, ok := a.hosts.Load(host)
+	if !ok {
+		hc, _ = a.hosts.LoadOrStore(host, &transferCounter{})
+	}
+	hc.(*transferCounter).add(down, up)
+	if probeCounter, ok := ctx.Value(transferCounterKey{}).(*transferCounter); ok {
+		probeCounter.add(down, up)
+	}
+}
+
+// TransferStats returns the run's byte totals and the topN hosts by bytes downloaded.
+func (p *Prober) TransferStats(topN int) TransferStats {
+	stats := TransferStats{
+		Downloaded: p.transfer.total.downloaded.Load(),
+		Uploaded:   p.transfer.total.uploaded.Load(),
+	}
+	p.transfer.hosts.Range(func(k, v interface{}) bool {
+		c := v.(*transferCounter)
+		stats.TopHosts = append(stats.TopHosts, HostTransfer{
+			Host:       k.(string),
+			Downloaded: c.downloaded.Load(),
+			Uploaded:   c.uploaded.Load(),
+		})
+		return true
+	})
+	sort.Slice(stats.TopHosts, func(i, j int) bool {
+		a, b := stats.TopHosts[i], stats.TopHosts[j]
+		if a.Downloaded+a.Uploaded != b.Downloaded+b.Uploaded {
+			return a.Downloaded+a.Uploaded > b.Downloaded+b.Uploaded
+		}
+		return a.Host < b.Host
+	})
+	if len(stats.TopHosts) > topN {
+		stats.TopHosts = stats.TopHosts[:topN]
+	}
+	return stats
+}
+
+// doRequest sends req with byte accounting. Compression is negotiated here
+// rather than by the transport so the body can be counted as received on the
+// wire before it is decoded; decoding mirrors the transport's transparent
+// gzip handling so callers see the same response either way.
+func (p *Prober) doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, head, err := p.sendRequest(client, req)
+	head.record(&p.transfer)
+	return resp, err
+}
+
+// exchangeHead holds the request and response head sizes of one request
+// until the caller decides it counts; a response body is counted as it is
+// read.
+type exchangeHead struct {
+	ctx      context.Context
+	host     string
+	up, down int64
+}
+
+func (h exchangeHead) record(a *transferAccounting) {
+	a.record(h.ctx, h.host, h.down, h.up)
+}
+
+// sendRequest is doRequest without recording the heads, for callers that may
+// throw the exchange away.
+func (p *Prober) sendRequest(client *http.Client, req *http.Request) (*http.Response, exchangeHead, error) {
+	req.Header.Set("Accept-Encoding", "gzip")
+	if len(p.config.VhostHosts) > 0 {
+		// Pooled connections are keyed by hostname; one opened for a
+		// hostname pinned to one IP must not serve it on another
+		req.Close = true
+	}
+	host := req.URL.Hostname()
+	ctx := req.Context()
+
+	head := exchangeHead{ctx: ctx, host: host, up: requestSize(req)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return resp, head, err
+	}
+	head.down = responseHeaderSize(resp)
+	recordProto(resp)
+	if p.config.CookieInfo {
+		recordSetCookies(resp)
+	}
+	if p.config.ExtractTLSHops {
+		recordTLSState(resp)
+	}
+
+	resp.Body = &countingBody{body: resp.Body, record: func(n int64) {
+		p.transfer.record(ctx, host, n, 0)
+	}}
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+		resp.Body = &gzipBody{body: resp.Body, limit: p.bodyLimit(resp.Header), trace: requestTraceFrom(resp.Request)}
+	}
+	return resp, head, nil
+}
+
+// isDecodedBody reports whether resp's body is plain content. doRequest strips
+// the gzip Content-Encoding it decodes; any other coding is still on the bytes.
+func isDecodedBody(resp *http.Response) bool {
+	ce := strings.TrimSpace(resp.Header.Get("Content-Encoding"))
+	return ce == "" || strings.EqualFold(ce, "identity")
+}
+
+// requestSize approximates the bytes of an HTTP/1.1 request head:
+// request line, Host and headers.
+func requestSize(req *http.Request) int64 {
+	size := len(req.Method) + 1 + len(req.URL.RequestURI()) + len(" HTTP/1.1\r\n")
+	size += len("Host: \r\n") + len(req.Host)
+	if req.Host == "" {
+		size += len(req.URL.Host)
+	}
+	size += headerSize(req.Header) + 2
+	return int64(size)
+}
+
+// responseHeaderSize approximates the bytes of a response head.
+func responseHeaderSize(resp *http.Response) int64 {
+	return int64(len(resp.Proto) + 1 + len(resp.Status) + 2 + headerSize(resp.Header) + 2)
+}
+
+func headerSize(h http.Header) int {
+	size := 0
+	for k, values := range h {
+		for _, v := range values {
+			size += len(k) + 2 + len(v) + 2
+		}
+	}
+	return size
+}
+
+// countingBody reports the bytes read from a response body.
+type countingBody struct {
+	body   io.ReadCloser
+	record func(n int64)
+}
+
+func (c *countingBody) Read(b []byte) (int, error) {
+	n, err := c.body.Read(b)
+	if n > 0 {
+		c.record(int64(n))
+	}
+	return n, err
+}
+
+func (c *countingBody) Close() error {
+	return c.body.Close()
+}
+
+// gzipBody lazily decodes a gzip response body on first read. At most limit
+// decoded bytes are returned, whatever reads the body: a small gzip bomb
+// would otherwise inflate without bound. A body longer than that ends at
+// the limit and is marked as decompression_truncated on its trace.
+type gzipBody struct {
+	body   io.ReadCloser
+	zr     *gzip.Reader
+	err    error
+	limit  int64         // decoded bytes still allowed
+	trace  *requestTrace // nil for untraced requests
+	capped bool
+}
+
+func (g *gzipBody) Read(b []byte) (int, error) {
+	if g.zr == nil {
+		if g.err == nil {
+			g.zr, g.err = gzip.NewReader(g.body)
+		}
+		if g.err != nil {
+			return 0, g.err
+		}
+	}
+	if g.limit <= 0 {
+		// One more decoded byte tells a body that ends at the cap from a longer one
+		if !g.capped && len(b) > 0 {
+			g.capped = true
+			var one [1]byte
+			if n, _ := io.ReadFull(g.zr, one[:]); n > 0 && g.trace != nil {
+				g.trace.mu.Lock()
+				g.trace.gzipCapped = true
+				g.trace.mu.Unlock()
+			}
+		}
+		return 0, io.EOF
+	}
+	if int64(len(b)) > g.limit {
+		b = b[:g.limit]
+	}
+	n, err := g.zr.Read(b)
+	g.limit -= int64(n)
+	return n, err
+}
+
+func (g *gzipBody) Close() error {
+	return g.body.Close()
+}
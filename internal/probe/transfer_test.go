@@ -0,0 +1,164 @@
+package probe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"probeHTTP/internal/config"
+)
+
+func TestProbeURL_CountsWireBytes(t *testing.T) {
+	plain := "<html><title>compressed</title>" + strings.Repeat("a", 100000) + "</html>"
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(plain))
+	zw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(gz.Bytes())
+	}))
+	defer server.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.AllowPrivateIPs = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if result.Title != "compressed" || result.ContentLength == gz.Len() {
+		t.Errorf("body not decoded: title=%q content_length=%d", result.Title, result.ContentLength)
+	}
+	if result.BytesDownloaded < int64(gz.Len()) || result.BytesDownloaded >= int64(len(plain)) {
+		t.Errorf("BytesDownloaded = %d, want on-the-wire size around %d (decoded %d)", result.BytesDownloaded, gz.Len(), len(plain))
+	}
+	if result.BytesUploaded == 0 {
+		t.Error("BytesUploaded should account for both request heads")
+	}
+
+	stats := prober.TransferStats(10)
+	if stats.Downloaded != result.BytesDownloaded || stats.Uploaded != result.BytesUploaded {
+		t.Errorf("global totals %d/%d differ from the single result %d/%d",
+			stats.Downloaded, stats.Uploaded, result.BytesDownloaded, result.BytesUploaded)
+	}
+	if len(stats.TopHosts) != 1 || stats.TopHosts[0].Host != "127.0.0.1" {
+		t.Errorf("TopHosts = %+v", stats.TopHosts)
+	}
+}
+
+func TestTransferStats_TopHosts(t *testing.T) {
+	cfg := config.New()
+	cfg.Silent = true
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	ctx := context.Background()
+	prober.transfer.record(ctx, "small.example", 10, 1)
+	prober.transfer.record(ctx, "big.example", 1000, 10)
+	prober.transfer.record(ctx, "mid.example", 100, 5)
+
+	stats := prober.TransferStats(2)
+	if stats.Downloaded != 1110 || stats.Uploaded != 16 {
+		t.Errorf("totals = %d/%d, want 1110/16", stats.Downloaded, stats.Uploaded)
+	}
+	if len(stats.TopHosts) != 2 || stats.TopHosts[0].Host != "big.example" || stats.TopHosts[1].Host != "mid.example" {
+		t.Errorf("TopHosts = %+v", stats.TopHosts)
+	}
+}
+
+// gzipMember compresses n zero bytes into one gzip member. Members can be
+// concatenated; the reader decodes them as one stream.
+func gzipMember(t *testing.T, n int) []byte {
+	t.Helper()
+	var gz bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	zw.Write(make([]byte, n))
+	zw.Close()
+	return gz.Bytes()
+}
+
+// A gzip bomb inflating to 1 GB (100 members of ~10 KB, 10 MB each) is
+// decoded only up to the read limit.
+func TestProbeURL_GzipBomb(t *testing.T) {
+	member := gzipMember(t, 10<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Type", "text/html")
+		for i := 0; i < 100; i++ {
+			if _, err := w.Write(member); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	prober := newHeaderProber(t, func(cfg *config.Config) { cfg.MaxBodySize = 1 << 20 })
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+	runtime.ReadMemStats(&after)
+
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if !result.DecompressionTruncated || !result.BodyTruncated {
+		t.Errorf("decompression_truncated = %v, body_truncated = %v; want both", result.DecompressionTruncated, result.BodyTruncated)
+	}
+	if result.ContentLength != 1<<20 {
+		t.Errorf("content_length = %d, want the 1 MB limit", result.ContentLength)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 64<<20 {
+		t.Errorf("probe allocated %d MB for a bomb capped at 1 MB", alloc>>20)
+	}
+}
+
+func TestGzipBody_Limit(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		truncated bool
+	}{
+		{"shorter", 999, false},
+		{"exactly the limit", 1000, false},
+		{"longer", 1001, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := &requestTrace{}
+			body := &gzipBody{body: io.NopCloser(bytes.NewReader(gzipMember(t, tt.size))), limit: 1000, trace: trace}
+			data, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if want := min(tt.size, 1000); len(data) != want {
+				t.Errorf("read %d bytes, want %d", len(data), want)
+			}
+			if trace.gzipCapped != tt.truncated {
+				t.Errorf("gzipCapped = %v, want %v", trace.gzipCapped, tt.truncated)
+			}
+		})
+	}
+}
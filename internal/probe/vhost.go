@@ -0,0 +1,153 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"probeHTTP/internal/output"
+)
+
+// vhostPin routes the connections of a -vhost-list probe for host to ip,
+// so host is sent as Host and SNI to a server DNS may know nothing about.
+type vhostPin struct {
+	host string
+	ip   string
+}
+
+type vhostPinKey struct{}
+
+// withVhostPin returns ctx pinning connections to host onto ip.
+func withVhostPin(ctx context.Context, host, ip string) context.Context {
+	return context.WithValue(ctx, vhostPinKey{}, &vhostPin{host: host, ip: ip})
+}
+
+func vhostPinFrom(ctx context.Context) *vhostPin {
+	pin, _ := ctx.Value(vhostPinKey{}).(*vhostPin)
+	return pin
+}
+
+type redirectPinKey struct{}
+
+// withRedirectPin returns ctx pinning connections to host onto ip, the
+// address a redirect hop's private address check vetted, so a second DNS
+// answer cannot send the hop elsewhere.
+func withRedirectPin(ctx context.Context, host, ip string) context.Context {
+	return context.WithValue(ctx, redirectPinKey{}, &vhostPin{host: host, ip: ip})
+}
+
+// pinnedAddr returns addr with its host replaced by the IP ctx pins it to,
+// if any.
+func pinnedAddr(ctx context.Context, addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	for _, key := range []any{vhostPinKey{}, redirectPinKey{}} {
+		if pin, _ := ctx.Value(key).(*vhostPin); pin != nil && strings.EqualFold(host, pin.host) {
+			return net.JoinHostPort(pin.ip, port)
+		}
+	}
+	return addr
+}
+
+// pinnedDial wraps dial so connections to a pinned host go to its IP.
+// Redirects to other hosts resolve normally.
+func pinnedDial(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dial(ctx, network, pinnedAddr(ctx, addr))
+	}
+}
+
+// rateLimitHost returns the rate limiter key for requests to host: the
+// pinned IP for a -vhost-list probe, so a long hostname list is still
+// one host's worth of requests to the server behind the IP.
+func rateLimitHost(ctx context.Context, host string) string {
+	if pin := vhostPinFrom(ctx); pin != nil && strings.EqualFold(host, pin.host) {
+		return pin.ip
+	}
+	return host
+}
+
+// vhostIP returns the IP of probeURL when it is a -vhost-list target: an
+// http(s) URL whose host is an IP address.
+func (p *Prober) vhostIP(probeURL string) (*url.URL, string, bool) {
+	if len(p.config.VhostHosts) == 0 {
+		return nil, "", false
+	}
+	u, err := url.Parse(probeURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || net.ParseIP(u.Hostname()) == nil {
+		return nil, "", false
+	}
+	return u, u.Hostname(), true
+}
+
+// VhostURLs returns the URLs of the -vhost-list results ProcessURLs adds
+// for probeURL besides its own, in the order they are probed.
+func (p *Prober) VhostURLs(probeURL string) []string {
+	target, _, ok := p.vhostIP(probeURL)
+	if !ok {
+		return nil
+	}
+	urls := make([]string, len(p.config.VhostHosts))
+	for i, host := range p.config.VhostHosts {
+		urls[i] = vhostURL(target, host)
+	}
+	return urls
+}
+
+// vhostURL returns target with its host replaced by the vhost hostname.
+func vhostURL(target *url.URL, host string) string {
+	candidate := *target
+	candidate.Host = host
+	if port := target.Port(); port != "" {
+		candidate.Host = net.JoinHostPort(host, port)
+	}
+	return candidate.String()
+}
+
+// probeVhosts probes every -vhost-list hostname against the IP target
+// probeURL, sending it as Host and SNI over connections to the IP, and
+// passes each result to emit. baseline is the IP's own result, the server's
+// default vhost; results unlike it are marked vhost_distinct.
+func (p *Prober) probeVhosts(ctx context.Context, probeURL, originalInput string, baseline output.ProbeResult, emit func(output.ProbeResult)) {
+	target, ip, ok := p.vhostIP(probeURL)
+	if !ok {
+		return
+	}
+	for _, host := range p.config.VhostHosts {
+		if ctx.Err() != nil {
+			return
+		}
+		candidateURL := vhostURL(target, host)
+		// Not coalesced: the same hostname on another IP is another probe
+		result := p.probeRecovered(candidateURL, originalInput, func() output.ProbeResult {
+			return p.probeURLWithRetries(withVhostPin(ctx, host, ip), candidateURL, originalInput)
+		})
+		result.VhostCandidate = host
+		result.HostIP = ip
+		result.VhostDistinct = vhostDistinct(baseline, result)
+		emit(result)
+	}
+}
+
+// vhostDistinct reports whether the response of a vhost candidate differs
+// from the default vhost's: another status chain, title, or word or line
+// count. Bodies are not compared byte for byte since catch-all pages often
+// carry timestamps or tokens. A failed candidate is never distinct; any
+// answer is when the default vhost failed.
+func vhostDistinct(baseline, candidate output.ProbeResult) bool {
+	if candidate.Error != "" {
+		return false
+	}
+	if baseline.Error != "" {
+		return true
+	}
+	return !slices.Equal(baseline.ChainStatusCodes, candidate.ChainStatusCodes) ||
+		baseline.StatusCode != candidate.StatusCode ||
+		baseline.Title != candidate.Title ||
+		baseline.Words != candidate.Words ||
+		baseline.Lines != candidate.Lines
+}
@@ -0,0 +1,129 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
+)
+
+// vhostServer serves the admin and intranet vhosts and a catch-all page for
+// every other Host, and records the SNI of each TLS request.
+func vhostServer(t *testing.T) (*httptest.Server, *sync.Map) {
+	t.Helper()
+	var sni sync.Map
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.Split(r.Host, ":")[0]
+		if r.TLS != nil {
+			sni.Store(host, r.TLS.ServerName)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		switch host {
+		case "admin.corp.test":
+			fmt.Fprint(w, "<html><head><title>Admin</title></head><body>admin console</body></html>")
+		case "intranet.corp.test":
+			fmt.Fprint(w, "<html><head><title>Welcome</title></head><body>intranet home page for all staff</body></html>")
+		default:
+			// Catch-all with a per-request token, like many default pages
+			fmt.Fprintf(w, "<html><head><title>Welcome</title></head><body>it works %d</body></html>", len(r.RemoteAddr))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &sni
+}
+
+func TestProcessURLs_VhostList(t *testing.T) {
+	server, sni := vhostServer(t)
+	prober := newHeaderProber(t, func(cfg *config.Config) {
+		cfg.InsecureSkipVerify = true
+		cfg.DisableHTTP3 = true
+		cfg.VhostHosts = []string{"admin.corp.test", "intranet.corp.test", "unknown.corp.test"}
+	})
+
+	var results []output.ProbeResult
+	for result := range prober.ProcessURLs(context.Background(), []string{server.URL}, map[string]string{server.URL: server.URL}, 2) {
+		results = append(results, result)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want the IP's and one per candidate", len(results))
+	}
+
+	byCandidate := map[string]output.ProbeResult{}
+	for _, r := range results {
+		byCandidate[r.VhostCandidate] = r
+	}
+	if ip := byCandidate[""]; ip.Error != "" || ip.Title != "Welcome" {
+		t.Fatalf("IP result: error %q, title %q", ip.Error, ip.Title)
+	}
+	tests := []struct {
+		host     string
+		title    string
+		distinct bool
+	}{
+		{"admin.corp.test", "Admin", true},
+		{"intranet.corp.test", "Welcome", true}, // Same title, other page
+		{"unknown.corp.test", "Welcome", false},
+	}
+	for _, tt := range tests {
+		r, ok := byCandidate[tt.host]
+		if !ok {
+			t.Errorf("%s: no result", tt.host)
+			continue
+		}
+		if r.Error != "" || r.Title != tt.title || r.VhostDistinct != tt.distinct {
+			t.Errorf("%s: error %q, title %q, distinct %v; want %q, %v", tt.host, r.Error, r.Title, r.VhostDistinct, tt.title, tt.distinct)
+		}
+		if r.HostIP != "127.0.0.1" || r.Input != server.URL || !strings.HasPrefix(r.URL, "https://"+tt.host+":") {
+			t.Errorf("%s: host_ip %q, input %q, url %q", tt.host, r.HostIP, r.Input, r.URL)
+		}
+		if got, _ := sni.Load(tt.host); got != tt.host {
+			t.Errorf("%s: SNI %v", tt.host, got)
+		}
+	}
+
+	// Candidates share the IP's rate limiter
+	prober.client.mu.Lock()
+	defer prober.client.mu.Unlock()
+	for host := range prober.client.limiters {
+		if host != "127.0.0.1" {
+			t.Errorf("rate limiter keyed by %q, want only the IP", host)
+		}
+	}
+}
+
+// A failed default vhost makes every answering candidate distinct; a
+// failed candidate never is.
+func TestVhostDistinct_Errors(t *testing.T) {
+	ok := output.ProbeResult{StatusCode: 200, ChainStatusCodes: []int{200}, Title: "Welcome"}
+	failed := output.ProbeResult{Error: "Request failed: tls: handshake failure"}
+	if !vhostDistinct(failed, ok) {
+		t.Error("candidate answering a failed default vhost not distinct")
+	}
+	if vhostDistinct(ok, failed) || vhostDistinct(failed, failed) {
+		t.Error("failed candidate distinct")
+	}
+	redirected := ok
+	redirected.ChainStatusCodes = []int{302, 200}
+	if !vhostDistinct(ok, redirected) {
+		t.Error("candidate with another redirect chain not distinct")
+	}
+}
+
+func TestRateLimitHost(t *testing.T) {
+	ctx := withVhostPin(context.Background(), "admin.corp.test", "192.0.2.10")
+	if got := rateLimitHost(ctx, "Admin.corp.test"); got != "192.0.2.10" {
+		t.Errorf("pinned host keyed by %q", got)
+	}
+	if got := rateLimitHost(ctx, "sso.corp.test"); got != "sso.corp.test" {
+		t.Errorf("redirect target keyed by %q", got)
+	}
+	if got := rateLimitHost(context.Background(), "admin.corp.test"); got != "admin.corp.test" {
+		t.Errorf("unpinned host keyed by %q", got)
+	}
+}
@@ -14,11 +14,27 @@ func (p *Prober) ProcessURLs(ctx context.Context, urls []string, originalInputMa
 	results := make(chan output.ProbeResult, bufSize)
 	urlChan := make(chan string, bufSize)
 
+	// -adaptive-concurrency: the workers are the ceiling, a gate decides
+	// how many of them may probe at once. Each run has its own controller.
+	done := make(chan struct{})
+	var adaptive *adaptiveConcurrency
+	if p.config.AdaptiveConcurrency {
+		adaptive = newAdaptiveConcurrency(p.config.AdaptiveFloor, concurrency)
+		p.config.Logger.Info("adaptive concurrency enabled",
+			"concurrency", adaptive.controller.limit,
+			"floor", adaptive.controller.floor,
+			"ceiling", concurrency,
+		)
+		ctx = context.WithValue(ctx, adaptiveKey{}, adaptive)
+		go adaptive.run(ctx, done, adaptiveInterval, p.config.Logger)
+	}
+	p.adaptive.Store(adaptive)
+
 	// Create worker pool
 	var wg sync.WaitGroup
 	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
-		go p.worker(ctx, urlChan, results, originalInputMap, &wg)
+		go p.worker(ctx, urlChan, results, originalInputMap, adaptive, &wg)
 	}
 
 	// Send URLs to workers
@@ -43,16 +59,23 @@ func (p *Prober) ProcessURLs(ctx context.Context, urls []string, originalInputMa
 	// Close results channel when all workers are done
 	go func() {
 		wg.Wait()
+		close(done)
 		close(results)
 	}()
 
 	return results
 }
 
-// worker processes URLs from the channel
-func (p *Prober) worker(ctx context.Context, urls <-chan string, results chan<- output.ProbeResult, originalInputMap map[string]string, wg *sync.WaitGroup) {
+// worker processes URLs from the channel. With adaptive set, each URL
+// waits for a slot of its gate.
+func (p *Prober) worker(ctx context.Context, urls <-chan string, results chan<- output.ProbeResult, originalInputMap map[string]string, adaptive *adaptiveConcurrency, wg *sync.WaitGroup) {
 	defer wg.Done()
 
+	// Every result leaves through emit, which stamps the run ID
+	emit := func(result output.ProbeResult) {
+		result.RunID = p.config.RunID
+		results <- result
+	}
 	for expandedURL := range urls {
 		// Check if context is cancelled
 		select {
@@ -61,8 +84,57 @@ func (p *Prober) worker(ctx context.Context, urls <-chan string, results chan<-
 		default:
 		}
 
-		originalInput := originalInputMap[expandedURL]
-		result := p.ProbeURL(ctx, expandedURL, originalInput)
-		results <- result
+		if adaptive == nil {
+			p.probeExpanded(ctx, expandedURL, originalInputMap[expandedURL], emit)
+			continue
+		}
+		if err := adaptive.gate.acquire(ctx); err != nil {
+			return
+		}
+		p.probeExpanded(ctx, expandedURL, originalInputMap[expandedURL], emit)
+		adaptive.gate.release()
+	}
+}
+
+// probeExpanded probes one expanded URL and emits its results: its own,
+// or a skipped_first_alive result, and those of its -vhost-list candidates.
+func (p *Prober) probeExpanded(ctx context.Context, expandedURL, originalInput string, emit func(output.ProbeResult)) {
+	if p.inputs == nil {
+		result := p.probeRecovered(expandedURL, originalInput, func() output.ProbeResult {
+			return p.ProbeURL(ctx, expandedURL, originalInput)
+		})
+		emit(result)
+		p.probeVhosts(ctx, expandedURL, originalInput, result, emit)
+		return
+	}
+
+	// -first-alive: skip or cancel the rest of an input once one URL answers
+	probeCtx, end, ok := p.inputs.begin(ctx, originalInput)
+	if !ok {
+		if !p.config.SuppressSkipped {
+			skipped := skippedFirstAliveResult(expandedURL, originalInput)
+			skipped.NormalizedURL = p.normalizeURL(expandedURL)
+			emit(skipped)
+		}
+		return
+	}
+	result := p.probeRecovered(expandedURL, originalInput, func() output.ProbeResult {
+		return p.ProbeURL(probeCtx, expandedURL, originalInput)
+	})
+	end()
+
+	if output.IsAlive(result, p.firstAliveStatus) {
+		p.inputs.markDone(originalInput)
+	} else if result.Error != "" && ctx.Err() == nil && p.inputs.isDone(originalInput) {
+		// Cancelled mid-flight because another URL of this input answered
+		if p.config.SuppressSkipped {
+			return
+		}
+		result = skippedFirstAliveResult(expandedURL, originalInput)
+		result.NormalizedURL = p.normalizeURL(expandedURL)
+		emit(result)
+		return
 	}
+	emit(result)
+	p.probeVhosts(ctx, expandedURL, originalInput, result, emit)
 }
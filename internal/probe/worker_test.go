@@ -2,12 +2,17 @@ package probe
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"probeHTTP/internal/config"
+	"probeHTTP/internal/output"
 )
 
 func TestProcessURLs_CancelledContextReturnsPromptly(t *testing.T) {
@@ -44,3 +49,191 @@ func TestProcessURLs_CancelledContextReturnsPromptly(t *testing.T) {
 		t.Fatalf("expected prompt return for cancelled context, took %s", elapsed)
 	}
 }
+
+func TestProcessURLs_CancelDuringBackoffReturnsPromptly(t *testing.T) {
+	// A closed port refuses connections, sending every probe into retry backoff
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := config.New()
+	cfg.Silent = true
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Timeout = 5
+	cfg.MaxRetries = 5 // backoffs of 1s, 2s, 4s, ...
+	prober := NewProber(cfg)
+	defer prober.Close()
+
+	var urls []string
+	originalInputMap := make(map[string]string)
+	for i := 0; i < 8; i++ {
+		u := fmt.Sprintf("http://%s/%d", addr, i)
+		urls = append(urls, u)
+		originalInputMap[u] = addr
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := prober.ProcessURLs(ctx, urls, originalInputMap, 8)
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+
+	start := time.Now()
+	var got []output.ProbeResult
+	for result := range results {
+		got = append(got, result)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("ProcessURLs took %s to return after cancellation", elapsed)
+	}
+
+	if len(got) != len(urls) {
+		t.Fatalf("got %d results, want %d", len(got), len(urls))
+	}
+	for _, result := range got {
+		if result.ErrorType != output.ErrorTypeCancelled || result.Error != "cancelled" {
+			t.Errorf("%s: error %q (%s), want cancelled", result.URL, result.Error, result.ErrorType)
+		}
+		if result.URL == "" || result.Input != addr || result.Host != "127.0.0.1" || result.NormalizedURL == "" {
+			t.Errorf("cancelled result not populated: %+v", result)
+		}
+	}
+}
+
+func newFirstAliveProber(t *testing.T, suppress bool) *Prober {
+	t.Helper()
+	return newTestProber(t, func(cfg *config.Config) {
+		cfg.FirstAlive = true
+		cfg.SuppressSkipped = suppress
+	})
+}
+
+func TestProcessURLs_FirstAliveSkipsQueued(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	prober := newFirstAliveProber(t, false)
+
+	urls := []string{server.URL + "/a", server.URL + "/b", server.URL + "/c"}
+	originalInputMap := map[string]string{}
+	for _, u := range urls {
+		originalInputMap[u] = "example"
+	}
+
+	var ok, skipped int
+	for result := range prober.ProcessURLs(context.Background(), urls, originalInputMap, 1) {
+		switch {
+		case result.Error == "":
+			ok++
+		case result.ErrorType == output.ErrorTypeSkippedFirstAlive:
+			skipped++
+		default:
+			t.Errorf("unexpected error result: %s", result.Error)
+		}
+	}
+	if ok != 1 || skipped != 2 {
+		t.Errorf("got %d ok / %d skipped, want 1 / 2", ok, skipped)
+	}
+}
+
+// A 401 stops the input only when -alive-codes counts it as alive.
+func TestProcessURLs_FirstAliveUsesAliveCodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	urls := []string{server.URL + "/a", server.URL + "/b", server.URL + "/c"}
+	originalInputMap := map[string]string{}
+	for _, u := range urls {
+		originalInputMap[u] = "example"
+	}
+	custom, _ := output.ParseStatusRanges("200-299,401,403")
+	tests := []struct {
+		name        string
+		alive       output.StatusRanges
+		wantProbed  int
+		wantSkipped int
+	}{
+		{"default codes", nil, 3, 0},
+		{"custom codes", custom, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prober := newFirstAliveProber(t, false)
+			if tt.alive != nil {
+				prober.firstAliveStatus = tt.alive
+			}
+
+			var probed, skipped int
+			for result := range prober.ProcessURLs(context.Background(), urls, originalInputMap, 1) {
+				if result.ErrorType == output.ErrorTypeSkippedFirstAlive {
+					skipped++
+				} else if result.StatusCode == http.StatusUnauthorized {
+					probed++
+				}
+			}
+			if probed != tt.wantProbed || skipped != tt.wantSkipped {
+				t.Errorf("got %d probed / %d skipped, want %d / %d", probed, skipped, tt.wantProbed, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestProcessURLs_FirstAliveCancelsInFlight(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer fast.Close()
+
+	prober := newFirstAliveProber(t, true)
+
+	urls := []string{slow.URL, fast.URL}
+	originalInputMap := map[string]string{slow.URL: "example", fast.URL: "example"}
+
+	start := time.Now()
+	var results []output.ProbeResult
+	for result := range prober.ProcessURLs(context.Background(), urls, originalInputMap, 2) {
+		results = append(results, result)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("in-flight probe was not cancelled, took %s", elapsed)
+	}
+	if len(results) != 1 || results[0].URL != fast.URL {
+		t.Errorf("expected only the fast result with -suppress-skipped, got %+v", results)
+	}
+}
+
+func TestInputTracker_Isolation(t *testing.T) {
+	tracker := newInputTracker()
+	ctxA, endA, ok := tracker.begin(context.Background(), "a")
+	if !ok {
+		t.Fatal("begin on fresh input should succeed")
+	}
+	defer endA()
+	_, endB, _ := tracker.begin(context.Background(), "b")
+	defer endB()
+
+	tracker.markDone("b")
+	if ctxA.Err() != nil {
+		t.Error("marking one input done must not cancel another")
+	}
+	if _, _, ok := tracker.begin(context.Background(), "b"); ok {
+		t.Error("begin after markDone should report skip")
+	}
+	if tracker.isDone("a") {
+		t.Error("input a should not be done")
+	}
+}
@@ -0,0 +1,57 @@
+package scope
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// Scope is the allowlist of -scope-file composed with the exclusions of
+// -exclude. A target is in scope when it matches an allow entry and no deny
+// entry: deny wins over allow, and a Scope without allow entries refuses
+// everything.
+//
+// Allow entries use the Matcher syntax, except that a bare hostname is an
+// apex domain covering itself and all of its subdomains. CIDRs match IP
+// targets only; hostnames are never resolved to check them.
+type Scope struct {
+	allow *Matcher
+	deny  *Matcher
+}
+
+// NewScope builds a Scope from allow entries and an optional deny Matcher.
+func NewScope(allowEntries []string, deny *Matcher) (*Scope, error) {
+	var expanded []string
+	for _, entry := range allowEntries {
+		entry = strings.TrimSpace(entry)
+		expanded = append(expanded, entry)
+		if isApex(entry) {
+			expanded = append(expanded, "*."+strings.TrimSuffix(entry, "."))
+		}
+	}
+	allow, err := NewMatcher(expanded)
+	if err != nil {
+		return nil, err
+	}
+	return &Scope{allow: allow, deny: deny}, nil
+}
+
+// isApex reports whether an allow entry is a bare hostname rather than an
+// IP, CIDR, glob, comment or URL prefix.
+func isApex(entry string) bool {
+	if entry == "" || strings.HasPrefix(entry, "#") || strings.ContainsAny(entry, "/*?[:") {
+		return false
+	}
+	_, err := netip.ParseAddr(entry)
+	return err != nil
+}
+
+// Allows reports whether rawURL is in scope. A nil Scope allows everything.
+func (s *Scope) Allows(rawURL string) bool {
+	if s == nil {
+		return true
+	}
+	if s.deny.Excluded(rawURL) {
+		return false
+	}
+	return s.allow.Excluded(rawURL) // matches an allow entry
+}
@@ -0,0 +1,73 @@
+package scope
+
+import "testing"
+
+func TestScope_Allows(t *testing.T) {
+	deny, err := NewMatcher([]string{"admin.example.com", "10.0.9.0/24"})
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	s, err := NewScope([]string{
+		"# engagement scope",
+		"example.com",
+		"*.corp.example.net",
+		"10.0.0.0/16",
+		"2001:db8::/32",
+		"https://portal.example.org/app",
+	}, deny)
+	if err != nil {
+		t.Fatalf("NewScope: %v", err)
+	}
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"https://example.com", true},
+		{"http://www.example.com:8080/x", true}, // apex covers subdomains
+		{"https://a.b.example.com", true},
+		{"https://notexample.com", false},
+		{"https://example.com.evil.net", false},
+		{"https://admin.example.com", false},  // deny wins over the apex
+		{"https://x.admin.example.com", true}, // deny entry is exact
+		{"https://vpn.corp.example.net", true},
+		{"https://corp.example.net", false}, // glob covers subdomains only
+		{"http://10.0.3.4", true},
+		{"http://10.0.9.4", false}, // denied CIDR inside the allowed one
+		{"http://10.1.0.1", false},
+		{"http://[2001:db8::1]/", true},
+		{"https://portal.example.org/app/login", true},
+		{"https://portal.example.org/other", false},
+		{"https://unrelated.test", false},
+	}
+	for _, tt := range tests {
+		if got := s.Allows(tt.target); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestScope_EmptyRefusesEverything(t *testing.T) {
+	s, err := NewScope([]string{"", "# nothing in scope yet"}, nil)
+	if err != nil {
+		t.Fatalf("NewScope: %v", err)
+	}
+	for _, target := range []string{"https://example.com", "http://10.0.0.1", "localhost"} {
+		if s.Allows(target) {
+			t.Errorf("empty scope allowed %q", target)
+		}
+	}
+}
+
+func TestScope_NilAllowsEverything(t *testing.T) {
+	var s *Scope
+	if !s.Allows("https://example.com") {
+		t.Error("nil scope refused a target")
+	}
+}
+
+func TestNewScope_InvalidEntry(t *testing.T) {
+	if _, err := NewScope([]string{"example.com", "db-[.example.com"}, nil); err == nil {
+		t.Error("expected error for an invalid glob")
+	}
+}
@@ -0,0 +1,234 @@
+package scope
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// Matcher decides whether a target is out of scope. Entries can be:
+//
+//	exact hosts        admin.example.com
+//	host globs         *.excluded.example.com (subdomains only), other path.Match globs
+//	IPs and CIDRs      10.0.0.5, 10.0.0.0/8, 2001:db8::/32
+//	URL prefixes       https://foo.example.com/admin, or foo.example.com/admin for any scheme
+//
+// Exact hosts and leading-wildcard globs are map lookups per label, CIDRs are
+// map lookups per distinct prefix length.
+type Matcher struct {
+	hosts       map[string]struct{}
+	suffixes    map[string]struct{} // "*.example.com" stored as "example.com"
+	globs       []string
+	prefixes    map[int]map[netip.Prefix]struct{} // prefix length -> masked prefixes
+	urlPrefixes []string                          // with scheme
+	hostPaths   []string                          // host[:port]/path, any scheme
+}
+
+// NewMatcher builds a Matcher from exclusion entries. Blank entries and
+// "#" comments are ignored.
+func NewMatcher(entries []string) (*Matcher, error) {
+	m := &Matcher{
+		hosts:    make(map[string]struct{}),
+		suffixes: make(map[string]struct{}),
+		prefixes: make(map[int]map[netip.Prefix]struct{}),
+	}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if err := m.add(entry); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Matcher) add(entry string) error {
+	lower := strings.ToLower(entry)
+	switch {
+	case strings.Contains(lower, "://"):
+		u, err := url.Parse(lower)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid URL prefix %q", entry)
+		}
+		m.urlPrefixes = append(m.urlPrefixes, withoutDefaultPort(lower))
+	case strings.Contains(lower, "/") && isCIDR(lower):
+		prefix, err := netip.ParsePrefix(lower)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %q: %v", entry, err)
+		}
+		m.addPrefix(prefix)
+	case strings.Contains(lower, "/"):
+		m.hostPaths = append(m.hostPaths, lower)
+	default:
+		if addr, err := netip.ParseAddr(strings.Trim(lower, "[]")); err == nil {
+			m.addPrefix(netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			return nil
+		}
+		if strings.HasPrefix(lower, "*.") && !strings.ContainsAny(lower[2:], "*?[") {
+			m.suffixes[lower[2:]] = struct{}{}
+			return nil
+		}
+		if strings.ContainsAny(lower, "*?[") {
+			if _, err := path.Match(lower, ""); err != nil {
+				return fmt.Errorf("invalid glob %q: %v", entry, err)
+			}
+			m.globs = append(m.globs, lower)
+			return nil
+		}
+		m.hosts[lower] = struct{}{}
+	}
+	return nil
+}
+
+// isCIDR reports whether s looks like addr/bits rather than host/path.
+func isCIDR(s string) bool {
+	addr, bits, _ := strings.Cut(s, "/")
+	if _, err := netip.ParseAddr(addr); err != nil {
+		return false
+	}
+	for _, c := range bits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return bits != ""
+}
+
+func (m *Matcher) addPrefix(prefix netip.Prefix) {
+	prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked()
+	set, ok := m.prefixes[prefix.Bits()]
+	if !ok {
+		set = make(map[netip.Prefix]struct{})
+		m.prefixes[prefix.Bits()] = set
+	}
+	set[prefix] = struct{}{}
+}
+
+// Empty reports whether the matcher has no entries.
+func (m *Matcher) Empty() bool {
+	return m == nil || (len(m.hosts) == 0 && len(m.suffixes) == 0 && len(m.globs) == 0 &&
+		len(m.prefixes) == 0 && len(m.urlPrefixes) == 0 && len(m.hostPaths) == 0)
+}
+
+// ExcludedHost reports whether host (a hostname or IP, without port) is excluded.
+func (m *Matcher) ExcludedHost(host string) bool {
+	if m == nil {
+		return false
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if host == "" {
+		return false
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		for bits, set := range m.prefixes {
+			if bits > addr.BitLen() {
+				continue
+			}
+			prefix, err := addr.Prefix(bits)
+			if err != nil {
+				continue
+			}
+			if _, ok := set[prefix]; ok {
+				return true
+			}
+		}
+	}
+
+	if _, ok := m.hosts[host]; ok {
+		return true
+	}
+	// Walk parent domains: a.b.example.com -> b.example.com -> example.com -> com
+	for rest := host; ; {
+		i := strings.IndexByte(rest, '.')
+		if i < 0 {
+			break
+		}
+		rest = rest[i+1:]
+		if _, ok := m.suffixes[rest]; ok {
+			return true
+		}
+	}
+	for _, glob := range m.globs {
+		if ok, _ := path.Match(glob, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Excluded reports whether rawURL is excluded by host, IP or URL prefix.
+// Scheme-less targets are treated as http.
+func (m *Matcher) Excluded(rawURL string) bool {
+	if m.Empty() {
+		return false
+	}
+	target := rawURL
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	if m.ExcludedHost(u.Hostname()) {
+		return true
+	}
+	if len(m.urlPrefixes) == 0 && len(m.hostPaths) == 0 {
+		return false
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	full := withoutDefaultPort(u.String())
+	hostPath := u.Host + u.EscapedPath()
+	for _, prefix := range m.urlPrefixes {
+		if strings.HasPrefix(full, prefix) {
+			return true
+		}
+	}
+	for _, prefix := range m.hostPaths {
+		if strings.HasPrefix(hostPath, prefix) || strings.HasPrefix(withoutPort(u.Host)+u.EscapedPath(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutDefaultPort strips the scheme's default port (:80/:443) from a URL
+// so "https://foo:443/admin" and "https://foo/admin" compare equal.
+func withoutDefaultPort(prefix string) string {
+	u, err := url.Parse(prefix)
+	if err != nil {
+		return prefix
+	}
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+		return u.String()
+	}
+	return prefix
+}
+
+// withoutPort strips any port from host.
+func withoutPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// LoadFile reads exclusion entries from a file, one per line.
+func LoadFile(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(data), "\n"), nil
+}
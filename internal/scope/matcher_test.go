@@ -0,0 +1,109 @@
+package scope
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcher_Excluded(t *testing.T) {
+	m, err := NewMatcher([]string{
+		"# scope exclusions",
+		"",
+		"excluded.example.com",
+		"*.internal.example.com",
+		"db-??.example.org",
+		"10.0.0.0/8",
+		"192.168.1.7",
+		"2001:db8::/32",
+		"https://foo.example.com/admin",
+		"bar.example.com/private",
+	})
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"https://excluded.example.com", true},
+		{"http://EXCLUDED.example.com:8080/x", true},
+		{"excluded.example.com", true},
+		{"https://sub.excluded.example.com", false},
+		{"https://example.com", false},
+		{"https://a.internal.example.com", true},
+		{"https://a.b.internal.example.com/path", true},
+		{"https://internal.example.com", false},
+		{"https://db-01.example.org", true},
+		{"https://db-001.example.org", false},
+		{"http://10.1.2.3", true},
+		{"http://11.1.2.3", false},
+		{"http://192.168.1.7:8443", true},
+		{"http://192.168.1.8", false},
+		{"http://[2001:db8::1]:80/", true},
+		{"http://[2001:db9::1]/", false},
+		{"https://foo.example.com/admin", true},
+		{"https://foo.example.com:443/admin/users", true},
+		{"https://foo.example.com/public", false},
+		{"http://foo.example.com/admin", false},
+		{"http://bar.example.com/private/x", true},
+		{"https://bar.example.com:8443/private", true},
+		{"https://bar.example.com/", false},
+	}
+	for _, tt := range tests {
+		if got := m.Excluded(tt.target); got != tt.want {
+			t.Errorf("Excluded(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher_MappedIPv4(t *testing.T) {
+	m, err := NewMatcher([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.ExcludedHost("::ffff:10.1.2.3") {
+		t.Error("IPv4-mapped IPv6 address should match an IPv4 CIDR")
+	}
+}
+
+func TestMatcher_Invalid(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "https://", "[bad"} {
+		if _, err := NewMatcher([]string{entry}); err == nil {
+			t.Errorf("NewMatcher(%q) should fail", entry)
+		}
+	}
+}
+
+func TestMatcher_Empty(t *testing.T) {
+	var nilMatcher *Matcher
+	if !nilMatcher.Empty() || nilMatcher.Excluded("https://example.com") {
+		t.Error("nil matcher should be empty and exclude nothing")
+	}
+	m, _ := NewMatcher([]string{"", "# only comments"})
+	if !m.Empty() {
+		t.Error("matcher with only comments should be empty")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "exclude.txt")
+	if err := os.WriteFile(file, []byte("# comment\nexcluded.example.com\r\n10.0.0.0/8\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := LoadFile(file)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	m, err := NewMatcher(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Excluded("https://excluded.example.com") || !m.Excluded("http://10.9.9.9") {
+		t.Error("entries from file should be applied")
+	}
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing file should fail")
+	}
+}
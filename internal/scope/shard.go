@@ -0,0 +1,51 @@
+package scope
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/twmb/murmur3"
+)
+
+// Shard selects one of Count disjoint buckets of targets, so a scan can be
+// split across machines that all read the same input. Index is 1-based.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// ParseShard parses an "i/n" shard spec (e.g. "2/5") with 1 <= i <= n.
+func ParseShard(spec string) (*Shard, error) {
+	idx, count, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid shard %q: want i/n", spec)
+	}
+	i, err := strconv.Atoi(idx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard index %q", idx)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard count %q", count)
+	}
+	if n < 1 || i < 1 || i > n {
+		return nil, fmt.Errorf("invalid shard %q: want 1 <= i <= n", spec)
+	}
+	return &Shard{Index: i, Count: n}, nil
+}
+
+// String returns the shard in its "i/n" form.
+func (s *Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// Contains reports whether key hashes into this shard's bucket. Keys should
+// be normalized URLs so every machine assigns a target to the same bucket
+// whatever the input order or spelling.
+func (s *Shard) Contains(key string) bool {
+	if s == nil || s.Count <= 1 {
+		return true
+	}
+	return int(murmur3.StringSum32(key)%uint32(s.Count)) == s.Index-1
+}
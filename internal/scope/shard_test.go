@@ -0,0 +1,60 @@
+package scope
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseShard(t *testing.T) {
+	s, err := ParseShard(" 2/5 ")
+	if err != nil {
+		t.Fatalf("ParseShard: %v", err)
+	}
+	if s.Index != 2 || s.Count != 5 || s.String() != "2/5" {
+		t.Errorf("got %+v (%s), want 2/5", *s, s)
+	}
+
+	for _, spec := range []string{"", "2", "0/5", "6/5", "1/0", "-1/3", "a/3", "1/b", "1/2/3"} {
+		if _, err := ParseShard(spec); err == nil {
+			t.Errorf("ParseShard(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestShard_Partition(t *testing.T) {
+	urls := make([]string, 10000)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://host%d.example.com:%d/", i, 443+i%3)
+	}
+
+	for _, n := range []int{1, 2, 3, 5, 16} {
+		owners := make(map[string]int, len(urls))
+		for i := 1; i <= n; i++ {
+			shard := &Shard{Index: i, Count: n}
+			kept := 0
+			for _, u := range urls {
+				if !shard.Contains(u) {
+					continue
+				}
+				if prev, dup := owners[u]; dup {
+					t.Fatalf("n=%d: %s in shards %d and %d", n, u, prev, i)
+				}
+				owners[u] = i
+				kept++
+			}
+			if n > 1 && (kept < len(urls)/n/2 || kept > len(urls)/n*2) {
+				t.Errorf("n=%d: shard %d kept %d of %d URLs, badly unbalanced", n, i, kept, len(urls))
+			}
+		}
+		if len(owners) != len(urls) {
+			t.Errorf("n=%d: shards cover %d of %d URLs", n, len(owners), len(urls))
+		}
+	}
+}
+
+func TestShard_NilContainsAll(t *testing.T) {
+	var s *Shard
+	if !s.Contains("https://example.com/") {
+		t.Error("nil shard should contain every key")
+	}
+}
@@ -1,9 +1,15 @@
 package storage
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/sha1"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"mime"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -42,11 +48,41 @@ func SanitizeHost(host string) string {
 	return sanitized
 }
 
+// Storage layouts of -store-layout.
+const (
+	LayoutHostDirs = "host-dirs" // {baseDir}/{sanitized_host}/{hash}.txt
+	LayoutFlat     = "flat"      // {baseDir}/{hash}.txt
+)
+
+// MaxShardDepth is the most hash-prefix directory levels a Layout may add.
+const MaxShardDepth = 8
+
+// Layout arranges the stored files of a storage directory. Files are named
+// after the hash of the full URL either way, so flat names don't collide
+// across hosts; the index keeps the URLs.
+type Layout struct {
+	Mode       string // LayoutHostDirs or LayoutFlat; "" is LayoutHostDirs
+	ShardDepth int    // Directory levels named after successive 2-digit prefixes of the hash
+}
+
+// dir returns the directory holding the files named after filename.
+func (l Layout) dir(baseDir, host, filename string) string {
+	parts := []string{baseDir}
+	if l.Mode != LayoutFlat {
+		parts = append(parts, SanitizeHost(host))
+	}
+	for i := 0; i < l.ShardDepth && 2*i+2 <= len(filename); i++ {
+		parts = append(parts, filename[2*i:2*i+2])
+	}
+	return filepath.Join(parts...)
+}
+
 // BuildStoragePath creates the full path for storing a response.
 // Structure: {baseDir}/{sanitized_host}/{hash}.txt — matches HTTPx layout (no response/ subdir).
-func BuildStoragePath(baseDir, host, filename string) string {
-	sanitizedHost := SanitizeHost(host)
-	return filepath.Join(baseDir, sanitizedHost, filename+".txt")
+// A flat layout drops the host directory; a shard depth of 2 turns {hash}.txt
+// into ab/cd/{hash}.txt for a hash starting with abcd.
+func BuildStoragePath(baseDir, host, filename string, layout Layout) string {
+	return filepath.Join(layout.dir(baseDir, host, filename), filename+".txt")
 }
 
 // EnsureDir creates a directory and all parent directories if they don't exist
@@ -57,9 +93,9 @@ func EnsureDir(path string) error {
 
 // StoreResponse writes the response data to disk.
 // Returns the path where the file was stored.
-func StoreResponse(baseDir string, parsedURL *url.URL, data []byte) (string, error) {
+func StoreResponse(baseDir string, parsedURL *url.URL, data []byte, layout Layout) (string, error) {
 	filename := GenerateFilename(parsedURL.String())
-	storagePath := BuildStoragePath(baseDir, parsedURL.Host, filename)
+	storagePath := BuildStoragePath(baseDir, parsedURL.Host, filename, layout)
 
 	if err := EnsureDir(storagePath); err != nil {
 		return "", fmt.Errorf("failed to create storage directory: %w", err)
@@ -72,6 +108,53 @@ func StoreResponse(baseDir string, parsedURL *url.URL, data []byte) (string, err
 	return storagePath, nil
 }
 
+// BodyExtension picks the file extension for a stored body from its content
+// type: .html, .json, .txt for other textual types, .bin for everything else.
+func BodyExtension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return ".html"
+	case mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json"):
+		return ".json"
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/javascript" || mediaType == "application/x-javascript":
+		return ".txt"
+	default:
+		return ".bin"
+	}
+}
+
+// BuildBodyPath creates the path for a stored final body next to its response.
+// Structure: {baseDir}/{sanitized_host}/{hash}.body{ext}, arranged by layout
+// like BuildStoragePath.
+func BuildBodyPath(baseDir, host, filename, ext string, layout Layout) string {
+	return filepath.Join(layout.dir(baseDir, host, filename), filename+".body"+ext)
+}
+
+// StoreFinalBody writes the final hop's body to disk, named after the same
+// URL hash as the stored response so the two sit side by side.
+// Returns the path where the file was stored.
+func StoreFinalBody(baseDir string, parsedURL *url.URL, contentType string, body []byte, layout Layout) (string, error) {
+	filename := GenerateFilename(parsedURL.String())
+	bodyPath := BuildBodyPath(baseDir, parsedURL.Host, filename, BodyExtension(contentType), layout)
+
+	if err := EnsureDir(bodyPath); err != nil {
+		return "", fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	if err := os.WriteFile(bodyPath, body, 0644); err != nil {
+		return "", fmt.Errorf("failed to write body: %w", err)
+	}
+
+	return bodyPath, nil
+}
+
 // FormatStoredResponse formats the stored response file content in raw HTTP format.
 // Output matches HTTPx: raw request/response pairs per hop, final URL on last line.
 //
@@ -112,25 +195,125 @@ func FormatStoredResponse(chain []ChainEntry, finalURL string) []byte {
 	return []byte(builder.String())
 }
 
-// indexMu protects concurrent writes to index.txt
+// indexMu protects concurrent writes to index.txt and index.jsonl
 var indexMu sync.Mutex
 
-// AppendToIndex appends a line to the index.txt file in the storage directory.
+// IndexEntry describes one stored response in index.jsonl. Paths are
+// relative to the storage directory.
+type IndexEntry struct {
+	Path             string `json:"path"`                // Stored response file
+	BodyPath         string `json:"body_path,omitempty"` // Stored final body, with -store-final-body
+	URL              string `json:"url"`
+	FinalURL         string `json:"final_url"`
+	StatusCode       int    `json:"status_code"`
+	ChainStatusCodes []int  `json:"chain_status_codes,omitempty"`
+	ContentType      string `json:"content_type,omitempty"`
+	BodyMMH3         string `json:"body_mmh3,omitempty"`
+	StartedAt        string `json:"started_at,omitempty"` // RFC3339Nano, first request of the probe
+	StoredAt         string `json:"stored_at"`            // RFC3339Nano, when the response was written
+	RunID            string `json:"run_id,omitempty"`     // Run that stored the response
+}
+
+// AppendToIndex appends a line to the index.txt file in the storage directory,
+// leaving index.jsonl alone. Probes record stored responses with AppendIndex.
 // Format matches HTTPx: {relative_path} {url} ({statusCode} {statusText})
 // Example: www.hall.ag_80/85c766da...c4.txt http://www.hall.ag:80 (200 OK)
-func AppendToIndex(baseDir, storagePath, urlStr string, statusCode int, statusText string) error {
+//
+// A non-empty bodyPath is appended as a trailing field after the status so
+// readers of the HTTPx format keep working:
+// www.hall.ag_80/85c766da...c4.txt http://www.hall.ag:80 (200 OK) www.hall.ag_80/85c766da...c4.body.html
+func AppendToIndex(baseDir, storagePath, urlStr string, statusCode int, statusText, bodyPath string) error {
+	relStorage := relPath(baseDir, storagePath)
+	var relBody string
+	if bodyPath != "" {
+		relBody = relPath(baseDir, bodyPath)
+	}
 	indexMu.Lock()
 	defer indexMu.Unlock()
+	return appendIndexLine(baseDir, relStorage, urlStr, statusCode, statusText, relBody)
+}
 
-	indexPath := filepath.Join(baseDir, "index.txt")
-	relPath, _ := filepath.Rel(baseDir, storagePath)
-	line := fmt.Sprintf("%s %s (%d %s)\n", relPath, urlStr, statusCode, statusText)
+// AppendIndex records a stored response in both index.txt and index.jsonl.
+// entry.Path and entry.BodyPath are paths inside baseDir, as StoreResponse
+// and StoreFinalBody return them; they are written relative to baseDir.
+// Each file gets its line in a single append, and concurrent calls write
+// both files before the next call starts, so the two indexes list
+// responses in the same order.
+func AppendIndex(baseDir string, entry IndexEntry, statusText string) error {
+	entry.Path = relPath(baseDir, entry.Path)
+	if entry.BodyPath != "" {
+		entry.BodyPath = relPath(baseDir, entry.BodyPath)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
 
-	f, err := os.OpenFile(indexPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	if err := appendIndexLine(baseDir, entry.Path, entry.URL, entry.StatusCode, statusText, entry.BodyPath); err != nil {
+		return err
+	}
+	return appendLine(filepath.Join(baseDir, "index.jsonl"), append(line, '\n'))
+}
+
+// LoadIndex reads the index.jsonl of a storage directory, in the order the
+// responses were stored. A directory without one has no entries.
+func LoadIndex(baseDir string) ([]IndexEntry, error) {
+	file, err := os.Open(filepath.Join(baseDir, "index.jsonl"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage index: %w", err)
+	}
+	defer file.Close()
+
+	var entries []IndexEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry IndexEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("storage index line %d: %w", lineNo, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read storage index: %w", err)
+	}
+	return entries, nil
+}
+
+// appendIndexLine writes one index.txt line for paths already relative to
+// baseDir. The caller holds indexMu.
+func appendIndexLine(baseDir, relStorage, urlStr string, statusCode int, statusText, relBody string) error {
+	line := fmt.Sprintf("%s %s (%d %s)", relStorage, urlStr, statusCode, statusText)
+	if relBody != "" {
+		line += " " + relBody
+	}
+	return appendLine(filepath.Join(baseDir, "index.txt"), []byte(line+"\n"))
+}
+
+// appendLine appends line to the file at path in one write.
+func appendLine(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	_, err = f.WriteString(line)
-	return err
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// relPath returns path, a path inside baseDir, relative to baseDir.
+func relPath(baseDir, path string) string {
+	rel, _ := filepath.Rel(baseDir, path)
+	return rel
 }
@@ -3,9 +3,11 @@ package storage
 import (
 	"crypto/sha1"
 	"encoding/hex"
+	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -67,7 +69,7 @@ func TestBuildStoragePath(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := BuildStoragePath(tt.baseDir, tt.host, tt.filename)
+			got := BuildStoragePath(tt.baseDir, tt.host, tt.filename, Layout{})
 			if got != tt.want {
 				t.Errorf("BuildStoragePath() = %q, want %q", got, tt.want)
 			}
@@ -79,6 +81,28 @@ func TestBuildStoragePath(t *testing.T) {
 	}
 }
 
+func TestBuildStoragePath_Layouts(t *testing.T) {
+	const hash = "abcdef0123"
+	tests := []struct {
+		layout   Layout
+		wantPath string
+		wantBody string
+	}{
+		{Layout{Mode: LayoutHostDirs}, "example.com_8080/abcdef0123.txt", "example.com_8080/abcdef0123.body.html"},
+		{Layout{Mode: LayoutFlat}, "abcdef0123.txt", "abcdef0123.body.html"},
+		{Layout{Mode: LayoutFlat, ShardDepth: 2}, "ab/cd/abcdef0123.txt", "ab/cd/abcdef0123.body.html"},
+		{Layout{ShardDepth: 1}, "example.com_8080/ab/abcdef0123.txt", "example.com_8080/ab/abcdef0123.body.html"},
+	}
+	for _, tt := range tests {
+		if got := BuildStoragePath("/srd", "example.com:8080", hash, tt.layout); got != filepath.Join("/srd", tt.wantPath) {
+			t.Errorf("BuildStoragePath(%+v) = %q, want /srd/%s", tt.layout, got, tt.wantPath)
+		}
+		if got := BuildBodyPath("/srd", "example.com:8080", hash, ".html", tt.layout); got != filepath.Join("/srd", tt.wantBody) {
+			t.Errorf("BuildBodyPath(%+v) = %q, want /srd/%s", tt.layout, got, tt.wantBody)
+		}
+	}
+}
+
 func TestFormatStoredResponse_SingleHop(t *testing.T) {
 	chain := []ChainEntry{
 		{
@@ -195,7 +219,7 @@ func TestStoreResponse(t *testing.T) {
 	data := []byte("test response data")
 	parsedURL := mustParseURL("https://example.com/page")
 
-	storagePath, err := StoreResponse(tmpDir, parsedURL, data)
+	storagePath, err := StoreResponse(tmpDir, parsedURL, data, Layout{})
 	if err != nil {
 		t.Fatalf("StoreResponse() error: %v", err)
 	}
@@ -221,11 +245,61 @@ func TestStoreResponse(t *testing.T) {
 	}
 }
 
+func TestBodyExtension(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"text/html; charset=utf-8", ".html"},
+		{"application/xhtml+xml", ".html"},
+		{"application/json", ".json"},
+		{"application/problem+json", ".json"},
+		{"text/plain", ".txt"},
+		{"text/css", ".txt"},
+		{"application/xml", ".txt"},
+		{"application/javascript", ".txt"},
+		{"application/pdf", ".bin"},
+		{"image/png", ".bin"},
+		{"", ".bin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := BodyExtension(tt.contentType); got != tt.want {
+				t.Errorf("BodyExtension(%q) = %q, want %q", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreFinalBody(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	body := []byte("<html><body>final</body></html>")
+	parsedURL := mustParseURL("https://example.com/page")
+
+	bodyPath, err := StoreFinalBody(tmpDir, parsedURL, "text/html", body, Layout{})
+	if err != nil {
+		t.Fatalf("StoreFinalBody() error: %v", err)
+	}
+
+	want := filepath.Join(tmpDir, "example.com", sha1Hex("https://example.com/page")+".body.html")
+	if bodyPath != want {
+		t.Errorf("body path = %q, want %q", bodyPath, want)
+	}
+	content, err := os.ReadFile(bodyPath)
+	if err != nil {
+		t.Fatalf("failed to read stored body: %v", err)
+	}
+	if string(content) != string(body) {
+		t.Errorf("stored body = %q, want %q", content, body)
+	}
+}
+
 func TestAppendToIndex(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	storagePath := filepath.Join(tmpDir, "example.com_80", "abc123.txt")
-	err := AppendToIndex(tmpDir, storagePath, "http://example.com:80", 200, "OK")
+	err := AppendToIndex(tmpDir, storagePath, "http://example.com:80", 200, "OK", "")
 	if err != nil {
 		t.Fatalf("AppendToIndex() error: %v", err)
 	}
@@ -246,11 +320,32 @@ func TestAppendToIndex(t *testing.T) {
 	}
 }
 
+func TestAppendToIndex_WithBodyPath(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	storagePath := filepath.Join(tmpDir, "example.com_80", "abc123.txt")
+	bodyPath := filepath.Join(tmpDir, "example.com_80", "abc123.body.html")
+	if err := AppendToIndex(tmpDir, storagePath, "http://example.com:80", 200, "OK", bodyPath); err != nil {
+		t.Fatalf("AppendToIndex() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "index.txt"))
+	if err != nil {
+		t.Fatalf("failed to read index.txt: %v", err)
+	}
+
+	// Body path trails the HTTPx fields so existing readers still match
+	expected := "example.com_80/abc123.txt http://example.com:80 (200 OK) example.com_80/abc123.body.html\n"
+	if string(content) != expected {
+		t.Errorf("index line = %q, want %q", content, expected)
+	}
+}
+
 func TestAppendToIndex_Multiple(t *testing.T) {
 	tmpDir := t.TempDir()
 
-	AppendToIndex(tmpDir, filepath.Join(tmpDir, "a.com_80", "hash1.txt"), "http://a.com:80", 200, "OK")
-	AppendToIndex(tmpDir, filepath.Join(tmpDir, "b.com_443", "hash2.txt"), "https://b.com:443", 301, "Moved Permanently")
+	AppendToIndex(tmpDir, filepath.Join(tmpDir, "a.com_80", "hash1.txt"), "http://a.com:80", 200, "OK", "")
+	AppendToIndex(tmpDir, filepath.Join(tmpDir, "b.com_443", "hash2.txt"), "https://b.com:443", 301, "Moved Permanently", "")
 
 	content, _ := os.ReadFile(filepath.Join(tmpDir, "index.txt"))
 	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
@@ -277,7 +372,7 @@ func TestAppendToIndex_Concurrent(t *testing.T) {
 		go func(i int) {
 			defer wg.Done()
 			storagePath := filepath.Join(tmpDir, "host", "file.txt")
-			AppendToIndex(tmpDir, storagePath, "http://example.com", 200, "OK")
+			AppendToIndex(tmpDir, storagePath, "http://example.com", 200, "OK", "")
 		}(i)
 	}
 	wg.Wait()
@@ -289,6 +384,165 @@ func TestAppendToIndex_Concurrent(t *testing.T) {
 	}
 }
 
+func TestAppendIndex_RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	entries := []IndexEntry{
+		{
+			Path:             filepath.Join(tmpDir, "example.com_80", "abc.txt"),
+			BodyPath:         filepath.Join(tmpDir, "example.com_80", "abc.body.html"),
+			URL:              "http://example.com/a path/(copy)",
+			FinalURL:         "http://example.com/a%20path/",
+			StatusCode:       200,
+			ChainStatusCodes: []int{301, 200},
+			ContentType:      "text/html; charset=utf-8",
+			BodyMMH3:         "-123456",
+			StartedAt:        "2026-10-17T10:00:00.5Z",
+			StoredAt:         "2026-10-17T10:00:01Z",
+		},
+		{
+			Path:       filepath.Join(tmpDir, "xn--bcher-kva.example_443", "def.txt"),
+			URL:        "https://bücher.example/übersicht?q=ä ö",
+			FinalURL:   "https://bücher.example/übersicht?q=ä ö",
+			StatusCode: 404,
+			StoredAt:   "2026-10-17T10:00:02Z",
+		},
+	}
+	for _, entry := range entries {
+		if err := AppendIndex(tmpDir, entry, "OK"); err != nil {
+			t.Fatalf("AppendIndex: %v", err)
+		}
+	}
+
+	loaded, err := LoadIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadIndex: %v", err)
+	}
+	entries[0].Path, entries[0].BodyPath = "example.com_80/abc.txt", "example.com_80/abc.body.html"
+	entries[1].Path = "xn--bcher-kva.example_443/def.txt"
+	if !reflect.DeepEqual(loaded, entries) {
+		t.Errorf("LoadIndex =\n%+v\nwant\n%+v", loaded, entries)
+	}
+
+	// index.txt keeps its format next to the JSON index
+	content, _ := os.ReadFile(filepath.Join(tmpDir, "index.txt"))
+	if !strings.HasPrefix(string(content), "example.com_80/abc.txt http://example.com/a path/(copy) (200 OK) example.com_80/abc.body.html\n") {
+		t.Errorf("index.txt = %q", content)
+	}
+}
+
+func TestAppendIndex_Concurrent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var wg sync.WaitGroup
+	n := 50
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			AppendIndex(tmpDir, IndexEntry{
+				Path:       filepath.Join(tmpDir, "host", fmt.Sprintf("%d.txt", i)),
+				URL:        fmt.Sprintf("http://example.com/%d", i),
+				StatusCode: 200,
+			}, "OK")
+		}(i)
+	}
+	wg.Wait()
+
+	entries, err := LoadIndex(tmpDir)
+	if err != nil || len(entries) != n {
+		t.Fatalf("LoadIndex = %d entries, %v; want %d", len(entries), err, n)
+	}
+	content, _ := os.ReadFile(filepath.Join(tmpDir, "index.txt"))
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if len(lines) != n {
+		t.Fatalf("index.txt has %d lines, want %d", len(lines), n)
+	}
+	// Both indexes list the responses in the same order
+	for i, entry := range entries {
+		if !strings.HasPrefix(lines[i], entry.Path+" "+entry.URL+" ") {
+			t.Errorf("line %d: index.txt %q, index.jsonl %s", i, lines[i], entry.URL)
+		}
+	}
+}
+
+// Every layout stores distinct files for the same path on different hosts
+// and ports, and the index leads back to each of them.
+func TestStoreResponse_LayoutRoundTrip(t *testing.T) {
+	urls := []string{
+		"http://example.com/",
+		"http://example.com:8080/",
+		"https://example.com/",
+		"https://other.example/",
+		"https://other.example/login?next=%2F",
+	}
+	layouts := map[string]Layout{
+		"host-dirs": {Mode: LayoutHostDirs},
+		"flat":      {Mode: LayoutFlat},
+		"sharded":   {Mode: LayoutFlat, ShardDepth: 2},
+	}
+	for name, layout := range layouts {
+		t.Run(name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for _, raw := range urls {
+				path, err := StoreResponse(tmpDir, mustParseURL(raw), []byte("response of "+raw), layout)
+				if err != nil {
+					t.Fatalf("StoreResponse(%s): %v", raw, err)
+				}
+				bodyPath, err := StoreFinalBody(tmpDir, mustParseURL(raw), "text/html", []byte("body of "+raw), layout)
+				if err != nil {
+					t.Fatalf("StoreFinalBody(%s): %v", raw, err)
+				}
+				entry := IndexEntry{Path: path, BodyPath: bodyPath, URL: raw, FinalURL: raw, StatusCode: 200}
+				if err := AppendIndex(tmpDir, entry, "OK"); err != nil {
+					t.Fatalf("AppendIndex: %v", err)
+				}
+			}
+
+			entries, err := LoadIndex(tmpDir)
+			if err != nil || len(entries) != len(urls) {
+				t.Fatalf("LoadIndex = %d entries, %v; want %d", len(entries), err, len(urls))
+			}
+			seen := map[string]bool{}
+			for i, entry := range entries {
+				if entry.URL != urls[i] || seen[entry.Path] {
+					t.Errorf("entry %d: %s at %s (duplicate: %v)", i, entry.URL, entry.Path, seen[entry.Path])
+				}
+				seen[entry.Path] = true
+				wantDepth := layout.ShardDepth
+				if layout.Mode != LayoutFlat {
+					wantDepth++
+				}
+				if depth := strings.Count(entry.Path, string(filepath.Separator)); depth != wantDepth {
+					t.Errorf("%s: %d directory levels, want %d", entry.Path, depth, wantDepth)
+				}
+				response, err := os.ReadFile(filepath.Join(tmpDir, entry.Path))
+				if err != nil || string(response) != "response of "+entry.URL {
+					t.Errorf("%s: %q, %v", entry.Path, response, err)
+				}
+				body, err := os.ReadFile(filepath.Join(tmpDir, entry.BodyPath))
+				if err != nil || string(body) != "body of "+entry.URL {
+					t.Errorf("%s: %q, %v", entry.BodyPath, body, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadIndex_Missing(t *testing.T) {
+	entries, err := LoadIndex(t.TempDir())
+	if err != nil || entries != nil {
+		t.Errorf("LoadIndex = %v, %v; want no entries", entries, err)
+	}
+}
+
+func TestLoadIndex_Malformed(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "index.jsonl"), []byte(`{"path":"a.txt","url":"http://a"}`+"\n\nnot json\n"), 0644)
+	if _, err := LoadIndex(tmpDir); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error = %v, want a line 3 error", err)
+	}
+}
+
 func TestSanitizeHost(t *testing.T) {
 	tests := []struct {
 		input string
@@ -0,0 +1,148 @@
+package waf
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Confidence levels reported by DetectWAF
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// wafRule defines a WAF detection rule
+type wafRule struct {
+	Name       string
+	Confidence string
+	CheckFunc  func(status int, h http.Header, body []byte) bool
+}
+
+// tsCookie matches F5 BIG-IP ASM session cookies (TS followed by hex)
+var tsCookie = regexp.MustCompile(`^TS[0-9a-fA-F]{6,}$`)
+
+// Rules are ordered so block-page signatures win over presence hints
+var wafRules = []wafRule{
+	{
+		Name:       "Cloudflare",
+		Confidence: ConfidenceHigh,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return h.Get("Cf-Ray") != "" && (status == 403 || status == 503) &&
+				bodyContains(body, "Attention Required", "cf-error-details")
+		},
+	},
+	{
+		Name:       "Akamai",
+		Confidence: ConfidenceHigh,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return headerContains(h, "Server", "akamaighost") && bodyContains(body, "Reference #")
+		},
+	},
+	{
+		Name:       "ModSecurity",
+		Confidence: ConfidenceHigh,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return (status == 406 || status == 501) && bodyContains(body, "mod_security", "modsecurity", "NOYB")
+		},
+	},
+	{
+		Name:       "F5 BIG-IP",
+		Confidence: ConfidenceHigh,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return bodyContains(body, "The requested URL was rejected") && bodyContains(body, "support ID")
+		},
+	},
+	{
+		Name:       "Imperva",
+		Confidence: ConfidenceHigh,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return h.Get("X-Iinfo") != "" || hasCookie(h, func(name string) bool {
+				return strings.HasPrefix(name, "visid_incap_") || strings.HasPrefix(name, "incap_ses_")
+			})
+		},
+	},
+	{
+		Name:       "F5 BIG-IP",
+		Confidence: ConfidenceMedium,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return hasCookie(h, func(name string) bool {
+				return strings.HasPrefix(name, "BIGipServer") || tsCookie.MatchString(name)
+			})
+		},
+	},
+	{
+		Name:       "ModSecurity",
+		Confidence: ConfidenceMedium,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return headerContains(h, "Server", "mod_security")
+		},
+	},
+	{
+		Name:       "AWS WAF",
+		Confidence: ConfidenceMedium,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return status == 403 && h.Get("X-Amzn-Requestid") != ""
+		},
+	},
+	{
+		Name:       "Cloudflare",
+		Confidence: ConfidenceLow,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return h.Get("Cf-Ray") != "" && (status == 403 || status == 503)
+		},
+	},
+	{
+		Name:       "Akamai",
+		Confidence: ConfidenceLow,
+		CheckFunc: func(status int, h http.Header, body []byte) bool {
+			return status == 403 && headerContains(h, "Server", "akamaighost")
+		},
+	},
+}
+
+// maxBodyScan bounds how much of the body is searched; block pages are small
+const maxBodyScan = 32 * 1024
+
+// DetectWAF checks a response for WAF signatures and returns the first
+// match with its confidence ("high", "medium" or "low").
+func DetectWAF(status int, headers http.Header, body []byte) (bool, string, string) {
+	if len(body) > maxBodyScan {
+		body = body[:maxBodyScan]
+	}
+	lowerBody := bytes.ToLower(body)
+	for _, rule := range wafRules {
+		if rule.CheckFunc(status, headers, lowerBody) {
+			return true, rule.Name, rule.Confidence
+		}
+	}
+	return false, "", ""
+}
+
+// headerContains reports whether header key contains substr (case-insensitive)
+func headerContains(h http.Header, key, substr string) bool {
+	return strings.Contains(strings.ToLower(h.Get(key)), substr)
+}
+
+// bodyContains reports whether the lowercased body contains any of the needles
+func bodyContains(body []byte, needles ...string) bool {
+	for _, needle := range needles {
+		if bytes.Contains(body, []byte(strings.ToLower(needle))) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCookie reports whether any Set-Cookie name satisfies match
+func hasCookie(h http.Header, match func(name string) bool) bool {
+	for _, line := range h.Values("Set-Cookie") {
+		name, _, _ := strings.Cut(line, "=")
+		if match(strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package waf
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDetectWAF(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		headers        http.Header
+		body           string
+		wantWAF        bool
+		wantName       string
+		wantConfidence string
+	}{
+		{
+			"Cloudflare block page",
+			403,
+			http.Header{"Cf-Ray": {"7d1c2b3a4e5f6a7b-FRA"}, "Server": {"cloudflare"}},
+			`<title>Attention Required! | Cloudflare</title><div id="cf-error-details">`,
+			true, "Cloudflare", ConfidenceHigh,
+		},
+		{
+			"Cloudflare 503 without block page",
+			503,
+			http.Header{"Cf-Ray": {"7d1c2b3a4e5f6a7b-FRA"}},
+			"",
+			true, "Cloudflare", ConfidenceLow,
+		},
+		{
+			"Cloudflare 200 is not a WAF hit",
+			200,
+			http.Header{"Cf-Ray": {"7d1c2b3a4e5f6a7b-FRA"}},
+			"<html>ok</html>",
+			false, "", "",
+		},
+		{
+			"Akamai access denied",
+			403,
+			http.Header{"Server": {"AkamaiGHost"}},
+			"<H1>Access Denied</H1>You don't have permission to access ... Reference&#32;#18&#46;c2d1;Reference #18.c2d1d417.1700000000.2a3b4c5d",
+			true, "Akamai", ConfidenceHigh,
+		},
+		{
+			"AWS WAF 403",
+			403,
+			http.Header{"X-Amzn-Requestid": {"6f0c1a9e-1111-2222-3333-444455556666"}},
+			"<html><h1>403 Forbidden</h1></html>",
+			true, "AWS WAF", ConfidenceMedium,
+		},
+		{
+			"AWS request id on 200",
+			200,
+			http.Header{"X-Amzn-Requestid": {"6f0c1a9e-1111-2222-3333-444455556666"}},
+			"",
+			false, "", "",
+		},
+		{
+			"Imperva cookies",
+			200,
+			http.Header{"Set-Cookie": {
+				"visid_incap_123456=abcDEF; expires=Thu, 01 Jan 2026 00:00:00 GMT; path=/; Domain=.example.com",
+				"incap_ses_789_123456=xyz; path=/; Domain=.example.com",
+			}},
+			"",
+			true, "Imperva", ConfidenceHigh,
+		},
+		{
+			"Imperva X-Iinfo",
+			403,
+			http.Header{"X-Iinfo": {"9-12345678-0 0NNN RT(1700000000000 0) q(0 -1 -1 -1) r(0 -1)"}},
+			"",
+			true, "Imperva", ConfidenceHigh,
+		},
+		{
+			"F5 rejection page",
+			200,
+			http.Header{},
+			"<html><head><title>Request Rejected</title></head><body>The requested URL was rejected. Please consult with your administrator.<br><br>Your support ID is: 1234567890123456789</body></html>",
+			true, "F5 BIG-IP", ConfidenceHigh,
+		},
+		{
+			"F5 BIG-IP cookies",
+			200,
+			http.Header{"Set-Cookie": {"BIGipServerpool_web=1677830336.20480.0000; path=/; Httponly", "TS01a2b3c4=01abcdef; Path=/"}},
+			"",
+			true, "F5 BIG-IP", ConfidenceMedium,
+		},
+		{
+			"F5 TS cookie alone",
+			200,
+			http.Header{"Set-Cookie": {"TS0123abcd=01abcdef; Path=/"}},
+			"",
+			true, "F5 BIG-IP", ConfidenceMedium,
+		},
+		{
+			"ModSecurity 406",
+			406,
+			http.Header{"Server": {"Apache"}},
+			"<html><head><title>Not Acceptable!</title></head><body><p>An appropriate representation of the requested resource could not be found on this server. This error was generated by Mod_Security.</p></body></html>",
+			true, "ModSecurity", ConfidenceHigh,
+		},
+		{
+			"ModSecurity server header",
+			200,
+			http.Header{"Server": {"Apache/2.4 Mod_Security/2.9"}},
+			"",
+			true, "ModSecurity", ConfidenceMedium,
+		},
+		{
+			"plain response",
+			200,
+			http.Header{"Server": {"nginx"}, "Set-Cookie": {"session=abc; Path=/", "TSX=1"}},
+			"<html>hello</html>",
+			false, "", "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waf, name, confidence := DetectWAF(tt.status, tt.headers, []byte(tt.body))
+			if waf != tt.wantWAF || name != tt.wantName || confidence != tt.wantConfidence {
+				t.Errorf("DetectWAF() = (%v, %q, %q), want (%v, %q, %q)",
+					waf, name, confidence, tt.wantWAF, tt.wantName, tt.wantConfidence)
+			}
+		})
+	}
+}
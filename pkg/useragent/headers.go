@@ -0,0 +1,133 @@
+package useragent
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+// Family is a browser family, used to pick request headers consistent with
+// a User-Agent.
+type Family string
+
+// Browser families with header profiles.
+const (
+	FamilyChrome  Family = "chrome"
+	FamilyEdge    Family = "edge"
+	FamilyFirefox Family = "firefox"
+	FamilySafari  Family = "safari"
+)
+
+// Header is a request header name and value.
+type Header struct {
+	Name  string
+	Value string
+}
+
+// HeaderProfile lists the header values a browser family sends on a
+// top-level navigation. One value is picked from each list.
+type HeaderProfile struct {
+	Accept         []string
+	AcceptLanguage []string
+	Fixed          []Header // Always sent
+	Optional       []Header // Each sent or left out at random
+}
+
+// fetchMetadata are the Sec-Fetch-* headers of a typed-in navigation
+var fetchMetadata = []Header{
+	{"Sec-Fetch-Dest", "document"},
+	{"Sec-Fetch-Mode", "navigate"},
+	{"Sec-Fetch-Site", "none"},
+	{"Sec-Fetch-User", "?1"},
+}
+
+// chromiumProfile is shared by Chrome and Edge
+var chromiumProfile = HeaderProfile{
+	Accept: []string{
+		"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
+	},
+	AcceptLanguage: []string{
+		"en-US,en;q=0.9",
+		"en-GB,en-US;q=0.9,en;q=0.8",
+		"de-DE,de;q=0.9,en-US;q=0.8,en;q=0.7",
+		"fr-FR,fr;q=0.9,en-US;q=0.8,en;q=0.7",
+	},
+	Fixed:    append([]Header{{"Upgrade-Insecure-Requests", "1"}}, fetchMetadata...),
+	Optional: []Header{{"Cache-Control", "max-age=0"}},
+}
+
+// Profiles maps each family to the headers its browsers send.
+var Profiles = map[Family]HeaderProfile{
+	FamilyChrome: chromiumProfile,
+	FamilyEdge:   chromiumProfile,
+	FamilyFirefox: {
+		Accept: []string{
+			"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
+		},
+		AcceptLanguage: []string{
+			"en-US,en;q=0.5",
+			"en-GB,en;q=0.5",
+			"de,en-US;q=0.7,en;q=0.3",
+			"fr,fr-FR;q=0.8,en-US;q=0.5,en;q=0.3",
+		},
+		Fixed:    append([]Header{{"Upgrade-Insecure-Requests", "1"}}, fetchMetadata...),
+		Optional: []Header{{"DNT", "1"}},
+	},
+	FamilySafari: {
+		Accept: []string{
+			"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		},
+		AcceptLanguage: []string{
+			"en-US,en;q=0.9",
+			"en-GB,en;q=0.9",
+			"de-DE,de;q=0.9",
+		},
+		Fixed: fetchMetadata,
+	},
+}
+
+// FamilyOf returns the browser family of a User-Agent. Unrecognized agents
+// (custom -ua values, tools) are treated as Chrome, the most common family.
+func FamilyOf(ua string) Family {
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		return FamilyEdge
+	case strings.Contains(ua, "Firefox/"):
+		return FamilyFirefox
+	case strings.Contains(ua, "Chrome/"):
+		return FamilyChrome
+	case strings.Contains(ua, "Safari/"):
+		return FamilySafari
+	default:
+		return FamilyChrome
+	}
+}
+
+// RandomHeaders picks browser headers consistent with ua: Accept and
+// Accept-Language from its family's profile, plus the family's fixed
+// headers and a random subset of its optional ones.
+func RandomHeaders(ua string) (Family, []Header) {
+	family := FamilyOf(ua)
+	profile := Profiles[family]
+
+	headers := []Header{
+		{"Accept", pick(profile.Accept)},
+		{"Accept-Language", pick(profile.AcceptLanguage)},
+	}
+	headers = append(headers, profile.Fixed...)
+	for _, h := range profile.Optional {
+		if pick([]bool{true, false}) {
+			headers = append(headers, h)
+		}
+	}
+	return family, headers
+}
+
+// pick returns a random element of values using crypto/rand
+func pick[T any](values []T) T {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(values))))
+	if err != nil {
+		return values[0]
+	}
+	return values[n.Int64()]
+}
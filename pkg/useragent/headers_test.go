@@ -0,0 +1,77 @@
+package useragent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFamilyOf(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want Family
+	}{
+		{Pool[0], FamilyChrome},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", FamilyFirefox},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15", FamilySafari},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", FamilyEdge},
+		{"curl/8.5.0", FamilyChrome},
+	}
+	for _, tt := range tests {
+		if got := FamilyOf(tt.ua); got != tt.want {
+			t.Errorf("FamilyOf(%q) = %q, want %q", tt.ua, got, tt.want)
+		}
+	}
+}
+
+func TestRandomHeaders_ConsistentWithFamily(t *testing.T) {
+	firefoxAccept := Profiles[FamilyFirefox].Accept[0]
+	for _, ua := range Pool {
+		for i := 0; i < 20; i++ {
+			family, headers := RandomHeaders(ua)
+			accept := headerValue(headers, "Accept")
+			language := headerValue(headers, "Accept-Language")
+			if !contains(Profiles[family].Accept, accept) || !contains(Profiles[family].AcceptLanguage, language) {
+				t.Fatalf("%s: Accept %q / Accept-Language %q not from the %s profile", ua, accept, language, family)
+			}
+			if family == FamilyChrome && accept == firefoxAccept {
+				t.Fatalf("Chrome UA %q got the Firefox Accept string", ua)
+			}
+			if family == FamilyChrome && strings.Contains(language, ";q=0.5") {
+				t.Fatalf("Chrome UA %q got a Firefox-style Accept-Language %q", ua, language)
+			}
+		}
+	}
+}
+
+func TestRandomHeaders_Varies(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		_, headers := RandomHeaders(Pool[0])
+		var parts []string
+		for _, h := range headers {
+			parts = append(parts, h.Name+": "+h.Value)
+		}
+		seen[strings.Join(parts, "\n")] = true
+	}
+	if len(seen) < 2 {
+		t.Error("RandomHeaders returned the same header set 50 times")
+	}
+}
+
+func headerValue(headers []Header, name string) string {
+	for _, h := range headers {
+		if h.Name == name {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -24,6 +24,17 @@ func BenchmarkCalculateMMH3(b *testing.B) {
 	}
 }
 
+// Benchmark the combined hash and entropy pass over a typical body
+func BenchmarkCalculateBodyDigest(b *testing.B) {
+	data := []byte(strings.Repeat("<p>test data with some content</p>\n", 2000))
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hash.CalculateBodyDigest(data)
+	}
+}
+
 // Benchmark header hash calculation
 func BenchmarkCalculateHeaderMMH3(b *testing.B) {
 	headers := http.Header{
@@ -95,7 +106,7 @@ func BenchmarkExpandURLs(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		for _, tc := range testCases {
-			parser.ExpandURLs(tc.url, tc.allSchemes, tc.ignorePorts, tc.customPorts)
+			parser.ExpandURLs(tc.url, tc.allSchemes, tc.ignorePorts, tc.customPorts, parser.StandardPorts)
 		}
 	}
 }
@@ -134,13 +145,125 @@ func BenchmarkProbeURL(b *testing.B) {
 	defer server.Close()
 
 	// Create config
-	cfg := config.New()
+	cfg := resetConfig()
 	cfg.Silent = true
+	cfg.AllowPrivateIPs = true
+	cfg.RateLimitPerHost = 1 << 20 // measure the probe, not the per-host limiter
 
 	// Create prober
 	prober := probe.NewProber(cfg)
 	ctx := context.Background()
 
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		prober.ProbeURL(ctx, server.URL, server.URL)
+	}
+}
+
+// Benchmark a probe of a 256 KiB body, where body reads dominate allocations
+func BenchmarkProbeURL_LargeBody(b *testing.B) {
+	body := []byte("<html><head><title>Large</title></head><body>" +
+		strings.Repeat("<p>test data with some content</p>\n", 7000) + "</body></html>")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	cfg := resetConfig()
+	cfg.Silent = true
+	cfg.AllowPrivateIPs = true
+	cfg.RateLimitPerHost = 1 << 20
+	prober := probe.NewProber(cfg)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		prober.ProbeURL(ctx, server.URL, server.URL)
+	}
+}
+
+// BenchmarkProbeURL_LargeBody with every -disable-analysis feature, close to
+// the cost of fetching the body
+func BenchmarkProbeURL_LargeBodyNoAnalysis(b *testing.B) {
+	body := []byte("<html><head><title>Large</title></head><body>" +
+		strings.Repeat("<p>test data with some content</p>\n", 7000) + "</body></html>")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	cfg := resetConfig()
+	cfg.Silent = true
+	cfg.AllowPrivateIPs = true
+	cfg.RateLimitPerHost = 1 << 20
+	cfg.DisabledAnalysis, _ = config.ParseAnalysisFeatures(strings.Join(config.AnalysisFeatureNames(), ","))
+	prober := probe.NewProber(cfg)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		prober.ProbeURL(ctx, server.URL, server.URL)
+	}
+}
+
+// Benchmark a probe of a 4 MiB body read in full and with -early-exit,
+// which stops once the title and keyword are found in the first chunk.
+// read-B/op is the body bytes read per probe.
+func BenchmarkProbeURL_EarlyExit(b *testing.B) {
+	body := []byte("<html><head><title>Dashboard [Jenkins]</title></head><body>X-Jenkins" +
+		strings.Repeat("<p>test data with some content</p>\n", 120_000) + "</body></html>")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	for _, earlyExit := range []bool{false, true} {
+		b.Run(fmt.Sprintf("early-exit=%v", earlyExit), func(b *testing.B) {
+			cfg := resetConfig()
+			cfg.Silent = true
+			cfg.AllowPrivateIPs = true
+			cfg.RateLimitPerHost = 1 << 20
+			cfg.KeywordList = []string{"X-Jenkins"}
+			cfg.EarlyExit = earlyExit
+			prober := probe.NewProber(cfg)
+			ctx := context.Background()
+
+			var read int
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				read += prober.ProbeURL(ctx, server.URL, server.URL).ContentLength
+			}
+			b.ReportMetric(float64(read)/float64(b.N), "read-B/op")
+		})
+	}
+}
+
+// Benchmark a probe with the -debug-on-error transcript built
+func BenchmarkProbeURL_DebugOnError(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><title>Test</title><body>Content</body></html>"))
+	}))
+	defer server.Close()
+
+	cfg := resetConfig()
+	cfg.Silent = true
+	cfg.AllowPrivateIPs = true
+	cfg.RateLimitPerHost = 1 << 20
+	cfg.DebugOnError = true
+	prober := probe.NewProber(cfg)
+	ctx := context.Background()
+
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		prober.ProbeURL(ctx, server.URL, server.URL)
@@ -164,8 +287,10 @@ func BenchmarkProcessURLsConcurrent(b *testing.B) {
 
 	for _, workers := range workerCounts {
 		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
-			cfg := config.New()
+			cfg := resetConfig()
 			cfg.Silent = true
+			cfg.AllowPrivateIPs = true
+			cfg.RateLimitPerHost = 1 << 20
 			prober := probe.NewProber(cfg)
 			ctx := context.Background()
 
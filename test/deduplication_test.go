@@ -48,7 +48,7 @@ func TestNormalizeURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parser.NormalizeURL(tt.input)
+			got := parser.NormalizeURL(tt.input, parser.StandardPorts)
 			if got != tt.expected {
 				t.Errorf("NormalizeURL() = %v, want %v", got, tt.expected)
 			}
@@ -56,32 +56,27 @@ func TestNormalizeURL(t *testing.T) {
 	}
 }
 
-// TestDeduplicateURLs tests the DeduplicateURLs function
+// TestDeduplicateURLs tests that expanded URLs from equivalent inputs
+// deduplicate with DeduplicateURLs' exact comparison
 func TestDeduplicateURLs(t *testing.T) {
 	tests := []struct {
 		name     string
-		input    []string
+		inputs   []string
 		expected []string
 	}{
 		{
-			name: "duplicate HTTP URLs",
-			input: []string{
-				"http://example.com:80/",
-				"http://example.com/",
-			},
-			expected: []string{"http://example.com:80/"},
+			name:     "duplicate HTTP URLs",
+			inputs:   []string{"http://example.com:80/", "http://example.com/"},
+			expected: []string{"http://example.com/"},
 		},
 		{
-			name: "duplicate HTTPS URLs",
-			input: []string{
-				"https://example.com:443/",
-				"https://example.com/",
-			},
-			expected: []string{"https://example.com:443/"},
+			name:     "duplicate HTTPS URLs",
+			inputs:   []string{"https://example.com:443/", "https://example.com/"},
+			expected: []string{"https://example.com/"},
 		},
 		{
 			name: "mixed duplicates",
-			input: []string{
+			inputs: []string{
 				"http://example.com:80/",
 				"http://example.com/",
 				"https://example.com:443/",
@@ -89,21 +84,26 @@ func TestDeduplicateURLs(t *testing.T) {
 				"http://example.com:8080/",
 			},
 			expected: []string{
-				"http://example.com:80/",
-				"https://example.com:443/",
+				"http://example.com/",
+				"https://example.com/",
 				"http://example.com:8080/",
 			},
 		},
+		{
+			name:     "bare host covers explicit default ports",
+			inputs:   []string{"example.com", "example.com:80", "example.com:443"},
+			expected: []string{"http://example.com/", "https://example.com/"},
+		},
 		{
 			name: "no duplicates",
-			input: []string{
+			inputs: []string{
 				"http://example.com:80/",
 				"https://example.com:443/",
 				"http://example.com:8080/",
 			},
 			expected: []string{
-				"http://example.com:80/",
-				"https://example.com:443/",
+				"http://example.com/",
+				"https://example.com/",
 				"http://example.com:8080/",
 			},
 		},
@@ -111,7 +111,11 @@ func TestDeduplicateURLs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parser.DeduplicateURLs(tt.input)
+			var expanded []string
+			for _, input := range tt.inputs {
+				expanded = append(expanded, parser.ExpandURLs(input, false, false, "", parser.StandardPorts).URLs()...)
+			}
+			got := parser.DeduplicateURLs(expanded)
 			if !reflect.DeepEqual(got, tt.expected) {
 				t.Errorf("DeduplicateURLs() = %v, want %v", got, tt.expected)
 			}
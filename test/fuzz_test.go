@@ -97,6 +97,6 @@ func FuzzExpandURLs(f *testing.F) {
 
 	f.Fuzz(func(t *testing.T, url string, allSchemes bool, ignorePorts bool, customPorts string) {
 		// Should not panic
-		_ = parser.ExpandURLs(url, allSchemes, ignorePorts, customPorts)
+		_ = parser.ExpandURLs(url, allSchemes, ignorePorts, customPorts, parser.StandardPorts)
 	})
 }
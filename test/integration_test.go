@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+
 	"probeHTTP/internal/output"
 	"probeHTTP/internal/probe"
 )
@@ -110,6 +116,7 @@ func TestProbeURL_Redirects(t *testing.T) {
 	redirectServer := createTestServer(redirectHandler(finalServer.URL))
 	defer redirectServer.Close()
 
+	cfg.AllowPrivateIPs = true
 	prober := probe.NewProber(cfg)
 	ctx := context.Background()
 	result := prober.ProbeURL(ctx, redirectServer.URL, redirectServer.URL)
@@ -205,6 +212,7 @@ func TestProbeURL_RedirectBodyReadError(t *testing.T) {
 	redirectServer := createTestServer(redirectHandler(finalServer.URL))
 	defer redirectServer.Close()
 
+	cfg.AllowPrivateIPs = true
 	prober := probe.NewProber(cfg)
 	defer prober.Close()
 	ctx := context.Background()
@@ -420,6 +428,62 @@ func TestProbeURL_HashConsistency(t *testing.T) {
 	assertStringEqual(t, result1.Hash.HeaderMMH3, result2.Hash.HeaderMMH3, "header hash consistency")
 }
 
+// TestProbeURL_DeterministicOutput tests that reruns marshal to identical
+// lines apart from timestamp/time fields and their generated run_id, the
+// fields -no-timestamp drops
+func TestProbeURL_DeterministicOutput(t *testing.T) {
+	cfg := resetConfig()
+	cfg.Silent = true
+	cfg.TechDetect = true
+	cfg.KeywordList = []string{"zeta", "alpha", "mid"}
+	cfg.IncludeResponseHeader = true
+
+	server := createTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Server", "nginx/1.18.0")
+		w.Header().Set("X-Powered-By", "PHP/8.1.2")
+		w.Header().Set("X-B", "2")
+		w.Header().Set("X-A", "1")
+		fmt.Fprint(w, `<html><head><title>Stable</title>
+<meta name="generator" content="WordPress 6.4">
+<script src="/wp-includes/js/jquery/jquery.min.js"></script>
+</head><body>zeta mid alpha</body></html>`)
+	})
+	defer server.Close()
+
+	var lines []string
+	for i := 0; i < 5; i++ {
+		// Each rerun is a new process with its own generated run ID
+		cfg.RunID = uuid.NewString()
+		prober := probe.NewProber(cfg)
+		result := prober.ProbeURL(context.Background(), server.URL, server.URL)
+		prober.Close()
+		if result.Error != "" {
+			t.Fatalf("probe failed: %s", result.Error)
+		}
+		if result.RunID != cfg.RunID {
+			t.Fatalf("run_id = %q, want %q", result.RunID, cfg.RunID)
+		}
+		result.Timestamp, result.StartedAt, result.CompletedAt, result.Time, result.ClockSkewMs = "", "", "", "", nil
+		result.RunID = ""
+		data, err := json.Marshal(result)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		lines = append(lines, string(data))
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != lines[0] {
+			t.Fatalf("run %d differs:\n%s\n%s", i, lines[0], lines[i])
+		}
+	}
+	if !strings.Contains(lines[0], `"matched_keywords":["alpha","mid","zeta"]`) {
+		t.Errorf("matched_keywords not sorted: %s", lines[0])
+	}
+}
+
 // TestProbeURL_PortExtraction tests port extraction from URLs
 func TestProbeURL_PortExtraction(t *testing.T) {
 	cfg := resetConfig()
@@ -550,3 +614,73 @@ func TestWorker(t *testing.T) {
 		t.Errorf("expected 2 results, got %d", count)
 	}
 }
+
+// TestHostProfiles_MultiPort probes several ports of one host and checks the
+// aggregated -host-profiles record.
+func TestHostProfiles_MultiPort(t *testing.T) {
+	cfg := resetConfig()
+	cfg.Silent = true
+	cfg.AllowPrivateIPs = true
+
+	web := createTestServer(simpleHTMLHandler)
+	defer web.Close()
+	_, webPort, _ := net.SplitHostPort(web.Listener.Addr().String())
+	redirector := createTestServer(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+webPort+"/", http.StatusMovedPermanently)
+	})
+	defer redirector.Close()
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedURL := "http://" + closed.Addr().String() + "/"
+	closed.Close()
+
+	urls := []string{web.URL + "/", web.URL + "/other", redirector.URL + "/", closedURL}
+	origMap := make(map[string]string)
+	for _, u := range urls {
+		origMap[u] = "127.0.0.1"
+	}
+
+	prober := probe.NewProber(cfg)
+	defer prober.Close()
+	profiler := output.NewProfiler(cfg.AliveStatus, func(scheme string) string { return "80" })
+	for result := range prober.ProcessURLs(context.Background(), urls, origMap, 4) {
+		profiler.Record(result)
+	}
+
+	path := filepath.Join(t.TempDir(), "profiles.jsonl")
+	if err := profiler.WriteProfiles(path); err != nil {
+		t.Fatalf("WriteProfiles: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d profiles, want 1 for 127.0.0.1:\n%s", len(lines), data)
+	}
+	var profile output.HostProfile
+	if err := json.Unmarshal([]byte(lines[0]), &profile); err != nil {
+		t.Fatalf("unmarshal profile: %v", err)
+	}
+
+	if profile.Host != "127.0.0.1" || profile.Probes != 4 || profile.Errors != 1 {
+		t.Errorf("host/probes/errors = %s/%d/%d, want 127.0.0.1/4/1", profile.Host, profile.Probes, profile.Errors)
+	}
+	if len(profile.Ports) != 2 {
+		t.Fatalf("ports = %+v, want the web and redirect ports", profile.Ports)
+	}
+	statuses := make(map[string][]int)
+	for _, p := range profile.Ports {
+		statuses[p.Port] = p.StatusCodes
+	}
+	_, redirectPort, _ := net.SplitHostPort(redirector.Listener.Addr().String())
+	if fmt.Sprint(statuses[webPort]) != "[200]" || fmt.Sprint(statuses[redirectPort]) != "[301]" {
+		t.Errorf("status codes by port = %v, want %s:[200] %s:[301]", statuses, webPort, redirectPort)
+	}
+	if fmt.Sprint(profile.Titles) != "[Test Page]" || fmt.Sprint(profile.WebServers) != "[TestServer/1.0]" {
+		t.Errorf("titles %v webservers %v, want one distinct value each", profile.Titles, profile.WebServers)
+	}
+	if !profile.RedirectsOffHost || fmt.Sprint(profile.RedirectHosts) != "[localhost]" {
+		t.Errorf("redirects_off_host = %v hosts %v, want true [localhost]", profile.RedirectsOffHost, profile.RedirectHosts)
+	}
+}
@@ -162,6 +162,10 @@ func TestParsePortList(t *testing.T) {
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("parsePortList() = %v, want %v", got, tt.want)
 			}
+
+			if n, err := parser.CountPortList(tt.input); err != nil || n != len(tt.want) {
+				t.Errorf("CountPortList() = %d, %v; want %d", n, err, len(tt.want))
+			}
 		})
 	}
 }
@@ -291,7 +295,7 @@ func TestParseInputURL(t *testing.T) {
 				Scheme:   "https",
 				Host:     "example.com",
 				Port:     "",
-				Path:     "/page#section",
+				Path:     "/page",
 			},
 		},
 		{
@@ -419,8 +423,8 @@ func TestGetSchemesToTest(t *testing.T) {
 					inputURL = tt.parsed.Host
 				}
 			}
-			got := parser.ExpandURLs(inputURL, tt.allSchemes, false, "")
-			
+			got := parser.ExpandURLs(inputURL, tt.allSchemes, false, "", parser.StandardPorts).URLs()
+
 			// Extract schemes from the expanded URLs
 			schemes := make(map[string]bool)
 			for _, url := range got {
@@ -430,7 +434,7 @@ func TestGetSchemesToTest(t *testing.T) {
 					schemes["https"] = true
 				}
 			}
-			
+
 			gotSchemes := make([]string, 0, len(schemes))
 			for scheme := range schemes {
 				gotSchemes = append(gotSchemes, scheme)
@@ -564,8 +568,8 @@ func TestGetPortsToTest(t *testing.T) {
 					inputURL = host
 				}
 			}
-			got := parser.ExpandURLs(inputURL, false, tt.ignorePorts, tt.customPorts)
-			
+			got := parser.ExpandURLs(inputURL, false, tt.ignorePorts, tt.customPorts, parser.StandardPorts).URLs()
+
 			// Extract ports from the expanded URLs for the specified scheme
 			ports := make(map[string]bool)
 			schemePrefix := tt.scheme + "://"
@@ -587,7 +591,7 @@ func TestGetPortsToTest(t *testing.T) {
 					}
 				}
 			}
-			
+
 			gotPorts := make([]string, 0, len(ports))
 			for port := range ports {
 				gotPorts = append(gotPorts, port)
@@ -670,7 +674,7 @@ func TestExpandURLs(t *testing.T) {
 			wantCount:   2,
 			wantContain: []string{
 				"http://example.com:443/",
-				"https://example.com:443/",
+				"https://example.com/", // default port not repeated
 			},
 		},
 		{
@@ -770,11 +774,14 @@ func TestExpandURLs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parser.ExpandURLs(tt.input, tt.allSchemes, tt.ignorePorts, tt.customPorts)
+			got := parser.ExpandURLs(tt.input, tt.allSchemes, tt.ignorePorts, tt.customPorts, parser.StandardPorts).URLs()
 
 			if len(got) != tt.wantCount {
 				t.Errorf("expandURLs() returned %d URLs, want %d\nGot: %v", len(got), tt.wantCount, got)
 			}
+			if n := parser.CountExpansions(tt.input, tt.allSchemes, tt.ignorePorts, tt.customPorts, parser.StandardPorts); n != len(got) {
+				t.Errorf("CountExpansions() = %d, ExpandURLs() returned %d", n, len(got))
+			}
 
 			// Check that all expected URLs are present
 			for _, wantURL := range tt.wantContain {
@@ -806,7 +813,7 @@ func TestExpandURLs(t *testing.T) {
 func TestExpandURLs_Deduplication(t *testing.T) {
 	// Test that same URL isn't duplicated
 	input := "http://example.com:80"
-	got := parser.ExpandURLs(input, false, false, "")
+	got := parser.ExpandURLs(input, false, false, "", parser.StandardPorts).URLs()
 
 	if len(got) != 1 {
 		t.Errorf("expandURLs() should not create duplicates, got %d URLs: %v", len(got), got)
@@ -837,11 +844,14 @@ func TestExpandURLs_ErrorHandling(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parser.ExpandURLs(tt.input, false, false, tt.customPorts)
+			got := parser.ExpandURLs(tt.input, false, false, tt.customPorts, parser.StandardPorts).URLs()
 
 			if len(got) != tt.wantCount {
 				t.Errorf("expandURLs() with error should return %d URLs, got %d: %v", tt.wantCount, len(got), got)
 			}
+			if n := parser.CountExpansions(tt.input, false, false, tt.customPorts, parser.StandardPorts); n != len(got) {
+				t.Errorf("CountExpansions() = %d, ExpandURLs() returned %d", n, len(got))
+			}
 		})
 	}
 }